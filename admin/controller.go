@@ -0,0 +1,189 @@
+// Package admin serves the control API of the index command, which pauses and resumes indexing, enqueues or skips
+// heights and changes the log level of a running indexer.
+package admin
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/DefiantLabs/cosmos-indexer/blockqueue"
+)
+
+// ErrQueueFull is returned by Enqueue when the indexer has not picked up the earlier requests yet
+var ErrQueueFull = errors.New("too many pending enqueue requests")
+
+// maxPendingRequests bounds the enqueue requests waiting to be picked up by the indexer
+const maxPendingRequests = 100
+
+// Controller holds the state changed through the admin API, the pause signals and the indexing schedule. The indexer
+// waits on it before sending a block to the RPC workers. A nil Controller never pauses nor skips.
+type Controller struct {
+	mu sync.Mutex
+	// paused is set through the admin API or signals, outsideWindow by the schedule, blocks wait while either is set
+	paused        bool
+	outsideWindow bool
+	resumed       chan struct{}
+	skipped       map[int64]struct{}
+	requests      chan blockqueue.Request
+}
+
+// running is returned by Resumed while indexing is neither paused nor outside of the schedule
+var running = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+func NewController() *Controller {
+	return &Controller{
+		resumed:  running,
+		skipped:  make(map[int64]struct{}),
+		requests: make(chan blockqueue.Request, maxPendingRequests),
+	}
+}
+
+// Pause stops blocks from being sent to the RPC workers, the blocks already fetched are still indexed. It returns
+// false if indexing was already paused.
+func (c *Controller) Pause() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.paused {
+		return false
+	}
+	c.paused = true
+	c.update()
+	return true
+}
+
+// Resume lets blocks flow again unless the schedule holds them, it returns false if indexing was not paused
+func (c *Controller) Resume() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.paused {
+		return false
+	}
+	c.paused = false
+	c.update()
+	return true
+}
+
+// SetOutsideWindow holds blocks while the indexing schedule is closed
+func (c *Controller) SetOutsideWindow(outside bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.outsideWindow = outside
+	c.update()
+}
+
+// update swaps the resumed channel when blocks start or stop waiting, it must be called with the lock held
+func (c *Controller) update() {
+	waiting := c.paused || c.outsideWindow
+	switch {
+	case waiting && c.resumed == running:
+		c.resumed = make(chan struct{})
+	case !waiting && c.resumed != running:
+		close(c.resumed)
+		c.resumed = running
+	}
+}
+
+func (c *Controller) Paused() bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.paused
+}
+
+func (c *Controller) OutsideWindow() bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.outsideWindow
+}
+
+// Resumed returns a channel that is closed once indexing is neither paused nor outside of the schedule
+func (c *Controller) Resumed() <-chan struct{} {
+	if c == nil {
+		return running
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.resumed
+}
+
+// Skip drops the block at height when it comes up for indexing, e.g. a block that crashes a parser
+func (c *Controller) Skip(height int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skipped[height] = struct{}{}
+}
+
+// Unskip lets a skipped block be indexed again, it returns false if the height was not skipped
+func (c *Controller) Unskip(height int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.skipped[height]; !ok {
+		return false
+	}
+	delete(c.skipped, height)
+	return true
+}
+
+func (c *Controller) Skipped(height int64) bool {
+	if c == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.skipped[height]
+	return ok
+}
+
+// SkippedHeights returns the skipped heights in ascending order
+func (c *Controller) SkippedHeights() []int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	heights := make([]int64, 0, len(c.skipped))
+	for height := range c.skipped {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	return heights
+}
+
+// Enqueue hands a request to the indexer, which enqueues its blocks in between the blocks of the configured range
+func (c *Controller) Enqueue(request blockqueue.Request) error {
+	select {
+	case c.requests <- request:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Requests returns the pending enqueue requests, a nil Controller returns a nil channel that never receives
+func (c *Controller) Requests() <-chan blockqueue.Request {
+	if c == nil {
+		return nil
+	}
+	return c.requests
+}
+
+// PendingRequests returns the number of enqueue requests not picked up by the indexer yet
+func (c *Controller) PendingRequests() int {
+	return len(c.requests)
+}
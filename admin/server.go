@@ -0,0 +1,258 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/auth"
+	"github.com/DefiantLabs/cosmos-indexer/blockqueue"
+	"github.com/DefiantLabs/cosmos-indexer/config"
+)
+
+// maxBodySize bounds the request bodies, which only hold a height, a range or a log level
+const maxBodySize = 1 << 16
+
+// Status is the response of every route changing the state, so clients see its effect right away
+type Status struct {
+	Paused          bool    `json:"paused"`
+	OutsideWindow   bool    `json:"outside_window"`
+	Skipped         []int64 `json:"skipped"`
+	PendingRequests int     `json:"pending_requests"`
+	LogLevel        string  `json:"log_level"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves the admin API over HTTP
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+}
+
+// NewServer creates a server listening on address, it starts serving once Serve is called. Every route requires the
+// admin scope, the authenticator must not be nil.
+func NewServer(address string, controller *Controller, authenticator *auth.Authenticator) (*Server, error) {
+	if authenticator == nil {
+		return nil, errors.New("the admin API requires authentication")
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		server:   &http.Server{Handler: NewHandler(controller, authenticator), ReadHeaderTimeout: 10 * time.Second},
+		listener: listener,
+	}, nil
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *Server) Serve() error {
+	err := s.server.Serve(s.listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting requests and waits up to 10 seconds for the running requests to finish
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		config.Log.Error("Error shutting down the admin API server", err)
+	}
+}
+
+type handler struct {
+	controller *Controller
+}
+
+type route struct {
+	method string
+	handle func(h *handler, w http.ResponseWriter, r *http.Request)
+}
+
+var routes = map[string]route{
+	"/status":    {http.MethodGet, (*handler).status},
+	"/pause":     {http.MethodPost, (*handler).pause},
+	"/resume":    {http.MethodPost, (*handler).resume},
+	"/enqueue":   {http.MethodPost, (*handler).enqueue},
+	"/skip":      {http.MethodPost, (*handler).skip},
+	"/unskip":    {http.MethodPost, (*handler).unskip},
+	"/log-level": {http.MethodPut, (*handler).logLevel},
+}
+
+// NewHandler returns the handler of the admin routes, all of which require the admin scope
+func NewHandler(controller *Controller, authenticator *auth.Authenticator) http.Handler {
+	return authenticator.Middleware(&handler{controller: controller})
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route, ok := routes[r.URL.Path]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	err := auth.Check(r.Context(), auth.ScopeAdmin)
+	switch {
+	case errors.Is(err, auth.ErrUnauthenticated):
+		auth.Unauthorized(w, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusForbidden, fmt.Sprintf("the %s scope is required", auth.ScopeAdmin))
+		return
+	}
+
+	if r.Method != route.method {
+		writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("only %s requests are supported", route.method))
+		return
+	}
+
+	route.handle(h, w, r)
+}
+
+func (h *handler) status(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, Status{
+		Paused:          h.controller.Paused(),
+		OutsideWindow:   h.controller.OutsideWindow(),
+		Skipped:         h.controller.SkippedHeights(),
+		PendingRequests: h.controller.PendingRequests(),
+		LogLevel:        config.LogLevel(),
+	})
+}
+
+func (h *handler) pause(w http.ResponseWriter, r *http.Request) {
+	if h.controller.Pause() {
+		config.Log.Warnf("Indexing paused by %s through the admin API", principalName(r))
+	}
+	h.status(w, r)
+}
+
+func (h *handler) resume(w http.ResponseWriter, r *http.Request) {
+	if h.controller.Resume() {
+		config.Log.Warnf("Indexing resumed by %s through the admin API", principalName(r))
+	}
+	h.status(w, r)
+}
+
+// enqueue takes the same requests as the block queue: a height, a range or a JSON request
+func (h *handler) enqueue(w http.ResponseWriter, r *http.Request) {
+	request, ok := readRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.controller.Enqueue(request); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	config.Log.Infof("Blocks %d-%d enqueued by %s through the admin API", request.StartHeight, request.EndHeight, principalName(r))
+
+	writeJSON(w, http.StatusAccepted, request)
+}
+
+func (h *handler) skip(w http.ResponseWriter, r *http.Request) {
+	height, ok := readHeight(w, r)
+	if !ok {
+		return
+	}
+
+	h.controller.Skip(height)
+	config.Log.Warnf("Block %d skipped by %s through the admin API", height, principalName(r))
+	h.status(w, r)
+}
+
+func (h *handler) unskip(w http.ResponseWriter, r *http.Request) {
+	height, ok := readHeight(w, r)
+	if !ok {
+		return
+	}
+
+	if !h.controller.Unskip(height) {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("block %d is not skipped", height))
+		return
+	}
+	config.Log.Infof("Block %d unskipped by %s through the admin API", height, principalName(r))
+	h.status(w, r)
+}
+
+func (h *handler) logLevel(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Level string `json:"level"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "the body must be a JSON object with the level")
+		return
+	}
+
+	if err := config.SetLogLevel(body.Level); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	config.Log.Warnf("Log level changed to %s by %s through the admin API", config.LogLevel(), principalName(r))
+	h.status(w, r)
+}
+
+// readRequest parses the body as a block request, responding with 400 and returning false if it is invalid
+func readRequest(w http.ResponseWriter, r *http.Request) (blockqueue.Request, bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return blockqueue.Request{}, false
+	}
+
+	request, err := blockqueue.ParseRequest(data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return request, false
+	}
+	return request, true
+}
+
+// readHeight parses the body as a single height
+func readHeight(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	request, ok := readRequest(w, r)
+	if !ok {
+		return 0, false
+	}
+
+	if request.StartHeight != request.EndHeight {
+		writeError(w, http.StatusBadRequest, "a single height is expected, not a range")
+		return 0, false
+	}
+	return request.StartHeight, true
+}
+
+func principalName(r *http.Request) string {
+	if principal := auth.FromContext(r.Context()); principal != nil {
+		return principal.Name
+	}
+	return "anonymous"
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		config.Log.Error("Error writing admin API response", err)
+	}
+}
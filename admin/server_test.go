@@ -0,0 +1,132 @@
+package admin
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/auth"
+	"github.com/DefiantLabs/cosmos-indexer/blockqueue"
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/stretchr/testify/suite"
+)
+
+type AdminTestSuite struct {
+	suite.Suite
+}
+
+func (suite *AdminTestSuite) TestController() {
+	controller := NewController()
+
+	select {
+	case <-controller.Resumed():
+	default:
+		suite.Fail("a new controller must not be paused")
+	}
+
+	suite.Require().True(controller.Pause())
+	suite.Require().False(controller.Pause())
+	suite.Require().True(controller.Paused())
+	resumed := controller.Resumed()
+	select {
+	case <-resumed:
+		suite.Fail("a paused controller must block")
+	default:
+	}
+
+	suite.Require().True(controller.Resume())
+	suite.Require().False(controller.Resume())
+	<-resumed
+
+	controller.SetOutsideWindow(true)
+	suite.Require().True(controller.Pause())
+	suite.Require().True(controller.Resume())
+	resumed = controller.Resumed()
+	select {
+	case <-resumed:
+		suite.Fail("resuming must not open a closed schedule window")
+	default:
+	}
+	controller.SetOutsideWindow(false)
+	<-resumed
+
+	controller.Skip(20)
+	controller.Skip(10)
+	suite.Require().True(controller.Skipped(10))
+	suite.Require().Equal([]int64{10, 20}, controller.SkippedHeights())
+	suite.Require().True(controller.Unskip(10))
+	suite.Require().False(controller.Unskip(10))
+	suite.Require().False(controller.Skipped(10))
+
+	var disabled *Controller
+	suite.Require().False(disabled.Paused())
+	suite.Require().False(disabled.Skipped(20))
+	suite.Require().Nil(disabled.Requests())
+	<-disabled.Resumed()
+}
+
+func (suite *AdminTestSuite) TestHandler() {
+	authenticator := auth.New(auth.Options{Keys: []auth.Key{
+		{Name: "operator", Hash: sha256.Sum256([]byte("admin-key")), Scopes: []auth.Scope{auth.ScopeAdmin}},
+		{Name: "reader", Hash: sha256.Sum256([]byte("read-key")), Scopes: []auth.Scope{auth.ScopeRead}},
+	}})
+	controller := NewController()
+	handler := NewHandler(controller, authenticator)
+
+	serve := func(method string, target string, key string, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, target, strings.NewReader(body))
+		if key != "" {
+			request.Header.Set("X-API-Key", key)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	suite.Require().Equal(http.StatusUnauthorized, serve(http.MethodPost, "/pause", "", "").Code)
+	suite.Require().Equal(http.StatusForbidden, serve(http.MethodPost, "/pause", "read-key", "").Code)
+	suite.Require().Equal(http.StatusNotFound, serve(http.MethodGet, "/unknown", "admin-key", "").Code)
+	suite.Require().Equal(http.StatusMethodNotAllowed, serve(http.MethodGet, "/pause", "admin-key", "").Code)
+
+	recorder := serve(http.MethodPost, "/pause", "admin-key", "")
+	suite.Require().Equal(http.StatusOK, recorder.Code)
+	var status Status
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &status))
+	suite.Require().True(status.Paused)
+
+	suite.Require().Equal(http.StatusOK, serve(http.MethodPost, "/resume", "admin-key", "").Code)
+	suite.Require().False(controller.Paused())
+
+	suite.Require().Equal(http.StatusAccepted, serve(http.MethodPost, "/enqueue", "admin-key", "100-200").Code)
+	request := <-controller.Requests()
+	suite.Require().Equal(int64(100), request.StartHeight)
+	suite.Require().Equal(int64(200), request.EndHeight)
+	suite.Require().Equal(http.StatusBadRequest, serve(http.MethodPost, "/enqueue", "admin-key", "200-100").Code)
+
+	suite.Require().Equal(http.StatusOK, serve(http.MethodPost, "/skip", "admin-key", `{"height": 42}`).Code)
+	suite.Require().True(controller.Skipped(42))
+	suite.Require().Equal(http.StatusBadRequest, serve(http.MethodPost, "/skip", "admin-key", "1-2").Code)
+	suite.Require().Equal(http.StatusOK, serve(http.MethodPost, "/unskip", "admin-key", "42").Code)
+	suite.Require().Equal(http.StatusNotFound, serve(http.MethodPost, "/unskip", "admin-key", "42").Code)
+
+	level := config.LogLevel()
+	defer func() { suite.Require().NoError(config.SetLogLevel(level)) }()
+	suite.Require().Equal(http.StatusOK, serve(http.MethodPut, "/log-level", "admin-key", `{"level": "debug"}`).Code)
+	suite.Require().Equal("debug", config.LogLevel())
+	suite.Require().Equal(http.StatusBadRequest, serve(http.MethodPut, "/log-level", "admin-key", `{"level": "loud"}`).Code)
+}
+
+func (suite *AdminTestSuite) TestEnqueueBounded() {
+	controller := NewController()
+	for i := 0; i < maxPendingRequests; i++ {
+		suite.Require().NoError(controller.Enqueue(blockqueue.Request{StartHeight: 1, EndHeight: 1}))
+	}
+	suite.Require().ErrorIs(controller.Enqueue(blockqueue.Request{StartHeight: 1, EndHeight: 1}), ErrQueueFull)
+}
+
+func TestAdmin(t *testing.T) {
+	suite.Run(t, new(AdminTestSuite))
+}
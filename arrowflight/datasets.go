@@ -0,0 +1,286 @@
+package arrowflight
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/apache/arrow/go/v11/arrow"
+	"github.com/apache/arrow/go/v11/arrow/array"
+)
+
+type columnKind int
+
+const (
+	columnString columnKind = iota
+	columnInt64
+	columnBool
+	columnTimestamp
+)
+
+type column struct {
+	name string
+	kind columnKind
+}
+
+// dataset is a flattened, columnar view over the indexed tables. Every dataset starts with chain_id and height
+// columns so requests can be scoped to a chain and a height range.
+type dataset struct {
+	name        string
+	description string
+	columns     []column
+	// query selects the columns in order, filters are appended to the WHERE clause. The tables are {table}
+	// placeholders aliased to their default names.
+	query   string
+	orderBy string
+	// compressed is set when the query selects the compressed form of the last column after the columns, the event
+	// attribute values stored compressed
+	compressed bool
+}
+
+var datasets = map[string]dataset{
+	"blocks": {
+		name:        "blocks",
+		description: "indexed blocks",
+		columns: []column{
+			{"chain_id", columnString},
+			{"height", columnInt64},
+			{"time", columnTimestamp},
+			{"proposer_cons_address", columnString},
+			{"tx_indexed", columnBool},
+			{"block_events_indexed", columnBool},
+			{"custom_parsers_applied", columnBool},
+			{"verified", columnBool},
+		},
+		query: `SELECT chains.chain_id, blocks.height, blocks.time_stamp, addresses.address, blocks.tx_indexed, blocks.block_events_indexed,
+				blocks.custom_parsers_applied, blocks.verified
+			FROM {blocks} blocks
+			JOIN {chains} chains ON chains.id = blocks.chain_id
+			LEFT JOIN {addresses} addresses ON addresses.id = blocks.proposer_cons_address_id
+			WHERE TRUE`,
+		orderBy: "blocks.height",
+	},
+	"txs": {
+		name:        "txs",
+		description: "indexed transactions",
+		columns: []column{
+			{"chain_id", columnString},
+			{"height", columnInt64},
+			{"time", columnTimestamp},
+			{"hash", columnString},
+			{"code", columnInt64},
+		},
+		query: `SELECT chains.chain_id, blocks.height, blocks.time_stamp, txes.hash, txes.code
+			FROM {txes} txes
+			JOIN {blocks} blocks ON blocks.id = txes.block_id
+			JOIN {chains} chains ON chains.id = blocks.chain_id
+			WHERE TRUE`,
+		orderBy: "blocks.height, txes.id",
+	},
+	"messages": {
+		name:        "messages",
+		description: "transaction messages, one row per message",
+		columns: []column{
+			{"chain_id", columnString},
+			{"height", columnInt64},
+			{"tx_hash", columnString},
+			{"message_index", columnInt64},
+			{"message_type", columnString},
+		},
+		query: `SELECT chains.chain_id, blocks.height, txes.hash, messages.message_index, message_types.message_type
+			FROM {messages} messages
+			JOIN {message_types} message_types ON message_types.id = messages.message_type_id
+			JOIN {txes} txes ON txes.id = messages.tx_id
+			JOIN {blocks} blocks ON blocks.id = txes.block_id
+			JOIN {chains} chains ON chains.id = blocks.chain_id
+			WHERE TRUE`,
+		orderBy: "blocks.height, txes.id, messages.message_index",
+	},
+	"message_events": {
+		name:        "message_events",
+		description: "message events, one row per event attribute",
+		columns: []column{
+			{"chain_id", columnString},
+			{"height", columnInt64},
+			{"tx_hash", columnString},
+			{"message_index", columnInt64},
+			{"event_index", columnInt64},
+			{"event_type", columnString},
+			{"attribute_index", columnInt64},
+			{"attribute_key", columnString},
+			{"attribute_value", columnString},
+		},
+		query: `SELECT chains.chain_id, blocks.height, txes.hash, messages.message_index, message_events.index, message_event_types.type,
+				message_event_attributes.index, message_event_attribute_keys.key, message_event_attributes.value,
+				message_event_attributes.compressed_value
+			FROM {message_event_attributes} message_event_attributes
+			JOIN {message_event_attribute_keys} message_event_attribute_keys ON message_event_attribute_keys.id = message_event_attributes.message_event_attribute_key_id
+			JOIN {message_events} message_events ON message_events.id = message_event_attributes.message_event_id
+			JOIN {message_event_types} message_event_types ON message_event_types.id = message_events.message_event_type_id
+			JOIN {messages} messages ON messages.id = message_events.message_id
+			JOIN {txes} txes ON txes.id = messages.tx_id
+			JOIN {blocks} blocks ON blocks.id = txes.block_id
+			JOIN {chains} chains ON chains.id = blocks.chain_id
+			WHERE TRUE`,
+		orderBy:    "blocks.height, txes.id, messages.message_index, message_events.index, message_event_attributes.index",
+		compressed: true,
+	},
+	"block_events": {
+		name:        "block_events",
+		description: "BeginBlock and EndBlock events, one row per event attribute",
+		columns: []column{
+			{"chain_id", columnString},
+			{"height", columnInt64},
+			{"lifecycle_position", columnString},
+			{"event_index", columnInt64},
+			{"event_type", columnString},
+			{"attribute_index", columnInt64},
+			{"attribute_key", columnString},
+			{"attribute_value", columnString},
+		},
+		query: `SELECT chains.chain_id, blocks.height,
+				CASE block_events.lifecycle_position WHEN 0 THEN 'begin_block' ELSE 'end_block' END,
+				block_events.index, block_event_types.type,
+				block_event_attributes.index, block_event_attribute_keys.key, block_event_attributes.value,
+				block_event_attributes.compressed_value
+			FROM {block_event_attributes} block_event_attributes
+			JOIN {block_event_attribute_keys} block_event_attribute_keys ON block_event_attribute_keys.id = block_event_attributes.block_event_attribute_key_id
+			JOIN {block_events} block_events ON block_events.id = block_event_attributes.block_event_id
+			JOIN {block_event_types} block_event_types ON block_event_types.id = block_events.block_event_type_id
+			JOIN {blocks} blocks ON blocks.id = block_events.block_id
+			JOIN {chains} chains ON chains.id = blocks.chain_id
+			WHERE TRUE`,
+		orderBy:    "blocks.height, block_events.lifecycle_position, block_events.index, block_event_attributes.index",
+		compressed: true,
+	},
+}
+
+func datasetNames() []string {
+	names := make([]string, 0, len(datasets))
+	for name := range datasets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (d dataset) schema() *arrow.Schema {
+	fields := make([]arrow.Field, 0, len(d.columns))
+	for _, col := range d.columns {
+		fields = append(fields, arrow.Field{Name: col.name, Type: col.kind.dataType(), Nullable: true})
+	}
+	return arrow.NewSchema(fields, nil)
+}
+
+// sql builds the dataset query for the request filters on the tables
+func (d dataset) sql(request Request, tables models.Tables) (string, []any) {
+	var query strings.Builder
+	var args []any
+
+	query.WriteString(tables.Expand(d.query))
+	if request.ChainID != "" {
+		query.WriteString(" AND chains.chain_id = ?")
+		args = append(args, request.ChainID)
+	}
+	if request.StartHeight > 0 {
+		query.WriteString(" AND blocks.height >= ?")
+		args = append(args, request.StartHeight)
+	}
+	if request.EndHeight > 0 {
+		query.WriteString(" AND blocks.height <= ?")
+		args = append(args, request.EndHeight)
+	}
+	fmt.Fprintf(&query, " ORDER BY %s", d.orderBy)
+
+	return query.String(), args
+}
+
+func (k columnKind) dataType() arrow.DataType {
+	switch k {
+	case columnInt64:
+		return arrow.PrimitiveTypes.Int64
+	case columnBool:
+		return arrow.FixedWidthTypes.Boolean
+	case columnTimestamp:
+		return arrow.FixedWidthTypes.Timestamp_us
+	default:
+		return arrow.BinaryTypes.String
+	}
+}
+
+// scanDestinations returns one nullable scan target per column, followed by the one of the compressed value
+func (d dataset) scanDestinations() []any {
+	destinations := make([]any, 0, len(d.columns))
+	for _, col := range d.columns {
+		switch col.kind {
+		case columnInt64:
+			destinations = append(destinations, &sql.NullInt64{})
+		case columnBool:
+			destinations = append(destinations, &sql.NullBool{})
+		case columnTimestamp:
+			destinations = append(destinations, &sql.NullTime{})
+		default:
+			destinations = append(destinations, &sql.NullString{})
+		}
+	}
+	if d.compressed {
+		destinations = append(destinations, new([]byte))
+	}
+	return destinations
+}
+
+// decompress reads the scanned compressed value, if any, into the last column
+func (d dataset) decompress(destinations []any) error {
+	if !d.compressed {
+		return nil
+	}
+
+	compressed := *destinations[len(d.columns)].(*[]byte)
+	if compressed == nil {
+		return nil
+	}
+	value, err := models.DecompressBytes(compressed)
+	if err != nil {
+		return err
+	}
+	*destinations[len(d.columns)-1].(*sql.NullString) = sql.NullString{String: string(value), Valid: true}
+	return nil
+}
+
+// appendRow appends the scanned values to the record builder fields
+func appendRow(builder *array.RecordBuilder, destinations []any) {
+	for i, destination := range destinations {
+		switch value := destination.(type) {
+		case *sql.NullInt64:
+			field := builder.Field(i).(*array.Int64Builder)
+			if value.Valid {
+				field.Append(value.Int64)
+			} else {
+				field.AppendNull()
+			}
+		case *sql.NullBool:
+			field := builder.Field(i).(*array.BooleanBuilder)
+			if value.Valid {
+				field.Append(value.Bool)
+			} else {
+				field.AppendNull()
+			}
+		case *sql.NullTime:
+			field := builder.Field(i).(*array.TimestampBuilder)
+			if value.Valid {
+				field.Append(arrow.Timestamp(value.Time.UnixMicro()))
+			} else {
+				field.AppendNull()
+			}
+		case *sql.NullString:
+			field := builder.Field(i).(*array.StringBuilder)
+			if value.Valid {
+				field.Append(value.String)
+			} else {
+				field.AppendNull()
+			}
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package arrowflight
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/apache/arrow/go/v11/arrow/array"
+	"github.com/apache/arrow/go/v11/arrow/flight"
+	"github.com/apache/arrow/go/v11/arrow/ipc"
+	"github.com/apache/arrow/go/v11/arrow/memory"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Request selects a dataset and optionally scopes it to a chain and an inclusive height range. It is sent as JSON in
+// CMD flight descriptors and is used as the ticket of the returned endpoint.
+type Request struct {
+	Dataset     string `json:"dataset"`
+	ChainID     string `json:"chain_id,omitempty"`
+	StartHeight int64  `json:"start_height,omitempty"`
+	EndHeight   int64  `json:"end_height,omitempty"`
+}
+
+// Service serves the indexed datasets as Arrow record batches streamed straight from the database
+type Service struct {
+	flight.BaseFlightServer
+	db *gorm.DB
+	// tables resolves the tables of the dataset queries through the naming strategy of the database
+	tables    models.Tables
+	batchSize int
+	mem       memory.Allocator
+}
+
+func NewService(db *gorm.DB, batchSize int) *Service {
+	var namer schema.Namer = schema.NamingStrategy{}
+	if db != nil {
+		namer = db.NamingStrategy
+	}
+	return &Service{db: db, tables: models.NewTables(namer), batchSize: batchSize, mem: memory.DefaultAllocator}
+}
+
+// NewServer creates a Flight server listening on address with the service registered
+func NewServer(address string, service *Service) (flight.Server, error) {
+	server := flight.NewServerWithMiddleware(nil)
+	if err := server.Init(address); err != nil {
+		return nil, err
+	}
+	server.RegisterFlightService(service)
+	return server, nil
+}
+
+// Descriptors either carry a JSON Request as command, or a path of a single dataset name
+func parseDescriptor(descriptor *flight.FlightDescriptor) (Request, error) {
+	var request Request
+	if descriptor == nil {
+		return request, status.Error(codes.InvalidArgument, "missing flight descriptor")
+	}
+
+	switch descriptor.Type {
+	case flight.DescriptorCMD:
+		if err := json.Unmarshal(descriptor.Cmd, &request); err != nil {
+			return request, status.Errorf(codes.InvalidArgument, "invalid command: %v", err)
+		}
+	case flight.DescriptorPATH:
+		if len(descriptor.Path) != 1 {
+			return request, status.Error(codes.InvalidArgument, "path descriptors must contain exactly one dataset name")
+		}
+		request.Dataset = descriptor.Path[0]
+	default:
+		return request, status.Error(codes.InvalidArgument, "unsupported descriptor type")
+	}
+
+	return request, nil
+}
+
+func lookupDataset(request Request) (dataset, error) {
+	set, ok := datasets[request.Dataset]
+	if !ok {
+		return set, status.Errorf(codes.NotFound, "unknown dataset %q, available datasets are %v", request.Dataset, datasetNames())
+	}
+	if request.StartHeight < 0 || request.EndHeight < 0 || (request.EndHeight > 0 && request.EndHeight < request.StartHeight) {
+		return set, status.Error(codes.InvalidArgument, "invalid height range")
+	}
+	return set, nil
+}
+
+func (s *Service) flightInfo(request Request, set dataset) (*flight.FlightInfo, error) {
+	ticket, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flight.FlightInfo{
+		Schema:           flight.SerializeSchema(set.schema(), s.mem),
+		FlightDescriptor: &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: ticket},
+		Endpoint:         []*flight.FlightEndpoint{{Ticket: &flight.Ticket{Ticket: ticket}}},
+		// Counting rows would require running the query, so totals are reported as unknown
+		TotalRecords: -1,
+		TotalBytes:   -1,
+	}, nil
+}
+
+func (s *Service) ListFlights(_ *flight.Criteria, stream flight.FlightService_ListFlightsServer) error {
+	for _, name := range datasetNames() {
+		info, err := s.flightInfo(Request{Dataset: name}, datasets[name])
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Service) GetFlightInfo(_ context.Context, descriptor *flight.FlightDescriptor) (*flight.FlightInfo, error) {
+	request, err := parseDescriptor(descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := lookupDataset(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.flightInfo(request, set)
+}
+
+func (s *Service) GetSchema(_ context.Context, descriptor *flight.FlightDescriptor) (*flight.SchemaResult, error) {
+	request, err := parseDescriptor(descriptor)
+	if err != nil {
+		return nil, err
+	}
+
+	set, err := lookupDataset(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &flight.SchemaResult{Schema: flight.SerializeSchema(set.schema(), s.mem)}, nil
+}
+
+func (s *Service) DoGet(ticket *flight.Ticket, stream flight.FlightService_DoGetServer) error {
+	var request Request
+	if err := json.Unmarshal(ticket.Ticket, &request); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid ticket: %v", err)
+	}
+
+	set, err := lookupDataset(request)
+	if err != nil {
+		return err
+	}
+
+	arrowSchema := set.schema()
+	writer := flight.NewRecordWriter(stream, ipc.WithSchema(arrowSchema), ipc.WithAllocator(s.mem))
+	defer writer.Close()
+
+	query, args := set.sql(request, s.tables)
+	rows, err := s.db.WithContext(stream.Context()).Raw(query, args...).Rows()
+	if err != nil {
+		config.Log.Error(fmt.Sprintf("Error querying Arrow Flight dataset %s", set.name), err)
+		return status.Errorf(codes.Internal, "failed to query dataset %s", set.name)
+	}
+	defer rows.Close()
+
+	builder := array.NewRecordBuilder(s.mem, arrowSchema)
+	defer builder.Release()
+
+	destinations := set.scanDestinations()
+	pending := 0
+
+	flush := func() error {
+		record := builder.NewRecord()
+		defer record.Release()
+		pending = 0
+		return writer.Write(record)
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(destinations...); err != nil {
+			return status.Errorf(codes.Internal, "failed to read dataset %s: %v", set.name, err)
+		}
+		if err := set.decompress(destinations); err != nil {
+			return status.Errorf(codes.Internal, "failed to read dataset %s: %v", set.name, err)
+		}
+
+		appendRow(builder, destinations)
+		pending++
+
+		if pending == s.batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return status.Errorf(codes.Internal, "failed to read dataset %s: %v", set.name, err)
+	}
+
+	if pending != 0 {
+		return flush()
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package arrowflight
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/apache/arrow/go/v11/arrow/flight"
+	"github.com/apache/arrow/go/v11/arrow/memory"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm/schema"
+)
+
+type ArrowFlightTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ArrowFlightTestSuite) TestDatasetSQLFilters() {
+	tables := models.NewTables(schema.NamingStrategy{})
+	query, args := datasets["messages"].sql(Request{Dataset: "messages", ChainID: "cosmoshub-4", StartHeight: 10, EndHeight: 20}, tables)
+	suite.Require().Contains(query, "AND chains.chain_id = ? AND blocks.height >= ? AND blocks.height <= ? ORDER BY")
+	suite.Require().Equal([]any{"cosmoshub-4", int64(10), int64(20)}, args)
+
+	query, args = datasets["blocks"].sql(Request{Dataset: "blocks"}, tables)
+	suite.Require().NotContains(query, "chains.chain_id = ?")
+	suite.Require().Empty(args)
+
+	// The tables are named by the naming strategy and keep their default names as aliases
+	query, _ = datasets["txs"].sql(Request{Dataset: "txs", ChainID: "cosmoshub-4"}, models.NewTables(schema.NamingStrategy{TablePrefix: "idx_"}))
+	suite.Require().Contains(query, `FROM "idx_txes" txes`)
+	suite.Require().Contains(query, `JOIN "idx_chains" chains ON chains.id = blocks.chain_id`)
+	suite.Require().NotContains(query, "{")
+}
+
+func (suite *ArrowFlightTestSuite) TestSchemasMatchQueries() {
+	for name, set := range datasets {
+		suite.Require().Equal(len(set.columns), len(set.schema().Fields()), name)
+		if set.compressed {
+			suite.Require().Len(set.scanDestinations(), len(set.columns)+1, name)
+			suite.Require().Equal("attribute_value", set.columns[len(set.columns)-1].name, name)
+		} else {
+			suite.Require().Len(set.scanDestinations(), len(set.columns), name)
+		}
+		suite.Require().Equal("chain_id", set.columns[0].name, name)
+		suite.Require().Equal("height", set.columns[1].name, name)
+	}
+}
+
+func (suite *ArrowFlightTestSuite) TestDecompress() {
+	models.SetCompression(64)
+	defer models.SetCompression(0)
+	large := strings.Repeat(`{"contract":"cosmos1contract","action":"airdrop"}`, 20)
+	_, compressed := models.AttributeValueColumns(large)
+	suite.Require().NotNil(compressed)
+
+	set := datasets["message_events"]
+	destinations := set.scanDestinations()
+	*destinations[len(destinations)-1].(*[]byte) = compressed.([]byte)
+	suite.Require().NoError(set.decompress(destinations))
+	suite.Require().Equal(sql.NullString{String: large, Valid: true}, *destinations[len(set.columns)-1].(*sql.NullString))
+
+	// Values stored as they are are kept
+	*destinations[len(destinations)-1].(*[]byte) = nil
+	*destinations[len(set.columns)-1].(*sql.NullString) = sql.NullString{String: "100uatom", Valid: true}
+	suite.Require().NoError(set.decompress(destinations))
+	suite.Require().Equal("100uatom", destinations[len(set.columns)-1].(*sql.NullString).String)
+}
+
+func (suite *ArrowFlightTestSuite) TestGetFlightInfo() {
+	service := NewService(nil, 100)
+
+	info, err := service.GetFlightInfo(context.Background(), &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{"txs"}})
+	suite.Require().NoError(err)
+
+	var ticket Request
+	suite.Require().NoError(json.Unmarshal(info.Endpoint[0].Ticket.Ticket, &ticket))
+	suite.Require().Equal(Request{Dataset: "txs"}, ticket)
+
+	arrowSchema, err := flight.DeserializeSchema(info.Schema, memory.DefaultAllocator)
+	suite.Require().NoError(err)
+	suite.Require().True(arrowSchema.Equal(datasets["txs"].schema()))
+
+	cmd, err := json.Marshal(Request{Dataset: "txs", StartHeight: 20, EndHeight: 10})
+	suite.Require().NoError(err)
+	_, err = service.GetFlightInfo(context.Background(), &flight.FlightDescriptor{Type: flight.DescriptorCMD, Cmd: cmd})
+	suite.Require().Equal(codes.InvalidArgument, status.Code(err))
+
+	_, err = service.GetFlightInfo(context.Background(), &flight.FlightDescriptor{Type: flight.DescriptorPATH, Path: []string{"unknown"}})
+	suite.Require().Equal(codes.NotFound, status.Code(err))
+}
+
+func TestArrowFlightTestSuite(t *testing.T) {
+	suite.Run(t, new(ArrowFlightTestSuite))
+}
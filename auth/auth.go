@@ -0,0 +1,190 @@
+// Package auth authenticates API clients with API keys or JWTs and authorizes them by scope.
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Scope is a permission granted to an API key or JWT
+type Scope string
+
+const (
+	// ScopeRead allows querying the indexed tables
+	ScopeRead Scope = "read"
+	// ScopeStream allows subscribing to newly indexed data
+	ScopeStream Scope = "stream"
+	// ScopeAdmin allows everything, including administrative endpoints
+	ScopeAdmin Scope = "admin"
+)
+
+var Scopes = []Scope{ScopeRead, ScopeStream, ScopeAdmin}
+
+var (
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrForbidden       = errors.New("forbidden")
+)
+
+// Key is an API key, only the SHA-256 hash of the key is kept
+type Key struct {
+	Name   string
+	Hash   [sha256.Size]byte
+	Scopes []Scope
+	// Rate and Burst override the rate limit of the key, 0 keeps the default
+	Rate  float64
+	Burst int
+}
+
+// Principal is an authenticated client
+type Principal struct {
+	// Name is the name of the API key or the subject of the JWT
+	Name   string
+	Scopes []Scope
+	Rate   float64
+	Burst  int
+	// JWT is true for token subjects, which unlike key names are not known upfront
+	JWT bool
+}
+
+// Anonymous is true for the principal of every request when authentication is disabled
+func (p *Principal) Anonymous() bool {
+	return p == unrestricted
+}
+
+func (p *Principal) Allows(scope Scope) bool {
+	for _, granted := range p.Scopes {
+		if granted == scope || granted == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Options configure how clients may authenticate, keys and JWTs may be combined
+type Options struct {
+	Keys []Key
+	// JWTSecret verifies HS256 tokens
+	JWTSecret []byte
+	// JWTPublicKey verifies RS256 tokens
+	JWTPublicKey *rsa.PublicKey
+	// JWTIssuer and JWTAudience are checked against the iss and aud claims when set
+	JWTIssuer   string
+	JWTAudience string
+}
+
+// Authenticator authenticates requests. A nil Authenticator disables authentication and grants every request all
+// scopes, so the servers only need to handle a single code path.
+type Authenticator struct {
+	keys    map[[sha256.Size]byte]Key
+	options Options
+}
+
+func New(options Options) *Authenticator {
+	keys := make(map[[sha256.Size]byte]Key, len(options.Keys))
+	for _, key := range options.Keys {
+		keys[key.Hash] = key
+	}
+	return &Authenticator{keys: keys, options: options}
+}
+
+// unrestricted is the principal of every request when authentication is disabled
+var unrestricted = &Principal{Name: "anonymous", Scopes: []Scope{ScopeAdmin}}
+
+// Authenticate returns the principal of the request credentials, nil without error when the request has none
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	if a == nil {
+		return unrestricted, nil
+	}
+
+	credential := credentials(r)
+	if credential == "" {
+		return nil, nil
+	}
+
+	// JWTs are the only credentials made of three dot separated parts
+	if strings.Count(credential, ".") == 2 {
+		return a.verifyJWT(credential)
+	}
+
+	key, ok := a.keys[sha256.Sum256([]byte(credential))]
+	if !ok {
+		return nil, errors.New("invalid API key")
+	}
+	return &Principal{Name: key.Name, Scopes: key.Scopes, Rate: key.Rate, Burst: key.Burst}, nil
+}
+
+// credentials takes the API key or JWT from the Authorization or X-API-Key header. Browsers cannot set headers on
+// WebSocket upgrades, so these may pass it in the access_token query parameter instead.
+func credentials(r *http.Request) string {
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		scheme, credential, _ := strings.Cut(authorization, " ")
+		if strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(credential)
+		}
+		return ""
+	}
+
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return r.URL.Query().Get("access_token")
+	}
+
+	return ""
+}
+
+type contextKey struct{}
+
+// Middleware authenticates every request and makes the principal available to Check. Requests with invalid
+// credentials are rejected right away, requests without credentials are left to the scope checks of the handler.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := a.Authenticate(r)
+		if err != nil {
+			Unauthorized(w, err.Error())
+			return
+		}
+
+		if principal != nil {
+			r = r.WithContext(WithPrincipal(r.Context(), principal))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext returns the principal of the request, nil if it is unauthenticated
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(contextKey{}).(*Principal)
+	return principal
+}
+
+// Check returns ErrUnauthenticated if the request is unauthenticated and ErrForbidden if it lacks the scope
+func Check(ctx context.Context, scope Scope) error {
+	principal := FromContext(ctx)
+	if principal == nil {
+		return ErrUnauthenticated
+	}
+	if !principal.Allows(scope) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Unauthorized responds with 401 and the Bearer challenge
+func Unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="cosmos-indexer"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
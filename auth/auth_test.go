@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type AuthTestSuite struct {
+	suite.Suite
+}
+
+func (suite *AuthTestSuite) request(credential string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/blocks", nil)
+	if credential != "" {
+		r.Header.Set("Authorization", "Bearer "+credential)
+	}
+	return r
+}
+
+func (suite *AuthTestSuite) TestAPIKeys() {
+	authenticator := New(Options{Keys: []Key{{Name: "dashboard", Hash: sha256.Sum256([]byte("secret-key")), Scopes: []Scope{ScopeRead}}}})
+
+	principal, err := authenticator.Authenticate(suite.request("secret-key"))
+	suite.Require().NoError(err)
+	suite.Require().Equal("dashboard", principal.Name)
+	suite.Require().True(principal.Allows(ScopeRead))
+	suite.Require().False(principal.Allows(ScopeStream))
+
+	r := httptest.NewRequest(http.MethodGet, "/blocks", nil)
+	r.Header.Set("X-API-Key", "secret-key")
+	principal, err = authenticator.Authenticate(r)
+	suite.Require().NoError(err)
+	suite.Require().Equal("dashboard", principal.Name)
+
+	_, err = authenticator.Authenticate(suite.request("wrong-key"))
+	suite.Require().Error(err)
+
+	// The query parameter is only accepted on WebSocket upgrades
+	principal, err = authenticator.Authenticate(httptest.NewRequest(http.MethodGet, "/blocks?access_token=secret-key", nil))
+	suite.Require().NoError(err)
+	suite.Require().Nil(principal)
+
+	r = httptest.NewRequest(http.MethodGet, "/stream?access_token=secret-key", nil)
+	r.Header.Set("Upgrade", "websocket")
+	principal, err = authenticator.Authenticate(r)
+	suite.Require().NoError(err)
+	suite.Require().Equal("dashboard", principal.Name)
+}
+
+func (suite *AuthTestSuite) TestDisabled() {
+	var authenticator *Authenticator
+	principal, err := authenticator.Authenticate(suite.request(""))
+	suite.Require().NoError(err)
+	suite.Require().True(principal.Allows(ScopeAdmin))
+}
+
+func encodeJWT(alg string, claims map[string]any, sign func([]byte) []byte) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(unsigned)))
+}
+
+func (suite *AuthTestSuite) TestHS256() {
+	secret := []byte("jwt-secret")
+	authenticator := New(Options{JWTSecret: secret, JWTIssuer: "https://issuer", JWTAudience: "indexer"})
+	hs256 := func(key []byte) func([]byte) []byte {
+		return func(data []byte) []byte {
+			mac := hmac.New(sha256.New, key)
+			mac.Write(data)
+			return mac.Sum(nil)
+		}
+	}
+	claims := func(exp time.Time, aud any) map[string]any {
+		return map[string]any{"sub": "service", "iss": "https://issuer", "aud": aud, "exp": exp.Unix(), "scope": "read stream"}
+	}
+
+	principal, err := authenticator.Authenticate(suite.request(encodeJWT("HS256", claims(time.Now().Add(time.Hour), []string{"other", "indexer"}), hs256(secret))))
+	suite.Require().NoError(err)
+	suite.Require().Equal("service", principal.Name)
+	suite.Require().True(principal.Allows(ScopeStream))
+	suite.Require().False(principal.Allows(ScopeAdmin))
+
+	invalid := []string{
+		encodeJWT("HS256", claims(time.Now().Add(-time.Hour), "indexer"), hs256(secret)),
+		encodeJWT("HS256", claims(time.Now().Add(time.Hour), "other"), hs256(secret)),
+		encodeJWT("HS256", claims(time.Now().Add(time.Hour), "indexer"), hs256([]byte("wrong-secret"))),
+		encodeJWT("none", claims(time.Now().Add(time.Hour), "indexer"), func([]byte) []byte { return nil }),
+		encodeJWT("HS256", map[string]any{"sub": "service", "iss": "https://issuer", "aud": "indexer"}, hs256(secret)),
+	}
+	for _, token := range invalid {
+		_, err = authenticator.Authenticate(suite.request(token))
+		suite.Require().Error(err, token)
+	}
+}
+
+func (suite *AuthTestSuite) TestRS256() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+	authenticator := New(Options{JWTPublicKey: &key.PublicKey})
+
+	rs256 := func(data []byte) []byte {
+		digest := sha256.Sum256(data)
+		signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		suite.Require().NoError(err)
+		return signature
+	}
+	claims := map[string]any{"sub": "admin", "exp": time.Now().Add(time.Hour).Unix(), "scope": "admin"}
+
+	principal, err := authenticator.Authenticate(suite.request(encodeJWT("RS256", claims, rs256)))
+	suite.Require().NoError(err)
+	suite.Require().True(principal.Allows(ScopeRead))
+
+	// A token signed with the public key as HMAC secret must not pass as HS256
+	_, err = authenticator.Authenticate(suite.request(encodeJWT("HS256", claims, func(data []byte) []byte {
+		mac := hmac.New(sha256.New, key.PublicKey.N.Bytes())
+		mac.Write(data)
+		return mac.Sum(nil)
+	})))
+	suite.Require().Error(err)
+}
+
+func TestAuthTestSuite(t *testing.T) {
+	suite.Run(t, new(AuthTestSuite))
+}
@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tolerated clock difference between the token issuer and the server
+const jwtLeeway = 30 * time.Second
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+}
+
+type jwtClaims struct {
+	Subject   string          `json:"sub"`
+	Issuer    string          `json:"iss"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	// Scope is the space separated list of scopes, as in OAuth 2.0 access tokens
+	Scope string `json:"scope"`
+}
+
+// verifyJWT checks the signature and claims of the token. Tokens must expire and the algorithm must match the
+// configured key, so a token cannot pick a weaker algorithm or none at all.
+func (a *Authenticator) verifyJWT(token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+
+	headerData, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("invalid JWT header")
+	}
+	var header jwtHeader
+	if err := json.Unmarshal(headerData, &header); err != nil {
+		return nil, errors.New("invalid JWT header")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("invalid JWT signature")
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	switch {
+	case header.Alg == "HS256" && len(a.options.JWTSecret) > 0:
+		mac := hmac.New(sha256.New, a.options.JWTSecret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return nil, errors.New("invalid JWT signature")
+		}
+	case header.Alg == "RS256" && a.options.JWTPublicKey != nil:
+		digest := sha256.Sum256(signed)
+		if err := rsa.VerifyPKCS1v15(a.options.JWTPublicKey, crypto.SHA256, digest[:], signature); err != nil {
+			return nil, errors.New("invalid JWT signature")
+		}
+	default:
+		return nil, fmt.Errorf("JWT algorithm %q is not accepted", header.Alg)
+	}
+
+	claimsData, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.New("invalid JWT claims")
+	}
+	var claims jwtClaims
+	if err := json.Unmarshal(claimsData, &claims); err != nil {
+		return nil, errors.New("invalid JWT claims")
+	}
+
+	if err := a.checkClaims(claims, time.Now()); err != nil {
+		return nil, err
+	}
+
+	principal := &Principal{Name: claims.Subject, JWT: true}
+	for _, scope := range strings.Fields(claims.Scope) {
+		principal.Scopes = append(principal.Scopes, Scope(scope))
+	}
+	return principal, nil
+}
+
+func (a *Authenticator) checkClaims(claims jwtClaims, now time.Time) error {
+	if claims.ExpiresAt == nil {
+		return errors.New("JWT has no expiry")
+	}
+	if now.After(time.Unix(*claims.ExpiresAt, 0).Add(jwtLeeway)) {
+		return errors.New("JWT expired")
+	}
+	if claims.NotBefore != nil && now.Add(jwtLeeway).Before(time.Unix(*claims.NotBefore, 0)) {
+		return errors.New("JWT is not valid yet")
+	}
+
+	if claims.Subject == "" {
+		return errors.New("JWT has no subject")
+	}
+
+	if a.options.JWTIssuer != "" && claims.Issuer != a.options.JWTIssuer {
+		return errors.New("JWT issuer is not accepted")
+	}
+
+	if a.options.JWTAudience != "" {
+		// The audience is either a single string or a list of them
+		var audiences []string
+		var audience string
+		if json.Unmarshal(claims.Audience, &audience) == nil {
+			audiences = []string{audience}
+		} else if json.Unmarshal(claims.Audience, &audiences) != nil {
+			return errors.New("invalid JWT audience")
+		}
+
+		accepted := false
+		for _, audience := range audiences {
+			accepted = accepted || audience == a.options.JWTAudience
+		}
+		if !accepted {
+			return errors.New("JWT audience is not accepted")
+		}
+	}
+
+	return nil
+}
+
+// ParseRSAPublicKey parses a PEM encoded PKIX or PKCS #1 RSA public key
+func ParseRSAPublicKey(data []byte) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("only RSA keys are supported")
+	}
+	return rsaKey, nil
+}
@@ -0,0 +1,42 @@
+package blockqueue
+
+import (
+	"context"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaConsumer reads the requests of a topic as member of a consumer group
+type KafkaConsumer struct {
+	reader *kafka.Reader
+}
+
+func NewKafkaConsumer(brokers string, topic string, groupID string) *KafkaConsumer {
+	return &KafkaConsumer{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: strings.Split(brokers, ","),
+			Topic:   topic,
+			GroupID: groupID,
+		}),
+	}
+}
+
+// Next fetches the next message, its offset is committed by the commit function of the message
+func (c *KafkaConsumer) Next(ctx context.Context) (Message, error) {
+	message, err := c.reader.FetchMessage(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Data: message.Value,
+		Commit: func(ctx context.Context) error {
+			return c.reader.CommitMessages(ctx, message)
+		},
+	}, nil
+}
+
+func (c *KafkaConsumer) Close() error {
+	return c.reader.Close()
+}
@@ -0,0 +1,56 @@
+package blockqueue
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSConsumer reads the requests of a subject as member of a queue group. Core NATS does not redeliver messages, so a
+// request is lost if the indexer stops before it was enqueued.
+type NATSConsumer struct {
+	conn         *nats.Conn
+	subscription *nats.Subscription
+}
+
+func NewNATSConsumer(url string, subject string, queueGroup string) (*NATSConsumer, error) {
+	conn, err := nats.Connect(url, nats.Name("cosmos-indexer"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := conn.QueueSubscribeSync(subject, queueGroup)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &NATSConsumer{conn: conn, subscription: subscription}, nil
+}
+
+// Next waits for the next message, replying to it on commit if the publisher asked for a reply
+func (c *NATSConsumer) Next(ctx context.Context) (Message, error) {
+	message, err := c.subscription.NextMsgWithContext(ctx)
+	if err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Data: message.Data,
+		Commit: func(ctx context.Context) error {
+			if message.Reply == "" {
+				return nil
+			}
+			return message.Respond(nil)
+		},
+	}, nil
+}
+
+func (c *NATSConsumer) Close() error {
+	if err := c.subscription.Unsubscribe(); err != nil {
+		c.conn.Close()
+		return err
+	}
+	c.conn.Close()
+	return nil
+}
@@ -0,0 +1,76 @@
+// Package blockqueue consumes requests to index blocks from message queues, so external systems such as a gap auditor
+// or an on-demand reindex service can drive the work queue of the indexer.
+package blockqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Request asks for the blocks from StartHeight to EndHeight, both inclusive, to be indexed. The index flags override
+// the configuration when they are set.
+type Request struct {
+	StartHeight       int64 `json:"start_height"`
+	EndHeight         int64 `json:"end_height"`
+	IndexBlockEvents  *bool `json:"index_block_events"`
+	IndexTransactions *bool `json:"index_transactions"`
+}
+
+// Message is a request read from a queue, Commit acknowledges it once its blocks were enqueued
+type Message struct {
+	Data   []byte
+	Commit func(ctx context.Context) error
+}
+
+// Consumer reads the messages of a queue
+type Consumer interface {
+	// Next blocks until a message arrives or the context is done
+	Next(ctx context.Context) (Message, error)
+	Close() error
+}
+
+// ParseRequest parses a message holding a height (100), a range (100-200) or a JSON request, where a single block may
+// be requested with {"height": 100} instead of a range
+func ParseRequest(data []byte) (Request, error) {
+	text := strings.TrimSpace(string(data))
+
+	var request Request
+	if strings.HasPrefix(text, "{") {
+		var fields struct {
+			Request
+			Height int64 `json:"height"`
+		}
+		if err := json.Unmarshal([]byte(text), &fields); err != nil {
+			return request, err
+		}
+		request = fields.Request
+		if fields.Height != 0 {
+			if request.StartHeight != 0 || request.EndHeight != 0 {
+				return request, errors.New("height cannot be combined with start_height and end_height")
+			}
+			request.StartHeight, request.EndHeight = fields.Height, fields.Height
+		}
+	} else {
+		start, end, isRange := strings.Cut(text, "-")
+		var err error
+		if request.StartHeight, err = strconv.ParseInt(strings.TrimSpace(start), 10, 64); err != nil {
+			return request, fmt.Errorf("invalid height %q", start)
+		}
+		request.EndHeight = request.StartHeight
+		if isRange {
+			if request.EndHeight, err = strconv.ParseInt(strings.TrimSpace(end), 10, 64); err != nil {
+				return request, fmt.Errorf("invalid end height %q", end)
+			}
+		}
+	}
+
+	if request.StartHeight < 1 || request.EndHeight < request.StartHeight {
+		return request, fmt.Errorf("invalid range %d-%d, heights start at 1 and the end must not be below the start", request.StartHeight, request.EndHeight)
+	}
+
+	return request, nil
+}
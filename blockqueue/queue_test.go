@@ -0,0 +1,43 @@
+package blockqueue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type QueueTestSuite struct {
+	suite.Suite
+}
+
+func (suite *QueueTestSuite) TestParseRequest() {
+	request, err := ParseRequest([]byte("100\n"))
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(100), request.StartHeight)
+	suite.Require().Equal(int64(100), request.EndHeight)
+
+	request, err = ParseRequest([]byte("100-200"))
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(200), request.EndHeight)
+
+	request, err = ParseRequest([]byte(`{"start_height": 100, "end_height": 110, "index_block_events": false}`))
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(110), request.EndHeight)
+	suite.Require().NotNil(request.IndexBlockEvents)
+	suite.Require().False(*request.IndexBlockEvents)
+	suite.Require().Nil(request.IndexTransactions)
+
+	request, err = ParseRequest([]byte(`{"height": 42}`))
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(42), request.StartHeight)
+	suite.Require().Equal(int64(42), request.EndHeight)
+
+	for _, invalid := range []string{"", "abc", "200-100", "0", `{"height": 1, "end_height": 2}`, `{"start_height": 5}`} {
+		_, err = ParseRequest([]byte(invalid))
+		suite.Require().Error(err, invalid)
+	}
+}
+
+func TestQueueTestSuite(t *testing.T) {
+	suite.Run(t, new(QueueTestSuite))
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/admin"
+	"github.com/DefiantLabs/cosmos-indexer/blockqueue"
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/core"
+	"github.com/DefiantLabs/cosmos-indexer/rpc"
+	"github.com/DefiantLabs/cosmos-indexer/schedule"
+)
+
+// startAdmin serves the admin API in the background and returns its server, which the caller shuts down
+func startAdmin(conf *config.IndexConfig, controller *admin.Controller) (*admin.Server, error) {
+	authenticator, err := setupAuth(conf.Admin.Auth())
+	if err != nil {
+		return nil, err
+	}
+
+	server, err := admin.NewServer(conf.Admin.Address, controller, authenticator)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		config.Log.Infof("Serving the admin API on %s", server.Addr())
+		if err := server.Serve(); err != nil {
+			config.Log.Error("Admin API server failed", err)
+		}
+	}()
+
+	return server, nil
+}
+
+// listenPauseSignals pauses indexing on pauseSignal and resumes it on resumeSignal until stop is closed, so that the
+// process and its caches survive e.g. a database maintenance window
+func (idxr *Indexer) listenPauseSignals(stop <-chan struct{}) {
+	if pauseSignal == nil {
+		return
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, pauseSignal, resumeSignal)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case received := <-signals:
+			if received == pauseSignal {
+				if idxr.adminController.Pause() {
+					config.Log.Warnf("Indexing paused by %s, the blocks in flight are finished and %s resumes", received, resumeSignal)
+				}
+			} else if idxr.adminController.Resume() {
+				config.Log.Warnf("Indexing resumed by %s", received)
+			}
+		}
+	}
+}
+
+// followSchedule holds the blocks outside of the indexing windows until stop is closed
+func (idxr *Indexer) followSchedule(window *schedule.Window, stop <-chan struct{}) {
+	for {
+		open, next := window.At(time.Now())
+		idxr.adminController.SetOutsideWindow(!open)
+
+		switch {
+		case open:
+			config.Log.Infof("Indexing window open until %s", next.Format(time.RFC3339))
+		case next.IsZero():
+			config.Log.Warn("The indexing schedule does not open another window, indexing stays on hold")
+			return
+		default:
+			config.Log.Infof("Outside of the indexing schedule, the next window opens at %s", next.Format(time.RFC3339))
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// relayBlocks forwards the enqueued blocks to the RPC workers, together with the blocks requested through the admin
+// API. Blocks wait while indexing is paused or outside of the schedule, and skipped blocks are dropped. The workers
+// are closed once the enqueue function is done or ctx is done, requests still pending then are dropped.
+func (idxr *Indexer) relayBlocks(ctx context.Context, enqueued <-chan *core.EnqueueData) {
+	defer idxr.rpcWorkers.close()
+
+	for ctx.Err() == nil {
+		select {
+		case <-ctx.Done():
+		case block, ok := <-enqueued:
+			if !ok {
+				return
+			}
+			idxr.relayBlock(ctx, block)
+		case request := <-idxr.adminController.Requests():
+			idxr.relayRequest(ctx, request)
+		case <-idxr.rpcWorkers.restart:
+			idxr.rpcWorkers.replace()
+			idxr.rpcWorkers.drain(ctx)
+		}
+	}
+}
+
+func (idxr *Indexer) relayBlock(ctx context.Context, block *core.EnqueueData) {
+	resumed := idxr.adminController.Resumed()
+	select {
+	case <-resumed:
+	default:
+		config.Log.Infof("Indexing is paused or outside of the schedule, block %d waits", block.Height)
+		select {
+		case <-resumed:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	if idxr.adminController.Skipped(block.Height) {
+		config.Log.Warnf("Skipping block %d as requested through the admin API", block.Height)
+		return
+	}
+
+	idxr.heightStates.queued(block.Height)
+	idxr.rpcWorkers.send(ctx, block)
+}
+
+// relayRequest enqueues the blocks of an admin request, heights outside of the range the node serves are skipped
+func (idxr *Indexer) relayRequest(ctx context.Context, request blockqueue.Request) {
+	earliestBlock, latestBlock, err := rpc.GetEarliestAndLatestBlockHeights(idxr.rpcClient)
+	if err != nil {
+		config.Log.Errorf("Dropping the admin request for blocks %d-%d, the blockchain heights could not be queried: %v", request.StartHeight, request.EndHeight, err)
+		return
+	}
+
+	start, end := request.StartHeight, request.EndHeight
+	if start < earliestBlock {
+		start = earliestBlock
+	}
+	if end > latestBlock {
+		end = latestBlock
+	}
+	if start != request.StartHeight || end != request.EndHeight {
+		config.Log.Warnf("Blocks of the admin request %d-%d past the blockchain earliest height (%d) and latest height (%d) will be skipped", request.StartHeight, request.EndHeight, earliestBlock, latestBlock)
+	}
+
+	enqueueData := core.RequestEnqueueData(*idxr.cfg, request)
+	for height := start; height <= end && ctx.Err() == nil; height++ {
+		blockData := enqueueData
+		blockData.Height = height
+		idxr.relayBlock(ctx, &blockData)
+	}
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
+	"github.com/DefiantLabs/cosmos-indexer/rpc"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// Stages of the pipeline timed by the bench command, in the order they are reported
+const (
+	stageRPCStatus           = "rpc.status"
+	stageRPCBlock            = "rpc.block"
+	stageRPCBlockResults     = "rpc.block_results"
+	stageRPCTxs              = "rpc.txs"
+	stageRPCValidators       = "rpc.validators"
+	stageRPCModuleVersions   = "rpc.module_versions"
+	stageParseBlockEvents    = "parse.block_events"
+	stageParseTxs            = "parse.txs"
+	stageDBTxs               = "db.txs"
+	stageDBCustomMessages    = "db.custom_messages"
+	stageDBBlockEvents       = "db.block_events"
+	stageDBCustomBlockEvents = "db.custom_block_events"
+)
+
+var benchStages = []string{
+	stageRPCStatus, stageRPCBlock, stageRPCBlockResults, stageRPCTxs, stageRPCValidators, stageRPCModuleVersions,
+	stageParseBlockEvents, stageParseTxs,
+	stageDBTxs, stageDBCustomMessages, stageDBBlockEvents, stageDBCustomBlockEvents,
+}
+
+var (
+	benchConf   config.BenchConfig
+	benchSchema string
+)
+
+func init() {
+	config.SetupLogFlags(&indexer.cfg.Log, benchCmd)
+	config.SetupDatabaseFlags(&indexer.cfg.Database, benchCmd)
+	config.SetupProbeFlags(&indexer.cfg.Probe, benchCmd)
+	config.SetupThrottlingFlag(&indexer.cfg.Base.Throttling, benchCmd)
+	config.SetupIndexSpecificFlags(indexer.cfg, benchCmd)
+	config.SetupBenchFlags(&benchConf, benchCmd)
+
+	rootCmd.AddCommand(benchCmd)
+}
+
+var benchCmd = &cobra.Command{
+	Use:   "bench",
+	Short: "Indexes a sample of blocks into a throwaway schema and reports the time spent per stage.",
+	Long: `Indexes bench.blocks blocks starting at base.start-block with the index configuration into a new schema,
+	which is dropped afterwards, and reports the time spent in RPC requests, parsing and database writes per stage.
+	Sinks, block queues, CDC notifications, the admin API, the metrics server and the indexing schedule are disabled,
+	so runs can be compared across hardware and configuration changes.`,
+	PreRunE: setupBench,
+	Run:     bench,
+}
+
+func setupBench(cmd *cobra.Command, args []string) error {
+	bindFlags(cmd, viperConf)
+
+	err := benchConf.Validate()
+	if err != nil {
+		return err
+	}
+	if indexer.cfg.Base.StartBlock < 1 {
+		return errors.New("base.start-block must be set to the first block of the sample")
+	}
+
+	// The schema is always new, so the drop at the end never removes indexed data
+	benchSchema = fmt.Sprintf("bench_%d", time.Now().Unix())
+	overrides := map[string]string{
+		"database.schema":           benchSchema,
+		"database.schema-per-chain": "false",
+		"base.end-block":            strconv.FormatInt(indexer.cfg.Base.StartBlock+benchConf.Blocks-1, 10),
+		"base.block-input-file":     "",
+		"base.reindex-message-type": "",
+		"base.dry":                  "false",
+		"admin.enabled":             "false",
+		"metrics.enabled":           "false",
+		"cdc.notify":                "false",
+		"schedule.cron":             "",
+	}
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if strings.HasSuffix(f.Name, ".enabled") && (strings.HasPrefix(f.Name, "sinks.") || strings.HasPrefix(f.Name, "queue.")) {
+			overrides[f.Name] = "false"
+		}
+	})
+	// Flags set here are not overridden with the config file values
+	for name, value := range overrides {
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("failed to override %s: %w", name, err)
+		}
+	}
+
+	indexer.timings = newStageTimings()
+	return setupIndex(cmd, args)
+}
+
+func bench(cmd *cobra.Command, args []string) {
+	start := time.Now()
+	index(cmd, args)
+	indexer.timings.report(os.Stdout, benchConf.Blocks, time.Since(start))
+
+	if benchConf.KeepSchema {
+		config.Log.Infof("Kept the benchmark schema %s", benchSchema)
+		return
+	}
+	if err := dropBenchSchema(indexer.cfg.Database); err != nil {
+		config.Log.Error(fmt.Sprintf("Failed to drop the benchmark schema %s", benchSchema), err)
+	}
+}
+
+// dropBenchSchema drops the benchmark schema with its tables, the connection of the index run is closed by then
+func dropBenchSchema(dbConfig config.Database) error {
+	database, err := dbTypes.PostgresDbConnect(dbConfig.Host, dbConfig.Port, dbConfig.Database, dbConfig.User, dbConfig.Password, strings.ToLower(dbConfig.LogLevel), dbTypes.ConnectOptions{})
+	if err != nil {
+		return err
+	}
+	sqlDB, err := database.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	return database.Exec(fmt.Sprintf("DROP SCHEMA %q CASCADE", benchSchema)).Error
+}
+
+// stageTimings collects the durations of the pipeline stages while benchmarking, it is nil otherwise. The durations are
+// exposed as metrics either way.
+type stageTimings struct {
+	mu        sync.Mutex
+	durations map[string][]time.Duration
+}
+
+func newStageTimings() *stageTimings {
+	return &stageTimings{durations: make(map[string][]time.Duration)}
+}
+
+// observe records the time since start for stage
+func (t *stageTimings) observe(stage string, start time.Time) {
+	duration := time.Since(start)
+	metrics.ObserveStage(stage, duration)
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.durations[stage] = append(t.durations[stage], duration)
+}
+
+// report writes the timings per stage. Stages run concurrently, e.g. the RPC workers, so their totals can add up to
+// more than the elapsed time.
+func (t *stageTimings) report(w io.Writer, blocks int64, elapsed time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fmt.Fprintf(w, "Indexed %d blocks in %s (%.2f blocks/s)\n\n", blocks, elapsed.Round(time.Millisecond), float64(blocks)/elapsed.Seconds())
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STAGE\tCALLS\tTOTAL\tMEAN\tP50\tP95\tMAX")
+	for _, stage := range benchStages {
+		durations := t.durations[stage]
+		if len(durations) == 0 {
+			continue
+		}
+
+		sorted := append([]time.Duration{}, durations...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		var total time.Duration
+		for _, duration := range sorted {
+			total += duration
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n", stage, len(sorted), roundDuration(total), roundDuration(total/time.Duration(len(sorted))),
+			roundDuration(sorted[(len(sorted)-1)/2]), roundDuration(sorted[(len(sorted)-1)*95/100]), roundDuration(sorted[len(sorted)-1]))
+	}
+	tw.Flush()
+}
+
+func roundDuration(duration time.Duration) time.Duration {
+	return duration.Round(10 * time.Microsecond)
+}
+
+// timedClient times the requests of the RPC workers and block enqueue functions
+type timedClient struct {
+	rpc.Client
+	timings *stageTimings
+}
+
+func (c *timedClient) Status(ctx context.Context) (*coretypes.ResultStatus, error) {
+	defer c.timings.observe(stageRPCStatus, time.Now())
+	return c.Client.Status(ctx)
+}
+
+func (c *timedClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	defer c.timings.observe(stageRPCBlock, time.Now())
+	return c.Client.Block(ctx, height)
+}
+
+func (c *timedClient) BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error) {
+	defer c.timings.observe(stageRPCBlockResults, time.Now())
+	return c.Client.BlockResults(ctx, height)
+}
+
+func (c *timedClient) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	defer c.timings.observe(stageRPCTxs, time.Now())
+	return c.Client.TxsEvent(ctx, height, req)
+}
+
+func (c *timedClient) Validators(ctx context.Context, height int64) ([]*cmttypes.Validator, error) {
+	defer c.timings.observe(stageRPCValidators, time.Now())
+	return rpc.GetValidators(ctx, c.Client, height)
+}
+
+func (c *timedClient) ModuleVersions(ctx context.Context, height int64) (rpc.ModuleVersions, error) {
+	defer c.timings.observe(stageRPCModuleVersions, time.Now())
+	return rpc.GetModuleVersions(ctx, c.Client, height)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/rpc"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/stretchr/testify/suite"
+)
+
+// blockClient serves the status and the blocks of a node, the other requests are not expected
+type blockClient struct {
+	rpc.Client
+}
+
+func (c *blockClient) Status(ctx context.Context) (*coretypes.ResultStatus, error) {
+	return &coretypes.ResultStatus{}, nil
+}
+
+func (c *blockClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	return &coretypes.ResultBlock{}, nil
+}
+
+type BenchTestSuite struct {
+	suite.Suite
+}
+
+func (suite *BenchTestSuite) TestTimedClient() {
+	timings := newStageTimings()
+	cl := &timedClient{Client: &blockClient{}, timings: timings}
+
+	_, err := cl.Status(context.Background())
+	suite.Require().NoError(err)
+	for height := int64(1); height <= 3; height++ {
+		_, err = cl.Block(context.Background(), height)
+		suite.Require().NoError(err)
+	}
+	suite.Require().Len(timings.durations[stageRPCStatus], 1)
+	suite.Require().Len(timings.durations[stageRPCBlock], 3)
+	suite.Require().Empty(timings.durations[stageRPCBlockResults])
+
+	// Outside of the bench command the durations are only exposed as metrics
+	cl.timings = nil
+	_, err = cl.Status(context.Background())
+	suite.Require().NoError(err)
+}
+
+func (suite *BenchTestSuite) TestReport() {
+	timings := newStageTimings()
+	timings.durations[stageDBTxs] = []time.Duration{4 * time.Millisecond}
+	for i := 20; i >= 1; i-- {
+		timings.durations[stageRPCBlock] = append(timings.durations[stageRPCBlock], time.Duration(i)*time.Millisecond)
+	}
+
+	var report strings.Builder
+	timings.report(&report, 10, 2*time.Second)
+
+	// The stages are reported in pipeline order and the ones without calls are left out
+	lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+	suite.Require().Equal([]string{
+		"Indexed 10 blocks in 2s (5.00 blocks/s)",
+		"",
+		"STAGE      CALLS  TOTAL  MEAN    P50   P95   MAX",
+		"rpc.block  20     210ms  10.5ms  10ms  19ms  20ms",
+		"db.txs     1      4ms    4ms     4ms   4ms   4ms",
+	}, lines)
+}
+
+func TestBenchTestSuite(t *testing.T) {
+	suite.Run(t, new(BenchTestSuite))
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
+)
+
+// trackChainHead records the head of the chain and the highest indexed height in the chain_heads table every interval
+// until stop is closed. A node that can't be reached is logged and asked again on the next tick. A chain whose head
+// stops advancing is reported as halted once, and again once it advances, so operators don't mistake it for lag.
+func (idxr *Indexer) trackChainHead(chainID uint, interval time.Duration, haltAfter time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	halted := false
+	for {
+		if head, ok := idxr.recordChainHead(chainID, interval, haltAfter); ok {
+			switch {
+			case head.Halted && !halted:
+				config.Log.Errorf("The chain head of %s has not advanced past height %d since %s, the chain appears halted. The indexer is %d blocks behind the head.",
+					idxr.cfg.Probe.ChainID, head.HeadHeight, head.HeadAdvancedAt.Format(time.RFC3339), head.LagBlocks)
+			case !head.Halted && halted:
+				config.Log.Infof("The chain head of %s advanced to height %d, the chain is no longer halted", idxr.cfg.Probe.ChainID, head.HeadHeight)
+			}
+			halted = head.Halted
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (idxr *Indexer) recordChainHead(chainID uint, timeout time.Duration, haltAfter time.Duration) (models.ChainHead, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	status, err := idxr.rpcClient.Status(ctx)
+	if err != nil {
+		config.Log.Error("Failed to query the chain head", err)
+		return models.ChainHead{}, false
+	}
+
+	head, err := dbTypes.RecordChainHead(idxr.db, chainID, status.SyncInfo.LatestBlockHeight, status.SyncInfo.LatestBlockTime, haltAfter)
+	if err != nil {
+		config.Log.Error("Failed to record the chain head", err)
+		return models.ChainHead{}, false
+	}
+
+	metrics.ObserveChainHead(idxr.cfg.Probe.ChainID, head.HeadHeight, head.IndexedHeight, head.LagBlocks, head.LagSeconds, head.Halted)
+	return head, true
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// handleShutdownSignals stops enqueueing blocks on the first interrupt, so the blocks in flight are indexed before the
+// pipeline stops. A second interrupt or the timeout passing cancels the indexing, which drops the blocks still in
+// flight, a timeout of 0 cancels it right away.
+func handleShutdownSignals(stopEnqueue context.CancelFunc, cancel context.CancelFunc, timeout time.Duration, done <-chan struct{}) {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	select {
+	case <-signals:
+	case <-done:
+		return
+	}
+
+	stopEnqueue()
+	if timeout == 0 {
+		config.Log.Info("Indexing interrupted, stopping the pipeline")
+		cancel()
+		return
+	}
+	config.Log.Infof("Indexing interrupted, indexing the blocks in flight for up to %s before stopping. Interrupt again to stop right away.", timeout)
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-signals:
+		config.Log.Info("Indexing interrupted again, stopping the pipeline")
+	case <-timer.C:
+		config.Log.Warnf("The blocks in flight were not indexed within %s, stopping the pipeline", timeout)
+	case <-done:
+		return
+	}
+	cancel()
+}
+
+// indexCheckpoint saves the height up to which every height of the indexed range was indexed or recorded as failed.
+// It is nil for runs that do not index a range from its start, e.g. reindexing or indexing the blocks of a file.
+type indexCheckpoint struct {
+	chainID uint
+	start   int64
+	mu      sync.Mutex
+}
+
+// resumeFromCheckpoint moves the start block past the checkpoint of the chain when the start block falls in the range of
+// the checkpoint, so the run does not go through the heights indexed before. A start block outside of the range starts
+// a new range, which replaces the checkpoint once it is saved.
+func (idxr *Indexer) resumeFromCheckpoint(chainID uint) *indexCheckpoint {
+	checkpoint, err := dbTypes.GetIndexerCheckpoint(idxr.db, chainID)
+	if err != nil {
+		config.Log.Fatal("Failed to read the indexing checkpoint", err)
+	}
+
+	start := idxr.cfg.Base.StartBlock
+	if start <= 0 {
+		start = 1
+	}
+	if checkpoint == nil || start < checkpoint.StartHeight || start > checkpoint.Height+1 {
+		return &indexCheckpoint{chainID: chainID, start: start}
+	}
+
+	if checkpoint.Height >= start {
+		config.Log.Infof("Resuming from the checkpoint at height %d, the heights %d to %d were indexed", checkpoint.Height+1, checkpoint.StartHeight, checkpoint.Height)
+		idxr.cfg.Base.StartBlock = checkpoint.Height + 1
+	}
+	return &indexCheckpoint{chainID: chainID, start: checkpoint.StartHeight}
+}
+
+// saveEvery saves the checkpoint periodically until stop is closed
+func (c *indexCheckpoint) saveEvery(db *gorm.DB, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.save(db)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// save advances the checkpoint to the highest contiguous height indexed. It continues from the stored checkpoint, which
+// a reorg rollback moves back, rather than from the height saved last.
+func (c *indexCheckpoint) save(db *gorm.DB) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stored, err := dbTypes.GetIndexerCheckpoint(db, c.chainID)
+	if err != nil {
+		config.Log.Error("Failed to read the indexing checkpoint", err)
+		return
+	}
+	from := c.start
+	if stored != nil && stored.StartHeight == c.start {
+		from = stored.Height + 1
+	}
+
+	height, err := dbTypes.ContiguousIndexedHeight(db, c.chainID, from)
+	if err != nil {
+		config.Log.Error("Failed to compute the indexing checkpoint", err)
+		return
+	}
+	if stored != nil && stored.StartHeight == c.start && stored.Height == height {
+		return
+	}
+
+	err = dbTypes.UpsertIndexerCheckpoint(db, models.IndexerCheckpoint{BlockchainID: c.chainID, StartHeight: c.start, Height: height})
+	if err != nil {
+		config.Log.Error("Failed to save the indexing checkpoint", err)
+		return
+	}
+	config.Log.Debugf("Saved the indexing checkpoint at height %d", height)
+}
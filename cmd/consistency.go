@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/probe"
+	"github.com/DefiantLabs/cosmos-indexer/rpc"
+	"github.com/cosmos/cosmos-sdk/types/module"
+)
+
+// setupConsistency compares the responses of the client with the node at consistency.rpc, queried through a probe
+// client of its own
+func setupConsistency(conf *config.IndexConfig, client rpc.Client, moduleBasics []module.AppModuleBasic) *rpc.ConsistencyClient {
+	probeConf := conf.Probe
+	probeConf.RPC = conf.Consistency.RPC
+
+	config.Log.Infof("Comparing the fetched blocks with %s", conf.Consistency.RPC)
+	return rpc.NewConsistencyClient(client, rpc.NewChainClient(probe.GetProbeClient(probeConf, moduleBasics)), rpc.ConsistencyOptions{
+		Name: conf.Consistency.RPC,
+		Warn: conf.Consistency.Warn,
+	})
+}
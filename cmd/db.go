@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/filter"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+type DBMaintainer struct {
+	cfg *config.DBConfig
+	db  *gorm.DB
+}
+
+var dbMaintainer DBMaintainer
+
+func init() {
+	dbMaintainer.cfg = &config.DBConfig{}
+	config.SetupLogFlags(&dbMaintainer.cfg.Log, dbCmd)
+	config.SetupDatabaseFlags(&dbMaintainer.cfg.Database, dbCmd)
+	config.SetupDatabaseChainFlag(&dbMaintainer.cfg.Database, dbCmd)
+	config.SetupTuneFlags(dbMaintainer.cfg, dbTuneCmd)
+	config.SetupDeleteFlags(dbMaintainer.cfg, dbDeleteCmd)
+
+	dbCmd.AddCommand(dbTuneCmd)
+	dbCmd.AddCommand(dbDeleteCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Maintains the database of the indexer.",
+}
+
+var dbTuneCmd = &cobra.Command{
+	Use:   "tune",
+	Short: "Recommends or creates indexes for the configured filters and the serve datasets.",
+	Long: `Analyzes the filters of the filter file and the joins of the datasets exposed by the serve command,
+	and prints the Postgres indexes that are missing for them, such as partial indexes on the filtered message types,
+	block event types and event attribute keys. With --tune.apply the indexes are created concurrently, which does
+	not block indexing.`,
+	PreRunE: setupDBTune,
+	Run:     dbTune,
+}
+
+var dbDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Redacts an address from every table of the database.",
+	Long: `Reports the rows of every table that contain the addresses given with --delete.address, for compliance
+	requests such as the erasure of an account's data. With --delete.apply the address is replaced in text and JSON
+	values, binary values containing it are cleared and the address row is renamed, in a single transaction.`,
+	PreRunE: setupDBDelete,
+	Run:     dbDelete,
+}
+
+func setupDBTune(cmd *cobra.Command, args []string) error {
+	return setupDBMaintainer(cmd, dbMaintainer.cfg.ValidateTune)
+}
+
+func setupDBDelete(cmd *cobra.Command, args []string) error {
+	return setupDBMaintainer(cmd, dbMaintainer.cfg.ValidateDelete)
+}
+
+// setupDBMaintainer validates the config shared by the db commands and the config of the command, and connects
+func setupDBMaintainer(cmd *cobra.Command, validateCommand func() error) error {
+	bindFlags(cmd, viperConf)
+
+	err := dbMaintainer.cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = validateCommand()
+	if err != nil {
+		return err
+	}
+
+	ignoredKeys := config.CheckSuperfluousDBKeys(viperConf.AllKeys())
+
+	if len(ignoredKeys) > 0 {
+		config.Log.Warnf("Warning, the following invalid keys will be ignored: %v", ignoredKeys)
+	}
+
+	setupLogger(dbMaintainer.cfg.Log.Level, dbMaintainer.cfg.Log.Path, dbMaintainer.cfg.Log.Pretty)
+
+	db, err := connectToDBAndMigrate(dbMaintainer.cfg.Database, dbMaintainer.cfg.Database.ChainID)
+	if err != nil {
+		config.Log.Fatal("Could not establish connection to the database", err)
+	}
+
+	dbMaintainer.db = db
+
+	return nil
+}
+
+func dbTune(cmd *cobra.Command, args []string) {
+	dbConn, err := dbMaintainer.db.DB()
+	if err != nil {
+		config.Log.Fatal("Failed to connect to DB", err)
+	}
+	defer dbConn.Close()
+
+	var targets dbTypes.TuneTargets
+	if dbMaintainer.cfg.Base.FilterFile != "" {
+		targets, err = filterTuneTargets(dbMaintainer.cfg.Base.FilterFile)
+		if err != nil {
+			config.Log.Fatal("Failed to parse the filter file", err)
+		}
+	}
+
+	recommendations, err := dbTypes.RecommendIndexes(dbMaintainer.db, targets)
+	if err != nil {
+		config.Log.Fatal("Failed to analyze the database indexes", err)
+	}
+
+	if len(recommendations) == 0 {
+		config.Log.Info("No missing indexes found")
+		return
+	}
+
+	for _, recommendation := range recommendations {
+		if !dbMaintainer.cfg.Tune.Apply {
+			fmt.Printf("-- %s\n%s;\n", recommendation.Reason, recommendation.Statement)
+			continue
+		}
+
+		config.Log.Infof("Creating index %s on %s for %s", recommendation.Name, recommendation.Table, recommendation.Reason)
+		if err := dbTypes.ApplyIndexRecommendation(dbMaintainer.db, recommendation); err != nil {
+			config.Log.Fatal(fmt.Sprintf("Failed to create index %s", recommendation.Name), err)
+		}
+	}
+}
+
+func dbDelete(cmd *cobra.Command, args []string) {
+	dbConn, err := dbMaintainer.db.DB()
+	if err != nil {
+		config.Log.Fatal("Failed to connect to DB", err)
+	}
+	defer dbConn.Close()
+
+	for _, address := range dbMaintainer.cfg.Delete.Addresses {
+		redactions, err := dbTypes.RedactAddress(dbMaintainer.db, address, dbMaintainer.cfg.Delete.Apply)
+		if err != nil {
+			config.Log.Fatal(fmt.Sprintf("Failed to redact %s", address), err)
+		}
+
+		if len(redactions) == 0 {
+			config.Log.Infof("No rows contain %s", address)
+			continue
+		}
+
+		for _, redaction := range redactions {
+			if dbMaintainer.cfg.Delete.Apply {
+				config.Log.Infof("Redacted %s from %d rows of %s.%s", address, redaction.Rows, redaction.Table, redaction.Column)
+				continue
+			}
+			fmt.Printf("%s\t%s.%s\t%d rows\n", address, redaction.Table, redaction.Column, redaction.Rows)
+		}
+	}
+}
+
+// filterTuneTargets collects the exact values selected by the filters, regex filters are skipped since no index
+// matches them
+func filterTuneTargets(path string) (dbTypes.TuneTargets, error) {
+	var targets dbTypes.TuneTargets
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return targets, err
+	}
+
+	beginBlockFilters, beginBlockRollingWindowFilters, endBlockFilters, endBlockRollingWindowFilters, messageTypeFilters, err := config.ParseJSONFilterConfig(data)
+	if err != nil {
+		return targets, err
+	}
+
+	blockEventFilters := append(beginBlockFilters, endBlockFilters...)
+	for _, rollingWindowFilter := range append(beginBlockRollingWindowFilters, endBlockRollingWindowFilters...) {
+		if window, ok := rollingWindowFilter.(*filter.DefaultRollingWindowBlockEventFilter); ok {
+			blockEventFilters = append(blockEventFilters, window.EventPatterns...)
+		}
+	}
+
+	for _, blockEventFilter := range blockEventFilters {
+		switch f := blockEventFilter.(type) {
+		case filter.DefaultBlockEventTypeFilter:
+			targets.BlockEventTypes = append(targets.BlockEventTypes, f.EventType)
+		case filter.DefaultBlockEventTypeAndAttributeValueFilter:
+			targets.BlockEventTypes = append(targets.BlockEventTypes, f.EventType)
+			targets.BlockEventAttributes = append(targets.BlockEventAttributes, dbTypes.BlockEventAttributeTarget{EventType: f.EventType, AttributeKey: f.AttributeKey})
+		}
+	}
+
+	for _, messageTypeFilter := range messageTypeFilters {
+		if f, ok := messageTypeFilter.(filter.DefaultMessageTypeFilter); ok {
+			targets.MessageTypes = append(targets.MessageTypes, f.MessageType)
+		}
+	}
+
+	return targets, nil
+}
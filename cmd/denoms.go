@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/rpc"
+	probeClient "github.com/DefiantLabs/probe/client"
+	"gorm.io/gorm"
+)
+
+// setupDenomDisplays stores the display units of the denoms and creates the display views. The units of the bank
+// module are read first, so the units of the metadata file take precedence. A node that can't be queried for them,
+// e.g. when replaying fixtures, does not stop indexing.
+func setupDenomDisplays(conf *config.IndexConfig, db *gorm.DB, cl *probeClient.ChainClient) error {
+	if !conf.Denoms.DisplayViews {
+		return nil
+	}
+
+	var displays []config.DenomDisplay
+	if conf.Denoms.BankMetadata {
+		bankDisplays, err := rpc.BankDenomDisplays(context.Background(), cl)
+		if err != nil {
+			config.Log.Warnf("Failed to query the denom metadata of the bank module, display units are only read from denoms.metadata-file: %v", err)
+		}
+		displays = append(displays, bankDisplays...)
+	}
+
+	if conf.Denoms.MetadataFile != "" {
+		fileDisplays, err := config.LoadDenomDisplays(conf.Denoms.MetadataFile)
+		if err != nil {
+			return err
+		}
+		displays = append(displays, fileDisplays...)
+	}
+
+	if err := dbTypes.UpsertDenomDisplays(db, displays); err != nil {
+		return err
+	}
+	config.Log.Infof("Stored the display units of %d denoms", len(displays))
+
+	return dbTypes.CreateDisplayViews(db)
+}
+
+// setupDenomTraces stores the origins of the IBC voucher denoms of the chain, which the value command normalizes the
+// fees with. Vouchers first received after startup are traced on the next start. A node that can't be queried for
+// them does not stop indexing.
+func setupDenomTraces(conf *config.IndexConfig, db *gorm.DB, cl *probeClient.ChainClient) error {
+	if !conf.Denoms.IBCTraces {
+		return nil
+	}
+
+	traces, err := rpc.IBCDenomTraces(context.Background(), cl)
+	if err != nil {
+		config.Log.Warnf("Failed to query the denom traces of the transfer module, IBC denoms are not normalized: %v", err)
+		return nil
+	}
+
+	if err := dbTypes.UpsertDenomTraces(db, traces); err != nil {
+		return err
+	}
+	config.Log.Infof("Stored the traces of %d IBC denoms", len(traces))
+
+	return nil
+}
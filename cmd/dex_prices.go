@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/prices"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+type DexPriceDeriver struct {
+	cfg *config.DexPricesConfig
+	db  *gorm.DB
+}
+
+var dexPriceDeriver DexPriceDeriver
+
+func init() {
+	dexPriceDeriver.cfg = &config.DexPricesConfig{}
+	config.SetupLogFlags(&dexPriceDeriver.cfg.Log, dexPricesCmd)
+	config.SetupDatabaseFlags(&dexPriceDeriver.cfg.Database, dexPricesCmd)
+	config.SetupDexPricesFlags(dexPriceDeriver.cfg, dexPricesCmd)
+
+	rootCmd.AddCommand(dexPricesCmd)
+}
+
+var dexPricesCmd = &cobra.Command{
+	Use:   "dex-prices",
+	Short: "Derives historical prices from the indexed DEX swaps.",
+	Long: `Derives the rate of every swap in the indexed blocks of a chain from the token_swapped events of Osmosis
+	and the swap events of CosmWasm pair contracts, and stores them in the dex_prices table. The value command prices
+	denoms no external API covers from them with a dex price source. Blocks are derived once, run it again to derive
+	newly indexed blocks; the messages and their events must be indexed.`,
+	PreRunE: setupDexPrices,
+	Run:     dexPrices,
+}
+
+func setupDexPrices(cmd *cobra.Command, args []string) error {
+	bindFlags(cmd, viperConf)
+
+	err := dexPriceDeriver.cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	ignoredKeys := config.CheckSuperfluousDexPricesKeys(viperConf.AllKeys())
+
+	if len(ignoredKeys) > 0 {
+		config.Log.Warnf("Warning, the following invalid keys will be ignored: %v", ignoredKeys)
+	}
+
+	setupLogger(dexPriceDeriver.cfg.Log.Level, dexPriceDeriver.cfg.Log.Path, dexPriceDeriver.cfg.Log.Pretty)
+
+	db, err := connectToDBAndMigrate(dexPriceDeriver.cfg.Database, dexPriceDeriver.cfg.Dex.ChainID)
+	if err != nil {
+		config.Log.Fatal("Could not establish connection to the database", err)
+	}
+
+	dexPriceDeriver.db = db
+
+	return nil
+}
+
+func dexPrices(cmd *cobra.Command, args []string) {
+	dbConn, err := dexPriceDeriver.db.DB()
+	if err != nil {
+		config.Log.Fatal("Failed to connect to DB", err)
+	}
+	defer dbConn.Close()
+
+	var chain models.Chain
+	err = dexPriceDeriver.db.Where("chain_id = ?", dexPriceDeriver.cfg.Dex.ChainID).First(&chain).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config.Log.Fatalf("Chain %s is not indexed", dexPriceDeriver.cfg.Dex.ChainID)
+	}
+	if err != nil {
+		config.Log.Fatal("Failed to look up the chain", err)
+	}
+
+	interrupted := make(chan os.Signal, 1)
+	signal.Notify(interrupted, os.Interrupt, syscall.SIGTERM)
+
+	var blocks, swaps int
+	fromHeight := dexPriceDeriver.cfg.Dex.StartHeight
+	for {
+		batch, err := dbTypes.UnderivedBlocks(dexPriceDeriver.db, chain.ID, fromHeight, dexPriceDeriver.cfg.Dex.EndHeight, dexPriceDeriver.cfg.Dex.Rederive, valueBatchSize)
+		if err != nil {
+			config.Log.Fatal("Failed to load the blocks to derive swap prices of", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for i := range batch {
+			select {
+			case <-interrupted:
+				config.Log.Infof("Deriving interrupted after %d blocks", blocks)
+				return
+			default:
+			}
+
+			derived, err := prices.DeriveDexPrices(dexPriceDeriver.db, &batch[i])
+			if err != nil {
+				config.Log.Fatal(fmt.Sprintf("Failed to derive the swap prices of block %d", batch[i].Height), err)
+			}
+			blocks++
+			swaps += derived
+		}
+
+		fromHeight = batch[len(batch)-1].Height + 1
+		config.Log.Infof("Derived swap prices of blocks up to height %d", batch[len(batch)-1].Height)
+	}
+
+	config.Log.Infof("Derived %d swap prices of %d blocks of %s", swaps, blocks, dexPriceDeriver.cfg.Dex.ChainID)
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/probe"
+	"github.com/DefiantLabs/cosmos-indexer/rpc"
+	"github.com/cosmos/cosmos-sdk/types/module"
+)
+
+// setupEndpointPool spreads the requests of the probe client over the additional RPC endpoints, each queried through
+// its own probe client. probe.rpc serves any request unless the endpoints file declares its role and weight.
+func setupEndpointPool(conf *config.IndexConfig, probeRPC rpc.Client, moduleBasics []module.AppModuleBasic) (*rpc.EndpointPool, error) {
+	declared := make([]config.RPCEndpoint, 0, len(conf.Endpoints.RPC))
+	for _, address := range conf.Endpoints.RPC {
+		declared = append(declared, config.RPCEndpoint{RPC: address})
+	}
+	if conf.Endpoints.File != "" {
+		fileEndpoints, err := config.LoadRPCEndpoints(conf.Endpoints.File)
+		if err != nil {
+			return nil, err
+		}
+		declared = append(declared, fileEndpoints...)
+	}
+
+	endpoints := []rpc.Endpoint{{Name: conf.Probe.RPC, Client: probeRPC}}
+	for _, endpoint := range declared {
+		if endpoint.RPC == conf.Probe.RPC {
+			endpoints[0].Role, endpoints[0].Weight = rpc.EndpointRole(endpoint.Role), endpoint.Weight
+			endpoints[0].StartHeight, endpoints[0].EndHeight = endpoint.StartHeight, endpoint.EndHeight
+			continue
+		}
+
+		probeConf := conf.Probe
+		probeConf.RPC = endpoint.RPC
+		endpoints = append(endpoints, rpc.Endpoint{
+			Name:        endpoint.RPC,
+			Client:      rpc.NewChainClient(probe.GetProbeClient(probeConf, moduleBasics)),
+			Role:        rpc.EndpointRole(endpoint.Role),
+			Weight:      endpoint.Weight,
+			StartHeight: endpoint.StartHeight,
+			EndHeight:   endpoint.EndHeight,
+		})
+	}
+
+	config.Log.Infof("Spreading the RPC requests over %d endpoints", len(endpoints))
+	return rpc.NewEndpointPool(endpoints, rpc.HealthOptions{
+		Window:                 conf.Endpoints.Window,
+		MaxErrorRate:           conf.Endpoints.MaxErrorRate,
+		MaxLatency:             conf.Endpoints.MaxLatency,
+		MaxConsecutiveFailures: conf.Endpoints.MaxConsecutiveFailures,
+		Blacklist:              conf.Endpoints.Blacklist,
+		Failover:               conf.Endpoints.Failover,
+		HeadWindow:             conf.Endpoints.HeadWindow,
+	}), nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/probe"
+	"github.com/DefiantLabs/cosmos-indexer/rpc"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/types/module"
+)
+
+// setupEras sends the requests for the heights of the eras in the eras file to their nodes, each queried through its
+// own probe client, with the compatibility behaviour the era declares. Eras without a node of their own are requested
+// from the default client. Transactions found by the tx_search are unpacked with the registry.
+func setupEras(conf *config.IndexConfig, defaultClient rpc.Client, moduleBasics []module.AppModuleBasic, registry codectypes.InterfaceRegistry) (*rpc.EraClient, error) {
+	declared, err := config.LoadChainEras(conf.Eras.File)
+	if err != nil {
+		return nil, err
+	}
+
+	eras := make([]rpc.Era, 0, len(declared))
+	for _, era := range declared {
+		probeConf := conf.Probe
+		client := defaultClient
+		if era.RPC != "" && era.RPC != conf.Probe.RPC {
+			probeConf.RPC = era.RPC
+			client = rpc.NewChainClient(probe.GetProbeClient(probeConf, moduleBasics))
+		}
+
+		opts := rpc.LegacyOptions{
+			AminoBlockResults: era.BlockResults == "amino",
+			TxSearch:          era.TxSearch == "tendermint",
+		}
+		if opts.AminoBlockResults || opts.TxSearch {
+			client = rpc.NewLegacyClient(client, probeConf.RPC, registry, opts)
+		}
+
+		config.Log.Infof("Indexing heights %d to %d from %s with %s block results and the %s tx search", era.StartHeight, era.EndHeight, probeConf.RPC, era.BlockResults, era.TxSearch)
+		eras = append(eras, rpc.Era{StartHeight: era.StartHeight, EndHeight: era.EndHeight, Client: client})
+	}
+
+	return rpc.NewEraClient(defaultClient, eras), nil
+}
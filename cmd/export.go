@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/export"
+	"github.com/DefiantLabs/cosmos-indexer/query"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+type Exporter struct {
+	cfg *config.ExportConfig
+	db  *gorm.DB
+}
+
+var exporter Exporter
+
+func init() {
+	exporter.cfg = &config.ExportConfig{}
+	config.SetupLogFlags(&exporter.cfg.Log, exportCmd)
+	config.SetupDatabaseFlags(&exporter.cfg.Database, exportCmd)
+	config.SetupExportFlags(exporter.cfg, exportCmd)
+	config.SetupDuckDBExportFlags(exporter.cfg, exportDuckDBCmd)
+	config.SetupSnowflakeExportFlags(exporter.cfg, exportSnowflakeCmd)
+	config.SetupStateExportFlags(exporter.cfg, exportStateCmd)
+	config.SetupBucketsExportFlags(exporter.cfg, exportBucketsCmd)
+
+	exportCmd.AddCommand(exportDuckDBCmd)
+	exportCmd.AddCommand(exportSnowflakeCmd)
+	exportCmd.AddCommand(exportStateCmd)
+	exportCmd.AddCommand(exportBucketsCmd)
+	rootCmd.AddCommand(exportCmd)
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Exports a height range of the indexed data to a file or data warehouse.",
+	Long: `Exports the data indexed for a chain between a start and end height to a standalone file,
+	e.g. to share a reproducible dataset for analysis, or loads it into a data warehouse.`,
+}
+
+var exportDuckDBCmd = &cobra.Command{
+	Use:   "duckdb",
+	Short: "Exports a height range of the indexed data to a DuckDB database file.",
+	Long: `Exports the data indexed for a chain between a start and end height to a single DuckDB database file
+	containing every indexer table. Requires the DuckDB CLI to be installed.`,
+	PreRunE: setupDuckDBExport,
+	Run:     exportDuckDB,
+}
+
+var exportSnowflakeCmd = &cobra.Command{
+	Use:   "snowflake",
+	Short: "Incrementally loads the indexed data into Snowflake.",
+	Long: `Loads the data indexed for a chain into Snowflake tables through an external S3 stage, using COPY
+	statements or Snowpipe. Each table keeps a watermark of what was loaded, so every run only loads the data indexed
+	since the previous one. With an interval the load runs on a schedule until interrupted.`,
+	PreRunE: setupSnowflakeExport,
+	Run:     exportSnowflake,
+}
+
+var exportStateCmd = &cobra.Command{
+	Use:   "state",
+	Short: "Exports the balances and delegations at a height to a genesis-like JSON file.",
+	Long: `Reconstructs the balances and delegations of a chain at --state.height from the imported genesis state and
+	the flows indexed up to the height, and writes them to a JSON file laid out like the app state of a genesis file,
+	e.g. for audits, airdrop snapshots and fork planning. The snapshot is only as complete as the index.`,
+	PreRunE: setupStateExport,
+	Run:     exportState,
+}
+
+var exportBucketsCmd = &cobra.Command{
+	Use:   "buckets",
+	Short: "Exports the blocks and transactions aggregated per hour, day, week or month to a CSV file.",
+	Long: `Aggregates the indexed blocks of a chain between --buckets.start-time and --buckets.end-time into buckets
+	of --buckets.interval in the calendar of --buckets.timezone, so days start at local midnight, and writes the block
+	and transaction counts and the height range of every bucket to a CSV file.`,
+	PreRunE: setupBucketsExport,
+	Run:     exportBuckets,
+}
+
+func setupDuckDBExport(cmd *cobra.Command, args []string) error {
+	return setupExport(cmd, exporter.cfg.ValidateDuckDB)
+}
+
+func setupSnowflakeExport(cmd *cobra.Command, args []string) error {
+	return setupExport(cmd, exporter.cfg.ValidateSnowflake)
+}
+
+func setupStateExport(cmd *cobra.Command, args []string) error {
+	return setupExport(cmd, exporter.cfg.ValidateState)
+}
+
+func setupBucketsExport(cmd *cobra.Command, args []string) error {
+	return setupExport(cmd, exporter.cfg.ValidateBuckets)
+}
+
+// setupExport validates the shared export config and the config of the export target, then connects to the database
+func setupExport(cmd *cobra.Command, validateTarget func() error) error {
+	bindFlags(cmd, viperConf)
+
+	err := exporter.cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	err = validateTarget()
+	if err != nil {
+		return err
+	}
+
+	ignoredKeys := config.CheckSuperfluousExportKeys(viperConf.AllKeys())
+
+	if len(ignoredKeys) > 0 {
+		config.Log.Warnf("Warning, the following invalid keys will be ignored: %v", ignoredKeys)
+	}
+
+	setupLogger(exporter.cfg.Log.Level, exporter.cfg.Log.Path, exporter.cfg.Log.Pretty)
+
+	db, err := connectToDBAndMigrate(exporter.cfg.Database, exporter.cfg.Export.ChainID)
+	if err != nil {
+		config.Log.Fatal("Could not establish connection to the database", err)
+	}
+
+	exporter.db = db
+
+	return nil
+}
+
+// anonymizer returns the anonymizer of anonymized exports, nil otherwise
+func anonymizer(conf *config.ExportConfig) *export.Anonymizer {
+	if !conf.Export.Anonymize {
+		return nil
+	}
+	return export.NewAnonymizer([]byte(conf.Export.AnonymizeKey))
+}
+
+func exportDuckDB(cmd *cobra.Command, args []string) {
+	dbConn, err := exporter.db.DB()
+	if err != nil {
+		config.Log.Fatal("Failed to connect to DB", err)
+	}
+	defer dbConn.Close()
+
+	err = export.DuckDB(context.Background(), exporter.db, export.DuckDBOptions{
+		ChainID:     exporter.cfg.Export.ChainID,
+		StartHeight: exporter.cfg.Export.StartHeight,
+		EndHeight:   exporter.cfg.Export.EndHeight,
+		Output:      exporter.cfg.DuckDB.Output,
+		Binary:      exporter.cfg.DuckDB.Binary,
+		Overwrite:   exporter.cfg.Export.Overwrite,
+		Anonymizer:  anonymizer(exporter.cfg),
+	})
+	if err != nil {
+		config.Log.Fatal("Failed to export to DuckDB", err)
+	}
+
+	config.Log.Infof("Exported heights %d to %d of %s to %s", exporter.cfg.Export.StartHeight, exporter.cfg.Export.EndHeight, exporter.cfg.Export.ChainID, exporter.cfg.DuckDB.Output)
+}
+
+func exportState(cmd *cobra.Command, args []string) {
+	dbConn, err := exporter.db.DB()
+	if err != nil {
+		config.Log.Fatal("Failed to connect to DB", err)
+	}
+	defer dbConn.Close()
+
+	err = export.State(exporter.db, export.StateOptions{
+		ChainID:   exporter.cfg.Export.ChainID,
+		Height:    exporter.cfg.State.Height,
+		Output:    exporter.cfg.State.Output,
+		Overwrite: exporter.cfg.Export.Overwrite,
+	})
+	if err != nil {
+		config.Log.Fatal("Failed to export the state", err)
+	}
+
+	config.Log.Infof("Exported the state of %s at height %d to %s", exporter.cfg.Export.ChainID, exporter.cfg.State.Height, exporter.cfg.State.Output)
+}
+
+func exportBuckets(cmd *cobra.Command, args []string) {
+	dbConn, err := exporter.db.DB()
+	if err != nil {
+		config.Log.Fatal("Failed to connect to DB", err)
+	}
+	defer dbConn.Close()
+
+	conf := exporter.cfg.Buckets
+	location, err := time.LoadLocation(conf.Timezone)
+	if err != nil {
+		config.Log.Fatal("Invalid buckets.timezone", err)
+	}
+	startTime, err := query.ParseTime(conf.StartTime, location)
+	if err != nil {
+		config.Log.Fatal("Invalid buckets.start-time", err)
+	}
+	endTime, err := query.ParseTime(conf.EndTime, location)
+	if err != nil {
+		config.Log.Fatal("Invalid buckets.end-time", err)
+	}
+
+	err = export.TimeBuckets(context.Background(), exporter.db, export.TimeBucketsOptions{
+		ChainID:   exporter.cfg.Export.ChainID,
+		Interval:  query.BucketInterval(conf.Interval),
+		Location:  location,
+		StartTime: startTime,
+		EndTime:   endTime,
+		Output:    conf.Output,
+		Overwrite: exporter.cfg.Export.Overwrite,
+	})
+	if err != nil {
+		config.Log.Fatal("Failed to export the time buckets", err)
+	}
+
+	config.Log.Infof("Exported the %s buckets of %s to %s", conf.Interval, exporter.cfg.Export.ChainID, conf.Output)
+}
+
+func exportSnowflake(cmd *cobra.Command, args []string) {
+	dbConn, err := exporter.db.DB()
+	if err != nil {
+		config.Log.Fatal("Failed to connect to DB", err)
+	}
+	defer dbConn.Close()
+
+	conf := exporter.cfg.Snowflake
+	stage, err := export.NewS3Stage(conf.StageRegion, conf.StageEndpoint, conf.StageBucket, conf.StagePrefix)
+	if err != nil {
+		config.Log.Fatal("Failed to set up the Snowflake stage", err)
+	}
+
+	opts := export.SnowflakeOptions{
+		ChainID:      exporter.cfg.Export.ChainID,
+		StartHeight:  exporter.cfg.Export.StartHeight,
+		EndHeight:    exporter.cfg.Export.EndHeight,
+		BatchHeights: conf.BatchHeights,
+		Snowflake: export.SnowflakeConfig{
+			Account:        conf.Account,
+			User:           conf.User,
+			PrivateKeyFile: conf.PrivateKeyFile,
+			Role:           conf.Role,
+			Warehouse:      conf.Warehouse,
+			Database:       conf.Database,
+			Schema:         conf.Schema,
+			Endpoint:       conf.Endpoint,
+		},
+		StageName:  conf.Stage,
+		Stage:      stage,
+		Snowpipe:   conf.Snowpipe,
+		Anonymizer: anonymizer(exporter.cfg),
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	for {
+		err = export.Snowflake(ctx, exporter.db, opts)
+		switch {
+		case ctx.Err() != nil:
+			config.Log.Info("Snowflake load interrupted")
+			return
+		case err != nil && conf.Interval == 0:
+			config.Log.Fatal("Failed to load into Snowflake", err)
+		case err != nil:
+			// Scheduled runs retry on the next interval, the watermarks keep the loaded ranges
+			config.Log.Error("Failed to load into Snowflake", err)
+		default:
+			config.Log.Infof("Loaded %s into Snowflake", exporter.cfg.Export.ChainID)
+		}
+
+		if conf.Interval == 0 {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(conf.Interval):
+		}
+	}
+}
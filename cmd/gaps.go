@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/blockqueue"
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/core"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	config.SetupLogFlags(&indexer.cfg.Log, gapsCmd)
+	config.SetupDatabaseFlags(&indexer.cfg.Database, gapsCmd)
+	config.SetupProbeFlags(&indexer.cfg.Probe, gapsCmd)
+	config.SetupThrottlingFlag(&indexer.cfg.Base.Throttling, gapsCmd)
+	config.SetupIndexSpecificFlags(indexer.cfg, gapsCmd)
+
+	rootCmd.AddCommand(gapsCmd)
+}
+
+var gapsCmd = &cobra.Command{
+	Use:   "gaps",
+	Short: "Reports the heights missing from the indexed range and optionally indexes them.",
+	Long: `Scans the blocks table for the heights from base.start-block to base.end-block, or the highest indexed block
+	when the end block is -1, that were neither indexed nor recorded as failed, e.g. the holes a crash left behind, and
+	prints the missing ranges. With gaps.fill the missing heights are indexed with the index configuration.`,
+	PreRunE: setupGaps,
+	Run:     gapsReport,
+}
+
+func setupGaps(cmd *cobra.Command, args []string) error {
+	bindFlags(cmd, viperConf)
+
+	if indexer.cfg.Gaps.Fill {
+		indexer.fillGaps = true
+		return setupIndex(cmd, args)
+	}
+
+	// Reporting only reads the database, so the node is not set up
+	err := indexer.cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	ignoredKeys := config.CheckSuperfluousIndexKeys(viperConf.AllKeys())
+
+	if len(ignoredKeys) > 0 {
+		config.Log.Warnf("Warning, the following invalid keys will be ignored: %v", ignoredKeys)
+	}
+
+	setupLogger(indexer.cfg.Log.Level, indexer.cfg.Log.Path, indexer.cfg.Log.Pretty)
+
+	db, err := connectToDBAndMigrate(indexer.cfg.Database, indexer.cfg.Probe.ChainID)
+	if err != nil {
+		config.Log.Fatal("Could not establish connection to the database", err)
+	}
+
+	indexer.db = db
+
+	return nil
+}
+
+func gapsReport(cmd *cobra.Command, args []string) {
+	if indexer.fillGaps {
+		index(cmd, args)
+		return
+	}
+
+	dbConn, err := indexer.db.DB()
+	if err != nil {
+		config.Log.Fatal("Failed to connect to DB", err)
+	}
+	defer dbConn.Close()
+
+	dbChainID, err := dbTypes.GetDBChainID(indexer.db, models.Chain{ChainID: indexer.cfg.Probe.ChainID, Name: indexer.cfg.Probe.ChainName})
+	if err != nil {
+		config.Log.Fatal("Failed to add/create chain in DB", err)
+	}
+
+	start, end := core.GapRange(indexer.db, *indexer.cfg, dbChainID)
+	gaps, err := dbTypes.FindMissingHeights(indexer.db, dbChainID, start, end)
+	if err != nil {
+		config.Log.Fatal("Failed to scan for missing heights", err)
+	}
+
+	var missing int64
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "START\tEND\tBLOCKS\t")
+	for _, gap := range gaps {
+		fmt.Fprintf(w, "%d\t%d\t%d\t\n", gap.Start, gap.End, gap.Blocks())
+		missing += gap.Blocks()
+	}
+	w.Flush()
+
+	fmt.Printf("%d blocks missing in %d gaps between heights %d and %d\n", missing, len(gaps), start, end)
+}
+
+// scanGaps scans the indexed range for missing heights every interval until stop is closed. Heights missing in two
+// scans in a row are enqueued through the admin controller, like the blocks added through the admin API, heights
+// missing in a single scan may still be in flight. Enqueued heights are only enqueued again after two more scans.
+func (idxr *Indexer) scanGaps(chainID uint, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var previous []dbTypes.HeightRange
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+
+		start, end := core.GapRange(idxr.db, *idxr.cfg, chainID)
+		gaps, err := dbTypes.FindMissingHeights(idxr.db, chainID, start, end)
+		if err != nil {
+			config.Log.Error("Failed to scan for missing heights", err)
+			continue
+		}
+
+		enqueue := dbTypes.OverlappingHeights(previous, gaps)
+		previous = gaps
+		if len(enqueue) > 0 {
+			previous = nil
+		}
+
+		for _, gap := range enqueue {
+			config.Log.Warnf("Heights %d-%d are missing from the index, enqueueing them to be indexed again", gap.Start, gap.End)
+			if err := idxr.adminController.Enqueue(blockqueue.Request{StartHeight: gap.Start, EndHeight: gap.End}); err != nil {
+				config.Log.Warnf("Failed to enqueue the missing heights %d-%d, they are enqueued after a later scan: %v", gap.Start, gap.End, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/genesis"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+type GenesisImporter struct {
+	cfg     *config.GenesisConfig
+	db      *gorm.DB
+	genesis *genesis.Genesis
+}
+
+var genesisImporter GenesisImporter
+
+func init() {
+	genesisImporter.cfg = &config.GenesisConfig{}
+	config.SetupLogFlags(&genesisImporter.cfg.Log, genesisCmd)
+	config.SetupDatabaseFlags(&genesisImporter.cfg.Database, genesisCmd)
+	config.SetupGenesisFlags(genesisImporter.cfg, genesisImportCmd)
+
+	genesisCmd.AddCommand(genesisImportCmd)
+	rootCmd.AddCommand(genesisCmd)
+}
+
+var genesisCmd = &cobra.Command{
+	Use:   "genesis",
+	Short: "Indexes the genesis state of a chain.",
+}
+
+var genesisImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Imports an exported genesis file as the state of the chain at height 0.",
+	Long: `Parses the genesis file given with --genesis.file and indexes its initial balances and supply, its validators,
+	including the ones created by genesis transactions, the gov params and the CosmWasm contracts into the genesis_*
+	tables of the chain named by its chain_id. Balance and supply histories are then complete from chain start.
+	Importing a genesis again replaces the state imported before.`,
+	PreRunE: setupGenesisImport,
+	Run:     genesisImport,
+}
+
+func setupGenesisImport(cmd *cobra.Command, args []string) error {
+	bindFlags(cmd, viperConf)
+
+	err := genesisImporter.cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	ignoredKeys := config.CheckSuperfluousGenesisKeys(viperConf.AllKeys())
+
+	if len(ignoredKeys) > 0 {
+		config.Log.Warnf("Warning, the following invalid keys will be ignored: %v", ignoredKeys)
+	}
+
+	setupLogger(genesisImporter.cfg.Log.Level, genesisImporter.cfg.Log.Path, genesisImporter.cfg.Log.Pretty)
+
+	// The chain ID is read from the genesis file, it selects the schema of the chain with database.schema-per-chain
+	file, err := os.Open(genesisImporter.cfg.Genesis.File)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	genesisImporter.genesis, err = genesis.Parse(file)
+	if err != nil {
+		return err
+	}
+
+	db, err := connectToDBAndMigrate(genesisImporter.cfg.Database, genesisImporter.genesis.ChainID)
+	if err != nil {
+		config.Log.Fatal("Could not establish connection to the database", err)
+	}
+
+	genesisImporter.db = db
+
+	return nil
+}
+
+func genesisImport(cmd *cobra.Command, args []string) {
+	dbConn, err := genesisImporter.db.DB()
+	if err != nil {
+		config.Log.Fatal("Failed to connect to DB", err)
+	}
+	defer dbConn.Close()
+
+	parsed := genesisImporter.genesis
+	chainID, err := dbTypes.GetDBChainID(genesisImporter.db, models.Chain{ChainID: parsed.ChainID})
+	if err != nil {
+		config.Log.Fatal("Failed to get the chain", err)
+	}
+
+	err = dbTypes.IndexGenesis(genesisImporter.db, chainID, &parsed.State)
+	if err != nil {
+		config.Log.Fatal("Failed to import the genesis state", err)
+	}
+
+	config.Log.Infof("Imported the genesis state of %s: %d balances, %d supplies, %d validators, %d params, %d contracts",
+		parsed.ChainID, len(parsed.State.Balances), len(parsed.State.Supply), len(parsed.State.Validators), len(parsed.State.Params), len(parsed.State.Contracts))
+}
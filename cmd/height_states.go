@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// heightStateTracker records the pipeline stages reached by the heights of a chain in the height_states table. The
+// stages are buffered and written in batches, so the stages do not add a write per block. A nil heightStateTracker does
+// not track anything.
+type heightStateTracker struct {
+	chainID uint
+
+	mu      sync.Mutex
+	pending map[int64]*models.HeightState
+	// uncommitted counts the parsed parts of a height, its block events and transactions, not committed yet
+	uncommitted map[int64]int
+}
+
+func newHeightStateTracker(chainID uint) *heightStateTracker {
+	return &heightStateTracker{
+		chainID:     chainID,
+		pending:     make(map[int64]*models.HeightState),
+		uncommitted: make(map[int64]int),
+	}
+}
+
+// queued starts a new pass of the height
+func (t *heightStateTracker) queued(height int64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.uncommitted, height)
+	t.pending[height] = &models.HeightState{Height: height, BlockchainID: t.chainID}
+	t.reached(height, models.HeightQueued)
+}
+
+func (t *heightStateTracker) fetched(height int64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.reached(height, models.HeightFetched)
+}
+
+// partParsed records that a part of the height was parsed and is handed to the DB writer
+func (t *heightStateTracker) partParsed(height int64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.uncommitted[height]++
+	t.reached(height, models.HeightParsed)
+}
+
+// partCommitted records that a part of the height was written, the height is committed once all its parsed parts are
+func (t *heightStateTracker) partCommitted(height int64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.uncommitted[height]--
+	if t.uncommitted[height] > 0 {
+		return
+	}
+	delete(t.uncommitted, height)
+	t.reached(height, models.HeightCommitted)
+}
+
+// rolledBack drops the stages of the heights rolled back after a reorg, the database rows were deleted with the blocks.
+// The heights start a new pass once they are queued again.
+func (t *heightStateTracker) rolledBack(start int64, end int64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for height := range t.pending {
+		if height >= start && height <= end {
+			delete(t.pending, height)
+		}
+	}
+	for height := range t.uncommitted {
+		if height >= start && height <= end {
+			delete(t.uncommitted, height)
+		}
+	}
+}
+
+// reached sets the stage of the height, it must be called with the lock held
+func (t *heightStateTracker) reached(height int64, stage models.HeightStage) {
+	state, ok := t.pending[height]
+	if !ok {
+		state = &models.HeightState{Height: height, BlockchainID: t.chainID}
+		t.pending[height] = state
+	}
+
+	now := time.Now()
+	state.State = stage
+	state.UpdatedAt = now
+	switch stage {
+	case models.HeightQueued:
+		state.QueuedAt = &now
+	case models.HeightFetched:
+		state.FetchedAt = &now
+	case models.HeightParsed:
+		state.ParsedAt = &now
+	case models.HeightCommitted:
+		state.CommittedAt = &now
+	case models.HeightVerified:
+		state.VerifiedAt = &now
+	}
+}
+
+// flushEvery writes the buffered stages every interval until stop is closed
+func (t *heightStateTracker) flushEvery(db *gorm.DB, interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			t.flush(db)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// flush writes the buffered stages. Stages that could not be written are kept for the next flush unless the height
+// reached another stage meanwhile.
+func (t *heightStateTracker) flush(db *gorm.DB) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[int64]*models.HeightState)
+	t.mu.Unlock()
+
+	if len(pending) == 0 {
+		return
+	}
+
+	// Heights are written in order, so concurrent flushes cannot deadlock on the rows
+	states := make([]models.HeightState, 0, len(pending))
+	for _, state := range pending {
+		states = append(states, *state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Height < states[j].Height })
+
+	if err := dbTypes.UpsertHeightStates(db, states); err != nil {
+		config.Log.Error("Failed to write the height states", err)
+
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		for height, state := range pending {
+			if _, ok := t.pending[height]; !ok {
+				t.pending[height] = state
+			}
+		}
+	}
+}
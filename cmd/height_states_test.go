@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/stretchr/testify/suite"
+)
+
+type HeightStatesTestSuite struct {
+	suite.Suite
+}
+
+func (suite *HeightStatesTestSuite) TestRolledBack() {
+	tracker := newHeightStateTracker(1)
+	for _, height := range []int64{2, 3, 5} {
+		tracker.queued(height)
+		tracker.partParsed(height)
+	}
+	tracker.partCommitted(2)
+
+	// The stages of the rolled back heights are not written back after their rows were deleted
+	tracker.rolledBack(2, 3)
+	suite.Require().Len(tracker.pending, 1)
+	suite.Require().Equal(models.HeightParsed, tracker.pending[5].State)
+	suite.Require().Equal(map[int64]int{5: 1}, tracker.uncommitted)
+
+	// and start a new pass once queued again
+	tracker.queued(3)
+	tracker.partParsed(3)
+	tracker.partCommitted(3)
+	suite.Require().Equal(models.HeightCommitted, tracker.pending[3].State)
+}
+
+func TestHeightStatesTestSuite(t *testing.T) {
+	suite.Run(t, new(HeightStatesTestSuite))
+}
@@ -1,23 +1,31 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/DefiantLabs/probe/client"
 
+	"github.com/DefiantLabs/cosmos-indexer/admin"
+	"github.com/DefiantLabs/cosmos-indexer/blockqueue"
 	"github.com/DefiantLabs/cosmos-indexer/config"
 	"github.com/DefiantLabs/cosmos-indexer/core"
 	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
 	"github.com/DefiantLabs/cosmos-indexer/db/models"
 	"github.com/DefiantLabs/cosmos-indexer/filter"
+	"github.com/DefiantLabs/cosmos-indexer/jobs"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
 	"github.com/DefiantLabs/cosmos-indexer/parsers"
 	"github.com/DefiantLabs/cosmos-indexer/probe"
 	"github.com/DefiantLabs/cosmos-indexer/rpc"
+	"github.com/DefiantLabs/cosmos-indexer/sinks"
 	"github.com/cosmos/cosmos-sdk/types/module"
 	"github.com/spf13/cobra"
 	"gorm.io/gorm"
@@ -28,6 +36,8 @@ type Indexer struct {
 	dryRun                              bool
 	db                                  *gorm.DB
 	cl                                  *client.ChainClient
+	rpcClient                           rpc.Client        // Blocks, block results and transactions are fetched through it, defaults to the probe client
+	endpointPool                        *rpc.EndpointPool // nil unless additional RPC endpoints are configured
 	blockEnqueueFunction                func(chan *core.EnqueueData) error
 	customModuleBasics                  []module.AppModuleBasic // Used for extending the AppModuleBasics registered in the probe client
 	blockEventFilterRegistries          blockEventFilterRegistries
@@ -39,6 +49,18 @@ type Indexer struct {
 	customMessageParserRegistry         map[string][]parsers.MessageParser    // Used for associating parsers to message types
 	customMessageParserTrackers         map[string]models.MessageParser       // Used for tracking message parsers in the database
 	customModels                        []any
+	sinkDispatcher                      *sinks.Dispatcher
+	viewRefresher                       *viewRefresher
+	aggregationJobs                     []jobs.Job        // Go jobs registered with RegisterAggregationJob
+	jobScheduler                        *jobs.Scheduler   // nil unless aggregation jobs are declared or registered
+	adminController                     *admin.Controller // Pauses, skips and adds blocks, see relayBlocks
+	rpcWorkers                          *rpcWorkerPool
+	watchdog                            *stallWatchdog             // nil unless the stall watchdog is enabled
+	heightStates                        *heightStateTracker        // nil unless the height states are written
+	moduleVersions                      *core.ModuleVersionTracker // nil unless the module versions are tracked
+	checkpoint                          *indexCheckpoint           // nil unless the run saves an indexing checkpoint
+	timings                             *stageTimings              // nil unless benchmarking, see the bench command
+	fillGaps                            bool                       // Indexes the missing heights, see the gaps command
 }
 
 type blockEventFilterRegistries struct {
@@ -46,10 +68,10 @@ type blockEventFilterRegistries struct {
 	endBlockEventFilterRegistry   *filter.StaticBlockEventFilterRegistry
 }
 
-var indexer Indexer
+// indexer is configured by the flags of the index, bench and gaps commands
+var indexer = Indexer{cfg: &config.IndexConfig{}}
 
 func init() {
-	indexer.cfg = &config.IndexConfig{}
 	config.SetupLogFlags(&indexer.cfg.Log, indexCmd)
 	config.SetupDatabaseFlags(&indexer.cfg.Database, indexCmd)
 	config.SetupProbeFlags(&indexer.cfg.Probe, indexCmd)
@@ -149,10 +171,21 @@ func customBlockEventRegistration(registry map[string][]parsers.BlockEventParser
 	return registry, tracker, nil
 }
 
+// RegisterAggregationJob adds a Go aggregation job, run by the indexer alongside the SQL jobs of the jobs file
+func RegisterAggregationJob(job jobs.Job) {
+	indexer.aggregationJobs = append(indexer.aggregationJobs, job)
+}
+
 func RegisterCustomModels(models []any) {
 	indexer.customModels = models
 }
 
+// RegisterRPCClient replaces the probe client as the source of blocks, block results and transactions. Transactions are
+// still decoded with the probe client codec.
+func RegisterRPCClient(client rpc.Client) {
+	indexer.rpcClient = client
+}
+
 func setupIndex(cmd *cobra.Command, args []string) error {
 	bindFlags(cmd, viperConf)
 
@@ -174,13 +207,25 @@ func setupIndex(cmd *cobra.Command, args []string) error {
 		indexer.cfg.Base.StartBlock = 1
 	}
 
-	db, err := connectToDBAndMigrate(indexer.cfg.Database)
+	db, err := connectToDBAndMigrate(indexer.cfg.Database, indexer.cfg.Probe.ChainID)
 	if err != nil {
 		config.Log.Fatal("Could not establish connection to the database", err)
 	}
 
 	indexer.db = db
 
+	if indexer.cfg.Search.Enabled {
+		if err := dbTypes.MigrateSearch(db); err != nil {
+			config.Log.Fatal("Failed to create the full-text search index", err)
+		}
+	}
+
+	if indexer.cfg.Cache.Enabled {
+		if err := dbTypes.EnableDictionaryCache(db, indexer.cfg.Cache.MaxAddresses); err != nil {
+			config.Log.Fatal("Failed to preload the dictionary cache", err)
+		}
+	}
+
 	indexer.dryRun = indexer.cfg.Base.Dry
 
 	indexer.blockEventFilterRegistries = blockEventFilterRegistries{
@@ -215,6 +260,12 @@ func setupIndex(cmd *cobra.Command, args []string) error {
 		indexer.messageTypeFilters = append(indexer.messageTypeFilters, fileMessageTypeFilters...)
 	}
 
+	if indexer.cfg.Wasm.Enabled {
+		if err := registerWasmParsers(indexer.db); err != nil {
+			config.Log.Fatal("Failed to set up the wasm parsers", err)
+		}
+	}
+
 	if len(indexer.customModels) != 0 {
 		err = dbTypes.MigrateInterfaces(indexer.db, indexer.customModels)
 		if err != nil {
@@ -244,6 +295,32 @@ func setupIndex(cmd *cobra.Command, args []string) error {
 
 	}
 
+	indexer.sinkDispatcher, err = setupSinks(indexer.cfg, indexer.db)
+	if err != nil {
+		config.Log.Fatal("Failed to set up sinks", err)
+	}
+
+	// Views are not created on dry runs since nothing is written that would change them
+	if !indexer.dryRun {
+		indexer.viewRefresher, err = setupViews(indexer.cfg, indexer.db)
+		if err != nil {
+			config.Log.Fatal("Failed to set up materialized views", err)
+		}
+
+		indexer.jobScheduler, err = setupJobs(indexer.cfg, indexer.db, indexer.aggregationJobs)
+		if err != nil {
+			config.Log.Fatal("Failed to set up aggregation jobs", err)
+		}
+	}
+
+	// The faults are injected once the setup is done, the setup has no retries to exercise
+	if indexer.cfg.Chaos.DBErrorRate > 0 {
+		config.Log.Warnf("Chaos mode: failing %.1f%% of the block writes with transient database errors", indexer.cfg.Chaos.DBErrorRate*100)
+		if err := dbTypes.RegisterChaosCallbacks(indexer.db, indexer.cfg.Chaos.DBErrorRate, indexer.cfg.Chaos.Seed); err != nil {
+			config.Log.Fatal("Failed to register the chaos callbacks", err)
+		}
+	}
+
 	return nil
 }
 
@@ -255,21 +332,100 @@ func setupIndexer() *Indexer {
 	config.SetChainConfig(indexer.cfg.Probe.AccountPrefix)
 
 	indexer.cl = probe.GetProbeClient(indexer.cfg.Probe, indexer.customModuleBasics)
+	switch {
+	case indexer.rpcClient != nil:
+		// Registered with RegisterRPCClient
+	case strings.HasPrefix(indexer.cfg.Base.ReplayFixtures, "s3://"):
+		indexer.rpcClient, err = rpc.NewS3FixtureClient(indexer.cfg.Base.ReplayFixtures, indexer.cfg.Base.ReplayFixturesRegion, indexer.cfg.Base.ReplayFixturesEndpoint, indexer.cl.Codec.InterfaceRegistry)
+		if err != nil {
+			config.Log.Fatal("Failed to open the RPC fixtures archive", err)
+		}
+	case indexer.cfg.Base.ReplayFixtures != "":
+		if _, err := os.Stat(indexer.cfg.Base.ReplayFixtures); err != nil {
+			config.Log.Fatal("Failed to open the RPC fixtures directory", err)
+		}
+		indexer.rpcClient = rpc.NewFixtureClient(indexer.cfg.Base.ReplayFixtures, indexer.cl.Codec.InterfaceRegistry)
+	case indexer.cfg.Synthetic.Enabled:
+		// The chain ends at the end block, or practically never when indexing indefinitely
+		latestHeight := indexer.cfg.Base.EndBlock
+		if latestHeight < 0 {
+			latestHeight = math.MaxInt32
+		}
+		indexer.rpcClient = rpc.NewSyntheticClient(rpc.SyntheticOptions{
+			ChainID:      indexer.cfg.Probe.ChainID,
+			LatestHeight: latestHeight,
+			TxsPerBlock:  indexer.cfg.Synthetic.TxsPerBlock,
+			MsgsPerTx:    indexer.cfg.Synthetic.MsgsPerTx,
+			BlockEvents:  indexer.cfg.Synthetic.BlockEvents,
+			Addresses:    indexer.cfg.Synthetic.Addresses,
+		})
+	default:
+		indexer.rpcClient = rpc.NewChainClient(indexer.cl)
+		if len(indexer.cfg.Endpoints.RPC) > 0 || indexer.cfg.Endpoints.File != "" {
+			indexer.endpointPool, err = setupEndpointPool(indexer.cfg, indexer.rpcClient, indexer.customModuleBasics)
+			if err != nil {
+				config.Log.Fatal("Failed to set up the RPC endpoints", err)
+			}
+			indexer.rpcClient = indexer.endpointPool
+		}
+		if indexer.cfg.Eras.File != "" {
+			indexer.rpcClient, err = setupEras(indexer.cfg, indexer.rpcClient, indexer.customModuleBasics, indexer.cl.Codec.InterfaceRegistry)
+			if err != nil {
+				config.Log.Fatal("Failed to set up the chain eras", err)
+			}
+		}
+		if indexer.cfg.Consistency.RPC != "" {
+			indexer.rpcClient = setupConsistency(indexer.cfg, indexer.rpcClient, indexer.customModuleBasics)
+		}
+		if indexer.cfg.Verify.Enabled {
+			indexer.rpcClient, err = setupVerification(indexer.cfg, indexer.rpcClient)
+			if err != nil {
+				config.Log.Fatal("Failed to set up the block verification", err)
+			}
+		}
+	}
+	if indexer.cfg.Base.RecordFixtures != "" {
+		indexer.rpcClient, err = rpc.NewRecordingClient(indexer.rpcClient, indexer.cfg.Base.RecordFixtures)
+		if err != nil {
+			config.Log.Fatal("Failed to create the RPC fixtures directory", err)
+		}
+	}
+	// Faults are injected after recording, so the fixtures hold the node's responses
+	if indexer.cfg.Chaos.RPCTimeoutRate > 0 || indexer.cfg.Chaos.RPCMalformedRate > 0 {
+		config.Log.Warnf("Chaos mode: %.1f%% of the RPC requests time out, %.1f%% are answered malformed", indexer.cfg.Chaos.RPCTimeoutRate*100, indexer.cfg.Chaos.RPCMalformedRate*100)
+		indexer.rpcClient = rpc.NewChaosClient(indexer.rpcClient, rpc.ChaosOptions{
+			TimeoutRate:   indexer.cfg.Chaos.RPCTimeoutRate,
+			MalformedRate: indexer.cfg.Chaos.RPCMalformedRate,
+			Seed:          indexer.cfg.Chaos.Seed,
+		})
+	}
+	if indexer.timings != nil || indexer.cfg.Metrics.Enabled {
+		indexer.rpcClient = &timedClient{Client: indexer.rpcClient, timings: indexer.timings}
+	}
+
+	if !indexer.dryRun {
+		if err := setupDenomDisplays(indexer.cfg, indexer.db, indexer.cl); err != nil {
+			config.Log.Fatal("Failed to set up the denom display units", err)
+		}
+		if err := setupDenomTraces(indexer.cfg, indexer.db, indexer.cl); err != nil {
+			config.Log.Fatal("Failed to store the IBC denom traces", err)
+		}
+	}
 
 	// Depending on the app configuration, wait for the chain to catch up
-	chainCatchingUp, err := rpc.IsCatchingUp(indexer.cl)
+	chainCatchingUp, err := rpc.IsCatchingUp(indexer.rpcClient)
 	for indexer.cfg.Base.WaitForChain && chainCatchingUp && err == nil {
 		// Wait between status checks, don't spam the node with requests
 		config.Log.Debug("Chain is still catching up, please wait or disable check in config.")
 		time.Sleep(time.Second * time.Duration(indexer.cfg.Base.WaitForChainDelay))
-		chainCatchingUp, err = rpc.IsCatchingUp(indexer.cl)
+		chainCatchingUp, err = rpc.IsCatchingUp(indexer.rpcClient)
 
 		// This EOF error pops up from time to time and is unpredictable
 		// It is most likely an error on the node, we would need to see any error logs on the node side
 		// Try one more time
 		if err != nil && strings.HasSuffix(err.Error(), "EOF") {
 			time.Sleep(time.Second * time.Duration(indexer.cfg.Base.WaitForChainDelay))
-			chainCatchingUp, err = rpc.IsCatchingUp(indexer.cl)
+			chainCatchingUp, err = rpc.IsCatchingUp(indexer.rpcClient)
 		}
 	}
 	if err != nil {
@@ -280,6 +436,15 @@ func setupIndexer() *Indexer {
 }
 
 func index(cmd *cobra.Command, args []string) {
+	// Interrupting the indexer cancels enqueueCtx, which stops relaying blocks to the RPC workers, so the pipeline
+	// drains the blocks in flight and stops. Cancelling ctx stops every stage of the pipeline right away, the blocks in
+	// flight are dropped and their DB transactions are rolled back.
+	ctx, cancel := context.WithCancel(cmd.Context())
+	defer cancel()
+	enqueueCtx, stopEnqueue := context.WithCancel(ctx)
+	defer stopEnqueue()
+	go handleShutdownSignals(stopEnqueue, cancel, time.Duration(indexer.cfg.Base.ShutdownTimeout)*time.Second, ctx.Done())
+
 	// Setup the indexer with config, db, and cl
 	idxr := setupIndexer()
 	dbConn, err := idxr.db.DB()
@@ -287,12 +452,70 @@ func index(cmd *cobra.Command, args []string) {
 		config.Log.Fatal("Failed to connect to DB", err)
 	}
 	defer dbConn.Close()
+	// Checked once the checkpoint and the height states were saved, so a supervisor restarts the indexer to resume
+	defer func() {
+		if idxr.watchdog.stoppedPipeline() {
+			config.Log.Fatal("Indexing stopped after the pipeline stalled, restart the indexer to resume")
+		}
+	}()
+	defer idxr.sinkDispatcher.Close()
+
+	if idxr.cfg.Metrics.Enabled {
+		metricsServer := metrics.StartServer(idxr.cfg.Metrics.Port)
+		defer metricsServer.Close()
+	}
+
+	hasCustomParsers := len(idxr.customBeginBlockParserTrackers) != 0 || len(idxr.customEndBlockParserTrackers) != 0 || len(idxr.customMessageParserTrackers) != 0
+	if hasCustomParsers && !idxr.dryRun && idxr.cfg.Metrics.ParserFlushInterval > 0 {
+		stopParserMetricsFlush := make(chan struct{})
+		parserMetricsFlushed := make(chan struct{})
+		go idxr.flushParserMetrics(time.Duration(idxr.cfg.Metrics.ParserFlushInterval)*time.Second, stopParserMetricsFlush, parserMetricsFlushed)
+		defer func() {
+			close(stopParserMetricsFlush)
+			<-parserMetricsFlushed
+		}()
+	}
 
 	// blockChans are just the block heights; limit max jobs in the queue, otherwise this queue would contain one
 	// item (block height) for every block on the entire blockchain we're indexing. Furthermore, once the queue
 	// is close to empty, we will spin up a new thread to fill it up with new jobs.
 	blockEnqueueChan := make(chan *core.EnqueueData, 10000)
 
+	// Signals and the admin API pause, skip and add blocks in between the enqueue function and the RPC workers, see
+	// relayBlocks
+	idxr.adminController = admin.NewController()
+	if idxr.cfg.Admin.Enabled {
+		adminServer, err := startAdmin(idxr.cfg, idxr.adminController)
+		if err != nil {
+			config.Log.Fatal("Failed to start the admin API server", err)
+		}
+		defer adminServer.Shutdown()
+	}
+
+	go idxr.listenPauseSignals(ctx.Done())
+
+	if idxr.endpointPool != nil {
+		idxr.endpointPool.Start(ctx)
+	}
+
+	// The runs in progress finish before the indexer exits, their transactions are rolled back once ctx is done
+	idxr.jobScheduler.Start(ctx)
+	defer func() {
+		cancel()
+		idxr.jobScheduler.Wait()
+	}()
+
+	window, err := idxr.cfg.Schedule.Window()
+	if err != nil {
+		config.Log.Fatal("Invalid indexing schedule", err)
+	}
+	if window != nil {
+		// Set before the first block is relayed, the schedule is followed in the background from then on
+		open, _ := window.At(time.Now())
+		idxr.adminController.SetOutsideWindow(!open)
+		go idxr.followSchedule(window, ctx.Done())
+	}
+
 	// This channel represents query job results for the RPC queries to Cosmos Nodes. Every time an RPC query
 	// completes, the query result will be sent to this channel (for later processing by a different thread).
 	// Realistically, I expect that RPC queries will be slower than our relational DB on the local network.
@@ -317,14 +540,45 @@ func index(cmd *cobra.Command, args []string) {
 		config.Log.Fatal("Failed to add/create chain in DB", err)
 	}
 
+	// The stages are written without ctx, so the ones of the interrupted blocks are written on shutdown as well
+	if !idxr.dryRun && idxr.cfg.Metrics.HeightStateInterval > 0 {
+		idxr.heightStates = newHeightStateTracker(dbChainID)
+		stopHeightStateFlush := make(chan struct{})
+		go idxr.heightStates.flushEvery(idxr.db, time.Duration(idxr.cfg.Metrics.HeightStateInterval)*time.Second, stopHeightStateFlush)
+		defer func() {
+			close(stopHeightStateFlush)
+			idxr.heightStates.flush(idxr.db)
+		}()
+	}
+
+	if !idxr.dryRun && idxr.cfg.Flags.TrackModuleVersions {
+		idxr.trackModuleVersions(ctx, dbChainID)
+	}
+
+	if !idxr.dryRun && idxr.cfg.Metrics.HeadInterval > 0 {
+		stopChainHead := make(chan struct{})
+		go idxr.trackChainHead(dbChainID, time.Duration(idxr.cfg.Metrics.HeadInterval)*time.Second, time.Duration(idxr.cfg.Metrics.HaltAfter)*time.Second, stopChainHead)
+		defer close(stopChainHead)
+	}
+
+	if !idxr.dryRun && !idxr.fillGaps && idxr.cfg.Gaps.Interval > 0 {
+		stopGapScans := make(chan struct{})
+		go idxr.scanGaps(dbChainID, time.Duration(idxr.cfg.Gaps.Interval)*time.Second, stopGapScans)
+		defer close(stopGapScans)
+	}
+
 	// This block consolidates all base RPC requests into one worker.
 	// Workers read from the enqueued blocks and query blockchain data from the RPC server.
+	// The workers are replaced by the stall watchdog, the output is closed once the last set of workers exited.
 	var blockRPCWaitGroup sync.WaitGroup
 	blockRPCWorkerDataChan := make(chan core.IndexerBlockEventData, 10)
-	for i := 0; i < rpcQueryThreads; i++ {
-		blockRPCWaitGroup.Add(1)
-		go core.BlockRPCWorker(&blockRPCWaitGroup, blockEnqueueChan, dbChainID, idxr.cfg.Probe.ChainID, idxr.cfg, idxr.cl, idxr.db, blockRPCWorkerDataChan)
-	}
+	idxr.rpcWorkers = newRPCWorkerPool(ctx, func(ctx context.Context, input chan *core.EnqueueData) {
+		for i := 0; i < rpcQueryThreads; i++ {
+			blockRPCWaitGroup.Add(1)
+			go core.BlockRPCWorker(ctx, &blockRPCWaitGroup, input, dbChainID, idxr.cfg.Probe.ChainID, idxr.cfg, idxr.rpcClient, idxr.db, blockRPCWorkerDataChan)
+		}
+	})
+	go idxr.relayBlocks(enqueueCtx, blockEnqueueChan)
 
 	go func() {
 		blockRPCWaitGroup.Wait()
@@ -335,46 +589,136 @@ func index(cmd *cobra.Command, args []string) {
 	blockEventsDataChan := make(chan *blockEventsDBData, 4*rpcQueryThreads)
 	txDataChan := make(chan *dbData, 4*rpcQueryThreads)
 
+	idxr.watchdog = newStallWatchdog(idxr.cfg.Watchdog.StallTimeout, func() map[string]int {
+		return map[string]int{
+			"enqueued":     len(blockEnqueueChan),
+			"rpc_workers":  idxr.rpcWorkers.depth(),
+			"rpc_results":  len(blockRPCWorkerDataChan),
+			"block_events": len(blockEventsDataChan),
+			"transactions": len(txDataChan),
+		}
+	}, idxr.adminController.Resumed, idxr.rpcWorkers.requestRestart, cancel)
+	go idxr.watchdog.watch(ctx.Done())
+
 	wg.Add(1)
-	go idxr.processBlocks(&wg, core.HandleFailedBlock, blockRPCWorkerDataChan, blockEventsDataChan, txDataChan, dbChainID, indexer.blockEventFilterRegistries)
+	go idxr.processBlocks(ctx, &wg, idxr.failedBlockHandler(ctx), blockRPCWorkerDataChan, blockEventsDataChan, txDataChan, dbChainID, indexer.blockEventFilterRegistries)
 
 	wg.Add(1)
-	go idxr.doDBUpdates(&wg, txDataChan, blockEventsDataChan, dbChainID)
+	go idxr.doDBUpdates(ctx, &wg, txDataChan, blockEventsDataChan, dbChainID)
 
 	switch {
 	// If block enqueue function has been explicitly set, use that
 	case idxr.blockEnqueueFunction != nil:
 	// Default block enqueue functions based on config values
+	case idxr.fillGaps:
+		idxr.blockEnqueueFunction, err = core.GenerateGapEnqueueFunction(idxr.db, *idxr.cfg, idxr.rpcClient, dbChainID)
+		if err != nil {
+			config.Log.Fatal("Failed to generate block enqueue function", err)
+		}
 	case idxr.cfg.Base.ReindexMessageType != "":
 		idxr.blockEnqueueFunction, err = core.GenerateMsgTypeEnqueueFunction(idxr.db, *idxr.cfg, dbChainID, idxr.cfg.Base.ReindexMessageType)
 		if err != nil {
 			config.Log.Fatal("Failed to generate block enqueue function", err)
 		}
+	case idxr.cfg.Queue.Enabled():
+		consumer, err := newBlockQueueConsumer(*idxr.cfg)
+		if err != nil {
+			config.Log.Fatal("Failed to connect to the block queue", err)
+		}
+		idxr.blockEnqueueFunction, err = core.GenerateQueueEnqueueFunction(*idxr.cfg, idxr.rpcClient, consumer)
+		if err != nil {
+			config.Log.Fatal("Failed to generate block enqueue function", err)
+		}
+	case idxr.cfg.Base.BlockInputFile == "-":
+		idxr.blockEnqueueFunction, err = core.GenerateBlockStreamEnqueueFunction(*idxr.cfg, idxr.rpcClient, os.Stdin)
+		if err != nil {
+			config.Log.Fatal("Failed to generate block enqueue function", err)
+		}
 	case idxr.cfg.Base.BlockInputFile != "":
-		idxr.blockEnqueueFunction, err = core.GenerateBlockFileEnqueueFunction(idxr.db, *idxr.cfg, idxr.cl, dbChainID, idxr.cfg.Base.BlockInputFile)
+		idxr.blockEnqueueFunction, err = core.GenerateBlockFileEnqueueFunction(idxr.db, *idxr.cfg, idxr.rpcClient, dbChainID, idxr.cfg.Base.BlockInputFile)
 		if err != nil {
 			config.Log.Fatal("Failed to generate block enqueue function", err)
 		}
 	default:
-		idxr.blockEnqueueFunction, err = core.GenerateDefaultEnqueueFunction(idxr.db, *idxr.cfg, idxr.cl, dbChainID)
+		// Reindexing goes through the heights indexed before, so only the other runs resume from the checkpoint
+		if !idxr.dryRun && !idxr.cfg.Base.ReIndex {
+			idxr.checkpoint = idxr.resumeFromCheckpoint(dbChainID)
+		}
+		idxr.blockEnqueueFunction, err = core.GenerateDefaultEnqueueFunction(idxr.db, *idxr.cfg, idxr.rpcClient, dbChainID)
 		if err != nil {
 			config.Log.Fatal("Failed to generate block enqueue function", err)
 		}
 	}
 
-	err = idxr.blockEnqueueFunction(blockEnqueueChan)
-	if err != nil {
-		config.Log.Fatal("Block enqueue failed", err)
+	// The checkpoint is saved once more after the pipeline stopped, covering the blocks drained on shutdown
+	if idxr.checkpoint != nil {
+		stopCheckpoints := make(chan struct{})
+		if idxr.cfg.Base.CheckpointInterval > 0 {
+			go idxr.checkpoint.saveEvery(idxr.db, time.Duration(idxr.cfg.Base.CheckpointInterval)*time.Second, stopCheckpoints)
+		}
+		defer func() {
+			close(stopCheckpoints)
+			idxr.checkpoint.save(idxr.db)
+		}()
 	}
 
-	close(blockEnqueueChan)
+	// The enqueue functions do not stop on their own when interrupted, the pipeline stops without waiting for them
+	enqueueDone := make(chan error, 1)
+	go func() {
+		enqueueDone <- idxr.blockEnqueueFunction(blockEnqueueChan)
+	}()
+
+	select {
+	case err = <-enqueueDone:
+		if err != nil {
+			config.Log.Fatal("Block enqueue failed", err)
+		}
+		close(blockEnqueueChan)
+	case <-enqueueCtx.Done():
+	}
 
 	wg.Wait()
 }
 
+// flushParserMetrics periodically persists custom parser execution metrics until stop is closed, then writes them a
+// last time and closes done
+func (idxr *Indexer) flushParserMetrics(interval time.Duration, stop chan struct{}, done chan struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var written []metrics.ParserStats
+	for {
+		select {
+		case <-ticker.C:
+			written = idxr.writeParserMetrics(written)
+		case <-stop:
+			idxr.writeParserMetrics(written)
+			return
+		}
+	}
+}
+
+// writeParserMetrics adds the parser stats counted since the written stats to the execution metrics of the chain. It
+// returns the stats written so far.
+func (idxr *Indexer) writeParserMetrics(written []metrics.ParserStats) []metrics.ParserStats {
+	current := metrics.GetParserStats()
+	err := dbTypes.UpsertParserExecutionMetrics(idxr.db, idxr.cfg.Probe.ChainID, metrics.ParserStatsSince(current, written))
+	if err != nil {
+		config.Log.Error("Failed to write custom parser execution metrics", err)
+		return written
+	}
+	return current
+}
+
 type dbData struct {
 	txDBWrappers []dbTypes.TxDBWrapper
+	failedTxs    []models.FailedTx
 	block        models.Block
+	// commit is the last commit of the block, when block commits are indexed
+	commit *dbTypes.BlockCommitDBWrapper
+	// evidence is the evidence of misbehaviour included in the block
+	evidence []dbTypes.BlockEvidenceDBWrapper
 }
 
 type blockEventsDBData struct {
@@ -383,92 +727,159 @@ type blockEventsDBData struct {
 
 // This function is responsible for processing raw RPC data into app-usable types. It handles both block events and transactions.
 // It parses each dataset according to the application configuration requirements and passes the data to the channels that handle the parsed data.
-func (idxr *Indexer) processBlocks(wg *sync.WaitGroup, failedBlockHandler core.FailedBlockHandler, blockRPCWorkerChan chan core.IndexerBlockEventData, blockEventsDataChan chan *blockEventsDBData, txDataChan chan *dbData, chainID uint, blockEventFilterRegistry blockEventFilterRegistries) {
+// It stops once ctx is done, dropping the blocks that are not passed on yet.
+func (idxr *Indexer) processBlocks(ctx context.Context, wg *sync.WaitGroup, failedBlockHandler core.FailedBlockHandler, blockRPCWorkerChan chan core.IndexerBlockEventData, blockEventsDataChan chan *blockEventsDBData, txDataChan chan *dbData, chainID uint, blockEventFilterRegistry blockEventFilterRegistries) {
 	defer close(blockEventsDataChan)
 	defer close(txDataChan)
 	defer wg.Done()
 
-	for blockData := range blockRPCWorkerChan {
-		currentHeight := blockData.BlockData.Block.Height
-		config.Log.Infof("Parsing data for block %d", currentHeight)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case blockData, ok := <-blockRPCWorkerChan:
+			if !ok {
+				return
+			}
+			metrics.SetChannelDepth("fetched", len(blockRPCWorkerChan))
+			idxr.recoverBlock(blockData.BlockData.Block.Height, models.ComponentProcessor, failedBlockHandler, dbTypes.UpsertFailedBlock, func() {
+				idxr.processBlock(ctx, failedBlockHandler, blockData, blockEventsDataChan, txDataChan, chainID, blockEventFilterRegistry)
+			})
+		}
+	}
+}
 
-		block, err := core.ProcessBlock(blockData.BlockData, blockData.BlockResultsData, chainID)
+// processBlock parses the RPC data of a block and passes the parsed block events and transactions on to be indexed
+func (idxr *Indexer) processBlock(ctx context.Context, failedBlockHandler core.FailedBlockHandler, blockData core.IndexerBlockEventData, blockEventsDataChan chan *blockEventsDBData, txDataChan chan *dbData, chainID uint, blockEventFilterRegistry blockEventFilterRegistries) {
+	currentHeight := blockData.BlockData.Block.Height
+	if !idxr.rpcWorkers.fetched(currentHeight) {
+		config.Log.Warnf("Dropping block %d fetched again by replaced RPC workers", currentHeight)
+		return
+	}
+	idxr.heightStates.fetched(currentHeight)
+	// Blocks already fetched when they were skipped through the admin API
+	if idxr.adminController.Skipped(currentHeight) {
+		config.Log.Warnf("Skipping block %d as requested through the admin API", currentHeight)
+		return
+	}
+	config.Log.Infof("Parsing data for block %d", currentHeight)
+
+	block, err := core.ProcessBlock(blockData.BlockData, blockData.BlockResultsData, chainID)
+	if err != nil {
+		config.Log.Error("ProcessBlock: unhandled error", err)
+		failedBlockHandler(currentHeight, core.UnprocessableTxError, err)
+		err := dbTypes.UpsertFailedBlock(idxr.db, currentHeight, idxr.cfg.Probe.ChainID, idxr.cfg.Probe.ChainName, core.NewBlockFailure(models.ComponentProcessor, core.UnprocessableTxError, err))
 		if err != nil {
-			config.Log.Error("ProcessBlock: unhandled error", err)
-			failedBlockHandler(currentHeight, core.UnprocessableTxError, err)
-			err := dbTypes.UpsertFailedBlock(idxr.db, currentHeight, idxr.cfg.Probe.ChainID, idxr.cfg.Probe.ChainName)
+			config.Log.Fatal("Failed to insert failed block", err)
+		}
+		return
+	}
+	parserBlockContext := parsers.NewBlockContext(idxr.cl, blockData.BlockData)
+	parserBlockContext.ModuleVersions = idxr.blockModuleVersions(ctx, currentHeight)
+
+	if blockData.IndexBlockEvents && !blockData.BlockEventRequestsFailed {
+		config.Log.Info("Parsing block events")
+		parseStart := time.Now()
+		blockDBWrapper, err := core.ProcessRPCBlockResults(*indexer.cfg, block, blockData.BlockResultsData, indexer.customBeginBlockEventParserRegistry, indexer.customEndBlockEventParserRegistry, parserBlockContext)
+		idxr.timings.observe(stageParseBlockEvents, parseStart)
+		if err != nil {
+			config.Log.Errorf("Failed to process block events during block %d event processing, adding to failed block events table", currentHeight)
+			failedBlockHandler(currentHeight, core.FailedBlockEventHandling, err)
+			err := dbTypes.UpsertFailedEventBlock(idxr.db, currentHeight, idxr.cfg.Probe.ChainID, idxr.cfg.Probe.ChainName, core.NewBlockFailure(models.ComponentProcessor, core.FailedBlockEventHandling, err))
 			if err != nil {
-				config.Log.Fatal("Failed to insert failed block", err)
+				config.Log.Fatal("Failed to insert failed block event", err)
 			}
-			continue
-		}
+		} else {
+			config.Log.Infof("Finished parsing block event data for block %d", currentHeight)
 
-		if blockData.IndexBlockEvents && !blockData.BlockEventRequestsFailed {
-			config.Log.Info("Parsing block events")
-			blockDBWrapper, err := core.ProcessRPCBlockResults(*indexer.cfg, block, blockData.BlockResultsData, indexer.customBeginBlockEventParserRegistry, indexer.customEndBlockEventParserRegistry)
-			if err != nil {
-				config.Log.Errorf("Failed to process block events during block %d event processing, adding to failed block events table", currentHeight)
-				failedBlockHandler(currentHeight, core.FailedBlockEventHandling, err)
-				err := dbTypes.UpsertFailedEventBlock(idxr.db, currentHeight, idxr.cfg.Probe.ChainID, idxr.cfg.Probe.ChainName)
-				if err != nil {
-					config.Log.Fatal("Failed to insert failed block event", err)
-				}
-			} else {
-				config.Log.Infof("Finished parsing block event data for block %d", currentHeight)
+			var beginBlockFilterError error
+			var endBlockFilterError error
+			if blockEventFilterRegistry.beginBlockEventFilterRegistry != nil && blockEventFilterRegistry.beginBlockEventFilterRegistry.NumFilters() > 0 {
+				blockDBWrapper.BeginBlockEvents, beginBlockFilterError = core.FilterRPCBlockEvents(blockDBWrapper.BeginBlockEvents, *blockEventFilterRegistry.beginBlockEventFilterRegistry)
+			}
 
-				var beginBlockFilterError error
-				var endBlockFilterError error
-				if blockEventFilterRegistry.beginBlockEventFilterRegistry != nil && blockEventFilterRegistry.beginBlockEventFilterRegistry.NumFilters() > 0 {
-					blockDBWrapper.BeginBlockEvents, beginBlockFilterError = core.FilterRPCBlockEvents(blockDBWrapper.BeginBlockEvents, *blockEventFilterRegistry.beginBlockEventFilterRegistry)
-				}
+			if blockEventFilterRegistry.endBlockEventFilterRegistry != nil && blockEventFilterRegistry.endBlockEventFilterRegistry.NumFilters() > 0 {
+				blockDBWrapper.EndBlockEvents, endBlockFilterError = core.FilterRPCBlockEvents(blockDBWrapper.EndBlockEvents, *blockEventFilterRegistry.endBlockEventFilterRegistry)
+			}
 
-				if blockEventFilterRegistry.endBlockEventFilterRegistry != nil && blockEventFilterRegistry.endBlockEventFilterRegistry.NumFilters() > 0 {
-					blockDBWrapper.EndBlockEvents, endBlockFilterError = core.FilterRPCBlockEvents(blockDBWrapper.EndBlockEvents, *blockEventFilterRegistry.endBlockEventFilterRegistry)
+			if beginBlockFilterError == nil && endBlockFilterError == nil {
+				idxr.heightStates.partParsed(currentHeight)
+				select {
+				case blockEventsDataChan <- &blockEventsDBData{blockDBWrapper: blockDBWrapper}:
+				case <-ctx.Done():
+					return
 				}
-
-				if beginBlockFilterError == nil && endBlockFilterError == nil {
-					blockEventsDataChan <- &blockEventsDBData{
-						blockDBWrapper: blockDBWrapper,
-					}
-				} else {
-					config.Log.Errorf("Failed to filter block events during block %d event processing, adding to failed block events table. Begin blocker filter error %s. End blocker filter error %s", currentHeight, beginBlockFilterError, endBlockFilterError)
-					failedBlockHandler(currentHeight, core.FailedBlockEventHandling, err)
-					err := dbTypes.UpsertFailedEventBlock(idxr.db, currentHeight, idxr.cfg.Probe.ChainID, idxr.cfg.Probe.ChainName)
-					if err != nil {
-						config.Log.Fatal("Failed to insert failed block event", err)
-					}
+			} else {
+				config.Log.Errorf("Failed to filter block events during block %d event processing, adding to failed block events table. Begin blocker filter error %s. End blocker filter error %s", currentHeight, beginBlockFilterError, endBlockFilterError)
+				filterErr := fmt.Errorf("begin blocker filter error: %v, end blocker filter error: %v", beginBlockFilterError, endBlockFilterError)
+				failedBlockHandler(currentHeight, core.FailedBlockEventHandling, filterErr)
+				err := dbTypes.UpsertFailedEventBlock(idxr.db, currentHeight, idxr.cfg.Probe.ChainID, idxr.cfg.Probe.ChainName, core.NewBlockFailure(models.ComponentProcessor, core.FailedBlockEventHandling, filterErr))
+				if err != nil {
+					config.Log.Fatal("Failed to insert failed block event", err)
 				}
 			}
 		}
+	}
 
-		if blockData.IndexTransactions && !blockData.TxRequestsFailed {
-			config.Log.Info("Parsing transactions")
-			var txDBWrappers []dbTypes.TxDBWrapper
-			var err error
+	if blockData.IndexTransactions && !blockData.TxRequestsFailed {
+		config.Log.Info("Parsing transactions")
+		var txDBWrappers []dbTypes.TxDBWrapper
+		var failedTxs []models.FailedTx
+		var err error
+
+		parseStart := time.Now()
+		if blockData.GetTxsResponse != nil {
+			config.Log.Debug("Processing TXs from RPC TX Search response")
+			txDBWrappers, failedTxs, _, err = core.ProcessRPCTXs(idxr.cfg, idxr.db.WithContext(ctx), idxr.cl.Codec, idxr.messageTypeFilters, blockData.GetTxsResponse, indexer.customMessageParserRegistry, parserBlockContext)
+		} else if blockData.BlockResultsData != nil {
+			config.Log.Debug("Processing TXs from BlockResults search response")
+			txDBWrappers, failedTxs, _, err = core.ProcessRPCBlockByHeightTXs(idxr.cfg, idxr.db.WithContext(ctx), idxr.cl.Codec, idxr.messageTypeFilters, blockData.BlockData, blockData.BlockResultsData, indexer.customMessageParserRegistry, parserBlockContext)
+		}
+		idxr.timings.observe(stageParseTxs, parseStart)
 
-			if blockData.GetTxsResponse != nil {
-				config.Log.Debug("Processing TXs from RPC TX Search response")
-				txDBWrappers, _, err = core.ProcessRPCTXs(idxr.cfg, idxr.db, idxr.cl, idxr.messageTypeFilters, blockData.GetTxsResponse, indexer.customMessageParserRegistry)
-			} else if blockData.BlockResultsData != nil {
-				config.Log.Debug("Processing TXs from BlockResults search response")
-				txDBWrappers, _, err = core.ProcessRPCBlockByHeightTXs(idxr.cfg, idxr.db, idxr.cl, idxr.messageTypeFilters, blockData.BlockData, blockData.BlockResultsData, indexer.customMessageParserRegistry)
+		if err != nil && ctx.Err() != nil {
+			return
+		} else if err != nil {
+			config.Log.Error("ProcessRpcTxs: unhandled error", err)
+			failedBlockHandler(currentHeight, core.UnprocessableTxError, err)
+			err := dbTypes.UpsertFailedBlock(idxr.db, currentHeight, idxr.cfg.Probe.ChainID, idxr.cfg.Probe.ChainName, core.NewBlockFailure(models.ComponentProcessor, core.UnprocessableTxError, err))
+			if err != nil {
+				config.Log.Fatal("Failed to insert failed block", err)
 			}
+			return
+		}
 
+		var commit *dbTypes.BlockCommitDBWrapper
+		if idxr.cfg.Flags.IndexBlockCommits {
+			commit, err = core.ProcessBlockCommit(blockData.BlockData.Block, blockData.CommitValidators)
 			if err != nil {
-				config.Log.Error("ProcessRpcTxs: unhandled error", err)
+				config.Log.Error("ProcessBlockCommit: unhandled error", err)
 				failedBlockHandler(currentHeight, core.UnprocessableTxError, err)
-				err := dbTypes.UpsertFailedBlock(idxr.db, currentHeight, idxr.cfg.Probe.ChainID, idxr.cfg.Probe.ChainName)
+				err := dbTypes.UpsertFailedBlock(idxr.db, currentHeight, idxr.cfg.Probe.ChainID, idxr.cfg.Probe.ChainName, core.NewBlockFailure(models.ComponentProcessor, core.UnprocessableTxError, err))
 				if err != nil {
 					config.Log.Fatal("Failed to insert failed block", err)
 				}
-			} else {
-				txDataChan <- &dbData{
-					txDBWrappers: txDBWrappers,
-					block:        block,
-				}
+				return
+			}
+		}
+
+		evidence, err := core.ProcessBlockEvidence(blockData.BlockData.Block, blockData.BlockResultsData)
+		if err != nil {
+			config.Log.Error("ProcessBlockEvidence: unhandled error", err)
+			failedBlockHandler(currentHeight, core.UnprocessableTxError, err)
+			err := dbTypes.UpsertFailedBlock(idxr.db, currentHeight, idxr.cfg.Probe.ChainID, idxr.cfg.Probe.ChainName, core.NewBlockFailure(models.ComponentProcessor, core.UnprocessableTxError, err))
+			if err != nil {
+				config.Log.Fatal("Failed to insert failed block", err)
 			}
+			return
+		}
 
+		idxr.heightStates.partParsed(currentHeight)
+		select {
+		case txDataChan <- &dbData{txDBWrappers: txDBWrappers, failedTxs: failedTxs, block: block, commit: commit, evidence: evidence}:
+		case <-ctx.Done():
 		}
+
 	}
 }
 
@@ -476,52 +887,108 @@ func (idxr *Indexer) processBlocks(wg *sync.WaitGroup, failedBlockHandler core.F
 // if this is a dry run, we will simply empty the channel and track progress
 // otherwise we will index the data in the DB.
 // it will also read rewars data and index that.
-func (idxr *Indexer) doDBUpdates(wg *sync.WaitGroup, txDataChan chan *dbData, blockEventsDataChan chan *blockEventsDBData, dbChainID uint) {
+// The DB calls run with ctx, once it is done the block being written is rolled back and the updates stop.
+func (idxr *Indexer) doDBUpdates(ctx context.Context, wg *sync.WaitGroup, txDataChan chan *dbData, blockEventsDataChan chan *blockEventsDBData, dbChainID uint) {
 	blocksProcessed := 0
-	dbWrites := 0
-	dbReattempts := 0
 	timeStart := time.Now()
 	defer wg.Done()
+	db := idxr.db.WithContext(ctx)
+	failedBlockHandler := idxr.failedBlockHandler(ctx)
 
-	for {
-		// break out of loop once all channels are fully consumed
-		if txDataChan == nil && blockEventsDataChan == nil {
-			config.Log.Info("DB updates complete")
-			break
-		}
-
-		select {
-		// read tx data from the data chan
-		case data, ok := <-txDataChan:
-			if !ok {
-				txDataChan = nil
-				continue
-			}
-			dbWrites++
+	// indexTxData indexes the txes of a block and runs the steps that follow, written holds the block and txes when they
+	// were already written by a bulk write
+	indexTxData := func(data *dbData, written *dbTypes.BulkBlock) {
+		idxr.recoverBlock(data.block.Height, models.ComponentDBWriter, failedBlockHandler, dbTypes.UpsertFailedBlock, func() {
 			// While debugging we'll sometimes want to turn off INSERTS to the DB
 			// Note that this does not turn off certain reads or DB connections.
 			if !idxr.dryRun {
-				config.Log.Info(fmt.Sprintf("Indexing %v TXs from block %d", len(data.txDBWrappers), data.block.Height))
-				_, indexedDataset, err := dbTypes.IndexNewBlock(idxr.db, data.block, data.txDBWrappers, *idxr.cfg)
-				if err != nil {
-					// Do a single reattempt on failure
-					dbReattempts++
-					_, _, err = dbTypes.IndexNewBlock(idxr.db, data.block, data.txDBWrappers, *idxr.cfg)
-					if err != nil {
+				var indexedBlock models.Block
+				var indexedDataset []dbTypes.TxDBWrapper
+				if written != nil {
+					indexedBlock, indexedDataset = written.Block, written.Txs
+				} else {
+					config.Log.Info(fmt.Sprintf("Indexing %v TXs from block %d", len(data.txDBWrappers), data.block.Height))
+					// The in-flight block is written again after transient errors, e.g. while the database fails over
+					writeStart := time.Now()
+					err := idxr.retryDB(ctx, fmt.Sprintf("indexing block %d", data.block.Height), func() (err error) {
+						indexedBlock, indexedDataset, err = dbTypes.IndexNewBlock(db, data.block, data.txDBWrappers, data.failedTxs, *idxr.cfg, idxr.customMessageParserTrackers)
+						return err
+					})
+					if err != nil && ctx.Err() != nil {
+						return
+					} else if err != nil {
 						config.Log.Fatal(fmt.Sprintf("Error indexing block %v.", data.block.Height), err)
 					}
+					idxr.timings.observe(stageDBTxs, writeStart)
 				}
 
-				err = dbTypes.IndexCustomMessages(*idxr.cfg, idxr.db, idxr.dryRun, indexedDataset, idxr.customMessageParserTrackers)
+				writeStart := time.Now()
+				err := idxr.retryDB(ctx, fmt.Sprintf("indexing custom messages for block %d", data.block.Height), func() error {
+					return dbTypes.IndexCustomMessages(*idxr.cfg, db, idxr.dryRun, &indexedBlock, indexedDataset, idxr.customMessageParserTrackers)
+				})
 
-				if err != nil {
+				if err != nil && ctx.Err() != nil {
+					return
+				} else if err != nil {
 					config.Log.Fatal(fmt.Sprintf("Error indexing custom messages for block %d", data.block.Height), err)
 				}
+				idxr.timings.observe(stageDBCustomMessages, writeStart)
+
+				if data.commit != nil {
+					err = idxr.retryDB(ctx, fmt.Sprintf("indexing the commit of block %d", data.block.Height), func() error {
+						return dbTypes.IndexBlockCommit(db, indexedBlock, data.commit)
+					})
+					if err != nil && ctx.Err() != nil {
+						return
+					} else if err != nil {
+						config.Log.Fatal(fmt.Sprintf("Error indexing the commit of block %d", data.block.Height), err)
+					}
+				}
+
+				if len(data.evidence) != 0 {
+					err = idxr.retryDB(ctx, fmt.Sprintf("indexing the evidence of block %d", data.block.Height), func() error {
+						return dbTypes.IndexBlockEvidence(db, indexedBlock, data.evidence)
+					})
+					if err != nil && ctx.Err() != nil {
+						return
+					} else if err != nil {
+						config.Log.Fatal(fmt.Sprintf("Error indexing the evidence of block %d", data.block.Height), err)
+					}
+				}
+
+				if idxr.cfg.Flags.IndexConsensusMetrics {
+					err = idxr.retryDB(ctx, fmt.Sprintf("deriving the consensus metrics of block %d", data.block.Height), func() error {
+						return dbTypes.UpsertConsensusMetrics(db, indexedBlock)
+					})
+					if err != nil && ctx.Err() != nil {
+						return
+					} else if err != nil {
+						config.Log.Fatal(fmt.Sprintf("Error deriving the consensus metrics of block %d", data.block.Height), err)
+					}
+				}
+
+				// A block of an abandoned fork is indexed again from the node, so it is neither committed nor published
+				if idxr.cfg.Reorg.Enabled && idxr.rollbackReorg(ctx, db, dbChainID, indexedBlock) {
+					idxr.watchdog.blockDone()
+					return
+				}
 
 				config.Log.Info(fmt.Sprintf("Finished indexing %v TXs from block %d", len(data.txDBWrappers), data.block.Height))
+				data.txDBWrappers = indexedDataset
+
+				idxr.heightStates.partCommitted(data.block.Height)
+				metrics.AddBlockIndexed(idxr.cfg.Probe.ChainID)
+				idxr.viewRefresher.blockIndexed(idxr.db)
+				idxr.jobScheduler.BlockIndexed(ctx, data.block.Height)
 			} else {
 				config.Log.Info(fmt.Sprintf("Processing block %d (dry run, block data will not be stored in DB).", data.block.Height))
 			}
+			idxr.watchdog.blockDone()
+
+			if idxr.sinkDispatcher.Enabled() {
+				records := append([]sinks.Record{sinks.NewBlockRecord(idxr.cfg.Probe.ChainID, data.block)}, sinks.NewTxRecords(idxr.cfg.Probe.ChainID, data.block, data.txDBWrappers)...)
+				idxr.publishToSinks(ctx, records)
+			}
 
 			// Just measuring how many blocks/second we can process
 			if idxr.cfg.Base.BlockTimer > 0 {
@@ -531,32 +998,178 @@ func (idxr *Indexer) doDBUpdates(wg *sync.WaitGroup, txDataChan chan *dbData, bl
 					config.Log.Info(fmt.Sprintf("Processing %d blocks took %f seconds. %d total blocks have been processed.\n", idxr.cfg.Base.BlockTimer, totalTime.Seconds(), blocksProcessed))
 					timeStart = time.Now()
 				}
-				if float64(dbReattempts)/float64(dbWrites) > .1 {
-					config.Log.Fatalf("More than 10%% of the last %v DB writes have failed.", dbWrites)
+			}
+		})
+	}
+
+	for {
+		// break out of loop once all channels are fully consumed
+		if txDataChan == nil && blockEventsDataChan == nil {
+			config.Log.Info("DB updates complete")
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			config.Log.Info("DB updates interrupted")
+			return
+		// read tx data from the data chan
+		case data, ok := <-txDataChan:
+			if !ok {
+				txDataChan = nil
+				continue
+			}
+			metrics.SetChannelDepth("parsed_txs", len(txDataChan))
+
+			if idxr.cfg.Base.BulkCopy && !idxr.dryRun {
+				batch := []*dbData{data}
+				for len(batch) < int(idxr.cfg.Base.BulkCopyBlocks) && len(txDataChan) > 0 {
+					batch = append(batch, <-txDataChan)
+				}
+
+				written := idxr.writeTxBatch(ctx, db, batch)
+				if ctx.Err() != nil {
+					return
+				}
+				for i, data := range batch {
+					if written == nil {
+						indexTxData(data, nil)
+					} else {
+						indexTxData(data, &written[i])
+					}
 				}
+				continue
 			}
+
+			indexTxData(data, nil)
 		case eventData, ok := <-blockEventsDataChan:
 			if !ok {
 				blockEventsDataChan = nil
 				continue
 			}
-			dbWrites++
-			numEvents := len(eventData.blockDBWrapper.BeginBlockEvents) + len(eventData.blockDBWrapper.EndBlockEvents)
-			config.Log.Info(fmt.Sprintf("Indexing %v Block Events from block %d", numEvents, eventData.blockDBWrapper.Block.Height))
-			identifierLoggingString := fmt.Sprintf("block %d", eventData.blockDBWrapper.Block.Height)
+			metrics.SetChannelDepth("parsed_block_events", len(blockEventsDataChan))
+			idxr.recoverBlock(eventData.blockDBWrapper.Block.Height, models.ComponentDBWriter, failedBlockHandler, dbTypes.UpsertFailedEventBlock, func() {
+				numEvents := len(eventData.blockDBWrapper.BeginBlockEvents) + len(eventData.blockDBWrapper.EndBlockEvents)
+				config.Log.Info(fmt.Sprintf("Indexing %v Block Events from block %d", numEvents, eventData.blockDBWrapper.Block.Height))
+				identifierLoggingString := fmt.Sprintf("block %d", eventData.blockDBWrapper.Block.Height)
+
+				var indexedDataset *dbTypes.BlockDBWrapper
+				writeStart := time.Now()
+				err := idxr.retryDB(ctx, "indexing block events for "+identifierLoggingString, func() (err error) {
+					indexedDataset, err = dbTypes.IndexBlockEvents(db, idxr.dryRun, eventData.blockDBWrapper, identifierLoggingString, *idxr.cfg, idxr.customBeginBlockParserTrackers, idxr.customEndBlockParserTrackers)
+					return err
+				})
+				if err != nil && ctx.Err() != nil {
+					return
+				} else if err != nil {
+					config.Log.Fatal(fmt.Sprintf("Error indexing block events for %s.", identifierLoggingString), err)
+				}
+				idxr.timings.observe(stageDBBlockEvents, writeStart)
 
-			indexedDataset, err := dbTypes.IndexBlockEvents(idxr.db, idxr.dryRun, eventData.blockDBWrapper, identifierLoggingString)
-			if err != nil {
-				config.Log.Fatal(fmt.Sprintf("Error indexing block events for %s.", identifierLoggingString), err)
-			}
+				writeStart = time.Now()
+				err = idxr.retryDB(ctx, "indexing custom block events for "+identifierLoggingString, func() error {
+					return dbTypes.IndexCustomBlockEvents(*idxr.cfg, db, idxr.dryRun, indexedDataset, identifierLoggingString, idxr.customBeginBlockParserTrackers, idxr.customEndBlockParserTrackers)
+				})
 
-			err = dbTypes.IndexCustomBlockEvents(*idxr.cfg, idxr.db, idxr.dryRun, indexedDataset, identifierLoggingString, idxr.customBeginBlockParserTrackers, idxr.customEndBlockParserTrackers)
+				if err != nil && ctx.Err() != nil {
+					return
+				} else if err != nil {
+					config.Log.Fatal(fmt.Sprintf("Error indexing custom block events for %s.", identifierLoggingString), err)
+				}
+				idxr.timings.observe(stageDBCustomBlockEvents, writeStart)
 
-			if err != nil {
-				config.Log.Fatal(fmt.Sprintf("Error indexing custom block events for %s.", identifierLoggingString), err)
-			}
+				// The tx path checks the block when transactions are indexed
+				if idxr.cfg.Reorg.Enabled && !idxr.dryRun && !idxr.cfg.Base.TransactionIndexingEnabled && idxr.rollbackReorg(ctx, db, dbChainID, *indexedDataset.Block) {
+					idxr.watchdog.blockDone()
+					return
+				}
+
+				config.Log.Info(fmt.Sprintf("Finished indexing %v Block Events from block %d", numEvents, eventData.blockDBWrapper.Block.Height))
+				idxr.heightStates.partCommitted(eventData.blockDBWrapper.Block.Height)
+				idxr.watchdog.blockDone()
+
+				// Blocks are counted on the tx path when transactions are indexed
+				if !idxr.dryRun && !idxr.cfg.Base.TransactionIndexingEnabled {
+					metrics.AddBlockIndexed(idxr.cfg.Probe.ChainID)
+					idxr.viewRefresher.blockIndexed(idxr.db)
+					idxr.jobScheduler.BlockIndexed(ctx, eventData.blockDBWrapper.Block.Height)
+				}
+
+				if idxr.sinkDispatcher.Enabled() {
+					records := sinks.NewBlockEventRecords(idxr.cfg.Probe.ChainID, indexedDataset)
+					// The tx path already publishes the block record when transactions are indexed
+					if !idxr.cfg.Base.TransactionIndexingEnabled {
+						records = append([]sinks.Record{sinks.NewBlockRecord(idxr.cfg.Probe.ChainID, *indexedDataset.Block)}, records...)
+					}
+					idxr.publishToSinks(ctx, records)
+				}
+			})
+		}
+	}
+}
 
-			config.Log.Info(fmt.Sprintf("Finished indexing %v Block Events from block %d", numEvents, eventData.blockDBWrapper.Block.Height))
+// retryDB retries a block write on transient DB errors as configured, the block stays in flight until it is written or
+// ctx is done
+func (idxr *Indexer) retryDB(ctx context.Context, description string, write func() error) error {
+	return dbTypes.RetryTransient(ctx, idxr.cfg.Base.DBRetryAttempts, idxr.cfg.Base.DBRetryMaxWait, description, write)
+}
+
+// writeTxBatch writes the txes of the blocks of the batch together with bulk COPY statements and returns the written
+// blocks in the order of the batch. It returns nil when the write failed for another reason than ctx being done, the
+// blocks are then indexed one by one, so the failure is handled for the block causing it.
+func (idxr *Indexer) writeTxBatch(ctx context.Context, db *gorm.DB, batch []*dbData) (written []dbTypes.BulkBlock) {
+	defer func() {
+		if r := recover(); r != nil {
+			config.Log.Errorf("Recovered from a panic writing blocks %d to %d in bulk, indexing them one by one: %v\n%s", batch[0].block.Height, batch[len(batch)-1].block.Height, r, debug.Stack())
+			written = nil
 		}
+	}()
+
+	config.Log.Info(fmt.Sprintf("Indexing the TXs of blocks %d to %d in bulk", batch[0].block.Height, batch[len(batch)-1].block.Height))
+	writeStart := time.Now()
+	err := idxr.retryDB(ctx, fmt.Sprintf("indexing blocks %d to %d in bulk", batch[0].block.Height, batch[len(batch)-1].block.Height), func() (err error) {
+		blocks := make([]dbTypes.BulkBlock, len(batch))
+		for i, data := range batch {
+			blocks[i] = dbTypes.BulkBlock{Block: data.block, Txs: data.txDBWrappers, FailedTxs: data.failedTxs}
+		}
+		written, err = dbTypes.IndexNewBlocksBulk(db, blocks, *idxr.cfg, idxr.customMessageParserTrackers)
+		return err
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			config.Log.Error(fmt.Sprintf("Error indexing blocks %d to %d in bulk, indexing them one by one.", batch[0].block.Height, batch[len(batch)-1].block.Height), err)
+		}
+		return nil
+	}
+	idxr.timings.observe(stageDBTxs, writeStart)
+
+	return written
+}
+
+// recoverBlock runs the processing of a block in component. A panic is recorded as a failure of the block with
+// recordFailure, and the pipeline carries on with the next block, so one malformed block cannot stop a backfill.
+func (idxr *Indexer) recoverBlock(height int64, component models.FailureComponent, failedBlockHandler core.FailedBlockHandler, recordFailure func(db *gorm.DB, blockHeight int64, chainID string, chainName string, failure models.BlockFailure) error, process func()) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		config.Log.Errorf("Recovered from a panic processing block %d: %v\n%s", height, r, debug.Stack())
+		err := fmt.Errorf("panic: %v", r)
+		failedBlockHandler(height, core.BlockPanic, err)
+		if err := recordFailure(idxr.db, height, idxr.cfg.Probe.ChainID, idxr.cfg.Probe.ChainName, core.NewBlockFailure(component, core.BlockPanic, err)); err != nil {
+			config.Log.Fatal("Failed to insert failed block", err)
+		}
+	}()
+
+	process()
+}
+
+// newBlockQueueConsumer connects to the message queue the blocks to index are consumed from
+func newBlockQueueConsumer(cfg config.IndexConfig) (blockqueue.Consumer, error) {
+	if cfg.Queue.Kafka.Enabled {
+		return blockqueue.NewKafkaConsumer(cfg.Queue.Kafka.Brokers, cfg.Queue.Kafka.Topic, cfg.Queue.Kafka.GroupID), nil
 	}
+	return blockqueue.NewNATSConsumer(cfg.Queue.NATS.URL, cfg.Queue.NATS.Subject, cfg.Queue.NATS.QueueGroup)
 }
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/core"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/parsers"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type RecoverBlockTestSuite struct {
+	suite.Suite
+}
+
+// panickingParser panics when asked for the module versions it supports, outside of the isolation of its parse and
+// index steps
+type panickingParser struct{}
+
+func (panickingParser) ModuleVersions() (string, uint64, uint64) {
+	panic("parser is broken")
+}
+
+func (suite *RecoverBlockTestSuite) TestPanicRecordsBlockAndKeepsProcessing() {
+	idxr := &Indexer{cfg: &config.IndexConfig{}}
+	idxr.cfg.Probe.ChainID = "testchain-1"
+
+	handled := map[int64]core.BlockProcessingFailure{}
+	failedBlockHandler := func(height int64, code core.BlockProcessingFailure, err error) {
+		handled[height] = code
+	}
+	failures := map[int64]models.BlockFailure{}
+	recordFailure := func(db *gorm.DB, blockHeight int64, chainID string, chainName string, failure models.BlockFailure) error {
+		suite.Require().Equal("testchain-1", chainID)
+		failures[blockHeight] = failure
+		return nil
+	}
+
+	// The blocks are processed one after another like in processBlocks, the parser of block 2 panics
+	var processed []int64
+	for height := int64(1); height <= 3; height++ {
+		idxr.recoverBlock(height, models.ComponentProcessor, failedBlockHandler, recordFailure, func() {
+			if height == 2 {
+				parsers.SupportsModuleVersions(panickingParser{}, map[string]uint64{"bank": 1})
+			}
+			processed = append(processed, height)
+		})
+	}
+
+	suite.Require().Equal([]int64{1, 3}, processed)
+	suite.Require().Equal(map[int64]core.BlockProcessingFailure{2: core.BlockPanic}, handled)
+	suite.Require().Len(failures, 1)
+	suite.Require().Equal(models.ComponentProcessor, failures[2].Component)
+	suite.Require().Equal(models.FailurePanic, failures[2].ErrorCode)
+	suite.Require().Contains(failures[2].ErrorMessage, "parser is broken")
+}
+
+func TestRecoverBlockTestSuite(t *testing.T) {
+	suite.Run(t, new(RecoverBlockTestSuite))
+}
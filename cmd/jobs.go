@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/jobs"
+	"gorm.io/gorm"
+)
+
+// setupJobs creates the scheduler of the SQL jobs declared in the jobs file and the registered Go jobs, nil if there
+// are none
+func setupJobs(conf *config.IndexConfig, db *gorm.DB, registered []jobs.Job) (*jobs.Scheduler, error) {
+	aggregationJobs := append([]jobs.Job{}, registered...)
+
+	if conf.Jobs.File != "" {
+		declared, err := config.LoadAggregationJobs(conf.Jobs.File)
+		if err != nil {
+			return nil, err
+		}
+		for _, job := range declared {
+			aggregationJobs = append(aggregationJobs, jobs.SQLJob(job))
+		}
+	}
+
+	if len(aggregationJobs) == 0 {
+		return nil, nil
+	}
+
+	return jobs.NewScheduler(db, conf.Probe.ChainID, aggregationJobs)
+}
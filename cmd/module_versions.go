@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/core"
+)
+
+// trackModuleVersions looks up the module versions of the indexed range, so the custom parsers are selected by the
+// module versions of each block. Failing to look them up is not fatal, the parsers then run for every block.
+func (idxr *Indexer) trackModuleVersions(ctx context.Context, chainID uint) {
+	tracker, err := core.NewModuleVersionTracker(idxr.db, idxr.rpcClient, chainID)
+	if err != nil {
+		config.Log.Warnf("Failed to load the recorded module versions, custom parsers run regardless of module versions: %v", err)
+		return
+	}
+
+	start, end, err := idxr.moduleVersionRange(ctx)
+	if err == nil {
+		err = tracker.Sync(ctx, start, end)
+	}
+	if err != nil {
+		config.Log.Warnf("Failed to look up the module versions, custom parsers run regardless of module versions: %v", err)
+		return
+	}
+
+	config.Log.Infof("Looked up the module versions from height %d to %d", start, end)
+	idxr.moduleVersions = tracker
+}
+
+// moduleVersionRange is the range of heights to look up the module versions of, up to the latest height when the
+// indexer does not stop
+func (idxr *Indexer) moduleVersionRange(ctx context.Context) (int64, int64, error) {
+	start := idxr.cfg.Base.StartBlock
+	if start < 1 {
+		start = 1
+	}
+	end := idxr.cfg.Base.EndBlock
+	if end < start {
+		status, err := idxr.rpcClient.Status(ctx)
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to query the latest height: %w", err)
+		}
+		end = status.SyncInfo.LatestBlockHeight
+	}
+	if end < start {
+		end = start
+	}
+	return start, end, nil
+}
+
+// blockModuleVersions returns the module versions at the height, nil when they are not tracked or can't be looked up
+func (idxr *Indexer) blockModuleVersions(ctx context.Context, height int64) map[string]uint64 {
+	if idxr.moduleVersions == nil {
+		return nil
+	}
+
+	versions, err := idxr.moduleVersions.At(ctx, height)
+	if err != nil {
+		config.Log.Warnf("Failed to look up the module versions of block %d, custom parsers run regardless of module versions: %v", height, err)
+		return nil
+	}
+	return versions
+}
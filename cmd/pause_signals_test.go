@@ -0,0 +1,70 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/admin"
+	"github.com/stretchr/testify/suite"
+)
+
+type PauseSignalsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *PauseSignalsTestSuite) TestPauseAndResume() {
+	// Keeps the default action of the signals, which terminates the process, from running before the indexer listens
+	guard := make(chan os.Signal, 10)
+	signal.Notify(guard, pauseSignal, resumeSignal)
+	defer signal.Stop(guard)
+
+	idxr := &Indexer{adminController: admin.NewController()}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		idxr.listenPauseSignals(stop)
+		close(done)
+	}()
+
+	// The signal is sent again until the indexer has registered for it
+	send := func(sig os.Signal) {
+		suite.Require().NoError(syscall.Kill(os.Getpid(), sig.(syscall.Signal)))
+	}
+	suite.Require().Eventually(func() bool {
+		send(pauseSignal)
+		return idxr.adminController.Paused()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	select {
+	case <-idxr.adminController.Resumed():
+		suite.FailNow("blocks are not held while indexing is paused")
+	default:
+	}
+
+	suite.Require().Eventually(func() bool {
+		send(resumeSignal)
+		return !idxr.adminController.Paused()
+	}, 5*time.Second, 10*time.Millisecond)
+
+	select {
+	case <-idxr.adminController.Resumed():
+	default:
+		suite.FailNow("blocks are held after indexing resumed")
+	}
+
+	close(stop)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		suite.FailNow("the indexer did not stop listening for the pause signals")
+	}
+}
+
+func TestPauseSignalsTestSuite(t *testing.T) {
+	suite.Run(t, new(PauseSignalsTestSuite))
+}
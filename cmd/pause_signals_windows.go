@@ -0,0 +1,6 @@
+package cmd
+
+import "os"
+
+// Windows has no user signals, indexing is only paused through the admin API
+var pauseSignal, resumeSignal os.Signal
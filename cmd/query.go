@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/parsers/wasm"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+func init() {
+	config.SetupLogFlags(&server.cfg.Log, queryCmd)
+	config.SetupDatabaseFlags(&server.cfg.Database, queryCmd)
+	config.SetupDatabaseChainFlag(&server.cfg.Database, queryCmd)
+	config.SetupServeSpecificFlags(server.cfg, queryCmd)
+
+	rootCmd.AddCommand(queryCmd)
+}
+
+var queryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "Serves a GraphQL API over the indexed database.",
+	Long: `Serves the GraphQL API of the serve command on graphql.address without Arrow Flight and the REST API. It
+	queries the blocks, transactions, messages and events stored by the index command by height range, message type,
+	address and event attributes, and the tables of the custom parsers registered with RegisterCustomModels and of the
+	built-in wasm parser as datasets. The auth, rate-limit, cdc and metrics settings of serve apply.`,
+	PreRunE: setupQuery,
+	Run:     serve,
+}
+
+func setupQuery(cmd *cobra.Command, args []string) error {
+	overrides := map[string]string{
+		"flight.enabled":  "false",
+		"rest.enabled":    "false",
+		"graphql.enabled": "true",
+	}
+	// Flags set here are not overridden with the config file values
+	for name, value := range overrides {
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("failed to override %s: %w", name, err)
+		}
+	}
+
+	return setupServe(cmd, args)
+}
+
+// datasetTables returns the tables of the custom models and of the built-in parsers that exist in the database, which
+// the query APIs expose as datasets
+func datasetTables(db *gorm.DB) []string {
+	models := append(append([]any{}, indexer.customModels...), wasm.Models()...)
+
+	tables := make([]string, 0, len(models))
+	for _, model := range models {
+		if db.Migrator().HasTable(model) {
+			tables = append(tables, dbTypes.TableName(db, model))
+		}
+	}
+	return tables
+}
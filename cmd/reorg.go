@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/blockqueue"
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/core"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// rollbackReorg checks that the block just written fits the indexed blocks below and above it. When the chain
+// reorganized, the heights indexed from the abandoned fork are rolled back and enqueued to be indexed again from the
+// node. It runs in the DB writer after each write, so the blocks written before are seen whatever order the workers
+// fetched them in, and no write interleaves with the rollback. It returns whether the block itself was rolled back, it
+// is then neither committed nor published.
+func (idxr *Indexer) rollbackReorg(ctx context.Context, db *gorm.DB, chainID uint, block models.Block) (rolledBack bool) {
+	indexed := func(height int64) (string, string, error) {
+		return dbTypes.GetBlockHashes(db, chainID, height)
+	}
+	forks, err := core.FindReorg(ctx, indexed, idxr.rpcClient, block, idxr.cfg.Reorg.MaxDepth)
+	if err != nil {
+		if ctx.Err() == nil {
+			config.Log.Warnf("Failed to check block %d for a reorg: %v", block.Height, err)
+		}
+		return false
+	}
+
+	for _, fork := range forks {
+		config.Log.Warnf("Block %d does not match the indexed blocks next to it, the chain reorganized. Rolling back the heights %d to %d to index them again.", block.Height, fork.Start, fork.End)
+
+		var deleted int64
+		err = idxr.retryDB(ctx, fmt.Sprintf("rolling back the heights %d to %d", fork.Start, fork.End), func() (err error) {
+			deleted, err = dbTypes.RollbackBlocks(db, chainID, fork.Start, fork.End, idxr.cfg.Ledger.Rollups)
+			return err
+		})
+		if err != nil && ctx.Err() != nil {
+			return rolledBack
+		} else if err != nil {
+			config.Log.Fatal(fmt.Sprintf("Error rolling back the heights %d to %d", fork.Start, fork.End), err)
+		}
+		config.Log.Infof("Rolled back %d blocks of the abandoned fork", deleted)
+		idxr.heightStates.rolledBack(fork.Start, fork.End)
+		if block.Height >= fork.Start && block.Height <= fork.End {
+			rolledBack = true
+		}
+
+		if err := idxr.adminController.Enqueue(blockqueue.Request{StartHeight: fork.Start, EndHeight: fork.End}); err != nil {
+			config.Log.Errorf("Failed to enqueue the rolled back heights %d to %d, index them again with base.start-block and base.end-block: %v", fork.Start, fork.End, err)
+		}
+	}
+	return rolledBack
+}
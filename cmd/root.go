@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/DefiantLabs/cosmos-indexer/config"
 	"github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
@@ -31,6 +33,13 @@ func Execute() error {
 	return rootCmd.Execute()
 }
 
+// ExecuteContext executes the root command with args instead of the process arguments until ctx is done, e.g. to run
+// the indexer in-process from integration tests.
+func ExecuteContext(ctx context.Context, args []string) error {
+	rootCmd.SetArgs(args)
+	return rootCmd.ExecuteContext(ctx)
+}
+
 func init() {
 	cobra.OnInitialize(getViperConfig)
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.cosmos-indexer/config.yaml)")
@@ -105,8 +114,19 @@ func setupLogger(logLevel string, logPath string, prettyLogging bool) {
 	config.DoConfigureLogger(logPath, logLevel, prettyLogging)
 }
 
-func connectToDBAndMigrate(dbConfig config.Database) (*gorm.DB, error) {
-	database, err := db.PostgresDbConnect(dbConfig.Host, dbConfig.Port, dbConfig.Database, dbConfig.User, dbConfig.Password, strings.ToLower(dbConfig.LogLevel))
+// connectToDBAndMigrate connects to the database, in the schema of the chain if every chain has its own schema
+func connectToDBAndMigrate(dbConfig config.Database, chainID string) (*gorm.DB, error) {
+	schema := dbConfig.Schema
+	if dbConfig.SchemaPerChain {
+		schema = db.ChainSchema(chainID)
+	}
+
+	database, err := db.PostgresDbConnect(dbConfig.Host, dbConfig.Port, dbConfig.Database, dbConfig.User, dbConfig.Password, strings.ToLower(dbConfig.LogLevel), db.ConnectOptions{
+		SearchPath:     schema,
+		Naming:         db.TableNaming(dbConfig.TablePrefix, dbConfig.SingularTables, dbConfig.TableNames),
+		IsolationLevel: strings.ReplaceAll(dbConfig.IsolationLevel, "-", " "),
+		LockTimeout:    dbConfig.LockTimeout,
+	})
 	if err != nil {
 		config.Log.Fatal("Could not establish connection to the database", err)
 	}
@@ -116,10 +136,29 @@ func connectToDBAndMigrate(dbConfig config.Database) (*gorm.DB, error) {
 	sqldb.SetMaxOpenConns(100)
 	sqldb.SetConnMaxLifetime(time.Hour)
 
+	if dbConfig.Compression {
+		models.SetCompression(dbConfig.CompressionMinSize)
+	}
+
 	err = db.MigrateModels(database)
 	if err != nil {
 		config.Log.Error("Error running DB migrations", err)
 	}
 
+	if err == nil && dbConfig.TimescaleDB {
+		var aggregates []config.ContinuousAggregate
+		if dbConfig.TimescaleAggregatesFile != "" {
+			aggregates, err = config.LoadContinuousAggregates(dbConfig.TimescaleAggregatesFile)
+			if err != nil {
+				config.Log.Fatal("Failed to load the continuous aggregates", err)
+			}
+		}
+
+		err = db.MigrateTimescale(database, aggregates)
+		if err != nil {
+			config.Log.Error("Error running TimescaleDB migrations", err)
+		}
+	}
+
 	return database, err
 }
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/arrowflight"
+	"github.com/DefiantLabs/cosmos-indexer/auth"
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/eventbus"
+	"github.com/DefiantLabs/cosmos-indexer/graphqlapi"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
+	"github.com/DefiantLabs/cosmos-indexer/query"
+	"github.com/DefiantLabs/cosmos-indexer/ratelimit"
+	"github.com/DefiantLabs/cosmos-indexer/restapi"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+type Server struct {
+	cfg           *config.ServeConfig
+	db            *gorm.DB
+	authenticator *auth.Authenticator
+	limiter       *ratelimit.Limiter
+}
+
+var server Server
+
+func init() {
+	server.cfg = &config.ServeConfig{}
+	config.SetupLogFlags(&server.cfg.Log, serveCmd)
+	config.SetupDatabaseFlags(&server.cfg.Database, serveCmd)
+	config.SetupDatabaseChainFlag(&server.cfg.Database, serveCmd)
+	config.SetupServeSpecificFlags(server.cfg, serveCmd)
+
+	rootCmd.AddCommand(serveCmd)
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serves the indexed data to clients.",
+	Long: `Serves the data indexed by the index command from the database. The indexed datasets are exposed
+	over Arrow Flight, so analytical clients can pull them as columnar record batches, and over a REST query API
+	described by the OpenAPI spec it serves at /openapi.json. With streaming enabled, WebSocket clients of the REST
+	API are pushed the blocks, transactions and events the indexer commits.`,
+	PreRunE: setupServe,
+	Run:     serve,
+}
+
+func setupServe(cmd *cobra.Command, args []string) error {
+	bindFlags(cmd, viperConf)
+
+	err := server.cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	ignoredKeys := config.CheckSuperfluousServeKeys(viperConf.AllKeys())
+
+	if len(ignoredKeys) > 0 {
+		config.Log.Warnf("Warning, the following invalid keys will be ignored: %v", ignoredKeys)
+	}
+
+	setupLogger(server.cfg.Log.Level, server.cfg.Log.Path, server.cfg.Log.Pretty)
+
+	if server.cfg.Auth.Enabled {
+		server.authenticator, err = setupAuth(server.cfg.Auth)
+		if err != nil {
+			return err
+		}
+	}
+
+	if server.cfg.RateLimit.Enabled {
+		limit := ratelimit.Limit{Rate: server.cfg.RateLimit.Rate, Burst: server.cfg.RateLimit.Burst}
+		var trustedProxies int
+		if server.cfg.RateLimit.TrustForwardedFor {
+			trustedProxies = server.cfg.RateLimit.TrustedProxies
+		}
+		server.limiter = ratelimit.New(limit, trustedProxies)
+	}
+
+	db, err := connectToDBAndMigrate(server.cfg.Database, server.cfg.Database.ChainID)
+	if err != nil {
+		config.Log.Fatal("Could not establish connection to the database", err)
+	}
+
+	server.db = db
+
+	return nil
+}
+
+func serve(cmd *cobra.Command, args []string) {
+	dbConn, err := server.db.DB()
+	if err != nil {
+		config.Log.Fatal("Failed to connect to DB", err)
+	}
+	defer dbConn.Close()
+
+	errChan := make(chan error, 1)
+
+	if server.cfg.Metrics.Enabled {
+		metricsServer := metrics.StartServer(server.cfg.Metrics.Port)
+		defer metricsServer.Close()
+	}
+
+	if server.cfg.Flight.Enabled {
+		flightServer, err := arrowflight.NewServer(server.cfg.Flight.Address, arrowflight.NewService(server.db, int(server.cfg.Flight.BatchSize)))
+		if err != nil {
+			config.Log.Fatal("Failed to start the Arrow Flight server", err)
+		}
+		defer flightServer.Shutdown()
+
+		config.Log.Infof("Serving Arrow Flight on %s", flightServer.Addr())
+		go func() {
+			errChan <- flightServer.Serve()
+		}()
+	}
+
+	client := query.New(server.db).WithDatasets(datasetTables(server.db))
+
+	// The servers pushing newly indexed data share the CDC notifications of a single listening connection
+	var events *eventbus.Bus[db.CDCEvent]
+	if server.cfg.ListensForCDC() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		events = eventbus.New[db.CDCEvent]()
+		go listenCDC(ctx, events)
+	}
+
+	if server.cfg.REST.Enabled {
+		var streamEvents *eventbus.Bus[db.CDCEvent]
+		if server.cfg.REST.Stream {
+			streamEvents = events
+		}
+
+		restServer, err := restapi.NewServer(server.cfg.REST.Address, client, streamEvents, server.authenticator, server.limiter)
+		if err != nil {
+			config.Log.Fatal("Failed to start the REST API server", err)
+		}
+		defer restServer.Shutdown()
+
+		config.Log.Infof("Serving the REST API on %s", restServer.Addr())
+		go func() {
+			errChan <- restServer.Serve()
+		}()
+	}
+
+	if server.cfg.GraphQL.Enabled {
+		graphQLServer, err := graphqlapi.NewServer(server.cfg.GraphQL.Address, client, events, server.authenticator, server.limiter)
+		if err != nil {
+			config.Log.Fatal("Failed to start the GraphQL server", err)
+		}
+		defer graphQLServer.Shutdown()
+
+		config.Log.Infof("Serving the GraphQL API on %s", graphQLServer.Addr())
+		go func() {
+			errChan <- graphQLServer.Serve()
+		}()
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	select {
+	case err := <-errChan:
+		if err != nil {
+			config.Log.Error("Server stopped", err)
+		}
+	case sig := <-signals:
+		config.Log.Infof("Received %v, shutting down", sig)
+	}
+}
+
+// setupAuth loads the API keys and JWT verification keys the API servers accept
+func setupAuth(conf config.APIAuth) (*auth.Authenticator, error) {
+	options := auth.Options{
+		JWTSecret:   []byte(conf.JWTSecret),
+		JWTIssuer:   conf.JWTIssuer,
+		JWTAudience: conf.JWTAudience,
+	}
+
+	if conf.KeysFile != "" {
+		keys, err := config.LoadAPIKeys(conf.KeysFile)
+		if err != nil {
+			return nil, err
+		}
+		options.Keys = keys
+		config.Log.Infof("Loaded %d API keys", len(keys))
+	}
+
+	if conf.JWTPublicKeyFile != "" {
+		data, err := os.ReadFile(conf.JWTPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		options.JWTPublicKey, err = auth.ParseRSAPublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth.jwt-public-key-file: %w", err)
+		}
+	}
+
+	return auth.New(options), nil
+}
+
+// listenCDC publishes the CDC notifications of the indexer on the bus until the context is done, reconnecting when
+// the listening connection fails
+func listenCDC(ctx context.Context, events *eventbus.Bus[db.CDCEvent]) {
+	for {
+		err := db.ListenCDC(ctx, server.db, server.cfg.CDC.Channel, events.Publish)
+		if ctx.Err() != nil {
+			return
+		}
+		config.Log.Error("Stopped listening for CDC notifications, retrying in 5 seconds", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/core"
+	"github.com/DefiantLabs/cosmos-indexer/sinks"
+	"gorm.io/gorm"
+)
+
+// Builds the sink dispatcher from the sink sections of the index config
+func setupSinks(conf *config.IndexConfig, db *gorm.DB) (*sinks.Dispatcher, error) {
+	var enabledSinks []sinks.Sink
+
+	if conf.Sinks.Redis.Enabled {
+		redisSink, err := sinks.NewRedisSink(sinks.RedisConfig{
+			Address:      conf.Sinks.Redis.Address,
+			Username:     conf.Sinks.Redis.Username,
+			Password:     conf.Sinks.Redis.Password,
+			DB:           conf.Sinks.Redis.DB,
+			KeyPrefix:    conf.Sinks.Redis.KeyPrefix,
+			Encoding:     conf.Sinks.Redis.Encoding,
+			PubSub:       conf.Sinks.Redis.PubSub,
+			Streams:      conf.Sinks.Redis.Streams,
+			StreamMaxLen: conf.Sinks.Redis.StreamMaxLen,
+		})
+		if err != nil {
+			return nil, err
+		}
+		enabledSinks = append(enabledSinks, redisSink)
+	}
+
+	if conf.Sinks.AMQP.Enabled {
+		amqpSink, err := sinks.NewAMQPSink(sinks.AMQPConfig{
+			URL:             conf.Sinks.AMQP.URL,
+			Exchange:        conf.Sinks.AMQP.Exchange,
+			ExchangeType:    conf.Sinks.AMQP.ExchangeType,
+			DeclareExchange: conf.Sinks.AMQP.DeclareExchange,
+			Exchanges: map[sinks.RecordType]string{
+				sinks.RecordTypeBlock:      conf.Sinks.AMQP.BlockExchange,
+				sinks.RecordTypeTx:         conf.Sinks.AMQP.TxExchange,
+				sinks.RecordTypeBlockEvent: conf.Sinks.AMQP.BlockEventExchange,
+			},
+			RoutingKeys: map[sinks.RecordType]string{
+				sinks.RecordTypeBlock:      conf.Sinks.AMQP.BlockRoutingKey,
+				sinks.RecordTypeTx:         conf.Sinks.AMQP.TxRoutingKey,
+				sinks.RecordTypeBlockEvent: conf.Sinks.AMQP.BlockEventRoutingKey,
+			},
+			Encoding: conf.Sinks.AMQP.Encoding,
+			Confirms: conf.Sinks.AMQP.PublisherConfirms,
+		})
+		if err != nil {
+			return nil, err
+		}
+		enabledSinks = append(enabledSinks, amqpSink)
+	}
+
+	if conf.Sinks.Kafka.Enabled {
+		kafkaSink, err := sinks.NewKafkaSink(sinks.KafkaConfig{
+			Brokers:      strings.Split(conf.Sinks.Kafka.Brokers, ","),
+			TopicPrefix:  conf.Sinks.Kafka.TopicPrefix,
+			Encoding:     conf.Sinks.Kafka.Encoding,
+			Compression:  conf.Sinks.Kafka.Compression,
+			CreateTopics: conf.Sinks.Kafka.CreateTopics,
+		})
+		if err != nil {
+			return nil, err
+		}
+		enabledSinks = append(enabledSinks, kafkaSink)
+	}
+
+	if conf.Sinks.PubSub.Enabled {
+		pubSubSink, err := sinks.NewPubSubSink(context.Background(), sinks.PubSubConfig{
+			ProjectID:       conf.Sinks.PubSub.ProjectID,
+			Topic:           conf.Sinks.PubSub.Topic,
+			CredentialsFile: conf.Sinks.PubSub.CredentialsFile,
+			Endpoint:        conf.Sinks.PubSub.Endpoint,
+			OrderingKeys:    conf.Sinks.PubSub.OrderingKeys,
+			Schema:          conf.Sinks.PubSub.Schema,
+			Encoding:        conf.Sinks.PubSub.Encoding,
+		})
+		if err != nil {
+			return nil, err
+		}
+		enabledSinks = append(enabledSinks, pubSubSink)
+	}
+
+	if conf.Sinks.AWS.Enabled {
+		awsSink, err := sinks.NewAWSSink(context.Background(), sinks.AWSConfig{
+			Region:            conf.Sinks.AWS.Region,
+			Endpoint:          conf.Sinks.AWS.Endpoint,
+			TopicARN:          conf.Sinks.AWS.SNSTopicARN,
+			QueueURL:          conf.Sinks.AWS.SQSQueueURL,
+			FailureTopicARN:   conf.Sinks.AWS.FailureTopicARN,
+			FailureQueueURL:   conf.Sinks.AWS.FailureQueueURL,
+			MessageAttributes: conf.Sinks.AWS.MessageAttributes,
+			Encoding:          conf.Sinks.AWS.Encoding,
+		})
+		if err != nil {
+			return nil, err
+		}
+		enabledSinks = append(enabledSinks, awsSink)
+	}
+
+	if conf.Sinks.EventHubs.Enabled {
+		eventHubsConf := sinks.EventHubsConfig{
+			ConnectionString: conf.Sinks.EventHubs.ConnectionString,
+			EventHub:         conf.Sinks.EventHubs.EventHub,
+			Encoding:         conf.Sinks.EventHubs.Encoding,
+			ChainID:          conf.Probe.ChainID,
+		}
+		if conf.Sinks.EventHubs.Checkpoint {
+			eventHubsConf.Checkpoints = sinks.NewDBCheckpointStore(db)
+		}
+
+		eventHubsSink, err := sinks.NewEventHubsSink(eventHubsConf)
+		if err != nil {
+			return nil, err
+		}
+		enabledSinks = append(enabledSinks, eventHubsSink)
+	}
+
+	if conf.Sinks.BigQuery.Enabled {
+		bigQuerySink, err := sinks.NewBigQuerySink(context.Background(), sinks.BigQueryConfig{
+			ProjectID:       conf.Sinks.BigQuery.ProjectID,
+			Dataset:         conf.Sinks.BigQuery.Dataset,
+			Location:        conf.Sinks.BigQuery.Location,
+			TablePrefix:     conf.Sinks.BigQuery.TablePrefix,
+			CredentialsFile: conf.Sinks.BigQuery.CredentialsFile,
+			Endpoint:        conf.Sinks.BigQuery.Endpoint,
+			Mode:            conf.Sinks.BigQuery.Mode,
+			BatchSize:       conf.Sinks.BigQuery.BatchSize,
+			BatchInterval:   conf.Sinks.BigQuery.BatchInterval,
+		})
+		if err != nil {
+			return nil, err
+		}
+		enabledSinks = append(enabledSinks, bigQuerySink)
+	}
+
+	if conf.Sinks.Delta.Enabled {
+		deltaSink, err := sinks.NewDeltaSink(sinks.DeltaConfig{
+			Location:    conf.Sinks.Delta.Location,
+			Region:      conf.Sinks.Delta.Region,
+			Endpoint:    conf.Sinks.Delta.Endpoint,
+			BatchBlocks: conf.Sinks.Delta.BatchBlocks,
+		})
+		if err != nil {
+			return nil, err
+		}
+		enabledSinks = append(enabledSinks, deltaSink)
+	}
+
+	if conf.Sinks.File.Enabled {
+		fileSink, err := sinks.NewFileSink(sinks.FileConfig{
+			Directory: conf.Sinks.File.Directory,
+			MaxSize:   conf.Sinks.File.MaxSize,
+			MaxAge:    conf.Sinks.File.MaxAge,
+			Gzip:      conf.Sinks.File.Gzip,
+		})
+		if err != nil {
+			return nil, err
+		}
+		enabledSinks = append(enabledSinks, fileSink)
+	}
+
+	// Template outputs can write to any of the sinks above, so the template sink is set up last
+	if conf.Sinks.Template.Enabled {
+		outputs, err := sinks.LoadTemplateOutputs(conf.Sinks.Template.OutputsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		sinksByName := make(map[string]sinks.Sink, len(enabledSinks))
+		for _, sink := range enabledSinks {
+			sinksByName[sink.Name()] = sink
+		}
+
+		templateSink, err := sinks.NewTemplateSink(outputs, sinksByName)
+		if err != nil {
+			return nil, err
+		}
+		enabledSinks = append(enabledSinks, templateSink)
+	}
+
+	return sinks.NewDispatcher(enabledSinks...), nil
+}
+
+// publishToSinks is called once the records' block data has been committed. Dry runs commit nothing, so nothing is
+// published then.
+func (idxr *Indexer) publishToSinks(ctx context.Context, records []sinks.Record) {
+	if idxr.dryRun {
+		return
+	}
+
+	if idxr.cfg.Sinks.PublishTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, idxr.cfg.Sinks.PublishTimeout)
+		defer cancel()
+	}
+	err := idxr.sinkDispatcher.Publish(ctx, records)
+	if err != nil && idxr.cfg.Sinks.FailOnError && ctx.Err() != context.Canceled {
+		config.Log.Fatal("Failed to publish indexed data to sinks", err)
+	}
+}
+
+// failedBlockHandler returns the handler that logs the failures of blocks and notifies the sinks about them
+func (idxr *Indexer) failedBlockHandler(ctx context.Context) core.FailedBlockHandler {
+	return func(height int64, code core.BlockProcessingFailure, err error) {
+		core.HandleFailedBlock(height, code, err)
+		idxr.watchdog.blockDone()
+
+		if idxr.sinkDispatcher.Enabled() {
+			idxr.publishToSinks(ctx, []sinks.Record{sinks.NewFailedBlockRecord(idxr.cfg.Probe.ChainID, height, core.FailedBlockReason(code), err)})
+		}
+	}
+}
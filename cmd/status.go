@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+type StatusReporter struct {
+	cfg *config.StatusConfig
+	db  *gorm.DB
+}
+
+var statusReporter StatusReporter
+
+func init() {
+	statusReporter.cfg = &config.StatusConfig{}
+	config.SetupLogFlags(&statusReporter.cfg.Log, statusCmd)
+	config.SetupDatabaseFlags(&statusReporter.cfg.Database, statusCmd)
+	config.SetupStatusFlags(statusReporter.cfg, statusCmd)
+
+	rootCmd.AddCommand(statusCmd)
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Reports how far the indexer is behind the chain head.",
+	Long: `Prints the chain head and the highest indexed height the index command last recorded in the chain_heads
+	table, the lag between them and whether the chain itself is halted. With the status.max-* flags it exits with an error when the indexer is too far
+	behind or stopped recording, so it can serve as a readiness check.`,
+	PreRunE: setupStatus,
+	Run:     reportStatus,
+}
+
+func setupStatus(cmd *cobra.Command, args []string) error {
+	bindFlags(cmd, viperConf)
+
+	err := statusReporter.cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	ignoredKeys := config.CheckSuperfluousStatusKeys(viperConf.AllKeys())
+
+	if len(ignoredKeys) > 0 {
+		config.Log.Warnf("Warning, the following invalid keys will be ignored: %v", ignoredKeys)
+	}
+
+	setupLogger(statusReporter.cfg.Log.Level, statusReporter.cfg.Log.Path, statusReporter.cfg.Log.Pretty)
+
+	db, err := connectToDBAndMigrate(statusReporter.cfg.Database, statusReporter.cfg.Status.ChainID)
+	if err != nil {
+		config.Log.Fatal("Could not establish connection to the database", err)
+	}
+
+	statusReporter.db = db
+
+	return nil
+}
+
+func reportStatus(cmd *cobra.Command, args []string) {
+	dbConn, err := statusReporter.db.DB()
+	if err != nil {
+		config.Log.Fatal("Failed to connect to DB", err)
+	}
+	defer dbConn.Close()
+
+	conf := statusReporter.cfg.Status
+	head, err := dbTypes.GetChainHead(statusReporter.db, conf.ChainID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config.Log.Fatalf("No chain head was recorded for %s, is the index command running with metrics.head-interval?", conf.ChainID)
+	}
+	if err != nil {
+		config.Log.Fatal("Failed to load the chain head", err)
+	}
+
+	age := time.Since(head.UpdatedAt)
+	fmt.Printf("chain ID:       %s\n", conf.ChainID)
+	fmt.Printf("head height:    %d (%s)\n", head.HeadHeight, head.HeadTime.UTC().Format(time.RFC3339))
+	if head.IndexedTime != nil {
+		fmt.Printf("indexed height: %d (%s)\n", head.IndexedHeight, head.IndexedTime.UTC().Format(time.RFC3339))
+	} else {
+		fmt.Println("indexed height: none")
+	}
+	fmt.Printf("lag:            %d blocks, %s\n", head.LagBlocks, (time.Duration(head.LagSeconds) * time.Second).String())
+	fmt.Printf("recorded:       %s ago\n", age.Round(time.Second))
+	if head.Halted {
+		fmt.Printf("chain:          halted, the head has not advanced since %s\n", head.HeadAdvancedAt.UTC().Format(time.RFC3339))
+	} else {
+		fmt.Println("chain:          advancing")
+	}
+
+	var failures []string
+	if conf.MaxLagBlocks > 0 && head.LagBlocks > conf.MaxLagBlocks {
+		failures = append(failures, fmt.Sprintf("the lag of %d blocks exceeds %d", head.LagBlocks, conf.MaxLagBlocks))
+	}
+	if conf.MaxLagSeconds > 0 && head.LagSeconds > float64(conf.MaxLagSeconds) {
+		failures = append(failures, fmt.Sprintf("the lag of %.0f seconds exceeds %d", head.LagSeconds, conf.MaxLagSeconds))
+	}
+	if conf.MaxAge > 0 && age > time.Duration(conf.MaxAge)*time.Second {
+		failures = append(failures, fmt.Sprintf("the chain head was recorded %s ago", age.Round(time.Second)))
+	}
+
+	for _, failure := range failures {
+		fmt.Fprintf(os.Stderr, "not ready: %s\n", failure)
+	}
+	if len(failures) > 0 {
+		os.Exit(1)
+	}
+}
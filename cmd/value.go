@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/prices"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// valueBatchSize is the number of blocks loaded at a time while valuing
+const valueBatchSize = 100
+
+type Valuer struct {
+	cfg *config.ValueConfig
+	db  *gorm.DB
+}
+
+var valuer Valuer
+
+func init() {
+	valuer.cfg = &config.ValueConfig{}
+	config.SetupLogFlags(&valuer.cfg.Log, valueCmd)
+	config.SetupDatabaseFlags(&valuer.cfg.Database, valueCmd)
+	config.SetupValueFlags(valuer.cfg, valueCmd)
+
+	rootCmd.AddCommand(valueCmd)
+}
+
+var valueCmd = &cobra.Command{
+	Use:   "value",
+	Short: "Values the indexed fees, transfers and rewards in USD.",
+	Long: `Values the fees, transfer events and reward withdrawals of the indexed blocks of a chain in USD, at the
+	price of each denom at the time of the block, and stores the valuations in the valuations table. Prices are looked
+	up in the price sources declared in --value.sources-file: fixed CSV files, the CoinGecko API, Osmosis pools and the
+	swap prices derived by dex-prices. Every flow is classified as income, a transfer, a trade, a fee or spam by the
+	rules declared in --value.rules-file. Blocks are valued once, run it again to value newly indexed blocks.`,
+	PreRunE: setupValue,
+	Run:     value,
+}
+
+func setupValue(cmd *cobra.Command, args []string) error {
+	bindFlags(cmd, viperConf)
+
+	err := valuer.cfg.Validate()
+	if err != nil {
+		return err
+	}
+
+	ignoredKeys := config.CheckSuperfluousValueKeys(viperConf.AllKeys())
+
+	if len(ignoredKeys) > 0 {
+		config.Log.Warnf("Warning, the following invalid keys will be ignored: %v", ignoredKeys)
+	}
+
+	setupLogger(valuer.cfg.Log.Level, valuer.cfg.Log.Path, valuer.cfg.Log.Pretty)
+
+	db, err := connectToDBAndMigrate(valuer.cfg.Database, valuer.cfg.Value.ChainID)
+	if err != nil {
+		config.Log.Fatal("Could not establish connection to the database", err)
+	}
+
+	valuer.db = db
+
+	return nil
+}
+
+func value(cmd *cobra.Command, args []string) {
+	dbConn, err := valuer.db.DB()
+	if err != nil {
+		config.Log.Fatal("Failed to connect to DB", err)
+	}
+	defer dbConn.Close()
+
+	declared, err := config.LoadPriceSources(valuer.cfg.Value.SourcesFile)
+	if err != nil {
+		config.Log.Fatal("Failed to read the price sources", err)
+	}
+	sources, err := prices.NewSources(valuer.db, declared)
+	if err != nil {
+		config.Log.Fatal("Failed to set up the price sources", err)
+	}
+
+	var rules []config.ClassificationRule
+	if valuer.cfg.Value.RulesFile != "" {
+		rules, err = config.LoadClassificationRules(valuer.cfg.Value.RulesFile)
+		if err != nil {
+			config.Log.Fatal("Failed to read the classification rules", err)
+		}
+	}
+
+	var chain models.Chain
+	err = valuer.db.Where("chain_id = ?", valuer.cfg.Value.ChainID).First(&chain).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config.Log.Fatalf("Chain %s is not indexed", valuer.cfg.Value.ChainID)
+	}
+	if err != nil {
+		config.Log.Fatal("Failed to look up the chain", err)
+	}
+
+	blockValuer, err := prices.NewValuer(valuer.db, sources, prices.NewClassifier(rules), valuer.cfg.Value.ReferenceDenom)
+	if err != nil {
+		config.Log.Fatal("Failed to load the denom display units and traces", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var blocks, flows int
+	fromHeight := valuer.cfg.Value.StartHeight
+	for {
+		batch, err := dbTypes.UnvaluedBlocks(valuer.db, chain.ID, fromHeight, valuer.cfg.Value.EndHeight, valuer.cfg.Value.Revalue, valueBatchSize)
+		if err != nil {
+			config.Log.Fatal("Failed to load the blocks to value", err)
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		for i := range batch {
+			valued, err := blockValuer.ValueBlock(ctx, &batch[i])
+			if ctx.Err() != nil {
+				config.Log.Infof("Valuing interrupted after %d blocks", blocks)
+				return
+			}
+			if err != nil {
+				config.Log.Fatal(fmt.Sprintf("Failed to value block %d", batch[i].Height), err)
+			}
+			blocks++
+			flows += valued
+		}
+
+		fromHeight = batch[len(batch)-1].Height + 1
+		config.Log.Infof("Valued blocks up to height %d", batch[len(batch)-1].Height)
+	}
+
+	config.Log.Infof("Valued %d flows of %d blocks of %s", flows, blocks, valuer.cfg.Value.ChainID)
+}
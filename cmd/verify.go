@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"context"
+	"encoding/hex"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/rpc"
+)
+
+// setupVerification verifies the blocks of the client with a light client following the headers of probe.rpc from the
+// trusted root, cross-checked with the witnesses
+func setupVerification(conf *config.IndexConfig, client rpc.Client) (*rpc.VerifyingClient, error) {
+	hash, err := hex.DecodeString(conf.Verify.TrustedHash) // Validated in the config
+	if err != nil {
+		return nil, err
+	}
+
+	config.Log.Infof("Verifying the fetched blocks from the trusted header at height %d with %d witnesses", conf.Verify.TrustedHeight, len(conf.Verify.Witnesses))
+	return rpc.NewVerifyingClient(context.Background(), client, rpc.VerifyOptions{
+		ChainID:        conf.Probe.ChainID,
+		Primary:        conf.Probe.RPC,
+		Witnesses:      conf.Verify.Witnesses,
+		TrustedHeight:  conf.Verify.TrustedHeight,
+		TrustedHash:    hash,
+		TrustingPeriod: conf.Verify.TrustingPeriod,
+	})
+}
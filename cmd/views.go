@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"gorm.io/gorm"
+)
+
+// viewRefresher tracks the refresh cadence of the materialized views declared in the views file
+type viewRefresher struct {
+	views     []config.MaterializedView
+	blocks    []int64
+	refreshed []time.Time
+}
+
+// setupViews creates the declared views and returns their refresher, nil if no views file is configured
+func setupViews(conf *config.IndexConfig, db *gorm.DB) (*viewRefresher, error) {
+	if conf.Views.File == "" {
+		return nil, nil
+	}
+
+	views, err := config.LoadMaterializedViews(conf.Views.File)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dbTypes.CreateMaterializedViews(db, views); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	refresher := &viewRefresher{
+		views:     views,
+		blocks:    make([]int64, len(views)),
+		refreshed: make([]time.Time, len(views)),
+	}
+	for i := range refresher.refreshed {
+		refresher.refreshed[i] = now
+	}
+
+	return refresher, nil
+}
+
+// blockIndexed refreshes the views that are due after another block was committed. A failed refresh is logged and
+// retried on the next block, it does not stop indexing.
+func (r *viewRefresher) blockIndexed(db *gorm.DB) {
+	if r == nil {
+		return
+	}
+
+	for i, view := range r.views {
+		r.blocks[i]++
+
+		dueByBlocks := view.RefreshBlocks > 0 && r.blocks[i] >= view.RefreshBlocks
+		dueByInterval := view.Interval > 0 && time.Since(r.refreshed[i]) >= view.Interval
+		if !dueByBlocks && !dueByInterval {
+			continue
+		}
+
+		start := time.Now()
+		if err := dbTypes.RefreshMaterializedView(db, view); err != nil {
+			config.Log.Error("Error refreshing materialized view "+view.Name, err)
+			continue
+		}
+		config.Log.Debugf("Refreshed materialized view %s in %s", view.Name, time.Since(start))
+
+		r.blocks[i] = 0
+		r.refreshed[i] = time.Now()
+	}
+}
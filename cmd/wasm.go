@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/parsers/wasm"
+	"gorm.io/gorm"
+)
+
+// registerWasmParsers migrates the tables of the built-in CosmWasm parsers and registers them next to the custom
+// message parsers, so they run, are tracked and record their errors like any other custom parser
+func registerWasmParsers(db *gorm.DB) error {
+	if err := dbTypes.MigrateInterfaces(db, wasm.Models()); err != nil {
+		return err
+	}
+
+	for messageType, parser := range wasm.MessageParsers() {
+		RegisterCustomMessageParser(messageType, parser)
+	}
+	return nil
+}
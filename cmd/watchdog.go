@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/core"
+)
+
+// rpcWorkerPool is the set of RPC workers fetching the relayed blocks. Only the relay sends to the workers, so it is
+// also the one replacing them when the watchdog requests a restart.
+type rpcWorkerPool struct {
+	// start runs a new set of workers reading input until ctx is done
+	start func(ctx context.Context, input chan *core.EnqueueData)
+	ctx   context.Context
+	// restart holds a pending restart request of the watchdog
+	restart chan struct{}
+	// pending holds the blocks the relay is about to send, it is only used by the relay
+	pending []*core.EnqueueData
+
+	mu sync.Mutex
+	// input is only replaced by the relay, which may read it without the lock
+	input chan *core.EnqueueData
+	// cancel stops the current workers
+	cancel context.CancelFunc
+	// fetching holds the blocks handed to the workers that did not come out of them yet
+	fetching map[int64]*core.EnqueueData
+	// sent counts the times each height was handed to the workers and did not come out of them yet, a block fetched
+	// by workers that were replaced meanwhile is only passed on once
+	sent map[int64]int
+}
+
+func newRPCWorkerPool(ctx context.Context, start func(ctx context.Context, input chan *core.EnqueueData)) *rpcWorkerPool {
+	workersCtx, cancel := context.WithCancel(ctx)
+	pool := &rpcWorkerPool{
+		start:    start,
+		ctx:      ctx,
+		restart:  make(chan struct{}, 1),
+		input:    make(chan *core.EnqueueData, 1),
+		cancel:   cancel,
+		fetching: make(map[int64]*core.EnqueueData),
+		sent:     make(map[int64]int),
+	}
+	pool.start(workersCtx, pool.input)
+	return pool
+}
+
+// send hands a block to the workers
+func (p *rpcWorkerPool) send(ctx context.Context, block *core.EnqueueData) {
+	p.pending = append(p.pending, block)
+	p.drain(ctx)
+}
+
+// drain sends the pending blocks until ctx is done, replacing the workers whenever the watchdog requests a restart
+// meanwhile
+func (p *rpcWorkerPool) drain(ctx context.Context) {
+	for len(p.pending) > 0 {
+		// The block is counted before it is sent, a worker may hand it on right away. When the workers are replaced
+		// instead, the count is taken back with those of the other blocks they did not hand on.
+		block := p.pending[0]
+		p.mu.Lock()
+		p.fetching[block.Height] = block
+		p.sent[block.Height]++
+		p.mu.Unlock()
+
+		select {
+		case p.input <- block:
+			p.pending = p.pending[1:]
+		case <-p.restart:
+			p.replace()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// replace starts new workers and cancels the current ones, which drop the block they are fetching. The blocks the
+// current workers did not hand on become pending again, so blocks held by a worker that hangs are not lost. A worker
+// that still hands on its block, e.g. after a request that ignores the cancellation returns, is counted as one of the
+// fetches of the block, so the block is only passed on once.
+func (p *rpcWorkerPool) replace() {
+	workersCtx, cancel := context.WithCancel(p.ctx)
+	input := make(chan *core.EnqueueData, 1)
+	p.start(workersCtx, input)
+
+	p.mu.Lock()
+	old, oldCancel := p.input, p.cancel
+	p.input, p.cancel = input, cancel
+	stuck := p.fetching
+	p.fetching = make(map[int64]*core.EnqueueData)
+	for height := range stuck {
+		if p.sent[height] > 0 {
+			p.sent[height]--
+		}
+	}
+	p.mu.Unlock()
+	oldCancel()
+	close(old)
+
+	pending := make([]*core.EnqueueData, 0, len(stuck)+len(p.pending))
+	for _, block := range stuck {
+		pending = append(pending, block)
+	}
+	for _, block := range p.pending {
+		if _, ok := stuck[block.Height]; !ok {
+			pending = append(pending, block)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].Height < pending[j].Height })
+	p.pending = pending
+
+	config.Log.Warnf("Restarted the RPC workers, %d blocks in flight are fetched again", len(stuck))
+}
+
+// fetched records that the workers handed on the block at height. It returns false when the block was already handed
+// on as often as it was sent, by workers that were replaced while fetching it, and the block must be dropped.
+func (p *rpcWorkerPool) fetched(height int64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.fetching, height)
+	if p.sent[height] == 0 {
+		return false
+	}
+	p.sent[height]--
+	if p.sent[height] == 0 {
+		delete(p.sent, height)
+	}
+	return true
+}
+
+// depth returns the number of blocks waiting for a worker
+func (p *rpcWorkerPool) depth() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.input)
+}
+
+// requestRestart asks the relay to replace the workers before it sends the next block
+func (p *rpcWorkerPool) requestRestart() {
+	select {
+	case p.restart <- struct{}{}:
+	default:
+	}
+}
+
+// close lets the workers exit once the blocks sent to them are fetched
+func (p *rpcWorkerPool) close() {
+	close(p.input)
+}
+
+// downstreamChannels are the channels of the pipeline after the RPC workers. Blocks waiting in them while no block
+// makes it through mean the processor or the DB writer stalled, which restarting the RPC workers does not resolve.
+var downstreamChannels = []string{"rpc_results", "block_events", "transactions"}
+
+// stallWatchdog detects when no block made it through the pipeline for the stall timeout while blocks are waiting.
+// A nil stallWatchdog is disabled.
+type stallWatchdog struct {
+	timeout time.Duration
+	// depths returns the number of blocks waiting in each channel of the pipeline
+	depths func() map[string]int
+	// resumed returns a channel that is closed while indexing is not paused nor outside of the schedule
+	resumed func() <-chan struct{}
+	// restart replaces the RPC workers
+	restart func()
+	// stop stops the pipeline, dropping the blocks in flight
+	stop func()
+	// exit ends the process when the pipeline did not stop either
+	exit func(stalled time.Duration)
+
+	mu       sync.Mutex
+	progress time.Time
+	stopped  bool
+}
+
+func newStallWatchdog(timeout time.Duration, depths func() map[string]int, resumed func() <-chan struct{}, restart func(), stop func()) *stallWatchdog {
+	if timeout <= 0 {
+		return nil
+	}
+	return &stallWatchdog{
+		timeout: timeout,
+		depths:  depths,
+		resumed: resumed,
+		restart: restart,
+		stop:    stop,
+		exit: func(stalled time.Duration) {
+			config.Log.Fatalf("The pipeline did not stop within %s after it stalled", stalled.Round(time.Second))
+		},
+		progress: time.Now(),
+	}
+}
+
+// blockDone records that a block left the pipeline, either indexed or recorded as failed
+func (w *stallWatchdog) blockDone() {
+	if w == nil {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.progress = time.Now()
+}
+
+// stoppedPipeline reports whether the watchdog stopped the pipeline after a stall of the processor or the DB writer
+func (w *stallWatchdog) stoppedPipeline() bool {
+	if w == nil {
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stopped
+}
+
+// watch checks for stalls until done is closed
+func (w *stallWatchdog) watch(done <-chan struct{}) {
+	if w == nil {
+		return
+	}
+
+	interval := w.timeout / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			w.check()
+		}
+	}
+}
+
+// check logs a stall with the pipeline channel depths and the goroutine stacks. A stall of the RPC workers restarts
+// them. A stall of the processor or the DB writer, which cannot be replaced while they hold a block, stops the
+// pipeline, so the run exits and resumes from its checkpoint when restarted; when the pipeline did not stop for
+// another timeout either, the process exits. The time spent paused or outside of the schedule is not a stall.
+func (w *stallWatchdog) check() {
+	select {
+	case <-w.resumed():
+	default:
+		w.blockDone()
+		return
+	}
+
+	w.mu.Lock()
+	stalled := time.Since(w.progress)
+	stopped := w.stopped
+	w.mu.Unlock()
+	if stalled < w.timeout {
+		return
+	}
+	if stopped {
+		w.exit(stalled)
+		return
+	}
+
+	depths := w.depths()
+	waiting := 0
+	for _, depth := range depths {
+		waiting += depth
+	}
+	if waiting == 0 {
+		return
+	}
+
+	config.Log.Errorf("No block was indexed for %s while blocks are waiting, channel depths: %s", stalled.Round(time.Second), formatDepths(depths))
+	config.Log.Errorf("Goroutine stacks of the stalled indexer:\n%s", goroutineStacks())
+
+	downstream := 0
+	for _, name := range downstreamChannels {
+		downstream += depths[name]
+	}
+	if downstream == 0 {
+		w.restart()
+		w.blockDone()
+		return
+	}
+
+	config.Log.Errorf("The processor or the DB writer stalled, stopping the pipeline")
+	w.mu.Lock()
+	w.stopped = true
+	w.progress = time.Now()
+	w.mu.Unlock()
+	w.stop()
+}
+
+func formatDepths(depths map[string]int) string {
+	formatted := make([]string, 0, len(depths))
+	for name, depth := range depths {
+		formatted = append(formatted, fmt.Sprintf("%s=%d", name, depth))
+	}
+	sort.Strings(formatted)
+	return strings.Join(formatted, ", ")
+}
+
+// goroutineStacks returns the stacks of all goroutines, growing the buffer until they fit
+func goroutineStacks() string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
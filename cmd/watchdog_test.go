@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/core"
+	"github.com/stretchr/testify/suite"
+)
+
+type WatchdogTestSuite struct {
+	suite.Suite
+}
+
+// workerSet records the blocks a set of workers received and whether it was cancelled. The workers never hand on a
+// block, like workers hanging on a request.
+type workerSet struct {
+	ctx      context.Context
+	received chan *core.EnqueueData
+}
+
+func startWorkerSets(sets chan *workerSet) func(ctx context.Context, input chan *core.EnqueueData) {
+	return func(ctx context.Context, input chan *core.EnqueueData) {
+		set := &workerSet{ctx: ctx, received: make(chan *core.EnqueueData, 10)}
+		go func() {
+			for block := range input {
+				set.received <- block
+			}
+		}()
+		sets <- set
+	}
+}
+
+func (suite *WatchdogTestSuite) receive(set *workerSet) int64 {
+	select {
+	case block := <-set.received:
+		return block.Height
+	case <-time.After(time.Second):
+		suite.FailNow("the workers did not receive a block")
+		return 0
+	}
+}
+
+func (suite *WatchdogTestSuite) TestReplaceRequeuesBlocksInFlight() {
+	sets := make(chan *workerSet, 2)
+	pool := newRPCWorkerPool(context.Background(), startWorkerSets(sets))
+	first := <-sets
+
+	pool.send(context.Background(), &core.EnqueueData{Height: 1})
+	pool.send(context.Background(), &core.EnqueueData{Height: 2})
+	suite.Require().Equal(int64(1), suite.receive(first))
+	suite.Require().Equal(int64(2), suite.receive(first))
+	suite.Require().True(pool.fetched(2))
+
+	// Block 1 is stuck in the first workers, the new workers fetch it again before the next block. The relay replaces
+	// the workers on a restart request like this.
+	pool.replace()
+	pool.drain(context.Background())
+	pool.send(context.Background(), &core.EnqueueData{Height: 3})
+	second := <-sets
+	suite.Require().ErrorIs(first.ctx.Err(), context.Canceled)
+	suite.Require().NoError(second.ctx.Err())
+	suite.Require().Equal(int64(1), suite.receive(second))
+	suite.Require().Equal(int64(3), suite.receive(second))
+}
+
+func (suite *WatchdogTestSuite) TestReplacedWorkersDoNotDuplicateBlocks() {
+	sets := make(chan *workerSet, 2)
+	pool := newRPCWorkerPool(context.Background(), startWorkerSets(sets))
+	first := <-sets
+
+	pool.send(context.Background(), &core.EnqueueData{Height: 1})
+	suite.Require().Equal(int64(1), suite.receive(first))
+	pool.replace()
+	pool.drain(context.Background())
+	second := <-sets
+	suite.Require().Equal(int64(1), suite.receive(second))
+
+	// Block 1 comes out of the replaced and the new workers, it is only passed on once
+	suite.Require().True(pool.fetched(1))
+	suite.Require().False(pool.fetched(1))
+
+	// A height sent again later is passed on again
+	pool.send(context.Background(), &core.EnqueueData{Height: 2})
+	pool.send(context.Background(), &core.EnqueueData{Height: 1})
+	suite.Require().Equal(int64(2), suite.receive(second))
+	suite.Require().Equal(int64(1), suite.receive(second))
+	suite.Require().True(pool.fetched(2))
+	suite.Require().True(pool.fetched(1))
+	suite.Require().False(pool.fetched(1))
+}
+
+// testWatchdog returns a watchdog that last saw progress long ago, recording its actions in the counters
+func testWatchdog(depths map[string]int, resumed chan struct{}, restarts *int, stops *int, exits *int) *stallWatchdog {
+	w := newStallWatchdog(time.Minute, func() map[string]int { return depths },
+		func() <-chan struct{} { return resumed }, func() { *restarts++ }, func() { *stops++ })
+	w.exit = func(time.Duration) { *exits++ }
+	w.progress = time.Now().Add(-time.Hour)
+	return w
+}
+
+func (suite *WatchdogTestSuite) TestStallOfRPCWorkers() {
+	resumed := make(chan struct{})
+	close(resumed)
+	var restarts, stops, exits int
+	depths := map[string]int{"enqueued": 5, "rpc_workers": 1}
+	w := testWatchdog(depths, resumed, &restarts, &stops, &exits)
+
+	w.check()
+	suite.Require().Equal(1, restarts)
+	suite.Require().Zero(stops)
+
+	// The restart counts as progress, the next stall is detected after another timeout
+	w.check()
+	suite.Require().Equal(1, restarts)
+	w.progress = time.Now().Add(-time.Hour)
+	w.check()
+	suite.Require().Equal(2, restarts)
+}
+
+func (suite *WatchdogTestSuite) TestNoStall() {
+	resumed := make(chan struct{})
+	close(resumed)
+	var restarts, stops, exits int
+	depths := map[string]int{}
+	w := testWatchdog(depths, resumed, &restarts, &stops, &exits)
+
+	// Nothing is waiting
+	w.check()
+	suite.Require().Zero(restarts)
+
+	// A block made it through
+	depths["enqueued"] = 5
+	w.blockDone()
+	w.check()
+	suite.Require().Zero(restarts)
+
+	// Indexing is paused
+	w.progress = time.Now().Add(-time.Hour)
+	w.resumed = func() <-chan struct{} { return make(chan struct{}) }
+	w.check()
+	suite.Require().Zero(restarts)
+	suite.Require().Zero(stops)
+}
+
+func (suite *WatchdogTestSuite) TestStallOfDBWriter() {
+	resumed := make(chan struct{})
+	close(resumed)
+	var restarts, stops, exits int
+	depths := map[string]int{"enqueued": 5, "transactions": 8}
+	w := testWatchdog(depths, resumed, &restarts, &stops, &exits)
+
+	// The workers are not restarted, the pipeline is stopped
+	w.check()
+	suite.Require().Zero(restarts)
+	suite.Require().Equal(1, stops)
+	suite.Require().True(w.stoppedPipeline())
+
+	// The process exits when the pipeline did not stop within another timeout
+	w.check()
+	suite.Require().Zero(exits)
+	w.progress = time.Now().Add(-time.Hour)
+	w.check()
+	suite.Require().Equal(1, exits)
+	suite.Require().Equal(1, stops)
+}
+
+func TestWatchdogTestSuite(t *testing.T) {
+	suite.Run(t, new(WatchdogTestSuite))
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/DefiantLabs/cosmos-indexer/util"
+	"github.com/spf13/cobra"
+)
+
+// Admin control API of the index command. It always requires an API key or JWT granted the admin scope, verified with
+// its own settings so the indexer does not need the auth section of the serve command.
+type adminAPI struct {
+	Enabled          bool   `mapstructure:"enabled"`
+	Address          string `mapstructure:"address"`
+	KeysFile         string `mapstructure:"keys-file"`
+	JWTSecret        string `mapstructure:"jwt-secret"`
+	JWTPublicKeyFile string `mapstructure:"jwt-public-key-file"`
+	JWTIssuer        string `mapstructure:"jwt-issuer"`
+	JWTAudience      string `mapstructure:"jwt-audience"`
+}
+
+// Auth returns the settings verifying the credentials of admin requests
+func (conf adminAPI) Auth() APIAuth {
+	return APIAuth{
+		Enabled:          conf.Enabled,
+		KeysFile:         conf.KeysFile,
+		JWTSecret:        conf.JWTSecret,
+		JWTPublicKeyFile: conf.JWTPublicKeyFile,
+		JWTIssuer:        conf.JWTIssuer,
+		JWTAudience:      conf.JWTAudience,
+	}
+}
+
+func setupAdminFlags(conf *adminAPI, cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&conf.Enabled, "admin.enabled", false, "serve the admin API to pause and resume indexing, enqueue or skip heights and change the log level")
+	cmd.PersistentFlags().StringVar(&conf.Address, "admin.address", "localhost:8082", "address the admin API listens on")
+	cmd.PersistentFlags().StringVar(&conf.KeysFile, "admin.keys-file", "", "JSON file declaring the API keys accepted by the admin API, only keys with the admin scope are authorized")
+	cmd.PersistentFlags().StringVar(&conf.JWTSecret, "admin.jwt-secret", "", "secret verifying HS256 JWTs of the admin API")
+	cmd.PersistentFlags().StringVar(&conf.JWTPublicKeyFile, "admin.jwt-public-key-file", "", "PEM RSA public key verifying RS256 JWTs of the admin API")
+	cmd.PersistentFlags().StringVar(&conf.JWTIssuer, "admin.jwt-issuer", "", "required iss claim of admin API JWTs")
+	cmd.PersistentFlags().StringVar(&conf.JWTAudience, "admin.jwt-audience", "", "required aud claim of admin API JWTs")
+}
+
+func validateAdminConf(conf adminAPI) error {
+	if !conf.Enabled {
+		return nil
+	}
+
+	if util.StrNotSet(conf.Address) {
+		return errors.New("admin.address must be set when the admin API is enabled")
+	}
+
+	return validateAuthConf(conf.Auth(), "admin")
+}
+
+func addAdminConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(adminAPI{}, "admin") {
+		validKeys[key] = struct{}{}
+	}
+}
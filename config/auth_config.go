@@ -0,0 +1,105 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/DefiantLabs/cosmos-indexer/auth"
+)
+
+// Authentication of the API servers, clients authenticate with an API key from the keys file or a JWT
+type APIAuth struct {
+	Enabled          bool   `mapstructure:"enabled"`
+	KeysFile         string `mapstructure:"keys-file"`
+	JWTSecret        string `mapstructure:"jwt-secret"`
+	JWTPublicKeyFile string `mapstructure:"jwt-public-key-file"`
+	JWTIssuer        string `mapstructure:"jwt-issuer"`
+	JWTAudience      string `mapstructure:"jwt-audience"`
+}
+
+// APIKey is a key declared in the keys file. Only the SHA-256 hash of the key is stored, so the file does not need to
+// be kept secret.
+type APIKey struct {
+	Name   string   `json:"name"`
+	SHA256 string   `json:"sha256"`
+	Scopes []string `json:"scopes"`
+	// RateLimit and RateLimitBurst override rate-limit.rate and rate-limit.burst for the key
+	RateLimit      float64 `json:"rate_limit"`
+	RateLimitBurst int     `json:"rate_limit_burst"`
+}
+
+// LoadAPIKeys reads and validates the API keys declared in a JSON file
+func LoadAPIKeys(path string) ([]auth.Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseAPIKeys(data)
+}
+
+func ParseAPIKeys(data []byte) ([]auth.Key, error) {
+	var file struct {
+		Keys []APIKey `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse API keys: %w", err)
+	}
+
+	keys := make([]auth.Key, 0, len(file.Keys))
+	names := make(map[string]struct{}, len(file.Keys))
+	hashes := make(map[[sha256.Size]byte]struct{}, len(file.Keys))
+	for _, apiKey := range file.Keys {
+		if apiKey.Name == "" {
+			return nil, fmt.Errorf("API key with hash %s has no name", apiKey.SHA256)
+		}
+		if _, ok := names[apiKey.Name]; ok {
+			return nil, fmt.Errorf("API key %s is declared twice", apiKey.Name)
+		}
+		names[apiKey.Name] = struct{}{}
+
+		key := auth.Key{Name: apiKey.Name}
+
+		hash, err := hex.DecodeString(apiKey.SHA256)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("sha256 of API key %s must be a hex encoded SHA-256 hash", apiKey.Name)
+		}
+		copy(key.Hash[:], hash)
+		if _, ok := hashes[key.Hash]; ok {
+			return nil, fmt.Errorf("API key %s has the same hash as another key", apiKey.Name)
+		}
+		hashes[key.Hash] = struct{}{}
+
+		if len(apiKey.Scopes) == 0 {
+			return nil, fmt.Errorf("API key %s has no scopes", apiKey.Name)
+		}
+		for _, scope := range apiKey.Scopes {
+			if !validScope(scope) {
+				return nil, fmt.Errorf("API key %s has unknown scope %q, valid scopes are %v", apiKey.Name, scope, auth.Scopes)
+			}
+			key.Scopes = append(key.Scopes, auth.Scope(scope))
+		}
+
+		if apiKey.RateLimit < 0 || apiKey.RateLimitBurst < 0 {
+			return nil, fmt.Errorf("rate_limit and rate_limit_burst of API key %s must be positive numbers or 0", apiKey.Name)
+		}
+		key.Rate = apiKey.RateLimit
+		key.Burst = apiKey.RateLimitBurst
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func validScope(scope string) bool {
+	for _, valid := range auth.Scopes {
+		if auth.Scope(scope) == valid {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"crypto/sha256"
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/auth"
+	"github.com/stretchr/testify/suite"
+)
+
+type AuthConfigTestSuite struct {
+	suite.Suite
+}
+
+func (suite *AuthConfigTestSuite) TestParseAPIKeys() {
+	keys, err := ParseAPIKeys([]byte(`{"keys": [{"name": "dashboard", "sha256": "85dbe15d75ef9308c7ae0f33c7a324cc6f4bf519a2ed2f3027bd33c140a4f9aa", "scopes": ["read", "stream"]}]}`))
+	suite.Require().NoError(err)
+	suite.Require().Equal([]auth.Key{{Name: "dashboard", Hash: sha256.Sum256([]byte("secret-key")), Scopes: []auth.Scope{auth.ScopeRead, auth.ScopeStream}}}, keys)
+
+	invalid := []string{
+		`{"keys": [{"sha256": "85dbe15d75ef9308c7ae0f33c7a324cc6f4bf519a2ed2f3027bd33c140a4f9aa", "scopes": ["read"]}]}`,
+		`{"keys": [{"name": "dashboard", "sha256": "secret-key", "scopes": ["read"]}]}`,
+		`{"keys": [{"name": "dashboard", "sha256": "85dbe15d75ef9308c7ae0f33c7a324cc6f4bf519a2ed2f3027bd33c140a4f9aa"}]}`,
+		`{"keys": [{"name": "dashboard", "sha256": "85dbe15d75ef9308c7ae0f33c7a324cc6f4bf519a2ed2f3027bd33c140a4f9aa", "scopes": ["write"]}]}`,
+		`{"keys": [{"name": "a", "sha256": "85dbe15d75ef9308c7ae0f33c7a324cc6f4bf519a2ed2f3027bd33c140a4f9aa", "scopes": ["read"]}, {"name": "b", "sha256": "85dbe15d75ef9308c7ae0f33c7a324cc6f4bf519a2ed2f3027bd33c140a4f9aa", "scopes": ["read"]}]}`,
+	}
+	for _, data := range invalid {
+		_, err := ParseAPIKeys([]byte(data))
+		suite.Require().Error(err, data)
+	}
+}
+
+func TestAuthConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(AuthConfigTestSuite))
+}
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+// BenchConfig is the config of the bench command on top of the index config, which indexes a sample of blocks into a
+// throwaway schema to time the stages of the pipeline
+type BenchConfig struct {
+	Blocks     int64 `mapstructure:"blocks"`
+	KeepSchema bool  `mapstructure:"keep-schema"`
+}
+
+func SetupBenchFlags(conf *BenchConfig, cmd *cobra.Command) {
+	cmd.Flags().Int64Var(&conf.Blocks, "bench.blocks", 100, "number of blocks to index, starting at base.start-block")
+	cmd.Flags().BoolVar(&conf.KeepSchema, "bench.keep-schema", false, "keep the schema the sample was indexed into instead of dropping it")
+}
+
+func (conf BenchConfig) Validate() error {
+	if conf.Blocks < 1 {
+		return errors.New("bench.blocks must be at least 1")
+	}
+	return nil
+}
+
+func addBenchConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(BenchConfig{}, "bench") {
+		validKeys[key] = struct{}{}
+	}
+}
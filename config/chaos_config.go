@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+// Fault injection for testing, RPC requests and block writes fail at the configured rates to exercise the retry,
+// failed block and recovery paths
+type chaos struct {
+	RPCTimeoutRate   float64 `mapstructure:"rpc-timeout-rate"`
+	RPCMalformedRate float64 `mapstructure:"rpc-malformed-rate"`
+	DBErrorRate      float64 `mapstructure:"db-error-rate"`
+	Seed             int64   `mapstructure:"seed"`
+}
+
+func setupChaosFlags(conf *chaos, cmd *cobra.Command) {
+	cmd.PersistentFlags().Float64Var(&conf.RPCTimeoutRate, "chaos.rpc-timeout-rate", 0, "share of block, block results and transaction requests failing with a timeout (testing only)")
+	cmd.PersistentFlags().Float64Var(&conf.RPCMalformedRate, "chaos.rpc-malformed-rate", 0, "share of block, block results and transaction responses replaced with malformed ones (testing only)")
+	cmd.PersistentFlags().Float64Var(&conf.DBErrorRate, "chaos.db-error-rate", 0, "share of the statements writing blocks failing with a transient database error (testing only)")
+	cmd.PersistentFlags().Int64Var(&conf.Seed, "chaos.seed", 0, "seed of the injected faults, to repeat a run (0 for a random seed)")
+}
+
+func validateChaosConf(conf chaos) error {
+	for _, rate := range []float64{conf.RPCTimeoutRate, conf.RPCMalformedRate, conf.DBErrorRate} {
+		if rate < 0 || rate > 1 {
+			return errors.New("chaos.rpc-timeout-rate, chaos.rpc-malformed-rate and chaos.db-error-rate must be between 0 and 1")
+		}
+	}
+
+	if conf.RPCTimeoutRate+conf.RPCMalformedRate > 1 {
+		return errors.New("chaos.rpc-timeout-rate and chaos.rpc-malformed-rate must add up to at most 1")
+	}
+
+	return nil
+}
+
+func addChaosConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(chaos{}, "chaos") {
+		validKeys[key] = struct{}{}
+	}
+}
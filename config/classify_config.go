@@ -0,0 +1,113 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ClassificationIncome   = "income"
+	ClassificationTransfer = "transfer"
+	ClassificationTrade    = "trade"
+	ClassificationFee      = "fee"
+	ClassificationSpam     = "spam"
+)
+
+var classifications = map[string]struct{}{
+	ClassificationIncome:   {},
+	ClassificationTransfer: {},
+	ClassificationTrade:    {},
+	ClassificationFee:      {},
+	ClassificationSpam:     {},
+}
+
+// ClassificationRule classifies the flows it matches as a taxable event. A flow matches a rule when it matches every
+// condition the rule sets; the first matching rule in the rules file classifies the flow.
+type ClassificationRule struct {
+	Name           string `json:"name"`
+	Classification string `json:"classification"`
+	// Kind is the kind of flow, fee, transfer or reward
+	Kind string `json:"kind"`
+	// MessageType, EventType and Denom are regular expressions matched against the type of the message that emitted
+	// the event of the flow, the event type and the base denom
+	MessageType string `json:"message_type"`
+	EventType   string `json:"event_type"`
+	Denom       string `json:"denom"`
+	// MaxValueUSD matches flows valued at most at this amount, e.g. airdropped dust. Flows without a value never match.
+	MaxValueUSD *decimal.Decimal `json:"max_value_usd"`
+
+	MessageTypePattern *regexp.Regexp `json:"-"`
+	EventTypePattern   *regexp.Regexp `json:"-"`
+	DenomPattern       *regexp.Regexp `json:"-"`
+}
+
+// LoadClassificationRules reads and validates the classification rules declared in a JSON file
+func LoadClassificationRules(path string) ([]ClassificationRule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseClassificationRules(data)
+}
+
+func ParseClassificationRules(data []byte) ([]ClassificationRule, error) {
+	var file struct {
+		Rules []ClassificationRule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse classification rules: %w", err)
+	}
+
+	if len(file.Rules) == 0 {
+		return nil, errors.New("no classification rules are declared")
+	}
+
+	names := make(map[string]struct{}, len(file.Rules))
+	for i := range file.Rules {
+		rule := &file.Rules[i]
+
+		if rule.Name == "" || rule.Name == "default" {
+			return nil, fmt.Errorf("classification rule %d needs a name other than default", i+1)
+		}
+		if _, ok := names[rule.Name]; ok {
+			return nil, fmt.Errorf("classification rule %s is declared twice", rule.Name)
+		}
+		names[rule.Name] = struct{}{}
+
+		if _, ok := classifications[rule.Classification]; !ok {
+			return nil, fmt.Errorf("classification %q of rule %s must be income, transfer, trade, fee or spam", rule.Classification, rule.Name)
+		}
+
+		switch rule.Kind {
+		case "", "fee", "transfer", "reward":
+		default:
+			return nil, fmt.Errorf("kind %q of classification rule %s must be fee, transfer or reward", rule.Kind, rule.Name)
+		}
+
+		var err error
+		if rule.MessageTypePattern, err = compileRulePattern(rule.MessageType); err != nil {
+			return nil, fmt.Errorf("invalid message_type of classification rule %s: %w", rule.Name, err)
+		}
+		if rule.EventTypePattern, err = compileRulePattern(rule.EventType); err != nil {
+			return nil, fmt.Errorf("invalid event_type of classification rule %s: %w", rule.Name, err)
+		}
+		if rule.DenomPattern, err = compileRulePattern(rule.Denom); err != nil {
+			return nil, fmt.Errorf("invalid denom of classification rule %s: %w", rule.Name, err)
+		}
+	}
+
+	return file.Rules, nil
+}
+
+func compileRulePattern(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ClassifyConfigTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ClassifyConfigTestSuite) TestParseClassificationRules() {
+	rules, err := ParseClassificationRules([]byte(`{"rules": [
+		{"name": "swaps", "kind": "transfer", "message_type": "^/osmosis\\.poolmanager\\.v1beta1\\.MsgSwap", "classification": "trade"},
+		{"name": "dust", "max_value_usd": "0.01", "classification": "spam"}
+	]}`))
+	suite.Require().NoError(err)
+	suite.Require().Len(rules, 2)
+	suite.Require().True(rules[0].MessageTypePattern.MatchString("/osmosis.poolmanager.v1beta1.MsgSwapExactAmountIn"))
+	suite.Require().Nil(rules[0].DenomPattern)
+	suite.Require().Equal("0.01", rules[1].MaxValueUSD.String())
+
+	invalid := []string{
+		`{"rules": []}`,
+		`{"rules": [{"classification": "income"}]}`,
+		`{"rules": [{"name": "default", "classification": "income"}]}`,
+		`{"rules": [{"name": "a", "classification": "gift"}]}`,
+		`{"rules": [{"name": "a", "kind": "swap", "classification": "trade"}]}`,
+		`{"rules": [{"name": "a", "denom": "factory/(", "classification": "spam"}]}`,
+		`{"rules": [{"name": "a", "classification": "spam"}, {"name": "a", "classification": "income"}]}`,
+	}
+	for _, data := range invalid {
+		_, err := ParseClassificationRules([]byte(data))
+		suite.Require().Error(err, data)
+	}
+}
+
+func TestClassifyConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(ClassifyConfigTestSuite))
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Coins of amount-style event attributes, e.g. 10uatom,5uosmo, parsed into denoms and numeric amounts at index time
+type coins struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	AttributeKeys []string `mapstructure:"attribute-keys"`
+}
+
+func setupCoinsFlags(conf *coins, cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&conf.Enabled, "coins.enabled", false, "parse the coins of the coins.attribute-keys event attributes into the message_event_attribute_coins and block_event_attribute_coins tables")
+	cmd.PersistentFlags().StringSliceVar(&conf.AttributeKeys, "coins.attribute-keys", []string{"amount"}, "event attribute keys whose values are coins, e.g. amount,tokens_in,tokens_out")
+}
+
+func validateCoinsConf(conf coins) error {
+	if !conf.Enabled {
+		return nil
+	}
+
+	if len(conf.AttributeKeys) == 0 {
+		return errors.New("coins.attribute-keys must be set when coins.enabled is set")
+	}
+
+	for _, key := range conf.AttributeKeys {
+		if strings.TrimSpace(key) == "" {
+			return errors.New("coins.attribute-keys must not contain empty keys")
+		}
+	}
+
+	return nil
+}
+
+func addCoinsConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(coins{}, "coins") {
+		validKeys[key] = struct{}{}
+	}
+}
@@ -3,13 +3,19 @@ package config
 import (
 	"errors"
 	"fmt"
+	"os"
 	"reflect"
+	"regexp"
 	"strings"
+	"time"
 
 	"github.com/DefiantLabs/cosmos-indexer/util"
 	"github.com/spf13/cobra"
 )
 
+// Schemas are passed as search_path and created quoted, so only names Postgres does not fold to lower case are allowed
+var schemaName = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
 // These configs are used across multiple commands, and are not specific to a single command
 type log struct {
 	Level  string
@@ -24,6 +30,31 @@ type Database struct {
 	User     string
 	Password string
 	LogLevel string `mapstructure:"log-level"`
+	// IsolationLevel and LockTimeout are the transaction settings of the connections, parallel writers upserting the same
+	// dictionary rows (addresses, denoms, message types) deadlock less with a lock timeout and retries
+	IsolationLevel string        `mapstructure:"isolation-level"`
+	LockTimeout    time.Duration `mapstructure:"lock-timeout"`
+	// CockroachDB turns off the features relying on Postgres specifics CockroachDB lacks, e.g. LISTEN/NOTIFY
+	CockroachDB bool `mapstructure:"cockroachdb"`
+	// TimescaleDB turns the fee time series into a hypertable and manages continuous aggregates over it on migration,
+	// the built-in ones and those declared in TimescaleAggregatesFile
+	TimescaleDB             bool   `mapstructure:"timescaledb"`
+	TimescaleAggregatesFile string `mapstructure:"timescale-aggregates-file"`
+	// Compression stores the raw message bytes, the raw bytes of failed transactions and messages and the event
+	// attribute values of at least CompressionMinSize bytes zstd compressed
+	Compression        bool `mapstructure:"compression"`
+	CompressionMinSize int  `mapstructure:"compression-min-size"`
+	// Schema holds the tables instead of public, it is used as search_path for all migrations and queries
+	Schema string
+	// SchemaPerChain places the tables of each chain in a schema named after the chain ID
+	SchemaPerChain bool `mapstructure:"schema-per-chain"`
+	// ChainID selects the chain schema for commands that do not take a chain ID of their own
+	ChainID string `mapstructure:"chain-id"`
+	// TablePrefix, SingularTables and TableNames change the table names of the indexer, e.g. to share a database with
+	// other applications. TableNames maps default table names to the names used instead.
+	TablePrefix    string            `mapstructure:"table-prefix"`
+	SingularTables bool              `mapstructure:"singular-tables"`
+	TableNames     map[string]string `mapstructure:"table-names"`
 }
 
 type Probe struct {
@@ -40,6 +71,8 @@ type throttlingBase struct {
 type retryBase struct {
 	RequestRetryAttempts int64  `mapstructure:"request-retry-attempts"`
 	RequestRetryMaxWait  uint64 `mapstructure:"request-retry-max-wait"`
+	DBRetryAttempts      int64  `mapstructure:"db-retry-attempts"`
+	DBRetryMaxWait       uint64 `mapstructure:"db-retry-max-wait"`
 }
 
 func SetupLogFlags(logConf *log, cmd *cobra.Command) {
@@ -55,6 +88,23 @@ func SetupDatabaseFlags(databaseConf *Database, cmd *cobra.Command) {
 	cmd.PersistentFlags().StringVar(&databaseConf.User, "database.user", "", "database user")
 	cmd.PersistentFlags().StringVar(&databaseConf.Password, "database.password", "", "database password")
 	cmd.PersistentFlags().StringVar(&databaseConf.LogLevel, "database.log-level", "", "database loglevel")
+	cmd.PersistentFlags().StringVar(&databaseConf.IsolationLevel, "database.isolation-level", "", "transaction isolation level: read-committed, repeatable-read or serializable (default of the database if unset)")
+	cmd.PersistentFlags().DurationVar(&databaseConf.LockTimeout, "database.lock-timeout", 0, "abort statements waiting longer for a lock, the write is retried (0 waits indefinitely)")
+	cmd.PersistentFlags().BoolVar(&databaseConf.CockroachDB, "database.cockroachdb", false, "the database is CockroachDB instead of Postgres")
+	cmd.PersistentFlags().BoolVar(&databaseConf.TimescaleDB, "database.timescaledb", false, "the database has the TimescaleDB extension, keep a fee hypertable and continuous aggregates over it")
+	cmd.PersistentFlags().StringVar(&databaseConf.TimescaleAggregatesFile, "database.timescale-aggregates-file", "", "path to a JSON file declaring continuous aggregates to manage in addition to the built-in ones")
+	cmd.PersistentFlags().BoolVar(&databaseConf.Compression, "database.compression", false, "store large raw message bytes, raw bytes of failed transactions and messages and event attribute values zstd compressed")
+	cmd.PersistentFlags().IntVar(&databaseConf.CompressionMinSize, "database.compression-min-size", 1024, "size in bytes from which values are stored compressed when database.compression is enabled")
+	cmd.PersistentFlags().StringVar(&databaseConf.Schema, "database.schema", "", "schema holding the tables instead of public")
+	cmd.PersistentFlags().BoolVar(&databaseConf.SchemaPerChain, "database.schema-per-chain", false, "place the tables of each chain in its own schema named after the chain ID")
+	cmd.PersistentFlags().StringVar(&databaseConf.TablePrefix, "database.table-prefix", "", "prefix prepended to the name of every table")
+	cmd.PersistentFlags().BoolVar(&databaseConf.SingularTables, "database.singular-tables", false, "use singular table names, e.g. block instead of blocks")
+	cmd.PersistentFlags().StringToStringVar(&databaseConf.TableNames, "database.table-names", nil, "table names replacing the default ones, e.g. blocks=chain_blocks,txes=chain_txes")
+}
+
+// SetupDatabaseChainFlag is used by commands that read the indexed data without a chain ID of their own
+func SetupDatabaseChainFlag(databaseConf *Database, cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&databaseConf.ChainID, "database.chain-id", "", "chain whose schema is used when database.schema-per-chain is enabled")
 }
 
 func SetupProbeFlags(probeConf *Probe, cmd *cobra.Command) {
@@ -84,10 +134,48 @@ func validateDatabaseConf(dbConf Database) error {
 	if util.StrNotSet(dbConf.Password) {
 		return errors.New("database password must be set")
 	}
+	switch dbConf.IsolationLevel {
+	case "", "read-committed", "repeatable-read", "serializable":
+	default:
+		return fmt.Errorf("database.isolation-level %s must be one of read-committed, repeatable-read or serializable", dbConf.IsolationLevel)
+	}
+	if dbConf.LockTimeout < 0 {
+		return errors.New("database.lock-timeout must be a positive duration or 0")
+	}
+	if dbConf.TimescaleDB && dbConf.CockroachDB {
+		return errors.New("database.timescaledb cannot be combined with database.cockroachdb")
+	}
+	if dbConf.TimescaleAggregatesFile != "" {
+		if !dbConf.TimescaleDB {
+			return errors.New("database.timescale-aggregates-file requires database.timescaledb")
+		}
+		if _, err := os.Stat(dbConf.TimescaleAggregatesFile); os.IsNotExist(err) {
+			return fmt.Errorf("database.timescale-aggregates-file %s does not exist", dbConf.TimescaleAggregatesFile)
+		}
+	}
+	if dbConf.Compression && dbConf.CompressionMinSize <= 0 {
+		return errors.New("database.compression-min-size must be greater than 0")
+	}
+	if dbConf.Schema != "" {
+		if dbConf.SchemaPerChain {
+			return errors.New("database.schema cannot be combined with database.schema-per-chain")
+		}
+		if !schemaName.MatchString(dbConf.Schema) || strings.HasPrefix(dbConf.Schema, "pg_") {
+			return fmt.Errorf("database.schema %s must be a lower case identifier of letters, digits and underscores not starting with pg_", dbConf.Schema)
+		}
+	}
 
 	return nil
 }
 
+// validateDatabaseChainConf checks that commands using SetupDatabaseChainFlag know the schema of the chain
+func validateDatabaseChainConf(dbConf Database) error {
+	if dbConf.SchemaPerChain && util.StrNotSet(dbConf.ChainID) {
+		return errors.New("database.chain-id must be set when database.schema-per-chain is enabled")
+	}
+	return nil
+}
+
 func validateProbeConf(probeConf Probe) (Probe, error) {
 	if util.StrNotSet(probeConf.RPC) {
 		return probeConf, errors.New("probe rpc must be set")
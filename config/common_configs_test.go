@@ -41,6 +41,26 @@ func (suite *ConfigTestSuite) TestValidateDatabaseConf() {
 	conf.Password = "fake-password"
 	err = validateDatabaseConf(conf)
 	suite.Require().NoError(err)
+
+	conf.IsolationLevel = "snapshot"
+	err = validateDatabaseConf(conf)
+	suite.Require().Error(err)
+
+	conf.IsolationLevel = "repeatable-read"
+	err = validateDatabaseConf(conf)
+	suite.Require().NoError(err)
+
+	conf.Schema = "Indexer"
+	err = validateDatabaseConf(conf)
+	suite.Require().Error(err)
+
+	conf.Schema = "indexer"
+	err = validateDatabaseConf(conf)
+	suite.Require().NoError(err)
+
+	conf.SchemaPerChain = true
+	err = validateDatabaseConf(conf)
+	suite.Require().Error(err)
 }
 
 func (suite *ConfigTestSuite) TestValidateProbeConf() {
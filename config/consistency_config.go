@@ -0,0 +1,25 @@
+package config
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// Comparison of the fetched blocks with the responses of an independent endpoint, to catch a malicious or corrupted
+// RPC provider
+type consistency struct {
+	// RPC is the endpoint the responses are compared with, empty disables the comparison
+	RPC string `mapstructure:"rpc"`
+	// Warn only logs and counts divergences instead of failing the blocks
+	Warn bool `mapstructure:"warn"`
+}
+
+func setupConsistencyFlags(conf *consistency, cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&conf.RPC, "consistency.rpc", "", "rpc node the fetched blocks, block results and transactions are compared with, empty disables the comparison")
+	cmd.PersistentFlags().BoolVar(&conf.Warn, "consistency.warn", false, "log and count divergent responses instead of failing their blocks")
+}
+
+func addConsistencyConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(consistency{}, "consistency") {
+		validKeys[key] = struct{}{}
+	}
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/spf13/cobra"
+)
+
+// DBConfig is the config of the db commands, which maintain the database of the indexer
+type DBConfig struct {
+	Database Database
+	Log      log
+	Base     dbBase
+	Tune     tune
+	Delete   deletion
+}
+
+// The db commands read the filters of the index command from the shared base section
+type dbBase struct {
+	FilterFile string `mapstructure:"filter-file"`
+}
+
+type tune struct {
+	Apply bool `mapstructure:"apply"`
+}
+
+func SetupTuneFlags(conf *DBConfig, cmd *cobra.Command) {
+	cmd.Flags().StringVar(&conf.Base.FilterFile, "base.filter-file", "", "path to the filter file of the index command, its filters are turned into partial indexes")
+	cmd.Flags().BoolVar(&conf.Tune.Apply, "tune.apply", false, "create the recommended indexes instead of printing them")
+}
+
+// The addresses of the delete command, whose rows are redacted
+type deletion struct {
+	Addresses []string `mapstructure:"address"`
+	Apply     bool     `mapstructure:"apply"`
+}
+
+func SetupDeleteFlags(conf *DBConfig, cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&conf.Delete.Addresses, "delete.address", nil, "address to redact from every table, can be repeated")
+	cmd.Flags().BoolVar(&conf.Delete.Apply, "delete.apply", false, "redact the rows instead of reporting them")
+}
+
+func (conf *DBConfig) Validate() error {
+	err := validateDatabaseConf(conf.Database)
+	if err != nil {
+		return err
+	}
+
+	return validateDatabaseChainConf(conf.Database)
+}
+
+func (conf *DBConfig) ValidateTune() error {
+	// The recommendations are read from the Postgres catalog, CockroachDB recommends indexes itself
+	if conf.Database.CockroachDB {
+		return errors.New("db tune is not supported on CockroachDB, see the index recommendations of its EXPLAIN output instead")
+	}
+
+	if conf.Base.FilterFile != "" {
+		if _, err := os.Stat(conf.Base.FilterFile); os.IsNotExist(err) {
+			return fmt.Errorf("base.filter-file %s does not exist", conf.Base.FilterFile)
+		}
+	}
+
+	return nil
+}
+
+func (conf *DBConfig) ValidateDelete() error {
+	if len(conf.Delete.Addresses) == 0 {
+		return errors.New("delete.address is required")
+	}
+
+	for _, address := range conf.Delete.Addresses {
+		if _, _, err := bech32.DecodeAndConvert(address); err != nil {
+			return fmt.Errorf("delete.address %s is not a bech32 address: %w", address, err)
+		}
+	}
+
+	return nil
+}
+
+func addDBConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(tune{}, "tune") {
+		validKeys[key] = struct{}{}
+	}
+	for _, key := range getValidConfigKeys(deletion{}, "delete") {
+		validKeys[key] = struct{}{}
+	}
+}
+
+// The config file is shared by all commands, so the db commands accept the same keys as the index command
+func CheckSuperfluousDBKeys(keys []string) []string {
+	return CheckSuperfluousIndexKeys(keys)
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// Display units of the denoms, which the display views convert the base denom amounts to, and the origins of the IBC
+// voucher denoms
+type denoms struct {
+	DisplayViews bool   `mapstructure:"display-views"`
+	BankMetadata bool   `mapstructure:"bank-metadata"`
+	MetadataFile string `mapstructure:"metadata-file"`
+	IBCTraces    bool   `mapstructure:"ibc-traces"`
+}
+
+// DenomDisplay is the display unit of a base denom: an amount of the base denom is divided by 10^Exponent to get the
+// amount in the display denom, e.g. uatom with exponent 6 to atom
+type DenomDisplay struct {
+	Base     string `json:"base"`
+	Display  string `json:"display"`
+	Exponent uint32 `json:"exponent"`
+}
+
+// DenomTrace is the origin of an IBC voucher denom: Denom, e.g. ibc/27394FB0..., is BaseDenom sent over Path, e.g.
+// uatom over transfer/channel-0
+type DenomTrace struct {
+	Denom     string
+	Path      string
+	BaseDenom string
+}
+
+func setupDenomsFlags(conf *denoms, cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&conf.DisplayViews, "denoms.display-views", false, "create the display_amount function and views converting base denom amounts to display units")
+	cmd.PersistentFlags().BoolVar(&conf.BankMetadata, "denoms.bank-metadata", true, "read the display units from the denom metadata of the bank module on startup")
+	cmd.PersistentFlags().StringVar(&conf.MetadataFile, "denoms.metadata-file", "", "path to a JSON file of display units, e.g. of IBC denoms, which take precedence over the bank module metadata")
+	cmd.PersistentFlags().BoolVar(&conf.IBCTraces, "denoms.ibc-traces", false, "read the origins of the IBC voucher denoms from the transfer module on startup, so fees are normalized to the denoms they were issued as")
+}
+
+func validateDenomsConf(conf denoms) error {
+	if conf.MetadataFile == "" {
+		return nil
+	}
+
+	if !conf.DisplayViews {
+		return errors.New("denoms.metadata-file requires denoms.display-views")
+	}
+
+	if _, err := os.Stat(conf.MetadataFile); os.IsNotExist(err) {
+		return fmt.Errorf("denoms.metadata-file %s does not exist", conf.MetadataFile)
+	}
+
+	return nil
+}
+
+func addDenomsConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(denoms{}, "denoms") {
+		validKeys[key] = struct{}{}
+	}
+}
+
+// LoadDenomDisplays reads and validates the display units of a JSON file
+func LoadDenomDisplays(path string) ([]DenomDisplay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseDenomDisplays(data)
+}
+
+func ParseDenomDisplays(data []byte) ([]DenomDisplay, error) {
+	var file struct {
+		Denoms []DenomDisplay `json:"denoms"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse denom display units: %w", err)
+	}
+
+	bases := make(map[string]struct{}, len(file.Denoms))
+	for _, denom := range file.Denoms {
+		if denom.Base == "" || denom.Display == "" {
+			return nil, errors.New("every denom display unit needs a base and a display denom")
+		}
+		if _, ok := bases[denom.Base]; ok {
+			return nil, fmt.Errorf("the display unit of denom %s is declared twice", denom.Base)
+		}
+		bases[denom.Base] = struct{}{}
+
+		// 10^77 still fits the decimal(78,0) amounts
+		if denom.Exponent > 77 {
+			return nil, fmt.Errorf("exponent %d of denom %s is too large", denom.Exponent, denom.Base)
+		}
+	}
+
+	return file.Denoms, nil
+}
@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type DenomsConfigTestSuite struct {
+	suite.Suite
+}
+
+func (suite *DenomsConfigTestSuite) TestParseDenomDisplays() {
+	denoms, err := ParseDenomDisplays([]byte(`{"denoms": [{"base": "ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2", "display": "atom", "exponent": 6}]}`))
+	suite.Require().NoError(err)
+	suite.Require().Equal([]DenomDisplay{{Base: "ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2", Display: "atom", Exponent: 6}}, denoms)
+
+	invalid := []string{
+		`{"denoms": [{"display": "atom", "exponent": 6}]}`,
+		`{"denoms": [{"base": "uatom", "exponent": 6}]}`,
+		`{"denoms": [{"base": "uatom", "display": "atom", "exponent": 78}]}`,
+		`{"denoms": [{"base": "uatom", "display": "atom", "exponent": 6}, {"base": "uatom", "display": "matom", "exponent": 3}]}`,
+		`{"denoms": [{"base": "uatom", "display": "atom", "exponent": -1}]}`,
+	}
+	for _, data := range invalid {
+		_, err := ParseDenomDisplays([]byte(data))
+		suite.Require().Error(err, data)
+	}
+}
+
+func TestDenomsConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(DenomsConfigTestSuite))
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/DefiantLabs/cosmos-indexer/util"
+	"github.com/spf13/cobra"
+)
+
+// DexPricesConfig is the config of the dex-prices command, which derives swap prices from the indexed DEX events
+type DexPricesConfig struct {
+	Database Database
+	Log      log
+	Dex      dexBase
+}
+
+type dexBase struct {
+	ChainID     string `mapstructure:"chain-id"`
+	StartHeight int64  `mapstructure:"start-height"`
+	EndHeight   int64  `mapstructure:"end-height"`
+	Rederive    bool   `mapstructure:"rederive"`
+}
+
+func SetupDexPricesFlags(conf *DexPricesConfig, cmd *cobra.Command) {
+	cmd.Flags().StringVar(&conf.Dex.ChainID, "dex.chain-id", "", "chain ID of the indexed chain to derive swap prices of")
+	cmd.Flags().Int64Var(&conf.Dex.StartHeight, "dex.start-height", 1, "first block height to derive swap prices of")
+	cmd.Flags().Int64Var(&conf.Dex.EndHeight, "dex.end-height", 0, "last block height to derive swap prices of (0 for every indexed block)")
+	cmd.Flags().BoolVar(&conf.Dex.Rederive, "dex.rederive", false, "derive the swap prices of the blocks in the range again")
+}
+
+func (conf *DexPricesConfig) Validate() error {
+	err := validateDatabaseConf(conf.Database)
+	if err != nil {
+		return err
+	}
+
+	if util.StrNotSet(conf.Dex.ChainID) {
+		return errors.New("dex.chain-id must be set")
+	}
+
+	if conf.Dex.StartHeight < 1 || (conf.Dex.EndHeight != 0 && conf.Dex.EndHeight < conf.Dex.StartHeight) {
+		return errors.New("dex.start-height must be at least 1 and dex.end-height must not be below it")
+	}
+
+	return nil
+}
+
+func addDexConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(dexBase{}, "dex") {
+		validKeys[key] = struct{}{}
+	}
+}
+
+// The config file is shared by all commands, so the dex-prices command accepts the same keys as the index command
+func CheckSuperfluousDexPricesKeys(keys []string) []string {
+	return CheckSuperfluousIndexKeys(keys)
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Additional RPC endpoints sharing the block requests with probe.rpc by role and weight, and the health scoring taking
+// degraded ones out of the rotation
+type endpoints struct {
+	RPC []string `mapstructure:"rpc"`
+	// File declares endpoints with a role and weight, see RPCEndpoint
+	File string `mapstructure:"file"`
+	// HeadWindow is the number of blocks behind the chain head requested from the head endpoints, older heights are
+	// backfill requested from the archive endpoints
+	HeadWindow int64 `mapstructure:"head-window"`
+	// Window is the number of recent requests of an endpoint its error rate and latency are scored over
+	Window       int           `mapstructure:"window"`
+	MaxErrorRate float64       `mapstructure:"max-error-rate"`
+	MaxLatency   time.Duration `mapstructure:"max-latency"`
+	// MaxConsecutiveFailures blacklists an endpoint without waiting for its window to fill up
+	MaxConsecutiveFailures int `mapstructure:"max-consecutive-failures"`
+	// Blacklist is how long an unhealthy endpoint is left out before it is probed again
+	Blacklist time.Duration `mapstructure:"blacklist"`
+	// Failover is the number of other endpoints a failed request is sent to
+	Failover int `mapstructure:"failover"`
+}
+
+func setupEndpointsFlags(conf *endpoints, cmd *cobra.Command) {
+	cmd.PersistentFlags().StringSliceVar(&conf.RPC, "endpoints.rpc", nil, "additional node rpc endpoints sharing the block requests with probe.rpc, can be repeated")
+	cmd.PersistentFlags().StringVar(&conf.File, "endpoints.file", "", "path to a JSON file declaring additional rpc endpoints with a role, weight and height range")
+	cmd.PersistentFlags().Int64Var(&conf.HeadWindow, "endpoints.head-window", 1000, "blocks behind the chain head requested from the head endpoints, older blocks are requested from the archive endpoints")
+	cmd.PersistentFlags().IntVar(&conf.Window, "endpoints.window", 20, "number of recent requests of an endpoint its health is scored over")
+	cmd.PersistentFlags().Float64Var(&conf.MaxErrorRate, "endpoints.max-error-rate", 0.5, "share of failed requests in the window over which an endpoint is blacklisted")
+	cmd.PersistentFlags().DurationVar(&conf.MaxLatency, "endpoints.max-latency", 10*time.Second, "average latency of the requests in the window over which an endpoint is blacklisted (0 to disable)")
+	cmd.PersistentFlags().IntVar(&conf.MaxConsecutiveFailures, "endpoints.max-consecutive-failures", 5, "number of failed requests in a row after which an endpoint is blacklisted right away (0 to disable)")
+	cmd.PersistentFlags().DurationVar(&conf.Blacklist, "endpoints.blacklist", time.Minute, "time an unhealthy endpoint is left out of the rotation before it is probed again")
+	cmd.PersistentFlags().IntVar(&conf.Failover, "endpoints.failover", 1, "number of other healthy endpoints a failed request is sent to before it fails (0 to disable)")
+}
+
+func validateEndpointsConf(conf endpoints) error {
+	// The health of a single endpoint is not scored, it receives every request anyway
+	if len(conf.RPC) == 0 && conf.File == "" {
+		return nil
+	}
+
+	if conf.File != "" {
+		if _, err := os.Stat(conf.File); os.IsNotExist(err) {
+			return fmt.Errorf("endpoints.file %s does not exist", conf.File)
+		}
+	}
+
+	if conf.HeadWindow < 0 {
+		return errors.New("endpoints.head-window must be a positive number or 0")
+	}
+
+	if conf.Window < 1 {
+		return errors.New("endpoints.window must be at least 1")
+	}
+
+	if conf.MaxErrorRate <= 0 || conf.MaxErrorRate > 1 {
+		return errors.New("endpoints.max-error-rate must be above 0 and at most 1")
+	}
+
+	if conf.MaxLatency < 0 {
+		return errors.New("endpoints.max-latency must be positive or 0")
+	}
+
+	if conf.MaxConsecutiveFailures < 0 {
+		return errors.New("endpoints.max-consecutive-failures must be a positive number or 0")
+	}
+
+	if conf.Failover < 0 {
+		return errors.New("endpoints.failover must be a positive number or 0")
+	}
+
+	if conf.Blacklist <= 0 {
+		return errors.New("endpoints.blacklist must be positive")
+	}
+
+	return nil
+}
+
+// RPCEndpoint is an endpoint declared in the endpoints file. Its role decides the requests it serves: any, archive for
+// the backfill of old heights, head for the heights within the head window, or fallback for when no other endpoint is
+// healthy. Endpoints of a role share its requests in proportion to their weight, 1 if unset. An endpoint with a start
+// or end height only serves the heights in that range, an end height of 0 leaves it open ended.
+type RPCEndpoint struct {
+	RPC         string `json:"rpc"`
+	Role        string `json:"role"`
+	Weight      int    `json:"weight"`
+	StartHeight int64  `json:"start_height"`
+	EndHeight   int64  `json:"end_height"`
+}
+
+// LoadRPCEndpoints reads and validates the endpoints declared in a JSON file
+func LoadRPCEndpoints(path string) ([]RPCEndpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseRPCEndpoints(data)
+}
+
+func ParseRPCEndpoints(data []byte) ([]RPCEndpoint, error) {
+	var file struct {
+		Endpoints []RPCEndpoint `json:"endpoints"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse rpc endpoints: %w", err)
+	}
+
+	addresses := make(map[string]struct{}, len(file.Endpoints))
+	for i := range file.Endpoints {
+		endpoint := &file.Endpoints[i]
+
+		if endpoint.RPC == "" {
+			return nil, fmt.Errorf("rpc endpoint %d has no rpc address", i+1)
+		}
+		if _, ok := addresses[endpoint.RPC]; ok {
+			return nil, fmt.Errorf("rpc endpoint %s is declared twice", endpoint.RPC)
+		}
+		addresses[endpoint.RPC] = struct{}{}
+
+		switch endpoint.Role {
+		case "":
+			endpoint.Role = "any"
+		case "any", "archive", "head", "fallback":
+		default:
+			return nil, fmt.Errorf("role %q of rpc endpoint %s must be any, archive, head or fallback", endpoint.Role, endpoint.RPC)
+		}
+
+		if endpoint.Weight < 0 {
+			return nil, fmt.Errorf("weight of rpc endpoint %s must be a positive number", endpoint.RPC)
+		}
+		if endpoint.Weight == 0 {
+			endpoint.Weight = 1
+		}
+
+		if endpoint.StartHeight < 0 || endpoint.EndHeight < 0 {
+			return nil, fmt.Errorf("heights of rpc endpoint %s must be positive numbers", endpoint.RPC)
+		}
+		if endpoint.EndHeight != 0 && endpoint.EndHeight < endpoint.StartHeight {
+			return nil, fmt.Errorf("end height of rpc endpoint %s is below its start height", endpoint.RPC)
+		}
+	}
+
+	return file.Endpoints, nil
+}
+
+func addEndpointsConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(endpoints{}, "endpoints") {
+		validKeys[key] = struct{}{}
+	}
+}
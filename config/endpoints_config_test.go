@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type EndpointsConfigTestSuite struct {
+	suite.Suite
+}
+
+func (suite *EndpointsConfigTestSuite) TestParseRPCEndpoints() {
+	endpoints, err := ParseRPCEndpoints([]byte(`{"endpoints": [{"rpc": "https://archive.example.com:443", "role": "archive", "weight": 3}, {"rpc": "https://public.example.com:443"}]}`))
+	suite.Require().NoError(err)
+	suite.Require().Len(endpoints, 2)
+	suite.Require().Equal("archive", endpoints[0].Role)
+	suite.Require().Equal(3, endpoints[0].Weight)
+	suite.Require().Equal("any", endpoints[1].Role)
+	suite.Require().Equal(1, endpoints[1].Weight)
+
+	endpoints, err = ParseRPCEndpoints([]byte(`{"endpoints": [{"rpc": "https://archive.example.com:443", "end_height": 4999999}, {"rpc": "http://localhost:26657", "start_height": 5000000}]}`))
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(4999999), endpoints[0].EndHeight)
+	suite.Require().Equal(int64(5000000), endpoints[1].StartHeight)
+
+	invalid := []string{
+		`{"endpoints": [{"role": "archive"}]}`,
+		`{"endpoints": [{"rpc": "https://a.example.com", "role": "primary"}]}`,
+		`{"endpoints": [{"rpc": "https://a.example.com", "weight": -1}]}`,
+		`{"endpoints": [{"rpc": "https://a.example.com"}, {"rpc": "https://a.example.com"}]}`,
+		`{"endpoints": [{"rpc": "https://a.example.com", "start_height": 100, "end_height": 10}]}`,
+		`{"endpoints": [{"rpc": "https://a.example.com", "start_height": -1}]}`,
+	}
+	for _, data := range invalid {
+		_, err := ParseRPCEndpoints([]byte(data))
+		suite.Require().Error(err, data)
+	}
+}
+
+func TestEndpointsConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(EndpointsConfigTestSuite))
+}
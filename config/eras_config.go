@@ -0,0 +1,107 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+// Chain eras indexed with the compatibility behaviour of older node versions, so the full history of a long-lived
+// chain can be indexed from the nodes of each era
+type eras struct {
+	// File declares the eras, see ChainEra
+	File string `mapstructure:"file"`
+}
+
+func setupErasFlags(conf *eras, cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&conf.File, "eras.file", "", "path to a JSON file declaring height ranges indexed from other nodes or with the compatibility behaviour of older node versions")
+}
+
+func validateErasConf(conf eras) error {
+	if conf.File != "" {
+		if _, err := os.Stat(conf.File); os.IsNotExist(err) {
+			return fmt.Errorf("eras.file %s does not exist", conf.File)
+		}
+	}
+	return nil
+}
+
+// ChainEra is a range of heights declared in the eras file, an end height of 0 leaves it open ended. Its blocks are
+// requested from the rpc node of the era, probe.rpc if unset. Block results "amino" decodes the block results of
+// Tendermint 0.32 and 0.33, tx search "tendermint" searches the transactions with the tx_search of the RPC server
+// instead of the gRPC transaction service of the Cosmos SDK.
+type ChainEra struct {
+	StartHeight  int64  `json:"start_height"`
+	EndHeight    int64  `json:"end_height"`
+	RPC          string `json:"rpc"`
+	BlockResults string `json:"block_results"`
+	TxSearch     string `json:"tx_search"`
+}
+
+// LoadChainEras reads and validates the eras declared in a JSON file
+func LoadChainEras(path string) ([]ChainEra, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseChainEras(data)
+}
+
+// ParseChainEras parses the eras of a JSON file, sorted by their start height
+func ParseChainEras(data []byte) ([]ChainEra, error) {
+	var file struct {
+		Eras []ChainEra `json:"eras"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse chain eras: %w", err)
+	}
+
+	for i := range file.Eras {
+		era := &file.Eras[i]
+
+		if era.StartHeight < 1 {
+			return nil, fmt.Errorf("chain era %d must start at a height of 1 or more", i+1)
+		}
+		if era.EndHeight != 0 && era.EndHeight < era.StartHeight {
+			return nil, fmt.Errorf("chain era %d must end at or after its start height %d", i+1, era.StartHeight)
+		}
+
+		switch era.BlockResults {
+		case "":
+			era.BlockResults = "default"
+		case "default", "amino":
+		default:
+			return nil, fmt.Errorf("block results %q of chain era %d must be default or amino", era.BlockResults, i+1)
+		}
+
+		switch era.TxSearch {
+		case "":
+			era.TxSearch = "grpc"
+		case "grpc", "tendermint":
+		default:
+			return nil, fmt.Errorf("tx search %q of chain era %d must be grpc or tendermint", era.TxSearch, i+1)
+		}
+	}
+
+	sort.SliceStable(file.Eras, func(i, j int) bool {
+		return file.Eras[i].StartHeight < file.Eras[j].StartHeight
+	})
+	for i := 1; i < len(file.Eras); i++ {
+		previous := file.Eras[i-1]
+		if previous.EndHeight == 0 || previous.EndHeight >= file.Eras[i].StartHeight {
+			return nil, fmt.Errorf("chain eras starting at heights %d and %d overlap", previous.StartHeight, file.Eras[i].StartHeight)
+		}
+	}
+
+	return file.Eras, nil
+}
+
+func addErasConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(eras{}, "eras") {
+		validKeys[key] = struct{}{}
+	}
+}
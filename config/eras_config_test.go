@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ErasConfigTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ErasConfigTestSuite) TestParseChainEras() {
+	eras, err := ParseChainEras([]byte(`{"eras": [
+		{"start_height": 5200791, "end_height": 0},
+		{"start_height": 1, "end_height": 5200790, "rpc": "https://cosmoshub-3.example.com:443", "block_results": "amino", "tx_search": "tendermint"}
+	]}`))
+	suite.Require().NoError(err)
+	suite.Require().Len(eras, 2)
+	suite.Require().Equal(int64(1), eras[0].StartHeight)
+	suite.Require().Equal("amino", eras[0].BlockResults)
+	suite.Require().Equal("tendermint", eras[0].TxSearch)
+	suite.Require().Equal("default", eras[1].BlockResults)
+	suite.Require().Equal("grpc", eras[1].TxSearch)
+
+	invalid := []string{
+		`{"eras": [{"start_height": 0}]}`,
+		`{"eras": [{"start_height": 10, "end_height": 5}]}`,
+		`{"eras": [{"start_height": 1, "block_results": "proto"}]}`,
+		`{"eras": [{"start_height": 1, "tx_search": "lcd"}]}`,
+		`{"eras": [{"start_height": 1, "end_height": 10}, {"start_height": 10}]}`,
+		`{"eras": [{"start_height": 1}, {"start_height": 10}]}`,
+	}
+	for _, data := range invalid {
+		_, err := ParseChainEras([]byte(data))
+		suite.Require().Error(err, data)
+	}
+}
+
+func TestErasConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(ErasConfigTestSuite))
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"errors"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/util"
+	"github.com/spf13/cobra"
+)
+
+// ExportConfig is the config of the export commands, which write a chain and height range of the indexed data to a file
+// or data warehouse
+type ExportConfig struct {
+	Database  Database
+	Log       log
+	Export    exportBase
+	DuckDB    duckDBExport
+	Snowflake snowflakeExport
+	State     stateExport
+	Buckets   bucketsExport
+}
+
+type exportBase struct {
+	ChainID      string `mapstructure:"chain-id"`
+	StartHeight  int64  `mapstructure:"start-height"`
+	EndHeight    int64  `mapstructure:"end-height"`
+	Overwrite    bool   `mapstructure:"overwrite"`
+	Anonymize    bool   `mapstructure:"anonymize"`
+	AnonymizeKey string `mapstructure:"anonymize-key"`
+}
+
+type duckDBExport struct {
+	Output string `mapstructure:"output"`
+	Binary string `mapstructure:"binary"`
+}
+
+type stateExport struct {
+	Height int64  `mapstructure:"height"`
+	Output string `mapstructure:"output"`
+}
+
+type bucketsExport struct {
+	Interval string `mapstructure:"interval"`
+	Timezone string `mapstructure:"timezone"`
+	// StartTime and EndTime are RFC 3339 times or dates starting at midnight in the timezone
+	StartTime string `mapstructure:"start-time"`
+	EndTime   string `mapstructure:"end-time"`
+	Output    string `mapstructure:"output"`
+}
+
+type snowflakeExport struct {
+	Account        string        `mapstructure:"account"`
+	User           string        `mapstructure:"user"`
+	PrivateKeyFile string        `mapstructure:"private-key-file"`
+	Role           string        `mapstructure:"role"`
+	Warehouse      string        `mapstructure:"warehouse"`
+	Database       string        `mapstructure:"database"`
+	Schema         string        `mapstructure:"schema"`
+	Endpoint       string        `mapstructure:"endpoint"`
+	Stage          string        `mapstructure:"stage"`
+	StageBucket    string        `mapstructure:"stage-bucket"`
+	StagePrefix    string        `mapstructure:"stage-prefix"`
+	StageRegion    string        `mapstructure:"stage-region"`
+	StageEndpoint  string        `mapstructure:"stage-endpoint"`
+	Snowpipe       bool          `mapstructure:"snowpipe"`
+	BatchHeights   int64         `mapstructure:"batch-heights"`
+	Interval       time.Duration `mapstructure:"interval"`
+}
+
+func SetupExportFlags(conf *ExportConfig, cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&conf.Export.ChainID, "export.chain-id", "", "chain ID of the indexed chain to export")
+	cmd.PersistentFlags().Int64Var(&conf.Export.StartHeight, "export.start-height", 1, "first block height to export")
+	cmd.PersistentFlags().Int64Var(&conf.Export.EndHeight, "export.end-height", 0, "last block height to export (0 for the latest indexed height where supported)")
+	cmd.PersistentFlags().BoolVar(&conf.Export.Overwrite, "export.overwrite", false, "replace the output file if it already exists")
+	cmd.PersistentFlags().BoolVar(&conf.Export.Anonymize, "export.anonymize", false, "pseudonymize addresses and drop memos and raw transactions, for sharing datasets externally")
+	cmd.PersistentFlags().StringVar(&conf.Export.AnonymizeKey, "export.anonymize-key", "", "secret key the addresses are pseudonymized with, the same key gives the same pseudonyms")
+}
+
+func SetupDuckDBExportFlags(conf *ExportConfig, cmd *cobra.Command) {
+	cmd.Flags().StringVar(&conf.DuckDB.Output, "duckdb.output", "", "path of the DuckDB database file to create")
+	cmd.Flags().StringVar(&conf.DuckDB.Binary, "duckdb.binary", "duckdb", "DuckDB CLI used to build the database file")
+}
+
+func SetupStateExportFlags(conf *ExportConfig, cmd *cobra.Command) {
+	cmd.Flags().Int64Var(&conf.State.Height, "state.height", 0, "height to reconstruct the balances and delegations at")
+	cmd.Flags().StringVar(&conf.State.Output, "state.output", "", "path of the JSON file to create")
+}
+
+func SetupBucketsExportFlags(conf *ExportConfig, cmd *cobra.Command) {
+	cmd.Flags().StringVar(&conf.Buckets.Interval, "buckets.interval", "day", "length of the time buckets, hour, day, week or month")
+	cmd.Flags().StringVar(&conf.Buckets.Timezone, "buckets.timezone", "UTC", "IANA time zone of the calendar the buckets follow, e.g. America/New_York")
+	cmd.Flags().StringVar(&conf.Buckets.StartTime, "buckets.start-time", "", "start of the time range, inclusive, as RFC 3339 time or as date")
+	cmd.Flags().StringVar(&conf.Buckets.EndTime, "buckets.end-time", "", "end of the time range, exclusive, as RFC 3339 time or as date")
+	cmd.Flags().StringVar(&conf.Buckets.Output, "buckets.output", "", "path of the CSV file to create")
+}
+
+func SetupSnowflakeExportFlags(conf *ExportConfig, cmd *cobra.Command) {
+	cmd.Flags().StringVar(&conf.Snowflake.Account, "snowflake.account", "", "Snowflake account identifier, e.g. myorg-myaccount")
+	cmd.Flags().StringVar(&conf.Snowflake.User, "snowflake.user", "", "Snowflake user authenticating with key pair authentication")
+	cmd.Flags().StringVar(&conf.Snowflake.PrivateKeyFile, "snowflake.private-key-file", "", "unencrypted PEM private key whose public key is set as RSA_PUBLIC_KEY of the user")
+	cmd.Flags().StringVar(&conf.Snowflake.Role, "snowflake.role", "", "role used to run the statements (defaults to the default role of the user)")
+	cmd.Flags().StringVar(&conf.Snowflake.Warehouse, "snowflake.warehouse", "", "warehouse used to run the statements")
+	cmd.Flags().StringVar(&conf.Snowflake.Database, "snowflake.database", "", "database the tables are created in")
+	cmd.Flags().StringVar(&conf.Snowflake.Schema, "snowflake.schema", "PUBLIC", "schema the tables are created in")
+	cmd.Flags().StringVar(&conf.Snowflake.Endpoint, "snowflake.endpoint", "", "account URL override (defaults to https://<account>.snowflakecomputing.com)")
+	cmd.Flags().StringVar(&conf.Snowflake.Stage, "snowflake.stage", "", "external stage the files are loaded from")
+	cmd.Flags().StringVar(&conf.Snowflake.StageBucket, "snowflake.stage-bucket", "", "S3 bucket of the external stage")
+	cmd.Flags().StringVar(&conf.Snowflake.StagePrefix, "snowflake.stage-prefix", "", "S3 key prefix of the external stage URL")
+	cmd.Flags().StringVar(&conf.Snowflake.StageRegion, "snowflake.stage-region", "", "AWS region of the bucket (defaults to the region of the shared AWS config)")
+	cmd.Flags().StringVar(&conf.Snowflake.StageEndpoint, "snowflake.stage-endpoint", "", "S3 endpoint override for S3 compatible storage")
+	cmd.Flags().BoolVar(&conf.Snowflake.Snowpipe, "snowflake.snowpipe", false, "queue the staged files with a Snowpipe per table instead of running COPY statements")
+	cmd.Flags().Int64Var(&conf.Snowflake.BatchHeights, "snowflake.batch-heights", 10000, "number of heights exported to each staged file")
+	cmd.Flags().DurationVar(&conf.Snowflake.Interval, "snowflake.interval", 0, "run the load on this interval until interrupted (0 to run once)")
+}
+
+func (conf *ExportConfig) Validate() error {
+	err := validateDatabaseConf(conf.Database)
+	if err != nil {
+		return err
+	}
+
+	if util.StrNotSet(conf.Export.ChainID) {
+		return errors.New("export.chain-id must be set")
+	}
+
+	if conf.Export.StartHeight < 1 || (conf.Export.EndHeight != 0 && conf.Export.EndHeight < conf.Export.StartHeight) {
+		return errors.New("export.start-height must be at least 1 and export.end-height must not be below it")
+	}
+
+	// Pseudonyms of a short key can be reversed by hashing every address of the chain under every key
+	if conf.Export.Anonymize && len(conf.Export.AnonymizeKey) < 16 {
+		return errors.New("export.anonymize-key must be at least 16 characters when export.anonymize is set")
+	}
+
+	return nil
+}
+
+func (conf *ExportConfig) ValidateDuckDB() error {
+	if conf.Export.EndHeight == 0 {
+		return errors.New("export.end-height must be set")
+	}
+
+	if util.StrNotSet(conf.DuckDB.Output) {
+		return errors.New("duckdb.output must be set")
+	}
+
+	if util.StrNotSet(conf.DuckDB.Binary) {
+		return errors.New("duckdb.binary must be set")
+	}
+
+	return nil
+}
+
+func (conf *ExportConfig) ValidateState() error {
+	if conf.State.Height < 1 {
+		return errors.New("state.height must be set")
+	}
+
+	if util.StrNotSet(conf.State.Output) {
+		return errors.New("state.output must be set")
+	}
+
+	// The snapshot is a list of addresses and their holdings, pseudonyms would leave nothing to audit
+	if conf.Export.Anonymize {
+		return errors.New("export.anonymize is not supported by the state export")
+	}
+
+	return nil
+}
+
+func (conf *ExportConfig) ValidateBuckets() error {
+	switch conf.Buckets.Interval {
+	case "hour", "day", "week", "month":
+	default:
+		return errors.New("buckets.interval must be hour, day, week or month")
+	}
+
+	if location, err := time.LoadLocation(conf.Buckets.Timezone); err != nil || location == time.Local {
+		return errors.New("buckets.timezone must be an IANA time zone name, e.g. America/New_York")
+	}
+
+	if util.StrNotSet(conf.Buckets.StartTime) || util.StrNotSet(conf.Buckets.EndTime) {
+		return errors.New("buckets.start-time and buckets.end-time must be set")
+	}
+
+	if util.StrNotSet(conf.Buckets.Output) {
+		return errors.New("buckets.output must be set")
+	}
+
+	return nil
+}
+
+func (conf *ExportConfig) ValidateSnowflake() error {
+	if util.StrNotSet(conf.Snowflake.Account) || util.StrNotSet(conf.Snowflake.User) || util.StrNotSet(conf.Snowflake.PrivateKeyFile) {
+		return errors.New("snowflake.account, snowflake.user and snowflake.private-key-file must be set")
+	}
+
+	if util.StrNotSet(conf.Snowflake.Warehouse) || util.StrNotSet(conf.Snowflake.Database) || util.StrNotSet(conf.Snowflake.Schema) {
+		return errors.New("snowflake.warehouse, snowflake.database and snowflake.schema must be set")
+	}
+
+	if util.StrNotSet(conf.Snowflake.Stage) || util.StrNotSet(conf.Snowflake.StageBucket) {
+		return errors.New("snowflake.stage and snowflake.stage-bucket must be set")
+	}
+
+	if conf.Snowflake.BatchHeights < 1 {
+		return errors.New("snowflake.batch-heights must be at least 1")
+	}
+
+	if conf.Snowflake.Interval < 0 {
+		return errors.New("snowflake.interval must not be negative")
+	}
+
+	return nil
+}
+
+func addExportConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(exportBase{}, "export") {
+		validKeys[key] = struct{}{}
+	}
+
+	for _, key := range getValidConfigKeys(duckDBExport{}, "duckdb") {
+		validKeys[key] = struct{}{}
+	}
+
+	for _, key := range getValidConfigKeys(snowflakeExport{}, "snowflake") {
+		validKeys[key] = struct{}{}
+	}
+
+	for _, key := range getValidConfigKeys(stateExport{}, "state") {
+		validKeys[key] = struct{}{}
+	}
+
+	for _, key := range getValidConfigKeys(bucketsExport{}, "buckets") {
+		validKeys[key] = struct{}{}
+	}
+}
+
+// The config file is shared by all commands, so the export commands accept the same keys as the index command
+func CheckSuperfluousExportKeys(keys []string) []string {
+	return CheckSuperfluousIndexKeys(keys)
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+// Detection of the heights missing from the blocks table, e.g. the holes a crash left behind, by the gaps command and
+// in the background of the index command
+type gaps struct {
+	// Fill makes the gaps command index the missing heights instead of only reporting them
+	Fill bool `mapstructure:"fill"`
+	// Interval is the number of seconds between the scans of the index command, 0 disables them
+	Interval int64 `mapstructure:"interval"`
+}
+
+func setupGapsFlags(conf *gaps, cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&conf.Fill, "gaps.fill", false, "index the missing heights found by the gaps command instead of only reporting them")
+	cmd.PersistentFlags().Int64Var(&conf.Interval, "gaps.interval", 0, "seconds between scans of the indexed range for missing heights while indexing, heights missing in two scans in a row are indexed again (0 to disable)")
+}
+
+func validateGapsConf(conf gaps) error {
+	if conf.Interval < 0 {
+		return errors.New("gaps.interval must be a positive number or 0")
+	}
+
+	return nil
+}
+
+func addGapsConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(gaps{}, "gaps") {
+		validKeys[key] = struct{}{}
+	}
+}
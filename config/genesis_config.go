@@ -0,0 +1,54 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/DefiantLabs/cosmos-indexer/util"
+	"github.com/spf13/cobra"
+)
+
+// GenesisConfig is the config of the genesis import command, which indexes the state of an exported genesis file
+type GenesisConfig struct {
+	Database Database
+	Log      log
+	Genesis  genesisBase
+}
+
+type genesisBase struct {
+	// File is the exported genesis file of the chain, the chain ID it is indexed for is read from it
+	File string `mapstructure:"file"`
+}
+
+func SetupGenesisFlags(conf *GenesisConfig, cmd *cobra.Command) {
+	cmd.Flags().StringVar(&conf.Genesis.File, "genesis.file", "", "path to the exported genesis file of the chain to import")
+}
+
+func (conf *GenesisConfig) Validate() error {
+	err := validateDatabaseConf(conf.Database)
+	if err != nil {
+		return err
+	}
+
+	if util.StrNotSet(conf.Genesis.File) {
+		return errors.New("genesis.file must be set")
+	}
+
+	if _, err := os.Stat(conf.Genesis.File); err != nil {
+		return fmt.Errorf("genesis.file %s can not be read: %w", conf.Genesis.File, err)
+	}
+
+	return nil
+}
+
+func addGenesisConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(genesisBase{}, "genesis") {
+		validKeys[key] = struct{}{}
+	}
+}
+
+// The config file is shared by all commands, so the genesis command accepts the same keys as the index command
+func CheckSuperfluousGenesisKeys(keys []string) []string {
+	return CheckSuperfluousIndexKeys(keys)
+}
@@ -4,16 +4,41 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/DefiantLabs/cosmos-indexer/util"
 	"github.com/spf13/cobra"
 )
 
 type IndexConfig struct {
-	Database Database
-	Base     indexBase
-	Log      log
-	Probe    Probe
-	Flags    flags
+	Database    Database
+	Base        indexBase
+	Log         log
+	Probe       Probe
+	Flags       flags
+	Metrics     metrics
+	CDC         cdc
+	Sinks       sinks
+	Views       views
+	Cache       dictionaryCache
+	Queue       blockQueue
+	Admin       adminAPI
+	Schedule    indexSchedule
+	Watchdog    watchdog
+	Synthetic   synthetic
+	Chaos       chaos
+	Denoms      denoms
+	Ledger      ledger
+	Jobs        jobs
+	Endpoints   endpoints
+	Eras        eras
+	Verify      verify
+	Consistency consistency
+	Reorg       reorg
+	Gaps        gaps
+	Search      search
+	Coins       coins
+	Wasm        wasm
 }
 
 type indexBase struct {
@@ -34,18 +59,62 @@ type indexBase struct {
 	BlockEventIndexingEnabled  bool   `mapstructure:"index-block-events"`
 	FilterFile                 string `mapstructure:"filter-file"`
 	Dry                        bool   `mapstructure:"dry"`
+	CustomParserTimeout        int64  `mapstructure:"custom-parser-timeout"`
+	RecordFixtures             string `mapstructure:"record-fixtures"`
+	ReplayFixtures             string `mapstructure:"replay-fixtures"`
+	ReplayFixturesRegion       string `mapstructure:"replay-fixtures-region"`
+	ReplayFixturesEndpoint     string `mapstructure:"replay-fixtures-endpoint"`
+	OversizedBlockTxs          int    `mapstructure:"oversized-block-txs"`
+	OversizedBlockBytes        int64  `mapstructure:"oversized-block-bytes"`
+	OversizedPageSize          uint64 `mapstructure:"oversized-page-size"`
+	ShutdownTimeout            int64  `mapstructure:"shutdown-timeout"`
+	CheckpointInterval         int64  `mapstructure:"checkpoint-interval"`
+	BulkCopy                   bool   `mapstructure:"bulk-copy"`
+	BulkCopyBlocks             int64  `mapstructure:"bulk-copy-blocks"`
+}
+
+// Prometheus metrics and metric persistence settings
+type metrics struct {
+	Enabled             bool   `mapstructure:"enabled"`
+	Port                string `mapstructure:"port"`
+	ParserFlushInterval int64  `mapstructure:"parser-flush-interval"`
+	HeightStateInterval int64  `mapstructure:"height-state-interval"`
+	HeadInterval        int64  `mapstructure:"head-interval"`
+	HaltAfter           int64  `mapstructure:"halt-after"`
+}
+
+// In-memory caches of the dictionary tables (addresses, denoms, message and event types)
+type dictionaryCache struct {
+	Enabled      bool `mapstructure:"enabled"`
+	MaxAddresses int  `mapstructure:"max-addresses"`
+}
+
+// Stall detection, the RPC workers are restarted when no block made it through the pipeline for the timeout
+type watchdog struct {
+	StallTimeout time.Duration `mapstructure:"stall-timeout"`
+}
+
+// Change-data-capture settings, events are emitted in the same transaction as the indexed block data
+type cdc struct {
+	Notify  bool   `mapstructure:"notify"`
+	Channel string `mapstructure:"channel"`
+	Outbox  bool   `mapstructure:"outbox"`
 }
 
 // Flags for specific, deeper indexing behavior
 type flags struct {
-	IndexTxMessageRaw bool `mapstructure:"index-tx-message-raw"`
+	IndexTxMessageRaw        bool `mapstructure:"index-tx-message-raw"`
+	IndexAddressInvolvements bool `mapstructure:"index-address-involvements"`
+	IndexBlockCommits        bool `mapstructure:"index-block-commits"`
+	IndexConsensusMetrics    bool `mapstructure:"index-consensus-metrics"`
+	TrackModuleVersions      bool `mapstructure:"track-module-versions"`
 }
 
 func SetupIndexSpecificFlags(conf *IndexConfig, cmd *cobra.Command) {
 	// chain indexing
 	cmd.PersistentFlags().Int64Var(&conf.Base.StartBlock, "base.start-block", 0, "block to start indexing at (use -1 to resume from highest block indexed)")
 	cmd.PersistentFlags().Int64Var(&conf.Base.EndBlock, "base.end-block", -1, "block to stop indexing at (use -1 to index indefinitely")
-	cmd.PersistentFlags().StringVar(&conf.Base.BlockInputFile, "base.block-input-file", "", "A file location containing a JSON list of block heights to index. Will override start and end block flags.")
+	cmd.PersistentFlags().StringVar(&conf.Base.BlockInputFile, "base.block-input-file", "", "A file location containing a JSON list of block heights to index, or - to read heights or JSON enqueue records line by line from stdin. Will override start and end block flags.")
 	cmd.PersistentFlags().BoolVar(&conf.Base.ReIndex, "base.reindex", false, "if true, this will re-attempt to index blocks we have already indexed (defaults to false)")
 	cmd.PersistentFlags().BoolVar(&conf.Base.ReattemptFailedBlocks, "base.reattempt-failed-blocks", false, "re-enqueue failed blocks for reattempts at startup.")
 	cmd.PersistentFlags().StringVar(&conf.Base.ReindexMessageType, "base.reindex-message-type", "", "a Cosmos message type URL. When set, the block enqueue method will reindex all blocks between start and end block that contain this message type.")
@@ -63,9 +132,69 @@ func SetupIndexSpecificFlags(conf *IndexConfig, cmd *cobra.Command) {
 	cmd.PersistentFlags().BoolVar(&conf.Base.ExitWhenCaughtUp, "base.exit-when-caught-up", false, "mainly used for Osmosis rewards indexing")
 	cmd.PersistentFlags().Int64Var(&conf.Base.RequestRetryAttempts, "base.request-retry-attempts", 0, "number of RPC query retries to make")
 	cmd.PersistentFlags().Uint64Var(&conf.Base.RequestRetryMaxWait, "base.request-retry-max-wait", 30, "max retry incremental backoff wait time in seconds")
+	cmd.PersistentFlags().Int64Var(&conf.Base.DBRetryAttempts, "base.db-retry-attempts", 10, "number of retries of a block write failing with a transient DB error, -1 to retry until the DB is back")
+	cmd.PersistentFlags().Uint64Var(&conf.Base.DBRetryMaxWait, "base.db-retry-max-wait", 30, "max DB retry incremental backoff wait time in seconds")
+	cmd.PersistentFlags().StringVar(&conf.Base.RecordFixtures, "base.record-fixtures", "", "directory to record every RPC response to while indexing, in a format rpc.FixtureClient replays")
+	cmd.PersistentFlags().StringVar(&conf.Base.ReplayFixtures, "base.replay-fixtures", "", "directory or s3://bucket/prefix location of recorded RPC responses to index from instead of the node")
+	cmd.PersistentFlags().StringVar(&conf.Base.ReplayFixturesRegion, "base.replay-fixtures-region", "", "AWS region of an S3 replay-fixtures location")
+	cmd.PersistentFlags().StringVar(&conf.Base.ReplayFixturesEndpoint, "base.replay-fixtures-endpoint", "", "custom S3 endpoint of an S3 replay-fixtures location, e.g. for MinIO")
+	cmd.PersistentFlags().IntVar(&conf.Base.OversizedBlockTxs, "base.oversized-block-txs", 1000, "blocks with more transactions are oversized, their transactions are fetched in smaller pages (0 to disable)")
+	cmd.PersistentFlags().Int64Var(&conf.Base.OversizedBlockBytes, "base.oversized-block-bytes", 4194304, "blocks whose transactions take more bytes are oversized, their transactions are fetched in smaller pages (0 to disable)")
+	cmd.PersistentFlags().Uint64Var(&conf.Base.OversizedPageSize, "base.oversized-page-size", 10, "number of transactions fetched per request for oversized blocks")
+	cmd.PersistentFlags().Int64Var(&conf.Base.CustomParserTimeout, "base.custom-parser-timeout", 60, "seconds a custom parser may spend parsing a single message or block event before it is recorded as a parser error (0 to disable)")
+	cmd.PersistentFlags().Int64Var(&conf.Base.ShutdownTimeout, "base.shutdown-timeout", 60, "seconds to index the blocks in flight after an interrupt before stopping anyway (0 to stop right away)")
+	cmd.PersistentFlags().Int64Var(&conf.Base.CheckpointInterval, "base.checkpoint-interval", 60, "seconds between saves of the indexing checkpoint, which is also saved on shutdown (0 to only save it on shutdown)")
+	cmd.PersistentFlags().BoolVar(&conf.Base.BulkCopy, "base.bulk-copy", false, "write the transactions of the queued blocks together with Postgres COPY statements, speeds up backfills")
+	cmd.PersistentFlags().Int64Var(&conf.Base.BulkCopyBlocks, "base.bulk-copy-blocks", 50, "maximum number of blocks written together when bulk-copy is enabled")
+
+	// metrics
+	cmd.PersistentFlags().BoolVar(&conf.Metrics.Enabled, "metrics.enabled", false, "serve Prometheus metrics")
+	cmd.PersistentFlags().StringVar(&conf.Metrics.Port, "metrics.port", "9100", "port to serve Prometheus metrics on")
+	cmd.PersistentFlags().Int64Var(&conf.Metrics.ParserFlushInterval, "metrics.parser-flush-interval", 30, "seconds between writes of custom parser execution metrics to the database (0 to disable)")
+	cmd.PersistentFlags().Int64Var(&conf.Metrics.HeightStateInterval, "metrics.height-state-interval", 5, "seconds between writes of the pipeline stages reached by each height to the height_states table (0 to disable)")
+	cmd.PersistentFlags().Int64Var(&conf.Metrics.HeadInterval, "metrics.head-interval", 10, "seconds between records of the chain head and the highest indexed height to the chain_heads table (0 to disable)")
+	cmd.PersistentFlags().Int64Var(&conf.Metrics.HaltAfter, "metrics.halt-after", 300, "seconds the chain head may not advance before the chain is recorded as halted (0 to disable)")
+
+	// change-data-capture
+	cmd.PersistentFlags().BoolVar(&conf.CDC.Notify, "cdc.notify", false, "send a Postgres NOTIFY for every committed block dataset")
+	cmd.PersistentFlags().StringVar(&conf.CDC.Channel, "cdc.channel", "cosmos_indexer", "Postgres channel to send CDC notifications on")
+	cmd.PersistentFlags().BoolVar(&conf.CDC.Outbox, "cdc.outbox", false, "write a row to the cdc_outbox_events table for every committed block dataset")
+
+	setupSinkFlags(&conf.Sinks, cmd)
+	setupBlockQueueFlags(&conf.Queue, cmd)
+	setupAdminFlags(&conf.Admin, cmd)
+	setupScheduleFlags(&conf.Schedule, cmd)
+	setupSyntheticFlags(&conf.Synthetic, cmd)
+	setupChaosFlags(&conf.Chaos, cmd)
+	setupDenomsFlags(&conf.Denoms, cmd)
+	setupLedgerFlags(&conf.Ledger, cmd)
+	setupJobsFlags(&conf.Jobs, cmd)
+	setupEndpointsFlags(&conf.Endpoints, cmd)
+	setupErasFlags(&conf.Eras, cmd)
+	setupVerifyFlags(&conf.Verify, cmd)
+	setupConsistencyFlags(&conf.Consistency, cmd)
+	setupReorgFlags(&conf.Reorg, cmd)
+	setupGapsFlags(&conf.Gaps, cmd)
+	setupSearchFlags(&conf.Search, cmd)
+	setupCoinsFlags(&conf.Coins, cmd)
+	setupWasmFlags(&conf.Wasm, cmd)
+
+	// materialized views
+	cmd.PersistentFlags().StringVar(&conf.Views.File, "views.file", "", "path to a JSON file declaring materialized views to create and refresh while indexing")
+
+	// dictionary cache
+	cmd.PersistentFlags().BoolVar(&conf.Cache.Enabled, "cache.enabled", false, "keep the dictionary tables in memory, preloaded on startup, to skip their lookups and upserts per block")
+	cmd.PersistentFlags().IntVar(&conf.Cache.MaxAddresses, "cache.max-addresses", 1000000, "maximum number of addresses kept in the dictionary cache (0 to not cache addresses)")
+
+	// stall watchdog
+	cmd.PersistentFlags().DurationVar(&conf.Watchdog.StallTimeout, "watchdog.stall-timeout", 15*time.Minute, "restart the RPC workers when no block was indexed for this long while blocks are waiting (0 to disable)")
 
 	// flags
 	cmd.PersistentFlags().BoolVar(&conf.Flags.IndexTxMessageRaw, "flags.index-tx-message-raw", false, "if true, this will index the raw message bytes. This will significantly increase the size of the database.")
+	cmd.PersistentFlags().BoolVar(&conf.Flags.IndexAddressInvolvements, "flags.index-address-involvements", false, "if true, record the addresses each transaction touched in the address_involvements table, for lookups of the transactions of an address")
+	cmd.PersistentFlags().BoolVar(&conf.Flags.IndexBlockCommits, "flags.index-block-commits", false, "if true, index the last commit of each block: the round of the committed height and the signature flag and timestamp of each validator, in the block_commits and block_commit_signatures tables")
+	cmd.PersistentFlags().BoolVar(&conf.Flags.IndexConsensusMetrics, "flags.index-consensus-metrics", false, "if true, derive the consensus metrics of each block in the consensus_metrics table: the block interval, proposer, and with flags.index-block-commits the rounds and signature participation")
+	cmd.PersistentFlags().BoolVar(&conf.Flags.TrackModuleVersions, "flags.track-module-versions", false, "if true, record the module versions of the x/upgrade module and the heights of the upgrades that changed them in the module_versions table, and skip the custom parsers that declare other module versions than a block's")
 }
 
 func (conf *IndexConfig) Validate() error {
@@ -104,6 +233,159 @@ func (conf *IndexConfig) Validate() error {
 		}
 	}
 
+	if conf.Base.CustomParserTimeout < 0 {
+		return errors.New("base.custom-parser-timeout must be a positive number or 0")
+	}
+
+	if conf.Base.ShutdownTimeout < 0 {
+		return errors.New("base.shutdown-timeout must be a positive number or 0")
+	}
+
+	if conf.Base.CheckpointInterval < 0 {
+		return errors.New("base.checkpoint-interval must be a positive number or 0")
+	}
+
+	if conf.Base.BulkCopy && conf.Base.BulkCopyBlocks < 1 {
+		return errors.New("base.bulk-copy-blocks must be greater than 0 when bulk-copy is enabled")
+	}
+
+	if conf.Base.OversizedBlockTxs < 0 {
+		return errors.New("base.oversized-block-txs must be a positive number or 0")
+	}
+
+	if conf.Base.OversizedBlockBytes < 0 {
+		return errors.New("base.oversized-block-bytes must be a positive number or 0")
+	}
+
+	if (conf.Base.OversizedBlockTxs > 0 || conf.Base.OversizedBlockBytes > 0) && conf.Base.OversizedPageSize == 0 {
+		return errors.New("base.oversized-page-size must be greater than 0")
+	}
+
+	if conf.Base.RecordFixtures != "" && conf.Base.ReplayFixtures != "" {
+		return errors.New("base.record-fixtures and base.replay-fixtures cannot be used together")
+	}
+
+	if conf.Metrics.Enabled && util.StrNotSet(conf.Metrics.Port) {
+		return errors.New("metrics.port must be set when metrics are enabled")
+	}
+
+	if conf.Metrics.ParserFlushInterval < 0 {
+		return errors.New("metrics.parser-flush-interval must be a positive number or 0")
+	}
+
+	if conf.Metrics.HeightStateInterval < 0 {
+		return errors.New("metrics.height-state-interval must be a positive number or 0")
+	}
+
+	if conf.Metrics.HeadInterval < 0 {
+		return errors.New("metrics.head-interval must be a positive number or 0")
+	}
+
+	if conf.Metrics.HaltAfter < 0 {
+		return errors.New("metrics.halt-after must be a positive number or 0")
+	}
+
+	if conf.CDC.Notify && util.StrNotSet(conf.CDC.Channel) {
+		return errors.New("cdc.channel must be set when cdc.notify is enabled")
+	}
+
+	// CockroachDB has no NOTIFY, and views are recreated with DDL statements it does not run in one transaction
+	if conf.Database.CockroachDB && conf.CDC.Notify {
+		return errors.New("cdc.notify is not supported on CockroachDB, use cdc.outbox instead")
+	}
+	if conf.Database.CockroachDB && conf.Views.File != "" {
+		return errors.New("views.file is not supported on CockroachDB")
+	}
+	// The bulk writes stage the rows in temporary tables, which CockroachDB only supports experimentally
+	if conf.Database.CockroachDB && conf.Base.BulkCopy {
+		return errors.New("base.bulk-copy is not supported on CockroachDB")
+	}
+
+	err = validateSinksConf(conf.Sinks)
+	if err != nil {
+		return err
+	}
+
+	err = validateBlockQueueConf(conf.Queue)
+	if err != nil {
+		return err
+	}
+
+	err = validateAdminConf(conf.Admin)
+	if err != nil {
+		return err
+	}
+
+	err = validateScheduleConf(conf.Schedule)
+	if err != nil {
+		return err
+	}
+
+	err = validateSyntheticConf(conf.Synthetic, conf.Base)
+	if err != nil {
+		return err
+	}
+
+	err = validateChaosConf(conf.Chaos)
+	if err != nil {
+		return err
+	}
+
+	err = validateDenomsConf(conf.Denoms)
+	if err != nil {
+		return err
+	}
+
+	err = validateLedgerConf(conf.Ledger)
+	if err != nil {
+		return err
+	}
+
+	err = validateJobsConf(conf.Jobs)
+	if err != nil {
+		return err
+	}
+
+	err = validateEndpointsConf(conf.Endpoints)
+	if err != nil {
+		return err
+	}
+
+	err = validateErasConf(conf.Eras)
+	if err != nil {
+		return err
+	}
+
+	err = validateVerifyConf(conf.Verify)
+	if err != nil {
+		return err
+	}
+
+	err = validateReorgConf(conf.Reorg)
+	if err != nil {
+		return err
+	}
+
+	err = validateGapsConf(conf.Gaps)
+	if err != nil {
+		return err
+	}
+
+	err = validateSearchConf(conf.Search)
+	if err != nil {
+		return err
+	}
+
+	err = validateCoinsConf(conf.Coins)
+	if err != nil {
+		return err
+	}
+
+	err = validateWasmConf(conf.Wasm)
+	if err != nil {
+		return err
+	}
+
 	if conf.Base.FilterFile != "" {
 		// check if file exists
 		if _, err := os.Stat(conf.Base.FilterFile); os.IsNotExist(err) {
@@ -111,6 +393,20 @@ func (conf *IndexConfig) Validate() error {
 		}
 	}
 
+	if conf.Cache.MaxAddresses < 0 {
+		return errors.New("cache.max-addresses must be a positive number or 0")
+	}
+
+	if conf.Watchdog.StallTimeout < 0 {
+		return errors.New("watchdog.stall-timeout must be positive or 0")
+	}
+
+	if conf.Views.File != "" {
+		if _, err := os.Stat(conf.Views.File); os.IsNotExist(err) {
+			return fmt.Errorf("views.file %s does not exist", conf.Views.File)
+		}
+	}
+
 	return nil
 }
 
@@ -120,6 +416,33 @@ func CheckSuperfluousIndexKeys(keys []string) []string {
 	addDatabaseConfigKeys(validKeys)
 	addLogConfigKeys(validKeys)
 	addProbeConfigKeys(validKeys)
+	addSinkConfigKeys(validKeys)
+	addBlockQueueConfigKeys(validKeys)
+	addAdminConfigKeys(validKeys)
+	addScheduleConfigKeys(validKeys)
+	addSyntheticConfigKeys(validKeys)
+	addChaosConfigKeys(validKeys)
+	addEndpointsConfigKeys(validKeys)
+	addErasConfigKeys(validKeys)
+	addVerifyConfigKeys(validKeys)
+	addConsistencyConfigKeys(validKeys)
+	addReorgConfigKeys(validKeys)
+	addGapsConfigKeys(validKeys)
+	addSearchConfigKeys(validKeys)
+	addCoinsConfigKeys(validKeys)
+	addWasmConfigKeys(validKeys)
+	addDenomsConfigKeys(validKeys)
+	addLedgerConfigKeys(validKeys)
+	addJobsConfigKeys(validKeys)
+	// Sections of the other commands sharing the config file
+	addServeConfigKeys(validKeys)
+	addExportConfigKeys(validKeys)
+	addDBConfigKeys(validKeys)
+	addBenchConfigKeys(validKeys)
+	addValueConfigKeys(validKeys)
+	addDexConfigKeys(validKeys)
+	addStatusConfigKeys(validKeys)
+	addGenesisConfigKeys(validKeys)
 
 	// add base keys
 	for _, key := range getValidConfigKeys(indexBase{}, "base") {
@@ -134,6 +457,26 @@ func CheckSuperfluousIndexKeys(keys []string) []string {
 		validKeys[key] = struct{}{}
 	}
 
+	for _, key := range getValidConfigKeys(metrics{}, "metrics") {
+		validKeys[key] = struct{}{}
+	}
+
+	for _, key := range getValidConfigKeys(cdc{}, "cdc") {
+		validKeys[key] = struct{}{}
+	}
+
+	for _, key := range getValidConfigKeys(views{}, "views") {
+		validKeys[key] = struct{}{}
+	}
+
+	for _, key := range getValidConfigKeys(watchdog{}, "watchdog") {
+		validKeys[key] = struct{}{}
+	}
+
+	for _, key := range getValidConfigKeys(dictionaryCache{}, "cache") {
+		validKeys[key] = struct{}{}
+	}
+
 	for _, key := range getValidConfigKeys(flags{}, "flags") {
 		validKeys[key] = struct{}{}
 	}
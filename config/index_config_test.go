@@ -52,6 +52,35 @@ func (suite *IndexConfigTestSuite) TestIndexConfig() {
 	conf.Base.EndBlock = 2
 	err = conf.Validate()
 	suite.Require().NoError(err)
+
+	conf.Chaos = chaos{RPCTimeoutRate: 0.6, RPCMalformedRate: 0.6}
+	err = conf.Validate()
+	suite.Require().Error(err)
+
+	conf.Chaos = chaos{RPCTimeoutRate: 0.1, RPCMalformedRate: 0.1, DBErrorRate: 1.5}
+	err = conf.Validate()
+	suite.Require().Error(err)
+
+	conf.Chaos.DBErrorRate = 0.1
+	err = conf.Validate()
+	suite.Require().NoError(err)
+
+	conf.Synthetic = synthetic{Enabled: true, TxsPerBlock: 10, MsgsPerTx: 1}
+	err = conf.Validate()
+	suite.Require().Error(err)
+
+	conf.Synthetic.Addresses = 100
+	err = conf.Validate()
+	suite.Require().NoError(err)
+
+	conf.Base.RecordFixtures = "fixtures"
+	err = conf.Validate()
+	suite.Require().Error(err)
+
+	conf.Synthetic.Enabled = false
+	conf.Base.ReplayFixtures = "fixtures"
+	err = conf.Validate()
+	suite.Require().Error(err)
 }
 
 func (suite *IndexConfigTestSuite) TestCheckSuperfluousIndexKeys() {
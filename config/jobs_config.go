@@ -0,0 +1,105 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/DefiantLabs/cosmos-indexer/schedule"
+	"github.com/spf13/cobra"
+)
+
+// Aggregation jobs declared by the user, run by the indexer every N blocks or on a cron schedule
+type jobs struct {
+	File string `mapstructure:"file"`
+}
+
+// AggregationJob is a SQL job declared in the jobs file. The SQL runs in a transaction and can refer to the height of
+// the last indexed block as @height.
+type AggregationJob struct {
+	Name string `json:"name"`
+	SQL  string `json:"sql"`
+	// EveryBlocks runs the job every time this many blocks were indexed, 0 disables the block cadence
+	EveryBlocks int64 `json:"every_blocks"`
+	// Cron runs the job on a standard 5 field cron spec or descriptor such as @hourly, evaluated in Timezone (UTC when
+	// empty)
+	Cron     string `json:"cron"`
+	Timezone string `json:"timezone"`
+}
+
+func setupJobsFlags(conf *jobs, cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&conf.File, "jobs.file", "", "path to a JSON file declaring SQL aggregation jobs to run while indexing")
+}
+
+func validateJobsConf(conf jobs) error {
+	if conf.File == "" {
+		return nil
+	}
+
+	if _, err := os.Stat(conf.File); os.IsNotExist(err) {
+		return fmt.Errorf("jobs.file %s does not exist", conf.File)
+	}
+
+	return nil
+}
+
+func addJobsConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(jobs{}, "jobs") {
+		validKeys[key] = struct{}{}
+	}
+}
+
+// LoadAggregationJobs reads and validates the jobs declared in a JSON file
+func LoadAggregationJobs(path string) ([]AggregationJob, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseAggregationJobs(data)
+}
+
+func ParseAggregationJobs(data []byte) ([]AggregationJob, error) {
+	var file struct {
+		Jobs []AggregationJob `json:"jobs"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse aggregation jobs: %w", err)
+	}
+
+	names := make(map[string]struct{}, len(file.Jobs))
+	for i := range file.Jobs {
+		job := &file.Jobs[i]
+
+		if !viewIdentifier.MatchString(job.Name) {
+			return nil, fmt.Errorf("aggregation job name %q must be a lowercase identifier", job.Name)
+		}
+		if _, ok := names[job.Name]; ok {
+			return nil, fmt.Errorf("aggregation job %s is declared twice", job.Name)
+		}
+		names[job.Name] = struct{}{}
+
+		if job.SQL == "" {
+			return nil, fmt.Errorf("aggregation job %s has no sql", job.Name)
+		}
+
+		if job.EveryBlocks < 0 {
+			return nil, fmt.Errorf("every_blocks of aggregation job %s must be a positive number or 0", job.Name)
+		}
+
+		if job.Timezone == "" {
+			job.Timezone = "UTC"
+		}
+		if job.Cron != "" {
+			if _, err := schedule.ParseCron(job.Cron, job.Timezone); err != nil {
+				return nil, fmt.Errorf("invalid cron of aggregation job %s: %w", job.Name, err)
+			}
+		}
+
+		if job.EveryBlocks == 0 && job.Cron == "" {
+			return nil, fmt.Errorf("aggregation job %s needs an every_blocks or cron cadence", job.Name)
+		}
+	}
+
+	return file.Jobs, nil
+}
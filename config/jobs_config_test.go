@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type JobsConfigTestSuite struct {
+	suite.Suite
+}
+
+func (suite *JobsConfigTestSuite) TestParseAggregationJobs() {
+	jobs, err := ParseAggregationJobs([]byte(`{"jobs": [
+		{"name": "hourly_fees", "sql": "INSERT INTO hourly_fees SELECT 1", "cron": "@hourly"},
+		{"name": "supply", "sql": "UPDATE supply SET height = @height", "every_blocks": 100, "cron": "0 0 * * *", "timezone": "Europe/Berlin"}
+	]}`))
+	suite.Require().NoError(err)
+	suite.Require().Len(jobs, 2)
+	suite.Require().Equal("UTC", jobs[0].Timezone)
+	suite.Require().Equal(int64(100), jobs[1].EveryBlocks)
+
+	invalid := []string{
+		`{"jobs": [{"name": "Hourly Fees", "sql": "SELECT 1", "cron": "@hourly"}]}`,
+		`{"jobs": [{"name": "hourly_fees", "cron": "@hourly"}]}`,
+		`{"jobs": [{"name": "hourly_fees", "sql": "SELECT 1"}]}`,
+		`{"jobs": [{"name": "hourly_fees", "sql": "SELECT 1", "every_blocks": -1}]}`,
+		`{"jobs": [{"name": "hourly_fees", "sql": "SELECT 1", "cron": "every hour"}]}`,
+		`{"jobs": [{"name": "hourly_fees", "sql": "SELECT 1", "cron": "@hourly", "timezone": "Mars/Olympus"}]}`,
+		`{"jobs": [{"name": "a", "sql": "SELECT 1", "every_blocks": 1}, {"name": "a", "sql": "SELECT 2", "every_blocks": 1}]}`,
+	}
+	for _, data := range invalid {
+		_, err := ParseAggregationJobs([]byte(data))
+		suite.Require().Error(err, data)
+	}
+}
+
+func TestJobsConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(JobsConfigTestSuite))
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/DefiantLabs/cosmos-indexer/util"
+	"github.com/spf13/cobra"
+)
+
+// Double-entry ledger of the fees and transfer events, projected in the same transaction as the indexed block data
+type ledger struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	FeeAccount string `mapstructure:"fee-account"`
+	Rollups    bool   `mapstructure:"rollups"`
+}
+
+func setupLedgerFlags(conf *ledger, cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&conf.Enabled, "ledger.enabled", false, "project the fees and transfer events of every indexed block into the ledger_entries table")
+	cmd.PersistentFlags().StringVar(&conf.FeeAccount, "ledger.fee-account", "fee_collector", "account the fees are booked to, e.g. the address of the fee collector module account of the chain")
+	cmd.PersistentFlags().BoolVar(&conf.Rollups, "ledger.rollups", false, "maintain per-address activity rollups (tx count, volume per denom, fees paid, first and last activity) from the ledger entries")
+}
+
+func validateLedgerConf(conf ledger) error {
+	if conf.Enabled && util.StrNotSet(conf.FeeAccount) {
+		return errors.New("ledger.fee-account must be set when ledger.enabled is set")
+	}
+
+	if conf.Rollups && !conf.Enabled {
+		return errors.New("ledger.rollups requires ledger.enabled")
+	}
+
+	return nil
+}
+
+func addLedgerConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(ledger{}, "ledger") {
+		validKeys[key] = struct{}{}
+	}
+}
@@ -100,7 +100,16 @@ func DoConfigureLogger(logPath string, logLevel string, prettyLogging bool) {
 	}
 
 	// Set the log level (default to info)
+	if err := SetLogLevel(logLevel); err != nil {
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	}
+}
+
+// SetLogLevel changes the level of the global logger, it may be called at runtime
+func SetLogLevel(logLevel string) error {
 	switch strings.ToLower(logLevel) {
+	case "trace":
+		zerolog.SetGlobalLevel(zerolog.TraceLevel)
 	case "debug":
 		zerolog.SetGlobalLevel(zerolog.DebugLevel)
 	case "info":
@@ -114,6 +123,12 @@ func DoConfigureLogger(logPath string, logLevel string, prettyLogging bool) {
 	case "panic":
 		zerolog.SetGlobalLevel(zerolog.PanicLevel)
 	default:
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+		return fmt.Errorf("unknown log level %q", logLevel)
 	}
+	return nil
+}
+
+// LogLevel returns the current level of the global logger
+func LogLevel() string {
+	return zerolog.GlobalLevel().String()
 }
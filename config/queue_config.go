@@ -0,0 +1,71 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/DefiantLabs/cosmos-indexer/util"
+	"github.com/spf13/cobra"
+)
+
+// Block enqueue from a message queue, the heights to index are consumed from the queue instead of the configured range
+type blockQueue struct {
+	Kafka kafkaQueue
+	NATS  natsQueue
+}
+
+type kafkaQueue struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Brokers string `mapstructure:"brokers"`
+	Topic   string `mapstructure:"topic"`
+	GroupID string `mapstructure:"group-id"`
+}
+
+type natsQueue struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	URL        string `mapstructure:"url"`
+	Subject    string `mapstructure:"subject"`
+	QueueGroup string `mapstructure:"queue-group"`
+}
+
+// Enabled is true if the blocks to index are consumed from a message queue
+func (conf blockQueue) Enabled() bool {
+	return conf.Kafka.Enabled || conf.NATS.Enabled
+}
+
+func setupBlockQueueFlags(conf *blockQueue, cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&conf.Kafka.Enabled, "queue.kafka.enabled", false, "index the heights and ranges consumed from a Kafka topic")
+	cmd.PersistentFlags().StringVar(&conf.Kafka.Brokers, "queue.kafka.brokers", "localhost:9092", "comma separated Kafka broker addresses")
+	cmd.PersistentFlags().StringVar(&conf.Kafka.Topic, "queue.kafka.topic", "", "Kafka topic the block requests are consumed from")
+	cmd.PersistentFlags().StringVar(&conf.Kafka.GroupID, "queue.kafka.group-id", "cosmos-indexer", "Kafka consumer group, offsets are committed once the blocks of a message are enqueued")
+
+	cmd.PersistentFlags().BoolVar(&conf.NATS.Enabled, "queue.nats.enabled", false, "index the heights and ranges consumed from a NATS subject")
+	cmd.PersistentFlags().StringVar(&conf.NATS.URL, "queue.nats.url", "nats://localhost:4222", "NATS server URL")
+	cmd.PersistentFlags().StringVar(&conf.NATS.Subject, "queue.nats.subject", "", "NATS subject the block requests are consumed from")
+	cmd.PersistentFlags().StringVar(&conf.NATS.QueueGroup, "queue.nats.queue-group", "cosmos-indexer", "NATS queue group, each request is delivered to one indexer of the group")
+}
+
+func validateBlockQueueConf(conf blockQueue) error {
+	if conf.Kafka.Enabled && conf.NATS.Enabled {
+		return errors.New("only one of queue.kafka.enabled and queue.nats.enabled can be set")
+	}
+
+	if conf.Kafka.Enabled && (util.StrNotSet(conf.Kafka.Brokers) || util.StrNotSet(conf.Kafka.Topic) || util.StrNotSet(conf.Kafka.GroupID)) {
+		return errors.New("queue.kafka.brokers, queue.kafka.topic and queue.kafka.group-id must be set when the Kafka queue is enabled")
+	}
+
+	if conf.NATS.Enabled && (util.StrNotSet(conf.NATS.URL) || util.StrNotSet(conf.NATS.Subject)) {
+		return errors.New("queue.nats.url and queue.nats.subject must be set when the NATS queue is enabled")
+	}
+
+	return nil
+}
+
+func addBlockQueueConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(kafkaQueue{}, "queue.kafka") {
+		validKeys[key] = struct{}{}
+	}
+
+	for _, key := range getValidConfigKeys(natsQueue{}, "queue.nats") {
+		validKeys[key] = struct{}{}
+	}
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+// Detection of chain reorganizations by the parent hash of the fetched blocks, the heights indexed from the abandoned
+// fork are rolled back and indexed again
+type reorg struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MaxDepth is the number of heights below a block compared with the node to find where the fork started
+	MaxDepth int64 `mapstructure:"max-depth"`
+}
+
+func setupReorgFlags(conf *reorg, cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&conf.Enabled, "reorg.enabled", false, "compare the parent hash of each block with the indexed block below it, and roll back and index again the heights of an abandoned fork")
+	cmd.PersistentFlags().Int64Var(&conf.MaxDepth, "reorg.max-depth", 100, "number of heights compared with the node to find the start of a fork, deeper forks are only rolled back that far")
+}
+
+func validateReorgConf(conf reorg) error {
+	if !conf.Enabled {
+		return nil
+	}
+
+	if conf.MaxDepth < 1 {
+		return errors.New("reorg.max-depth must be at least 1")
+	}
+
+	return nil
+}
+
+func addReorgConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(reorg{}, "reorg") {
+		validKeys[key] = struct{}{}
+	}
+}
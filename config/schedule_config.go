@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/schedule"
+	"github.com/spf13/cobra"
+)
+
+// Indexing schedule, blocks are only fetched while a window opened by the cron spec lasts
+type indexSchedule struct {
+	Cron     string        `mapstructure:"cron"`
+	Duration time.Duration `mapstructure:"duration"`
+	Timezone string        `mapstructure:"timezone"`
+}
+
+func setupScheduleFlags(conf *indexSchedule, cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&conf.Cron, "schedule.cron", "", "cron spec opening an indexing window, e.g. \"0 0 * * *\" for midnight (empty to index around the clock)")
+	cmd.PersistentFlags().DurationVar(&conf.Duration, "schedule.duration", 0, "how long an indexing window stays open, e.g. 6h")
+	cmd.PersistentFlags().StringVar(&conf.Timezone, "schedule.timezone", "UTC", "IANA timezone the cron spec is evaluated in")
+}
+
+// Window parses the schedule, it returns nil when indexing is not scheduled
+func (conf indexSchedule) Window() (*schedule.Window, error) {
+	if conf.Cron == "" {
+		return nil, nil
+	}
+	return schedule.Parse(conf.Cron, conf.Duration, conf.Timezone)
+}
+
+func validateScheduleConf(conf indexSchedule) error {
+	if conf.Cron == "" {
+		return nil
+	}
+
+	if conf.Duration <= 0 {
+		return errors.New("schedule.duration must be positive when schedule.cron is set")
+	}
+
+	_, err := conf.Window()
+	return err
+}
+
+func addScheduleConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(indexSchedule{}, "schedule") {
+		validKeys[key] = struct{}{}
+	}
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Full-text search over the memos of the transactions and the values of selected message event attributes
+type search struct {
+	Enabled       bool     `mapstructure:"enabled"`
+	AttributeKeys []string `mapstructure:"attribute-keys"`
+}
+
+func setupSearchFlags(conf *search, cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&conf.Enabled, "search.enabled", false, "index the transaction memos and the values of search.attribute-keys for full-text search")
+	cmd.PersistentFlags().StringSliceVar(&conf.AttributeKeys, "search.attribute-keys", nil, "message event attribute keys whose values are searchable, e.g. action,memo")
+}
+
+func validateSearchConf(conf search) error {
+	if len(conf.AttributeKeys) == 0 {
+		return nil
+	}
+
+	if !conf.Enabled {
+		return errors.New("search.attribute-keys requires search.enabled")
+	}
+
+	for _, key := range conf.AttributeKeys {
+		if strings.TrimSpace(key) == "" {
+			return errors.New("search.attribute-keys must not contain empty keys")
+		}
+	}
+
+	return nil
+}
+
+func addSearchConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(search{}, "search") {
+		validKeys[key] = struct{}{}
+	}
+}
@@ -0,0 +1,209 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/DefiantLabs/cosmos-indexer/util"
+	"github.com/spf13/cobra"
+)
+
+// ServeConfig is the config of the serve command, which exposes the indexed data to clients
+type ServeConfig struct {
+	Database  Database
+	Log       log
+	Flight    flight
+	REST      rest
+	GraphQL   graphQL
+	CDC       cdc
+	Auth      APIAuth
+	RateLimit rateLimit
+	Metrics   serveMetrics
+}
+
+// Arrow Flight settings
+type flight struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	Address   string `mapstructure:"address"`
+	BatchSize int64  `mapstructure:"batch-size"`
+}
+
+// REST query API settings
+type rest struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Address string `mapstructure:"address"`
+	Stream  bool   `mapstructure:"stream"`
+}
+
+// GraphQL API settings
+type graphQL struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Address string `mapstructure:"address"`
+}
+
+// Rate limits of the REST and GraphQL APIs, per API key or per IP address for requests without a key
+type rateLimit struct {
+	Enabled           bool    `mapstructure:"enabled"`
+	Rate              float64 `mapstructure:"rate"`
+	Burst             int     `mapstructure:"burst"`
+	TrustForwardedFor bool    `mapstructure:"trust-forwarded-for"`
+	TrustedProxies    int     `mapstructure:"trusted-proxies"`
+}
+
+// The serve command shares the metrics keys of the index command, without the parser metrics
+type serveMetrics struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Port    string `mapstructure:"port"`
+}
+
+func SetupServeSpecificFlags(conf *ServeConfig, cmd *cobra.Command) {
+	// arrow flight
+	cmd.PersistentFlags().BoolVar(&conf.Flight.Enabled, "flight.enabled", true, "serve the indexed datasets over Arrow Flight")
+	cmd.PersistentFlags().StringVar(&conf.Flight.Address, "flight.address", "localhost:8815", "address the Arrow Flight server listens on")
+	cmd.PersistentFlags().Int64Var(&conf.Flight.BatchSize, "flight.batch-size", 10000, "maximum number of rows per Arrow record batch")
+
+	// rest api
+	cmd.PersistentFlags().BoolVar(&conf.REST.Enabled, "rest.enabled", false, "serve the REST query API")
+	cmd.PersistentFlags().StringVar(&conf.REST.Address, "rest.address", "localhost:8080", "address the REST query API listens on")
+	cmd.PersistentFlags().BoolVar(&conf.REST.Stream, "rest.stream", false, "push newly indexed data to WebSocket clients of /stream, requires the indexer to run with cdc.notify")
+
+	// graphql api
+	cmd.PersistentFlags().BoolVar(&conf.GraphQL.Enabled, "graphql.enabled", false, "serve the GraphQL API, subscriptions require the indexer to run with cdc.notify")
+	cmd.PersistentFlags().StringVar(&conf.GraphQL.Address, "graphql.address", "localhost:8081", "address the GraphQL API listens on")
+
+	// authentication
+	cmd.PersistentFlags().BoolVar(&conf.Auth.Enabled, "auth.enabled", false, "require an API key or JWT for the REST and GraphQL APIs")
+	cmd.PersistentFlags().StringVar(&conf.Auth.KeysFile, "auth.keys-file", "", "JSON file declaring the accepted API keys by SHA-256 hash, with their scopes")
+	cmd.PersistentFlags().StringVar(&conf.Auth.JWTSecret, "auth.jwt-secret", "", "secret verifying HS256 JWTs")
+	cmd.PersistentFlags().StringVar(&conf.Auth.JWTPublicKeyFile, "auth.jwt-public-key-file", "", "PEM RSA public key verifying RS256 JWTs")
+	cmd.PersistentFlags().StringVar(&conf.Auth.JWTIssuer, "auth.jwt-issuer", "", "required iss claim of JWTs")
+	cmd.PersistentFlags().StringVar(&conf.Auth.JWTAudience, "auth.jwt-audience", "", "required aud claim of JWTs")
+
+	// rate limiting
+	cmd.PersistentFlags().BoolVar(&conf.RateLimit.Enabled, "rate-limit.enabled", false, "rate limit the REST and GraphQL APIs per API key, or per IP address for requests without a key")
+	cmd.PersistentFlags().Float64Var(&conf.RateLimit.Rate, "rate-limit.rate", 10, "sustained requests per second allowed per client")
+	cmd.PersistentFlags().IntVar(&conf.RateLimit.Burst, "rate-limit.burst", 20, "requests a client may send at once on top of the sustained rate")
+	cmd.PersistentFlags().BoolVar(&conf.RateLimit.TrustForwardedFor, "rate-limit.trust-forwarded-for", false, "take the client IP from X-Forwarded-For, only enable behind a proxy setting it")
+	cmd.PersistentFlags().IntVar(&conf.RateLimit.TrustedProxies, "rate-limit.trusted-proxies", 1, "number of proxies in front of serve appending to X-Forwarded-For, the client IP is the entry this many from the right")
+
+	// metrics
+	cmd.PersistentFlags().BoolVar(&conf.Metrics.Enabled, "metrics.enabled", false, "serve Prometheus metrics at /metrics")
+	cmd.PersistentFlags().StringVar(&conf.Metrics.Port, "metrics.port", "9100", "port the Prometheus metrics are served on")
+
+	cmd.PersistentFlags().StringVar(&conf.CDC.Channel, "cdc.channel", "cosmos_indexer", "Postgres channel the indexer sends CDC notifications on")
+}
+
+func (conf *ServeConfig) Validate() error {
+	err := validateDatabaseConf(conf.Database)
+	if err != nil {
+		return err
+	}
+
+	err = validateDatabaseChainConf(conf.Database)
+	if err != nil {
+		return err
+	}
+
+	if conf.Database.CockroachDB && conf.REST.Stream {
+		return errors.New("rest.stream needs Postgres LISTEN, which CockroachDB does not support")
+	}
+
+	if !conf.Flight.Enabled && !conf.REST.Enabled && !conf.GraphQL.Enabled {
+		return errors.New("at least one server must be enabled")
+	}
+
+	if conf.Flight.Enabled {
+		if util.StrNotSet(conf.Flight.Address) {
+			return errors.New("flight.address must be set when the Arrow Flight server is enabled")
+		}
+		if conf.Flight.BatchSize <= 0 {
+			return errors.New("flight.batch-size must be a positive number")
+		}
+	}
+
+	if conf.REST.Enabled && util.StrNotSet(conf.REST.Address) {
+		return errors.New("rest.address must be set when the REST API is enabled")
+	}
+
+	if conf.REST.Stream && !conf.REST.Enabled {
+		return errors.New("rest.enabled must be set when rest.stream is enabled")
+	}
+
+	if conf.GraphQL.Enabled && util.StrNotSet(conf.GraphQL.Address) {
+		return errors.New("graphql.address must be set when the GraphQL API is enabled")
+	}
+
+	if conf.ListensForCDC() && util.StrNotSet(conf.CDC.Channel) {
+		return errors.New("cdc.channel must be set when rest.stream or the GraphQL API is enabled")
+	}
+
+	if conf.RateLimit.Enabled && (conf.RateLimit.Rate <= 0 || conf.RateLimit.Burst < 1) {
+		return errors.New("rate-limit.rate must be a positive number and rate-limit.burst at least 1")
+	}
+
+	if conf.RateLimit.Enabled && conf.RateLimit.TrustForwardedFor && conf.RateLimit.TrustedProxies < 1 {
+		return errors.New("rate-limit.trusted-proxies must be at least 1 when rate-limit.trust-forwarded-for is enabled")
+	}
+
+	if conf.Metrics.Enabled && util.StrNotSet(conf.Metrics.Port) {
+		return errors.New("metrics.port must be set when metrics are enabled")
+	}
+
+	if conf.Auth.Enabled {
+		return validateAuthConf(conf.Auth, "auth")
+	}
+
+	return nil
+}
+
+// validateAuthConf checks that credentials can be verified, the keys of the settings are prefixed by section
+func validateAuthConf(conf APIAuth, section string) error {
+	if util.StrNotSet(conf.KeysFile) && util.StrNotSet(conf.JWTSecret) && util.StrNotSet(conf.JWTPublicKeyFile) {
+		return fmt.Errorf("%[1]s.keys-file, %[1]s.jwt-secret or %[1]s.jwt-public-key-file must be set when %[1]s is enabled", section)
+	}
+
+	for key, file := range map[string]string{"keys-file": conf.KeysFile, "jwt-public-key-file": conf.JWTPublicKeyFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return fmt.Errorf("%s.%s %s does not exist", section, key, file)
+		}
+	}
+
+	return nil
+}
+
+// ListensForCDC is true when a server pushes newly indexed data, which it learns about from the CDC notifications.
+// GraphQL subscriptions are disabled on CockroachDB, which has no LISTEN.
+func (conf *ServeConfig) ListensForCDC() bool {
+	return !conf.Database.CockroachDB && (conf.REST.Stream || conf.GraphQL.Enabled)
+}
+
+func addServeConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(flight{}, "flight") {
+		validKeys[key] = struct{}{}
+	}
+
+	for _, key := range getValidConfigKeys(rest{}, "rest") {
+		validKeys[key] = struct{}{}
+	}
+
+	for _, key := range getValidConfigKeys(graphQL{}, "graphql") {
+		validKeys[key] = struct{}{}
+	}
+
+	for _, key := range getValidConfigKeys(APIAuth{}, "auth") {
+		validKeys[key] = struct{}{}
+	}
+
+	for _, key := range getValidConfigKeys(rateLimit{}, "rate-limit") {
+		validKeys[key] = struct{}{}
+	}
+}
+
+// The config file is shared by all commands, so the serve command accepts the same keys as the index command
+func CheckSuperfluousServeKeys(keys []string) []string {
+	return CheckSuperfluousIndexKeys(keys)
+}
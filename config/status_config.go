@@ -0,0 +1,59 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/DefiantLabs/cosmos-indexer/util"
+	"github.com/spf13/cobra"
+)
+
+// StatusConfig is the config of the status command, which reports the indexing lag recorded by the index command
+type StatusConfig struct {
+	Database Database
+	Log      log
+	Status   statusBase
+}
+
+type statusBase struct {
+	ChainID string `mapstructure:"chain-id"`
+	// MaxLagBlocks and MaxLagSeconds make the command fail when the indexer is further behind, 0 disables the check
+	MaxLagBlocks  int64 `mapstructure:"max-lag-blocks"`
+	MaxLagSeconds int64 `mapstructure:"max-lag-seconds"`
+	// MaxAge makes the command fail when the head was not recorded for longer, e.g. because the indexer stopped
+	MaxAge int64 `mapstructure:"max-age"`
+}
+
+func SetupStatusFlags(conf *StatusConfig, cmd *cobra.Command) {
+	cmd.Flags().StringVar(&conf.Status.ChainID, "status.chain-id", "", "chain ID of the indexed chain to report the status of")
+	cmd.Flags().Int64Var(&conf.Status.MaxLagBlocks, "status.max-lag-blocks", 0, "exit with an error when the highest indexed block is more blocks behind the chain head (0 to disable)")
+	cmd.Flags().Int64Var(&conf.Status.MaxLagSeconds, "status.max-lag-seconds", 0, "exit with an error when the highest indexed block is more seconds behind the chain head (0 to disable)")
+	cmd.Flags().Int64Var(&conf.Status.MaxAge, "status.max-age", 0, "exit with an error when the chain head was recorded more seconds ago (0 to disable)")
+}
+
+func (conf *StatusConfig) Validate() error {
+	err := validateDatabaseConf(conf.Database)
+	if err != nil {
+		return err
+	}
+
+	if util.StrNotSet(conf.Status.ChainID) {
+		return errors.New("status.chain-id must be set")
+	}
+
+	if conf.Status.MaxLagBlocks < 0 || conf.Status.MaxLagSeconds < 0 || conf.Status.MaxAge < 0 {
+		return errors.New("status.max-lag-blocks, status.max-lag-seconds and status.max-age must be positive numbers or 0")
+	}
+
+	return nil
+}
+
+func addStatusConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(statusBase{}, "status") {
+		validKeys[key] = struct{}{}
+	}
+}
+
+// The config file is shared by all commands, so the status command accepts the same keys as the index command
+func CheckSuperfluousStatusKeys(keys []string) []string {
+	return CheckSuperfluousIndexKeys(keys)
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+)
+
+// Synthetic chain data indexed instead of the node's, to load the parse and DB stages for capacity planning
+type synthetic struct {
+	Enabled     bool `mapstructure:"enabled"`
+	TxsPerBlock int  `mapstructure:"txs-per-block"`
+	MsgsPerTx   int  `mapstructure:"msgs-per-tx"`
+	BlockEvents int  `mapstructure:"block-events"`
+	Addresses   int  `mapstructure:"addresses"`
+}
+
+func setupSyntheticFlags(conf *synthetic, cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&conf.Enabled, "synthetic.enabled", false, "index generated blocks of bank sends instead of the node's blocks")
+	cmd.PersistentFlags().IntVar(&conf.TxsPerBlock, "synthetic.txs-per-block", 100, "transactions of every generated block")
+	cmd.PersistentFlags().IntVar(&conf.MsgsPerTx, "synthetic.msgs-per-tx", 1, "messages of every generated transaction")
+	cmd.PersistentFlags().IntVar(&conf.BlockEvents, "synthetic.block-events", 10, "begin block and end block events of every generated block")
+	cmd.PersistentFlags().IntVar(&conf.Addresses, "synthetic.addresses", 10000, "number of accounts the generated transactions are sent between")
+}
+
+func validateSyntheticConf(conf synthetic, base indexBase) error {
+	if !conf.Enabled {
+		return nil
+	}
+
+	if base.RecordFixtures != "" || base.ReplayFixtures != "" {
+		return errors.New("synthetic.enabled cannot be used with base.record-fixtures or base.replay-fixtures")
+	}
+
+	if conf.TxsPerBlock < 0 || conf.MsgsPerTx < 0 || conf.BlockEvents < 0 {
+		return errors.New("synthetic.txs-per-block, synthetic.msgs-per-tx and synthetic.block-events must be positive or 0")
+	}
+
+	if conf.Addresses < 1 {
+		return errors.New("synthetic.addresses must be at least 1")
+	}
+
+	return nil
+}
+
+func addSyntheticConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(synthetic{}, "synthetic") {
+		validKeys[key] = struct{}{}
+	}
+}
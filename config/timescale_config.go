@@ -0,0 +1,106 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ContinuousAggregate is a TimescaleDB continuous aggregate declared in the aggregates file. The query must group by a
+// time_bucket over a hypertable, e.g. the fee_points table. The refresh policy keeps the window between StartOffset and
+// EndOffset before now materialized, refreshing it every ScheduleInterval.
+type ContinuousAggregate struct {
+	Name  string `json:"name"`
+	Query string `json:"query"`
+	// StartOffset bounds how far back a refresh looks, e.g. "72h", unset refreshes from the first bucket
+	StartOffset string `json:"start_offset"`
+	// EndOffset leaves out the most recent buckets that still change, "1h" if unset
+	EndOffset string `json:"end_offset"`
+	// ScheduleInterval is how often the policy refreshes the aggregate, "1h" if unset
+	ScheduleInterval string `json:"schedule_interval"`
+
+	Start    time.Duration `json:"-"`
+	End      time.Duration `json:"-"`
+	Schedule time.Duration `json:"-"`
+}
+
+// LoadContinuousAggregates reads and validates the continuous aggregates declared in a JSON file
+func LoadContinuousAggregates(path string) ([]ContinuousAggregate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseContinuousAggregates(data)
+}
+
+func ParseContinuousAggregates(data []byte) ([]ContinuousAggregate, error) {
+	var file struct {
+		Aggregates []ContinuousAggregate `json:"aggregates"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse continuous aggregates: %w", err)
+	}
+
+	names := make(map[string]struct{}, len(file.Aggregates))
+	for i := range file.Aggregates {
+		aggregate := &file.Aggregates[i]
+
+		if !viewIdentifier.MatchString(aggregate.Name) {
+			return nil, fmt.Errorf("continuous aggregate name %q must be a lowercase identifier", aggregate.Name)
+		}
+		if _, ok := names[aggregate.Name]; ok {
+			return nil, fmt.Errorf("continuous aggregate %s is declared twice", aggregate.Name)
+		}
+		names[aggregate.Name] = struct{}{}
+
+		if aggregate.Query == "" {
+			return nil, fmt.Errorf("continuous aggregate %s has no query", aggregate.Name)
+		}
+
+		if err := aggregate.ParseOffsets(); err != nil {
+			return nil, err
+		}
+	}
+
+	return file.Aggregates, nil
+}
+
+// ParseOffsets parses the offsets and schedule interval of the refresh policy, applying the defaults
+func (a *ContinuousAggregate) ParseOffsets() error {
+	var err error
+	if a.Start, err = parsePolicyDuration(a.StartOffset, 0); err != nil {
+		return fmt.Errorf("invalid start_offset of continuous aggregate %s: %w", a.Name, err)
+	}
+	if a.End, err = parsePolicyDuration(a.EndOffset, time.Hour); err != nil {
+		return fmt.Errorf("invalid end_offset of continuous aggregate %s: %w", a.Name, err)
+	}
+	if a.Schedule, err = parsePolicyDuration(a.ScheduleInterval, time.Hour); err != nil {
+		return fmt.Errorf("invalid schedule_interval of continuous aggregate %s: %w", a.Name, err)
+	}
+
+	if a.Start != 0 && a.Start <= a.End {
+		return fmt.Errorf("start_offset of continuous aggregate %s must be greater than its end_offset", a.Name)
+	}
+	if a.Schedule <= 0 {
+		return fmt.Errorf("schedule_interval of continuous aggregate %s must be positive", a.Name)
+	}
+
+	return nil
+}
+
+func parsePolicyDuration(value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if duration < 0 {
+		return 0, fmt.Errorf("%s is negative", value)
+	}
+	return duration, nil
+}
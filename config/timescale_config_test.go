@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type TimescaleConfigTestSuite struct {
+	suite.Suite
+}
+
+func (suite *TimescaleConfigTestSuite) TestParseContinuousAggregates() {
+	aggregates, err := ParseContinuousAggregates([]byte(`{"aggregates": [{"name": "daily_fees", "query": "SELECT 1", "start_offset": "72h"}]}`))
+	suite.Require().NoError(err)
+	suite.Require().Len(aggregates, 1)
+	suite.Require().Equal(72*time.Hour, aggregates[0].Start)
+	suite.Require().Equal(time.Hour, aggregates[0].End)
+	suite.Require().Equal(time.Hour, aggregates[0].Schedule)
+
+	invalid := []string{
+		`{"aggregates": [{"name": "Daily Fees", "query": "SELECT 1"}]}`,
+		`{"aggregates": [{"name": "daily_fees"}]}`,
+		`{"aggregates": [{"name": "daily_fees", "query": "SELECT 1", "end_offset": "soon"}]}`,
+		`{"aggregates": [{"name": "daily_fees", "query": "SELECT 1", "start_offset": "1h", "end_offset": "2h"}]}`,
+		`{"aggregates": [{"name": "daily_fees", "query": "SELECT 1", "schedule_interval": "0s"}]}`,
+		`{"aggregates": [{"name": "a", "query": "SELECT 1"}, {"name": "a", "query": "SELECT 2"}]}`,
+	}
+	for _, data := range invalid {
+		_, err := ParseContinuousAggregates([]byte(data))
+		suite.Require().Error(err, data)
+	}
+}
+
+func TestTimescaleConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(TimescaleConfigTestSuite))
+}
@@ -0,0 +1,186 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/util"
+	"github.com/spf13/cobra"
+)
+
+// ValueConfig is the config of the value command, which values the fees, transfers and rewards of the indexed blocks
+// in USD
+type ValueConfig struct {
+	Database Database
+	Log      log
+	Value    valueBase
+}
+
+type valueBase struct {
+	ChainID     string `mapstructure:"chain-id"`
+	StartHeight int64  `mapstructure:"start-height"`
+	EndHeight   int64  `mapstructure:"end-height"`
+	SourcesFile string `mapstructure:"sources-file"`
+	Revalue     bool   `mapstructure:"revalue"`
+	RulesFile   string `mapstructure:"rules-file"`
+	// ReferenceDenom is the base denom the fees are converted to, e.g. uatom
+	ReferenceDenom string `mapstructure:"reference-denom"`
+}
+
+const (
+	PriceSourceCSV       = "csv"
+	PriceSourceCoinGecko = "coingecko"
+	PriceSourceOsmosis   = "osmosis"
+	PriceSourceDex       = "dex"
+)
+
+// defaultDexMaxAge is how old the swaps dex sources price denoms by may be when max_age is not set
+const defaultDexMaxAge = 24 * time.Hour
+
+// PriceSource is a source of USD prices declared in the price sources file. The sources are asked in the order they
+// are declared.
+type PriceSource struct {
+	Type string `json:"type"`
+	// File is the CSV file of csv sources
+	File string `json:"file"`
+	// URL is the API of coingecko sources (the public API when empty) and the REST API of the node of osmosis sources
+	URL    string `json:"url"`
+	APIKey string `json:"api_key"`
+	// IDs maps the denoms to CoinGecko coin IDs, e.g. uatom to cosmos
+	IDs   map[string]string `json:"ids"`
+	Pools []OsmosisPool     `json:"pools"`
+	// Stablecoins are the base denoms of the USD stablecoins dex sources quote denoms against
+	Stablecoins []string `json:"stablecoins"`
+	// MaxAge is how old the swaps dex sources price denoms by may be, e.g. 1h (24h when empty)
+	MaxAge string `json:"max_age"`
+}
+
+// Window returns the max age of the swaps of dex sources
+func (source PriceSource) Window() time.Duration {
+	maxAge, err := time.ParseDuration(source.MaxAge)
+	if err != nil || maxAge <= 0 {
+		return defaultDexMaxAge
+	}
+	return maxAge
+}
+
+// OsmosisPool is the pool pricing Denom against QuoteDenom, a USD stablecoin, with the exponents of their display units
+type OsmosisPool struct {
+	Denom         string `json:"denom"`
+	PoolID        uint64 `json:"pool_id"`
+	QuoteDenom    string `json:"quote_denom"`
+	BaseExponent  uint32 `json:"base_exponent"`
+	QuoteExponent uint32 `json:"quote_exponent"`
+}
+
+func SetupValueFlags(conf *ValueConfig, cmd *cobra.Command) {
+	cmd.Flags().StringVar(&conf.Value.ChainID, "value.chain-id", "", "chain ID of the indexed chain to value")
+	cmd.Flags().Int64Var(&conf.Value.StartHeight, "value.start-height", 1, "first block height to value")
+	cmd.Flags().Int64Var(&conf.Value.EndHeight, "value.end-height", 0, "last block height to value (0 for every indexed block)")
+	cmd.Flags().StringVar(&conf.Value.SourcesFile, "value.sources-file", "", "path to a JSON file declaring the price sources in order of precedence")
+	cmd.Flags().BoolVar(&conf.Value.Revalue, "value.revalue", false, "value the blocks in the range again, e.g. after adding a price source")
+	cmd.Flags().StringVar(&conf.Value.ReferenceDenom, "value.reference-denom", "", "base denom the fees are converted to at the prices of their block, e.g. uatom (no conversion when empty)")
+	cmd.Flags().StringVar(&conf.Value.RulesFile, "value.rules-file", "", "path to a JSON file declaring the rules classifying the flows as taxable events (defaults to fee, income and transfer by kind)")
+}
+
+func (conf *ValueConfig) Validate() error {
+	err := validateDatabaseConf(conf.Database)
+	if err != nil {
+		return err
+	}
+
+	if util.StrNotSet(conf.Value.ChainID) {
+		return errors.New("value.chain-id must be set")
+	}
+
+	if conf.Value.StartHeight < 1 || (conf.Value.EndHeight != 0 && conf.Value.EndHeight < conf.Value.StartHeight) {
+		return errors.New("value.start-height must be at least 1 and value.end-height must not be below it")
+	}
+
+	if util.StrNotSet(conf.Value.SourcesFile) {
+		return errors.New("value.sources-file must be set")
+	}
+	if _, err := os.Stat(conf.Value.SourcesFile); os.IsNotExist(err) {
+		return fmt.Errorf("value.sources-file %s does not exist", conf.Value.SourcesFile)
+	}
+
+	if conf.Value.RulesFile != "" {
+		if _, err := os.Stat(conf.Value.RulesFile); os.IsNotExist(err) {
+			return fmt.Errorf("value.rules-file %s does not exist", conf.Value.RulesFile)
+		}
+	}
+
+	return nil
+}
+
+func addValueConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(valueBase{}, "value") {
+		validKeys[key] = struct{}{}
+	}
+}
+
+// The config file is shared by all commands, so the value command accepts the same keys as the index command
+func CheckSuperfluousValueKeys(keys []string) []string {
+	return CheckSuperfluousIndexKeys(keys)
+}
+
+// LoadPriceSources reads and validates the price sources declared in a JSON file
+func LoadPriceSources(path string) ([]PriceSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParsePriceSources(data)
+}
+
+func ParsePriceSources(data []byte) ([]PriceSource, error) {
+	var file struct {
+		Sources []PriceSource `json:"sources"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse price sources: %w", err)
+	}
+
+	if len(file.Sources) == 0 {
+		return nil, errors.New("no price sources are declared")
+	}
+
+	for _, source := range file.Sources {
+		switch source.Type {
+		case PriceSourceCSV:
+			if source.File == "" {
+				return nil, errors.New("csv price sources need a file")
+			}
+		case PriceSourceCoinGecko:
+			if len(source.IDs) == 0 {
+				return nil, errors.New("coingecko price sources need the ids of the denoms")
+			}
+		case PriceSourceOsmosis:
+			if source.URL == "" || len(source.Pools) == 0 {
+				return nil, errors.New("osmosis price sources need the url of a node and pools")
+			}
+			for _, pool := range source.Pools {
+				if pool.Denom == "" || pool.QuoteDenom == "" || pool.PoolID == 0 {
+					return nil, errors.New("every osmosis pool needs a denom, pool_id and quote_denom")
+				}
+			}
+		case PriceSourceDex:
+			if len(source.Stablecoins) == 0 {
+				return nil, errors.New("dex price sources need the stablecoins to quote against")
+			}
+			if source.MaxAge != "" {
+				if maxAge, err := time.ParseDuration(source.MaxAge); err != nil || maxAge <= 0 {
+					return nil, fmt.Errorf("invalid max_age %q of dex price source, must be a positive duration", source.MaxAge)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unknown price source type %q, must be csv, coingecko, osmosis or dex", source.Type)
+		}
+	}
+
+	return file.Sources, nil
+}
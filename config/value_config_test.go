@@ -0,0 +1,46 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ValueConfigTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ValueConfigTestSuite) TestParsePriceSources() {
+	sources, err := ParsePriceSources([]byte(`{"sources": [
+		{"type": "csv", "file": "prices.csv"},
+		{"type": "osmosis", "url": "http://localhost:1317", "pools": [{"denom": "uosmo", "pool_id": 1464, "quote_denom": "uusdc", "base_exponent": 6, "quote_exponent": 6}]},
+		{"type": "coingecko", "ids": {"uatom": "cosmos"}},
+		{"type": "dex", "stablecoins": ["uusdc"], "max_age": "1h"}
+	]}`))
+	suite.Require().NoError(err)
+	suite.Require().Len(sources, 4)
+	suite.Require().Equal(uint64(1464), sources[1].Pools[0].PoolID)
+	suite.Require().Equal("cosmos", sources[2].IDs["uatom"])
+	suite.Require().Equal(time.Hour, sources[3].Window())
+	suite.Require().Equal(24*time.Hour, sources[0].Window())
+
+	invalid := []string{
+		`{"sources": []}`,
+		`{"sources": [{"type": "csv"}]}`,
+		`{"sources": [{"type": "coingecko"}]}`,
+		`{"sources": [{"type": "osmosis", "pools": [{"denom": "uosmo", "pool_id": 1, "quote_denom": "uusdc"}]}]}`,
+		`{"sources": [{"type": "osmosis", "url": "http://localhost:1317", "pools": [{"denom": "uosmo", "quote_denom": "uusdc"}]}]}`,
+		`{"sources": [{"type": "dex"}]}`,
+		`{"sources": [{"type": "dex", "stablecoins": ["uusdc"], "max_age": "an hour"}]}`,
+		`{"sources": [{"type": "binance"}]}`,
+	}
+	for _, data := range invalid {
+		_, err := ParsePriceSources([]byte(data))
+		suite.Require().Error(err, data)
+	}
+}
+
+func TestValueConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(ValueConfigTestSuite))
+}
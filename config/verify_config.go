@@ -0,0 +1,60 @@
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// Light client verification of the fetched blocks against a trusted root, for indexing from untrusted RPC providers
+type verify struct {
+	Enabled bool `mapstructure:"enabled"`
+	// TrustedHeight and TrustedHash are the header the light client trusts, e.g. taken from a block explorer or a node
+	// of your own
+	TrustedHeight  int64         `mapstructure:"trusted-height"`
+	TrustedHash    string        `mapstructure:"trusted-hash"`
+	TrustingPeriod time.Duration `mapstructure:"trusting-period"`
+	// Witnesses are the RPC nodes the headers of probe.rpc are cross-checked with
+	Witnesses []string `mapstructure:"witnesses"`
+}
+
+func setupVerifyFlags(conf *verify, cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&conf.Enabled, "verify.enabled", false, "verify the fetched blocks with a light client before indexing them")
+	cmd.PersistentFlags().Int64Var(&conf.TrustedHeight, "verify.trusted-height", 0, "height of the header the light client trusts")
+	cmd.PersistentFlags().StringVar(&conf.TrustedHash, "verify.trusted-hash", "", "hex encoded hash of the header the light client trusts")
+	cmd.PersistentFlags().DurationVar(&conf.TrustingPeriod, "verify.trusting-period", 168*time.Hour, "period the trusted header is trusted for, shorter than the unbonding period of the chain")
+	cmd.PersistentFlags().StringSliceVar(&conf.Witnesses, "verify.witnesses", nil, "rpc nodes the headers of probe.rpc are cross-checked with, can be repeated")
+}
+
+func validateVerifyConf(conf verify) error {
+	if !conf.Enabled {
+		return nil
+	}
+
+	if conf.TrustedHeight < 1 {
+		return errors.New("verify.trusted-height must be set when verify.enabled is true")
+	}
+
+	hash, err := hex.DecodeString(conf.TrustedHash)
+	if err != nil || len(hash) != 32 {
+		return errors.New("verify.trusted-hash must be a hex encoded SHA-256 hash")
+	}
+
+	if conf.TrustingPeriod <= 0 {
+		return errors.New("verify.trusting-period must be positive")
+	}
+
+	if len(conf.Witnesses) == 0 {
+		return errors.New("verify.witnesses must list at least one rpc node when verify.enabled is true")
+	}
+
+	return nil
+}
+
+func addVerifyConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(verify{}, "verify") {
+		validKeys[key] = struct{}{}
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+)
+
+// Materialized views declared by the user, created on startup and refreshed while indexing
+type views struct {
+	File string `mapstructure:"file"`
+}
+
+// MaterializedView is a view declared in the views file. Views with unique columns get a unique index and are refreshed
+// concurrently, which only writes the rows that changed and does not block readers.
+type MaterializedView struct {
+	Name          string   `json:"name"`
+	Query         string   `json:"query"`
+	UniqueColumns []string `json:"unique_columns"`
+	// RefreshBlocks refreshes the view every time this many blocks were indexed, 0 disables the block cadence
+	RefreshBlocks int64 `json:"refresh_blocks"`
+	// RefreshInterval refreshes the view on the first indexed block after the interval passed, e.g. "10m"
+	RefreshInterval string `json:"refresh_interval"`
+
+	Interval time.Duration `json:"-"`
+}
+
+var viewIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// LoadMaterializedViews reads and validates the views declared in a JSON file
+func LoadMaterializedViews(path string) ([]MaterializedView, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseMaterializedViews(data)
+}
+
+func ParseMaterializedViews(data []byte) ([]MaterializedView, error) {
+	var file struct {
+		Views []MaterializedView `json:"views"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse materialized views: %w", err)
+	}
+
+	names := make(map[string]struct{}, len(file.Views))
+	for i := range file.Views {
+		view := &file.Views[i]
+
+		if !viewIdentifier.MatchString(view.Name) {
+			return nil, fmt.Errorf("materialized view name %q must be a lowercase identifier", view.Name)
+		}
+		if _, ok := names[view.Name]; ok {
+			return nil, fmt.Errorf("materialized view %s is declared twice", view.Name)
+		}
+		names[view.Name] = struct{}{}
+
+		if view.Query == "" {
+			return nil, fmt.Errorf("materialized view %s has no query", view.Name)
+		}
+
+		for _, column := range view.UniqueColumns {
+			if !viewIdentifier.MatchString(column) {
+				return nil, fmt.Errorf("unique column %q of materialized view %s must be a lowercase identifier", column, view.Name)
+			}
+		}
+
+		if view.RefreshBlocks < 0 {
+			return nil, fmt.Errorf("refresh_blocks of materialized view %s must be a positive number or 0", view.Name)
+		}
+
+		if view.RefreshInterval != "" {
+			interval, err := time.ParseDuration(view.RefreshInterval)
+			if err != nil {
+				return nil, fmt.Errorf("invalid refresh_interval of materialized view %s: %w", view.Name, err)
+			}
+			view.Interval = interval
+		}
+
+		if view.RefreshBlocks == 0 && view.Interval <= 0 {
+			return nil, fmt.Errorf("materialized view %s needs a refresh_blocks or refresh_interval cadence", view.Name)
+		}
+	}
+
+	return file.Views, nil
+}
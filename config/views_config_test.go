@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ViewsConfigTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ViewsConfigTestSuite) TestParseMaterializedViews() {
+	views, err := ParseMaterializedViews([]byte(`{"views": [{"name": "daily_counts", "query": "SELECT 1 AS day", "unique_columns": ["day"], "refresh_blocks": 10, "refresh_interval": "5m"}]}`))
+	suite.Require().NoError(err)
+	suite.Require().Len(views, 1)
+	suite.Require().Equal(int64(10), views[0].RefreshBlocks)
+	suite.Require().Equal(5*time.Minute, views[0].Interval)
+
+	invalid := []string{
+		`{"views": [{"name": "Daily Counts", "query": "SELECT 1", "refresh_blocks": 1}]}`,
+		`{"views": [{"name": "daily_counts", "refresh_blocks": 1}]}`,
+		`{"views": [{"name": "daily_counts", "query": "SELECT 1"}]}`,
+		`{"views": [{"name": "daily_counts", "query": "SELECT 1", "refresh_interval": "soon"}]}`,
+		`{"views": [{"name": "daily_counts", "query": "SELECT 1", "unique_columns": ["day; DROP"], "refresh_blocks": 1}]}`,
+		`{"views": [{"name": "a", "query": "SELECT 1", "refresh_blocks": 1}, {"name": "a", "query": "SELECT 2", "refresh_blocks": 1}]}`,
+	}
+	for _, data := range invalid {
+		_, err := ParseMaterializedViews([]byte(data))
+		suite.Require().Error(err, data)
+	}
+}
+
+func TestViewsConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(ViewsConfigTestSuite))
+}
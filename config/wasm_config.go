@@ -0,0 +1,44 @@
+package config
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Built-in parsing of the events of CosmWasm contracts executed, instantiated and migrated by the indexed messages
+type wasm struct {
+	Enabled bool `mapstructure:"enabled"`
+	// Contracts restricts the indexed events to the contracts of these addresses, all contracts are indexed when empty
+	Contracts []string `mapstructure:"contracts"`
+}
+
+func setupWasmFlags(conf *wasm, cmd *cobra.Command) {
+	cmd.PersistentFlags().BoolVar(&conf.Enabled, "wasm.enabled", false, "index the events of CosmWasm contracts emitted by the execute, instantiate and migrate messages")
+	cmd.PersistentFlags().StringSliceVar(&conf.Contracts, "wasm.contracts", nil, "addresses of the contracts whose events are indexed, all contracts when empty")
+}
+
+func validateWasmConf(conf wasm) error {
+	if len(conf.Contracts) == 0 {
+		return nil
+	}
+
+	if !conf.Enabled {
+		return errors.New("wasm.contracts requires wasm.enabled")
+	}
+
+	for _, contract := range conf.Contracts {
+		if strings.TrimSpace(contract) == "" {
+			return errors.New("wasm.contracts must not contain empty addresses")
+		}
+	}
+
+	return nil
+}
+
+func addWasmConfigKeys(validKeys map[string]struct{}) {
+	for _, key := range getValidConfigKeys(wasm{}, "wasm") {
+		validKeys[key] = struct{}{}
+	}
+}
@@ -1,10 +1,14 @@
 package core
 
 import (
+	"bufio"
 	"encoding/json"
+	"fmt"
+	"io"
 	"math"
 	"os"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,19 +17,80 @@ import (
 	"github.com/DefiantLabs/cosmos-indexer/db/models"
 	"github.com/DefiantLabs/cosmos-indexer/rpc"
 	"github.com/DefiantLabs/cosmos-indexer/util"
-	"github.com/DefiantLabs/probe/client"
 	"gorm.io/gorm"
 )
 
 var EnqueueFunctions = map[string]func(chan int64) error{}
 
 type EnqueueData struct {
-	Height            int64
-	IndexBlockEvents  bool
-	IndexTransactions bool
+	Height            int64 `json:"height"`
+	IndexBlockEvents  bool  `json:"index_block_events"`
+	IndexTransactions bool  `json:"index_transactions"`
 }
 
-func GenerateBlockFileEnqueueFunction(db *gorm.DB, cfg config.IndexConfig, client *client.ChainClient, chainID uint, blockInputFile string) (func(chan *EnqueueData) error, error) {
+// GenerateBlockStreamEnqueueFunction enqueues the blocks read from the input as they arrive, e.g. heights piped into the
+// index command by other tooling. Every line holds a height or a JSON enqueue record such as
+// {"height": 100, "index_block_events": false}, fields missing from a record are taken from the configuration.
+// Heights outside of the range the node serves are skipped, and the input is read until it is closed.
+func GenerateBlockStreamEnqueueFunction(cfg config.IndexConfig, client rpc.Client, input io.Reader) (func(chan *EnqueueData) error, error) {
+	return func(blockChan chan *EnqueueData) error {
+		earliestBlock, latestBlock, err := rpc.GetEarliestAndLatestBlockHeights(client)
+		if err != nil {
+			config.Log.Error("Error getting blockchain latest height.", err)
+			return err
+		}
+
+		enqueued := make(map[int64]struct{})
+		scanner := bufio.NewScanner(input)
+		for lineNumber := 1; scanner.Scan(); lineNumber++ {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+
+			enqueueData := EnqueueData{
+				IndexBlockEvents:  cfg.Base.BlockEventIndexingEnabled,
+				IndexTransactions: cfg.Base.TransactionIndexingEnabled,
+			}
+			if strings.HasPrefix(line, "{") {
+				err = json.Unmarshal([]byte(line), &enqueueData)
+			} else {
+				enqueueData.Height, err = strconv.ParseInt(line, 10, 64)
+			}
+			if err != nil {
+				return fmt.Errorf("line %d of the block input is neither a height nor an enqueue record: %w", lineNumber, err)
+			}
+
+			if _, ok := enqueued[enqueueData.Height]; ok {
+				continue
+			}
+
+			// The chain keeps growing while the input is read
+			if enqueueData.Height > latestBlock {
+				latestBlock, err = rpc.GetLatestBlockHeightWithRetry(client, cfg.Base.RequestRetryAttempts, cfg.Base.RequestRetryMaxWait)
+				if err != nil {
+					config.Log.Error("Error getting blockchain latest height.", err)
+					return err
+				}
+			}
+			if enqueueData.Height < earliestBlock || enqueueData.Height > latestBlock {
+				config.Log.Warnf("Block %d is past the blockchain earliest height (%d) and latest height (%d) and will be skipped", enqueueData.Height, earliestBlock, latestBlock)
+				continue
+			}
+
+			if cfg.Base.Throttling != 0 {
+				time.Sleep(time.Second * time.Duration(cfg.Base.Throttling))
+			}
+			config.Log.Debugf("Sending block %v to be indexed.", enqueueData.Height)
+			enqueued[enqueueData.Height] = struct{}{}
+			blockChan <- &enqueueData
+		}
+
+		return scanner.Err()
+	}, nil
+}
+
+func GenerateBlockFileEnqueueFunction(db *gorm.DB, cfg config.IndexConfig, client rpc.Client, chainID uint, blockInputFile string) (func(chan *EnqueueData) error, error) {
 	return func(blockChan chan *EnqueueData) error {
 		plan, err := os.ReadFile(blockInputFile)
 		if err != nil {
@@ -109,13 +174,15 @@ func GenerateMsgTypeEnqueueFunction(db *gorm.DB, cfg config.IndexConfig, chainID
 		endBlock = heighestBlock.Height
 	}
 
-	rows, err := db.Raw(`SELECT height FROM blocks
-							JOIN txes ON txes.block_id = blocks.id
-							JOIN messages ON messages.tx_id = txes.id
-							JOIN message_types ON message_types.id = messages.message_type_id
+	query := fmt.Sprintf(`SELECT height FROM %s AS blocks
+							JOIN %s AS txes ON txes.block_id = blocks.id
+							JOIN %s AS messages ON messages.tx_id = txes.id
+							JOIN %s AS message_types ON message_types.id = messages.message_type_id
 							AND message_types.message_type = ?
 							WHERE height >= ? AND height <= ? AND chain_id = ?::int;
-							`, msgType, startBlock, endBlock, chainID).Rows()
+							`, dbTypes.TableName(db, &models.Block{}), dbTypes.TableName(db, &models.Tx{}),
+		dbTypes.TableName(db, &models.Message{}), dbTypes.TableName(db, &models.MessageType{}))
+	rows, err := db.Raw(query, msgType, startBlock, endBlock, chainID).Rows()
 	if err != nil {
 		config.Log.Errorf("Error checking DB for blocks to reindex. Err: %v", err)
 		return nil, err
@@ -147,12 +214,78 @@ func GenerateMsgTypeEnqueueFunction(db *gorm.DB, cfg config.IndexConfig, chainID
 	}, nil
 }
 
+// GenerateGapEnqueueFunction enqueues the heights from the start block to the end block that were not indexed, see
+// dbTypes.FindMissingHeights, e.g. the holes a crash left behind. An end block of -1 ends the range at the highest
+// indexed block. Heights outside of the range the node serves are skipped.
+func GenerateGapEnqueueFunction(db *gorm.DB, cfg config.IndexConfig, client rpc.Client, chainID uint) (func(chan *EnqueueData) error, error) {
+	startBlock, endBlock := GapRange(db, cfg, chainID)
+	gaps, err := dbTypes.FindMissingHeights(db, chainID, startBlock, endBlock)
+	if err != nil {
+		config.Log.Errorf("Error checking DB for missing blocks. Err: %v", err)
+		return nil, err
+	}
+
+	var missing int64
+	for _, gap := range gaps {
+		missing += gap.Blocks()
+	}
+	config.Log.Infof("Found %d missing blocks in %d gaps between heights %d and %d", missing, len(gaps), startBlock, endBlock)
+
+	return func(blockChan chan *EnqueueData) error {
+		earliestBlock, latestBlock, err := rpc.GetEarliestAndLatestBlockHeights(client)
+		if err != nil {
+			config.Log.Error("Error getting blockchain latest height.", err)
+			return err
+		}
+
+		for _, gap := range gaps {
+			start, end := gap.Start, gap.End
+			if start < earliestBlock {
+				start = earliestBlock
+			}
+			if end > latestBlock {
+				end = latestBlock
+			}
+			if start != gap.Start || end != gap.End {
+				config.Log.Warnf("Blocks of the gap %d-%d past the blockchain earliest height (%d) and latest height (%d) will be skipped", gap.Start, gap.End, earliestBlock, latestBlock)
+			}
+
+			for height := start; height <= end; height++ {
+				if cfg.Base.Throttling != 0 {
+					time.Sleep(time.Second * time.Duration(cfg.Base.Throttling))
+				}
+				config.Log.Debugf("Sending missing block %v to be indexed.", height)
+				blockChan <- &EnqueueData{
+					IndexBlockEvents:  cfg.Base.BlockEventIndexingEnabled,
+					IndexTransactions: cfg.Base.TransactionIndexingEnabled,
+					Height:            height,
+				}
+			}
+		}
+		return nil
+	}, nil
+}
+
+// GapRange returns the range of heights scanned for gaps, the start block to the end block or the highest indexed block
+// when the end block is -1
+func GapRange(db *gorm.DB, cfg config.IndexConfig, chainID uint) (int64, int64) {
+	startBlock := cfg.Base.StartBlock
+	if startBlock <= 0 {
+		startBlock = 1
+	}
+	endBlock := cfg.Base.EndBlock
+	if endBlock == -1 {
+		endBlock = dbTypes.GetHighestIndexedBlock(db, chainID).Height
+	}
+	return startBlock, endBlock
+}
+
 // The default enqueue function will enqueue blocks according to the configuration passed in. It has a few default cases detailed here:
 // Based on whether transaction indexing or block event indexing are enabled, it will choose a start block based on passed in config values.
 // If reindexing is disabled, it will not reindex blocks that have already been indexed. This means it may skip around finding blocks that have not been
 // indexed according to the current configuration.
 // If failed block reattempts are enabled, it will enqueue those according to the passed in configuration as well.
-func GenerateDefaultEnqueueFunction(db *gorm.DB, cfg config.IndexConfig, client *client.ChainClient, chainID uint) (func(chan *EnqueueData) error, error) {
+func GenerateDefaultEnqueueFunction(db *gorm.DB, cfg config.IndexConfig, client rpc.Client, chainID uint) (func(chan *EnqueueData) error, error) {
 	var failedBlockEnqueueData []*EnqueueData
 	if cfg.Base.ReattemptFailedBlocks {
 		var failedEventBlocks []models.FailedEventBlock
@@ -160,7 +293,7 @@ func GenerateDefaultEnqueueFunction(db *gorm.DB, cfg config.IndexConfig, client
 
 		uniqueBlockFailures := make(map[int64]*EnqueueData)
 		if cfg.Base.BlockEventIndexingEnabled {
-			err := db.Table("failed_event_blocks").Where("blockchain_id = ?::int", chainID).Order("height asc").Scan(&failedEventBlocks).Error
+			err := db.Model(&models.FailedEventBlock{}).Where("blockchain_id = ?::int", chainID).Order("height asc").Scan(&failedEventBlocks).Error
 			if err != nil {
 				config.Log.Error("Error retrieving failed event blocks for reenqueue", err)
 				return nil, err
@@ -168,7 +301,7 @@ func GenerateDefaultEnqueueFunction(db *gorm.DB, cfg config.IndexConfig, client
 		}
 
 		if cfg.Base.TransactionIndexingEnabled {
-			err := db.Table("failed_blocks").Where("blockchain_id = ?::int", chainID).Order("height asc").Scan(&failedBlocks).Error
+			err := db.Model(&models.FailedBlock{}).Where("blockchain_id = ?::int", chainID).Order("height asc").Scan(&failedBlocks).Error
 			if err != nil {
 				config.Log.Error("Error retrieving failed blocks for reenqueue", err)
 				return nil, err
@@ -0,0 +1,71 @@
+package core
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/stretchr/testify/suite"
+)
+
+// growingClient serves a chain whose latest height moves to the next of latestHeights on every status request
+type growingClient struct {
+	nodeClient
+	earliestHeight int64
+	latestHeights  []int64
+}
+
+func (c *growingClient) Status(ctx context.Context) (*coretypes.ResultStatus, error) {
+	latestHeight := c.latestHeights[0]
+	if len(c.latestHeights) > 1 {
+		c.latestHeights = c.latestHeights[1:]
+	}
+	return &coretypes.ResultStatus{SyncInfo: coretypes.SyncInfo{EarliestBlockHeight: c.earliestHeight, LatestBlockHeight: latestHeight}}, nil
+}
+
+type BlockEnqueueTestSuite struct {
+	suite.Suite
+}
+
+func (suite *BlockEnqueueTestSuite) TestBlockStream() {
+	cfg := config.IndexConfig{}
+	cfg.Base.BlockEventIndexingEnabled = true
+	cfg.Base.TransactionIndexingEnabled = true
+	cl := &growingClient{earliestHeight: 5, latestHeights: []int64{15, 25}}
+
+	// Block 10 is sent twice, block 3 is below the earliest height and block 20 is produced while the input is read
+	input := strings.NewReader("10\n{\"height\": 12, \"index_block_events\": false}\n\n 10 \n3\n20\n")
+	enqueue, err := GenerateBlockStreamEnqueueFunction(cfg, cl, input)
+	suite.Require().NoError(err)
+
+	blockChan := make(chan *EnqueueData, 10)
+	suite.Require().NoError(enqueue(blockChan))
+	close(blockChan)
+
+	var enqueued []EnqueueData
+	for enqueueData := range blockChan {
+		enqueued = append(enqueued, *enqueueData)
+	}
+	suite.Require().Equal([]EnqueueData{
+		{Height: 10, IndexBlockEvents: true, IndexTransactions: true},
+		{Height: 12, IndexBlockEvents: false, IndexTransactions: true},
+		{Height: 20, IndexBlockEvents: true, IndexTransactions: true},
+	}, enqueued)
+}
+
+func (suite *BlockEnqueueTestSuite) TestBlockStreamMalformedLine() {
+	cl := &growingClient{earliestHeight: 1, latestHeights: []int64{100}}
+	enqueue, err := GenerateBlockStreamEnqueueFunction(config.IndexConfig{}, cl, strings.NewReader("10\nten\n"))
+	suite.Require().NoError(err)
+
+	blockChan := make(chan *EnqueueData, 10)
+	err = enqueue(blockChan)
+	suite.Require().ErrorContains(err, "line 2 of the block input")
+	suite.Require().Len(blockChan, 1)
+}
+
+func TestBlockEnqueueTestSuite(t *testing.T) {
+	suite.Run(t, new(BlockEnqueueTestSuite))
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"encoding/base64"
+	"time"
 
 	abci "github.com/cometbft/cometbft/abci/types"
 
@@ -9,11 +10,12 @@ import (
 	"github.com/DefiantLabs/cosmos-indexer/db"
 	"github.com/DefiantLabs/cosmos-indexer/db/models"
 	"github.com/DefiantLabs/cosmos-indexer/filter"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
 	"github.com/DefiantLabs/cosmos-indexer/parsers"
 	ctypes "github.com/cometbft/cometbft/rpc/core/types"
 )
 
-func ProcessRPCBlockResults(conf config.IndexConfig, block models.Block, blockResults *ctypes.ResultBlockResults, customBeginBlockParsers map[string][]parsers.BlockEventParser, customEndBlockParsers map[string][]parsers.BlockEventParser) (*db.BlockDBWrapper, error) {
+func ProcessRPCBlockResults(conf config.IndexConfig, block models.Block, blockResults *ctypes.ResultBlockResults, customBeginBlockParsers map[string][]parsers.BlockEventParser, customEndBlockParsers map[string][]parsers.BlockEventParser, blockContext parsers.BlockContext) (*db.BlockDBWrapper, error) {
 	var blockDBWrapper db.BlockDBWrapper
 
 	blockDBWrapper.Block = &block
@@ -22,13 +24,13 @@ func ProcessRPCBlockResults(conf config.IndexConfig, block models.Block, blockRe
 	blockDBWrapper.UniqueBlockEventTypes = make(map[string]models.BlockEventType)
 
 	var err error
-	blockDBWrapper.BeginBlockEvents, err = ProcessRPCBlockEvents(blockDBWrapper.Block, blockResults.BeginBlockEvents, models.BeginBlockEvent, blockDBWrapper.UniqueBlockEventTypes, blockDBWrapper.UniqueBlockEventAttributeKeys, customBeginBlockParsers, conf)
+	blockDBWrapper.BeginBlockEvents, err = ProcessRPCBlockEvents(blockDBWrapper.Block, blockResults.BeginBlockEvents, models.BeginBlockEvent, blockDBWrapper.UniqueBlockEventTypes, blockDBWrapper.UniqueBlockEventAttributeKeys, customBeginBlockParsers, blockContext, conf)
 
 	if err != nil {
 		return nil, err
 	}
 
-	blockDBWrapper.EndBlockEvents, err = ProcessRPCBlockEvents(blockDBWrapper.Block, blockResults.EndBlockEvents, models.EndBlockEvent, blockDBWrapper.UniqueBlockEventTypes, blockDBWrapper.UniqueBlockEventAttributeKeys, customEndBlockParsers, conf)
+	blockDBWrapper.EndBlockEvents, err = ProcessRPCBlockEvents(blockDBWrapper.Block, blockResults.EndBlockEvents, models.EndBlockEvent, blockDBWrapper.UniqueBlockEventTypes, blockDBWrapper.UniqueBlockEventAttributeKeys, customEndBlockParsers, blockContext, conf)
 
 	if err != nil {
 		return nil, err
@@ -37,7 +39,7 @@ func ProcessRPCBlockResults(conf config.IndexConfig, block models.Block, blockRe
 	return &blockDBWrapper, nil
 }
 
-func ProcessRPCBlockEvents(block *models.Block, blockEvents []abci.Event, blockLifecyclePosition models.BlockLifecyclePosition, uniqueEventTypes map[string]models.BlockEventType, uniqueAttributeKeys map[string]models.BlockEventAttributeKey, customParsers map[string][]parsers.BlockEventParser, conf config.IndexConfig) ([]db.BlockEventDBWrapper, error) {
+func ProcessRPCBlockEvents(block *models.Block, blockEvents []abci.Event, blockLifecyclePosition models.BlockLifecyclePosition, uniqueEventTypes map[string]models.BlockEventType, uniqueAttributeKeys map[string]models.BlockEventAttributeKey, customParsers map[string][]parsers.BlockEventParser, blockContext parsers.BlockContext, conf config.IndexConfig) ([]db.BlockEventDBWrapper, error) {
 	beginBlockEvents := make([]db.BlockEventDBWrapper, len(blockEvents))
 
 	for index, event := range blockEvents {
@@ -84,13 +86,32 @@ func ProcessRPCBlockEvents(block *models.Block, blockEvents []abci.Event, blockL
 
 		if customParsers != nil {
 			if customBlockEventParsers, ok := customParsers[event.Type]; ok {
-				for index, customParser := range customBlockEventParsers {
+				for parserIndex, customParser := range customBlockEventParsers {
+					if !parsers.SupportsModuleVersions(customParser, blockContext.ModuleVersions) {
+						continue
+					}
 					// We deliberately ignore the error here, as we want to continue processing the block events even if a custom parser fails
-					parsedData, err := customParser.ParseBlockEvent(event, conf)
+					parser, parserEvent := customParser, event
+					parseContext := parsers.BlockEventParseContext{
+						Config:            conf,
+						Block:             blockContext,
+						LifecyclePosition: blockLifecyclePosition,
+						EventIndex:        index,
+						Events:            blockEvents,
+					}
+					start := time.Now()
+					parsedData, err := parsers.RunParseWithTimeout(customParserTimeout(&conf), func() (*any, error) {
+						if contextualParser, ok := parser.(parsers.ContextualBlockEventParser); ok {
+							return contextualParser.ParseBlockEventWithContext(parserEvent, parseContext)
+						}
+						return parser.ParseBlockEvent(parserEvent, conf)
+					})
+					metrics.ObserveParserExecution(customParser.Identifier(), metrics.ParserStageParse, time.Since(start), err)
+					logIsolatedParserFailure(customParser.Identifier(), err)
 					beginBlockEvents[index].BlockEventParsedDatasets = append(beginBlockEvents[index].BlockEventParsedDatasets, parsers.BlockEventParsedData{
 						Data:   parsedData,
 						Error:  err,
-						Parser: &customBlockEventParsers[index],
+						Parser: &customBlockEventParsers[parserIndex],
 					})
 				}
 			}
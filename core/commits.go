@@ -0,0 +1,69 @@
+package core
+
+import (
+	"fmt"
+
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	cmttypes "github.com/cometbft/cometbft/types"
+	sdkTypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+// hasAbsentSignatures tells if a commit has signatures without the address of their validator
+func hasAbsentSignatures(commit *cmttypes.Commit) bool {
+	if commit == nil {
+		return false
+	}
+	for _, sig := range commit.Signatures {
+		if sig.BlockIDFlag == cmttypes.BlockIDFlagAbsent {
+			return true
+		}
+	}
+	return false
+}
+
+// ProcessBlockCommit processes the last commit of a block into the commit of the previous height, with a signature per
+// validator of its set. Absent signatures carry no address, they are attributed to the validator at their position in
+// validators, the set of the committed height, when it was fetched. The first block has no commit to process.
+func ProcessBlockCommit(block *cmttypes.Block, validators []*cmttypes.Validator) (*dbTypes.BlockCommitDBWrapper, error) {
+	commit := block.LastCommit
+	if commit == nil || commit.Height == 0 {
+		return nil, nil
+	}
+	if validators != nil && len(validators) != len(commit.Signatures) {
+		return nil, fmt.Errorf("the commit of block %d has %d signatures for %d validators", block.Height, len(commit.Signatures), len(validators))
+	}
+
+	wrapper := &dbTypes.BlockCommitDBWrapper{
+		Commit: models.BlockCommit{Height: commit.Height, Round: commit.Round},
+	}
+	for i, sig := range commit.Signatures {
+		signature := models.BlockCommitSignature{Index: i}
+
+		address := sig.ValidatorAddress
+		switch sig.BlockIDFlag {
+		case cmttypes.BlockIDFlagCommit:
+			signature.Flag = models.BlockIDFlagCommit
+		case cmttypes.BlockIDFlagNil:
+			signature.Flag = models.BlockIDFlagNil
+		case cmttypes.BlockIDFlagAbsent:
+			signature.Flag = models.BlockIDFlagAbsent
+			if validators != nil {
+				address = validators[i].Address
+			}
+		default:
+			return nil, fmt.Errorf("unknown block ID flag %d in the commit of block %d", sig.BlockIDFlag, block.Height)
+		}
+
+		if sig.BlockIDFlag != cmttypes.BlockIDFlagAbsent {
+			timestamp := sig.Timestamp
+			signature.Timestamp = &timestamp
+		}
+		if len(address) > 0 {
+			signature.ValidatorAddress = &models.Address{Address: sdkTypes.ConsAddress(address).String()}
+		}
+		wrapper.Signatures = append(wrapper.Signatures, signature)
+	}
+
+	return wrapper, nil
+}
@@ -0,0 +1,113 @@
+package core
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	abci "github.com/cometbft/cometbft/abci/types"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	sdkTypes "github.com/cosmos/cosmos-sdk/types"
+)
+
+// slash is the double sign slash of a validator emitted by the slashing module when it handles an evidence
+type slash struct {
+	jailed      bool
+	burnedCoins string
+}
+
+// ProcessBlockEvidence processes the evidence included in a block. The offenders are matched with the double sign
+// slash events of the begin blocker of the block, which handles the evidence, when its results were fetched.
+func ProcessBlockEvidence(block *cmttypes.Block, blockResults *ctypes.ResultBlockResults) ([]dbTypes.BlockEvidenceDBWrapper, error) {
+	if len(block.Evidence.Evidence) == 0 {
+		return nil, nil
+	}
+
+	var slashes map[string]slash
+	if blockResults != nil {
+		var err error
+		if slashes, err = doubleSignSlashes(blockResults.BeginBlockEvents); err != nil {
+			return nil, fmt.Errorf("failed to decode the slash events of block %d: %w", block.Height, err)
+		}
+	}
+
+	var evidence []dbTypes.BlockEvidenceDBWrapper
+	for i, ev := range block.Evidence.Evidence {
+		wrapper := dbTypes.BlockEvidenceDBWrapper{
+			Evidence: models.BlockEvidence{
+				Index:  i,
+				Hash:   fmt.Sprintf("%X", ev.Hash()),
+				Height: ev.Height(),
+				Time:   ev.Time(),
+			},
+		}
+
+		switch ev := ev.(type) {
+		case *cmttypes.DuplicateVoteEvidence:
+			wrapper.Evidence.Type = models.EvidenceDuplicateVote
+			wrapper.Evidence.TotalVotingPower = ev.TotalVotingPower
+			wrapper.Offenders = append(wrapper.Offenders, evidenceOffender(ev.VoteA.ValidatorAddress, ev.ValidatorPower, slashes))
+		case *cmttypes.LightClientAttackEvidence:
+			wrapper.Evidence.Type = models.EvidenceLightClientAttack
+			wrapper.Evidence.TotalVotingPower = ev.TotalVotingPower
+			for _, validator := range ev.ByzantineValidators {
+				wrapper.Offenders = append(wrapper.Offenders, evidenceOffender(validator.Address, validator.VotingPower, slashes))
+			}
+		default:
+			return nil, fmt.Errorf("unknown evidence type %T in block %d", ev, block.Height)
+		}
+
+		evidence = append(evidence, wrapper)
+	}
+
+	return evidence, nil
+}
+
+func evidenceOffender(address cmttypes.Address, power int64, slashes map[string]slash) models.EvidenceOffender {
+	consAddress := sdkTypes.ConsAddress(address).String()
+	offender := models.EvidenceOffender{ValidatorAddress: models.Address{Address: consAddress}, Power: power}
+	if slash, ok := slashes[consAddress]; ok {
+		offender.Slashed = true
+		offender.Jailed = slash.jailed
+		offender.BurnedCoins = slash.burnedCoins
+	}
+	return offender
+}
+
+// doubleSignSlashes returns the double sign slashes of the slash events by the consensus address of the validator. The
+// attributes are base64 encoded like the block events the indexer processes.
+func doubleSignSlashes(events []abci.Event) (map[string]slash, error) {
+	slashes := map[string]slash{}
+	for _, event := range events {
+		if event.Type != "slash" {
+			continue
+		}
+
+		attributes := map[string]string{}
+		for _, attribute := range event.Attributes {
+			key, err := base64.StdEncoding.DecodeString(attribute.Key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := base64.StdEncoding.DecodeString(attribute.Value)
+			if err != nil {
+				return nil, err
+			}
+			attributes[string(key)] = string(value)
+		}
+		if attributes["reason"] != "double_sign" || attributes["address"] == "" {
+			continue
+		}
+
+		// The jailed attribute holds the address of the jailed validator, older versions a boolean
+		jailed := attributes["jailed"] != ""
+		if parsed, err := strconv.ParseBool(attributes["jailed"]); err == nil {
+			jailed = parsed
+		}
+		slashes[attributes["address"]] = slash{jailed: jailed, burnedCoins: attributes["burned_coins"]}
+	}
+	return slashes, nil
+}
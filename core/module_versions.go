@@ -0,0 +1,153 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/rpc"
+	"gorm.io/gorm"
+)
+
+// ModuleVersionTracker keeps the module version history of a chain, to select the custom parsers written for the
+// module versions of a block. The history of the indexed range is looked up once with Sync, later heights are looked
+// up as they are indexed.
+type ModuleVersionTracker struct {
+	db      *gorm.DB
+	cl      rpc.Client
+	chainID uint
+
+	mu sync.Mutex
+	// history holds the recorded versions of every module by ascending height
+	history map[string][]models.ModuleVersion
+	// synced is the highest height the history is complete up to, latest are the versions at that height
+	synced int64
+	latest rpc.ModuleVersions
+}
+
+func NewModuleVersionTracker(db *gorm.DB, cl rpc.Client, chainID uint) (*ModuleVersionTracker, error) {
+	recorded, err := dbTypes.GetModuleVersions(db, chainID)
+	if err != nil {
+		return nil, err
+	}
+
+	tracker := &ModuleVersionTracker{db: db, cl: cl, chainID: chainID, history: map[string][]models.ModuleVersion{}}
+	tracker.add(recorded)
+	return tracker, nil
+}
+
+// Sync looks up the module versions from the start to the end height, recording the versions at the start and the
+// heights of the upgrades that changed them
+func (t *ModuleVersionTracker) Sync(ctx context.Context, start int64, end int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	startVersions, err := rpc.GetModuleVersions(ctx, t.cl, start)
+	if err != nil {
+		return err
+	}
+	initial := make([]rpc.ModuleVersionChange, 0, len(startVersions))
+	for module, version := range startVersions {
+		initial = append(initial, rpc.ModuleVersionChange{Height: start, Module: module, Version: version})
+	}
+	if err := t.record(initial); err != nil {
+		return err
+	}
+	t.synced, t.latest = start, startVersions
+
+	return t.extend(ctx, end)
+}
+
+// At returns the module versions in effect at the height, nil when they are unknown because the history was not
+// synced or the height is below it
+func (t *ModuleVersionTracker) At(ctx context.Context, height int64) (map[string]uint64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.latest == nil {
+		return nil, nil
+	}
+	if height > t.synced {
+		if err := t.extend(ctx, height); err != nil {
+			return nil, err
+		}
+	}
+
+	versions := map[string]uint64{}
+	for module, history := range t.history {
+		// The last version recorded at or below the height
+		i := sort.Search(len(history), func(i int) bool { return history[i].Height > height })
+		if i == 0 {
+			continue
+		}
+		if version := history[i-1].Version; version > 0 {
+			versions[module] = version
+		}
+	}
+	if len(versions) == 0 && height < t.lowest() {
+		return nil, nil
+	}
+	return versions, nil
+}
+
+// extend looks up the upgrades from the synced height to the end height. Must be called with the lock held.
+func (t *ModuleVersionTracker) extend(ctx context.Context, end int64) error {
+	if end <= t.synced {
+		return nil
+	}
+
+	changes, endVersions, err := rpc.ModuleVersionChanges(ctx, t.cl, t.synced, t.latest, end)
+	if err != nil {
+		return err
+	}
+	if err := t.record(changes); err != nil {
+		return err
+	}
+	for _, change := range changes {
+		config.Log.Infof("Module %s is at version %d from height %d", change.Module, change.Version, change.Height)
+	}
+
+	t.synced, t.latest = end, endVersions
+	return nil
+}
+
+// record stores the changes and adds them to the history. Must be called with the lock held.
+func (t *ModuleVersionTracker) record(changes []rpc.ModuleVersionChange) error {
+	versions := make([]models.ModuleVersion, 0, len(changes))
+	for _, change := range changes {
+		versions = append(versions, models.ModuleVersion{ChainID: t.chainID, Module: change.Module, Height: change.Height, Version: change.Version})
+	}
+	if err := dbTypes.RecordModuleVersions(t.db, versions); err != nil {
+		return err
+	}
+	t.add(versions)
+	return nil
+}
+
+func (t *ModuleVersionTracker) add(versions []models.ModuleVersion) {
+	for _, version := range versions {
+		history := t.history[version.Module]
+		i := sort.Search(len(history), func(i int) bool { return history[i].Height >= version.Height })
+		if i < len(history) && history[i].Height == version.Height {
+			continue
+		}
+		history = append(history, models.ModuleVersion{})
+		copy(history[i+1:], history[i:])
+		history[i] = version
+		t.history[version.Module] = history
+	}
+}
+
+// lowest is the lowest height versions are recorded for
+func (t *ModuleVersionTracker) lowest() int64 {
+	lowest := int64(-1)
+	for _, history := range t.history {
+		if len(history) > 0 && (lowest < 0 || history[0].Height < lowest) {
+			lowest = history[0].Height
+		}
+	}
+	return lowest
+}
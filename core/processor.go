@@ -1,12 +1,20 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"time"
 
 	"github.com/DefiantLabs/cosmos-indexer/config"
 	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/parsers"
+	"github.com/DefiantLabs/cosmos-indexer/rpc"
 	ctypes "github.com/cometbft/cometbft/rpc/core/types"
 	sdkTypes "github.com/cosmos/cosmos-sdk/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type BlockProcessingFailure int
@@ -19,6 +27,7 @@ const (
 	OsmosisNodeRewardIndexError
 	NodeMissingHistoryForBlock
 	FailedBlockEventHandling
+	BlockPanic
 )
 
 type FailedBlockHandler func(height int64, code BlockProcessingFailure, err error)
@@ -37,27 +46,99 @@ func ProcessBlock(blockData *ctypes.ResultBlock, blockResultsData *ctypes.Result
 
 	block.ProposerConsAddress = models.Address{Address: propAddressFromHex.String()}
 	block.TimeStamp = blockData.Block.Time
+	block.Hash = blockData.BlockID.Hash.String()
+	block.ParentHash = blockData.Block.LastBlockID.Hash.String()
 
 	return block, nil
 }
 
 // Log error to stdout. Not much else we can do to handle right now.
-func HandleFailedBlock(height int64, code BlockProcessingFailure, err error) {
-	reason := "{unknown error}"
+// FailedBlockReason returns a human readable description of the failure code
+func FailedBlockReason(code BlockProcessingFailure) string {
 	switch code {
 	case NodeMissingBlockTxs:
-		reason = "node has no TX history for block"
+		return "node has no TX history for block"
 	case BlockQueryError:
-		reason = "failed to query block result for block"
+		return "failed to query block result for block"
 	case OsmosisNodeRewardLookupError:
-		reason = "Failed Osmosis rewards lookup for block"
+		return "Failed Osmosis rewards lookup for block"
 	case OsmosisNodeRewardIndexError:
-		reason = "Failed Osmosis rewards indexing for block"
+		return "Failed Osmosis rewards indexing for block"
 	case NodeMissingHistoryForBlock:
-		reason = "Node has no TX history for block"
+		return "Node has no TX history for block"
 	case FailedBlockEventHandling:
-		reason = "Failed to process block event"
+		return "Failed to process block event"
+	case BlockPanic:
+		return "Panic while processing block"
+	}
+
+	return "{unknown error}"
+}
+
+func HandleFailedBlock(height int64, code BlockProcessingFailure, err error) {
+	config.Log.Error(fmt.Sprintf("Block %v failed. Reason: %v", height, FailedBlockReason(code)), err)
+}
+
+// ErrUnknownMsgType is wrapped by the errors of messages whose type is not registered with the codec
+var ErrUnknownMsgType = errors.New("unknown message type")
+
+// NewBlockFailure describes the failure of a block in component for the failed block tables
+func NewBlockFailure(component models.FailureComponent, code BlockProcessingFailure, err error) models.BlockFailure {
+	failure := models.BlockFailure{ErrorCode: failureCode(code, err), Component: component}
+	if err != nil {
+		failure.ErrorMessage = err.Error()
 	}
+	return failure
+}
 
-	config.Log.Error(fmt.Sprintf("Block %v failed. Reason: %v", height, reason), err)
+// failureCode classifies the failure by the error where it tells more than the processing failure code
+func failureCode(code BlockProcessingFailure, err error) models.FailureCode {
+	switch {
+	case code == BlockPanic:
+		return models.FailurePanic
+	case errors.Is(err, ErrUnknownMsgType):
+		return models.FailureUnknownMsgType
+	case errors.Is(err, rpc.ErrUnverified):
+		return models.FailureVerification
+	case errors.Is(err, rpc.ErrDivergence):
+		return models.FailureDivergence
+	}
+
+	switch code {
+	case BlockQueryError, NodeMissingBlockTxs, NodeMissingHistoryForBlock:
+		if isTimeout(err) {
+			return models.FailureRPCTimeout
+		}
+		return models.FailureRPCError
+	case UnprocessableTxError:
+		return models.FailureTxProcessing
+	case FailedBlockEventHandling:
+		return models.FailureBlockEventProcessing
+	}
+
+	return models.FailureUnknown
+}
+
+// isTimeout reports whether err is a request timing out, whether over HTTP or gRPC
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) || status.Code(err) == codes.DeadlineExceeded
+}
+
+func customParserTimeout(cfg *config.IndexConfig) time.Duration {
+	return time.Duration(cfg.Base.CustomParserTimeout) * time.Second
+}
+
+// Panics and timeouts are recorded as regular parser errors, but are logged as well since they usually point to a broken parser
+func logIsolatedParserFailure(identifier string, err error) {
+	if !parsers.IsIsolatedFailure(err) {
+		return
+	}
+
+	config.Log.Errorf("Custom parser %s failed: %v", identifier, err)
+
+	var panicErr *parsers.PanicError
+	if errors.As(err, &panicErr) {
+		config.Log.Debugf("Custom parser %s panic stack trace:\n%s", identifier, panicErr.Stack)
+	}
 }
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/blockqueue"
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/rpc"
+)
+
+// GenerateQueueEnqueueFunction enqueues the blocks requested by the messages of a queue until the consumer fails.
+// A message is committed once all of its blocks were enqueued, messages that cannot be parsed are logged and committed
+// so they do not block the queue. Heights past the latest block of the chain are skipped.
+func GenerateQueueEnqueueFunction(cfg config.IndexConfig, client rpc.Client, consumer blockqueue.Consumer) (func(chan *EnqueueData) error, error) {
+	return func(blockChan chan *EnqueueData) error {
+		defer consumer.Close()
+		ctx := context.Background()
+
+		earliestBlock, latestBlock, err := rpc.GetEarliestAndLatestBlockHeights(client)
+		if err != nil {
+			config.Log.Error("Error getting blockchain latest height.", err)
+			return err
+		}
+
+		for {
+			message, err := consumer.Next(ctx)
+			if err != nil {
+				config.Log.Error("Error reading block requests from the queue.", err)
+				return err
+			}
+
+			request, err := blockqueue.ParseRequest(message.Data)
+			if err != nil {
+				config.Log.Errorf("Skipping invalid block request %q: %v", message.Data, err)
+			} else {
+				if request.EndHeight > latestBlock {
+					latestBlock, err = rpc.GetLatestBlockHeightWithRetry(client, cfg.Base.RequestRetryAttempts, cfg.Base.RequestRetryMaxWait)
+					if err != nil {
+						config.Log.Error("Error getting blockchain latest height.", err)
+						return err
+					}
+				}
+
+				enqueueData := RequestEnqueueData(cfg, request)
+
+				start, end := request.StartHeight, request.EndHeight
+				if start < earliestBlock {
+					start = earliestBlock
+				}
+				if end > latestBlock {
+					end = latestBlock
+				}
+				if start != request.StartHeight || end != request.EndHeight {
+					config.Log.Warnf("Blocks of the request %d-%d past the blockchain earliest height (%d) and latest height (%d) will be skipped", request.StartHeight, request.EndHeight, earliestBlock, latestBlock)
+				}
+
+				for height := start; height <= end; height++ {
+					if cfg.Base.Throttling != 0 {
+						time.Sleep(time.Second * time.Duration(cfg.Base.Throttling))
+					}
+					config.Log.Debugf("Sending block %v to be indexed.", height)
+					blockData := enqueueData
+					blockData.Height = height
+					blockChan <- &blockData
+				}
+			}
+
+			if err := message.Commit(ctx); err != nil {
+				config.Log.Error("Error committing the block request to the queue.", err)
+				return err
+			}
+		}
+	}, nil
+}
+
+// RequestEnqueueData returns the enqueue data of the blocks of a request, the index flags not set by the request are
+// taken from the configuration. The height is left for the caller to set.
+func RequestEnqueueData(cfg config.IndexConfig, request blockqueue.Request) EnqueueData {
+	enqueueData := EnqueueData{
+		IndexBlockEvents:  cfg.Base.BlockEventIndexingEnabled,
+		IndexTransactions: cfg.Base.TransactionIndexingEnabled,
+	}
+	if request.IndexBlockEvents != nil {
+		enqueueData.IndexBlockEvents = *request.IndexBlockEvents
+	}
+	if request.IndexTransactions != nil {
+		enqueueData.IndexTransactions = *request.IndexTransactions
+	}
+	return enqueueData
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"fmt"
+
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/rpc"
+)
+
+// IndexedHashes returns the hash and the parent hash of the block indexed for the height, empty when it is not indexed
+type IndexedHashes func(height int64) (hash string, parentHash string, err error)
+
+// FindReorg checks the block just written against the indexed blocks next to it: its parent hash against the hash of
+// the block below, and its hash against the parent hash of the block above. Both are checked since the workers fetch
+// the blocks in any order. When either differs the chain reorganized, or blocks were fetched from a node on another
+// fork, and the node tells which blocks are on the abandoned fork. The block itself when the node serves another block
+// for its height, otherwise the indexed blocks below or above it that differ from the blocks the node serves, compared
+// up to maxDepth heights away. It returns the ranges of heights to roll back and index again, none when the hashes
+// match or the blocks next to it are not indexed.
+func FindReorg(ctx context.Context, indexed IndexedHashes, cl rpc.Client, block models.Block, maxDepth int64) ([]dbTypes.HeightRange, error) {
+	var parentForked, childForked bool
+	if block.Height > 1 && block.ParentHash != "" {
+		stored, _, err := indexed(block.Height - 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the hash of block %d: %w", block.Height-1, err)
+		}
+		parentForked = stored != "" && stored != block.ParentHash
+	}
+	if block.Hash != "" {
+		_, storedParent, err := indexed(block.Height + 1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the parent hash of block %d: %w", block.Height+1, err)
+		}
+		childForked = storedParent != "" && storedParent != block.Hash
+	}
+	if !parentForked && !childForked {
+		return nil, nil
+	}
+
+	nodeBlock, err := rpc.GetBlock(ctx, cl, block.Height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get block %d to find the abandoned fork: %w", block.Height, err)
+	}
+	if nodeBlock.BlockID.Hash.String() != block.Hash {
+		return []dbTypes.HeightRange{{Start: block.Height, End: block.Height}}, nil
+	}
+
+	var forks []dbTypes.HeightRange
+	if parentForked {
+		start, err := walkFork(ctx, indexed, cl, block.Height-1, -1, maxDepth)
+		if err != nil {
+			return nil, err
+		}
+		forks = append(forks, dbTypes.HeightRange{Start: start, End: block.Height - 1})
+	}
+	if childForked {
+		end, err := walkFork(ctx, indexed, cl, block.Height+1, 1, maxDepth)
+		if err != nil {
+			return nil, err
+		}
+		forks = append(forks, dbTypes.HeightRange{Start: block.Height + 1, End: end})
+	}
+	return forks, nil
+}
+
+// walkFork compares the indexed blocks from the height next to the forked height, in the direction of step, with the
+// blocks the node serves until they match, a height is not indexed or maxDepth heights were compared. It returns the
+// last height whose indexed block is on the abandoned fork.
+func walkFork(ctx context.Context, indexed IndexedHashes, cl rpc.Client, forkHeight int64, step int64, maxDepth int64) (int64, error) {
+	for depth := int64(1); depth < maxDepth && forkHeight+step >= 1; depth++ {
+		height := forkHeight + step
+		stored, _, err := indexed(height)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read the hash of block %d: %w", height, err)
+		}
+		if stored == "" {
+			break
+		}
+
+		nodeBlock, err := rpc.GetBlock(ctx, cl, height)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get block %d to find the fork height: %w", height, err)
+		}
+		if nodeBlock.BlockID.Hash.String() == stored {
+			break
+		}
+		forkHeight = height
+	}
+	return forkHeight, nil
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	cmtbytes "github.com/cometbft/cometbft/libs/bytes"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/stretchr/testify/suite"
+)
+
+// nodeClient serves the blocks of a chain whose block at each height has the hash in hashes
+type nodeClient struct {
+	hashes map[int64]byte
+}
+
+func (c *nodeClient) Status(ctx context.Context) (*coretypes.ResultStatus, error) {
+	return &coretypes.ResultStatus{}, nil
+}
+
+func (c *nodeClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	hash, ok := c.hashes[height]
+	if !ok {
+		return nil, fmt.Errorf("block %d not found", height)
+	}
+	block := &cmttypes.Block{Header: cmttypes.Header{Height: height, LastBlockID: cmttypes.BlockID{Hash: cmtbytes.HexBytes{c.hashes[height-1]}}}}
+	return &coretypes.ResultBlock{BlockID: cmttypes.BlockID{Hash: cmtbytes.HexBytes{hash}}, Block: block}, nil
+}
+
+func (c *nodeClient) BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error) {
+	return nil, errors.New("block results not served")
+}
+
+func (c *nodeClient) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	return nil, errors.New("txs not served")
+}
+
+type ReorgTestSuite struct {
+	suite.Suite
+}
+
+// hashOf returns the hash string of a block hash served by nodeClient
+func hashOf(hash byte) string {
+	return cmtbytes.HexBytes{hash}.String()
+}
+
+// indexedChain returns the hashes of the blocks indexed with the hash and parent hash per height
+func indexedChain(blocks map[int64][2]byte) IndexedHashes {
+	return func(height int64) (string, string, error) {
+		hashes, ok := blocks[height]
+		if !ok {
+			return "", "", nil
+		}
+		return hashOf(hashes[0]), hashOf(hashes[1]), nil
+	}
+}
+
+func writtenBlock(height int64, hash byte, parentHash byte) models.Block {
+	return models.Block{Height: height, Hash: hashOf(hash), ParentHash: hashOf(parentHash)}
+}
+
+func (suite *ReorgTestSuite) TestMatchingNeighbours() {
+	node := &nodeClient{hashes: map[int64]byte{1: 1, 2: 2, 3: 3}}
+	forks, err := FindReorg(context.Background(), indexedChain(map[int64][2]byte{1: {1, 0}, 3: {3, 2}}), node, writtenBlock(2, 2, 1), 10)
+	suite.Require().NoError(err)
+	suite.Require().Empty(forks)
+
+	// Blocks whose neighbours are not indexed yet are checked once the neighbours are written
+	forks, err = FindReorg(context.Background(), indexedChain(map[int64][2]byte{}), node, writtenBlock(2, 2, 1), 10)
+	suite.Require().NoError(err)
+	suite.Require().Empty(forks)
+}
+
+func (suite *ReorgTestSuite) TestAbandonedBlocksBelow() {
+	// Heights 3 and 4 were indexed from a fork the chain abandoned at height 3
+	node := &nodeClient{hashes: map[int64]byte{1: 1, 2: 2, 3: 3, 4: 4, 5: 5}}
+	indexed := indexedChain(map[int64][2]byte{1: {1, 0}, 2: {2, 1}, 3: {0x33, 2}, 4: {0x44, 0x33}})
+
+	forks, err := FindReorg(context.Background(), indexed, node, writtenBlock(5, 5, 4), 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]dbTypes.HeightRange{{Start: 3, End: 4}}, forks)
+
+	// Deeper forks are only rolled back as far as the maximum depth
+	forks, err = FindReorg(context.Background(), indexed, node, writtenBlock(5, 5, 4), 1)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]dbTypes.HeightRange{{Start: 4, End: 4}}, forks)
+}
+
+func (suite *ReorgTestSuite) TestAbandonedBlocksAbove() {
+	// A worker fetched heights 3 and 4 of the abandoned fork before height 2 was written
+	node := &nodeClient{hashes: map[int64]byte{1: 1, 2: 2, 3: 3, 4: 4, 5: 5}}
+	indexed := indexedChain(map[int64][2]byte{1: {1, 0}, 3: {0x33, 0x22}, 4: {0x44, 0x33}, 5: {5, 4}})
+
+	forks, err := FindReorg(context.Background(), indexed, node, writtenBlock(2, 2, 1), 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]dbTypes.HeightRange{{Start: 3, End: 4}}, forks)
+}
+
+func (suite *ReorgTestSuite) TestAbandonedBlockWritten() {
+	// The block written was fetched from the abandoned fork, the blocks next to it are on the chain of the node
+	node := &nodeClient{hashes: map[int64]byte{1: 1, 2: 2, 3: 3}}
+	forks, err := FindReorg(context.Background(), indexedChain(map[int64][2]byte{1: {1, 0}, 3: {3, 2}}), node, writtenBlock(2, 0x22, 1), 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]dbTypes.HeightRange{{Start: 2, End: 2}}, forks)
+}
+
+func (suite *ReorgTestSuite) TestNodeUnavailable() {
+	node := &nodeClient{hashes: map[int64]byte{}}
+	_, err := FindReorg(context.Background(), indexedChain(map[int64][2]byte{1: {0x11, 0}}), node, writtenBlock(2, 2, 1), 10)
+	suite.Require().Error(err)
+}
+
+func TestReorgTestSuite(t *testing.T) {
+	suite.Run(t, new(ReorgTestSuite))
+}
@@ -1,14 +1,18 @@
 package core
 
 import (
-	"net/http"
+	"context"
+	"fmt"
+	"runtime/debug"
 	"sync"
 
 	"github.com/DefiantLabs/cosmos-indexer/config"
 	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
 	"github.com/DefiantLabs/cosmos-indexer/rpc"
-	"github.com/DefiantLabs/probe/client"
 	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
 	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
 	"gorm.io/gorm"
 )
@@ -22,24 +26,65 @@ type IndexerBlockEventData struct {
 	TxRequestsFailed         bool
 	IndexBlockEvents         bool
 	IndexTransactions        bool
+	// CommitValidators is the validator set of the height committed by the last commit of the block, fetched when
+	// block commits are indexed and the commit has absent signatures
+	CommitValidators []*cmttypes.Validator
 }
 
 // This function is responsible for making all RPC requests to the chain needed for later processing.
 // The indexer relies on a number of RPC endpoints for full block data, including block event and transaction searches.
-func BlockRPCWorker(wg *sync.WaitGroup, blockEnqueueChan chan *EnqueueData, chainID uint, chainStringID string, cfg *config.IndexConfig, chainClient *client.ChainClient, db *gorm.DB, outputChannel chan IndexerBlockEventData) {
+// The worker stops once ctx is done, the block it is fetching then is dropped without being recorded as failed.
+func BlockRPCWorker(ctx context.Context, wg *sync.WaitGroup, blockEnqueueChan chan *EnqueueData, chainID uint, chainStringID string, cfg *config.IndexConfig, chainClient rpc.Client, db *gorm.DB, outputChannel chan IndexerBlockEventData) {
 	defer wg.Done()
-	rpcClient := rpc.URIClient{
-		Address: chainClient.Config.RPCAddr,
-		Client:  &http.Client{},
+
+	recordPanic := func(height int64, failure models.BlockFailure) error {
+		return dbTypes.UpsertFailedBlock(db, height, chainStringID, cfg.Probe.ChainName, failure)
+	}
+	runBlockRPCWorker(ctx, blockEnqueueChan, chainStringID, cfg, chainClient, db, outputChannel, recordPanic)
+}
+
+// runBlockRPCWorker fetches the enqueued blocks until the channel is closed or ctx is done. The worker starts over after
+// a panic, so one malformed block does not stop the others from being fetched.
+func runBlockRPCWorker(ctx context.Context, blockEnqueueChan chan *EnqueueData, chainStringID string, cfg *config.IndexConfig, chainClient rpc.Client, db *gorm.DB, outputChannel chan IndexerBlockEventData, recordPanic func(height int64, failure models.BlockFailure) error) {
+	for !fetchBlocks(ctx, blockEnqueueChan, chainStringID, cfg, chainClient, db, outputChannel, recordPanic) {
 	}
+}
+
+// fetchBlocks fetches the enqueued blocks until the channel is closed or ctx is done. It returns false after recovering
+// from a panic, the block being fetched is recorded as failed with recordPanic then.
+func fetchBlocks(ctx context.Context, blockEnqueueChan chan *EnqueueData, chainStringID string, cfg *config.IndexConfig, chainClient rpc.Client, db *gorm.DB, outputChannel chan IndexerBlockEventData, recordPanic func(height int64, failure models.BlockFailure) error) (closed bool) {
+	var block *EnqueueData
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		if block == nil {
+			panic(r)
+		}
+
+		config.Log.Errorf("RPC worker panicked fetching block %d: %v\n%s", block.Height, r, debug.Stack())
+		err := fmt.Errorf("panic: %v", r)
+		HandleFailedBlock(block.Height, BlockPanic, err)
+		if err := recordPanic(block.Height, NewBlockFailure(models.ComponentRPCWorker, BlockPanic, err)); err != nil {
+			config.Log.Fatal("Failed to insert failed block", err)
+		}
+	}()
 
 	for {
 		// Get the next block to process
-		block, open := <-blockEnqueueChan
+		var open bool
+		select {
+		case <-ctx.Done():
+			config.Log.Debugf("Indexing interrupted. Exiting RPC worker.")
+			return true
+		case block, open = <-blockEnqueueChan:
+		}
 		if !open {
 			config.Log.Debugf("Block enqueue channel closed. Exiting RPC worker.")
-			break
+			return true
 		}
+		metrics.SetChannelDepth("enqueued", len(blockEnqueueChan))
 
 		currentHeightIndexerData := IndexerBlockEventData{
 			BlockEventRequestsFailed: false,
@@ -49,15 +94,19 @@ func BlockRPCWorker(wg *sync.WaitGroup, blockEnqueueChan chan *EnqueueData, chai
 		}
 
 		// Get the block from the RPC
-		blockData, err := rpc.GetBlock(chainClient, block.Height)
+		blockData, err := rpc.GetBlock(ctx, chainClient, block.Height)
+		if ctx.Err() != nil {
+			return true
+		}
 		if err != nil {
 			// This is the only response we continue on. If we can't get the block, we can't index anything.
 			config.Log.Errorf("Error getting block %v from RPC. Err: %v", block, err)
-			err := dbTypes.UpsertFailedEventBlock(db, block.Height, chainStringID, cfg.Probe.ChainName)
+			failure := NewBlockFailure(models.ComponentRPCWorker, BlockQueryError, err)
+			err := dbTypes.UpsertFailedEventBlock(db, block.Height, chainStringID, cfg.Probe.ChainName, failure)
 			if err != nil {
 				config.Log.Fatal("Failed to insert failed block event", err)
 			}
-			err = dbTypes.UpsertFailedBlock(db, block.Height, chainStringID, cfg.Probe.ChainName)
+			err = dbTypes.UpsertFailedBlock(db, block.Height, chainStringID, cfg.Probe.ChainName, failure)
 			if err != nil {
 				config.Log.Fatal("Failed to insert failed block", err)
 			}
@@ -66,12 +115,29 @@ func BlockRPCWorker(wg *sync.WaitGroup, blockEnqueueChan chan *EnqueueData, chai
 
 		currentHeightIndexerData.BlockData = blockData
 
+		if cfg.Flags.IndexBlockCommits && hasAbsentSignatures(blockData.Block.LastCommit) {
+			commitHeight := blockData.Block.LastCommit.Height
+			validators, err := rpc.GetValidators(ctx, chainClient, commitHeight)
+			if ctx.Err() != nil {
+				return true
+			}
+			// The commit is indexed without the addresses of the absent validators rather than failing the block
+			if err != nil {
+				config.Log.Warnf("Error getting the validators of height %d for the commit of block %d. Err: %v", commitHeight, block.Height, err)
+			} else {
+				currentHeightIndexerData.CommitValidators = validators
+			}
+		}
+
 		if block.IndexBlockEvents {
-			bresults, err := rpc.GetBlockResultWithRetry(rpcClient, block.Height, cfg.Base.RequestRetryAttempts, cfg.Base.RequestRetryMaxWait)
+			bresults, err := rpc.GetBlockResultWithRetry(ctx, chainClient, block.Height, cfg.Base.RequestRetryAttempts, cfg.Base.RequestRetryMaxWait)
+			if ctx.Err() != nil {
+				return true
+			}
 
 			if err != nil {
 				config.Log.Errorf("Error getting block results for block %v from RPC. Err: %v", block, err)
-				err := dbTypes.UpsertFailedEventBlock(db, block.Height, chainStringID, cfg.Probe.ChainName)
+				err := dbTypes.UpsertFailedEventBlock(db, block.Height, chainStringID, cfg.Probe.ChainName, NewBlockFailure(models.ComponentRPCWorker, BlockQueryError, err))
 				if err != nil {
 					config.Log.Fatal("Failed to insert failed block event", err)
 				}
@@ -83,17 +149,23 @@ func BlockRPCWorker(wg *sync.WaitGroup, blockEnqueueChan chan *EnqueueData, chai
 		}
 
 		if block.IndexTransactions {
-			txsEventResp, err := rpc.GetTxsByBlockHeight(chainClient, block.Height)
+			txsEventResp, err := getTxs(ctx, cfg, chainClient, blockData, block.Height)
+			if ctx.Err() != nil {
+				return true
+			}
 
 			if err != nil {
 				// Attempt to get block results to attempt an in-app codec decode of transactions.
 				if currentHeightIndexerData.BlockResultsData == nil {
 
-					bresults, err := rpc.GetBlockResultWithRetry(rpcClient, block.Height, cfg.Base.RequestRetryAttempts, cfg.Base.RequestRetryMaxWait)
+					bresults, err := rpc.GetBlockResultWithRetry(ctx, chainClient, block.Height, cfg.Base.RequestRetryAttempts, cfg.Base.RequestRetryMaxWait)
+					if ctx.Err() != nil {
+						return true
+					}
 
 					if err != nil {
 						config.Log.Errorf("Error getting txs for block %v from RPC. Err: %v", block, err)
-						err := dbTypes.UpsertFailedBlock(db, block.Height, chainStringID, cfg.Probe.ChainName)
+						err := dbTypes.UpsertFailedBlock(db, block.Height, chainStringID, cfg.Probe.ChainName, NewBlockFailure(models.ComponentRPCWorker, BlockQueryError, err))
 						if err != nil {
 							config.Log.Fatal("Failed to insert failed block", err)
 						}
@@ -111,6 +183,29 @@ func BlockRPCWorker(wg *sync.WaitGroup, blockEnqueueChan chan *EnqueueData, chai
 			}
 		}
 
-		outputChannel <- currentHeightIndexerData
+		select {
+		case outputChannel <- currentHeightIndexerData:
+		case <-ctx.Done():
+			return true
+		}
+	}
+}
+
+// getTxs gets the transactions of the block in pages of 100, or in pages of base.oversized-page-size when the block is
+// oversized. A block below the thresholds whose pages fail, e.g. because its transactions carry huge events, is fetched
+// in the smaller pages once more before the request is given up.
+func getTxs(ctx context.Context, cfg *config.IndexConfig, chainClient rpc.Client, blockData *ctypes.ResultBlock, height int64) (*txTypes.GetTxsEventResponse, error) {
+	pageSize := cfg.Base.OversizedPageSize
+	if rpc.OversizedBlock(blockData, cfg.Base.OversizedBlockTxs, cfg.Base.OversizedBlockBytes) {
+		config.Log.Infof("Block %d is oversized with %d transactions, getting them %d at a time", height, len(blockData.Block.Data.Txs), pageSize)
+		return rpc.GetTxsByBlockHeightChunked(ctx, chainClient, height, pageSize)
 	}
+
+	resp, err := rpc.GetTxsByBlockHeight(ctx, chainClient, height)
+	if err == nil || ctx.Err() != nil || pageSize == 0 || blockData.Block == nil || uint64(len(blockData.Block.Data.Txs)) <= pageSize {
+		return resp, err
+	}
+
+	config.Log.Warnf("Error getting txs for block %d from RPC, getting them %d at a time. Err: %v", height, pageSize, err)
+	return rpc.GetTxsByBlockHeightChunked(ctx, chainClient, height, pageSize)
 }
@@ -0,0 +1,75 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/stretchr/testify/suite"
+)
+
+// panickingClient serves the blocks of node and panics on the block at panicHeight, like a client choking on a
+// malformed response
+type panickingClient struct {
+	nodeClient
+	panicHeight int64
+}
+
+func (c *panickingClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	if height == c.panicHeight {
+		panic("malformed block")
+	}
+	return c.nodeClient.Block(ctx, height)
+}
+
+type RPCWorkerTestSuite struct {
+	suite.Suite
+}
+
+func (suite *RPCWorkerTestSuite) TestPanicRecordsBlockAndKeepsFetching() {
+	cl := &panickingClient{nodeClient: nodeClient{hashes: map[int64]byte{1: 1, 2: 2, 3: 3}}, panicHeight: 2}
+	enqueued := make(chan *EnqueueData, 3)
+	for height := int64(1); height <= 3; height++ {
+		enqueued <- &EnqueueData{Height: height}
+	}
+	close(enqueued)
+
+	failures := map[int64]models.BlockFailure{}
+	recordPanic := func(height int64, failure models.BlockFailure) error {
+		failures[height] = failure
+		return nil
+	}
+	output := make(chan IndexerBlockEventData, 3)
+	done := make(chan struct{})
+	go func() {
+		runBlockRPCWorker(context.Background(), enqueued, "testchain-1", &config.IndexConfig{}, cl, nil, output, recordPanic)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		suite.FailNow("the RPC worker did not finish the enqueued blocks")
+	}
+
+	// The block the client panicked on is recorded as failed
+	suite.Require().Len(failures, 1)
+	suite.Require().Equal(models.ComponentRPCWorker, failures[2].Component)
+	suite.Require().Equal(models.FailurePanic, failures[2].ErrorCode)
+	suite.Require().Contains(failures[2].ErrorMessage, "malformed block")
+
+	// and the worker went on with the next block
+	close(output)
+	var fetched []int64
+	for blockData := range output {
+		fetched = append(fetched, blockData.BlockData.Block.Height)
+	}
+	suite.Require().Equal([]int64{1, 3}, fetched)
+}
+
+func TestRPCWorkerTestSuite(t *testing.T) {
+	suite.Run(t, new(RPCWorkerTestSuite))
+}
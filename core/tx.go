@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/big"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 	"unsafe"
@@ -14,10 +15,14 @@ import (
 	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
 	"github.com/DefiantLabs/cosmos-indexer/db/models"
 	"github.com/DefiantLabs/cosmos-indexer/filter"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
 	"github.com/DefiantLabs/cosmos-indexer/parsers"
 	"github.com/DefiantLabs/cosmos-indexer/util"
-	"github.com/DefiantLabs/probe/client"
+	probeClient "github.com/DefiantLabs/probe/client"
+	abci "github.com/cometbft/cometbft/abci/types"
 	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
 	"github.com/cosmos/cosmos-sdk/crypto/keys/multisig"
 	cryptoTypes "github.com/cosmos/cosmos-sdk/crypto/types"
 	"github.com/cosmos/cosmos-sdk/types"
@@ -44,149 +49,242 @@ func toEvents(msgEvents types.StringEvents) (list []txtypes.LogMessageEvent) {
 	return list
 }
 
+// messageLogsFromEvents rebuilds the message logs of a successful transaction from its events. Nodes of Cosmos SDK
+// v0.50+ no longer return message logs, the events of each message carry its index in a msg_index attribute instead.
+// Events without it, such as the fee and signature events of the transaction, belong to no message, as in the logs.
+func messageLogsFromEvents(events []abci.Event, messages int) types.ABCIMessageLogs {
+	logs := make(types.ABCIMessageLogs, messages)
+	for i := range logs {
+		logs[i].MsgIndex = uint32(i)
+	}
+
+	for _, event := range events {
+		msgIndex := -1
+		attributes := make([]types.Attribute, 0, len(event.Attributes))
+		for _, attribute := range event.Attributes {
+			if attribute.Key == "msg_index" {
+				if index, err := strconv.Atoi(attribute.Value); err == nil {
+					msgIndex = index
+				}
+				continue
+			}
+			attributes = append(attributes, types.Attribute{Key: attribute.Key, Value: attribute.Value})
+		}
+
+		if msgIndex < 0 || msgIndex >= messages {
+			continue
+		}
+		logs[msgIndex].Events = append(logs[msgIndex].Events, types.StringEvent{Type: event.Type, Attributes: attributes})
+	}
+
+	return logs
+}
+
 func getUnexportedField(field reflect.Value) interface{} {
 	return reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Interface()
 }
 
-func ProcessRPCBlockByHeightTXs(cfg *config.IndexConfig, db *gorm.DB, cl *client.ChainClient, messageTypeFilters []filter.MessageTypeFilter, blockResults *coretypes.ResultBlock, resultBlockRes *coretypes.ResultBlockResults, customParsers map[string][]parsers.MessageParser) ([]dbTypes.TxDBWrapper, *time.Time, error) {
+func ProcessRPCBlockByHeightTXs(cfg *config.IndexConfig, db *gorm.DB, codec probeClient.Codec, messageTypeFilters []filter.MessageTypeFilter, blockResults *coretypes.ResultBlock, resultBlockRes *coretypes.ResultBlockResults, customParsers map[string][]parsers.MessageParser, blockContext parsers.BlockContext) ([]dbTypes.TxDBWrapper, []models.FailedTx, *time.Time, error) {
 	if len(blockResults.Block.Txs) != len(resultBlockRes.TxsResults) {
 		config.Log.Fatalf("blockResults & resultBlockRes: different length")
 	}
 
 	blockTime := &blockResults.Block.Time
-	blockTimeStr := blockTime.Format(time.RFC3339)
-	currTxDbWrappers := make([]dbTypes.TxDBWrapper, len(blockResults.Block.Txs))
+	currTxDbWrappers := make([]dbTypes.TxDBWrapper, 0, len(blockResults.Block.Txs))
+	var failedTxs []models.FailedTx
 
 	for txIdx, tendermintTx := range blockResults.Block.Txs {
-		txResult := resultBlockRes.TxsResults[txIdx]
+		processedTx, err := processBlockResultsTx(cfg, db, codec, messageTypeFilters, blockResults, tendermintTx, resultBlockRes.TxsResults[txIdx], customParsers, blockContext)
+		if err != nil {
+			failedTxs = append(failedTxs, newFailedTx(tendermintHashToHex(tendermintTx.Hash()), tendermintTx, err))
+			continue
+		}
 
-		// Indexer types only used by the indexer app (similar to the cosmos types)
-		var indexerMergedTx txtypes.MergedTx
-		var indexerTx txtypes.IndexerTx
-		var txBody txtypes.Body
-		var currMessages []types.Msg
-		var currLogMsgs []txtypes.LogMessage
+		currTxDbWrappers = append(currTxDbWrappers, processedTx)
+	}
 
-		txDecoder := cl.Codec.TxConfig.TxDecoder()
+	return currTxDbWrappers, failedTxs, blockTime, nil
+}
 
-		txBasic, err := txDecoder(tendermintTx)
-		var txFull *cosmosTx.Tx
-		if err != nil {
-			txBasic, err = InAppTxDecoder(cl.Codec)(tendermintTx)
-			if err != nil {
-				return nil, blockTime, fmt.Errorf("ProcessRPCBlockByHeightTXs: TX cannot be parsed from block %v. This is usually a proto definition error. Err: %v", blockResults.Block.Height, err)
-			}
-			txFull = txBasic.(*cosmosTx.Tx)
-		} else {
-			// This is a hack, but as far as I can tell necessary. "wrapper" struct is private in Cosmos SDK.
-			field := reflect.ValueOf(txBasic).Elem().FieldByName("tx")
-			iTx := getUnexportedField(field)
-			txFull = iTx.(*cosmosTx.Tx)
-		}
+// processBlockResultsTx processes a transaction of the block, decoding it in the app when the codec cannot
+func processBlockResultsTx(cfg *config.IndexConfig, db *gorm.DB, codec probeClient.Codec, messageTypeFilters []filter.MessageTypeFilter, blockResults *coretypes.ResultBlock, tendermintTx cmttypes.Tx, txResult *abci.ResponseDeliverTx, customParsers map[string][]parsers.MessageParser, blockContext parsers.BlockContext) (processedTx dbTypes.TxDBWrapper, err error) {
+	blockTimeStr := blockResults.Block.Time.Format(time.RFC3339)
 
-		logs := types.ABCIMessageLogs{}
+	// Indexer types only used by the indexer app (similar to the cosmos types)
+	var indexerMergedTx txtypes.MergedTx
+	var indexerTx txtypes.IndexerTx
+	var txBody txtypes.Body
+	var currMessages []types.Msg
+	var currLogMsgs []txtypes.LogMessage
+	var failedMessages []models.FailedMessage
 
-		// Failed TXs do not have proper JSON in the .Log field, causing ParseABCILogs to fail to unmarshal the logs
-		// We can entirely ignore failed TXs in downstream parsers, because according to the Cosmos specification, a single failed message in a TX fails the whole TX
-		if txResult.Code == 0 {
-			logs, err = types.ParseABCILogs(txResult.Log)
-		} else {
-			err = nil
-		}
+	txDecoder := codec.TxConfig.TxDecoder()
 
+	txBasic, err := txDecoder(tendermintTx)
+	var txFull *cosmosTx.Tx
+	if err != nil {
+		txBasic, err = InAppTxDecoder(codec)(tendermintTx)
 		if err != nil {
-			return nil, blockTime, fmt.Errorf("logs could not be parsed")
+			return processedTx, fmt.Errorf("ProcessRPCBlockByHeightTXs: TX cannot be parsed from block %v. This is usually a proto definition error. Err: %v", blockResults.Block.Height, err)
 		}
+		txFull = txBasic.(*cosmosTx.Tx)
+	} else {
+		// This is a hack, but as far as I can tell necessary. "wrapper" struct is private in Cosmos SDK.
+		field := reflect.ValueOf(txBasic).Elem().FieldByName("tx")
+		iTx := getUnexportedField(field)
+		txFull = iTx.(*cosmosTx.Tx)
+	}
 
-		txHash := tendermintTx.Hash()
-
-		var messagesRaw [][]byte
+	logs := types.ABCIMessageLogs{}
 
-		// Get the Messages and Message Logs
-		for msgIdx := range txFull.Body.Messages {
+	// Failed TXs do not have proper JSON in the .Log field, causing ParseABCILogs to fail to unmarshal the logs
+	// We can entirely ignore failed TXs in downstream parsers, because according to the Cosmos specification, a single failed message in a TX fails the whole TX
+	if txResult.Code == 0 && txResult.Log == "" {
+		logs = messageLogsFromEvents(txResult.Events, len(txFull.Body.Messages))
+	} else if txResult.Code == 0 {
+		logs, err = types.ParseABCILogs(txResult.Log)
+	} else {
+		err = nil
+	}
 
-			shouldIndex, err := messageTypeShouldIndex(txFull.Body.Messages[msgIdx].TypeUrl, messageTypeFilters, customParsers)
-			if err != nil {
-				return nil, blockTime, err
-			}
+	if err != nil {
+		return processedTx, fmt.Errorf("logs could not be parsed")
+	}
 
-			if !shouldIndex {
-				config.Log.Debug(fmt.Sprintf("[Block: %v] [TX: %v] Skipping msg of type '%v'.", blockResults.Block.Height, tendermintHashToHex(txHash), txFull.Body.Messages[msgIdx].TypeUrl))
-				currMessages = append(currMessages, nil)
-				currLogMsgs = append(currLogMsgs, txtypes.LogMessage{
-					MessageIndex: msgIdx,
-				})
-				messagesRaw = append(messagesRaw, nil)
-				continue
-			}
+	txHash := tendermintTx.Hash()
 
-			currMsg := txFull.Body.Messages[msgIdx].GetCachedValue()
+	var messagesRaw [][]byte
 
-			if currMsg != nil {
-				msg := currMsg.(types.Msg)
-				messagesRaw = append(messagesRaw, txFull.Body.Messages[msgIdx].Value)
-				currMessages = append(currMessages, msg)
-				msgEvents := types.StringEvents{}
-				if txResult.Code == 0 {
-					msgEvents = logs[msgIdx].Events
-				}
+	// Get the Messages and Message Logs
+	for msgIdx := range txFull.Body.Messages {
 
-				currTxLog := txtypes.LogMessage{
-					MessageIndex: msgIdx,
-					Events:       toEvents(msgEvents),
-				}
-				currLogMsgs = append(currLogMsgs, currTxLog)
-			} else {
-				return nil, blockTime, fmt.Errorf("tx message could not be processed")
-			}
+		shouldIndex, err := messageTypeShouldIndex(txFull.Body.Messages[msgIdx].TypeUrl, messageTypeFilters, customParsers)
+		if err != nil {
+			return processedTx, err
 		}
 
-		txBody.Messages = currMessages
-		indexerTx.Body = txBody
-		indexerTxResp := txtypes.Response{
-			TxHash:    tendermintHashToHex(txHash),
-			Height:    fmt.Sprintf("%d", blockResults.Block.Height),
-			TimeStamp: blockTimeStr,
-			RawLog:    txResult.Log,
-			Log:       currLogMsgs,
-			Code:      txResult.Code,
+		if !shouldIndex {
+			config.Log.Debug(fmt.Sprintf("[Block: %v] [TX: %v] Skipping msg of type '%v'.", blockResults.Block.Height, tendermintHashToHex(txHash), txFull.Body.Messages[msgIdx].TypeUrl))
+			currMessages = append(currMessages, nil)
+			currLogMsgs = append(currLogMsgs, txtypes.LogMessage{
+				MessageIndex: msgIdx,
+			})
+			messagesRaw = append(messagesRaw, nil)
+			continue
 		}
 
-		indexerTx.AuthInfo = *txFull.AuthInfo
-		indexerMergedTx.TxResponse = indexerTxResp
-		indexerMergedTx.Tx = indexerTx
-		indexerMergedTx.Tx.AuthInfo = *txFull.AuthInfo
+		currMsg := txFull.Body.Messages[msgIdx].GetCachedValue()
 
-		processedTx, _, err := ProcessTx(cfg, db, indexerMergedTx, messagesRaw, customParsers)
-		if err != nil {
-			return currTxDbWrappers, blockTime, err
-		}
+		if currMsg != nil {
+			msg := currMsg.(types.Msg)
+			messagesRaw = append(messagesRaw, txFull.Body.Messages[msgIdx].Value)
+			currMessages = append(currMessages, msg)
+			msgEvents := types.StringEvents{}
+			if txResult.Code == 0 {
+				msgEvents = logs[msgIdx].Events
+			}
 
-		filteredSigners := []types.AccAddress{}
-		for _, filteredMessage := range txBody.Messages {
-			if filteredMessage != nil {
-				filteredSigners = append(filteredSigners, filteredMessage.GetSigners()...)
+			currTxLog := txtypes.LogMessage{
+				MessageIndex: msgIdx,
+				Events:       toEvents(msgEvents),
 			}
+			currLogMsgs = append(currLogMsgs, currTxLog)
+		} else {
+			err := fmt.Errorf("message %d of tx %s could not be decoded: %w", msgIdx, tendermintHashToHex(txHash), ErrUnknownMsgType)
+			failedMessages = append(failedMessages, newFailedMessage(msgIdx, txFull.Body.Messages[msgIdx], err))
+			currMessages = append(currMessages, nil)
+			currLogMsgs = append(currLogMsgs, txtypes.LogMessage{
+				MessageIndex: msgIdx,
+			})
+			messagesRaw = append(messagesRaw, nil)
 		}
+	}
 
-		signers, err := ProcessSigners(cl, txFull.AuthInfo, filteredSigners)
-		if err != nil {
-			return currTxDbWrappers, blockTime, err
-		}
+	txBody.Messages = currMessages
+	txBody.Memo = txFull.Body.Memo
+	indexerTx.Body = txBody
+	indexerTxResp := txtypes.Response{
+		TxHash:    tendermintHashToHex(txHash),
+		Height:    fmt.Sprintf("%d", blockResults.Block.Height),
+		TimeStamp: blockTimeStr,
+		RawLog:    txResult.Log,
+		Log:       currLogMsgs,
+		Code:      txResult.Code,
+	}
 
-		processedTx.Tx.SignerAddresses = signers
+	indexerTx.AuthInfo = *txFull.AuthInfo
+	indexerMergedTx.TxResponse = indexerTxResp
+	indexerMergedTx.Tx = indexerTx
+	indexerMergedTx.Tx.AuthInfo = *txFull.AuthInfo
 
-		fees, err := ProcessFees(db, indexerTx.AuthInfo, signers)
-		if err != nil {
-			return currTxDbWrappers, blockTime, err
+	processedTx, _, err = ProcessTx(cfg, db, indexerMergedTx, messagesRaw, customParsers, blockContext)
+	if err != nil {
+		return processedTx, err
+	}
+	addPlaceholderMessages(&processedTx, failedMessages)
+
+	filteredSigners := []types.AccAddress{}
+	for _, filteredMessage := range txBody.Messages {
+		if filteredMessage != nil {
+			filteredSigners = append(filteredSigners, filteredMessage.GetSigners()...)
 		}
+	}
+
+	signers, err := ProcessSigners(codec, txFull.AuthInfo, filteredSigners)
+	if err != nil {
+		return processedTx, err
+	}
 
-		processedTx.Tx.Fees = fees
+	processedTx.Tx.SignerAddresses = signers
 
-		currTxDbWrappers[txIdx] = processedTx
+	fees, err := ProcessFees(db, indexerTx.AuthInfo, signers)
+	if err != nil {
+		return processedTx, err
 	}
 
-	return currTxDbWrappers, blockTime, nil
+	processedTx.Tx.Fees = fees
+
+	return processedTx, nil
+}
+
+// newFailedMessage records a message that could not be decoded, the rest of its tx is indexed
+func newFailedMessage(msgIdx int, message *codectypes.Any, err error) models.FailedMessage {
+	config.Log.Warnf("Indexing message %d of type %s as a placeholder: %v", msgIdx, message.TypeUrl, err)
+	return models.FailedMessage{
+		MessageIndex: msgIdx,
+		MessageType:  message.TypeUrl,
+		ErrorCode:    failureCode(UnprocessableTxError, err),
+		ErrorMessage: err.Error(),
+		Raw:          message.Value,
+	}
+}
+
+// addPlaceholderMessages indexes the messages that could not be decoded as messages of their type without events. Like
+// the decoded messages, the placeholders are only indexed for successful txes.
+func addPlaceholderMessages(processedTx *dbTypes.TxDBWrapper, failedMessages []models.FailedMessage) {
+	processedTx.FailedMessages = failedMessages
+	if processedTx.Tx.Code != 0 {
+		return
+	}
+
+	for _, failedMessage := range failedMessages {
+		messageType := models.MessageType{MessageType: failedMessage.MessageType}
+		processedTx.UniqueMessageTypes[failedMessage.MessageType] = messageType
+		processedTx.Messages = append(processedTx.Messages, dbTypes.MessageDBWrapper{
+			Message: models.Message{MessageIndex: failedMessage.MessageIndex, MessageType: messageType, MessageBytes: failedMessage.Raw},
+		})
+	}
+}
+
+// newFailedTx records the processing failure of a transaction, the other transactions of its block are indexed
+func newFailedTx(hash string, raw []byte, err error) models.FailedTx {
+	config.Log.Errorf("Failed to process tx %s, recording it as failed: %v", hash, err)
+	return models.FailedTx{
+		Hash:         hash,
+		ErrorCode:    failureCode(UnprocessableTxError, err),
+		ErrorMessage: err.Error(),
+		Raw:          raw,
+	}
 }
 
 func tendermintHashToHex(hash []byte) string {
@@ -194,128 +292,159 @@ func tendermintHashToHex(hash []byte) string {
 }
 
 // ProcessRPCTXs - Given an RPC response, build out the more specific data used by the parser.
-func ProcessRPCTXs(cfg *config.IndexConfig, db *gorm.DB, cl *client.ChainClient, messageTypeFilters []filter.MessageTypeFilter, txEventResp *cosmosTx.GetTxsEventResponse, customParsers map[string][]parsers.MessageParser) ([]dbTypes.TxDBWrapper, *time.Time, error) {
-	currTxDbWrappers := make([]dbTypes.TxDBWrapper, len(txEventResp.Txs))
+// Transactions that cannot be processed are returned as failed, the other transactions of the block are processed.
+func ProcessRPCTXs(cfg *config.IndexConfig, db *gorm.DB, codec probeClient.Codec, messageTypeFilters []filter.MessageTypeFilter, txEventResp *cosmosTx.GetTxsEventResponse, customParsers map[string][]parsers.MessageParser, blockContext parsers.BlockContext) ([]dbTypes.TxDBWrapper, []models.FailedTx, *time.Time, error) {
+	currTxDbWrappers := make([]dbTypes.TxDBWrapper, 0, len(txEventResp.Txs))
+	var failedTxs []models.FailedTx
 	var blockTime *time.Time
 
-	for txIdx := range txEventResp.Txs {
-		// Indexer types only used by the indexer app (similar to the cosmos types)
-		var indexerMergedTx txtypes.MergedTx
-		var indexerTx txtypes.IndexerTx
-		var txBody txtypes.Body
-		var currMessages []types.Msg
-		var currLogMsgs []txtypes.LogMessage
-		var messagesRaw [][]byte
-
-		currTx := txEventResp.Txs[txIdx]
+	for txIdx, currTx := range txEventResp.Txs {
 		currTxResp := txEventResp.TxResponses[txIdx]
+		processedTx, txTime, err := processRPCTx(cfg, db, codec, messageTypeFilters, currTx, currTxResp, customParsers, blockContext)
+		if err != nil {
+			raw, _ := currTx.Marshal()
+			failedTxs = append(failedTxs, newFailedTx(currTxResp.TxHash, raw, err))
+			continue
+		}
 
-		// Get the Messages and Message Logs
-		for msgIdx := range currTx.Body.Messages {
+		if blockTime == nil {
+			blockTime = &txTime
+		}
 
-			shouldIndex, err := messageTypeShouldIndex(currTx.Body.Messages[msgIdx].TypeUrl, messageTypeFilters, customParsers)
-			if err != nil {
-				return nil, blockTime, err
-			}
+		currTxDbWrappers = append(currTxDbWrappers, processedTx)
+	}
+
+	return currTxDbWrappers, failedTxs, blockTime, nil
+}
+
+// processRPCTx processes a transaction of a TX search response, unpacking its messages individually when the response
+// did not
+func processRPCTx(cfg *config.IndexConfig, db *gorm.DB, codec probeClient.Codec, messageTypeFilters []filter.MessageTypeFilter, currTx *cosmosTx.Tx, currTxResp *types.TxResponse, customParsers map[string][]parsers.MessageParser, blockContext parsers.BlockContext) (processedTx dbTypes.TxDBWrapper, txTime time.Time, err error) {
+	// Indexer types only used by the indexer app (similar to the cosmos types)
+	var indexerMergedTx txtypes.MergedTx
+	var indexerTx txtypes.IndexerTx
+	var txBody txtypes.Body
+	var currMessages []types.Msg
+	var currLogMsgs []txtypes.LogMessage
+	var messagesRaw [][]byte
+	var failedMessages []models.FailedMessage
+
+	txLogs := currTxResp.Logs
+	if len(txLogs) == 0 && currTxResp.Code == 0 {
+		txLogs = messageLogsFromEvents(currTxResp.Events, len(currTx.Body.Messages))
+	}
+
+	// Get the Messages and Message Logs
+	for msgIdx := range currTx.Body.Messages {
+
+		shouldIndex, err := messageTypeShouldIndex(currTx.Body.Messages[msgIdx].TypeUrl, messageTypeFilters, customParsers)
+		if err != nil {
+			return processedTx, txTime, err
+		}
 
-			if !shouldIndex {
-				config.Log.Debug(fmt.Sprintf("[Block: %v] [TX: %v] Skipping msg of type '%v'.", currTxResp.Height, currTxResp.TxHash, currTx.Body.Messages[msgIdx].TypeUrl))
+		if !shouldIndex {
+			config.Log.Debug(fmt.Sprintf("[Block: %v] [TX: %v] Skipping msg of type '%v'.", currTxResp.Height, currTxResp.TxHash, currTx.Body.Messages[msgIdx].TypeUrl))
+			currMessages = append(currMessages, nil)
+			currLogMsgs = append(currLogMsgs, txtypes.LogMessage{
+				MessageIndex: msgIdx,
+			})
+			messagesRaw = append(messagesRaw, nil)
+			continue
+		}
+
+		currMsg := currTx.Body.Messages[msgIdx].GetCachedValue()
+		messagesRaw = append(messagesRaw, currTx.Body.Messages[msgIdx].Value)
+
+		// If we reached here, unpacking the entire TX raw was not successful
+		// Attempt to unpack the message individually.
+		if currMsg == nil {
+			var currMsgUnpack types.Msg
+			err := codec.InterfaceRegistry.UnpackAny(currTx.Body.Messages[msgIdx], &currMsgUnpack)
+			if err != nil || currMsgUnpack == nil {
+				// The rest of the tx is indexed, the message is indexed as a placeholder and recorded as failed
+				err = fmt.Errorf("tx message could not be processed. Unpacking protos failed and CachedValue is not present. TX Hash: %s, Msg type: %s, Msg index: %d, Code: %d: %w: %v",
+					currTxResp.TxHash,
+					currTx.Body.Messages[msgIdx].TypeUrl,
+					msgIdx,
+					currTxResp.Code,
+					ErrUnknownMsgType,
+					err,
+				)
+				failedMessages = append(failedMessages, newFailedMessage(msgIdx, currTx.Body.Messages[msgIdx], err))
 				currMessages = append(currMessages, nil)
 				currLogMsgs = append(currLogMsgs, txtypes.LogMessage{
 					MessageIndex: msgIdx,
 				})
-				messagesRaw = append(messagesRaw, nil)
+				messagesRaw[msgIdx] = nil
 				continue
 			}
+			currMsg = currMsgUnpack
+		}
 
-			currMsg := currTx.Body.Messages[msgIdx].GetCachedValue()
-			messagesRaw = append(messagesRaw, currTx.Body.Messages[msgIdx].Value)
-
-			// If we reached here, unpacking the entire TX raw was not successful
-			// Attempt to unpack the message individually.
-			if currMsg == nil {
-				var currMsgUnpack types.Msg
-				err := cl.Codec.InterfaceRegistry.UnpackAny(currTx.Body.Messages[msgIdx], &currMsgUnpack)
-				if err != nil || currMsgUnpack == nil {
-					return nil, blockTime, fmt.Errorf("tx message could not be processed. Unpacking protos failed and CachedValue is not present. TX Hash: %s, Msg type: %s, Msg index: %d, Code: %d",
-						currTxResp.TxHash,
-						currTx.Body.Messages[msgIdx].TypeUrl,
-						msgIdx,
-						currTxResp.Code,
-					)
-				}
-				currMsg = currMsgUnpack
-			}
-
-			if currMsg != nil {
-				msg := currMsg.(types.Msg)
-				currMessages = append(currMessages, msg)
-				if len(currTxResp.Logs) >= msgIdx+1 {
-					msgEvents := currTxResp.Logs[msgIdx].Events
-					currTxLog := txtypes.LogMessage{
-						MessageIndex: msgIdx,
-						Events:       toEvents(msgEvents),
-					}
-					currLogMsgs = append(currLogMsgs, currTxLog)
+		if currMsg != nil {
+			msg := currMsg.(types.Msg)
+			currMessages = append(currMessages, msg)
+			if len(txLogs) >= msgIdx+1 {
+				msgEvents := txLogs[msgIdx].Events
+				currTxLog := txtypes.LogMessage{
+					MessageIndex: msgIdx,
+					Events:       toEvents(msgEvents),
 				}
+				currLogMsgs = append(currLogMsgs, currTxLog)
 			}
 		}
+	}
 
-		txBody.Messages = currMessages
-		indexerTx.Body = txBody
-
-		indexerTxResp := txtypes.Response{
-			TxHash:    currTxResp.TxHash,
-			Height:    fmt.Sprintf("%d", currTxResp.Height),
-			TimeStamp: currTxResp.Timestamp,
-			RawLog:    currTxResp.RawLog,
-			Log:       currLogMsgs,
-			Code:      currTxResp.Code,
-		}
-
-		indexerTx.AuthInfo = *currTx.AuthInfo
-		indexerMergedTx.TxResponse = indexerTxResp
-		indexerMergedTx.Tx = indexerTx
-		indexerMergedTx.Tx.AuthInfo = *currTx.AuthInfo
-
-		processedTx, txTime, err := ProcessTx(cfg, db, indexerMergedTx, messagesRaw, customParsers)
-		if err != nil {
-			return currTxDbWrappers, blockTime, err
-		}
+	txBody.Messages = currMessages
+	txBody.Memo = currTx.Body.Memo
+	indexerTx.Body = txBody
+
+	indexerTxResp := txtypes.Response{
+		TxHash:    currTxResp.TxHash,
+		Height:    fmt.Sprintf("%d", currTxResp.Height),
+		TimeStamp: currTxResp.Timestamp,
+		RawLog:    currTxResp.RawLog,
+		Log:       currLogMsgs,
+		Code:      currTxResp.Code,
+	}
 
-		if blockTime == nil {
-			blockTime = &txTime
-		}
+	indexerTx.AuthInfo = *currTx.AuthInfo
+	indexerMergedTx.TxResponse = indexerTxResp
+	indexerMergedTx.Tx = indexerTx
+	indexerMergedTx.Tx.AuthInfo = *currTx.AuthInfo
 
-		filteredSigners := []types.AccAddress{}
-		for _, filteredMessage := range txBody.Messages {
-			if filteredMessage != nil {
-				filteredSigners = append(filteredSigners, filteredMessage.GetSigners()...)
-			}
-		}
-
-		err = currTx.AuthInfo.UnpackInterfaces(cl.Codec.InterfaceRegistry)
-		if err != nil {
-			return currTxDbWrappers, blockTime, err
-		}
+	processedTx, txTime, err = ProcessTx(cfg, db, indexerMergedTx, messagesRaw, customParsers, blockContext)
+	if err != nil {
+		return processedTx, txTime, err
+	}
+	addPlaceholderMessages(&processedTx, failedMessages)
 
-		signers, err := ProcessSigners(cl, currTx.AuthInfo, filteredSigners)
-		if err != nil {
-			return currTxDbWrappers, blockTime, err
+	filteredSigners := []types.AccAddress{}
+	for _, filteredMessage := range txBody.Messages {
+		if filteredMessage != nil {
+			filteredSigners = append(filteredSigners, filteredMessage.GetSigners()...)
 		}
-		processedTx.Tx.SignerAddresses = signers
+	}
 
-		fees, err := ProcessFees(db, indexerTx.AuthInfo, signers)
-		if err != nil {
-			return currTxDbWrappers, blockTime, err
-		}
+	err = currTx.AuthInfo.UnpackInterfaces(codec.InterfaceRegistry)
+	if err != nil {
+		return processedTx, txTime, err
+	}
 
-		processedTx.Tx.Fees = fees
+	signers, err := ProcessSigners(codec, currTx.AuthInfo, filteredSigners)
+	if err != nil {
+		return processedTx, txTime, err
+	}
+	processedTx.Tx.SignerAddresses = signers
 
-		currTxDbWrappers[txIdx] = processedTx
+	fees, err := ProcessFees(db, indexerTx.AuthInfo, signers)
+	if err != nil {
+		return processedTx, txTime, err
 	}
 
-	return currTxDbWrappers, blockTime, nil
+	processedTx.Tx.Fees = fees
+
+	return processedTx, txTime, nil
 }
 
 func messageTypeShouldIndex(messageType string, filters []filter.MessageTypeFilter, customParsers map[string][]parsers.MessageParser) (bool, error) {
@@ -349,7 +478,7 @@ func messageTypeShouldIndex(messageType string, filters []filter.MessageTypeFilt
 	return true, nil
 }
 
-func ProcessTx(cfg *config.IndexConfig, db *gorm.DB, tx txtypes.MergedTx, messagesRaw [][]byte, customParsers map[string][]parsers.MessageParser) (txDBWapper dbTypes.TxDBWrapper, txTime time.Time, err error) {
+func ProcessTx(cfg *config.IndexConfig, db *gorm.DB, tx txtypes.MergedTx, messagesRaw [][]byte, customParsers map[string][]parsers.MessageParser, blockContext parsers.BlockContext) (txDBWapper dbTypes.TxDBWrapper, txTime time.Time, err error) {
 	txTime, err = time.Parse(time.RFC3339, tx.TxResponse.TimeStamp)
 	if err != nil {
 		config.Log.Error("Error parsing tx timestamp.", err)
@@ -376,8 +505,27 @@ func ProcessTx(cfg *config.IndexConfig, db *gorm.DB, tx txtypes.MergedTx, messag
 				if customParsers != nil {
 					if customMessageParsers, ok := customParsers[messageType]; ok {
 						for index, customParser := range customMessageParsers {
+							if !parsers.SupportsModuleVersions(customParser, blockContext.ModuleVersions) {
+								continue
+							}
 							// We deliberately ignore the error here, as we want to continue processing the message even if a custom parser fails
-							parsedData, err := customParser.ParseMessage(message, messageLog, *cfg)
+							parser, parserMessage, parserMessageLog := customParser, message, messageLog
+							parseContext := parsers.MessageParseContext{
+								Config:       *cfg,
+								Block:        blockContext,
+								TxResponse:   tx.TxResponse,
+								MessageIndex: messageIndex,
+								Messages:     tx.Tx.Body.Messages,
+							}
+							start := time.Now()
+							parsedData, err := parsers.RunParseWithTimeout(customParserTimeout(cfg), func() (*any, error) {
+								if contextualParser, ok := parser.(parsers.ContextualMessageParser); ok {
+									return contextualParser.ParseMessageWithContext(parserMessage, parserMessageLog, parseContext)
+								}
+								return parser.ParseMessage(parserMessage, parserMessageLog, *cfg)
+							})
+							metrics.ObserveParserExecution(customParser.Identifier(), metrics.ParserStageParse, time.Since(start), err)
+							logIsolatedParserFailure(customParser.Identifier(), err)
 
 							currMessageDBWrapper.MessageParsedDatasets = append(currMessageDBWrapper.MessageParsedDatasets, parsers.MessageParsedData{
 								Data:   parsedData,
@@ -393,7 +541,7 @@ func ProcessTx(cfg *config.IndexConfig, db *gorm.DB, tx txtypes.MergedTx, messag
 		}
 	}
 
-	txDBWapper.Tx = models.Tx{Hash: tx.TxResponse.TxHash, Code: code}
+	txDBWapper.Tx = models.Tx{Hash: tx.TxResponse.TxHash, Code: code, Memo: tx.Tx.Body.Memo}
 	txDBWapper.Messages = messages
 	txDBWapper.UniqueMessageTypes = uniqueMessageTypes
 	txDBWapper.UniqueMessageAttributeKeys = uniqueEventAttributeKeys
@@ -406,7 +554,7 @@ func ProcessTx(cfg *config.IndexConfig, db *gorm.DB, tx txtypes.MergedTx, messag
 // 1. Processes signers from the auth info
 // 2. Processes signers from the signers array
 // 3. Processes the fee payer
-func ProcessSigners(cl *client.ChainClient, authInfo *cosmosTx.AuthInfo, messageSigners []types.AccAddress) ([]models.Address, error) {
+func ProcessSigners(codec probeClient.Codec, authInfo *cosmosTx.AuthInfo, messageSigners []types.AccAddress) ([]models.Address, error) {
 	// For unique checks
 	signerAddressMap := make(map[string]models.Address)
 	// For deterministic output of signer values
@@ -415,11 +563,11 @@ func ProcessSigners(cl *client.ChainClient, authInfo *cosmosTx.AuthInfo, message
 	// If there is a signer info, get the addresses from the keys add it to the list of signers
 	for _, signerInfo := range authInfo.SignerInfos {
 		if signerInfo.PublicKey != nil {
-			pubKey, err := cl.Codec.InterfaceRegistry.Resolve(signerInfo.PublicKey.TypeUrl)
+			pubKey, err := codec.InterfaceRegistry.Resolve(signerInfo.PublicKey.TypeUrl)
 			if err != nil {
 				return nil, err
 			}
-			err = cl.Codec.InterfaceRegistry.UnpackAny(signerInfo.PublicKey, &pubKey)
+			err = codec.InterfaceRegistry.UnpackAny(signerInfo.PublicKey, &pubKey)
 			if err != nil {
 				return nil, err
 			}
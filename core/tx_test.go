@@ -0,0 +1,117 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/parsers"
+	probeClient "github.com/DefiantLabs/probe/client"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/types"
+	cosmosTx "github.com/cosmos/cosmos-sdk/types/tx"
+	bankTypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/suite"
+)
+
+type TxTestSuite struct {
+	suite.Suite
+	codec probeClient.Codec
+}
+
+func (suite *TxTestSuite) SetupSuite() {
+	suite.codec = probeClient.MakeCodec(probeClient.DefaultModuleBasics)
+}
+
+// transfer returns a bank send packed with its cached value, like the messages of a decoded tx
+func (suite *TxTestSuite) transfer(amount int64) *codectypes.Any {
+	msg := &bankTypes.MsgSend{
+		FromAddress: types.AccAddress([]byte("from_address________")).String(),
+		ToAddress:   types.AccAddress([]byte("to_address__________")).String(),
+		Amount:      types.NewCoins(types.NewInt64Coin("stake", amount)),
+	}
+	packed, err := codectypes.NewAnyWithValue(msg)
+	suite.Require().NoError(err)
+	return packed
+}
+
+// messageLog returns the log of a message with a single event of the type
+func messageLog(msgIndex uint32, eventType string) types.ABCIMessageLog {
+	return types.ABCIMessageLog{
+		MsgIndex: msgIndex,
+		Events:   types.StringEvents{{Type: eventType, Attributes: []types.Attribute{{Key: "amount", Value: "1stake"}}}},
+	}
+}
+
+// txWithUnknownMessage returns a tx whose second message is of a type the codec does not know, between two transfers
+func (suite *TxTestSuite) txWithUnknownMessage(code uint32) (*cosmosTx.Tx, *types.TxResponse) {
+	unknown := &codectypes.Any{TypeUrl: "/unknown.v1.MsgUnknown", Value: []byte{1, 2, 3}}
+	tx := &cosmosTx.Tx{
+		Body:     &cosmosTx.TxBody{Messages: []*codectypes.Any{suite.transfer(1), unknown, suite.transfer(2)}},
+		AuthInfo: &cosmosTx.AuthInfo{Fee: &cosmosTx.Fee{}},
+	}
+	resp := &types.TxResponse{
+		Height:    10,
+		TxHash:    "ABCD",
+		Code:      code,
+		Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Format(time.RFC3339),
+	}
+	if code == 0 {
+		resp.Logs = types.ABCIMessageLogs{messageLog(0, "first"), messageLog(1, "unknown"), messageLog(2, "third")}
+	}
+	return tx, resp
+}
+
+func (suite *TxTestSuite) TestUnknownMessageIndexedAsPlaceholder() {
+	tx, resp := suite.txWithUnknownMessage(0)
+	processedTx, _, err := processRPCTx(&config.IndexConfig{}, nil, suite.codec, nil, tx, resp, nil, parsers.BlockContext{})
+	suite.Require().NoError(err)
+
+	// The message is recorded as failed with its raw bytes
+	suite.Require().Len(processedTx.FailedMessages, 1)
+	failed := processedTx.FailedMessages[0]
+	suite.Require().Equal(1, failed.MessageIndex)
+	suite.Require().Equal("/unknown.v1.MsgUnknown", failed.MessageType)
+	suite.Require().Equal(models.FailureUnknownMsgType, failed.ErrorCode)
+	suite.Require().Equal([]byte{1, 2, 3}, failed.Raw)
+
+	// and indexed as a placeholder without events next to the other messages of the tx
+	suite.Require().Len(processedTx.Messages, 3)
+	messages := map[int]int{}
+	for i, message := range processedTx.Messages {
+		messages[message.Message.MessageIndex] = i
+	}
+	placeholder := processedTx.Messages[messages[1]]
+	suite.Require().Equal("/unknown.v1.MsgUnknown", placeholder.Message.MessageType.MessageType)
+	suite.Require().Equal([]byte{1, 2, 3}, placeholder.Message.MessageBytes)
+	suite.Require().Empty(placeholder.MessageEvents)
+	suite.Require().Contains(processedTx.UniqueMessageTypes, "/unknown.v1.MsgUnknown")
+
+	// The messages after the placeholder keep the events of their own log
+	first := processedTx.Messages[messages[0]]
+	suite.Require().Equal("/cosmos.bank.v1beta1.MsgSend", first.Message.MessageType.MessageType)
+	suite.Require().Len(first.MessageEvents, 1)
+	suite.Require().Equal("first", first.MessageEvents[0].MessageEvent.MessageEventType.Type)
+	third := processedTx.Messages[messages[2]]
+	suite.Require().Len(third.MessageEvents, 1)
+	suite.Require().Equal("third", third.MessageEvents[0].MessageEvent.MessageEventType.Type)
+	suite.Require().NotContains(processedTx.UniqueMessageEventTypes, "unknown")
+}
+
+func (suite *TxTestSuite) TestUnknownMessageOfFailedTx() {
+	tx, resp := suite.txWithUnknownMessage(5)
+	processedTx, _, err := processRPCTx(&config.IndexConfig{}, nil, suite.codec, nil, tx, resp, nil, parsers.BlockContext{})
+	suite.Require().NoError(err)
+
+	// The messages of failed txes are not indexed, the placeholder neither, but the message is still recorded as failed
+	suite.Require().Equal(uint32(5), processedTx.Tx.Code)
+	suite.Require().Empty(processedTx.Messages)
+	suite.Require().Len(processedTx.FailedMessages, 1)
+	suite.Require().Equal(1, processedTx.FailedMessages[0].MessageIndex)
+	suite.Require().Equal(models.FailureUnknownMsgType, processedTx.FailedMessages[0].ErrorCode)
+}
+
+func TestTxTestSuite(t *testing.T) {
+	suite.Run(t, new(TxTestSuite))
+}
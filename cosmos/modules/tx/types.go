@@ -61,6 +61,7 @@ type LogMessageEvent struct {
 
 type Body struct {
 	Messages []sdk.Msg `json:"messages"`
+	Memo     string    `json:"memo"`
 }
 
 type AuthInfo struct {
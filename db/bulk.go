@@ -0,0 +1,427 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+// BulkBlock is a block with its txes and failed txes, written together with other blocks by IndexNewBlocksBulk
+type BulkBlock struct {
+	Block     models.Block
+	Txs       []TxDBWrapper
+	FailedTxs []models.FailedTx
+}
+
+// IndexNewBlocksBulk indexes the blocks and their txes like IndexNewBlock, all of them in a single transaction. The txes,
+// fees, signers, messages, message events and message event attributes of the blocks are each written with one COPY
+// into a temporary table and one upsert from it, instead of an insert per block and tx, which speeds up backfills. The
+// blocks are returned loaded with the indexed data. It requires the pgx Postgres driver.
+func IndexNewBlocksBulk(db *gorm.DB, blocks []BulkBlock, indexerConfig config.IndexConfig, messageParserTrackers map[string]models.MessageParser) ([]BulkBlock, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return blocks, err
+	}
+
+	// The COPY statements run on the connection of the transaction, so the connection is held for the whole write
+	conn, err := sqlDB.Conn(db.Statement.Context)
+	if err != nil {
+		return blocks, err
+	}
+	defer conn.Close()
+
+	pinned := db.Session(&gorm.Session{Context: db.Statement.Context})
+	pinned.Statement.ConnPool = conn
+
+	err = transaction(pinned, func(dbTransaction *gorm.DB) error {
+		return indexBulkBlocks(dbTransaction, conn, blocks, indexerConfig, messageParserTrackers)
+	})
+
+	if err == nil {
+		for _, block := range blocks {
+			dictionaries.storeTxs(block.Block, block.Txs)
+		}
+	}
+
+	return blocks, err
+}
+
+// bulkKey identifies a written row by its parent and its index in it, e.g. a message by its tx and message index
+type bulkKey struct {
+	parentID uint
+	index    uint64
+}
+
+func indexBulkBlocks(db *gorm.DB, conn *sql.Conn, blocks []BulkBlock, indexerConfig config.IndexConfig, messageParserTrackers map[string]models.MessageParser) error {
+	// A tx is indexed once by hash, a hash repeated in the batch is written with its last block like IndexNewBlock does
+	uniqueTxes := make(map[string]*TxDBWrapper)
+	var hashes []string
+	var txs []TxDBWrapper
+	for blockIndex := range blocks {
+		block := &blocks[blockIndex]
+		if err := upsertIndexedBlock(db, &block.Block, block.FailedTxs); err != nil {
+			return err
+		}
+
+		for txIndex := range block.Txs {
+			tx := &block.Txs[txIndex]
+			tx.Tx.BlockID = block.Block.ID
+			tx.Tx.Block = block.Block
+			if _, ok := uniqueTxes[tx.Tx.Hash]; !ok {
+				hashes = append(hashes, tx.Tx.Hash)
+			}
+			uniqueTxes[tx.Tx.Hash] = tx
+		}
+		txs = append(txs, block.Txs...)
+	}
+
+	// The addresses named in event attributes are indexed with the signers and fee payers
+	var involved map[string][]string
+	if indexerConfig.Flags.IndexAddressInvolvements {
+		involved = involvedAddresses(txs, indexerConfig.Probe.AccountPrefix)
+	}
+
+	uniqueAddress, err := indexTxAddresses(db, txs, involved)
+	if err != nil {
+		return err
+	}
+
+	if err := copyTxes(db, conn, hashes, uniqueTxes); err != nil {
+		config.Log.Error("Error copying txes.", err)
+		return err
+	}
+
+	if err := copyTxFeesAndSigners(db, conn, hashes, uniqueTxes); err != nil {
+		config.Log.Error("Error copying tx fees and signers.", err)
+		return err
+	}
+
+	if err := copyMessages(db, conn, hashes, uniqueTxes, txs, indexerConfig); err != nil {
+		config.Log.Error("Error copying messages.", err)
+		return err
+	}
+
+	// Every occurrence of a tx is loaded with the indexed data
+	txs = txs[:0]
+	var txIDs []uint
+	var failedMessages []models.FailedMessage
+	for blockIndex := range blocks {
+		for txIndex := range blocks[blockIndex].Txs {
+			tx := *uniqueTxes[blocks[blockIndex].Txs[txIndex].Tx.Hash]
+			blocks[blockIndex].Txs[txIndex] = tx
+			txs = append(txs, tx)
+			txIDs = append(txIDs, tx.Tx.ID)
+			for _, failedMessage := range tx.FailedMessages {
+				failedMessage.TxID = tx.Tx.ID
+				failedMessages = append(failedMessages, failedMessage)
+			}
+		}
+	}
+
+	if err := indexFailedMessages(db, txIDs, failedMessages); err != nil {
+		config.Log.Error("Error creating failed messages.", err)
+		return err
+	}
+
+	if indexerConfig.Coins.Enabled {
+		if err := indexMessageEventAttributeCoins(db, txs, txIDs, indexerConfig.Coins.AttributeKeys); err != nil {
+			config.Log.Error("Error indexing the coins of the message event attributes.", err)
+			return err
+		}
+	}
+
+	if indexerConfig.Search.Enabled && len(indexerConfig.Search.AttributeKeys) != 0 {
+		if err := indexMessageEventSearch(db, txs, txIDs, indexerConfig.Search.AttributeKeys); err != nil {
+			config.Log.Error("Error indexing the search documents of the message events.", err)
+			return err
+		}
+	}
+
+	if err := indexCustomMessages(db, indexerConfig, txs, messageParserTrackers, true); err != nil {
+		return err
+	}
+
+	for blockIndex := range blocks {
+		block := &blocks[blockIndex]
+
+		if indexerConfig.Flags.IndexAddressInvolvements {
+			blockTxIDs := make([]uint, len(block.Txs))
+			for txIndex, tx := range block.Txs {
+				blockTxIDs[txIndex] = tx.Tx.ID
+			}
+			if err := indexAddressInvolvements(db, block.Block, block.Txs, blockTxIDs, involved, uniqueAddress); err != nil {
+				config.Log.Error("Error indexing the address involvements of the txes.", err)
+				return err
+			}
+		}
+
+		if indexerConfig.Ledger.Enabled {
+			if err := projectTxLedger(db, &block.Block, indexerConfig.Ledger.FeeAccount, indexerConfig.Ledger.Rollups); err != nil {
+				config.Log.Error("Error projecting the ledger entries of the txes.", err)
+				return err
+			}
+		}
+
+		if indexerConfig.Database.TimescaleDB {
+			if err := replaceFeePoints(db, &block.Block); err != nil {
+				config.Log.Error("Error replacing the fee points of the block.", err)
+				return err
+			}
+		}
+
+		if err := emitCDCEvent(db, indexerConfig, newTxsCDCEvent(indexerConfig, block.Block, block.Txs)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyTxes writes the txes and sets their IDs
+func copyTxes(db *gorm.DB, conn *sql.Conn, hashes []string, txes map[string]*TxDBWrapper) error {
+	rows := make([][]any, 0, len(hashes))
+	for _, hash := range hashes {
+		tx := txes[hash].Tx
+		rows = append(rows, []any{tx.Hash, int64(tx.Code), int64(tx.BlockID), tx.Memo})
+	}
+
+	var written []struct {
+		ID   uint
+		Hash string
+	}
+	err := copyUpsert(db, conn, TableName(db, &models.Tx{}), []string{"hash", "code", "block_id", "memo"}, rows,
+		"(hash) DO UPDATE SET code = EXCLUDED.code, block_id = EXCLUDED.block_id, memo = EXCLUDED.memo", "id, hash", &written)
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range written {
+		txes[tx.Hash].Tx.ID = tx.ID
+	}
+	return nil
+}
+
+// copyTxFeesAndSigners writes the fees of the txes, setting their IDs, and links the txes to their signers
+func copyTxFeesAndSigners(db *gorm.DB, conn *sql.Conn, hashes []string, txes map[string]*TxDBWrapper) error {
+	var feeRows, signerRows [][]any
+	for _, hash := range hashes {
+		tx := &txes[hash].Tx
+		for feeIndex := range tx.Fees {
+			fee := &tx.Fees[feeIndex]
+			fee.TxID = tx.ID
+			amount := pgtype.Numeric{Int: fee.Amount.Coefficient(), Exp: fee.Amount.Exponent(), Valid: true}
+			feeRows = append(feeRows, []any{int64(fee.TxID), amount, int64(fee.DenominationID), int64(fee.PayerAddressID)})
+		}
+		for _, signer := range tx.SignerAddresses {
+			signerRows = append(signerRows, []any{int64(tx.ID), int64(signer.ID)})
+		}
+	}
+
+	var written []struct {
+		ID             uint
+		TxID           uint
+		DenominationID uint
+	}
+	err := copyUpsert(db, conn, TableName(db, &models.Fee{}), []string{"tx_id", "amount", "denomination_id", "payer_address_id"}, feeRows,
+		"(tx_id, denomination_id) DO UPDATE SET amount = EXCLUDED.amount", "id, tx_id, denomination_id", &written)
+	if err != nil {
+		return err
+	}
+
+	feeIDs := make(map[bulkKey]uint, len(written))
+	for _, fee := range written {
+		feeIDs[bulkKey{fee.TxID, uint64(fee.DenominationID)}] = fee.ID
+	}
+	for _, hash := range hashes {
+		fees := txes[hash].Tx.Fees
+		for feeIndex := range fees {
+			fees[feeIndex].ID = feeIDs[bulkKey{fees[feeIndex].TxID, uint64(fees[feeIndex].DenominationID)}]
+		}
+	}
+
+	return copyUpsert(db, conn, db.NamingStrategy.JoinTableName("tx_signer_addresses"), []string{"tx_id", "address_id"}, signerRows, "DO NOTHING", "", nil)
+}
+
+// copyMessages writes the messages of the txes with their events and event attributes, and sets their IDs
+func copyMessages(db *gorm.DB, conn *sql.Conn, hashes []string, txes map[string]*TxDBWrapper, allTxs []TxDBWrapper, indexerConfig config.IndexConfig) error {
+	messageTypes, err := indexMessageTypes(db, allTxs)
+	if err != nil {
+		return err
+	}
+
+	eventTypes, err := indexMessageEventTypes(db, allTxs)
+	if err != nil {
+		return err
+	}
+
+	attributeKeys, err := indexMessageEventAttributeKeys(db, allTxs)
+	if err != nil {
+		return err
+	}
+
+	var rows [][]any
+	for _, hash := range hashes {
+		tx := txes[hash]
+		for messageIndex := range tx.Messages {
+			message := &tx.Messages[messageIndex].Message
+			message.TxID = tx.Tx.ID
+			message.Tx = tx.Tx
+			message.MessageType = messageTypes[message.MessageType.MessageType]
+			message.MessageTypeID = message.MessageType.ID
+			if !indexerConfig.Flags.IndexTxMessageRaw {
+				message.MessageBytes = nil
+			}
+
+			var messageBytes any
+			if message.MessageBytes != nil {
+				messageBytes = models.CompressBytes(message.MessageBytes)
+			}
+			rows = append(rows, []any{int64(message.TxID), int64(message.MessageTypeID), int64(message.MessageIndex), messageBytes})
+		}
+	}
+
+	var writtenMessages []struct {
+		ID           uint
+		TxID         uint
+		MessageIndex int
+	}
+	err = copyUpsert(db, conn, TableName(db, &models.Message{}), []string{"tx_id", "message_type_id", "message_index", "message_bytes"}, rows,
+		"(tx_id, message_index) DO UPDATE SET message_type_id = EXCLUDED.message_type_id, message_bytes = EXCLUDED.message_bytes",
+		"id, tx_id, message_index", &writtenMessages)
+	if err != nil {
+		return err
+	}
+
+	messageIDs := make(map[bulkKey]uint, len(writtenMessages))
+	for _, message := range writtenMessages {
+		messageIDs[bulkKey{message.TxID, uint64(message.MessageIndex)}] = message.ID
+	}
+
+	rows = rows[:0]
+	for _, hash := range hashes {
+		tx := txes[hash]
+		for messageIndex := range tx.Messages {
+			message := &tx.Messages[messageIndex]
+			message.Message.ID = messageIDs[bulkKey{message.Message.TxID, uint64(message.Message.MessageIndex)}]
+			for eventIndex := range message.MessageEvents {
+				event := &message.MessageEvents[eventIndex].MessageEvent
+				event.MessageID = message.Message.ID
+				event.Message = message.Message
+				event.MessageEventType = eventTypes[event.MessageEventType.Type]
+				event.MessageEventTypeID = event.MessageEventType.ID
+				rows = append(rows, []any{int64(event.MessageID), int64(event.Index), int64(event.MessageEventTypeID)})
+			}
+		}
+	}
+
+	var writtenEvents []struct {
+		ID        uint
+		MessageID uint
+		Index     uint64
+	}
+	err = copyUpsert(db, conn, TableName(db, &models.MessageEvent{}), []string{"message_id", "index", "message_event_type_id"}, rows,
+		"(message_id, index) DO UPDATE SET message_event_type_id = EXCLUDED.message_event_type_id", "id, message_id, index", &writtenEvents)
+	if err != nil {
+		return err
+	}
+
+	eventIDs := make(map[bulkKey]uint, len(writtenEvents))
+	for _, event := range writtenEvents {
+		eventIDs[bulkKey{event.MessageID, event.Index}] = event.ID
+	}
+
+	rows = rows[:0]
+	for _, hash := range hashes {
+		tx := txes[hash]
+		for messageIndex := range tx.Messages {
+			for eventIndex := range tx.Messages[messageIndex].MessageEvents {
+				event := &tx.Messages[messageIndex].MessageEvents[eventIndex]
+				event.MessageEvent.ID = eventIDs[bulkKey{event.MessageEvent.MessageID, event.MessageEvent.Index}]
+				for attributeIndex := range event.Attributes {
+					attribute := &event.Attributes[attributeIndex]
+					attribute.MessageEventID = event.MessageEvent.ID
+					attribute.MessageEvent = event.MessageEvent
+					attribute.MessageEventAttributeKey = attributeKeys[attribute.MessageEventAttributeKey.Key]
+					attribute.MessageEventAttributeKeyID = attribute.MessageEventAttributeKey.ID
+					value, compressedValue := models.AttributeValueColumns(attribute.Value)
+					rows = append(rows, []any{int64(attribute.MessageEventID), value, compressedValue, int64(attribute.Index), int64(attribute.MessageEventAttributeKeyID)})
+				}
+			}
+		}
+	}
+
+	var writtenAttributes []struct {
+		ID             uint
+		MessageEventID uint
+		Index          uint64
+	}
+	err = copyUpsert(db, conn, TableName(db, &models.MessageEventAttribute{}), []string{"message_event_id", "value", "compressed_value", "index", "message_event_attribute_key_id"}, rows,
+		"(message_event_id, index) DO UPDATE SET value = EXCLUDED.value, compressed_value = EXCLUDED.compressed_value, message_event_attribute_key_id = EXCLUDED.message_event_attribute_key_id",
+		"id, message_event_id, index", &writtenAttributes)
+	if err != nil {
+		return err
+	}
+
+	attributeIDs := make(map[bulkKey]uint, len(writtenAttributes))
+	for _, attribute := range writtenAttributes {
+		attributeIDs[bulkKey{attribute.MessageEventID, attribute.Index}] = attribute.ID
+	}
+	for _, hash := range hashes {
+		tx := txes[hash]
+		for messageIndex := range tx.Messages {
+			for eventIndex := range tx.Messages[messageIndex].MessageEvents {
+				attributes := tx.Messages[messageIndex].MessageEvents[eventIndex].Attributes
+				for attributeIndex := range attributes {
+					attributes[attributeIndex].ID = attributeIDs[bulkKey{attributes[attributeIndex].MessageEventID, attributes[attributeIndex].Index}]
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// copyUpsert copies the rows into a temporary table with the columns of the table, then inserts them into the table
+// with the ON CONFLICT clause. The rows written are scanned into returned when returning lists columns. The temporary
+// table is dropped with the transaction. Must be called inside a transaction on conn.
+func copyUpsert(db *gorm.DB, conn *sql.Conn, table string, columns []string, rows [][]any, conflict string, returning string, returned any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	staging := pgx.Identifier{"bulk_" + table}.Sanitize()
+	columnList := strings.Join(columns, ", ")
+	err := db.Exec(fmt.Sprintf("CREATE TEMPORARY TABLE %s ON COMMIT DROP AS SELECT %s FROM %s WITH NO DATA",
+		staging, columnList, pgx.Identifier{table}.Sanitize())).Error
+	if err != nil {
+		return err
+	}
+
+	err = conn.Raw(func(driverConn any) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("bulk copy requires the pgx Postgres driver")
+		}
+
+		_, err := pgxConn.Conn().CopyFrom(db.Statement.Context, pgx.Identifier{"bulk_" + table}, columns, pgx.CopyFromRows(rows))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	upsert := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s ON CONFLICT %s",
+		pgx.Identifier{table}.Sanitize(), columnList, columnList, staging, conflict)
+	if returning == "" {
+		return db.Exec(upsert).Error
+	}
+	return db.Raw(upsert + " RETURNING " + returning).Scan(returned).Error
+}
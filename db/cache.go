@@ -0,0 +1,159 @@
+package db
+
+import (
+	"sync"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// The dictionary tables, rows of them are looked up by their unique value and never change once created
+const (
+	addressDictionary                  = "addresses"
+	denomDictionary                    = "denoms"
+	messageTypeDictionary              = "message_types"
+	messageEventTypeDictionary         = "message_event_types"
+	messageEventAttributeKeyDictionary = "message_event_attribute_keys"
+	blockEventTypeDictionary           = "block_event_types"
+	blockEventAttributeKeyDictionary   = "block_event_attribute_keys"
+)
+
+// dictionaryCache holds the IDs of dictionary rows by table and value. Rows are only added once the transaction
+// creating them committed, so a cached ID always exists in the database.
+type dictionaryCache struct {
+	mu           sync.RWMutex
+	ids          map[string]map[string]uint
+	addresses    int
+	maxAddresses int
+}
+
+// dictionaries is nil unless EnableDictionaryCache was called, every lookup misses then
+var dictionaries *dictionaryCache
+
+// EnableDictionaryCache preloads the dictionary tables and keeps the rows created by later blocks in memory, so
+// blocks reusing them skip the lookups and upserts. Addresses are cached up to maxAddresses, the most recent first,
+// further addresses are upserted as without the cache.
+func EnableDictionaryCache(db *gorm.DB, maxAddresses int) error {
+	cache := &dictionaryCache{ids: make(map[string]map[string]uint), maxAddresses: maxAddresses}
+
+	preloads := []struct {
+		dictionary string
+		model      any
+		column     string
+		limit      int
+	}{
+		{addressDictionary, &models.Address{}, "address", maxAddresses},
+		{denomDictionary, &models.Denom{}, "base", -1},
+		{messageTypeDictionary, &models.MessageType{}, "message_type", -1},
+		{messageEventTypeDictionary, &models.MessageEventType{}, "type", -1},
+		{messageEventAttributeKeyDictionary, &models.MessageEventAttributeKey{}, "key", -1},
+		{blockEventTypeDictionary, &models.BlockEventType{}, "type", -1},
+		{blockEventAttributeKeyDictionary, &models.BlockEventAttributeKey{}, "key", -1},
+	}
+
+	for _, preload := range preloads {
+		if preload.limit == 0 {
+			continue
+		}
+
+		var rows []struct {
+			ID    uint
+			Value string
+		}
+		err := db.Model(preload.model).Select("id, " + preload.column + " AS value").Order("id DESC").Limit(preload.limit).Scan(&rows).Error
+		if err != nil {
+			return err
+		}
+
+		for _, row := range rows {
+			cache.add(preload.dictionary, row.Value, row.ID)
+		}
+		config.Log.Infof("Preloaded %d rows of %s into the dictionary cache", len(rows), preload.dictionary)
+	}
+
+	dictionaries = cache
+	return nil
+}
+
+func (c *dictionaryCache) lookup(dictionary string, value string) (uint, bool) {
+	if c == nil {
+		return 0, false
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	id, ok := c.ids[dictionary][value]
+	return id, ok
+}
+
+// add must be called with the lock held or before the cache is shared
+func (c *dictionaryCache) add(dictionary string, value string, id uint) {
+	if id == 0 {
+		return
+	}
+
+	ids, ok := c.ids[dictionary]
+	if !ok {
+		ids = make(map[string]uint)
+		c.ids[dictionary] = ids
+	}
+	if _, ok := ids[value]; ok {
+		return
+	}
+	if dictionary == addressDictionary {
+		if c.addresses >= c.maxAddresses {
+			return
+		}
+		c.addresses++
+	}
+	ids[value] = id
+}
+
+// storeTxs adds the dictionary rows of a committed block of txes
+func (c *dictionaryCache) storeTxs(block models.Block, txs []TxDBWrapper) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.add(addressDictionary, block.ProposerConsAddress.Address, block.ProposerConsAddress.ID)
+	for _, tx := range txs {
+		for _, signer := range tx.Tx.SignerAddresses {
+			c.add(addressDictionary, signer.Address, signer.ID)
+		}
+		for _, fee := range tx.Tx.Fees {
+			c.add(addressDictionary, fee.PayerAddress.Address, fee.PayerAddress.ID)
+			c.add(denomDictionary, fee.Denomination.Base, fee.Denomination.ID)
+		}
+		for _, message := range tx.Messages {
+			c.add(messageTypeDictionary, message.Message.MessageType.MessageType, message.Message.MessageType.ID)
+			for _, event := range message.MessageEvents {
+				c.add(messageEventTypeDictionary, event.MessageEvent.MessageEventType.Type, event.MessageEvent.MessageEventType.ID)
+				for _, attribute := range event.Attributes {
+					c.add(messageEventAttributeKeyDictionary, attribute.MessageEventAttributeKey.Key, attribute.MessageEventAttributeKey.ID)
+				}
+			}
+		}
+	}
+}
+
+// storeBlockEvents adds the dictionary rows of committed block events
+func (c *dictionaryCache) storeBlockEvents(blockDBWrapper *BlockDBWrapper) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.add(addressDictionary, blockDBWrapper.Block.ProposerConsAddress.Address, blockDBWrapper.Block.ProposerConsAddress.ID)
+	for _, eventType := range blockDBWrapper.UniqueBlockEventTypes {
+		c.add(blockEventTypeDictionary, eventType.Type, eventType.ID)
+	}
+	for _, attributeKey := range blockDBWrapper.UniqueBlockEventAttributeKeys {
+		c.add(blockEventAttributeKeyDictionary, attributeKey.Key, attributeKey.ID)
+	}
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/stretchr/testify/suite"
+)
+
+type DictionaryCacheTestSuite struct {
+	suite.Suite
+}
+
+func (suite *DictionaryCacheTestSuite) TestStoreTxs() {
+	cache := &dictionaryCache{ids: make(map[string]map[string]uint), maxAddresses: 2}
+
+	block := models.Block{ProposerConsAddress: models.Address{ID: 1, Address: "cons"}}
+	txs := []TxDBWrapper{{
+		Tx: models.Tx{
+			SignerAddresses: []models.Address{{ID: 2, Address: "signer"}, {ID: 3, Address: "other"}},
+			Fees:            []models.Fee{{Denomination: models.Denom{ID: 4, Base: "uatom"}}},
+		},
+		Messages: []MessageDBWrapper{{Message: models.Message{MessageType: models.MessageType{ID: 5, MessageType: "/cosmos.bank.v1beta1.MsgSend"}}}},
+	}}
+	cache.storeTxs(block, txs)
+
+	id, ok := cache.lookup(addressDictionary, "signer")
+	suite.Require().True(ok)
+	suite.Require().Equal(uint(2), id)
+
+	// Addresses beyond the maximum are not cached
+	_, ok = cache.lookup(addressDictionary, "other")
+	suite.Require().False(ok)
+
+	id, ok = cache.lookup(messageTypeDictionary, "/cosmos.bank.v1beta1.MsgSend")
+	suite.Require().True(ok)
+	suite.Require().Equal(uint(5), id)
+
+	id, ok = cache.lookup(denomDictionary, "uatom")
+	suite.Require().True(ok)
+	suite.Require().Equal(uint(4), id)
+}
+
+func (suite *DictionaryCacheTestSuite) TestDisabled() {
+	var cache *dictionaryCache
+	_, ok := cache.lookup(addressDictionary, "cons")
+	suite.Require().False(ok)
+	cache.storeTxs(models.Block{}, nil)
+}
+
+func TestDictionaryCacheTestSuite(t *testing.T) {
+	suite.Run(t, new(DictionaryCacheTestSuite))
+}
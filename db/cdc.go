@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+const (
+	CDCKindTxs         = "txs"
+	CDCKindBlockEvents = "block_events"
+)
+
+// CDCEvent is the payload sent over NOTIFY and stored in the outbox table when a block dataset is committed
+type CDCEvent struct {
+	ChainID     string `json:"chain_id"`
+	Height      int64  `json:"height"`
+	Kind        string `json:"kind"`
+	Txs         int    `json:"txs"`
+	Messages    int    `json:"messages"`
+	BlockEvents int    `json:"block_events"`
+}
+
+// Must be called inside the indexing transaction. Postgres only delivers notifications once the transaction commits,
+// so listeners never see a block that is later rolled back.
+func emitCDCEvent(db *gorm.DB, conf config.IndexConfig, event CDCEvent) error {
+	if !conf.CDC.Notify && !conf.CDC.Outbox {
+		return nil
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if conf.CDC.Notify {
+		if err := db.Exec("SELECT pg_notify(?, ?)", conf.CDC.Channel, string(payload)).Error; err != nil {
+			config.Log.Error("Error sending CDC notification.", err)
+			return err
+		}
+	}
+
+	if conf.CDC.Outbox {
+		outboxEvent := models.CDCOutboxEvent{
+			ChainID: event.ChainID,
+			Height:  event.Height,
+			Kind:    event.Kind,
+			Payload: string(payload),
+		}
+		if err := db.Create(&outboxEvent).Error; err != nil {
+			config.Log.Error("Error creating CDC outbox event.", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func newTxsCDCEvent(conf config.IndexConfig, block models.Block, txs []TxDBWrapper) CDCEvent {
+	event := CDCEvent{
+		ChainID: conf.Probe.ChainID,
+		Height:  block.Height,
+		Kind:    CDCKindTxs,
+		Txs:     len(txs),
+	}
+	for _, tx := range txs {
+		event.Messages += len(tx.Messages)
+	}
+	return event
+}
+
+func newBlockEventsCDCEvent(conf config.IndexConfig, blockDBWrapper *BlockDBWrapper) CDCEvent {
+	return CDCEvent{
+		ChainID:     conf.Probe.ChainID,
+		Height:      blockDBWrapper.Block.Height,
+		Kind:        CDCKindBlockEvents,
+		BlockEvents: len(blockDBWrapper.BeginBlockEvents) + len(blockDBWrapper.EndBlockEvents),
+	}
+}
+
+// ListenCDC listens on the CDC notification channel and calls handle for every event until the context is done or the
+// connection fails. It holds a dedicated connection of the pool for as long as it listens.
+func ListenCDC(ctx context.Context, db *gorm.DB, channel string, handle func(CDCEvent)) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("listening for CDC notifications requires the pgx Postgres driver")
+		}
+
+		if _, err := pgxConn.Conn().Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return err
+		}
+
+		for {
+			notification, err := pgxConn.Conn().WaitForNotification(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+
+			var event CDCEvent
+			if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+				config.Log.Errorf("Ignoring invalid CDC notification %q: %v", notification.Payload, err)
+				continue
+			}
+			handle(event)
+		}
+	})
+}
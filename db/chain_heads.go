@@ -0,0 +1,75 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// RecordChainHead records the head of the chain next to the highest height indexed for it, a block whose transactions
+// or block events were indexed, and returns the recorded row. The chain is recorded as halted once its head did not
+// advance for haltAfter, 0 disables the detection.
+func RecordChainHead(db *gorm.DB, chainID uint, headHeight int64, headTime time.Time, haltAfter time.Duration) (models.ChainHead, error) {
+	now := time.Now()
+	head := models.ChainHead{
+		BlockchainID:   chainID,
+		HeadHeight:     headHeight,
+		HeadTime:       headTime,
+		HeadAdvancedAt: now,
+	}
+
+	var previous models.ChainHead
+	err := db.Where("blockchain_id = ?", chainID).Take(&previous).Error
+	switch {
+	case err == nil:
+		if headHeight <= previous.HeadHeight && !previous.HeadAdvancedAt.IsZero() {
+			head.HeadAdvancedAt = previous.HeadAdvancedAt
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return head, err
+	}
+	head.Halted = haltAfter > 0 && now.Sub(head.HeadAdvancedAt) >= haltAfter
+
+	var indexed models.Block
+	err = db.Where("chain_id = ? AND (tx_indexed OR block_events_indexed)", chainID).Order("height DESC").Limit(1).Take(&indexed).Error
+	switch {
+	case err == nil:
+		head.IndexedHeight = indexed.Height
+		head.IndexedTime = &indexed.TimeStamp
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return head, err
+	}
+
+	head.LagBlocks = headHeight - head.IndexedHeight
+	if head.LagBlocks < 0 {
+		head.LagBlocks = 0
+	}
+	if head.IndexedTime != nil && headTime.After(*head.IndexedTime) {
+		head.LagSeconds = headTime.Sub(*head.IndexedTime).Seconds()
+	}
+
+	err = db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "blockchain_id"}},
+		UpdateAll: true,
+	}).Omit("Chain").Create(&head).Error
+
+	return head, err
+}
+
+// GetChainHead returns the chain head recorded for the chain with the chain ID, gorm.ErrRecordNotFound if the chain
+// is not indexed or its head was never recorded
+func GetChainHead(db *gorm.DB, chainID string) (models.ChainHead, error) {
+	var head models.ChainHead
+
+	var chain models.Chain
+	if err := db.Where("chain_id = ?", chainID).First(&chain).Error; err != nil {
+		return head, err
+	}
+
+	err := db.Where("blockchain_id = ?", chain.ID).Take(&head).Error
+	head.Chain = chain
+	return head, err
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"database/sql"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// chaosConnectionFailure is the SQLSTATE of the injected errors, a lost connection which is retried as transient
+const chaosConnectionFailure = "08006"
+
+// RegisterChaosCallbacks fails statements run in a transaction with a transient connection error at rate, a number
+// between 0 and 1. The indexed data of a block is written in transactions, so their retries and the rollback of the
+// partial writes are exercised; statements outside of transactions, e.g. the startup queries, are not faulted. A seed
+// of 0 seeds the faults randomly.
+func RegisterChaosCallbacks(db *gorm.DB, rate float64, seed int64) error {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	var mu sync.Mutex
+	rng := rand.New(rand.NewSource(seed)) //nolint:gosec
+
+	injectError := func(tx *gorm.DB) {
+		if tx.Error != nil {
+			return
+		}
+		if _, inTransaction := tx.Statement.ConnPool.(*sql.Tx); !inTransaction {
+			return
+		}
+
+		mu.Lock()
+		fault := rng.Float64() < rate
+		mu.Unlock()
+		if fault {
+			tx.AddError(&pgconn.PgError{Severity: "FATAL", Code: chaosConnectionFailure, Message: "chaos: connection failure injected"}) //nolint:errcheck
+		}
+	}
+
+	if err := db.Callback().Create().Before("gorm:create").Register("indexer:chaos", injectError); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("indexer:chaos", injectError); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("indexer:chaos", injectError); err != nil {
+		return err
+	}
+	return db.Callback().Raw().Before("gorm:raw").Register("indexer:chaos", injectError)
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetIndexerCheckpoint returns the indexing checkpoint of the chain, nil when none was saved yet
+func GetIndexerCheckpoint(db *gorm.DB, chainID uint) (*models.IndexerCheckpoint, error) {
+	var checkpoint models.IndexerCheckpoint
+	err := db.Where("blockchain_id = ?", chainID).Take(&checkpoint).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// ContiguousIndexedHeight returns the highest height up to which every height from the from height on is a block whose
+// transactions or block events were indexed, or a block recorded in failed_blocks or failed_event_blocks. It returns
+// from - 1 when the from height itself is neither.
+func ContiguousIndexedHeight(db *gorm.DB, chainID uint, from int64) (int64, error) {
+	var height int64
+	err := db.Raw(fmt.Sprintf(`WITH heights AS (
+			SELECT height FROM %q WHERE chain_id = @chain_id AND height >= @from AND (tx_indexed OR block_events_indexed)
+			UNION
+			SELECT height FROM %q WHERE blockchain_id = @chain_id AND height >= @from
+			UNION
+			SELECT height FROM %q WHERE blockchain_id = @chain_id AND height >= @from
+		)
+		SELECT COALESCE(MAX(height), @from - 1) FROM (
+			SELECT height, height - ROW_NUMBER() OVER (ORDER BY height) AS island FROM heights
+		) islands WHERE island = @from - 1`,
+		TableName(db, &models.Block{}), TableName(db, &models.FailedBlock{}), TableName(db, &models.FailedEventBlock{})),
+		map[string]any{"chain_id": chainID, "from": from}).Scan(&height).Error
+	return height, err
+}
+
+// UpsertIndexerCheckpoint saves the indexing checkpoint of its chain, replacing the previous one
+func UpsertIndexerCheckpoint(db *gorm.DB, checkpoint models.IndexerCheckpoint) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "blockchain_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"start_height", "height", "updated_at"}),
+	}).Omit("Chain").Create(&checkpoint).Error
+}
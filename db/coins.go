@@ -0,0 +1,159 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// attributeCoinPattern matches a coin of an attribute value, integer coins such as 10uatom as well as the decimal
+// coins of reward and commission events such as 0.5uatom
+var attributeCoinPattern = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)([a-zA-Z][a-zA-Z0-9/:._-]*)$`)
+
+type attributeCoin struct {
+	position uint
+	amount   decimal.Decimal
+	denom    string
+}
+
+// parseAttributeCoins parses the comma separated coins of an attribute value. Parts that are not coins, e.g. an empty
+// amount, are skipped and keep their position.
+func parseAttributeCoins(value string) []attributeCoin {
+	var coins []attributeCoin
+	for position, part := range strings.Split(value, ",") {
+		match := attributeCoinPattern.FindStringSubmatch(strings.TrimSpace(part))
+		if match == nil {
+			continue
+		}
+		amount, err := decimal.NewFromString(match[1])
+		if err != nil {
+			continue
+		}
+		coins = append(coins, attributeCoin{position: uint(position), amount: amount, denom: match[2]})
+	}
+	return coins
+}
+
+// coinDenoms resolves the denoms of the parsed coins to their IDs, creating the unknown ones
+type coinDenoms struct {
+	db  *gorm.DB
+	ids map[string]uint
+}
+
+func (d *coinDenoms) id(base string) (uint, error) {
+	if id, ok := d.ids[base]; ok {
+		return id, nil
+	}
+	denom, err := FindOrCreateDenomByBase(d.db, base)
+	if err != nil {
+		return 0, err
+	}
+	d.ids[base] = denom.ID
+	return denom.ID, nil
+}
+
+// indexMessageEventAttributeCoins replaces the coins of the message event attributes of the txes whose key is one of
+// the keys. Must be called inside the indexing transaction, after the message event attributes were written.
+func indexMessageEventAttributeCoins(db *gorm.DB, txs []TxDBWrapper, txIDs []uint, keys []string) error {
+	if len(txIDs) == 0 {
+		return nil
+	}
+
+	err := db.Exec(fmt.Sprintf(`DELETE FROM %q WHERE message_event_attribute_id IN (
+		SELECT a.id FROM %q a JOIN %q e ON e.id = a.message_event_id JOIN %q m ON m.id = e.message_id WHERE m.tx_id IN ?)`,
+		TableName(db, &models.MessageEventAttributeCoin{}), TableName(db, &models.MessageEventAttribute{}),
+		TableName(db, &models.MessageEvent{}), TableName(db, &models.Message{})), txIDs).Error
+	if err != nil {
+		return err
+	}
+
+	coinKeys := attributeKeySet(keys)
+	denoms := coinDenoms{db: db, ids: map[string]uint{}}
+
+	var coins []models.MessageEventAttributeCoin
+	for _, tx := range txs {
+		for _, message := range tx.Messages {
+			for _, event := range message.MessageEvents {
+				for _, attribute := range event.Attributes {
+					if !coinKeys[attribute.MessageEventAttributeKey.Key] {
+						continue
+					}
+					for _, coin := range parseAttributeCoins(attribute.Value) {
+						denomID, err := denoms.id(coin.denom)
+						if err != nil {
+							return err
+						}
+						coins = append(coins, models.MessageEventAttributeCoin{
+							MessageEventAttributeID: attribute.ID,
+							Position:                coin.position,
+							MessageEventID:          event.MessageEvent.ID,
+							DenomID:                 denomID,
+							Amount:                  coin.amount,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	if len(coins) == 0 {
+		return nil
+	}
+	return db.Omit("Denom").CreateInBatches(coins, 500).Error
+}
+
+// indexBlockEventAttributeCoins replaces the coins of the block event attributes of the block whose key is one of the
+// keys. Must be called inside the block event indexing transaction, after the attributes were written.
+func indexBlockEventAttributeCoins(db *gorm.DB, blockDBWrapper *BlockDBWrapper, keys []string) error {
+	err := db.Exec(fmt.Sprintf(`DELETE FROM %q WHERE block_event_attribute_id IN (
+		SELECT a.id FROM %q a JOIN %q e ON e.id = a.block_event_id WHERE e.block_id = ?)`,
+		TableName(db, &models.BlockEventAttributeCoin{}), TableName(db, &models.BlockEventAttribute{}),
+		TableName(db, &models.BlockEvent{})), blockDBWrapper.Block.ID).Error
+	if err != nil {
+		return err
+	}
+
+	coinKeys := attributeKeySet(keys)
+	denoms := coinDenoms{db: db, ids: map[string]uint{}}
+
+	var coins []models.BlockEventAttributeCoin
+	for _, events := range [][]BlockEventDBWrapper{blockDBWrapper.BeginBlockEvents, blockDBWrapper.EndBlockEvents} {
+		for _, event := range events {
+			for _, attribute := range event.Attributes {
+				if !coinKeys[attribute.BlockEventAttributeKey.Key] {
+					continue
+				}
+				for _, coin := range parseAttributeCoins(attribute.Value) {
+					denomID, err := denoms.id(coin.denom)
+					if err != nil {
+						return err
+					}
+					coins = append(coins, models.BlockEventAttributeCoin{
+						BlockEventAttributeID: attribute.ID,
+						Position:              coin.position,
+						BlockEventID:          event.BlockEvent.ID,
+						DenomID:               denomID,
+						Amount:                coin.amount,
+					})
+				}
+			}
+		}
+	}
+
+	if len(coins) == 0 {
+		return nil
+	}
+	return db.Omit("Denom").CreateInBatches(coins, 500).Error
+}
+
+func attributeKeySet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return set
+}
@@ -0,0 +1,36 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/suite"
+)
+
+type CoinsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *CoinsTestSuite) TestParseAttributeCoins() {
+	coins := parseAttributeCoins("10uatom, 5ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2,,0.25uosmo")
+	suite.Require().Len(coins, 3)
+
+	suite.Require().Equal(uint(0), coins[0].position)
+	suite.Require().True(decimal.NewFromInt(10).Equal(coins[0].amount))
+	suite.Require().Equal("uatom", coins[0].denom)
+
+	suite.Require().Equal(uint(1), coins[1].position)
+	suite.Require().Equal("ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2", coins[1].denom)
+
+	// The empty part keeps its position
+	suite.Require().Equal(uint(3), coins[2].position)
+	suite.Require().True(decimal.RequireFromString("0.25").Equal(coins[2].amount))
+
+	suite.Require().Empty(parseAttributeCoins(""))
+	suite.Require().Empty(parseAttributeCoins("cosmos1abc"))
+	suite.Require().Empty(parseAttributeCoins("uatom"))
+}
+
+func TestCoinsTestSuite(t *testing.T) {
+	suite.Run(t, new(CoinsTestSuite))
+}
@@ -0,0 +1,42 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// UpsertConsensusMetrics derives the consensus metrics of the indexed block and its neighbours from the blocks and block
+// commits indexed so far. A block adds the interval of the next block and its commit adds the rounds and signatures of
+// the previous block, so the metrics are complete whatever order the blocks are indexed in.
+func UpsertConsensusMetrics(db *gorm.DB, block models.Block) error {
+	blocks := TableName(db, &models.Block{})
+	commits := TableName(db, &models.BlockCommit{})
+	signatures := TableName(db, &models.BlockCommitSignature{})
+
+	return db.Exec(fmt.Sprintf(`INSERT INTO %[1]q (block_id, height, interval_ms, proposer_address_id, rounds, signed_validators, validators, participation_pct)
+		SELECT b.id, b.height,
+			(EXTRACT(EPOCH FROM b.time_stamp - p.time_stamp) * 1000)::bigint,
+			b.proposer_cons_address_id,
+			c.round + 1,
+			s.signed, s.total,
+			CASE WHEN s.total > 0 THEN s.signed * 100.0 / s.total END
+		FROM %[2]q b
+		LEFT JOIN %[2]q p ON p.chain_id = b.chain_id AND p.height = b.height - 1
+		LEFT JOIN %[2]q n ON n.chain_id = b.chain_id AND n.height = b.height + 1
+		LEFT JOIN %[3]q c ON c.block_id = n.id AND c.height = b.height
+		LEFT JOIN LATERAL (
+			SELECT count(*) FILTER (WHERE flag = ?) AS signed, count(*) AS total FROM %[4]q WHERE block_commit_id = c.id
+		) s ON c.id IS NOT NULL
+		WHERE b.chain_id = ? AND b.height BETWEEN ? AND ?
+		ON CONFLICT (block_id) DO UPDATE SET
+			interval_ms = EXCLUDED.interval_ms,
+			proposer_address_id = EXCLUDED.proposer_address_id,
+			rounds = EXCLUDED.rounds,
+			signed_validators = EXCLUDED.signed_validators,
+			validators = EXCLUDED.validators,
+			participation_pct = EXCLUDED.participation_pct`,
+		TableName(db, &models.ConsensusMetric{}), blocks, commits, signatures),
+		string(models.BlockIDFlagCommit), block.ChainID, block.Height-1, block.Height+1).Error
+}
@@ -3,25 +3,73 @@ package db
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/DefiantLabs/cosmos-indexer/config"
 	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
 	"github.com/DefiantLabs/cosmos-indexer/parsers"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
 )
 
+// ConnectOptions are the session settings of the connections, zero values keep the defaults of the database
+type ConnectOptions struct {
+	// SearchPath is the schema of the tables, it is created if it does not exist yet
+	SearchPath string
+	// Naming is the naming strategy of the tables, nil uses the default table names
+	Naming schema.Namer
+	// IsolationLevel is the default_transaction_isolation of the connections, e.g. repeatable read
+	IsolationLevel string
+	// LockTimeout aborts statements waiting longer for a lock, instead of waiting on another writer indefinitely
+	LockTimeout time.Duration
+}
+
 // PostgresDbConnect connects to the database according to the passed in parameters
-func PostgresDbConnect(host string, port string, database string, user string, password string, level string) (*gorm.DB, error) {
+func PostgresDbConnect(host string, port string, database string, user string, password string, level string, options ConnectOptions) (*gorm.DB, error) {
 	dsn := fmt.Sprintf("host=%s port=%s dbname=%s user=%s password=%s sslmode=disable", host, port, database, user, password)
+	searchPath := options.SearchPath
+	if searchPath != "" {
+		dsn += fmt.Sprintf(" search_path=%s", searchPath)
+	}
+	if options.IsolationLevel != "" {
+		dsn += fmt.Sprintf(" default_transaction_isolation='%s'", options.IsolationLevel)
+	}
+	if options.LockTimeout > 0 {
+		dsn += fmt.Sprintf(" lock_timeout=%d", options.LockTimeout.Milliseconds())
+	}
 	gormLogLevel := logger.Silent
 
 	if level == "info" {
 		gormLogLevel = logger.Info
 	}
-	return gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(gormLogLevel)})
+	gormDB, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(gormLogLevel), NamingStrategy: options.Naming})
+	if err != nil {
+		return nil, err
+	}
+
+	if searchPath != "" {
+		// Postgres checks the CREATE privilege on the database before IF NOT EXISTS, so existing schemas are skipped
+		// for roles that may only use them
+		var exists bool
+		if err := gormDB.Raw("SELECT EXISTS (SELECT 1 FROM pg_namespace WHERE nspname = ?)", searchPath).Scan(&exists).Error; err != nil {
+			return nil, err
+		}
+		if !exists {
+			if err := gormDB.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %q", searchPath)).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := registerRowCountCallbacks(gormDB); err != nil {
+		return nil, err
+	}
+
+	return gormDB, nil
 }
 
 // MigrateModels runs the gorm automigrations with all the db models. This will migrate as needed and do nothing if nothing has changed.
@@ -46,6 +94,22 @@ func MigrateModels(db *gorm.DB) error {
 		return err
 	}
 
+	if err := migrateCDCModels(db); err != nil {
+		return err
+	}
+
+	if err := migrateSinkModels(db); err != nil {
+		return err
+	}
+
+	if err := migrateJobModels(db); err != nil {
+		return err
+	}
+
+	if err := migrateGenesisModels(db); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -64,12 +128,31 @@ func migrateBlockModels(db *gorm.DB) error {
 		&models.BlockEventAttributeKey{},
 		&models.FailedBlock{},
 		&models.FailedEventBlock{},
+		&models.HeightState{},
+		&models.ChainHead{},
+		&models.IndexerCheckpoint{},
+		&models.BlockCommit{},
+		&models.BlockCommitSignature{},
+		&models.ConsensusMetric{},
+		&models.BlockEvidence{},
+		&models.EvidenceOffender{},
+		&models.ModuleVersion{},
 	)
 }
 
 func migrateDenomModels(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&models.Denom{},
+		&models.DenomMetadata{},
+		&models.DenomTrace{},
+		&models.NormalizedFee{},
+		&models.Valuation{},
+		&models.DexPrice{},
+		&models.LedgerEntry{},
+		&models.MessageEventAttributeCoin{},
+		&models.BlockEventAttributeCoin{},
+		&models.AddressActivity{},
+		&models.AddressSummary{},
 	)
 }
 
@@ -78,6 +161,7 @@ func migrateTXModels(db *gorm.DB) error {
 		&models.Tx{},
 		&models.Fee{},
 		&models.Address{},
+		&models.AddressInvolvement{},
 		&models.MessageType{},
 		&models.Message{},
 		&models.FailedTx{},
@@ -86,15 +170,53 @@ func migrateTXModels(db *gorm.DB) error {
 		&models.MessageEventType{},
 		&models.MessageEventAttribute{},
 		&models.MessageEventAttributeKey{},
+		&models.MessageEventSearch{},
 	)
 }
 
 func migrateParserModels(db *gorm.DB) error {
+	// The execution metrics were keyed without the chain before
+	if db.Migrator().HasIndex(&models.ParserExecutionMetric{}, "idx_parser_execution_metric_identifier_stage") {
+		if err := db.Migrator().DropIndex(&models.ParserExecutionMetric{}, "idx_parser_execution_metric_identifier_stage"); err != nil {
+			return err
+		}
+	}
+
 	return db.AutoMigrate(
 		&models.BlockEventParser{},
 		&models.BlockEventParserError{},
 		&models.MessageParser{},
 		&models.MessageParserError{},
+		&models.ParserExecutionMetric{},
+	)
+}
+
+func migrateCDCModels(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&models.CDCOutboxEvent{},
+	)
+}
+
+func migrateSinkModels(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&models.SinkCheckpoint{},
+	)
+}
+
+func migrateJobModels(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&models.JobRun{},
+	)
+}
+
+func migrateGenesisModels(db *gorm.DB) error {
+	return db.AutoMigrate(
+		&models.GenesisState{},
+		&models.GenesisBalance{},
+		&models.GenesisSupply{},
+		&models.GenesisValidator{},
+		&models.GenesisParam{},
+		&models.GenesisContract{},
 	)
 }
 
@@ -113,7 +235,7 @@ func GetDBChainID(db *gorm.DB, chain models.Chain) (uint, error) {
 func GetHighestIndexedBlock(db *gorm.DB, chainID uint) models.Block {
 	var block models.Block
 	// this can potentially be optimized by getting max first and selecting it (this gets translated into a select * limit 1)
-	db.Table("blocks").Where("chain_id = ?::int AND tx_indexed = true AND time_stamp != '0001-01-01T00:00:00.000Z'", chainID).Order("height desc").First(&block)
+	db.Model(&models.Block{}).Where("chain_id = ?::int AND tx_indexed = true AND time_stamp != '0001-01-01T00:00:00.000Z'", chainID).Order("height desc").First(&block)
 	return block
 }
 
@@ -136,7 +258,7 @@ func GetBlocksFromStart(db *gorm.DB, chainID uint, startHeight int64, endHeight
 func GetHighestEventIndexedBlock(db *gorm.DB, chainID uint) (models.Block, error) {
 	var block models.Block
 	// this can potentially be optimized by getting max first and selecting it (this gets translated into a select * limit 1)
-	err := db.Table("blocks").Where("chain_id = ?::int AND block_events_indexed = true AND time_stamp != '0001-01-01T00:00:00.000Z'", chainID).Order("height desc").First(&block).Error
+	err := db.Model(&models.Block{}).Where("chain_id = ?::int AND block_events_indexed = true AND time_stamp != '0001-01-01T00:00:00.000Z'", chainID).Order("height desc").First(&block).Error
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return block, nil
@@ -145,151 +267,276 @@ func GetHighestEventIndexedBlock(db *gorm.DB, chainID uint) (models.Block, error
 	return block, err
 }
 
-func UpsertFailedBlock(db *gorm.DB, blockHeight int64, chainID string, chainName string) error {
-	return db.Transaction(func(dbTransaction *gorm.DB) error {
-		failedBlock := models.FailedBlock{Height: blockHeight, Chain: models.Chain{ChainID: chainID, Name: chainName}}
+// UpsertFailedBlock records the failure of the block at height, a block that failed before has its failure replaced and
+// its retry count incremented
+func UpsertFailedBlock(db *gorm.DB, blockHeight int64, chainID string, chainName string, failure models.BlockFailure) error {
+	err := transaction(db, func(dbTransaction *gorm.DB) error {
+		failedBlock := models.FailedBlock{Height: blockHeight, Chain: models.Chain{ChainID: chainID, Name: chainName}, BlockFailure: failure}
 
 		if err := dbTransaction.Where(&failedBlock.Chain).FirstOrCreate(&failedBlock.Chain).Error; err != nil {
 			config.Log.Error("Error creating chain DB object.", err)
 			return err
 		}
 
-		if err := dbTransaction.Where(&failedBlock).FirstOrCreate(&failedBlock).Error; err != nil {
+		if err := dbTransaction.Clauses(upsertFailure()).Create(&failedBlock).Error; err != nil {
 			config.Log.Error("Error creating failed block DB object.", err)
 			return err
 		}
 		return nil
 	})
+	if err == nil {
+		metrics.AddFailedBlock("block", string(failure.Component), string(failure.ErrorCode))
+	}
+	return err
 }
 
-func UpsertFailedEventBlock(db *gorm.DB, blockHeight int64, chainID string, chainName string) error {
-	return db.Transaction(func(dbTransaction *gorm.DB) error {
-		failedEventBlock := models.FailedEventBlock{Height: blockHeight, Chain: models.Chain{ChainID: chainID, Name: chainName}}
+func UpsertFailedEventBlock(db *gorm.DB, blockHeight int64, chainID string, chainName string, failure models.BlockFailure) error {
+	err := transaction(db, func(dbTransaction *gorm.DB) error {
+		failedEventBlock := models.FailedEventBlock{Height: blockHeight, Chain: models.Chain{ChainID: chainID, Name: chainName}, BlockFailure: failure}
 
 		if err := dbTransaction.Where(&failedEventBlock.Chain).FirstOrCreate(&failedEventBlock.Chain).Error; err != nil {
 			config.Log.Error("Error creating chain DB object.", err)
 			return err
 		}
 
-		if err := dbTransaction.Where(&failedEventBlock).FirstOrCreate(&failedEventBlock).Error; err != nil {
+		if err := dbTransaction.Clauses(upsertFailure()).Create(&failedEventBlock).Error; err != nil {
 			config.Log.Error("Error creating failed event block DB object.", err)
 			return err
 		}
 		return nil
 	})
+	if err == nil {
+		metrics.AddFailedBlock("events", string(failure.Component), string(failure.ErrorCode))
+	}
+	return err
 }
 
-func IndexNewBlock(db *gorm.DB, block models.Block, txs []TxDBWrapper, indexerConfig config.IndexConfig) (models.Block, []TxDBWrapper, error) {
-	// consider optimizing the transaction, but how? Ordering matters due to foreign key constraints
-	// Order required: Block -> (For each Tx: Signer Address -> Tx -> (For each Message: Message -> Taxable Events))
-	// Also, foreign key relations are struct value based so create needs to be called first to get right foreign key ID
-	err := db.Transaction(func(dbTransaction *gorm.DB) error {
-		// remove from failed blocks if exists
-		if err := dbTransaction.
-			Exec("DELETE FROM failed_blocks WHERE height = ? AND blockchain_id = ?", block.Height, block.ChainID).
-			Error; err != nil {
-			config.Log.Error("Error updating failed block.", err)
-			return err
-		}
+// resetBlockMarkers clears the completeness markers that do not hold for newly indexed data of the block
+func resetBlockMarkers(db *gorm.DB, block *models.Block) error {
+	block.CustomParsersApplied = false
+	block.Verified = false
+	block.Valued = false
+	block.DexPricesDerived = false
+	return db.Model(&models.Block{}).
+		Where("id = ?", block.ID).
+		Updates(map[string]any{"custom_parsers_applied": false, "verified": false, "valued": false, "dex_prices_derived": false}).
+		Error
+}
 
-		consAddress, err := FindOrCreateAddressByAddress(dbTransaction, block.ProposerConsAddress.Address)
-		// create cons address if it doesn't exist
-		if err != nil {
-			config.Log.Error("Error getting/creating cons address DB object.", err)
-			return err
-		}
+// markCustomParsersApplied records that the custom parsers ran on the data indexed for the block
+func markCustomParsersApplied(db *gorm.DB, block *models.Block) error {
+	block.CustomParsersApplied = true
+	return db.Model(&models.Block{}).Where("id = ?", block.ID).Update("custom_parsers_applied", true).Error
+}
 
-		// create block if it doesn't exist
-		block.ProposerConsAddressID = consAddress.ID
-		block.ProposerConsAddress = consAddress
-		block.TxIndexed = true
-		if err := dbTransaction.
-			Where(models.Block{Height: block.Height, ChainID: block.ChainID}).
-			Assign(models.Block{TxIndexed: true, TimeStamp: block.TimeStamp}).
-			FirstOrCreate(&block).Error; err != nil {
-			config.Log.Error("Error getting/creating block DB object.", err)
-			return err
-		}
+// indexFailedTxes records the txes of the block that could not be processed, dropping the ones of earlier attempts
+// that are processed now
+func indexFailedTxes(db *gorm.DB, block models.Block, failedTxs []models.FailedTx) error {
+	if err := db.Where("block_id = ?", block.ID).Delete(&models.FailedTx{}).Error; err != nil {
+		return err
+	}
 
-		// pull txes and insert them
-		uniqueTxes := make(map[string]models.Tx)
-		uniqueAddress := make(map[string]models.Address)
+	if len(failedTxs) == 0 {
+		return nil
+	}
 
-		denomMap := make(map[string]models.Denom)
+	for index := range failedTxs {
+		failedTxs[index].BlockID = block.ID
+	}
 
-		for _, tx := range txs {
-			tx.Tx.BlockID = block.ID
-			tx.Tx.Block = block
-			uniqueTxes[tx.Tx.Hash] = tx.Tx
-			if len(tx.Tx.SignerAddresses) != 0 {
-				for _, signerAddress := range tx.Tx.SignerAddresses {
-					uniqueAddress[signerAddress.Address] = signerAddress
-				}
-			}
-			for feeIndex, fee := range tx.Tx.Fees {
-				uniqueAddress[fee.PayerAddress.Address] = fee.PayerAddress
+	return db.Omit("Block").Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"block_id", "error_code", "error_message", "raw"}),
+	}).Create(&failedTxs).Error
+}
 
-				denom := fee.Denomination
+// indexFailedMessages replaces the failed messages of the txes
+func indexFailedMessages(db *gorm.DB, txIDs []uint, failedMessages []models.FailedMessage) error {
+	if len(txIDs) == 0 {
+		return nil
+	}
 
-				if _, ok := denomMap[denom.Base]; !ok {
-					denom, err = FindOrCreateDenomByBase(dbTransaction, denom.Base)
-					if err != nil {
-						config.Log.Error("Error getting/creating denom DB object.", err)
-						return err
-					}
-					denomMap[denom.Base] = denom
-				} else {
-					denom = denomMap[denom.Base]
-				}
+	if err := db.Where("tx_id IN ?", txIDs).Delete(&models.FailedMessage{}).Error; err != nil {
+		return err
+	}
 
-				tx.Tx.Fees[feeIndex].DenominationID = denom.ID
-				tx.Tx.Fees[feeIndex].Denomination = denom
-			}
+	if len(failedMessages) == 0 {
+		return nil
+	}
+
+	return db.Omit("Tx").Create(&failedMessages).Error
+}
+
+// upsertFailure replaces the failure of a block that is already in the failed table and counts the retry
+func upsertFailure() clause.OnConflict {
+	retried := clause.Assignment{
+		Column: clause.Column{Name: "retry_count"},
+		Value:  gorm.Expr("? + 1", clause.Column{Table: clause.CurrentTable, Name: "retry_count"}),
+	}
+
+	return clause.OnConflict{
+		Columns:   []clause.Column{{Name: "height"}, {Name: "blockchain_id"}},
+		DoUpdates: append(clause.AssignmentColumns([]string{"error_code", "error_message", "component"}), retried),
+	}
+}
+
+// upsertIndexedBlock creates the block with its proposer or marks the existing one as tx indexed, and replaces its
+// failed txes. It also removes the block from the failed blocks.
+func upsertIndexedBlock(db *gorm.DB, block *models.Block, failedTxs []models.FailedTx) error {
+	// remove from failed blocks if exists
+	if err := db.
+		Where("height = ? AND blockchain_id = ?", block.Height, block.ChainID).
+		Delete(&models.FailedBlock{}).
+		Error; err != nil {
+		config.Log.Error("Error updating failed block.", err)
+		return err
+	}
+
+	consAddress, err := FindOrCreateAddressByAddress(db, block.ProposerConsAddress.Address)
+	// create cons address if it doesn't exist
+	if err != nil {
+		config.Log.Error("Error getting/creating cons address DB object.", err)
+		return err
+	}
+
+	// create block if it doesn't exist
+	block.ProposerConsAddressID = consAddress.ID
+	block.ProposerConsAddress = consAddress
+	block.TxIndexed = true
+	if err := db.
+		Where(models.Block{Height: block.Height, ChainID: block.ChainID}).
+		Assign(models.Block{TxIndexed: true, TimeStamp: block.TimeStamp, Hash: block.Hash, ParentHash: block.ParentHash}).
+		FirstOrCreate(block).Error; err != nil {
+		config.Log.Error("Error getting/creating block DB object.", err)
+		return err
+	}
+
+	if err := resetBlockMarkers(db, block); err != nil {
+		config.Log.Error("Error resetting block completeness markers.", err)
+		return err
+	}
+
+	if err := indexFailedTxes(db, *block, failedTxs); err != nil {
+		config.Log.Error("Error updating failed txes.", err)
+		return err
+	}
+	return nil
+}
+
+// indexTxAddresses creates the fee denoms and the signer, fee payer and involved addresses of the txes, and sets them
+// with their IDs on the signers and fees of the txes. It returns the addresses by address.
+func indexTxAddresses(db *gorm.DB, txs []TxDBWrapper, involved map[string][]string) (map[string]models.Address, error) {
+	uniqueAddress := make(map[string]models.Address)
+	denomMap := make(map[string]models.Denom)
+
+	for _, tx := range txs {
+		for _, signerAddress := range tx.Tx.SignerAddresses {
+			uniqueAddress[signerAddress.Address] = signerAddress
 		}
+		for feeIndex, fee := range tx.Tx.Fees {
+			uniqueAddress[fee.PayerAddress.Address] = fee.PayerAddress
+
+			denom, ok := denomMap[fee.Denomination.Base]
+			if !ok {
+				var err error
+				denom, err = FindOrCreateDenomByBase(db, fee.Denomination.Base)
+				if err != nil {
+					config.Log.Error("Error getting/creating denom DB object.", err)
+					return nil, err
+				}
+				denomMap[denom.Base] = denom
+			}
 
-		var addressesSlice []models.Address
-		for _, address := range uniqueAddress {
-			addressesSlice = append(addressesSlice, address)
+			tx.Tx.Fees[feeIndex].DenominationID = denom.ID
+			tx.Tx.Fees[feeIndex].Denomination = denom
 		}
+	}
 
-		if len(addressesSlice) != 0 {
-			if err := dbTransaction.Clauses(clause.OnConflict{
-				Columns:   []clause.Column{{Name: "address"}},
-				DoUpdates: clause.AssignmentColumns([]string{"address"}),
-			}).Create(addressesSlice).Error; err != nil {
-				config.Log.Error("Error getting/creating addresses.", err)
-				return err
+	for _, addresses := range involved {
+		for _, address := range addresses {
+			if _, ok := uniqueAddress[address]; !ok {
+				uniqueAddress[address] = models.Address{Address: address}
 			}
 		}
+	}
 
-		for _, address := range addressesSlice {
+	var addressesSlice []models.Address
+	for _, address := range uniqueAddress {
+		if id, ok := dictionaries.lookup(addressDictionary, address.Address); ok {
+			address.ID = id
 			uniqueAddress[address.Address] = address
+			continue
 		}
+		addressesSlice = append(addressesSlice, address)
+	}
 
-		var txesSlice []models.Tx
-		for _, tx := range uniqueTxes {
+	if len(addressesSlice) != 0 {
+		if err := db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "address"}},
+			DoUpdates: clause.AssignmentColumns([]string{"address"}),
+		}).Create(addressesSlice).Error; err != nil {
+			config.Log.Error("Error getting/creating addresses.", err)
+			return nil, err
+		}
+	}
 
-			var signerAddressID uint
+	for _, address := range addressesSlice {
+		uniqueAddress[address.Address] = address
+	}
 
-			if len(tx.SignerAddresses) != 0 {
-				for addressIndex := range tx.SignerAddresses {
-					signerAddressID = uniqueAddress[tx.SignerAddresses[addressIndex].Address].ID
-					tx.SignerAddresses[addressIndex] = uniqueAddress[tx.SignerAddresses[addressIndex].Address]
-					tx.SignerAddresses[addressIndex].ID = signerAddressID
-				}
-			}
+	// The signers and fees share their backing arrays with the txes of the caller
+	for _, tx := range txs {
+		for addressIndex := range tx.Tx.SignerAddresses {
+			tx.Tx.SignerAddresses[addressIndex] = uniqueAddress[tx.Tx.SignerAddresses[addressIndex].Address]
+		}
+		for feeIndex := range tx.Tx.Fees {
+			tx.Tx.Fees[feeIndex].PayerAddress = uniqueAddress[tx.Tx.Fees[feeIndex].PayerAddress.Address]
+			tx.Tx.Fees[feeIndex].PayerAddressID = tx.Tx.Fees[feeIndex].PayerAddress.ID
+		}
+	}
 
-			for feeIndex := range tx.Fees {
-				tx.Fees[feeIndex].PayerAddressID = uniqueAddress[tx.Fees[feeIndex].PayerAddress.Address].ID
-				tx.Fees[feeIndex].PayerAddress = uniqueAddress[tx.Fees[feeIndex].PayerAddress.Address]
-			}
+	return uniqueAddress, nil
+}
+
+// IndexNewBlock indexes the block and its txes. Custom message parsers that index atomically are run in the same transaction,
+// deferred parsers are left to IndexCustomMessages. The failed txes replace the ones recorded by earlier attempts of the block.
+func IndexNewBlock(db *gorm.DB, block models.Block, txs []TxDBWrapper, failedTxs []models.FailedTx, indexerConfig config.IndexConfig, messageParserTrackers map[string]models.MessageParser) (models.Block, []TxDBWrapper, error) {
+	// consider optimizing the transaction, but how? Ordering matters due to foreign key constraints
+	// Order required: Block -> (For each Tx: Signer Address -> Tx -> (For each Message: Message -> Taxable Events))
+	// Also, foreign key relations are struct value based so create needs to be called first to get right foreign key ID
+	err := transaction(db, func(dbTransaction *gorm.DB) error {
+		if err := upsertIndexedBlock(dbTransaction, &block, failedTxs); err != nil {
+			return err
+		}
+
+		// The addresses named in event attributes are indexed with the signers and fee payers
+		var involved map[string][]string
+		if indexerConfig.Flags.IndexAddressInvolvements {
+			involved = involvedAddresses(txs, indexerConfig.Probe.AccountPrefix)
+		}
+
+		uniqueAddress, err := indexTxAddresses(dbTransaction, txs, involved)
+		if err != nil {
+			return err
+		}
+
+		// pull txes and insert them
+		uniqueTxes := make(map[string]models.Tx)
+		for _, tx := range txs {
+			tx.Tx.BlockID = block.ID
+			tx.Tx.Block = block
+			uniqueTxes[tx.Tx.Hash] = tx.Tx
+		}
+
+		var txesSlice []models.Tx
+		for _, tx := range uniqueTxes {
 			txesSlice = append(txesSlice, tx)
 		}
 
 		if len(txesSlice) != 0 {
 			if err := dbTransaction.Clauses(clause.OnConflict{
 				Columns:   []clause.Column{{Name: "hash"}},
-				DoUpdates: clause.AssignmentColumns([]string{"code", "block_id"}),
+				DoUpdates: clause.AssignmentColumns([]string{"code", "block_id", "memo"}),
 			}).Create(txesSlice).Error; err != nil {
 				config.Log.Error("Error getting/creating txes.", err)
 				return err
@@ -318,9 +565,17 @@ func IndexNewBlock(db *gorm.DB, block models.Block, txs []TxDBWrapper, indexerCo
 
 		// This complex set of loops is to ensure that foreign key relations are created and attached to downstream models before batch insertion is executed.
 		// We are trading off in-app performance for batch insertion here and should consider complexity increase vs performance increase.
+		txIDs := make([]uint, 0, len(txs))
+		var failedMessages []models.FailedMessage
 		for txIndex, tx := range txs {
 			tx.Tx = uniqueTxes[tx.Tx.Hash]
 			txs[txIndex].Tx = tx.Tx
+			txIDs = append(txIDs, tx.Tx.ID)
+			for _, failedMessage := range tx.FailedMessages {
+				failedMessage.TxID = tx.Tx.ID
+				failedMessages = append(failedMessages, failedMessage)
+			}
+
 			var messagesSlice []*models.Message
 			for messageIndex := range tx.Messages {
 				tx.Messages[messageIndex].Message.TxID = tx.Tx.ID
@@ -390,7 +645,7 @@ func IndexNewBlock(db *gorm.DB, block models.Block, txs []TxDBWrapper, indexerCo
 			if len(messagesEventsAttributesSlice) != 0 {
 				if err := dbTransaction.Clauses(clause.OnConflict{
 					Columns:   []clause.Column{{Name: "message_event_id"}, {Name: "index"}},
-					DoUpdates: clause.AssignmentColumns([]string{"value", "message_event_attribute_key_id"}),
+					DoUpdates: clause.AssignmentColumns([]string{"value", "compressed_value", "message_event_attribute_key_id"}),
 				}).Create(messagesEventsAttributesSlice).Error; err != nil {
 					config.Log.Error("Error getting/creating message event attributes.", err)
 					return err
@@ -398,9 +653,57 @@ func IndexNewBlock(db *gorm.DB, block models.Block, txs []TxDBWrapper, indexerCo
 			}
 		}
 
-		return nil
+		if err := indexFailedMessages(dbTransaction, txIDs, failedMessages); err != nil {
+			config.Log.Error("Error creating failed messages.", err)
+			return err
+		}
+
+		if indexerConfig.Coins.Enabled {
+			if err := indexMessageEventAttributeCoins(dbTransaction, txs, txIDs, indexerConfig.Coins.AttributeKeys); err != nil {
+				config.Log.Error("Error indexing the coins of the message event attributes.", err)
+				return err
+			}
+		}
+
+		if indexerConfig.Search.Enabled && len(indexerConfig.Search.AttributeKeys) != 0 {
+			if err := indexMessageEventSearch(dbTransaction, txs, txIDs, indexerConfig.Search.AttributeKeys); err != nil {
+				config.Log.Error("Error indexing the search documents of the message events.", err)
+				return err
+			}
+		}
+
+		if indexerConfig.Flags.IndexAddressInvolvements {
+			if err := indexAddressInvolvements(dbTransaction, block, txs, txIDs, involved, uniqueAddress); err != nil {
+				config.Log.Error("Error indexing the address involvements of the txes.", err)
+				return err
+			}
+		}
+
+		if err := indexCustomMessages(dbTransaction, indexerConfig, txs, messageParserTrackers, true); err != nil {
+			return err
+		}
+
+		if indexerConfig.Ledger.Enabled {
+			if err := projectTxLedger(dbTransaction, &block, indexerConfig.Ledger.FeeAccount, indexerConfig.Ledger.Rollups); err != nil {
+				config.Log.Error("Error projecting the ledger entries of the txes.", err)
+				return err
+			}
+		}
+
+		if indexerConfig.Database.TimescaleDB {
+			if err := replaceFeePoints(dbTransaction, &block); err != nil {
+				config.Log.Error("Error replacing the fee points of the block.", err)
+				return err
+			}
+		}
+
+		return emitCDCEvent(dbTransaction, indexerConfig, newTxsCDCEvent(indexerConfig, block, txs))
 	})
 
+	if err == nil {
+		dictionaries.storeTxs(block, txs)
+	}
+
 	// Contract: ensure that block and txs have been loaded with the indexed data before returning
 	return block, txs, err
 }
@@ -415,6 +718,11 @@ func indexMessageTypes(db *gorm.DB, txs []TxDBWrapper) (map[string]models.Messag
 
 	var messageTypesSlice []models.MessageType
 	for _, messageType := range fullUniqueBlockMessageTypes {
+		if id, ok := dictionaries.lookup(messageTypeDictionary, messageType.MessageType); ok {
+			messageType.ID = id
+			fullUniqueBlockMessageTypes[messageType.MessageType] = messageType
+			continue
+		}
 		messageTypesSlice = append(messageTypesSlice, messageType)
 	}
 
@@ -446,6 +754,11 @@ func indexMessageEventTypes(db *gorm.DB, txs []TxDBWrapper) (map[string]models.M
 
 	var messageTypesSlice []models.MessageEventType
 	for _, messageType := range fullUniqueBlockMessageEventTypes {
+		if id, ok := dictionaries.lookup(messageEventTypeDictionary, messageType.Type); ok {
+			messageType.ID = id
+			fullUniqueBlockMessageEventTypes[messageType.Type] = messageType
+			continue
+		}
 		messageTypesSlice = append(messageTypesSlice, messageType)
 	}
 
@@ -477,6 +790,11 @@ func indexMessageEventAttributeKeys(db *gorm.DB, txs []TxDBWrapper) (map[string]
 
 	var messageEventAttributeKeysSlice []models.MessageEventAttributeKey
 	for _, messageEventAttributeKey := range fullUniqueMessageEventAttributeKeys {
+		if id, ok := dictionaries.lookup(messageEventAttributeKeyDictionary, messageEventAttributeKey.Key); ok {
+			messageEventAttributeKey.ID = id
+			fullUniqueMessageEventAttributeKeys[messageEventAttributeKey.Key] = messageEventAttributeKey
+			continue
+		}
 		messageEventAttributeKeysSlice = append(messageEventAttributeKeysSlice, messageEventAttributeKey)
 	}
 
@@ -497,47 +815,170 @@ func indexMessageEventAttributeKeys(db *gorm.DB, txs []TxDBWrapper) (map[string]
 	return fullUniqueMessageEventAttributeKeys, nil
 }
 
-func IndexCustomMessages(conf config.IndexConfig, db *gorm.DB, dryRun bool, blockDBWrapper []TxDBWrapper, messageParserTrackers map[string]models.MessageParser) error {
-	return db.Transaction(func(dbTransaction *gorm.DB) error {
-		for _, tx := range blockDBWrapper {
-			for _, message := range tx.Messages {
-				if len(message.MessageParsedDatasets) != 0 {
-					for _, parsedData := range message.MessageParsedDatasets {
+// IndexCustomMessages runs the custom message parsers that opted out of indexing atomically with the block, then marks
+// the custom parsers of the block as applied
+func IndexCustomMessages(conf config.IndexConfig, db *gorm.DB, dryRun bool, block *models.Block, blockDBWrapper []TxDBWrapper, messageParserTrackers map[string]models.MessageParser) error {
+	return transaction(db, func(dbTransaction *gorm.DB) error {
+		if err := indexCustomMessages(dbTransaction, conf, blockDBWrapper, messageParserTrackers, false); err != nil {
+			return err
+		}
+		return markCustomParsersApplied(dbTransaction, block)
+	})
+}
 
-						// Pre clear old errors
-						if parsedData.Parser != nil {
-							err := DeleteCustomMessageParserError(db, message.Message, messageParserTrackers[(*parsedData.Parser).Identifier()])
-							if err != nil {
-								config.Log.Error("Error clearing block event error.", err)
-								return err
-							}
+// IndexBlockCommit stores the commit included in the block, replacing its signatures if the block is indexed again
+func IndexBlockCommit(db *gorm.DB, block models.Block, commit *BlockCommitDBWrapper) error {
+	return transaction(db, func(dbTransaction *gorm.DB) error {
+		commit.Commit.BlockID = block.ID
+		if err := dbTransaction.
+			Where(models.BlockCommit{BlockID: block.ID}).
+			Assign(models.BlockCommit{Height: commit.Commit.Height, Round: commit.Commit.Round}).
+			FirstOrCreate(&commit.Commit).Error; err != nil {
+			config.Log.Error("Error getting/creating block commit DB object.", err)
+			return err
+		}
+
+		if err := dbTransaction.Where("block_commit_id = ?", commit.Commit.ID).Delete(&models.BlockCommitSignature{}).Error; err != nil {
+			config.Log.Error("Error clearing block commit signatures.", err)
+			return err
+		}
+		if len(commit.Signatures) == 0 {
+			return nil
+		}
+
+		for i := range commit.Signatures {
+			signature := &commit.Signatures[i]
+			signature.BlockCommitID = commit.Commit.ID
+			if signature.ValidatorAddress == nil {
+				continue
+			}
+			address, err := FindOrCreateAddressByAddress(dbTransaction, signature.ValidatorAddress.Address)
+			if err != nil {
+				config.Log.Error("Error getting/creating validator address DB object.", err)
+				return err
+			}
+			signature.ValidatorAddressID = &address.ID
+			signature.ValidatorAddress = &address
+		}
+
+		return dbTransaction.Omit("BlockCommit", "ValidatorAddress").Create(&commit.Signatures).Error
+	})
+}
+
+// IndexBlockEvidence stores the evidence included in the block with its offenders, replacing the evidence stored if the
+// block is indexed again
+func IndexBlockEvidence(db *gorm.DB, block models.Block, evidence []BlockEvidenceDBWrapper) error {
+	return transaction(db, func(dbTransaction *gorm.DB) error {
+		if err := dbTransaction.Where("block_evidence_id IN (?)", dbTransaction.Model(&models.BlockEvidence{}).Select("id").Where("block_id = ?", block.ID)).Delete(&models.EvidenceOffender{}).Error; err != nil {
+			config.Log.Error("Error clearing evidence offenders.", err)
+			return err
+		}
+		if err := dbTransaction.Where("block_id = ?", block.ID).Delete(&models.BlockEvidence{}).Error; err != nil {
+			config.Log.Error("Error clearing block evidence.", err)
+			return err
+		}
+
+		for i := range evidence {
+			evidence[i].Evidence.BlockID = block.ID
+			if err := dbTransaction.Omit("Block").Create(&evidence[i].Evidence).Error; err != nil {
+				config.Log.Error("Error creating block evidence DB object.", err)
+				return err
+			}
+
+			for j := range evidence[i].Offenders {
+				offender := &evidence[i].Offenders[j]
+				address, err := FindOrCreateAddressByAddress(dbTransaction, offender.ValidatorAddress.Address)
+				if err != nil {
+					config.Log.Error("Error getting/creating validator address DB object.", err)
+					return err
+				}
+				offender.BlockEvidenceID = evidence[i].Evidence.ID
+				offender.ValidatorAddressID = address.ID
+				offender.ValidatorAddress = address
+			}
+			if len(evidence[i].Offenders) == 0 {
+				continue
+			}
+			if err := dbTransaction.Omit("BlockEvidence", "ValidatorAddress").Create(&evidence[i].Offenders).Error; err != nil {
+				config.Log.Error("Error creating evidence offender DB objects.", err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func indexCustomMessages(dbTransaction *gorm.DB, conf config.IndexConfig, blockDBWrapper []TxDBWrapper, messageParserTrackers map[string]models.MessageParser, atomic bool) error {
+	for _, tx := range blockDBWrapper {
+		for _, message := range tx.Messages {
+			if len(message.MessageParsedDatasets) != 0 {
+				for _, parsedData := range message.MessageParsedDatasets {
+					if parsedData.Parser == nil || parsers.IndexesAtomically(*parsedData.Parser) != atomic {
+						continue
+					}
+
+					// Pre clear old errors
+					if parsedData.Parser != nil {
+						err := DeleteCustomMessageParserError(dbTransaction, message.Message, messageParserTrackers[(*parsedData.Parser).Identifier()])
+						if err != nil {
+							config.Log.Error("Error clearing block event error.", err)
+							return err
 						}
+					}
 
-						if parsedData.Error == nil && parsedData.Data != nil && parsedData.Parser != nil {
+					if parsedData.Error == nil && parsedData.Data != nil && parsedData.Parser != nil {
 
-							// to avoid an import cyrcle, this intermediate type was required. It may be possible to remove it by changing the belongs-to relation to a one-to-many relation on the two models.
-							combinedEventsWithAttribues := []parsers.MessageEventWithAttributes{}
-							for _, event := range message.MessageEvents {
-								attrs := event.Attributes
-								combinedEventsWithAttribues = append(combinedEventsWithAttribues, parsers.MessageEventWithAttributes{Event: event.MessageEvent, Attributes: attrs})
-							}
-							err := (*parsedData.Parser).IndexMessage(parsedData.Data, dbTransaction, message.Message, combinedEventsWithAttribues, conf)
-							if err != nil {
+						// to avoid an import cyrcle, this intermediate type was required. It may be possible to remove it by changing the belongs-to relation to a one-to-many relation on the two models.
+						combinedEventsWithAttribues := []parsers.MessageEventWithAttributes{}
+						for _, event := range message.MessageEvents {
+							attrs := event.Attributes
+							combinedEventsWithAttribues = append(combinedEventsWithAttribues, parsers.MessageEventWithAttributes{Event: event.MessageEvent, Attributes: attrs})
+						}
+						identifier := (*parsedData.Parser).Identifier()
+						countedTransaction, rowsWritten := withRowCounter(dbTransaction)
+						start := time.Now()
+						// Run each parser in a savepoint so a panicking parser only rolls back its own writes
+						err := countedTransaction.Transaction(func(parserTransaction *gorm.DB) error {
+							return parsers.RecoverParser(func() error {
+								if contextualParser, ok := (*parsedData.Parser).(parsers.ContextualMessageParser); ok {
+									return contextualParser.IndexMessageWithContext(parsedData.Data, parsers.MessageIndexContext{
+										Config:        conf,
+										DB:            parserTransaction,
+										Tx:            tx.Tx,
+										Message:       message.Message,
+										MessageEvents: combinedEventsWithAttribues,
+									})
+								}
+								return (*parsedData.Parser).IndexMessage(parsedData.Data, parserTransaction, message.Message, combinedEventsWithAttribues, conf)
+							})
+						})
+						metrics.ObserveParserExecution(identifier, metrics.ParserStageIndex, time.Since(start), err)
+						if err != nil {
+							if !parsers.IsIsolatedFailure(err) {
 								config.Log.Error("Error indexing message.", err)
 								return err
 							}
-						} else if parsedData.Error != nil {
-							err := CreateMessageParserError(db, message.Message, messageParserTrackers[(*parsedData.Parser).Identifier()], parsedData.Error)
+
+							config.Log.Errorf("Custom parser %s failed indexing message: %v", identifier, err)
+							err = CreateMessageParserError(dbTransaction, message.Message, messageParserTrackers[identifier], err)
 							if err != nil {
 								config.Log.Error("Error inserting message parser error.", err)
 								return err
 							}
+							continue
+						}
+						addParserRows(dbTransaction, identifier, *rowsWritten)
+					} else if parsedData.Error != nil {
+						err := CreateMessageParserError(dbTransaction, message.Message, messageParserTrackers[(*parsedData.Parser).Identifier()], parsedData.Error)
+						if err != nil {
+							config.Log.Error("Error inserting message parser error.", err)
+							return err
 						}
 					}
 				}
 			}
 		}
+	}
 
-		return nil
-	})
+	return nil
 }
@@ -1,14 +1,25 @@
 package db
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/DefiantLabs/cosmos-indexer/config"
 	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
+	"github.com/DefiantLabs/cosmos-indexer/parsers"
+	abci "github.com/cometbft/cometbft/abci/types"
 	"github.com/ory/dockertest/v3"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 // TODO: Optimize tests to use a single database instance, clean database after each test, and teardown database after all tests are done
@@ -77,7 +88,7 @@ func SetupTestDatabase() (func(), *gorm.DB, error) {
 	var db *gorm.DB
 	if err := pool.Retry(func() error {
 		var err error
-		db, err = PostgresDbConnect(resource.GetBoundIP("5432/tcp"), resource.GetPort("5432/tcp"), "test", "test", "test", "debug")
+		db, err = PostgresDbConnect(resource.GetBoundIP("5432/tcp"), resource.GetPort("5432/tcp"), "test", "test", "test", "debug", ConnectOptions{})
 		if err != nil {
 			return err
 		}
@@ -158,6 +169,1232 @@ func (suite *DBTestSuite) TestGetHighestBlockFunctions() {
 	suite.Assert().Equal(block3.Height, eventBlock.Height)
 }
 
+func (suite *DBTestSuite) TestMaterializedViews() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	view := config.MaterializedView{
+		Name:          "block_counts",
+		Query:         "SELECT chain_id, count(*) AS blocks FROM blocks GROUP BY chain_id",
+		UniqueColumns: []string{"chain_id"},
+		RefreshBlocks: 1,
+	}
+
+	err = CreateMaterializedViews(suite.db, []config.MaterializedView{view})
+	suite.Require().NoError(err)
+
+	chain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&chain).Error)
+	suite.Require().NoError(suite.db.Create(&models.Block{Height: 1, ChainID: chain.ID, TimeStamp: time.Now()}).Error)
+
+	err = RefreshMaterializedView(suite.db, view)
+	suite.Require().NoError(err)
+
+	var blocks int64
+	suite.Require().NoError(suite.db.Raw("SELECT blocks FROM block_counts WHERE chain_id = ?", chain.ID).Scan(&blocks).Error)
+	suite.Require().Equal(int64(1), blocks)
+
+	// A changed definition replaces the view
+	view.Query = "SELECT chain_id, max(height) AS blocks FROM blocks GROUP BY chain_id"
+	err = CreateMaterializedViews(suite.db, []config.MaterializedView{view})
+	suite.Require().NoError(err)
+}
+
+func (suite *DBTestSuite) TestRecommendIndexes() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.db.Create(&models.BlockEventType{Type: "transfer"}).Error)
+
+	targets := TuneTargets{BlockEventTypes: []string{"transfer", "unknown"}}
+	recommendations, err := RecommendIndexes(suite.db, targets)
+	suite.Require().NoError(err)
+
+	var names []string
+	for _, recommendation := range recommendations {
+		names = append(names, recommendation.Name)
+		suite.Require().NoError(ApplyIndexRecommendation(suite.db, recommendation))
+	}
+	suite.Require().Contains(names, "tune_txes_block_id")
+	suite.Require().Contains(names, "tune_block_events_type_1")
+
+	// Applied indexes are not recommended again
+	recommendations, err = RecommendIndexes(suite.db, targets)
+	suite.Require().NoError(err)
+	suite.Require().Empty(recommendations)
+}
+
+func (suite *DBTestSuite) TestDenomDisplays() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(UpsertDenomDisplays(suite.db, []config.DenomDisplay{{Base: "uatom", Display: "atom", Exponent: 3}}))
+	// Later units replace the earlier ones
+	suite.Require().NoError(UpsertDenomDisplays(suite.db, []config.DenomDisplay{{Base: "uatom", Display: "atom", Exponent: 6}}))
+	suite.Require().NoError(CreateDisplayViews(suite.db))
+	suite.Require().NoError(CreateDisplayViews(suite.db))
+
+	uatom, err := FindOrCreateDenomByBase(suite.db, "uatom")
+	suite.Require().NoError(err)
+	unknown, err := FindOrCreateDenomByBase(suite.db, "unknown")
+	suite.Require().NoError(err)
+
+	var amount decimal.NullDecimal
+	suite.Require().NoError(suite.db.Raw("SELECT display_amount(?, 1500000)", uatom.ID).Scan(&amount).Error)
+	suite.Require().True(amount.Valid)
+	suite.Require().Equal("1.5", amount.Decimal.String())
+
+	var display *string
+	suite.Require().NoError(suite.db.Raw("SELECT display_denom(?)", uatom.ID).Scan(&display).Error)
+	suite.Require().Equal("atom", *display)
+
+	suite.Require().NoError(suite.db.Raw("SELECT display_amount(?, 1500000)", unknown.ID).Scan(&amount).Error)
+	suite.Require().False(amount.Valid)
+}
+
+func (suite *DBTestSuite) TestUpsertFailedBlock() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	timeout := models.BlockFailure{ErrorCode: models.FailureRPCTimeout, ErrorMessage: "context deadline exceeded", Component: models.ComponentRPCWorker}
+	suite.Require().NoError(UpsertFailedBlock(suite.db, 10, "testchain-1", "testchain", timeout))
+
+	var failedBlock models.FailedBlock
+	suite.Require().NoError(suite.db.Where("height = ?", 10).First(&failedBlock).Error)
+	suite.Require().Equal(timeout, failedBlock.BlockFailure)
+
+	// A block failing again has its failure replaced and the retry counted
+	unknownMsg := models.BlockFailure{ErrorCode: models.FailureUnknownMsgType, ErrorMessage: "unknown message type", Component: models.ComponentProcessor}
+	suite.Require().NoError(UpsertFailedBlock(suite.db, 10, "testchain-1", "testchain", unknownMsg))
+
+	failedBlock = models.FailedBlock{}
+	suite.Require().NoError(suite.db.Where("height = ?", 10).First(&failedBlock).Error)
+	unknownMsg.RetryCount = 1
+	suite.Require().Equal(unknownMsg, failedBlock.BlockFailure)
+
+	var count int64
+	suite.Require().NoError(suite.db.Model(&models.FailedBlock{}).Count(&count).Error)
+	suite.Require().Equal(int64(1), count)
+}
+
+func (suite *DBTestSuite) TestIndexFailedTxes() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	block := models.Block{Height: 1, ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: time.Now()}
+	failedTx := models.FailedTx{Hash: "ABCD", ErrorCode: models.FailureUnknownMsgType, ErrorMessage: "unknown message type", Raw: []byte{1, 2, 3}}
+	indexedBlock, _, err := IndexNewBlock(suite.db, block, nil, []models.FailedTx{failedTx}, config.IndexConfig{}, nil)
+	suite.Require().NoError(err)
+	suite.Require().True(indexedBlock.TxIndexed)
+
+	var failedTxes []models.FailedTx
+	suite.Require().NoError(suite.db.Find(&failedTxes).Error)
+	suite.Require().Len(failedTxes, 1)
+	suite.Require().Equal(indexedBlock.ID, failedTxes[0].BlockID)
+	suite.Require().Equal(failedTx.Raw, failedTxes[0].Raw)
+	suite.Require().Equal(models.FailureUnknownMsgType, failedTxes[0].ErrorCode)
+
+	// Reindexing the block without failures drops the failed txes of the earlier attempt
+	_, _, err = IndexNewBlock(suite.db, block, nil, nil, config.IndexConfig{}, nil)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.Find(&failedTxes).Error)
+	suite.Require().Empty(failedTxes)
+}
+
+func (suite *DBTestSuite) TestRedactAddress() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	const address = "cosmos1redactme"
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	block := models.Block{Height: 1, ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: address}, TimeStamp: time.Now()}
+	failedTx := models.FailedTx{Hash: "ABCD", ErrorMessage: "unknown message sent by " + address, Raw: []byte("from " + address)}
+	_, _, err = IndexNewBlock(suite.db, block, nil, []models.FailedTx{failedTx}, config.IndexConfig{}, nil)
+	suite.Require().NoError(err)
+
+	// A large attribute value is stored compressed
+	models.SetCompression(64)
+	defer models.SetCompression(0)
+	large := strings.Repeat(`{"airdrop":"`+address+`"}`, 10)
+	wrapper := &BlockDBWrapper{
+		Block: &block,
+		BeginBlockEvents: []BlockEventDBWrapper{{
+			BlockEvent: models.BlockEvent{Index: 0, LifecyclePosition: models.BeginBlockEvent, BlockEventType: models.BlockEventType{Type: "wasm"}},
+			Attributes: []models.BlockEventAttribute{{Index: 0, Value: large, BlockEventAttributeKey: models.BlockEventAttributeKey{Key: "msg"}}},
+		}},
+		UniqueBlockEventTypes:         map[string]models.BlockEventType{"wasm": {Type: "wasm"}},
+		UniqueBlockEventAttributeKeys: map[string]models.BlockEventAttributeKey{"msg": {Key: "msg"}},
+	}
+	_, err = IndexBlockEvents(suite.db, false, wrapper, "", config.IndexConfig{}, nil, nil)
+	suite.Require().NoError(err)
+
+	// Without apply the rows are only reported
+	redactions, err := RedactAddress(suite.db, address, false)
+	suite.Require().NoError(err)
+	suite.Require().ElementsMatch([]AddressRedaction{
+		{Table: "addresses", Column: "address", Rows: 1},
+		{Table: "failed_txes", Column: "error_message", Rows: 1},
+		{Table: "failed_txes", Column: "raw", Rows: 1},
+		{Table: "block_event_attributes", Column: "compressed_value", Rows: 1},
+	}, redactions)
+
+	var count int64
+	suite.Require().NoError(suite.db.Model(&models.Address{}).Where("address = ?", address).Count(&count).Error)
+	suite.Require().Equal(int64(1), count)
+
+	redactions, err = RedactAddress(suite.db, address, true)
+	suite.Require().NoError(err)
+	suite.Require().Len(redactions, 4)
+
+	var failedTxes []models.FailedTx
+	suite.Require().NoError(suite.db.Find(&failedTxes).Error)
+	suite.Require().Len(failedTxes, 1)
+	suite.Require().Equal("unknown message sent by [redacted]", failedTxes[0].ErrorMessage)
+	suite.Require().Nil(failedTxes[0].Raw)
+
+	var attributes []models.BlockEventAttribute
+	suite.Require().NoError(suite.db.Find(&attributes).Error)
+	suite.Require().Len(attributes, 1)
+	suite.Require().Equal(strings.ReplaceAll(large, address, redactedMarker), attributes[0].Value)
+
+	suite.Require().NoError(suite.db.Model(&models.Address{}).Where("address = ?", address).Count(&count).Error)
+	suite.Require().Zero(count)
+
+	redactions, err = RedactAddress(suite.db, address, false)
+	suite.Require().NoError(err)
+	suite.Require().Empty(redactions)
+}
+
+func (suite *DBTestSuite) TestLedgerProjection() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	attribute := func(index uint64, key string, value string) models.BlockEventAttribute {
+		return models.BlockEventAttribute{Index: index, Value: value, BlockEventAttributeKey: models.BlockEventAttributeKey{Key: key}}
+	}
+	// Two transfers merged into one event, as older SDK versions emitted them
+	wrapper := &BlockDBWrapper{
+		Block: &models.Block{Height: 1, ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: time.Now()},
+		BeginBlockEvents: []BlockEventDBWrapper{{
+			BlockEvent: models.BlockEvent{Index: 0, LifecyclePosition: models.BeginBlockEvent, BlockEventType: models.BlockEventType{Type: "transfer"}},
+			Attributes: []models.BlockEventAttribute{
+				attribute(0, "recipient", "cosmos1feecollector"),
+				attribute(1, "sender", "cosmos1mint"),
+				attribute(2, "amount", "100uatom"),
+				attribute(3, "recipient", "cosmos1distribution"),
+				attribute(4, "sender", "cosmos1feecollector"),
+				attribute(5, "amount", "60uatom,5uosmo"),
+			},
+		}},
+		UniqueBlockEventTypes: map[string]models.BlockEventType{"transfer": {Type: "transfer"}},
+		UniqueBlockEventAttributeKeys: map[string]models.BlockEventAttributeKey{
+			"recipient": {Key: "recipient"}, "sender": {Key: "sender"}, "amount": {Key: "amount"},
+		},
+	}
+
+	conf := config.IndexConfig{}
+	conf.Ledger.Enabled = true
+	conf.Ledger.FeeAccount = "fee_collector"
+	conf.Ledger.Rollups = true
+	_, err = IndexBlockEvents(suite.db, false, wrapper, "", conf, nil, nil)
+	suite.Require().NoError(err)
+
+	var entries []models.LedgerEntry
+	suite.Require().NoError(suite.db.Preload("Denom").Order("id").Find(&entries).Error)
+	suite.Require().Len(entries, 6)
+	suite.Require().Equal(models.LedgerCredit, entries[0].Side)
+	suite.Require().Equal("cosmos1mint", entries[0].Account)
+	suite.Require().Equal("cosmos1feecollector", entries[0].Counterparty)
+	suite.Require().Equal(models.LedgerDebit, entries[1].Side)
+	suite.Require().Equal("cosmos1feecollector", entries[1].Account)
+	suite.Require().Equal("begin_block:0", entries[1].Reference)
+	suite.Require().Equal("uosmo", entries[5].Denom.Base)
+
+	// Debits and credits balance per denom
+	balances := map[string]int64{}
+	for _, entry := range entries {
+		if entry.Side == models.LedgerDebit {
+			balances[entry.Denom.Base] += entry.Amount.IntPart()
+		} else {
+			balances[entry.Denom.Base] -= entry.Amount.IntPart()
+		}
+	}
+	suite.Require().Equal(map[string]int64{"uatom": 0, "uosmo": 0}, balances)
+
+	// Projecting the block again replaces its entries and its share of the rollups
+	suite.Require().NoError(projectBlockEventLedger(suite.db, wrapper.Block, true))
+	var count int64
+	suite.Require().NoError(suite.db.Model(&models.LedgerEntry{}).Count(&count).Error)
+	suite.Require().Equal(int64(6), count)
+
+	var uatom models.Denom
+	suite.Require().NoError(suite.db.Where("base = ?", "uatom").First(&uatom).Error)
+	var activity models.AddressActivity
+	suite.Require().NoError(suite.db.Where("account = ? AND denom_id = ?", "cosmos1feecollector", uatom.ID).First(&activity).Error)
+	suite.Require().Equal("100", activity.VolumeIn.String())
+	suite.Require().Equal("60", activity.VolumeOut.String())
+
+	var summary models.AddressSummary
+	suite.Require().NoError(suite.db.Where("account = ?", "cosmos1distribution").First(&summary).Error)
+	suite.Require().Equal(int64(1), summary.FirstHeight)
+	suite.Require().Equal(int64(1), summary.LastHeight)
+	suite.Require().Zero(summary.TxCount)
+}
+
+func (suite *DBTestSuite) TestAttributeCoins() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	attribute := func(index uint64, key string, value string) models.BlockEventAttribute {
+		return models.BlockEventAttribute{Index: index, Value: value, BlockEventAttributeKey: models.BlockEventAttributeKey{Key: key}}
+	}
+	wrapper := &BlockDBWrapper{
+		Block: &models.Block{Height: 1, ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: time.Now()},
+		EndBlockEvents: []BlockEventDBWrapper{{
+			BlockEvent: models.BlockEvent{Index: 0, LifecyclePosition: models.EndBlockEvent, BlockEventType: models.BlockEventType{Type: "transfer"}},
+			Attributes: []models.BlockEventAttribute{
+				attribute(0, "recipient", "cosmos1distribution"),
+				attribute(1, "amount", "60uatom,5uosmo"),
+			},
+		}},
+		UniqueBlockEventTypes:         map[string]models.BlockEventType{"transfer": {Type: "transfer"}},
+		UniqueBlockEventAttributeKeys: map[string]models.BlockEventAttributeKey{"recipient": {Key: "recipient"}, "amount": {Key: "amount"}},
+	}
+
+	conf := config.IndexConfig{}
+	conf.Coins.Enabled = true
+	conf.Coins.AttributeKeys = []string{"amount"}
+	_, err = IndexBlockEvents(suite.db, false, wrapper, "", conf, nil, nil)
+	suite.Require().NoError(err)
+
+	// A multi-coin value is exploded into a row per coin, linked to its attribute and event
+	var coins []models.BlockEventAttributeCoin
+	suite.Require().NoError(suite.db.Preload("Denom").Order("position").Find(&coins).Error)
+	suite.Require().Len(coins, 2)
+	suite.Require().Equal(wrapper.EndBlockEvents[0].BlockEvent.ID, coins[0].BlockEventID)
+	suite.Require().Equal(wrapper.EndBlockEvents[0].Attributes[1].ID, coins[0].BlockEventAttributeID)
+	suite.Require().Equal("uatom", coins[0].Denom.Base)
+	suite.Require().Equal("60", coins[0].Amount.String())
+	suite.Require().Equal(uint(1), coins[1].Position)
+	suite.Require().Equal("uosmo", coins[1].Denom.Base)
+
+	// Indexing the coins again replaces them
+	suite.Require().NoError(indexBlockEventAttributeCoins(suite.db, wrapper, conf.Coins.AttributeKeys))
+	var count int64
+	suite.Require().NoError(suite.db.Model(&models.BlockEventAttributeCoin{}).Count(&count).Error)
+	suite.Require().Equal(int64(2), count)
+}
+
+func (suite *DBTestSuite) TestBlockMarkers() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	block := models.Block{Height: 1, ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: time.Now()}
+	indexedBlock, _, err := IndexNewBlock(suite.db, block, nil, nil, config.IndexConfig{}, nil)
+	suite.Require().NoError(err)
+	suite.Require().False(indexedBlock.CustomParsersApplied)
+
+	suite.Require().NoError(IndexCustomMessages(config.IndexConfig{}, suite.db, false, &indexedBlock, nil, nil))
+	var stored models.Block
+	suite.Require().NoError(suite.db.First(&stored, indexedBlock.ID).Error)
+	suite.Require().True(stored.CustomParsersApplied)
+	suite.Require().True(stored.TxIndexed)
+
+	// Indexing the block again resets the markers that do not hold for the new data
+	suite.Require().NoError(suite.db.Model(&stored).Update("verified", true).Error)
+	_, _, err = IndexNewBlock(suite.db, block, nil, nil, config.IndexConfig{}, nil)
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.db.First(&stored, indexedBlock.ID).Error)
+	suite.Require().False(stored.CustomParsersApplied)
+	suite.Require().False(stored.Verified)
+}
+
+func (suite *DBTestSuite) TestBlockEventMarkers() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	wrapper := func() *BlockDBWrapper {
+		return &BlockDBWrapper{
+			Block: &models.Block{Height: 3, ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: time.Now()},
+			BeginBlockEvents: []BlockEventDBWrapper{{
+				BlockEvent: models.BlockEvent{Index: 0, LifecyclePosition: models.BeginBlockEvent, BlockEventType: models.BlockEventType{Type: "mint"}},
+				Attributes: []models.BlockEventAttribute{{Index: 0, Value: "100umint", BlockEventAttributeKey: models.BlockEventAttributeKey{Key: "amount"}}},
+			}},
+			UniqueBlockEventTypes:         map[string]models.BlockEventType{"mint": {Type: "mint"}},
+			UniqueBlockEventAttributeKeys: map[string]models.BlockEventAttributeKey{"amount": {Key: "amount"}},
+		}
+	}
+
+	indexed, err := IndexBlockEvents(suite.db, false, wrapper(), "", config.IndexConfig{}, nil, nil)
+	suite.Require().NoError(err)
+	suite.Require().False(indexed.Block.CustomParsersApplied)
+
+	suite.Require().NoError(IndexCustomBlockEvents(config.IndexConfig{}, suite.db, false, indexed, "", nil, nil))
+	suite.Require().True(indexed.Block.CustomParsersApplied)
+	var stored models.Block
+	suite.Require().NoError(suite.db.First(&stored, indexed.Block.ID).Error)
+	suite.Require().True(stored.CustomParsersApplied)
+	suite.Require().True(stored.BlockEventsIndexed)
+
+	// Indexing the block events again resets the markers that do not hold for the new data
+	suite.Require().NoError(suite.db.Model(&stored).Update("verified", true).Error)
+	reindexed, err := IndexBlockEvents(suite.db, false, wrapper(), "", config.IndexConfig{}, nil, nil)
+	suite.Require().NoError(err)
+	suite.Require().Equal(stored.ID, reindexed.Block.ID)
+	suite.Require().NoError(suite.db.First(&stored, indexed.Block.ID).Error)
+	suite.Require().False(stored.CustomParsersApplied)
+	suite.Require().False(stored.Verified)
+	suite.Require().True(stored.BlockEventsIndexed)
+}
+
+func (suite *DBTestSuite) TestIndexBlockCommit() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	block := models.Block{Height: 2, ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: time.Now()}
+	indexedBlock, _, err := IndexNewBlock(suite.db, block, nil, nil, config.IndexConfig{}, nil)
+	suite.Require().NoError(err)
+
+	signedAt := time.Now().UTC().Truncate(time.Second)
+	commit := func(round int32) *BlockCommitDBWrapper {
+		return &BlockCommitDBWrapper{
+			Commit: models.BlockCommit{Height: 1, Round: round},
+			Signatures: []models.BlockCommitSignature{
+				{Index: 0, ValidatorAddress: &models.Address{Address: "testchainaddress"}, Flag: models.BlockIDFlagCommit, Timestamp: &signedAt},
+				{Index: 1, Flag: models.BlockIDFlagAbsent},
+			},
+		}
+	}
+	suite.Require().NoError(IndexBlockCommit(suite.db, indexedBlock, commit(0)))
+
+	var signatures []models.BlockCommitSignature
+	suite.Require().NoError(suite.db.Order("index").Find(&signatures).Error)
+	suite.Require().Len(signatures, 2)
+	suite.Require().Equal(indexedBlock.ProposerConsAddressID, *signatures[0].ValidatorAddressID)
+	suite.Require().True(signedAt.Equal(*signatures[0].Timestamp))
+	suite.Require().Nil(signatures[1].ValidatorAddressID)
+	suite.Require().Nil(signatures[1].Timestamp)
+
+	// Indexing the block again replaces its commit
+	suite.Require().NoError(IndexBlockCommit(suite.db, indexedBlock, commit(2)))
+	var commits []models.BlockCommit
+	suite.Require().NoError(suite.db.Find(&commits).Error)
+	suite.Require().Len(commits, 1)
+	suite.Require().Equal(int32(2), commits[0].Round)
+	suite.Require().NoError(suite.db.Where("block_commit_id = ?", commits[0].ID).Find(&signatures).Error)
+	suite.Require().Len(signatures, 2)
+}
+
+func (suite *DBTestSuite) TestUpsertConsensusMetrics() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	blockTime := time.Now().UTC().Truncate(time.Second)
+	var indexed []models.Block
+	for i, interval := range []time.Duration{0, 6 * time.Second} {
+		block := models.Block{Height: int64(i + 1), ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: blockTime.Add(interval)}
+		indexedBlock, _, err := IndexNewBlock(suite.db, block, nil, nil, config.IndexConfig{}, nil)
+		suite.Require().NoError(err)
+		indexed = append(indexed, indexedBlock)
+	}
+
+	// The second block commits the first in its second round with half of the validators
+	suite.Require().NoError(IndexBlockCommit(suite.db, indexed[1], &BlockCommitDBWrapper{
+		Commit: models.BlockCommit{Height: 1, Round: 1},
+		Signatures: []models.BlockCommitSignature{
+			{Index: 0, ValidatorAddress: &models.Address{Address: "testchainaddress"}, Flag: models.BlockIDFlagCommit, Timestamp: &blockTime},
+			{Index: 1, Flag: models.BlockIDFlagAbsent},
+		},
+	}))
+	suite.Require().NoError(UpsertConsensusMetrics(suite.db, indexed[1]))
+
+	var metrics []models.ConsensusMetric
+	suite.Require().NoError(suite.db.Order("height").Find(&metrics).Error)
+	suite.Require().Len(metrics, 2)
+
+	suite.Require().Nil(metrics[0].IntervalMs)
+	suite.Require().Equal(int32(2), *metrics[0].Rounds)
+	suite.Require().Equal(1, *metrics[0].SignedValidators)
+	suite.Require().Equal(2, *metrics[0].Validators)
+	suite.Require().InDelta(50, *metrics[0].ParticipationPct, 0.001)
+	suite.Require().Equal(indexed[0].ProposerConsAddressID, metrics[0].ProposerAddressID)
+
+	suite.Require().Equal(int64(6000), *metrics[1].IntervalMs)
+	suite.Require().Nil(metrics[1].Rounds)
+	suite.Require().Nil(metrics[1].ParticipationPct)
+}
+
+func (suite *DBTestSuite) TestUpsertParserExecutionMetrics() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	// The counts written by each flush are added, the percentiles replaced
+	suite.Require().NoError(UpsertParserExecutionMetrics(suite.db, "testchain-1", []metrics.ParserStats{
+		{Identifier: "test-parser", Stage: metrics.ParserStageParse, Invocations: 5, Errors: 1, P50: time.Millisecond},
+	}))
+	suite.Require().NoError(UpsertParserExecutionMetrics(suite.db, "testchain-1", []metrics.ParserStats{
+		{Identifier: "test-parser", Stage: metrics.ParserStageParse, Invocations: 3, P50: 2 * time.Millisecond},
+	}))
+	suite.Require().NoError(UpsertParserExecutionMetrics(suite.db, "testchain-2", []metrics.ParserStats{
+		{Identifier: "test-parser", Stage: metrics.ParserStageParse, Invocations: 1},
+	}))
+
+	var parserMetrics []models.ParserExecutionMetric
+	suite.Require().NoError(suite.db.Order("chain_id").Find(&parserMetrics).Error)
+	suite.Require().Len(parserMetrics, 2)
+	suite.Require().Equal("testchain-1", parserMetrics[0].ChainID)
+	suite.Require().Equal(uint64(8), parserMetrics[0].Invocations)
+	suite.Require().Equal(uint64(1), parserMetrics[0].Errors)
+	suite.Require().Equal(int64(2000), parserMetrics[0].P50Microseconds)
+	suite.Require().Equal(uint64(1), parserMetrics[1].Invocations)
+
+	// The rows a parser wrote are only counted once its transaction commits
+	writeRows := func(chainID string, rollback bool) error {
+		return transaction(suite.db, func(tx *gorm.DB) error {
+			counted, rows := withRowCounter(tx)
+			if err := counted.Create(&models.Chain{ChainID: chainID}).Error; err != nil {
+				return err
+			}
+			addParserRows(tx, "rows-parser", *rows)
+			if rollback {
+				return errors.New("rolled back")
+			}
+			return nil
+		})
+	}
+	rowsWritten := func() uint64 {
+		for _, stat := range metrics.GetParserStats() {
+			if stat.Identifier == "rows-parser" {
+				return stat.RowsWritten
+			}
+		}
+		return 0
+	}
+	suite.Require().Error(writeRows("rolledback-1", true))
+	suite.Require().Zero(rowsWritten())
+	suite.Require().NoError(writeRows("committed-1", false))
+	suite.Require().Equal(uint64(1), rowsWritten())
+}
+
+func (suite *DBTestSuite) TestIndexBlockEvidence() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	block := models.Block{Height: 10, ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: time.Now()}
+	indexedBlock, _, err := IndexNewBlock(suite.db, block, nil, nil, config.IndexConfig{}, nil)
+	suite.Require().NoError(err)
+
+	evidence := func() []BlockEvidenceDBWrapper {
+		return []BlockEvidenceDBWrapper{{
+			Evidence: models.BlockEvidence{Index: 0, Hash: "ABCD", Type: models.EvidenceDuplicateVote, Height: 8, Time: time.Now(), TotalVotingPower: 100},
+			Offenders: []models.EvidenceOffender{
+				{ValidatorAddress: models.Address{Address: "offenderaddress"}, Power: 10, Slashed: true, Jailed: true, BurnedCoins: "500uatom"},
+			},
+		}}
+	}
+	suite.Require().NoError(IndexBlockEvidence(suite.db, indexedBlock, evidence()))
+
+	var offenders []models.EvidenceOffender
+	suite.Require().NoError(suite.db.Preload("ValidatorAddress").Preload("BlockEvidence").Find(&offenders).Error)
+	suite.Require().Len(offenders, 1)
+	suite.Require().Equal("offenderaddress", offenders[0].ValidatorAddress.Address)
+	suite.Require().Equal(int64(8), offenders[0].BlockEvidence.Height)
+	suite.Require().Equal(indexedBlock.ID, offenders[0].BlockEvidence.BlockID)
+	suite.Require().Equal("500uatom", offenders[0].BurnedCoins)
+
+	// Indexing the block again replaces its evidence
+	suite.Require().NoError(IndexBlockEvidence(suite.db, indexedBlock, evidence()))
+	var count int64
+	suite.Require().NoError(suite.db.Model(&models.BlockEvidence{}).Count(&count).Error)
+	suite.Require().Equal(int64(1), count)
+	suite.Require().NoError(suite.db.Model(&models.EvidenceOffender{}).Count(&count).Error)
+	suite.Require().Equal(int64(1), count)
+}
+
+func (suite *DBTestSuite) TestIndexGenesis() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	genesis := func() *GenesisDBWrapper {
+		return &GenesisDBWrapper{
+			State: models.GenesisState{GenesisTime: time.Now(), InitialHeight: 1},
+			Balances: []models.GenesisBalance{
+				{Address: models.Address{Address: "genesisaddress"}, Denom: models.Denom{Base: "uatom"}, Amount: decimal.NewFromInt(1000)},
+			},
+			Supply:     []models.GenesisSupply{{Denom: models.Denom{Base: "uatom"}, Amount: decimal.NewFromInt(1000)}},
+			Validators: []models.GenesisValidator{{OperatorAddress: "genesisvaloper", ConsAddress: &models.Address{Address: "genesisvalcons"}, Tokens: decimal.NewFromInt(500)}},
+			Params:     []models.GenesisParam{{Module: "gov", Key: "params", Value: `{"quorum": "0.4"}`}},
+			Contracts:  []models.GenesisContract{{Address: models.Address{Address: "genesiscontract"}, CodeID: 1, Label: "pool"}},
+		}
+	}
+	suite.Require().NoError(IndexGenesis(suite.db, initChain.ID, genesis()))
+
+	var balances []models.GenesisBalance
+	suite.Require().NoError(suite.db.Preload("Address").Preload("Denom").Find(&balances).Error)
+	suite.Require().Len(balances, 1)
+	suite.Require().Equal("genesisaddress", balances[0].Address.Address)
+	suite.Require().Equal("uatom", balances[0].Denom.Base)
+
+	var validator models.GenesisValidator
+	suite.Require().NoError(suite.db.Preload("ConsAddress").First(&validator).Error)
+	suite.Require().Equal("genesisvalcons", validator.ConsAddress.Address)
+
+	// Importing the genesis again replaces the state imported before
+	suite.Require().NoError(IndexGenesis(suite.db, initChain.ID, genesis()))
+	for _, model := range []any{&models.GenesisState{}, &models.GenesisBalance{}, &models.GenesisSupply{}, &models.GenesisValidator{}, &models.GenesisParam{}, &models.GenesisContract{}} {
+		var count int64
+		suite.Require().NoError(suite.db.Model(model).Count(&count).Error)
+		suite.Require().Equal(int64(1), count)
+	}
+}
+
+func (suite *DBTestSuite) TestRollbackBlocks() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	denom := models.Denom{Base: "uatom"}
+	suite.Require().NoError(suite.db.Create(&denom).Error)
+	conf := config.IndexConfig{}
+	conf.Coins.Enabled = true
+	conf.Coins.AttributeKeys = []string{"amount"}
+	conf.Search.Enabled = true
+	conf.Search.AttributeKeys = []string{"recipient"}
+	conf.Flags.IndexAddressInvolvements = true
+	start := time.Now().Add(-time.Hour)
+	for height := int64(1); height <= 3; height++ {
+		block := models.Block{Height: height, ChainID: initChain.ID, Hash: fmt.Sprintf("HASH%d", height), ParentHash: fmt.Sprintf("HASH%d", height-1), ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: start.Add(time.Duration(height) * time.Minute)}
+		failedTx := models.FailedTx{Hash: fmt.Sprintf("TX%d", height), ErrorCode: models.FailureUnknownMsgType}
+		indexedBlock, _, err := IndexNewBlock(suite.db, block, testBlocks(initChain.ID, height, 1, 1)[0].Txs, []models.FailedTx{failedTx}, conf, nil)
+		suite.Require().NoError(err)
+		blockEvents := &BlockDBWrapper{
+			Block: &block,
+			BeginBlockEvents: []BlockEventDBWrapper{{
+				BlockEvent: models.BlockEvent{Index: 0, LifecyclePosition: models.BeginBlockEvent, BlockEventType: models.BlockEventType{Type: "mint"}},
+				Attributes: []models.BlockEventAttribute{{Index: 0, Value: "100uatom", BlockEventAttributeKey: models.BlockEventAttributeKey{Key: "amount"}}},
+			}},
+			UniqueBlockEventTypes:         map[string]models.BlockEventType{"mint": {Type: "mint"}},
+			UniqueBlockEventAttributeKeys: map[string]models.BlockEventAttributeKey{"amount": {Key: "amount"}},
+		}
+		_, err = IndexBlockEvents(suite.db, false, blockEvents, "", conf, nil, nil)
+		suite.Require().NoError(err)
+		evidence := []BlockEvidenceDBWrapper{{
+			Evidence:  models.BlockEvidence{Hash: fmt.Sprintf("EVIDENCE%d", height), Type: models.EvidenceDuplicateVote, Height: height, Time: time.Now()},
+			Offenders: []models.EvidenceOffender{{ValidatorAddress: models.Address{Address: "offenderaddress"}, Power: 10}},
+		}}
+		suite.Require().NoError(IndexBlockEvidence(suite.db, indexedBlock, evidence))
+
+		// Ledger entries, rollups, failed blocks and height states are keyed by the block ID or height only
+		entry := models.LedgerEntry{BlockID: indexedBlock.ID, Account: "cosmos1recipient", DenomID: denom.ID, Side: models.LedgerDebit, Amount: decimal.NewFromInt(10), Kind: models.FlowTransfer}
+		suite.Require().NoError(suite.db.Create(&entry).Error)
+		suite.Require().NoError(rollUpLedgerEntries(suite.db, &indexedBlock, false, 1))
+		suite.Require().NoError(suite.db.Create(&models.FailedEventBlock{Height: height, BlockchainID: initChain.ID}).Error)
+		suite.Require().NoError(UpsertHeightStates(suite.db, []models.HeightState{{Height: height, BlockchainID: initChain.ID, State: models.HeightCommitted}}))
+	}
+	_, err = RecordChainHead(suite.db, initChain.ID, 10, start.Add(10*time.Minute), 0)
+	suite.Require().NoError(err)
+
+	hash, parentHash, err := GetBlockHashes(suite.db, initChain.ID, 2)
+	suite.Require().NoError(err)
+	suite.Require().Equal("HASH2", hash)
+	suite.Require().Equal("HASH1", parentHash)
+	hash, parentHash, err = GetBlockHashes(suite.db, initChain.ID, 4)
+	suite.Require().NoError(err)
+	suite.Require().Empty(hash)
+	suite.Require().Empty(parentHash)
+
+	for _, model := range []any{&models.MessageEventAttributeCoin{}, &models.BlockEventAttributeCoin{}, &models.MessageEventSearch{}} {
+		var count int64
+		suite.Require().NoError(suite.db.Model(model).Count(&count).Error)
+		suite.Require().Equal(int64(3), count, "%T", model)
+	}
+
+	// The rows referencing the rolled back blocks are deleted with them, transitively, the addresses are kept
+	deleted, err := RollbackBlocks(suite.db, initChain.ID, 2, 3, true)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(2), deleted)
+
+	var heights []int64
+	suite.Require().NoError(suite.db.Model(&models.Block{}).Order("height").Pluck("height", &heights).Error)
+	suite.Require().Equal([]int64{1}, heights)
+	for _, model := range []any{&models.FailedTx{}, &models.BlockEvidence{}, &models.EvidenceOffender{}} {
+		var count int64
+		suite.Require().NoError(suite.db.Model(model).Count(&count).Error)
+		suite.Require().Equal(int64(1), count)
+	}
+	var addresses int64
+	suite.Require().NoError(suite.db.Model(&models.Address{}).Where("address = ?", "offenderaddress").Count(&addresses).Error)
+	suite.Require().Equal(int64(1), addresses)
+
+	// The rows without a foreign key are rolled back explicitly, the rollups keep the share of block 1
+	for _, model := range []any{&models.LedgerEntry{}, &models.FailedEventBlock{}, &models.HeightState{}} {
+		var count int64
+		suite.Require().NoError(suite.db.Model(model).Count(&count).Error)
+		suite.Require().Equal(int64(1), count, "%T", model)
+	}
+	// and so are the rows keyed by the IDs of the events and txes of the blocks, which would be counted twice when the
+	// heights are indexed again
+	for _, model := range []any{&models.MessageEventAttributeCoin{}, &models.BlockEventAttributeCoin{}, &models.MessageEventSearch{}} {
+		var count int64
+		suite.Require().NoError(suite.db.Model(model).Count(&count).Error)
+		suite.Require().Equal(int64(1), count, "%T", model)
+	}
+	var involvementHeights []int64
+	suite.Require().NoError(suite.db.Model(&models.AddressInvolvement{}).Distinct("height").Pluck("height", &involvementHeights).Error)
+	suite.Require().Equal([]int64{1}, involvementHeights)
+
+	var activity models.AddressActivity
+	suite.Require().NoError(suite.db.Where("account = ?", "cosmos1recipient").First(&activity).Error)
+	suite.Require().Equal("10", activity.VolumeIn.String())
+	var summary models.AddressSummary
+	suite.Require().NoError(suite.db.Where("account = ?", "cosmos1recipient").First(&summary).Error)
+	suite.Require().Zero(summary.TxCount)
+
+	head, err := GetChainHead(suite.db, "testchain-1")
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(1), head.IndexedHeight)
+	suite.Require().Equal(int64(9), head.LagBlocks)
+	suite.Require().InDelta(9*time.Minute.Seconds(), head.LagSeconds, 1)
+}
+
+func (suite *DBTestSuite) TestIndexerCheckpoint() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	checkpoint, err := GetIndexerCheckpoint(suite.db, initChain.ID)
+	suite.Require().NoError(err)
+	suite.Require().Nil(checkpoint)
+
+	// Heights 1 to 3 and 6 are indexed, 4 failed and 5 is missing
+	for _, height := range []int64{1, 2, 3, 6} {
+		block := models.Block{Height: height, ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: time.Now()}
+		_, _, err := IndexNewBlock(suite.db, block, nil, nil, config.IndexConfig{}, nil)
+		suite.Require().NoError(err)
+	}
+	suite.Require().NoError(suite.db.Create(&models.FailedBlock{Height: 4, BlockchainID: initChain.ID}).Error)
+
+	for from, expected := range map[int64]int64{1: 4, 3: 4, 5: 4, 6: 6, 7: 6} {
+		height, err := ContiguousIndexedHeight(suite.db, initChain.ID, from)
+		suite.Require().NoError(err)
+		suite.Require().Equal(expected, height, "from %d", from)
+	}
+
+	suite.Require().NoError(UpsertIndexerCheckpoint(suite.db, models.IndexerCheckpoint{BlockchainID: initChain.ID, StartHeight: 1, Height: 3}))
+	suite.Require().NoError(UpsertIndexerCheckpoint(suite.db, models.IndexerCheckpoint{BlockchainID: initChain.ID, StartHeight: 1, Height: 4}))
+	checkpoint, err = GetIndexerCheckpoint(suite.db, initChain.ID)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(1), checkpoint.StartHeight)
+	suite.Require().Equal(int64(4), checkpoint.Height)
+
+	// Rolling back indexed heights moves the checkpoint before them
+	_, err = RollbackBlocks(suite.db, initChain.ID, 3, 3, false)
+	suite.Require().NoError(err)
+	checkpoint, err = GetIndexerCheckpoint(suite.db, initChain.ID)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(2), checkpoint.Height)
+}
+
+func (suite *DBTestSuite) TestFindMissingHeights() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	// Heights 2, 3, 6 and 9 are indexed, 5 failed and is left to the failed block reattempts
+	for _, height := range []int64{2, 3, 6, 9} {
+		block := models.Block{Height: height, ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: time.Now()}
+		_, _, err := IndexNewBlock(suite.db, block, nil, nil, config.IndexConfig{}, nil)
+		suite.Require().NoError(err)
+	}
+	suite.Require().NoError(suite.db.Create(&models.FailedBlock{Height: 5, BlockchainID: initChain.ID}).Error)
+
+	gaps, err := FindMissingHeights(suite.db, initChain.ID, 1, 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]HeightRange{{Start: 1, End: 1}, {Start: 4, End: 4}, {Start: 7, End: 8}, {Start: 10, End: 10}}, gaps)
+
+	gaps, err = FindMissingHeights(suite.db, initChain.ID, 2, 3)
+	suite.Require().NoError(err)
+	suite.Require().Empty(gaps)
+
+	// Blocks of other chains do not fill the gaps
+	otherChain := models.Chain{ChainID: "testchain-2"}
+	suite.Require().NoError(suite.db.Create(&otherChain).Error)
+	gaps, err = FindMissingHeights(suite.db, otherChain.ID, 2, 3)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]HeightRange{{Start: 2, End: 3}}, gaps)
+}
+
+func (suite *DBTestSuite) TestUpsertHeightStates() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	now := time.Now()
+	suite.Require().NoError(UpsertHeightStates(suite.db, []models.HeightState{
+		{Height: 1, BlockchainID: initChain.ID, State: models.HeightFetched, QueuedAt: &now, FetchedAt: &now, UpdatedAt: now},
+	}))
+	suite.Require().NoError(UpsertHeightStates(suite.db, []models.HeightState{
+		{Height: 1, BlockchainID: initChain.ID, State: models.HeightCommitted, CommittedAt: &now, UpdatedAt: now},
+	}))
+
+	var state models.HeightState
+	suite.Require().NoError(suite.db.Where("height = ?", 1).First(&state).Error)
+	suite.Require().Equal(models.HeightCommitted, state.State)
+	suite.Require().NotNil(state.QueuedAt)
+	suite.Require().NotNil(state.FetchedAt)
+	suite.Require().NotNil(state.CommittedAt)
+
+	// Queueing the height again clears the stages of the previous pass
+	suite.Require().NoError(UpsertHeightStates(suite.db, []models.HeightState{
+		{Height: 1, BlockchainID: initChain.ID, State: models.HeightQueued, QueuedAt: &now, UpdatedAt: now},
+	}))
+	suite.Require().NoError(suite.db.Where("height = ?", 1).First(&state).Error)
+	suite.Require().Equal(models.HeightQueued, state.State)
+	suite.Require().Nil(state.FetchedAt)
+	suite.Require().Nil(state.CommittedAt)
+}
+
+func (suite *DBTestSuite) TestRecordChainHead() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	// Nothing indexed yet, the whole chain is the lag
+	headTime := time.Now().UTC().Truncate(time.Second)
+	head, err := RecordChainHead(suite.db, initChain.ID, 100, headTime, time.Hour)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(100), head.LagBlocks)
+	suite.Require().Nil(head.IndexedTime)
+
+	block := models.Block{Height: 90, ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: headTime.Add(-time.Minute)}
+	_, _, err = IndexNewBlock(suite.db, block, nil, nil, config.IndexConfig{}, nil)
+	suite.Require().NoError(err)
+
+	_, err = RecordChainHead(suite.db, initChain.ID, 100, headTime, time.Hour)
+	suite.Require().NoError(err)
+
+	head, err = GetChainHead(suite.db, "testchain-1")
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(100), head.HeadHeight)
+	suite.Require().Equal(int64(90), head.IndexedHeight)
+	suite.Require().Equal(int64(10), head.LagBlocks)
+	suite.Require().InDelta(60, head.LagSeconds, 1)
+
+	suite.Require().False(head.Halted)
+
+	var rows int64
+	suite.Require().NoError(suite.db.Model(&models.ChainHead{}).Count(&rows).Error)
+	suite.Require().Equal(int64(1), rows)
+
+	// A head that did not advance for the halt threshold is a halted chain, until it advances again
+	suite.Require().NoError(suite.db.Model(&models.ChainHead{}).Where("blockchain_id = ?", initChain.ID).Update("head_advanced_at", time.Now().Add(-2*time.Hour)).Error)
+	head, err = RecordChainHead(suite.db, initChain.ID, 100, headTime, time.Hour)
+	suite.Require().NoError(err)
+	suite.Require().True(head.Halted)
+
+	head, err = RecordChainHead(suite.db, initChain.ID, 101, headTime.Add(time.Minute), time.Hour)
+	suite.Require().NoError(err)
+	suite.Require().False(head.Halted)
+}
+
+func (suite *DBTestSuite) TestCompression() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	models.SetCompression(64)
+	defer models.SetCompression(0)
+
+	attribute := func(index uint64, key string, value string) models.BlockEventAttribute {
+		return models.BlockEventAttribute{Index: index, Value: value, BlockEventAttributeKey: models.BlockEventAttributeKey{Key: key}}
+	}
+	large := strings.Repeat(`{"contract":"cosmos1contract","action":"airdrop"}`, 20)
+	values := []string{large, "100uatom", "note"}
+	wrapper := &BlockDBWrapper{
+		Block: &models.Block{Height: 1, ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: time.Now()},
+		BeginBlockEvents: []BlockEventDBWrapper{{
+			BlockEvent: models.BlockEvent{Index: 0, LifecyclePosition: models.BeginBlockEvent, BlockEventType: models.BlockEventType{Type: "wasm"}},
+			Attributes: []models.BlockEventAttribute{attribute(0, "msg", values[0]), attribute(1, "amount", values[1]), attribute(2, "note", values[2])},
+		}},
+		UniqueBlockEventTypes:         map[string]models.BlockEventType{"wasm": {Type: "wasm"}},
+		UniqueBlockEventAttributeKeys: map[string]models.BlockEventAttributeKey{"msg": {Key: "msg"}, "amount": {Key: "amount"}, "note": {Key: "note"}},
+	}
+	_, err = IndexBlockEvents(suite.db, false, wrapper, "", config.IndexConfig{}, nil, nil)
+	suite.Require().NoError(err)
+
+	// The large value is moved to compressed_value, the others stay readable and filterable by SQL
+	var stored []struct {
+		Value           *string
+		CompressedValue []byte
+	}
+	suite.Require().NoError(suite.db.Raw(fmt.Sprintf("SELECT value, compressed_value FROM %q ORDER BY index", TableName(suite.db, &models.BlockEventAttribute{}))).Scan(&stored).Error)
+	suite.Require().Len(stored, 3)
+	suite.Require().Nil(stored[0].Value)
+	suite.Require().Less(len(stored[0].CompressedValue), len(large))
+	for i := 1; i < len(stored); i++ {
+		suite.Require().Equal(values[i], *stored[i].Value)
+		suite.Require().Nil(stored[i].CompressedValue)
+	}
+
+	var attributes []models.BlockEventAttribute
+	suite.Require().NoError(suite.db.Order("index").Find(&attributes).Error)
+	suite.Require().Len(attributes, 3)
+	for i, attribute := range attributes {
+		suite.Require().Equal(values[i], attribute.Value)
+	}
+
+	raw := []byte(strings.Repeat("raw transaction bytes", 10))
+	failedTx := models.FailedTx{Hash: "ABCD", ErrorCode: models.FailureUnknownMsgType, ErrorMessage: "unknown message type", Raw: raw}
+	_, _, err = IndexNewBlock(suite.db, *wrapper.Block, nil, []models.FailedTx{failedTx}, config.IndexConfig{}, nil)
+	suite.Require().NoError(err)
+
+	var storedRaw []byte
+	suite.Require().NoError(suite.db.Raw(fmt.Sprintf("SELECT raw FROM %q", TableName(suite.db, &models.FailedTx{}))).Scan(&storedRaw).Error)
+	suite.Require().Less(len(storedRaw), len(raw))
+	decompressed, err := models.DecompressBytes(storedRaw)
+	suite.Require().NoError(err)
+	suite.Require().Equal(raw, decompressed)
+
+	// Compressed values are still read once compression is disabled
+	models.SetCompression(0)
+	var failedTxes []models.FailedTx
+	suite.Require().NoError(suite.db.Find(&failedTxes).Error)
+	suite.Require().Len(failedTxes, 1)
+	suite.Require().Equal(raw, failedTxes[0].Raw)
+
+	var ledgerAttributes []ledgerAttribute
+	suite.Require().NoError(suite.db.Raw(fmt.Sprintf("SELECT index, value, compressed_value FROM %q ORDER BY index", TableName(suite.db, &models.BlockEventAttribute{}))).Scan(&ledgerAttributes).Error)
+	suite.Require().Len(ledgerAttributes, 3)
+	for i, attribute := range ledgerAttributes {
+		suite.Require().Equal(values[i], attribute.Value)
+	}
+}
+
+// testBlocks returns blocks of the chain from the start height with txs transferring a coin, each with a message, an
+// event and its attributes
+func testBlocks(chainID uint, start int64, blocks int, txsPerBlock int) []BulkBlock {
+	bulkBlocks := make([]BulkBlock, blocks)
+	for blockIndex := range bulkBlocks {
+		height := start + int64(blockIndex)
+		block := models.Block{Height: height, ChainID: chainID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: time.Now()}
+
+		txs := make([]TxDBWrapper, txsPerBlock)
+		for txIndex := range txs {
+			sender := fmt.Sprintf("cosmos1sender%d", txIndex)
+			txs[txIndex] = TxDBWrapper{
+				Tx: models.Tx{
+					Hash:            fmt.Sprintf("%d-%d", height, txIndex),
+					SignerAddresses: []models.Address{{Address: sender}},
+					Fees:            []models.Fee{{Amount: decimal.NewFromInt(5000), Denomination: models.Denom{Base: "uatom"}, PayerAddress: models.Address{Address: sender}}},
+				},
+				Messages: []MessageDBWrapper{{
+					Message: models.Message{MessageIndex: 0, MessageType: models.MessageType{MessageType: "/cosmos.bank.v1beta1.MsgSend"}},
+					MessageEvents: []MessageEventDBWrapper{{
+						MessageEvent: models.MessageEvent{Index: 0, MessageEventType: models.MessageEventType{Type: "transfer"}},
+						Attributes: []models.MessageEventAttribute{
+							{Index: 0, Value: sender, MessageEventAttributeKey: models.MessageEventAttributeKey{Key: "sender"}},
+							{Index: 1, Value: "cosmos1recipient", MessageEventAttributeKey: models.MessageEventAttributeKey{Key: "recipient"}},
+							{Index: 2, Value: "100uatom", MessageEventAttributeKey: models.MessageEventAttributeKey{Key: "amount"}},
+						},
+					}},
+				}},
+				UniqueMessageTypes:      map[string]models.MessageType{"/cosmos.bank.v1beta1.MsgSend": {MessageType: "/cosmos.bank.v1beta1.MsgSend"}},
+				UniqueMessageEventTypes: map[string]models.MessageEventType{"transfer": {Type: "transfer"}},
+				UniqueMessageAttributeKeys: map[string]models.MessageEventAttributeKey{
+					"sender": {Key: "sender"}, "recipient": {Key: "recipient"}, "amount": {Key: "amount"},
+				},
+			}
+		}
+		bulkBlocks[blockIndex] = BulkBlock{Block: block, Txs: txs}
+	}
+	return bulkBlocks
+}
+
+func (suite *DBTestSuite) TestIndexNewBlocksBulk() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	written, err := IndexNewBlocksBulk(suite.db, testBlocks(initChain.ID, 1, 3, 2), config.IndexConfig{}, nil)
+	suite.Require().NoError(err)
+	suite.Require().Len(written, 3)
+
+	// The blocks are returned loaded with the IDs of the written rows
+	tx := written[1].Txs[0]
+	suite.Require().NotZero(written[1].Block.ID)
+	suite.Require().Equal(written[1].Block.ID, tx.Tx.BlockID)
+	suite.Require().NotZero(tx.Tx.Fees[0].ID)
+	event := tx.Messages[0].MessageEvents[0]
+	suite.Require().Equal(tx.Messages[0].Message.ID, event.MessageEvent.MessageID)
+	suite.Require().Equal(event.MessageEvent.ID, event.Attributes[2].MessageEventID)
+
+	var stored models.MessageEventAttribute
+	suite.Require().NoError(suite.db.Preload("MessageEventAttributeKey").First(&stored, event.Attributes[2].ID).Error)
+	suite.Require().Equal("100uatom", stored.Value)
+	suite.Require().Equal("amount", stored.MessageEventAttributeKey.Key)
+
+	var storedTx models.Tx
+	suite.Require().NoError(suite.db.Preload("SignerAddresses").Preload("Fees").First(&storedTx, tx.Tx.ID).Error)
+	suite.Require().Equal("cosmos1sender0", storedTx.SignerAddresses[0].Address)
+	suite.Require().Equal("5000", storedTx.Fees[0].Amount.String())
+
+	// Writing the blocks again updates the rows in place
+	_, err = IndexNewBlocksBulk(suite.db, testBlocks(initChain.ID, 1, 3, 2), config.IndexConfig{}, nil)
+	suite.Require().NoError(err)
+
+	counts := map[any]int64{&models.Tx{}: 6, &models.Fee{}: 6, &models.Message{}: 6, &models.MessageEvent{}: 6, &models.MessageEventAttribute{}: 18}
+	for model, expected := range counts {
+		var count int64
+		suite.Require().NoError(suite.db.Model(model).Count(&count).Error)
+		suite.Require().Equal(expected, count)
+	}
+}
+
+func (suite *DBTestSuite) TestCDCEvents() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	conf := config.IndexConfig{}
+	conf.Probe.ChainID = "testchain-1"
+	conf.CDC.Notify = true
+	conf.CDC.Outbox = true
+	conf.CDC.Channel = "test_cdc"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	notifications := make(chan CDCEvent, 2)
+	go func() {
+		_ = ListenCDC(ctx, suite.db, conf.CDC.Channel, func(event CDCEvent) { notifications <- event })
+	}()
+	suite.Require().Eventually(func() bool {
+		var listening int64
+		suite.db.Raw("SELECT COUNT(*) FROM pg_stat_activity WHERE query = ?", `LISTEN "test_cdc"`).Scan(&listening)
+		return listening == 1
+	}, 5*time.Second, 50*time.Millisecond)
+
+	block := testBlocks(initChain.ID, 1, 1, 2)[0]
+	_, _, err = IndexNewBlock(suite.db, block.Block, block.Txs, nil, conf, nil)
+	suite.Require().NoError(err)
+
+	wrapper := &BlockDBWrapper{
+		Block: &block.Block,
+		BeginBlockEvents: []BlockEventDBWrapper{{
+			BlockEvent: models.BlockEvent{Index: 0, LifecyclePosition: models.BeginBlockEvent, BlockEventType: models.BlockEventType{Type: "mint"}},
+		}},
+		UniqueBlockEventTypes: map[string]models.BlockEventType{"mint": {Type: "mint"}},
+	}
+	_, err = IndexBlockEvents(suite.db, false, wrapper, "", conf, nil, nil)
+	suite.Require().NoError(err)
+
+	expected := []CDCEvent{
+		{ChainID: "testchain-1", Height: 1, Kind: CDCKindTxs, Txs: 2, Messages: 2},
+		{ChainID: "testchain-1", Height: 1, Kind: CDCKindBlockEvents, BlockEvents: 1},
+	}
+
+	// Every committed dataset is written to the outbox
+	var outbox []models.CDCOutboxEvent
+	suite.Require().NoError(suite.db.Order("id").Find(&outbox).Error)
+	suite.Require().Len(outbox, 2)
+	for i, outboxEvent := range outbox {
+		suite.Require().Equal("testchain-1", outboxEvent.ChainID)
+		suite.Require().Equal(int64(1), outboxEvent.Height)
+		suite.Require().Equal(expected[i].Kind, outboxEvent.Kind)
+
+		var payload CDCEvent
+		suite.Require().NoError(json.Unmarshal([]byte(outboxEvent.Payload), &payload))
+		suite.Require().Equal(expected[i], payload)
+	}
+
+	// and sent to the listeners with the same payload
+	for _, event := range expected {
+		select {
+		case notification := <-notifications:
+			suite.Require().Equal(event, notification)
+		case <-time.After(5 * time.Second):
+			suite.FailNow("no CDC notification received")
+		}
+	}
+}
+
+// contextParser is a contextual block event parser recording the contexts it indexes with, it stores the parsed denom
+// through the scoped DB of the context
+type contextParser struct {
+	contexts []parsers.BlockEventIndexContext
+}
+
+func (p *contextParser) Identifier() string {
+	return "context-parser"
+}
+
+func (p *contextParser) ParseBlockEvent(abci.Event, config.IndexConfig) (*any, error) {
+	return nil, errors.New("the contextual parse method must be called")
+}
+
+func (p *contextParser) IndexBlockEvent(*any, *gorm.DB, models.Block, models.BlockEvent, []models.BlockEventAttribute, config.IndexConfig) error {
+	return errors.New("the contextual index method must be called")
+}
+
+func (p *contextParser) ParseBlockEventWithContext(abci.Event, parsers.BlockEventParseContext) (*any, error) {
+	return nil, errors.New("block events are parsed in core")
+}
+
+func (p *contextParser) IndexBlockEventWithContext(data *any, indexContext parsers.BlockEventIndexContext) error {
+	p.contexts = append(p.contexts, indexContext)
+	return indexContext.DB.Create(&models.Denom{Base: (*data).(string)}).Error
+}
+
+func (suite *DBTestSuite) TestContextualBlockEventParser() {
+	err := MigrateModels(suite.db)
+	suite.Require().NoError(err)
+
+	initChain := models.Chain{ChainID: "testchain-1"}
+	suite.Require().NoError(suite.db.Create(&initChain).Error)
+
+	contextual := &contextParser{}
+	var parser parsers.BlockEventParser = contextual
+	trackers := map[string]models.BlockEventParser{
+		contextual.Identifier(): {Identifier: contextual.Identifier(), BlockLifecyclePosition: models.BeginBlockEvent},
+	}
+	suite.Require().NoError(FindOrCreateCustomBlockEventParsers(suite.db, trackers))
+
+	var data any = "umint"
+	wrapper := &BlockDBWrapper{
+		Block: &models.Block{Height: 7, ChainID: initChain.ID, ProposerConsAddress: models.Address{Address: "testchainaddress"}, TimeStamp: time.Now()},
+		BeginBlockEvents: []BlockEventDBWrapper{{
+			BlockEvent:               models.BlockEvent{Index: 0, LifecyclePosition: models.BeginBlockEvent, BlockEventType: models.BlockEventType{Type: "mint"}},
+			Attributes:               []models.BlockEventAttribute{{Index: 0, Value: "100umint", BlockEventAttributeKey: models.BlockEventAttributeKey{Key: "amount"}}},
+			BlockEventParsedDatasets: []parsers.BlockEventParsedData{{Data: &data, Parser: &parser}},
+		}},
+		UniqueBlockEventTypes:         map[string]models.BlockEventType{"mint": {Type: "mint"}},
+		UniqueBlockEventAttributeKeys: map[string]models.BlockEventAttributeKey{"amount": {Key: "amount"}},
+	}
+	conf := config.IndexConfig{}
+	conf.Probe.ChainID = "testchain-1"
+	_, err = IndexBlockEvents(suite.db, false, wrapper, "", conf, trackers, nil)
+	suite.Require().NoError(err)
+
+	// The parser indexed with the written block, event and attributes
+	suite.Require().Len(contextual.contexts, 1)
+	indexContext := contextual.contexts[0]
+	suite.Require().Equal("testchain-1", indexContext.Config.Probe.ChainID)
+	suite.Require().NotZero(indexContext.Block.ID)
+	suite.Require().Equal(int64(7), indexContext.Block.Height)
+	suite.Require().Equal(initChain.ID, indexContext.Block.ChainID)
+	suite.Require().NotZero(indexContext.BlockEvent.ID)
+	suite.Require().Equal(indexContext.Block.ID, indexContext.BlockEvent.BlockID)
+	suite.Require().Len(indexContext.Attributes, 1)
+	suite.Require().Equal(indexContext.BlockEvent.ID, indexContext.Attributes[0].BlockEventID)
+	suite.Require().Equal("100umint", indexContext.Attributes[0].Value)
+
+	// and its writes through the scoped DB were committed with the block
+	var denoms []string
+	suite.Require().NoError(suite.db.Model(&models.Denom{}).Pluck("base", &denoms).Error)
+	suite.Require().Equal([]string{"umint"}, denoms)
+}
+
 func TestDBSuite(t *testing.T) {
 	suite.Run(t, new(DBTestSuite))
 }
+
+// The benchmarks write 200 blocks of 20 txs with the per-block and the bulk write path, e.g.
+// go test ./db -run '^$' -bench IndexNewBlock -benchtime 1x
+const (
+	benchmarkBlocks      = 200
+	benchmarkTxsPerBlock = 20
+)
+
+func setupBenchmarkDatabase(b *testing.B) (*gorm.DB, uint) {
+	clean, db, err := SetupTestDatabase()
+	if err != nil {
+		b.Skip("a test database is required:", err)
+	}
+	b.Cleanup(clean)
+
+	if err := MigrateModels(db); err != nil {
+		b.Fatal(err)
+	}
+	chain := models.Chain{ChainID: "testchain-1"}
+	if err := db.Create(&chain).Error; err != nil {
+		b.Fatal(err)
+	}
+	db.Logger = db.Logger.LogMode(logger.Silent)
+	return db, chain.ID
+}
+
+func BenchmarkIndexNewBlock(b *testing.B) {
+	db, chainID := setupBenchmarkDatabase(b)
+	b.ResetTimer()
+	start := time.Now()
+
+	for i := 0; i < b.N; i++ {
+		for _, block := range testBlocks(chainID, int64(i*benchmarkBlocks+1), benchmarkBlocks, benchmarkTxsPerBlock) {
+			if _, _, err := IndexNewBlock(db, block.Block, block.Txs, nil, config.IndexConfig{}, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N*benchmarkBlocks)/time.Since(start).Seconds(), "blocks/s")
+}
+
+func BenchmarkIndexNewBlocksBulk(b *testing.B) {
+	db, chainID := setupBenchmarkDatabase(b)
+	b.ResetTimer()
+	start := time.Now()
+
+	// Batches of 50 blocks, the default of base.bulk-copy-blocks
+	for i := 0; i < b.N; i++ {
+		blocks := testBlocks(chainID, int64(i*benchmarkBlocks+1), benchmarkBlocks, benchmarkTxsPerBlock)
+		for batch := 0; batch < len(blocks); batch += 50 {
+			if _, err := IndexNewBlocksBulk(db, blocks[batch:batch+50], config.IndexConfig{}, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.ReportMetric(float64(b.N*benchmarkBlocks)/time.Since(start).Seconds(), "blocks/s")
+}
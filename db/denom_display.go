@@ -0,0 +1,106 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpsertDenomDisplays stores the display units of the denoms, replacing the units stored for them before
+func UpsertDenomDisplays(db *gorm.DB, displays []config.DenomDisplay) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, display := range displays {
+			denom, err := FindOrCreateDenomByBase(tx, display.Base)
+			if err != nil {
+				return err
+			}
+
+			metadata := models.DenomMetadata{DenomID: denom.ID, Display: display.Display, Exponent: display.Exponent}
+			err = tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "denom_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"display", "exponent"}),
+			}).Create(&metadata).Error
+			if err != nil {
+				return fmt.Errorf("failed to store the display unit of %s: %w", display.Base, err)
+			}
+		}
+		return nil
+	})
+}
+
+// CreateDisplayViews creates the functions converting an amount of a denom to its display unit and the views of the
+// indexed amounts in display units. Amounts of denoms without a display unit convert to NULL rather than to a guess.
+//
+//   - display_amount(denom_id, amount) is the amount in the display unit of the denom
+//   - display_denom(denom_id) is the display denom of the denom
+//   - fee_display_amounts are the fees of the transactions with their display amounts
+func CreateDisplayViews(db *gorm.DB) error {
+	metadata := TableName(db, &models.DenomMetadata{})
+	denoms := TableName(db, &models.Denom{})
+	fees := TableName(db, &models.Fee{})
+
+	statements := []string{
+		// In SQL functions, columns take precedence over parameters of the same name, so the parameters are referenced
+		// by position
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION display_amount(denom_id bigint, amount numeric) RETURNS numeric
+			LANGUAGE sql STABLE AS $$ SELECT $2 / power(10::numeric, m.exponent) FROM %q m WHERE m.denom_id = $1 $$`, metadata),
+		fmt.Sprintf(`CREATE OR REPLACE FUNCTION display_denom(denom_id bigint) RETURNS text
+			LANGUAGE sql STABLE AS $$ SELECT m.display FROM %q m WHERE m.denom_id = $1 $$`, metadata),
+		fmt.Sprintf(`CREATE OR REPLACE VIEW fee_display_amounts AS
+			SELECT f.id, f.tx_id, f.payer_address_id, d.base AS denom, f.amount, m.display AS display_denom,
+				f.amount / power(10::numeric, m.exponent) AS display_amount
+			FROM %q f
+			JOIN %q d ON d.id = f.denomination_id
+			LEFT JOIN %q m ON m.denom_id = d.id`, fees, denoms, metadata),
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpsertDenomTraces stores the origins of the IBC voucher denoms, replacing the traces stored for them before
+func UpsertDenomTraces(db *gorm.DB, traces []config.DenomTrace) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, trace := range traces {
+			denom, err := FindOrCreateDenomByBase(tx, trace.Denom)
+			if err != nil {
+				return err
+			}
+
+			denomTrace := models.DenomTrace{DenomID: denom.ID, Path: trace.Path, BaseDenom: trace.BaseDenom}
+			err = tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "denom_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{"path", "base_denom"}),
+			}).Create(&denomTrace).Error
+			if err != nil {
+				return fmt.Errorf("failed to store the trace of %s: %w", trace.Denom, err)
+			}
+		}
+		return nil
+	})
+}
+
+// DenomTraces returns the origins of the IBC voucher denoms by voucher denom
+func DenomTraces(db *gorm.DB) (map[string]config.DenomTrace, error) {
+	var rows []config.DenomTrace
+	err := db.Raw(fmt.Sprintf(`SELECT d.base AS denom, t.path, t.base_denom FROM %q t JOIN %q d ON d.id = t.denom_id`,
+		TableName(db, &models.DenomTrace{}), TableName(db, &models.Denom{}))).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	traces := make(map[string]config.DenomTrace, len(rows))
+	for _, row := range rows {
+		traces[row.Denom] = row
+	}
+	return traces, nil
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// SwapEventTypes are the message event types DEX swaps are emitted as: token_swapped by the gamm and poolmanager
+// modules of Osmosis, wasm by the pair contracts of CosmWasm DEXes
+var SwapEventTypes = []string{"token_swapped", "wasm"}
+
+// EventAttribute is an attribute of a message event
+type EventAttribute struct {
+	EventID         uint
+	Type            string
+	Key             string
+	Value           string `gorm:"serializer:attribute_value"`
+	CompressedValue []byte `gorm:"serializer:attribute_value"`
+}
+
+// BlockSwapAttributes returns the attributes of the swap event types of the successful transactions of a block,
+// ordered by event and attribute index
+func BlockSwapAttributes(db *gorm.DB, blockID uint) ([]EventAttribute, error) {
+	var attributes []EventAttribute
+	err := db.Raw(fmt.Sprintf(`SELECT e.id AS event_id, et.type, k.key, a.value, a.compressed_value FROM %q a
+		JOIN %q k ON k.id = a.message_event_attribute_key_id
+		JOIN %q e ON e.id = a.message_event_id
+		JOIN %q et ON et.id = e.message_event_type_id
+		JOIN %q m ON m.id = e.message_id
+		JOIN %q t ON t.id = m.tx_id
+		WHERE t.block_id = ? AND t.code = 0 AND et.type IN ?
+		ORDER BY e.id, a.index`,
+		TableName(db, &models.MessageEventAttribute{}), TableName(db, &models.MessageEventAttributeKey{}),
+		TableName(db, &models.MessageEvent{}), TableName(db, &models.MessageEventType{}),
+		TableName(db, &models.Message{}), TableName(db, &models.Tx{})), blockID, SwapEventTypes).Scan(&attributes).Error
+	return attributes, err
+}
+
+// UnderivedBlocks returns up to limit blocks of the chain from a height up to the end height (0 for no end), ordered
+// by height, whose swap prices were not derived yet. With rederive they are returned whether they were or not.
+func UnderivedBlocks(db *gorm.DB, chainID uint, fromHeight int64, endHeight int64, rederive bool, limit int) ([]models.Block, error) {
+	return blocksWithout(db, "dex_prices_derived", chainID, fromHeight, endHeight, rederive, limit)
+}
+
+// StoreDexPrices replaces the swap prices of the block and marks it derived
+func StoreDexPrices(db *gorm.DB, block *models.Block, prices []models.DexPrice) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("block_id = ?", block.ID).Delete(&models.DexPrice{}).Error; err != nil {
+			return err
+		}
+
+		if len(prices) != 0 {
+			if err := tx.Omit("Denom", "QuoteDenom").CreateInBatches(prices, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		block.DexPricesDerived = true
+		return tx.Model(&models.Block{}).Where("id = ?", block.ID).Update("dex_prices_derived", true).Error
+	})
+}
+
+// LatestDexRate returns the rate of the latest swap of the denom against one of the quote denoms, or against any
+// denom if none are given, made in the time window (from, to]. The rate is the amount of the other denom per unit of
+// the denom, in base units, whichever side of the swap the denom was on. found is false if there was no swap.
+func LatestDexRate(db *gorm.DB, denomID uint, quoteDenomIDs []uint, from time.Time, to time.Time) (rate decimal.Decimal, otherDenomID uint, found bool, err error) {
+	query := db.Model(&models.DexPrice{}).Where("time > ? AND time <= ? AND price > 0", from, to)
+	if len(quoteDenomIDs) == 0 {
+		query = query.Where("(denom_id = ? OR quote_denom_id = ?)", denomID, denomID)
+	} else {
+		query = query.Where("((denom_id = ? AND quote_denom_id IN ?) OR (quote_denom_id = ? AND denom_id IN ?))", denomID, quoteDenomIDs, denomID, quoteDenomIDs)
+	}
+
+	var price models.DexPrice
+	err = query.Order("time DESC, id DESC").Take(&price).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return decimal.Decimal{}, 0, false, nil
+	}
+	if err != nil {
+		return decimal.Decimal{}, 0, false, err
+	}
+
+	if price.DenomID == denomID {
+		return price.Price, price.QuoteDenomID, true, nil
+	}
+	return decimal.NewFromInt(1).DivRound(price.Price, 36), price.DenomID, true, nil
+}
@@ -1,16 +1,23 @@
 package db
 
 import (
+	"time"
+
 	"github.com/DefiantLabs/cosmos-indexer/config"
 	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
+	"github.com/DefiantLabs/cosmos-indexer/parsers"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
-func IndexBlockEvents(db *gorm.DB, dryRun bool, blockDBWrapper *BlockDBWrapper, identifierLoggingString string) (*BlockDBWrapper, error) {
-	err := db.Transaction(func(dbTransaction *gorm.DB) error {
+// IndexBlockEvents indexes the block events. Custom block event parsers that index atomically are run in the same transaction,
+// deferred parsers are left to IndexCustomBlockEvents.
+func IndexBlockEvents(db *gorm.DB, dryRun bool, blockDBWrapper *BlockDBWrapper, identifierLoggingString string, conf config.IndexConfig, beginBlockParserTrackers map[string]models.BlockEventParser, endBlockParserTrackers map[string]models.BlockEventParser) (*BlockDBWrapper, error) {
+	err := transaction(db, func(dbTransaction *gorm.DB) error {
 		if err := dbTransaction.
-			Exec("DELETE FROM failed_event_blocks WHERE height = ? AND blockchain_id = ?", blockDBWrapper.Block.Height, blockDBWrapper.Block.ChainID).
+			Where("height = ? AND blockchain_id = ?", blockDBWrapper.Block.Height, blockDBWrapper.Block.ChainID).
+			Delete(&models.FailedEventBlock{}).
 			Error; err != nil {
 			config.Log.Error("Error updating failed block.", err)
 			return err
@@ -32,32 +39,44 @@ func IndexBlockEvents(db *gorm.DB, dryRun bool, blockDBWrapper *BlockDBWrapper,
 
 		if err := dbTransaction.
 			Where(models.Block{Height: blockDBWrapper.Block.Height, ChainID: blockDBWrapper.Block.ChainID}).
-			Assign(models.Block{BlockEventsIndexed: true, TimeStamp: blockDBWrapper.Block.TimeStamp, ProposerConsAddress: blockDBWrapper.Block.ProposerConsAddress}).
+			Assign(models.Block{BlockEventsIndexed: true, TimeStamp: blockDBWrapper.Block.TimeStamp, Hash: blockDBWrapper.Block.Hash, ParentHash: blockDBWrapper.Block.ParentHash, ProposerConsAddress: blockDBWrapper.Block.ProposerConsAddress}).
 			FirstOrCreate(&blockDBWrapper.Block).Error; err != nil {
 			config.Log.Error("Error getting/creating block DB object.", err)
 			return err
 		}
 
+		if err := resetBlockMarkers(dbTransaction, blockDBWrapper.Block); err != nil {
+			config.Log.Error("Error resetting block completeness markers.", err)
+			return err
+		}
+
 		var uniqueBlockEventTypes []models.BlockEventType
 
-		for _, value := range blockDBWrapper.UniqueBlockEventTypes {
+		for key, value := range blockDBWrapper.UniqueBlockEventTypes {
+			if id, ok := dictionaries.lookup(blockEventTypeDictionary, value.Type); ok {
+				value.ID = id
+				blockDBWrapper.UniqueBlockEventTypes[key] = value
+				continue
+			}
 			uniqueBlockEventTypes = append(uniqueBlockEventTypes, value)
 		}
 
 		// Bulk find or create on unique event types
-		if err := dbTransaction.Clauses(
-			clause.Returning{
-				Columns: []clause.Column{
-					{Name: "id"}, {Name: "type"},
+		if len(uniqueBlockEventTypes) != 0 {
+			if err := dbTransaction.Clauses(
+				clause.Returning{
+					Columns: []clause.Column{
+						{Name: "id"}, {Name: "type"},
+					},
 				},
-			},
-			clause.OnConflict{
-				Columns:   []clause.Column{{Name: "type"}},
-				DoUpdates: clause.AssignmentColumns([]string{"type"}),
-			},
-		).Create(&uniqueBlockEventTypes).Error; err != nil {
-			config.Log.Error("Error creating begin block event types.", err)
-			return err
+				clause.OnConflict{
+					Columns:   []clause.Column{{Name: "type"}},
+					DoUpdates: clause.AssignmentColumns([]string{"type"}),
+				},
+			).Create(&uniqueBlockEventTypes).Error; err != nil {
+				config.Log.Error("Error creating begin block event types.", err)
+				return err
+			}
 		}
 
 		for _, value := range uniqueBlockEventTypes {
@@ -66,23 +85,30 @@ func IndexBlockEvents(db *gorm.DB, dryRun bool, blockDBWrapper *BlockDBWrapper,
 
 		var uniqueBlockEventAttributeKeys []models.BlockEventAttributeKey
 
-		for _, value := range blockDBWrapper.UniqueBlockEventAttributeKeys {
+		for key, value := range blockDBWrapper.UniqueBlockEventAttributeKeys {
+			if id, ok := dictionaries.lookup(blockEventAttributeKeyDictionary, value.Key); ok {
+				value.ID = id
+				blockDBWrapper.UniqueBlockEventAttributeKeys[key] = value
+				continue
+			}
 			uniqueBlockEventAttributeKeys = append(uniqueBlockEventAttributeKeys, value)
 		}
 
-		if err := dbTransaction.Clauses(
-			clause.Returning{
-				Columns: []clause.Column{
-					{Name: "id"}, {Name: "key"},
+		if len(uniqueBlockEventAttributeKeys) != 0 {
+			if err := dbTransaction.Clauses(
+				clause.Returning{
+					Columns: []clause.Column{
+						{Name: "id"}, {Name: "key"},
+					},
 				},
-			},
-			clause.OnConflict{
-				Columns:   []clause.Column{{Name: "key"}},
-				DoUpdates: clause.AssignmentColumns([]string{"key"}),
-			},
-		).Create(&uniqueBlockEventAttributeKeys).Error; err != nil {
-			config.Log.Error("Error creating begin block event attribute keys.", err)
-			return err
+				clause.OnConflict{
+					Columns:   []clause.Column{{Name: "key"}},
+					DoUpdates: clause.AssignmentColumns([]string{"key"}),
+				},
+			).Create(&uniqueBlockEventAttributeKeys).Error; err != nil {
+				config.Log.Error("Error creating begin block event attribute keys.", err)
+				return err
+			}
 		}
 
 		for _, value := range uniqueBlockEventAttributeKeys {
@@ -156,7 +182,7 @@ func IndexBlockEvents(db *gorm.DB, dryRun bool, blockDBWrapper *BlockDBWrapper,
 				if err := dbTransaction.Clauses(clause.OnConflict{
 					Columns: []clause.Column{{Name: "block_event_id"}, {Name: "index"}},
 					// Force update of value
-					DoUpdates: clause.AssignmentColumns([]string{"value"}),
+					DoUpdates: clause.AssignmentColumns([]string{"value", "compressed_value"}),
 				}).Create(&allAttributes).Error; err != nil {
 					config.Log.Error("Error creating begin block event attributes.", err)
 					return err
@@ -164,37 +190,71 @@ func IndexBlockEvents(db *gorm.DB, dryRun bool, blockDBWrapper *BlockDBWrapper,
 			}
 		}
 
-		return nil
+		if err := indexCustomBlockEvents(dbTransaction, conf, blockDBWrapper, beginBlockParserTrackers, endBlockParserTrackers, true); err != nil {
+			return err
+		}
+
+		if conf.Coins.Enabled {
+			if err := indexBlockEventAttributeCoins(dbTransaction, blockDBWrapper, conf.Coins.AttributeKeys); err != nil {
+				config.Log.Error("Error indexing the coins of the block event attributes.", err)
+				return err
+			}
+		}
+
+		if conf.Ledger.Enabled {
+			if err := projectBlockEventLedger(dbTransaction, blockDBWrapper.Block, conf.Ledger.Rollups); err != nil {
+				config.Log.Error("Error projecting the ledger entries of the block events.", err)
+				return err
+			}
+		}
+
+		return emitCDCEvent(dbTransaction, conf, newBlockEventsCDCEvent(conf, blockDBWrapper))
 	})
 
+	if err == nil {
+		dictionaries.storeBlockEvents(blockDBWrapper)
+	}
+
 	// Contract: ensure that wrapper has been loaded with all data before returning
 	return blockDBWrapper, err
 }
 
+// IndexCustomBlockEvents runs the custom block event parsers that opted out of indexing atomically with the block, then
+// marks the custom parsers of the block as applied
 func IndexCustomBlockEvents(conf config.IndexConfig, db *gorm.DB, dryRun bool, blockDBWrapper *BlockDBWrapper, identifierLoggingString string, beginBlockParserTrackers map[string]models.BlockEventParser, endBlockParserTrackers map[string]models.BlockEventParser) error {
-	return db.Transaction(func(dbTransaction *gorm.DB) error {
-		// call generic function below
-		err := indexLifecycleCustomBlockEvents(dbTransaction, conf, blockDBWrapper, blockDBWrapper.BeginBlockEvents, beginBlockParserTrackers)
-		if err != nil {
-			config.Log.Error("Error indexing begin block events.", err)
+	return transaction(db, func(dbTransaction *gorm.DB) error {
+		if err := indexCustomBlockEvents(dbTransaction, conf, blockDBWrapper, beginBlockParserTrackers, endBlockParserTrackers, false); err != nil {
 			return err
 		}
+		return markCustomParsersApplied(dbTransaction, blockDBWrapper.Block)
+	})
+}
 
-		// do the same here
-		err = indexLifecycleCustomBlockEvents(dbTransaction, conf, blockDBWrapper, blockDBWrapper.EndBlockEvents, endBlockParserTrackers)
-		if err != nil {
-			config.Log.Error("Error indexing end block events.", err)
-			return err
-		}
+func indexCustomBlockEvents(dbTransaction *gorm.DB, conf config.IndexConfig, blockDBWrapper *BlockDBWrapper, beginBlockParserTrackers map[string]models.BlockEventParser, endBlockParserTrackers map[string]models.BlockEventParser, atomic bool) error {
+	// call generic function below
+	err := indexLifecycleCustomBlockEvents(dbTransaction, conf, blockDBWrapper, blockDBWrapper.BeginBlockEvents, beginBlockParserTrackers, atomic)
+	if err != nil {
+		config.Log.Error("Error indexing begin block events.", err)
+		return err
+	}
 
-		return nil
-	})
+	// do the same here
+	err = indexLifecycleCustomBlockEvents(dbTransaction, conf, blockDBWrapper, blockDBWrapper.EndBlockEvents, endBlockParserTrackers, atomic)
+	if err != nil {
+		config.Log.Error("Error indexing end block events.", err)
+		return err
+	}
+
+	return nil
 }
 
-func indexLifecycleCustomBlockEvents(db *gorm.DB, conf config.IndexConfig, blockDBWrapper *BlockDBWrapper, events []BlockEventDBWrapper, parserTrackers map[string]models.BlockEventParser) error {
+func indexLifecycleCustomBlockEvents(db *gorm.DB, conf config.IndexConfig, blockDBWrapper *BlockDBWrapper, events []BlockEventDBWrapper, parserTrackers map[string]models.BlockEventParser, atomic bool) error {
 	for _, blockEvent := range events {
 		if len(blockEvent.BlockEventParsedDatasets) != 0 {
 			for _, parsedData := range blockEvent.BlockEventParsedDatasets {
+				if parsedData.Parser == nil || parsers.IndexesAtomically(*parsedData.Parser) != atomic {
+					continue
+				}
 
 				// Pre clear old errors
 				if parsedData.Parser != nil {
@@ -206,11 +266,40 @@ func indexLifecycleCustomBlockEvents(db *gorm.DB, conf config.IndexConfig, block
 				}
 
 				if parsedData.Error == nil && parsedData.Data != nil && parsedData.Parser != nil {
-					err := (*parsedData.Parser).IndexBlockEvent(parsedData.Data, db, *blockDBWrapper.Block, blockEvent.BlockEvent, blockEvent.Attributes, conf)
+					identifier := (*parsedData.Parser).Identifier()
+					countedTransaction, rowsWritten := withRowCounter(db)
+					start := time.Now()
+					// Run each parser in a savepoint so a panicking parser only rolls back its own writes
+					err := countedTransaction.Transaction(func(parserTransaction *gorm.DB) error {
+						return parsers.RecoverParser(func() error {
+							if contextualParser, ok := (*parsedData.Parser).(parsers.ContextualBlockEventParser); ok {
+								return contextualParser.IndexBlockEventWithContext(parsedData.Data, parsers.BlockEventIndexContext{
+									Config:     conf,
+									DB:         parserTransaction,
+									Block:      *blockDBWrapper.Block,
+									BlockEvent: blockEvent.BlockEvent,
+									Attributes: blockEvent.Attributes,
+								})
+							}
+							return (*parsedData.Parser).IndexBlockEvent(parsedData.Data, parserTransaction, *blockDBWrapper.Block, blockEvent.BlockEvent, blockEvent.Attributes, conf)
+						})
+					})
+					metrics.ObserveParserExecution(identifier, metrics.ParserStageIndex, time.Since(start), err)
 					if err != nil {
-						config.Log.Error("Error indexing block event.", err)
-						return err
+						if !parsers.IsIsolatedFailure(err) {
+							config.Log.Error("Error indexing block event.", err)
+							return err
+						}
+
+						config.Log.Errorf("Custom parser %s failed indexing block event: %v", identifier, err)
+						err = CreateBlockEventParserError(db, blockEvent.BlockEvent, parserTrackers[identifier], err)
+						if err != nil {
+							config.Log.Error("Error indexing block event error.", err)
+							return err
+						}
+						continue
 					}
+					addParserRows(db, identifier, *rowsWritten)
 				} else if parsedData.Error != nil {
 					err := CreateBlockEventParserError(db, blockEvent.BlockEvent, parserTrackers[(*parsedData.Parser).Identifier()], parsedData.Error)
 					if err != nil {
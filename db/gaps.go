@@ -0,0 +1,73 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// HeightRange is a range of heights including both bounds
+type HeightRange struct {
+	Start int64
+	End   int64
+}
+
+// Blocks returns the number of heights in the range
+func (r HeightRange) Blocks() int64 {
+	return r.End - r.Start + 1
+}
+
+// FindMissingHeights returns the ranges of heights from start to end, ordered by height, that have neither a block whose
+// transactions or block events were indexed nor a block recorded in failed_blocks or failed_event_blocks. Failed blocks
+// are left to base.reattempt-failed-blocks, the missing heights are those nothing was written for, e.g. after a crash.
+func FindMissingHeights(db *gorm.DB, chainID uint, start int64, end int64) ([]HeightRange, error) {
+	var gaps []HeightRange
+	if end < start {
+		return gaps, nil
+	}
+
+	// The bounds are added as covered heights so the gaps at the start and end of the range are found like the others
+	err := db.Raw(fmt.Sprintf(`WITH heights AS (
+			SELECT height FROM %q WHERE chain_id = @chain_id AND height BETWEEN @start AND @end AND (tx_indexed OR block_events_indexed)
+			UNION
+			SELECT height FROM %q WHERE blockchain_id = @chain_id AND height BETWEEN @start AND @end
+			UNION
+			SELECT height FROM %q WHERE blockchain_id = @chain_id AND height BETWEEN @start AND @end
+			UNION
+			SELECT @start - 1
+			UNION
+			SELECT @end + 1
+		)
+		SELECT height + 1 AS start, next_height - 1 AS "end" FROM (
+			SELECT height, LEAD(height) OVER (ORDER BY height) AS next_height FROM heights
+		) islands WHERE next_height > height + 1 ORDER BY height`,
+		TableName(db, &models.Block{}), TableName(db, &models.FailedBlock{}), TableName(db, &models.FailedEventBlock{})),
+		map[string]any{"chain_id": chainID, "start": start, "end": end}).Scan(&gaps).Error
+	return gaps, err
+}
+
+// OverlappingHeights returns the heights of the ranges in b that are also in a range in a. Both are ordered by height
+// and their ranges do not overlap, like the ranges returned by FindMissingHeights.
+func OverlappingHeights(a []HeightRange, b []HeightRange) []HeightRange {
+	var overlaps []HeightRange
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		start, end := a[i].Start, a[i].End
+		if b[j].Start > start {
+			start = b[j].Start
+		}
+		if b[j].End < end {
+			end = b[j].End
+		}
+		if start <= end {
+			overlaps = append(overlaps, HeightRange{Start: start, End: end})
+		}
+
+		if a[i].End < b[j].End {
+			i++
+		} else {
+			j++
+		}
+	}
+	return overlaps
+}
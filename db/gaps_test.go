@@ -0,0 +1,29 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type GapsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *GapsTestSuite) TestOverlappingHeights() {
+	previous := []HeightRange{{Start: 1, End: 5}, {Start: 10, End: 10}, {Start: 20, End: 30}}
+	current := []HeightRange{{Start: 3, End: 12}, {Start: 25, End: 25}, {Start: 28, End: 40}}
+	suite.Require().Equal([]HeightRange{{Start: 3, End: 5}, {Start: 10, End: 10}, {Start: 25, End: 25}, {Start: 28, End: 30}}, OverlappingHeights(previous, current))
+
+	suite.Require().Empty(OverlappingHeights(previous, nil))
+	suite.Require().Empty(OverlappingHeights([]HeightRange{{Start: 1, End: 2}}, []HeightRange{{Start: 3, End: 4}}))
+}
+
+func (suite *GapsTestSuite) TestBlocks() {
+	suite.Require().Equal(int64(1), HeightRange{Start: 7, End: 7}.Blocks())
+	suite.Require().Equal(int64(10), HeightRange{Start: 1, End: 10}.Blocks())
+}
+
+func TestGapsSuite(t *testing.T) {
+	suite.Run(t, new(GapsTestSuite))
+}
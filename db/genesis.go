@@ -0,0 +1,119 @@
+package db
+
+import (
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// GenesisDBWrapper is the state of a genesis file to import. The addresses and denoms of its rows are set by value and
+// looked up or created when it is imported.
+type GenesisDBWrapper struct {
+	State      models.GenesisState
+	Balances   []models.GenesisBalance
+	Supply     []models.GenesisSupply
+	Validators []models.GenesisValidator
+	Params     []models.GenesisParam
+	Contracts  []models.GenesisContract
+}
+
+// IndexGenesis imports the genesis state of the chain in a single transaction, replacing a genesis imported before
+func IndexGenesis(db *gorm.DB, chainID uint, genesis *GenesisDBWrapper) error {
+	return transaction(db, func(dbTransaction *gorm.DB) error {
+		for _, model := range []any{&models.GenesisBalance{}, &models.GenesisSupply{}, &models.GenesisValidator{}, &models.GenesisParam{}, &models.GenesisContract{}, &models.GenesisState{}} {
+			if err := dbTransaction.Where("chain_id = ?", chainID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		genesis.State.ChainID = chainID
+		genesis.State.ImportedAt = time.Now()
+		if err := dbTransaction.Omit("Chain").Create(&genesis.State).Error; err != nil {
+			return err
+		}
+
+		denoms := coinDenoms{db: dbTransaction, ids: map[string]uint{}}
+		addresses := map[string]uint{}
+		addressID := func(address string) (uint, error) {
+			if id, ok := addresses[address]; ok {
+				return id, nil
+			}
+			found, err := FindOrCreateAddressByAddress(dbTransaction, address)
+			if err != nil {
+				return 0, err
+			}
+			addresses[address] = found.ID
+			return found.ID, nil
+		}
+
+		for i := range genesis.Balances {
+			balance := &genesis.Balances[i]
+			var err error
+			balance.ChainID = chainID
+			if balance.AddressID, err = addressID(balance.Address.Address); err != nil {
+				return err
+			}
+			if balance.DenomID, err = denoms.id(balance.Denom.Base); err != nil {
+				return err
+			}
+		}
+		for i := range genesis.Supply {
+			supply := &genesis.Supply[i]
+			var err error
+			supply.ChainID = chainID
+			if supply.DenomID, err = denoms.id(supply.Denom.Base); err != nil {
+				return err
+			}
+		}
+		for i := range genesis.Validators {
+			validator := &genesis.Validators[i]
+			validator.ChainID = chainID
+			if validator.ConsAddress != nil {
+				id, err := addressID(validator.ConsAddress.Address)
+				if err != nil {
+					return err
+				}
+				validator.ConsAddressID = &id
+			}
+		}
+		for i := range genesis.Params {
+			genesis.Params[i].ChainID = chainID
+		}
+		for i := range genesis.Contracts {
+			contract := &genesis.Contracts[i]
+			var err error
+			contract.ChainID = chainID
+			if contract.AddressID, err = addressID(contract.Address.Address); err != nil {
+				return err
+			}
+		}
+
+		if len(genesis.Balances) > 0 {
+			if err := dbTransaction.Omit("Chain", "Address", "Denom").CreateInBatches(genesis.Balances, 500).Error; err != nil {
+				return err
+			}
+		}
+		if len(genesis.Supply) > 0 {
+			if err := dbTransaction.Omit("Chain", "Denom").CreateInBatches(genesis.Supply, 500).Error; err != nil {
+				return err
+			}
+		}
+		if len(genesis.Validators) > 0 {
+			if err := dbTransaction.Omit("Chain", "ConsAddress").CreateInBatches(genesis.Validators, 500).Error; err != nil {
+				return err
+			}
+		}
+		if len(genesis.Params) > 0 {
+			if err := dbTransaction.Omit("Chain").CreateInBatches(genesis.Params, 500).Error; err != nil {
+				return err
+			}
+		}
+		if len(genesis.Contracts) > 0 {
+			if err := dbTransaction.Omit("Chain", "Address").CreateInBatches(genesis.Contracts, 500).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,39 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpsertHeightStates records the stages the heights reached. A state with QueuedAt set starts a new pass of its height,
+// which clears the stages reached by the previous pass, otherwise the stages not set in the state are kept.
+func UpsertHeightStates(db *gorm.DB, states []models.HeightState) error {
+	if len(states) == 0 {
+		return nil
+	}
+
+	return db.Clauses(upsertHeightStatesClause()).Omit("Chain").Create(&states).Error
+}
+
+func upsertHeightStatesClause() clause.OnConflict {
+	assignments := clause.AssignmentColumns([]string{"state", "updated_at"})
+	assignments = append(assignments, clause.Assignment{
+		Column: clause.Column{Name: "queued_at"},
+		Value:  gorm.Expr("COALESCE(excluded.queued_at, ?)", clause.Column{Table: clause.CurrentTable, Name: "queued_at"}),
+	})
+	for _, column := range []string{"fetched_at", "parsed_at", "committed_at", "verified_at"} {
+		assignments = append(assignments, clause.Assignment{
+			Column: clause.Column{Name: column},
+			Value: gorm.Expr(fmt.Sprintf("CASE WHEN excluded.queued_at IS NULL THEN COALESCE(excluded.%[1]s, ?) ELSE excluded.%[1]s END", column),
+				clause.Column{Table: clause.CurrentTable, Name: column}),
+		})
+	}
+
+	return clause.OnConflict{
+		Columns:   []clause.Column{{Name: "height"}, {Name: "blockchain_id"}},
+		DoUpdates: assignments,
+	}
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// involvedAddresses returns the addresses each transaction touched by its hash: its signers, its fee payers and the
+// account addresses of the chain in the attribute values of its message events. Attribute values are only matched
+// when the account prefix is known.
+func involvedAddresses(txs []TxDBWrapper, accountPrefix string) map[string][]string {
+	involved := make(map[string][]string, len(txs))
+	for _, tx := range txs {
+		addresses := make(map[string]struct{})
+		for _, signer := range tx.Tx.SignerAddresses {
+			addresses[signer.Address] = struct{}{}
+		}
+		for _, fee := range tx.Tx.Fees {
+			addresses[fee.PayerAddress.Address] = struct{}{}
+		}
+		for _, message := range tx.Messages {
+			for _, event := range message.MessageEvents {
+				for _, attribute := range event.Attributes {
+					if isAccountAddress(attribute.Value, accountPrefix) {
+						addresses[attribute.Value] = struct{}{}
+					}
+				}
+			}
+		}
+		delete(addresses, "")
+
+		sorted := make([]string, 0, len(addresses))
+		for address := range addresses {
+			sorted = append(sorted, address)
+		}
+		sort.Strings(sorted)
+		involved[tx.Tx.Hash] = sorted
+	}
+	return involved
+}
+
+// isAccountAddress is true for bech32 addresses with the account prefix, e.g. accounts and contracts but not validator
+// operators
+func isAccountAddress(value string, accountPrefix string) bool {
+	if accountPrefix == "" || !strings.HasPrefix(value, accountPrefix+"1") {
+		return false
+	}
+	hrp, _, err := bech32.DecodeAndConvert(value)
+	return err == nil && hrp == accountPrefix
+}
+
+// indexAddressInvolvements replaces the involvements of the txes with the involved addresses, which must already be
+// indexed. Must be called inside the indexing transaction, after the txes were written.
+func indexAddressInvolvements(db *gorm.DB, block models.Block, txs []TxDBWrapper, txIDs []uint, involved map[string][]string, addresses map[string]models.Address) error {
+	if len(txIDs) == 0 {
+		return nil
+	}
+
+	if err := db.Where("tx_id IN ?", txIDs).Delete(&models.AddressInvolvement{}).Error; err != nil {
+		return err
+	}
+
+	var involvements []models.AddressInvolvement
+	for _, tx := range txs {
+		for _, address := range involved[tx.Tx.Hash] {
+			involvements = append(involvements, models.AddressInvolvement{AddressID: addresses[address].ID, Height: block.Height, TxID: tx.Tx.ID})
+		}
+	}
+
+	if len(involvements) == 0 {
+		return nil
+	}
+
+	return db.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(involvements, 1000).Error
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/stretchr/testify/suite"
+)
+
+type InvolvementsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *InvolvementsTestSuite) TestInvolvedAddresses() {
+	address := func(prefix string, seed byte) string {
+		encoded, err := bech32.ConvertAndEncode(prefix, []byte{seed, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19})
+		suite.Require().NoError(err)
+		return encoded
+	}
+	signer, recipient, contract := address("cosmos", 1), address("cosmos", 2), address("cosmos", 3)
+
+	attribute := func(value string) models.MessageEventAttribute {
+		return models.MessageEventAttribute{Value: value}
+	}
+	txs := []TxDBWrapper{{
+		Tx: models.Tx{
+			Hash:            "ABCD",
+			SignerAddresses: []models.Address{{Address: signer}},
+			Fees:            []models.Fee{{PayerAddress: models.Address{Address: signer}}},
+		},
+		Messages: []MessageDBWrapper{{
+			MessageEvents: []MessageEventDBWrapper{{
+				Attributes: []models.MessageEventAttribute{
+					attribute(signer),
+					attribute(recipient),
+					attribute(contract),
+					attribute("100uatom"),
+					attribute(address("cosmosvaloper", 4)),
+					attribute(address("osmo", 5)),
+					attribute("cosmos1notanaddress"),
+				},
+			}},
+		}},
+	}, {
+		Tx: models.Tx{Hash: "EF01"},
+	}}
+
+	involved := involvedAddresses(txs, "cosmos")
+	suite.Require().ElementsMatch([]string{signer, recipient, contract}, involved["ABCD"])
+	suite.Require().Empty(involved["EF01"])
+
+	// Without the account prefix only the signers and fee payers are known
+	involved = involvedAddresses(txs, "")
+	suite.Require().Equal([]string{signer}, involved["ABCD"])
+}
+
+func TestInvolvementsTestSuite(t *testing.T) {
+	suite.Run(t, new(InvolvementsTestSuite))
+}
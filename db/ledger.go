@@ -0,0 +1,259 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ledgerTransferKeys are the attributes of transfer events the ledger is projected from
+var ledgerTransferKeys = []string{"sender", "recipient", "amount"}
+
+type ledgerAttribute struct {
+	EventID           uint
+	TxID              *uint
+	Reference         string
+	LifecyclePosition models.BlockLifecyclePosition
+	Index             uint64
+	Key               string
+	Value             string `gorm:"serializer:attribute_value"`
+	CompressedValue   []byte `gorm:"serializer:attribute_value"`
+}
+
+// ledgerTransfer is a transfer of an amount of coins, e.g. 10uatom,5uosmo, from the sender to the recipient
+type ledgerTransfer struct {
+	eventID   uint
+	txID      *uint
+	reference string
+	sender    string
+	recipient string
+	amount    string
+}
+
+// projectTxLedger replaces the ledger entries of the fees and message transfer events of the block. Must be called
+// inside the indexing transaction, after the transactions and their events were written, so the ledger always
+// matches the indexed data. Failed transactions only moved their fees. With rollups the address activity rollups are
+// updated with the difference.
+func projectTxLedger(db *gorm.DB, block *models.Block, feeAccount string, rollups bool) error {
+	if rollups {
+		if err := rollUpLedgerEntries(db, block, false, -1); err != nil {
+			return err
+		}
+	}
+
+	if err := db.Where("block_id = ? AND block_event_id IS NULL", block.ID).Delete(&models.LedgerEntry{}).Error; err != nil {
+		return err
+	}
+
+	var fees []struct {
+		ID      uint
+		TxID    uint
+		Hash    string
+		Payer   string
+		DenomID uint
+		Amount  decimal.Decimal
+	}
+	err := db.Raw(fmt.Sprintf(`SELECT f.id, t.id AS tx_id, t.hash, a.address AS payer, f.denomination_id AS denom_id, f.amount FROM %q f
+		JOIN %q t ON t.id = f.tx_id
+		JOIN %q a ON a.id = f.payer_address_id
+		WHERE t.block_id = ? ORDER BY f.id`,
+		TableName(db, &models.Fee{}), TableName(db, &models.Tx{}), TableName(db, &models.Address{})), block.ID).Scan(&fees).Error
+	if err != nil {
+		return err
+	}
+
+	var entries []models.LedgerEntry
+	for i := range fees {
+		fee := &fees[i]
+		entries = appendLedgerMovement(entries, models.LedgerEntry{
+			BlockID:   block.ID,
+			DenomID:   fee.DenomID,
+			Amount:    fee.Amount,
+			Kind:      models.FlowFee,
+			Reference: fee.Hash,
+			TxID:      &fee.TxID,
+			FeeID:     &fee.ID,
+		}, fee.Payer, feeAccount)
+	}
+
+	var attributes []ledgerAttribute
+	err = db.Raw(fmt.Sprintf(`SELECT e.id AS event_id, t.id AS tx_id, t.hash AS reference, k.key, a.value, a.compressed_value FROM %q a
+		JOIN %q k ON k.id = a.message_event_attribute_key_id
+		JOIN %q e ON e.id = a.message_event_id
+		JOIN %q et ON et.id = e.message_event_type_id
+		JOIN %q m ON m.id = e.message_id
+		JOIN %q t ON t.id = m.tx_id
+		WHERE t.block_id = ? AND t.code = 0 AND et.type = 'transfer' AND k.key IN ?
+		ORDER BY e.id, a.index`,
+		TableName(db, &models.MessageEventAttribute{}), TableName(db, &models.MessageEventAttributeKey{}),
+		TableName(db, &models.MessageEvent{}), TableName(db, &models.MessageEventType{}),
+		TableName(db, &models.Message{}), TableName(db, &models.Tx{})), block.ID, ledgerTransferKeys).Scan(&attributes).Error
+	if err != nil {
+		return err
+	}
+
+	entries, err = appendLedgerTransfers(db, entries, block, groupLedgerTransfers(attributes), func(entry *models.LedgerEntry, eventID uint) {
+		entry.MessageEventID = &eventID
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := createLedgerEntries(db, entries); err != nil {
+		return err
+	}
+
+	if rollups {
+		return rollUpLedgerEntries(db, block, false, 1)
+	}
+	return nil
+}
+
+// projectBlockEventLedger replaces the ledger entries of the transfer events of the begin and end blockers of the
+// block, such as minting and reward distribution. Must be called inside the block event indexing transaction.
+func projectBlockEventLedger(db *gorm.DB, block *models.Block, rollups bool) error {
+	if rollups {
+		if err := rollUpLedgerEntries(db, block, true, -1); err != nil {
+			return err
+		}
+	}
+
+	if err := db.Where("block_id = ? AND block_event_id IS NOT NULL", block.ID).Delete(&models.LedgerEntry{}).Error; err != nil {
+		return err
+	}
+
+	var attributes []ledgerAttribute
+	err := db.Raw(fmt.Sprintf(`SELECT e.id AS event_id, e.lifecycle_position, e.index, k.key, a.value, a.compressed_value FROM %q a
+		JOIN %q k ON k.id = a.block_event_attribute_key_id
+		JOIN %q e ON e.id = a.block_event_id
+		JOIN %q et ON et.id = e.block_event_type_id
+		WHERE e.block_id = ? AND et.type = 'transfer' AND k.key IN ?
+		ORDER BY e.id, a.index`,
+		TableName(db, &models.BlockEventAttribute{}), TableName(db, &models.BlockEventAttributeKey{}),
+		TableName(db, &models.BlockEvent{}), TableName(db, &models.BlockEventType{})), block.ID, ledgerTransferKeys).Scan(&attributes).Error
+	if err != nil {
+		return err
+	}
+
+	for i := range attributes {
+		lifecycle := "begin_block"
+		if attributes[i].LifecyclePosition == models.EndBlockEvent {
+			lifecycle = "end_block"
+		}
+		attributes[i].Reference = fmt.Sprintf("%s:%d", lifecycle, attributes[i].Index)
+	}
+
+	entries, err := appendLedgerTransfers(db, nil, block, groupLedgerTransfers(attributes), func(entry *models.LedgerEntry, eventID uint) {
+		entry.BlockEventID = &eventID
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := createLedgerEntries(db, entries); err != nil {
+		return err
+	}
+
+	if rollups {
+		return rollUpLedgerEntries(db, block, true, 1)
+	}
+	return nil
+}
+
+// groupLedgerTransfers groups the attributes of transfer events, ordered by event and attribute index, into transfers.
+// Older SDK versions merged the transfer events of a message into one event, so an attribute seen again starts the
+// next transfer.
+func groupLedgerTransfers(attributes []ledgerAttribute) []ledgerTransfer {
+	var transfers []ledgerTransfer
+	var current *ledgerTransfer
+	seen := map[string]struct{}{}
+
+	for _, attribute := range attributes {
+		_, repeated := seen[attribute.Key]
+		if current == nil || current.eventID != attribute.EventID || repeated {
+			transfers = append(transfers, ledgerTransfer{eventID: attribute.EventID, txID: attribute.TxID, reference: attribute.Reference})
+			current = &transfers[len(transfers)-1]
+			seen = map[string]struct{}{}
+		}
+		seen[attribute.Key] = struct{}{}
+
+		switch attribute.Key {
+		case "sender":
+			current.sender = attribute.Value
+		case "recipient":
+			current.recipient = attribute.Value
+		case "amount":
+			current.amount = attribute.Value
+		}
+	}
+
+	return transfers
+}
+
+// appendLedgerTransfers appends the entries of every coin of the transfers. Transfers missing a side and amounts that
+// are not coins are skipped.
+func appendLedgerTransfers(db *gorm.DB, entries []models.LedgerEntry, block *models.Block, transfers []ledgerTransfer, setEvent func(*models.LedgerEntry, uint)) ([]models.LedgerEntry, error) {
+	denomIDs := map[string]uint{}
+
+	for _, transfer := range transfers {
+		if transfer.sender == "" || transfer.recipient == "" {
+			continue
+		}
+
+		for _, coin := range strings.Split(transfer.amount, ",") {
+			match := flowCoinPattern.FindStringSubmatch(strings.TrimSpace(coin))
+			if match == nil {
+				continue
+			}
+			amount, err := decimal.NewFromString(match[1])
+			if err != nil {
+				continue
+			}
+
+			denomID, ok := denomIDs[match[2]]
+			if !ok {
+				denom, err := FindOrCreateDenomByBase(db, match[2])
+				if err != nil {
+					return nil, err
+				}
+				denomID = denom.ID
+				denomIDs[match[2]] = denomID
+			}
+
+			entry := models.LedgerEntry{
+				BlockID:   block.ID,
+				DenomID:   denomID,
+				Amount:    amount,
+				Kind:      models.FlowTransfer,
+				Reference: transfer.reference,
+				TxID:      transfer.txID,
+			}
+			setEvent(&entry, transfer.eventID)
+			entries = appendLedgerMovement(entries, entry, transfer.sender, transfer.recipient)
+		}
+	}
+
+	return entries, nil
+}
+
+// appendLedgerMovement books the movement of the entry's amount from one account to another, as a credit to the
+// account it left and a debit to the account it entered
+func appendLedgerMovement(entries []models.LedgerEntry, entry models.LedgerEntry, from string, to string) []models.LedgerEntry {
+	credit := entry
+	credit.Side, credit.Account, credit.Counterparty = models.LedgerCredit, from, to
+
+	debit := entry
+	debit.Side, debit.Account, debit.Counterparty = models.LedgerDebit, to, from
+
+	return append(entries, credit, debit)
+}
+
+func createLedgerEntries(db *gorm.DB, entries []models.LedgerEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return db.Omit("Denom").CreateInBatches(entries, 500).Error
+}
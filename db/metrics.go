@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type rowCounterKey struct{}
+
+// Registers callbacks that add the rows affected by each statement to a counter carried on the statement context.
+// Only statements executed through a DB handle returned by withRowCounter are counted.
+func registerRowCountCallbacks(db *gorm.DB) error {
+	countRows := func(tx *gorm.DB) {
+		if tx.Error != nil || tx.Statement.Context == nil {
+			return
+		}
+		if counter, ok := tx.Statement.Context.Value(rowCounterKey{}).(*int64); ok {
+			atomic.AddInt64(counter, tx.RowsAffected)
+		}
+	}
+
+	if err := db.Callback().Create().After("gorm:create").Register("indexer:count_rows", countRows); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("indexer:count_rows", countRows); err != nil {
+		return err
+	}
+	if err := db.Callback().Delete().After("gorm:delete").Register("indexer:count_rows", countRows); err != nil {
+		return err
+	}
+	return db.Callback().Raw().After("gorm:raw").Register("indexer:count_rows", countRows)
+}
+
+// Returns a DB handle that counts all rows written through it
+func withRowCounter(db *gorm.DB) (*gorm.DB, *int64) {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	counter := new(int64)
+	return db.WithContext(context.WithValue(ctx, rowCounterKey{}, counter)), counter
+}
+
+type parserRowsKey struct{}
+
+// parserRows holds the rows custom parsers wrote in a transaction until it commits, rows of a transaction that rolled
+// back were not written
+type parserRows struct {
+	// nested rows are counted by the outermost transaction
+	nested bool
+	rows   map[string]int64
+}
+
+// withParserRows returns a DB handle whose transaction collects the rows written by custom parsers. Transactions nested
+// in one that collects them already add to its rows.
+func withParserRows(db *gorm.DB) (*gorm.DB, *parserRows) {
+	ctx := db.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if _, ok := ctx.Value(parserRowsKey{}).(*parserRows); ok {
+		return db, &parserRows{nested: true}
+	}
+
+	rows := &parserRows{}
+	return db.WithContext(context.WithValue(ctx, parserRowsKey{}, rows)), rows
+}
+
+func (r *parserRows) reset() {
+	if !r.nested {
+		r.rows = map[string]int64{}
+	}
+}
+
+func (r *parserRows) commit() {
+	if r.nested {
+		return
+	}
+	for identifier, rows := range r.rows {
+		metrics.AddParserRowsWritten(identifier, rows)
+	}
+}
+
+// addParserRows counts the rows a custom parser wrote once the transaction of db commits. Outside of a transaction the
+// rows are counted right away.
+func addParserRows(db *gorm.DB, identifier string, rows int64) {
+	if db.Statement.Context != nil {
+		if parserRows, ok := db.Statement.Context.Value(parserRowsKey{}).(*parserRows); ok {
+			parserRows.rows[identifier] += rows
+			return
+		}
+	}
+	metrics.AddParserRowsWritten(identifier, rows)
+}
+
+// UpsertParserExecutionMetrics adds the counts of the stats to the execution metrics of the parsers on the chain, so the
+// stats must only hold the counts added since they were last written
+func UpsertParserExecutionMetrics(db *gorm.DB, chainID string, stats []metrics.ParserStats) error {
+	if len(stats) == 0 {
+		return nil
+	}
+
+	parserMetrics := make([]models.ParserExecutionMetric, len(stats))
+	for index, stat := range stats {
+		parserMetrics[index] = models.ParserExecutionMetric{
+			ChainID:         chainID,
+			Identifier:      stat.Identifier,
+			Stage:           string(stat.Stage),
+			Invocations:     stat.Invocations,
+			Errors:          stat.Errors,
+			P50Microseconds: stat.P50.Microseconds(),
+			P99Microseconds: stat.P99.Microseconds(),
+			RowsWritten:     stat.RowsWritten,
+		}
+	}
+
+	table := TableName(db, &models.ParserExecutionMetric{})
+	added := func(column string) clause.Assignment {
+		return clause.Assignment{Column: clause.Column{Name: column}, Value: gorm.Expr(fmt.Sprintf("%q.%s + EXCLUDED.%s", table, column, column))}
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "chain_id"}, {Name: "identifier"}, {Name: "stage"}},
+		DoUpdates: append(clause.Set{added("invocations"), added("errors"), added("rows_written")},
+			clause.AssignmentColumns([]string{"p50_microseconds", "p99_microseconds", "updated_at"})...),
+	}).Create(&parserMetrics).Error
+}
@@ -26,6 +26,19 @@ type BlockDBWrapper struct {
 	UniqueBlockEventAttributeKeys map[string]models.BlockEventAttributeKey
 }
 
+// BlockCommitDBWrapper is the commit included in a block with the signatures of its validators, whose addresses are
+// looked up or created when it is indexed
+type BlockCommitDBWrapper struct {
+	Commit     models.BlockCommit
+	Signatures []models.BlockCommitSignature
+}
+
+// BlockEvidenceDBWrapper is an evidence included in a block with the validators it proves misbehaved
+type BlockEvidenceDBWrapper struct {
+	Evidence  models.BlockEvidence
+	Offenders []models.EvidenceOffender
+}
+
 type BlockEventDBWrapper struct {
 	BlockEvent               models.BlockEvent
 	Attributes               []models.BlockEventAttribute
@@ -36,6 +49,7 @@ type BlockEventDBWrapper struct {
 type TxDBWrapper struct {
 	Tx                         models.Tx
 	Messages                   []MessageDBWrapper
+	FailedMessages             []models.FailedMessage // Messages that could not be decoded, Messages holds placeholders for them
 	UniqueMessageTypes         map[string]models.MessageType
 	UniqueMessageEventTypes    map[string]models.MessageEventType
 	UniqueMessageAttributeKeys map[string]models.MessageEventAttributeKey
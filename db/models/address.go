@@ -4,3 +4,12 @@ type Address struct {
 	ID      uint
 	Address string `gorm:"uniqueIndex"`
 }
+
+// AddressInvolvement records that a transaction touched an address: the address signed it, paid its fees or is named
+// in an attribute of its message events. The primary key leads with the address, so the transactions of an address
+// are read from the index in height order without scanning the event attributes.
+type AddressInvolvement struct {
+	AddressID uint  `gorm:"primaryKey;autoIncrement:false"`
+	Height    int64 `gorm:"primaryKey;autoIncrement:false"`
+	TxID      uint  `gorm:"primaryKey;autoIncrement:false;index"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AddressActivity is the running total of the amounts of a denom an account received, sent and paid in fees, rolled up
+// from the ledger entries of the indexed blocks
+type AddressActivity struct {
+	ID        uint
+	Account   string `gorm:"uniqueIndex:idx_address_activity_account_denom,priority:1"`
+	DenomID   uint   `gorm:"uniqueIndex:idx_address_activity_account_denom,priority:2"`
+	Denom     Denom
+	VolumeIn  decimal.Decimal `gorm:"type:decimal(78,0);"`
+	VolumeOut decimal.Decimal `gorm:"type:decimal(78,0);"`
+	FeesPaid  decimal.Decimal `gorm:"type:decimal(78,0);"`
+}
+
+// AddressSummary is the running summary of the activity of an account: the number of transactions it paid fees or
+// moved funds in, and the first and last block it was active in
+type AddressSummary struct {
+	ID            uint
+	Account       string `gorm:"uniqueIndex"`
+	TxCount       int64
+	FirstHeight   int64
+	FirstActivity time.Time
+	LastHeight    int64
+	LastActivity  time.Time
+}
@@ -5,16 +5,30 @@ import (
 )
 
 type Block struct {
-	ID                    uint
-	TimeStamp             time.Time
-	Height                int64 `gorm:"uniqueIndex:chainheight"`
-	ChainID               uint  `gorm:"uniqueIndex:chainheight"`
+	ID uint
+	// TimeStamp is indexed with the chain for the time range and time bucket queries
+	TimeStamp             time.Time `gorm:"index:idx_blocks_chain_time,priority:2"`
+	Height                int64     `gorm:"uniqueIndex:chainheight"`
+	ChainID               uint      `gorm:"uniqueIndex:chainheight;index:idx_blocks_chain_time,priority:1"`
 	Chain                 Chain
 	ProposerConsAddress   Address
 	ProposerConsAddressID uint
 	TxIndexed             bool
 	// TODO: Should block event indexing be split out or rolled up?
 	BlockEventsIndexed bool
+	// CustomParsersApplied is set once the custom parsers ran on the data indexed for the block. Indexing the block again
+	// resets it until they ran on the new data.
+	CustomParsersApplied bool
+	// Verified is set once the indexed data of the block was checked against the chain, indexing the block again resets it
+	Verified bool
+	// Valued is set once the flows of the block were valued in USD, indexing the block again resets it
+	Valued bool
+	// DexPricesDerived is set once the prices of the swaps of the block were derived, indexing the block again resets it
+	DexPricesDerived bool
+	// Hash is the hex encoded hash of the block, the next block refers to it as its parent, which detects reorgs
+	Hash string
+	// ParentHash is the hex encoded hash of the previous block, checked against the block indexed for that height
+	ParentHash string
 }
 
 // Used to keep track of BeginBlock and EndBlock events
@@ -46,9 +60,12 @@ type BlockEventType struct {
 type BlockEventAttribute struct {
 	ID           uint
 	BlockEvent   BlockEvent
-	BlockEventID uint `gorm:"uniqueIndex:eventAttributeIndex,priority:1"`
-	Value        string
-	Index        uint64 `gorm:"uniqueIndex:eventAttributeIndex,priority:2"`
+	BlockEventID uint   `gorm:"uniqueIndex:eventAttributeIndex,priority:1"`
+	Value        string `gorm:"serializer:attribute_value;type:text"`
+	// CompressedValue holds the zstd compressed value of a large value, which leaves value NULL. It is only set in the
+	// database, the serializer reads it into Value and writes it from Value.
+	CompressedValue []byte `gorm:"serializer:attribute_value;type:bytea"`
+	Index           uint64 `gorm:"uniqueIndex:eventAttributeIndex,priority:2"`
 	// Keys are limited to a smallish subset of string values set by the Cosmos SDK and external modules
 	// Save DB space by storing the key as a foreign key
 	BlockEventAttributeKeyID uint
@@ -65,6 +82,7 @@ type FailedBlock struct {
 	Height       int64 `gorm:"uniqueIndex:failedchainheight"`
 	BlockchainID uint  `gorm:"uniqueIndex:failedchainheight"`
 	Chain        Chain `gorm:"foreignKey:BlockchainID"`
+	BlockFailure
 }
 
 type FailedEventBlock struct {
@@ -72,4 +90,93 @@ type FailedEventBlock struct {
 	Height       int64 `gorm:"uniqueIndex:failedchaineventheight"`
 	BlockchainID uint  `gorm:"uniqueIndex:failedchaineventheight"`
 	Chain        Chain `gorm:"foreignKey:BlockchainID"`
+	BlockFailure
+}
+
+// HeightState is the last stage of the indexing pipeline a height reached. The time each stage was reached is kept for
+// the latest pass of the height, which starts when the height is queued again.
+type HeightState struct {
+	ID           uint
+	Height       int64       `gorm:"uniqueIndex:chainheightstate"`
+	BlockchainID uint        `gorm:"uniqueIndex:chainheightstate"`
+	Chain        Chain       `gorm:"foreignKey:BlockchainID"`
+	State        HeightStage `gorm:"index"`
+	QueuedAt     *time.Time
+	FetchedAt    *time.Time
+	ParsedAt     *time.Time
+	CommittedAt  *time.Time
+	VerifiedAt   *time.Time
+	UpdatedAt    time.Time
+}
+
+// ChainHead is the latest head of a chain seen by the indexer next to the highest height it indexed, one row per
+// chain, so lag dashboards and readiness checks need a single query. LagSeconds is the difference of the block times.
+// HeadAdvancedAt is when the head height last changed, Halted is set while it has not changed for longer than the
+// halt threshold, so a halted chain is told apart from an indexer falling behind.
+type ChainHead struct {
+	BlockchainID   uint  `gorm:"primaryKey;autoIncrement:false"`
+	Chain          Chain `gorm:"foreignKey:BlockchainID"`
+	HeadHeight     int64
+	HeadTime       time.Time
+	HeadAdvancedAt time.Time
+	Halted         bool
+	IndexedHeight  int64
+	IndexedTime    *time.Time
+	LagBlocks      int64
+	LagSeconds     float64
+	UpdatedAt      time.Time
+}
+
+// IndexerCheckpoint is the height up to which every height from the start height on was indexed or recorded as failed,
+// one row per chain. An index run starting in the range resumes right after it.
+type IndexerCheckpoint struct {
+	BlockchainID uint  `gorm:"primaryKey;autoIncrement:false"`
+	Chain        Chain `gorm:"foreignKey:BlockchainID"`
+	StartHeight  int64
+	Height       int64
+	UpdatedAt    time.Time
+}
+
+// HeightStage is a stage of the indexing pipeline, in the order heights go through them
+type HeightStage string
+
+const (
+	HeightQueued    HeightStage = "queued"
+	HeightFetched   HeightStage = "fetched"
+	HeightParsed    HeightStage = "parsed"
+	HeightCommitted HeightStage = "committed"
+	HeightVerified  HeightStage = "verified"
+)
+
+// FailureCode classifies why a block failed
+type FailureCode string
+
+const (
+	FailureRPCTimeout           FailureCode = "rpc_timeout"
+	FailureRPCError             FailureCode = "rpc_error"
+	FailureUnknownMsgType       FailureCode = "unknown_msg_type"
+	FailureTxProcessing         FailureCode = "tx_processing"
+	FailureBlockEventProcessing FailureCode = "block_event_processing"
+	FailurePanic                FailureCode = "panic"
+	FailureVerification         FailureCode = "verification_failed"
+	FailureDivergence           FailureCode = "divergent_response"
+	FailureUnknown              FailureCode = "unknown"
+)
+
+// FailureComponent is the stage of the indexing pipeline a block failed in
+type FailureComponent string
+
+const (
+	ComponentRPCWorker FailureComponent = "rpc_worker"
+	ComponentProcessor FailureComponent = "processor"
+	ComponentDBWriter  FailureComponent = "db_writer"
+)
+
+// BlockFailure describes the latest failure of a block. RetryCount counts the failures after the first one, so blocks
+// failing on every reattempt stand out.
+type BlockFailure struct {
+	ErrorCode    FailureCode `gorm:"index"`
+	ErrorMessage string
+	Component    FailureComponent
+	RetryCount   int
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// CDCOutboxEvent is a change-data-capture record written in the same transaction as the indexed block data.
+// Consumers read the outbox in ID order and delete or checkpoint the rows they have processed.
+type CDCOutboxEvent struct {
+	ID        uint   `gorm:"primaryKey"`
+	ChainID   string `gorm:"index:idx_cdc_outbox_chain_height"`
+	Height    int64  `gorm:"index:idx_cdc_outbox_chain_height"`
+	Kind      string
+	Payload   string `gorm:"type:jsonb"`
+	CreatedAt time.Time
+}
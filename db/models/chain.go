@@ -5,3 +5,15 @@ type Chain struct {
 	ChainID string `gorm:"uniqueIndex"` // e.g. osmosis-1
 	Name    string // e.g. Osmosis
 }
+
+// ModuleVersion records that a module of the chain is at the version from the height on, as tracked by the x/upgrade
+// module. A row is kept for every module at the first height the versions were looked up at and for every module whose
+// version changed at an upgrade after, version 0 for a module that was removed.
+type ModuleVersion struct {
+	ID      uint
+	ChainID uint `gorm:"uniqueIndex:idx_module_version,priority:1"`
+	Chain   Chain
+	Module  string `gorm:"uniqueIndex:idx_module_version,priority:2"`
+	Height  int64  `gorm:"uniqueIndex:idx_module_version,priority:3"`
+	Version uint64
+}
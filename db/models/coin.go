@@ -0,0 +1,26 @@
+package models
+
+import "github.com/shopspring/decimal"
+
+// MessageEventAttributeCoin is a coin of a message event attribute holding coins, e.g. 5uosmo of 10uatom,5uosmo,
+// parsed at index time so amounts can be aggregated in SQL. Position is the index of the coin in the attribute value.
+// The event is stored with the coin so the coins of events can be summed per denom without joining the attributes.
+// Amounts are unscaled, decimal coins such as rewards keep their fraction.
+type MessageEventAttributeCoin struct {
+	MessageEventAttributeID uint `gorm:"primaryKey;autoIncrement:false"`
+	Position                uint `gorm:"primaryKey;autoIncrement:false"`
+	MessageEventID          uint `gorm:"index"`
+	DenomID                 uint `gorm:"index"`
+	Denom                   Denom
+	Amount                  decimal.Decimal `gorm:"type:numeric"`
+}
+
+// BlockEventAttributeCoin is a coin of a block event attribute holding coins, see MessageEventAttributeCoin
+type BlockEventAttributeCoin struct {
+	BlockEventAttributeID uint `gorm:"primaryKey;autoIncrement:false"`
+	Position              uint `gorm:"primaryKey;autoIncrement:false"`
+	BlockEventID          uint `gorm:"index"`
+	DenomID               uint `gorm:"index"`
+	Denom                 Denom
+	Amount                decimal.Decimal `gorm:"type:numeric"`
+}
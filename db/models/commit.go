@@ -0,0 +1,56 @@
+package models
+
+import "time"
+
+// BlockIDFlag tells what a validator of the set signed in a commit
+type BlockIDFlag string
+
+const (
+	// BlockIDFlagAbsent is a validator whose signature is not in the commit
+	BlockIDFlagAbsent BlockIDFlag = "absent"
+	// BlockIDFlagCommit is a validator that signed the committed block
+	BlockIDFlagCommit BlockIDFlag = "commit"
+	// BlockIDFlagNil is a validator that voted nil, e.g. because it did not receive the block in time
+	BlockIDFlagNil BlockIDFlag = "nil"
+)
+
+// BlockCommit is the commit of the previous block included in a block: the height and round the previous block was
+// committed at, with a signature row per validator of its set
+type BlockCommit struct {
+	ID uint
+	// BlockID is the block including the commit, Height the height of the committed block
+	BlockID uint `gorm:"uniqueIndex"`
+	Block   Block
+	Height  int64 `gorm:"index"`
+	Round   int32
+}
+
+// BlockCommitSignature is the slot of a validator in a commit, at its position in the validator set. The validator
+// address of absent signatures is taken from the validator set of the committed height, it is unknown when the node
+// did not return the set.
+type BlockCommitSignature struct {
+	BlockCommitID      uint `gorm:"primaryKey;autoIncrement:false"`
+	BlockCommit        BlockCommit
+	Index              int   `gorm:"primaryKey;autoIncrement:false"`
+	ValidatorAddressID *uint `gorm:"index"`
+	ValidatorAddress   *Address
+	Flag               BlockIDFlag
+	// Timestamp is when the validator signed, unset for absent signatures
+	Timestamp *time.Time
+}
+
+// ConsensusMetric holds the consensus performance of a block for network health dashboards. IntervalMs is the time
+// since the previous block, known once it is indexed. Rounds, the signatures and their participation come from the
+// commit of the block, which is included in the next block and known once that is indexed with its commit.
+type ConsensusMetric struct {
+	BlockID           uint `gorm:"primaryKey;autoIncrement:false"`
+	Block             Block
+	Height            int64 `gorm:"index"`
+	IntervalMs        *int64
+	ProposerAddressID uint
+	ProposerAddress   Address
+	Rounds            *int32
+	SignedValidators  *int
+	Validators        *int
+	ParticipationPct  *float64
+}
@@ -0,0 +1,173 @@
+package models
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+
+	"github.com/klauspost/compress/zstd"
+	"gorm.io/gorm/schema"
+)
+
+// zstdMagic starts every zstd frame, it tells compressed binary values from the values stored as they are
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+var (
+	// compressionMinSize is the size from which values of the compressed columns are written compressed, 0 disables it
+	compressionMinSize atomic.Int64
+
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+)
+
+func init() {
+	var err error
+	if zstdEncoder, err = zstd.NewWriter(nil); err != nil {
+		panic(err)
+	}
+	if zstdDecoder, err = zstd.NewReader(nil); err != nil {
+		panic(err)
+	}
+
+	schema.RegisterSerializer("zstd", ZstdSerializer{})
+	schema.RegisterSerializer("attribute_value", AttributeValueSerializer{})
+}
+
+// SetCompression makes the compressed columns write values of at least minSize bytes zstd compressed, 0 writes every
+// value as it is. Values are decompressed when read either way, so the setting can be changed on an indexed database.
+func SetCompression(minSize int) {
+	compressionMinSize.Store(int64(minSize))
+}
+
+// ZstdSerializer is the GORM serializer of the binary columns tagged `gorm:"serializer:zstd"`: the raw bytes of failed
+// transactions and of messages. Values are stored as a zstd frame. A value that already starts like a zstd frame is
+// always compressed, so it is read back unchanged.
+type ZstdSerializer struct{}
+
+func (ZstdSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var data []byte
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		data = v
+	default:
+		return fmt.Errorf("failed to scan compressed value: %#v", dbValue)
+	}
+
+	value, err := DecompressBytes(data)
+	if err != nil {
+		return err
+	}
+	return field.Set(ctx, dst, value)
+}
+
+func (ZstdSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	switch v := fieldValue.(type) {
+	case []byte:
+		if v == nil {
+			return nil, nil
+		}
+		return CompressBytes(v), nil
+	default:
+		return nil, fmt.Errorf("invalid field type %T for ZstdSerializer, only []byte supported", fieldValue)
+	}
+}
+
+// CompressBytes returns the value to store for data in a compressed binary column
+func CompressBytes(data []byte) []byte {
+	ambiguous := bytes.HasPrefix(data, zstdMagic)
+	if !ambiguous && !compressible(len(data)) {
+		return data
+	}
+
+	compressed := zstdEncoder.EncodeAll(data, make([]byte, 0, len(data)/2))
+	if !ambiguous && len(compressed) >= len(data) {
+		return data
+	}
+	return compressed
+}
+
+// DecompressBytes returns the data of a value read from a compressed binary column
+func DecompressBytes(value []byte) ([]byte, error) {
+	if !bytes.HasPrefix(value, zstdMagic) {
+		return value, nil
+	}
+
+	data, err := zstdDecoder.DecodeAll(value, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress value: %w", err)
+	}
+	return data, nil
+}
+
+// AttributeValueSerializer is the GORM serializer of the event attribute values, tagged
+// `gorm:"serializer:attribute_value"` on both the Value and the CompressedValue field of a struct. A value of at least
+// the compression size is written as a zstd frame to compressed_value with value left NULL, so SQL filtering on and
+// reading the value column keeps working for every other value. Either column is read into Value.
+type AttributeValueSerializer struct{}
+
+func (AttributeValueSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var data []byte
+	switch v := dbValue.(type) {
+	case nil:
+		// The value is in the other column
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("failed to scan attribute value: %#v", dbValue)
+	}
+
+	valueField, err := attributeValueField(field)
+	if err != nil {
+		return err
+	}
+	if field != valueField {
+		if data, err = DecompressBytes(data); err != nil {
+			return err
+		}
+	}
+	return valueField.Set(ctx, dst, string(data))
+}
+
+func (AttributeValueSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	valueField, err := attributeValueField(field)
+	if err != nil {
+		return nil, err
+	}
+
+	value, compressed := AttributeValueColumns(valueField.ReflectValueOf(ctx, dst).String())
+	if field != valueField {
+		return compressed, nil
+	}
+	return value, nil
+}
+
+// attributeValueField returns the Value field of the struct of a field tagged with the attribute value serializer
+func attributeValueField(field *schema.Field) (*schema.Field, error) {
+	valueField := field.Schema.LookUpField("Value")
+	if valueField == nil || valueField.FieldType.Kind() != reflect.String {
+		return nil, fmt.Errorf("attribute value serializer of %s.%s needs a string Value field", field.Schema.Name, field.Name)
+	}
+	return valueField, nil
+}
+
+// AttributeValueColumns returns what to store for an attribute value in the value and the compressed_value column,
+// one of the two is nil
+func AttributeValueColumns(value string) (interface{}, interface{}) {
+	if compressible(len(value)) {
+		if compressed := zstdEncoder.EncodeAll([]byte(value), make([]byte, 0, len(value)/2)); len(compressed) < len(value) {
+			return nil, compressed
+		}
+	}
+	return value, nil
+}
+
+func compressible(size int) bool {
+	minSize := compressionMinSize.Load()
+	return minSize > 0 && int64(size) >= minSize
+}
@@ -4,3 +4,23 @@ type Denom struct {
 	ID   uint
 	Base string `gorm:"uniqueIndex"`
 }
+
+// DenomMetadata is the display unit of a denom, an amount of the denom is divided by 10^Exponent to get the amount in
+// the Display denom
+type DenomMetadata struct {
+	ID       uint
+	DenomID  uint `gorm:"uniqueIndex"`
+	Denom    Denom
+	Display  string
+	Exponent uint32
+}
+
+// DenomTrace is the origin of an IBC voucher denom, which is BaseDenom sent to the chain over Path, e.g. uatom over
+// transfer/channel-0
+type DenomTrace struct {
+	ID        uint
+	DenomID   uint `gorm:"uniqueIndex"`
+	Denom     Denom
+	Path      string
+	BaseDenom string `gorm:"index"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// EvidenceType is the kind of misbehaviour a block evidence proves
+type EvidenceType string
+
+const (
+	// EvidenceDuplicateVote proves a validator signed two conflicting votes for the same height and round
+	EvidenceDuplicateVote EvidenceType = "duplicate_vote"
+	// EvidenceLightClientAttack proves validators signed a conflicting block to deceive light clients
+	EvidenceLightClientAttack EvidenceType = "light_client_attack"
+)
+
+// BlockEvidence is an evidence of misbehaviour included in a block, at its index in the evidence of the block. Height
+// is the height of the infraction, Time the time of the block at that height.
+type BlockEvidence struct {
+	ID               uint
+	BlockID          uint `gorm:"uniqueIndex:idx_block_evidence_index"`
+	Block            Block
+	Index            int    `gorm:"uniqueIndex:idx_block_evidence_index"`
+	Hash             string `gorm:"index"`
+	Type             EvidenceType
+	Height           int64 `gorm:"index"`
+	Time             time.Time
+	TotalVotingPower int64
+}
+
+// EvidenceOffender is a validator an evidence proves misbehaved, with its voting power at the infraction height. The
+// slash of the offender is taken from the double sign slash event the evidence resulted in, it is unknown when the
+// block events were not fetched.
+type EvidenceOffender struct {
+	BlockEvidenceID    uint `gorm:"primaryKey;autoIncrement:false"`
+	BlockEvidence      BlockEvidence
+	ValidatorAddressID uint `gorm:"primaryKey;autoIncrement:false"`
+	ValidatorAddress   Address
+	Power              int64
+	Slashed            bool
+	Jailed             bool
+	// BurnedCoins are the coins burned by the slash, recorded by the chains whose slash events carry them
+	BurnedCoins string
+}
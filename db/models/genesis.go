@@ -0,0 +1,81 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// GenesisState records the genesis file imported for a chain. The genesis tables hold the state of the chain before
+// its first block, height 0, so balance and supply histories are complete from chain start.
+type GenesisState struct {
+	ID            uint
+	ChainID       uint `gorm:"uniqueIndex"`
+	Chain         Chain
+	GenesisTime   time.Time
+	InitialHeight int64
+	ImportedAt    time.Time
+}
+
+// GenesisBalance is the balance of a denom held by an address at genesis
+type GenesisBalance struct {
+	ID        uint
+	ChainID   uint `gorm:"uniqueIndex:idx_genesis_balance,priority:1"`
+	Chain     Chain
+	AddressID uint `gorm:"uniqueIndex:idx_genesis_balance,priority:2"`
+	Address   Address
+	DenomID   uint `gorm:"uniqueIndex:idx_genesis_balance,priority:3"`
+	Denom     Denom
+	Amount    decimal.Decimal `gorm:"type:decimal(78,0);"`
+}
+
+// GenesisSupply is the total supply of a denom at genesis
+type GenesisSupply struct {
+	ID      uint
+	ChainID uint `gorm:"uniqueIndex:idx_genesis_supply,priority:1"`
+	Chain   Chain
+	DenomID uint `gorm:"uniqueIndex:idx_genesis_supply,priority:2"`
+	Denom   Denom
+	Amount  decimal.Decimal `gorm:"type:decimal(78,0);"`
+}
+
+// GenesisValidator is a validator of the genesis staking state, or created by a genesis transaction when Gentx is set.
+// The tokens of a genesis transaction are its self delegation.
+type GenesisValidator struct {
+	ID              uint
+	ChainID         uint `gorm:"uniqueIndex:idx_genesis_validator,priority:1"`
+	Chain           Chain
+	OperatorAddress string `gorm:"uniqueIndex:idx_genesis_validator,priority:2"`
+	ConsAddressID   *uint
+	ConsAddress     *Address
+	Moniker         string
+	Status          string
+	Jailed          bool
+	Tokens          decimal.Decimal `gorm:"type:decimal(78,0);"`
+	DelegatorShares decimal.Decimal `gorm:"type:decimal(78,18);"`
+	CommissionRate  decimal.Decimal `gorm:"type:decimal(78,18);"`
+	Gentx           bool
+}
+
+// GenesisParam is a parameter set of a module at genesis as JSON, e.g. the deposit params of gov
+type GenesisParam struct {
+	ID      uint
+	ChainID uint `gorm:"uniqueIndex:idx_genesis_param,priority:1"`
+	Chain   Chain
+	Module  string `gorm:"uniqueIndex:idx_genesis_param,priority:2"`
+	Key     string `gorm:"uniqueIndex:idx_genesis_param,priority:3"`
+	Value   string `gorm:"type:text"`
+}
+
+// GenesisContract is a CosmWasm contract instantiated in the genesis state
+type GenesisContract struct {
+	ID        uint
+	ChainID   uint `gorm:"uniqueIndex:idx_genesis_contract,priority:1"`
+	Chain     Chain
+	AddressID uint `gorm:"uniqueIndex:idx_genesis_contract,priority:2"`
+	Address   Address
+	CodeID    uint64
+	Creator   string
+	Admin     string
+	Label     string
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// JobRun is a run of an aggregation job. Trigger is what started it, blocks or cron, and Height the height of the last
+// indexed block when it started. Error is empty for runs that succeeded.
+type JobRun struct {
+	ID         uint   `gorm:"primaryKey"`
+	ChainID    string `gorm:"index:idx_job_runs_chain_job"`
+	Job        string `gorm:"index:idx_job_runs_chain_job"`
+	Trigger    string
+	Height     int64
+	StartedAt  time.Time
+	FinishedAt time.Time
+	Error      string
+}
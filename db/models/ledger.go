@@ -0,0 +1,34 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// LedgerSide is the side of the account a ledger entry is booked on
+type LedgerSide string
+
+const (
+	LedgerDebit  LedgerSide = "debit"
+	LedgerCredit LedgerSide = "credit"
+)
+
+// LedgerEntry is one side of an amount moved between two accounts by a fee or a transfer event. Every movement is
+// booked twice under the same reference, as a credit to the account the amount left and a debit to the account it
+// entered, so the debits and credits of a reference balance. Reference is the hash of the transaction, or the
+// lifecycle position and index of a block event, e.g. end_block:3.
+type LedgerEntry struct {
+	ID             uint
+	BlockID        uint   `gorm:"index"`
+	Account        string `gorm:"index:idx_ledger_account_denom,priority:1"`
+	DenomID        uint   `gorm:"index:idx_ledger_account_denom,priority:2"`
+	Denom          Denom
+	Side           LedgerSide
+	Amount         decimal.Decimal `gorm:"type:decimal(78,0);"`
+	Counterparty   string
+	Kind           FlowKind
+	Reference      string
+	TxID           *uint
+	FeeID          *uint
+	MessageEventID *uint
+	BlockEventID   *uint
+}
@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 type BlockEventParser struct {
 	ID                     uint
 	BlockLifecyclePosition BlockLifecyclePosition `gorm:"uniqueIndex:idx_block_event_parser_identifier_lifecycle_position"`
@@ -29,3 +31,18 @@ type MessageParserError struct {
 	Message         Message
 	Error           string
 }
+
+// Aggregated execution statistics for a custom parser stage on a chain, periodically added to by the indexers of the
+// chain. The percentiles are those of the indexer that wrote last.
+type ParserExecutionMetric struct {
+	ID              uint
+	ChainID         string `gorm:"uniqueIndex:idx_parser_execution_metric_chain_identifier_stage"`
+	Identifier      string `gorm:"uniqueIndex:idx_parser_execution_metric_chain_identifier_stage"`
+	Stage           string `gorm:"uniqueIndex:idx_parser_execution_metric_chain_identifier_stage"`
+	Invocations     uint64
+	Errors          uint64
+	P50Microseconds int64
+	P99Microseconds int64
+	RowsWritten     uint64
+	UpdatedAt       time.Time
+}
@@ -0,0 +1,8 @@
+package models
+
+// MessageEventSearch is the full-text document of a message event, the values of its attributes with a key selected
+// for search. Events without such attributes have no document.
+type MessageEventSearch struct {
+	MessageEventID uint   `gorm:"primaryKey;autoIncrement:false"`
+	Document       string `gorm:"type:tsvector;index:,type:gin"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// SinkCheckpoint is the last height delivered by a checkpointing sink, tracked per record type
+type SinkCheckpoint struct {
+	ID         uint   `gorm:"primaryKey"`
+	ChainID    string `gorm:"uniqueIndex:idx_sink_checkpoint"`
+	Sink       string `gorm:"uniqueIndex:idx_sink_checkpoint"`
+	RecordType string `gorm:"uniqueIndex:idx_sink_checkpoint"`
+	Height     int64
+	UpdatedAt  time.Time
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+// indexerTables are the models of the tables that queries outside of gorm read, e.g. the query package and the exports
+var indexerTables = []any{
+	&Chain{}, &Block{}, &FailedBlock{}, &FailedEventBlock{}, &Address{}, &AddressInvolvement{}, &Denom{},
+	&Tx{}, &FailedTx{}, &Fee{}, &MessageType{}, &Message{}, &FailedMessage{},
+	&MessageEventType{}, &MessageEvent{}, &MessageEventAttributeKey{}, &MessageEventAttribute{}, &MessageEventAttributeCoin{},
+	&MessageEventSearch{}, &BlockEventType{}, &BlockEvent{}, &BlockEventAttributeKey{}, &BlockEventAttribute{},
+	&BlockEventAttributeCoin{}, &BlockEventParser{}, &BlockEventParserError{}, &MessageParser{}, &MessageParserError{},
+}
+
+// indexerJoinTables are the many2many tables of the models
+var indexerJoinTables = []string{"tx_signer_addresses"}
+
+// Tables resolves the default names of the indexer tables, e.g. blocks, to their names under a naming strategy with
+// a table prefix, singular or renamed tables
+type Tables struct {
+	names    map[string]string
+	defaults map[string]string
+	replacer *strings.Replacer
+}
+
+func NewTables(namer schema.Namer) Tables {
+	tables := Tables{names: map[string]string{}, defaults: map[string]string{}}
+	for _, model := range indexerTables {
+		typeName := reflect.Indirect(reflect.ValueOf(model)).Type().Name()
+		tables.add(schema.NamingStrategy{}.TableName(typeName), namer.TableName(typeName))
+	}
+	for _, joinTable := range indexerJoinTables {
+		tables.add(joinTable, namer.JoinTableName(joinTable))
+	}
+
+	placeholders := make([]string, 0, 2*len(tables.names))
+	for defaultName, name := range tables.names {
+		placeholders = append(placeholders, "{"+defaultName+"}", fmt.Sprintf("%q", name))
+	}
+	tables.replacer = strings.NewReplacer(placeholders...)
+	return tables
+}
+
+func (t Tables) add(defaultName string, name string) {
+	t.names[defaultName] = name
+	t.defaults[name] = defaultName
+}
+
+// Name returns the name of the table with the default name, other names are returned as they are
+func (t Tables) Name(defaultName string) string {
+	if name, ok := t.names[defaultName]; ok {
+		return name
+	}
+	return defaultName
+}
+
+// Default returns the default name of the table, other names are returned as they are
+func (t Tables) Default(name string) string {
+	if defaultName, ok := t.defaults[name]; ok {
+		return defaultName
+	}
+	return name
+}
+
+// Expand replaces the {table} placeholders of the query with the quoted names of the tables. Queries alias the tables
+// to their default names, e.g. FROM {blocks} blocks, so the column references do not depend on the naming.
+func (t Tables) Expand(query string) string {
+	return t.replacer.Replace(query)
+}
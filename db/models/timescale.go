@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FeePoint is a fee paid by a transaction, denormalized into a TimescaleDB hypertable partitioned by the time of its
+// block. It has no primary key since unique indexes of a hypertable must include the time column; the points of a
+// block are replaced by chain and height when the block is indexed again.
+type FeePoint struct {
+	Time    time.Time `gorm:"not null;index"`
+	ChainID uint      `gorm:"index:idx_fee_points_chain_height,priority:1"`
+	Height  int64     `gorm:"index:idx_fee_points_chain_height,priority:2"`
+	TxID    uint
+	Payer   string
+	DenomID uint
+	Amount  decimal.Decimal `gorm:"type:decimal(78,0);"`
+}
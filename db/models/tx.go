@@ -12,15 +12,21 @@ type Tx struct {
 	Code            uint32
 	BlockID         uint
 	Block           Block
+	Memo            string
 	SignerAddresses []Address `gorm:"many2many:tx_signer_addresses;"`
 	Fees            []Fee
 }
 
+// FailedTx is a transaction that could not be processed, the other transactions of its block are indexed. Raw holds
+// the transaction bytes so it can be decoded again once the cause is fixed.
 type FailedTx struct {
-	ID      uint
-	Hash    string `gorm:"uniqueIndex"`
-	BlockID uint
-	Block   Block
+	ID           uint
+	Hash         string `gorm:"uniqueIndex"`
+	BlockID      uint
+	Block        Block
+	ErrorCode    FailureCode `gorm:"index"`
+	ErrorMessage string
+	Raw          []byte `gorm:"serializer:zstd;type:bytea"`
 }
 
 type Fee struct {
@@ -53,15 +59,21 @@ type Message struct {
 	Tx            Tx
 	MessageTypeID uint `gorm:"foreignKey:MessageTypeID,index:idx_txid_typeid"`
 	MessageType   MessageType
-	MessageIndex  int `gorm:"uniqueIndex:messageIndex,priority:2"`
-	MessageBytes  []byte
+	MessageIndex  int    `gorm:"uniqueIndex:messageIndex,priority:2"`
+	MessageBytes  []byte `gorm:"serializer:zstd;type:bytea"`
 }
 
+// FailedMessage is the dead letter of a message that could not be decoded. The message is indexed as a placeholder of
+// its type without events, Raw keeps its bytes even when raw messages are not indexed.
 type FailedMessage struct {
 	ID           uint
 	MessageIndex int
-	TxID         uint
+	TxID         uint `gorm:"index"`
 	Tx           Tx
+	MessageType  string
+	ErrorCode    FailureCode `gorm:"index"`
+	ErrorMessage string
+	Raw          []byte `gorm:"serializer:zstd;type:bytea"`
 }
 
 type MessageEvent struct {
@@ -83,9 +95,12 @@ type MessageEventType struct {
 type MessageEventAttribute struct {
 	ID             uint
 	MessageEvent   MessageEvent
-	MessageEventID uint `gorm:"uniqueIndex:messageAttributeIndex,priority:1"`
-	Value          string
-	Index          uint64 `gorm:"uniqueIndex:messageAttributeIndex,priority:2"`
+	MessageEventID uint   `gorm:"uniqueIndex:messageAttributeIndex,priority:1"`
+	Value          string `gorm:"serializer:attribute_value;type:text"`
+	// CompressedValue holds the zstd compressed value of a large value, which leaves value NULL. It is only set in the
+	// database, the serializer reads it into Value and writes it from Value.
+	CompressedValue []byte `gorm:"serializer:attribute_value;type:bytea"`
+	Index           uint64 `gorm:"uniqueIndex:messageAttributeIndex,priority:2"`
 	// Keys are limited to a smallish subset of string values set by the Cosmos SDK and external modules
 	// Save DB space by storing the key as a foreign key
 	MessageEventAttributeKeyID uint
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// FlowKind is the kind of flow of funds an indexed event or fee is
+type FlowKind string
+
+const (
+	FlowTransfer FlowKind = "transfer"
+	FlowFee      FlowKind = "fee"
+	FlowReward   FlowKind = "reward"
+)
+
+// Classification is the taxable event a flow is classified as by the classification rules
+type Classification string
+
+const (
+	ClassificationIncome   Classification = "income"
+	ClassificationTransfer Classification = "transfer"
+	ClassificationTrade    Classification = "trade"
+	ClassificationFee      Classification = "fee"
+	ClassificationSpam     Classification = "spam"
+)
+
+// Valuation is the USD value of an amount moved by a flow of a block, at the price of the denom at the time of the
+// block. The flow is the fee, message event or block event referenced. PriceUSD is the price of one display unit of
+// the denom, price and value are NULL when no price source covers the denom or its display unit is unknown.
+// ClassificationRule is the name of the rule that classified the flow, "default" when no rule matched.
+type Valuation struct {
+	ID                 uint
+	BlockID            uint `gorm:"index"`
+	Kind               FlowKind
+	FeeID              *uint
+	MessageEventID     *uint
+	BlockEventID       *uint
+	DenomID            uint
+	Denom              Denom
+	Amount             decimal.Decimal     `gorm:"type:decimal(78,0);"`
+	PriceUSD           decimal.NullDecimal `gorm:"type:decimal(40,18);"`
+	ValueUSD           decimal.NullDecimal `gorm:"type:decimal(78,18);"`
+	PriceSource        string
+	Classification     Classification `gorm:"index"`
+	ClassificationRule string
+}
+
+// NormalizedFee is a fee normalized for analytics across denoms, stored alongside the fee by the value command.
+// BaseDenom is the denom the fee denom was issued as, the base denom of its trace for IBC vouchers and the fee denom
+// itself otherwise. ReferenceAmount is the fee in base units of the reference denom, converted at the prices of the
+// block; it is NULL when no reference denom is configured or either denom has no price. Fees paid in the reference
+// denom or a voucher of it are not converted and have no price source.
+type NormalizedFee struct {
+	FeeID            uint `gorm:"primaryKey;autoIncrement:false"`
+	BlockID          uint `gorm:"index"`
+	BaseDenom        string
+	TracePath        string
+	ReferenceDenomID *uint
+	ReferenceAmount  decimal.NullDecimal `gorm:"type:numeric;"`
+	PriceSource      string
+}
+
+// DexPrice is the exchange rate of a swap on a DEX of an indexed chain. Price is the amount of the quote denom received
+// per unit of the denom swapped, both in base units. Pool identifies the pool or pair contract the swap was made in.
+type DexPrice struct {
+	ID             uint
+	BlockID        uint `gorm:"index"`
+	MessageEventID uint
+	Time           time.Time `gorm:"index:idx_dex_prices_denom_time,priority:2;index:idx_dex_prices_quote_time,priority:2"`
+	DenomID        uint      `gorm:"index:idx_dex_prices_denom_time,priority:1"`
+	Denom          Denom
+	QuoteDenomID   uint  `gorm:"index:idx_dex_prices_quote_time,priority:1"`
+	QuoteDenom     Denom `gorm:"foreignKey:QuoteDenomID"`
+	Pool           string
+	Price          decimal.Decimal `gorm:"type:numeric;"`
+}
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetModuleVersions returns the module versions recorded for the chain, ordered by height and module
+func GetModuleVersions(db *gorm.DB, chainID uint) ([]models.ModuleVersion, error) {
+	var versions []models.ModuleVersion
+	err := db.Where("chain_id = ?", chainID).Order("height, module").Find(&versions).Error
+	return versions, err
+}
+
+// RecordModuleVersions records the versions, keeping the rows recorded before for the same module and height
+func RecordModuleVersions(db *gorm.DB, versions []models.ModuleVersion) error {
+	if len(versions) == 0 {
+		return nil
+	}
+	return db.Omit("Chain").Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(versions, 500).Error
+}
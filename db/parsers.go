@@ -6,7 +6,7 @@ import (
 )
 
 func FindOrCreateCustomBlockEventParsers(db *gorm.DB, parsers map[string]models.BlockEventParser) error {
-	err := db.Transaction(func(dbTransaction *gorm.DB) error {
+	err := transaction(db, func(dbTransaction *gorm.DB) error {
 		for key := range parsers {
 			currParser := parsers[key]
 			res := db.FirstOrCreate(&currParser, &currParser)
@@ -22,7 +22,7 @@ func FindOrCreateCustomBlockEventParsers(db *gorm.DB, parsers map[string]models.
 }
 
 func FindOrCreateCustomMessageParsers(db *gorm.DB, parsers map[string]models.MessageParser) error {
-	err := db.Transaction(func(dbTransaction *gorm.DB) error {
+	err := transaction(db, func(dbTransaction *gorm.DB) error {
 		for key := range parsers {
 			currParser := parsers[key]
 			res := db.FirstOrCreate(&currParser, &currParser)
@@ -38,7 +38,7 @@ func FindOrCreateCustomMessageParsers(db *gorm.DB, parsers map[string]models.Mes
 }
 
 func CreateBlockEventParserError(db *gorm.DB, blockEvent models.BlockEvent, parser models.BlockEventParser, parserError error) error {
-	err := db.Transaction(func(dbTransaction *gorm.DB) error {
+	err := transaction(db, func(dbTransaction *gorm.DB) error {
 		res := db.Create(&models.BlockEventParserError{
 			BlockEventParserID: parser.ID,
 			BlockEventID:       blockEvent.ID,
@@ -50,7 +50,7 @@ func CreateBlockEventParserError(db *gorm.DB, blockEvent models.BlockEvent, pars
 }
 
 func DeleteCustomBlockEventParserError(db *gorm.DB, blockEvent models.BlockEvent, parser models.BlockEventParser) error {
-	err := db.Transaction(func(dbTransaction *gorm.DB) error {
+	err := transaction(db, func(dbTransaction *gorm.DB) error {
 		parserError := models.BlockEventParserError{
 			BlockEventParserID: parser.ID,
 			BlockEventID:       blockEvent.ID,
@@ -62,7 +62,7 @@ func DeleteCustomBlockEventParserError(db *gorm.DB, blockEvent models.BlockEvent
 }
 
 func CreateMessageParserError(db *gorm.DB, message models.Message, parser models.MessageParser, parserError error) error {
-	err := db.Transaction(func(dbTransaction *gorm.DB) error {
+	err := transaction(db, func(dbTransaction *gorm.DB) error {
 		res := db.Create(&models.MessageParserError{
 			Error:           parserError.Error(),
 			MessageParserID: parser.ID,
@@ -74,7 +74,7 @@ func CreateMessageParserError(db *gorm.DB, message models.Message, parser models
 }
 
 func DeleteCustomMessageParserError(db *gorm.DB, message models.Message, parser models.MessageParser) error {
-	err := db.Transaction(func(dbTransaction *gorm.DB) error {
+	err := transaction(db, func(dbTransaction *gorm.DB) error {
 		parserError := models.MessageParserError{
 			MessageParserID: parser.ID,
 			MessageID:       message.ID,
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// redactedMarker replaces an address in the text it is redacted from
+const redactedMarker = "[redacted]"
+
+// AddressRedaction is the number of rows of a column that contain an address
+type AddressRedaction struct {
+	Table  string
+	Column string
+	Rows   int64
+}
+
+type redactableColumn struct {
+	TableName  string
+	ColumnName string
+	DataType   string
+}
+
+// RedactAddress removes an address from every table of the current schema, including the tables of custom parsers,
+// and returns the rows containing it by column. Without apply nothing is changed, so the rows can be reviewed first.
+//
+// The rows are redacted rather than deleted, blocks and transactions stay complete and the foreign keys to the
+// address stay valid: the address row is renamed to a placeholder, which unlinks the signers, fee payers and proposers
+// referencing it, the address is replaced in text and JSON values, e.g. event attributes and denoms, and binary values
+// containing it, e.g. raw messages, are set to NULL as they can't be edited without breaking their encoding. The
+// compressed event attribute values are decompressed to be redacted like text. All changes are made in a single
+// transaction.
+func RedactAddress(db *gorm.DB, address string, apply bool) ([]AddressRedaction, error) {
+	var columns []redactableColumn
+	err := db.Raw(`SELECT c.table_name, c.column_name, c.data_type FROM information_schema.columns c
+		JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+		WHERE c.table_schema = current_schema() AND t.table_type = 'BASE TABLE'
+		AND c.data_type IN ('text', 'character varying', 'json', 'jsonb', 'bytea')
+		ORDER BY c.table_name, c.ordinal_position`).Scan(&columns).Error
+	if err != nil {
+		return nil, err
+	}
+
+	addressTable := TableName(db, &models.Address{})
+	attributeTables := []string{TableName(db, &models.MessageEventAttribute{}), TableName(db, &models.BlockEventAttribute{})}
+	args := map[string]any{"address": address, "marker": redactedMarker}
+
+	var redactions []AddressRedaction
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, column := range columns {
+			if column.ColumnName == "compressed_value" && (column.TableName == attributeTables[0] || column.TableName == attributeTables[1]) {
+				continue
+			}
+
+			match, redacted := redactionSQL(column, column.TableName == addressTable && column.ColumnName == "address")
+
+			var rows int64
+			if apply {
+				result := tx.Exec(fmt.Sprintf("UPDATE %q SET %q = %s WHERE %s", column.TableName, column.ColumnName, redacted, match), args)
+				if result.Error != nil {
+					return fmt.Errorf("failed to redact %s.%s: %w", column.TableName, column.ColumnName, result.Error)
+				}
+				rows = result.RowsAffected
+			} else {
+				err := tx.Raw(fmt.Sprintf("SELECT count(*) FROM %q WHERE %s", column.TableName, match), args).Scan(&rows).Error
+				if err != nil {
+					return fmt.Errorf("failed to count %s.%s: %w", column.TableName, column.ColumnName, err)
+				}
+			}
+
+			if rows > 0 {
+				redactions = append(redactions, AddressRedaction{Table: column.TableName, Column: column.ColumnName, Rows: rows})
+			}
+		}
+
+		for _, table := range attributeTables {
+			rows, err := redactCompressedValues(tx, table, address, apply)
+			if err != nil {
+				return err
+			}
+			if rows > 0 {
+				redactions = append(redactions, AddressRedaction{Table: table, Column: "compressed_value", Rows: rows})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return redactions, nil
+}
+
+// redactionSQL returns the condition matching the values of the column that contain the address and the redacted
+// value. The address rows get a placeholder unique to the row, the address column has a unique index.
+func redactionSQL(column redactableColumn, addressRow bool) (match string, redacted string) {
+	name := fmt.Sprintf("%q", column.ColumnName)
+
+	switch {
+	case addressRow:
+		return fmt.Sprintf("strpos(%s, @address) > 0", name), "@marker || ' ' || id"
+	case column.DataType == "bytea":
+		return fmt.Sprintf("position(convert_to(@address, 'UTF8') in %s) > 0", name), "NULL"
+	case column.DataType == "json" || column.DataType == "jsonb":
+		return fmt.Sprintf("strpos(%s::text, @address) > 0", name), fmt.Sprintf("replace(%s::text, @address, @marker)::%s", name, column.DataType)
+	default:
+		return fmt.Sprintf("strpos(%s, @address) > 0", name), fmt.Sprintf("replace(%s, @address, @marker)", name)
+	}
+}
+
+// redactCompressedValues redacts the address from the compressed values of an event attribute table, which SQL can't
+// search, and returns the number of values containing it. The redacted values are written back as the indexer writes
+// them.
+func redactCompressedValues(tx *gorm.DB, table string, address string, apply bool) (int64, error) {
+	var compressed []struct {
+		ID              uint
+		Value           string `gorm:"serializer:attribute_value"`
+		CompressedValue []byte `gorm:"serializer:attribute_value"`
+	}
+	err := tx.Raw(fmt.Sprintf("SELECT id, compressed_value FROM %q WHERE compressed_value IS NOT NULL", table)).Scan(&compressed).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s.compressed_value: %w", table, err)
+	}
+
+	var rows int64
+	for _, attribute := range compressed {
+		if !strings.Contains(attribute.Value, address) {
+			continue
+		}
+		rows++
+
+		if apply {
+			value, compressedValue := models.AttributeValueColumns(strings.ReplaceAll(attribute.Value, address, redactedMarker))
+			err := tx.Exec(fmt.Sprintf("UPDATE %q SET value = ?, compressed_value = ? WHERE id = ?", table), value, compressedValue, attribute.ID).Error
+			if err != nil {
+				return 0, fmt.Errorf("failed to redact %s.compressed_value: %w", table, err)
+			}
+		}
+	}
+	return rows, nil
+}
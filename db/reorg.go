@@ -0,0 +1,195 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// foreignKey is a single column foreign key of the current schema
+type foreignKey struct {
+	ChildTable   string
+	ChildColumn  string
+	ParentTable  string
+	ParentColumn string
+}
+
+// GetBlockHashes returns the hash and the parent hash stored for the block of the height, empty when the block is not
+// indexed or was indexed before the hashes were stored
+func GetBlockHashes(db *gorm.DB, chainID uint, height int64) (string, string, error) {
+	var blocks []models.Block
+	err := db.Select("hash", "parent_hash").Where("chain_id = ? AND height = ?", chainID, height).Limit(1).Find(&blocks).Error
+	if err != nil || len(blocks) == 0 {
+		return "", "", err
+	}
+	return blocks[0].Hash, blocks[0].ParentHash, nil
+}
+
+// RollbackBlocks deletes the indexed blocks of the chain from the start to the end height, inclusive, together with
+// every row referencing them through foreign keys, transitively: their transactions, messages, events and attributes,
+// and the rows of the custom parsers. The tables are found in the schema, so the tables of custom parsers are rolled
+// back as long as they declare their foreign keys. The rows keyed by the block, height, event or tx IDs without a
+// foreign key are rolled back explicitly: the ledger entries, subtracted from the address activity rollups first when
+// rollups is set, the valuations, normalized fees and DEX prices, the coins and search documents of the events, the
+// address involvements of the txes, the failed blocks and the height states of the heights. The indexed height of the
+// chain head moves back to the highest block left. Everything is deleted in a single transaction, which also moves
+// the indexing checkpoint of the chain before the start height. It returns the number of deleted blocks.
+func RollbackBlocks(db *gorm.DB, chainID uint, start int64, end int64, rollups bool) (int64, error) {
+	var keys []foreignKey
+	err := db.Raw(`SELECT child.relname AS child_table, child_column.attname AS child_column,
+			parent.relname AS parent_table, parent_column.attname AS parent_column
+		FROM pg_constraint con
+		JOIN pg_class child ON child.oid = con.conrelid
+		JOIN pg_class parent ON parent.oid = con.confrelid
+		JOIN pg_namespace ns ON ns.oid = child.relnamespace
+		JOIN pg_attribute child_column ON child_column.attrelid = con.conrelid AND child_column.attnum = con.conkey[1]
+		JOIN pg_attribute parent_column ON parent_column.attrelid = con.confrelid AND parent_column.attnum = con.confkey[1]
+		WHERE con.contype = 'f' AND ns.nspname = current_schema() AND cardinality(con.conkey) = 1
+		ORDER BY child.relname, child_column.attname`).Scan(&keys).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the foreign keys: %w", err)
+	}
+
+	referencing := map[string][]foreignKey{}
+	for _, key := range keys {
+		// Rows referencing rows of their own table are deleted with the table
+		if key.ChildTable != key.ParentTable {
+			referencing[key.ParentTable] = append(referencing[key.ParentTable], key)
+		}
+	}
+
+	var deleted int64
+	err = transaction(db, func(tx *gorm.DB) error {
+		blocks := TableName(tx, &models.Block{})
+		args := map[string]any{"chain_id": chainID, "start": start, "end": end}
+		if err := rollbackUnreferenced(tx, chainID, start, end, rollups); err != nil {
+			return err
+		}
+
+		var err error
+		deleted, err = deleteReferenced(tx, referencing, blocks, "chain_id = @chain_id AND height BETWEEN @start AND @end", args, map[string]bool{})
+		if err != nil {
+			return err
+		}
+		if err := rollbackChainHead(tx, chainID, start); err != nil {
+			return err
+		}
+		// The rolled back heights are no longer indexed, so a resumed run must index them again
+		return tx.Model(&models.IndexerCheckpoint{}).Where("blockchain_id = ? AND height >= ?", chainID, start).
+			Update("height", start-1).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// rollbackUnreferenced deletes the rows of the heights that do not reference the blocks through a foreign key, so they
+// are not found by deleteReferenced
+func rollbackUnreferenced(tx *gorm.DB, chainID uint, start int64, end int64, rollups bool) error {
+	var blocks []models.Block
+	err := tx.Where("chain_id = ? AND height BETWEEN ? AND ?", chainID, start, end).Order("height").Find(&blocks).Error
+	if err != nil {
+		return fmt.Errorf("failed to read the blocks to roll back: %w", err)
+	}
+
+	blockIDs := make([]uint, 0, len(blocks))
+	for i := range blocks {
+		blockIDs = append(blockIDs, blocks[i].ID)
+		if rollups {
+			// The rollups only hold the difference of the entries, so they are subtracted before the entries are deleted
+			for _, blockEvents := range []bool{false, true} {
+				if err := rollUpLedgerEntries(tx, &blocks[i], blockEvents, -1); err != nil {
+					return fmt.Errorf("failed to roll back the address activity of block %d: %w", blocks[i].Height, err)
+				}
+			}
+		}
+	}
+
+	if len(blockIDs) != 0 {
+		for _, model := range []any{&models.LedgerEntry{}, &models.Valuation{}, &models.NormalizedFee{}, &models.DexPrice{}} {
+			if err := tx.Where("block_id IN ?", blockIDs).Delete(model).Error; err != nil {
+				return fmt.Errorf("failed to roll back %s: %w", TableName(tx, model), err)
+			}
+		}
+
+		// The coins, search documents and involvements are keyed by the IDs of the events and txes of the blocks, they
+		// must be deleted while those rows still exist
+		txIDs := tx.Model(&models.Tx{}).Select("id").Where("block_id IN ?", blockIDs)
+		messageIDs := tx.Model(&models.Message{}).Select("id").Where("tx_id IN (?)", txIDs)
+		messageEventIDs := tx.Model(&models.MessageEvent{}).Select("id").Where("message_id IN (?)", messageIDs)
+		blockEventIDs := tx.Model(&models.BlockEvent{}).Select("id").Where("block_id IN ?", blockIDs)
+		keyed := []struct {
+			model  any
+			column string
+			ids    *gorm.DB
+		}{
+			{&models.MessageEventAttributeCoin{}, "message_event_id", messageEventIDs},
+			{&models.MessageEventSearch{}, "message_event_id", messageEventIDs},
+			{&models.BlockEventAttributeCoin{}, "block_event_id", blockEventIDs},
+			{&models.AddressInvolvement{}, "tx_id", txIDs},
+		}
+		for _, rows := range keyed {
+			if err := tx.Where(rows.column+" IN (?)", rows.ids).Delete(rows.model).Error; err != nil {
+				return fmt.Errorf("failed to roll back %s: %w", TableName(tx, rows.model), err)
+			}
+		}
+	}
+
+	for _, model := range []any{&models.FailedBlock{}, &models.FailedEventBlock{}, &models.HeightState{}} {
+		if err := tx.Where("blockchain_id = ? AND height BETWEEN ? AND ?", chainID, start, end).Delete(model).Error; err != nil {
+			return fmt.Errorf("failed to roll back %s: %w", TableName(tx, model), err)
+		}
+	}
+	return nil
+}
+
+// rollbackChainHead moves the indexed height of the chain head back to the highest indexed block below the start
+// height, when it was at or above it
+func rollbackChainHead(tx *gorm.DB, chainID uint, start int64) error {
+	var indexed []models.Block
+	err := tx.Where("chain_id = ? AND height < ? AND (tx_indexed OR block_events_indexed)", chainID, start).
+		Order("height DESC").Limit(1).Find(&indexed).Error
+	if err != nil {
+		return err
+	}
+
+	updates := map[string]any{"indexed_height": int64(0), "indexed_time": nil, "lag_blocks": gorm.Expr("head_height"), "lag_seconds": 0}
+	if len(indexed) != 0 {
+		updates = map[string]any{
+			"indexed_height": indexed[0].Height,
+			"indexed_time":   indexed[0].TimeStamp,
+			"lag_blocks":     gorm.Expr("GREATEST(head_height - ?, 0)", indexed[0].Height),
+			"lag_seconds":    gorm.Expr("GREATEST(EXTRACT(EPOCH FROM head_time - ?), 0)", indexed[0].TimeStamp),
+		}
+	}
+	err = tx.Model(&models.ChainHead{}).Where("blockchain_id = ? AND indexed_height >= ?", chainID, start).Updates(updates).Error
+	if err != nil {
+		return fmt.Errorf("failed to roll back the chain head: %w", err)
+	}
+	return nil
+}
+
+// deleteReferenced deletes the rows of the table matching the condition after the rows referencing them. Tables on
+// the path from the blocks are skipped, so a cycle of foreign keys does not recurse forever.
+func deleteReferenced(tx *gorm.DB, referencing map[string][]foreignKey, table string, condition string, args map[string]any, path map[string]bool) (int64, error) {
+	path[table] = true
+	defer delete(path, table)
+
+	for _, key := range referencing[table] {
+		if path[key.ChildTable] {
+			continue
+		}
+		childCondition := fmt.Sprintf("%q IN (SELECT %q FROM %q WHERE %s)", key.ChildColumn, key.ParentColumn, table, condition)
+		if _, err := deleteReferenced(tx, referencing, key.ChildTable, childCondition, args, path); err != nil {
+			return 0, err
+		}
+	}
+
+	result := tx.Exec(fmt.Sprintf("DELETE FROM %q WHERE %s", table, condition), args)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to roll back %s: %w", table, result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,116 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const (
+	// SQLSTATE of transactions aborted by serializable isolation, CockroachDB runs every transaction with it
+	serializationFailure     = "40001"
+	serializationRetries     = 5
+	serializationRetryPeriod = 50 * time.Millisecond
+)
+
+func isSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == serializationFailure
+}
+
+// transaction runs fc in a transaction and runs it again in a new one when the database aborted it with a
+// serialization failure, which CockroachDB returns for conflicting transactions that the client must retry. fc runs
+// again with whatever it changed outside the database in the aborted attempt, so it must be safe to rerun. The rows
+// custom parsers write in the transaction are counted once it commits.
+func transaction(db *gorm.DB, fc func(tx *gorm.DB) error) error {
+	db, rows := withParserRows(db)
+
+	wait := serializationRetryPeriod
+	for attempt := 1; ; attempt++ {
+		rows.reset()
+		err := db.Transaction(fc)
+		if err == nil {
+			rows.commit()
+		}
+		if err == nil || attempt > serializationRetries || !isSerializationFailure(err) {
+			return err
+		}
+
+		config.Log.Infof("Retrying transaction after serialization failure (attempt %d of %d)", attempt, serializationRetries)
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// SQLSTATEs of errors that go away once the database is reachable again or the conflicting transaction is done. Class
+// 08 (connection exceptions) is matched as a whole.
+var transientErrorCodes = map[string]struct{}{
+	serializationFailure: {},
+	"40P01":              {}, // deadlock_detected
+	"57P01":              {}, // admin_shutdown
+	"57P02":              {}, // crash_shutdown
+	"57P03":              {}, // cannot_connect_now, e.g. while a replica is promoted
+	"53300":              {}, // too_many_connections
+	"25006":              {}, // read_only_sql_transaction, a demoted primary during failover
+	"55P03":              {}, // lock_not_available, the lock timeout expired
+}
+
+// IsTransientError is true for errors of lost connections, failovers and aborted transactions, after which the same
+// write can succeed when it is attempted again
+func IsTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		_, ok := transientErrorCodes[pgErr.Code]
+		return ok || strings.HasPrefix(pgErr.Code, "08")
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, driver.ErrBadConn) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) ||
+		pgconn.SafeToRetry(err) || pgconn.Timeout(err)
+}
+
+// RetryTransient runs fc until it succeeds, fails with an error that is not transient or the attempts are used up.
+// Attempts work like the RPC request retries: 0 runs fc once, a negative number retries until fc succeeds. The wait
+// between attempts doubles up to maxWaitSeconds, the connection pool replaces broken connections in the meantime. The
+// retries stop once ctx is done, returning the last error.
+func RetryTransient(ctx context.Context, attempts int64, maxWaitSeconds uint64, description string, fc func() error) error {
+	maxWait := time.Duration(maxWaitSeconds) * time.Second
+	if maxWait < time.Second {
+		maxWait = time.Second
+	}
+	wait := time.Second
+
+	for attempt := int64(1); ; attempt++ {
+		err := fc()
+		if err == nil || ctx.Err() != nil || !IsTransientError(err) || (attempts >= 0 && attempt > attempts) {
+			return err
+		}
+
+		config.Log.Warnf("Transient database error %s, retrying in %v (attempt %d): %v", description, wait, attempt, err)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		if wait *= 2; wait > maxWait {
+			wait = maxWait
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/suite"
+)
+
+type RetryTestSuite struct {
+	suite.Suite
+}
+
+func (suite *RetryTestSuite) TestIsSerializationFailure() {
+	suite.Require().True(isSerializationFailure(&pgconn.PgError{Code: "40001"}))
+	suite.Require().True(isSerializationFailure(fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40001"})))
+	suite.Require().False(isSerializationFailure(&pgconn.PgError{Code: "23505"}))
+	suite.Require().False(isSerializationFailure(errors.New("40001")))
+}
+
+func (suite *RetryTestSuite) TestIsTransientError() {
+	suite.Require().True(IsTransientError(&pgconn.PgError{Code: "08006"}))
+	suite.Require().True(IsTransientError(&pgconn.PgError{Code: "57P01"}))
+	suite.Require().True(IsTransientError(fmt.Errorf("write: %w", syscall.ECONNRESET)))
+	suite.Require().False(IsTransientError(&pgconn.PgError{Code: "23505"}))
+	suite.Require().False(IsTransientError(errors.New("invalid input")))
+	suite.Require().False(IsTransientError(nil))
+}
+
+func (suite *RetryTestSuite) TestRetryTransient() {
+	attempts := 0
+	err := RetryTransient(context.Background(), 3, 0, "test", func() error {
+		attempts++
+		if attempts < 2 {
+			return &pgconn.PgError{Code: "40001"}
+		}
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal(2, attempts)
+
+	attempts = 0
+	err = RetryTransient(context.Background(), 3, 0, "test", func() error {
+		attempts++
+		return &pgconn.PgError{Code: "23505"}
+	})
+	suite.Require().Error(err)
+	suite.Require().Equal(1, attempts)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts = 0
+	err = RetryTransient(ctx, -1, 0, "test", func() error {
+		attempts++
+		cancel()
+		return &pgconn.PgError{Code: "40001"}
+	})
+	suite.Require().Error(err)
+	suite.Require().Equal(1, attempts)
+}
+
+func TestRetryTestSuite(t *testing.T) {
+	suite.Run(t, new(RetryTestSuite))
+}
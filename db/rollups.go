@@ -0,0 +1,50 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// rollUpLedgerEntries adds the ledger entries of the block, those of its block events or those of its transactions, to
+// the address activity rollups, or subtracts them with a sign of -1 before the entries are replaced. Only the entries
+// of one block are grouped, so the rollups are kept up to date without scanning the ledger. The first and last
+// activity only move outwards, an account that no longer appears in a reindexed block keeps them.
+func rollUpLedgerEntries(db *gorm.DB, block *models.Block, blockEvents bool, sign int) error {
+	scope := "block_event_id IS NULL"
+	if blockEvents {
+		scope = "block_event_id IS NOT NULL"
+	}
+
+	activities := TableName(db, &models.AddressActivity{})
+	err := db.Exec(fmt.Sprintf(`INSERT INTO %[1]q (account, denom_id, volume_in, volume_out, fees_paid)
+		SELECT account, denom_id,
+			? * SUM(CASE WHEN kind = 'transfer' AND side = 'debit' THEN amount ELSE 0 END),
+			? * SUM(CASE WHEN kind = 'transfer' AND side = 'credit' THEN amount ELSE 0 END),
+			? * SUM(CASE WHEN kind = 'fee' AND side = 'credit' THEN amount ELSE 0 END)
+		FROM %[2]q WHERE block_id = ? AND %[3]s
+		GROUP BY account, denom_id
+		ON CONFLICT (account, denom_id) DO UPDATE SET
+			volume_in = %[1]q.volume_in + EXCLUDED.volume_in,
+			volume_out = %[1]q.volume_out + EXCLUDED.volume_out,
+			fees_paid = %[1]q.fees_paid + EXCLUDED.fees_paid`,
+		activities, TableName(db, &models.LedgerEntry{}), scope), sign, sign, sign, block.ID).Error
+	if err != nil {
+		return err
+	}
+
+	summaries := TableName(db, &models.AddressSummary{})
+	return db.Exec(fmt.Sprintf(`INSERT INTO %[1]q (account, tx_count, first_height, first_activity, last_height, last_activity)
+		SELECT account, ? * COUNT(DISTINCT tx_id), ?, ?, ?, ?
+		FROM %[2]q WHERE block_id = ? AND %[3]s
+		GROUP BY account
+		ON CONFLICT (account) DO UPDATE SET
+			tx_count = %[1]q.tx_count + EXCLUDED.tx_count,
+			first_activity = CASE WHEN EXCLUDED.first_height < %[1]q.first_height THEN EXCLUDED.first_activity ELSE %[1]q.first_activity END,
+			first_height = LEAST(%[1]q.first_height, EXCLUDED.first_height),
+			last_activity = CASE WHEN EXCLUDED.last_height > %[1]q.last_height THEN EXCLUDED.last_activity ELSE %[1]q.last_activity END,
+			last_height = GREATEST(%[1]q.last_height, EXCLUDED.last_height)`,
+		summaries, TableName(db, &models.LedgerEntry{}), scope),
+		sign, block.Height, block.TimeStamp, block.Height, block.TimeStamp, block.ID).Error
+}
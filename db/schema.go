@@ -0,0 +1,57 @@
+package db
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Postgres truncates longer identifiers
+const maxIdentifierLength = 63
+
+var nonIdentifierChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// ChainSchema returns the schema holding the tables of a chain when every chain has its own schema. The chain ID is
+// lowercased and anything but letters, digits and underscores is replaced, e.g. cosmoshub-4 becomes cosmoshub_4.
+func ChainSchema(chainID string) string {
+	schema := nonIdentifierChars.ReplaceAllString(strings.ToLower(chainID), "_")
+	// Schemas starting with pg_ are reserved and identifiers must not start with a digit
+	if schema == "" || strings.HasPrefix(schema, "pg_") || (schema[0] >= '0' && schema[0] <= '9') {
+		schema = "chain_" + schema
+	}
+	if len(schema) > maxIdentifierLength {
+		schema = schema[:maxIdentifierLength]
+	}
+	return schema
+}
+
+// tableNamer renames single tables on top of the naming strategy, the overrides are keyed by the default table name
+type tableNamer struct {
+	schema.NamingStrategy
+	tables map[string]string
+}
+
+func (n tableNamer) TableName(table string) string {
+	if name, ok := n.tables[schema.NamingStrategy{}.TableName(table)]; ok {
+		return name
+	}
+	return n.NamingStrategy.TableName(table)
+}
+
+// TableNaming returns the naming strategy for the tables of the indexer. The prefix is prepended to every table name,
+// singular uses e.g. block instead of blocks, and tables maps default table names (e.g. blocks) to names used as is.
+func TableNaming(prefix string, singular bool, tables map[string]string) schema.Namer {
+	return tableNamer{
+		NamingStrategy: schema.NamingStrategy{TablePrefix: prefix, SingularTable: singular},
+		tables:         tables,
+	}
+}
+
+// TableName returns the table of the model under the naming strategy of the connection, for SQL that is not built by
+// gorm itself
+func TableName(db *gorm.DB, model any) string {
+	return db.NamingStrategy.TableName(reflect.Indirect(reflect.ValueOf(model)).Type().Name())
+}
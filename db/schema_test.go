@@ -0,0 +1,48 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/stretchr/testify/suite"
+)
+
+type SchemaTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SchemaTestSuite) TestChainSchema() {
+	suite.Require().Equal("cosmoshub_4", ChainSchema("cosmoshub-4"))
+	suite.Require().Equal("osmosis_1", ChainSchema("Osmosis-1"))
+	suite.Require().Equal("chain_9000_1", ChainSchema("9000-1"))
+	suite.Require().Equal("chain_pg_test", ChainSchema("pg_test"))
+	suite.Require().Len(ChainSchema(strings.Repeat("a", 100)), 63)
+}
+
+func (suite *SchemaTestSuite) TestTableNaming() {
+	naming := TableNaming("idx_", false, map[string]string{"txes": "transactions"})
+	suite.Require().Equal("idx_blocks", naming.TableName("Block"))
+	suite.Require().Equal("transactions", naming.TableName("Tx"))
+
+	naming = TableNaming("", true, nil)
+	suite.Require().Equal("block_event", naming.TableName("BlockEvent"))
+}
+
+func (suite *SchemaTestSuite) TestTables() {
+	tables := models.NewTables(TableNaming("idx_", false, map[string]string{"txes": "transactions"}))
+	suite.Require().Equal("idx_blocks", tables.Name("blocks"))
+	suite.Require().Equal("transactions", tables.Name("txes"))
+	suite.Require().Equal("idx_tx_signer_addresses", tables.Name("tx_signer_addresses"))
+	suite.Require().Equal("txes", tables.Default("transactions"))
+	suite.Require().Equal(`SELECT 1 FROM "transactions" txes JOIN "idx_blocks" blocks ON blocks.id = txes.block_id`,
+		tables.Expand("SELECT 1 FROM {txes} txes JOIN {blocks} blocks ON blocks.id = txes.block_id"))
+
+	// Other tables are left as they are
+	suite.Require().Equal("contract_events", tables.Name("contract_events"))
+	suite.Require().Equal("{contract_events}", tables.Expand("{contract_events}"))
+}
+
+func TestSchemaTestSuite(t *testing.T) {
+	suite.Run(t, new(SchemaTestSuite))
+}
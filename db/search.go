@@ -0,0 +1,83 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// SearchConfiguration is the text search configuration of the documents and memo index, queries must parse their
+// search text with the same one. The simple configuration does not stem words, so addresses, denoms and contract
+// actions match as they are written in any language.
+const SearchConfiguration = "simple"
+
+// MigrateSearch creates the GIN index over the memos of the txes. The documents of the message events are indexed
+// with their table.
+func MigrateSearch(db *gorm.DB) error {
+	txes := TableName(db, &models.Tx{})
+	index := "idx_" + txes + "_memo_search"
+	if db.Migrator().HasIndex(&models.Tx{}, index) {
+		return nil
+	}
+
+	config.Log.Infof("Creating the full-text index %s over the memos of %s", index, txes)
+	return db.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS %q ON %q USING GIN (to_tsvector('%s', memo))", index, txes, SearchConfiguration)).Error
+}
+
+// indexMessageEventSearch replaces the documents of the message events of the txes with the values of their attributes
+// with one of the keys. Must be called inside the indexing transaction, after the message events were written.
+func indexMessageEventSearch(db *gorm.DB, txs []TxDBWrapper, txIDs []uint, keys []string) error {
+	if len(txIDs) == 0 {
+		return nil
+	}
+
+	searches := TableName(db, &models.MessageEventSearch{})
+	err := db.Exec(fmt.Sprintf(`DELETE FROM %q WHERE message_event_id IN (
+		SELECT e.id FROM %q e JOIN %q m ON m.id = e.message_id WHERE m.tx_id IN ?)`,
+		searches, TableName(db, &models.MessageEvent{}), TableName(db, &models.Message{})), txIDs).Error
+	if err != nil {
+		return err
+	}
+
+	searchable := attributeKeySet(keys)
+
+	var values []string
+	var args []any
+	for _, tx := range txs {
+		for _, message := range tx.Messages {
+			for _, event := range message.MessageEvents {
+				var document []string
+				for _, attribute := range event.Attributes {
+					if searchable[attribute.MessageEventAttributeKey.Key] {
+						document = append(document, attribute.Value)
+					}
+				}
+				if len(document) == 0 {
+					continue
+				}
+
+				values = append(values, fmt.Sprintf("(?, to_tsvector('%s', ?))", SearchConfiguration))
+				args = append(args, event.MessageEvent.ID, strings.Join(document, " "))
+			}
+		}
+	}
+
+	// Statements are bounded by the number of parameters Postgres accepts
+	const batchSize = 1000
+	for start := 0; start < len(values); start += batchSize {
+		end := start + batchSize
+		if end > len(values) {
+			end = len(values)
+		}
+
+		err := db.Exec(fmt.Sprintf("INSERT INTO %q (message_event_id, document) VALUES %s", searches, strings.Join(values[start:end], ", ")), args[2*start:2*end]...).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+package db
+
+import (
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GetSinkCheckpoints returns the last delivered height of each record type for the sink
+func GetSinkCheckpoints(db *gorm.DB, chainID string, sink string) (map[string]int64, error) {
+	var checkpoints []models.SinkCheckpoint
+	if err := db.Where("chain_id = ? AND sink = ?", chainID, sink).Find(&checkpoints).Error; err != nil {
+		return nil, err
+	}
+
+	heights := make(map[string]int64, len(checkpoints))
+	for _, checkpoint := range checkpoints {
+		heights[checkpoint.RecordType] = checkpoint.Height
+	}
+
+	return heights, nil
+}
+
+func UpsertSinkCheckpoint(db *gorm.DB, chainID string, sink string, recordType string, height int64) error {
+	checkpoint := models.SinkCheckpoint{ChainID: chainID, Sink: sink, RecordType: recordType, Height: height}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "chain_id"}, {Name: "sink"}, {Name: "record_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"height", "updated_at"}),
+	}).Create(&checkpoint).Error
+}
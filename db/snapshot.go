@@ -0,0 +1,179 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// SnapshotBalance is the balance of a denom held by an address at the snapshot height
+type SnapshotBalance struct {
+	Address string
+	Denom   string
+	Amount  decimal.Decimal
+}
+
+// SnapshotDelegation is the amount of the bond denom a delegator has delegated to a validator at the snapshot height
+type SnapshotDelegation struct {
+	Delegator string
+	Validator string
+	Amount    decimal.Decimal
+}
+
+// delegationEventTypes are the staking events the delegations are reconstructed from
+var delegationEventTypes = []string{"delegate", "unbond", "redelegate", "message"}
+
+// delegationKeys are the attributes of the delegation events, the sender of the message event is the delegator of the
+// events of SDK versions before v0.47, which have no delegator attribute
+var delegationKeys = []string{"delegator", "validator", "source_validator", "destination_validator", "amount", "sender"}
+
+// delegationAmountPattern matches the amount of a delegation event, with the bond denom since v0.47 and without before
+var delegationAmountPattern = regexp.MustCompile(`^([0-9]+)[a-zA-Z]?[a-zA-Z0-9/:._-]*$`)
+
+type delegationAttribute struct {
+	MessageID       uint
+	EventID         uint
+	Type            string
+	Key             string
+	Value           string `gorm:"serializer:attribute_value"`
+	CompressedValue []byte `gorm:"serializer:attribute_value"`
+}
+
+// SnapshotBalances reconstructs the balances of the chain at the height from the imported genesis balances and the
+// ledger entries of the blocks indexed up to the height. The balances are only complete when the genesis was imported
+// and every block up to the height was indexed. Coins minted or burned without a transfer are not in the ledger, so
+// the accounts they left or entered, e.g. module accounts, can have negative balances.
+func SnapshotBalances(db *gorm.DB, chainID uint, height int64) ([]SnapshotBalance, error) {
+	var balances []SnapshotBalance
+	err := db.Raw(fmt.Sprintf(`SELECT f.address, d.base AS denom, SUM(f.amount) AS amount FROM (
+			SELECT a.address, g.denom_id, g.amount FROM %q g JOIN %q a ON a.id = g.address_id WHERE g.chain_id = ?
+			UNION ALL
+			SELECT l.account, l.denom_id, CASE WHEN l.side = ? THEN l.amount ELSE -l.amount END FROM %q l
+			JOIN %q b ON b.id = l.block_id
+			WHERE b.chain_id = ? AND b.height <= ?
+		) f JOIN %q d ON d.id = f.denom_id
+		GROUP BY f.address, d.base HAVING SUM(f.amount) <> 0
+		ORDER BY f.address, d.base`,
+		TableName(db, &models.GenesisBalance{}), TableName(db, &models.Address{}), TableName(db, &models.LedgerEntry{}),
+		TableName(db, &models.Block{}), TableName(db, &models.Denom{})),
+		chainID, models.LedgerDebit, chainID, height).Scan(&balances).Error
+	return balances, err
+}
+
+// SnapshotDelegations reconstructs the delegations of the chain at the height from the delegate, unbond and redelegate
+// events of the successful transactions indexed up to the height. Delegations made at genesis, slashes and the shares
+// to tokens rate of the validators are not known to the index, so the amounts are the tokens delegated minus the
+// tokens undelegated.
+func SnapshotDelegations(db *gorm.DB, chainID uint, height int64) ([]SnapshotDelegation, error) {
+	var attributes []delegationAttribute
+	err := db.Raw(fmt.Sprintf(`SELECT m.id AS message_id, e.id AS event_id, et.type, k.key, a.value, a.compressed_value FROM %q a
+		JOIN %q k ON k.id = a.message_event_attribute_key_id
+		JOIN %q e ON e.id = a.message_event_id
+		JOIN %q et ON et.id = e.message_event_type_id
+		JOIN %q m ON m.id = e.message_id
+		JOIN %q t ON t.id = m.tx_id
+		JOIN %q b ON b.id = t.block_id
+		WHERE b.chain_id = ? AND b.height <= ? AND t.code = 0 AND et.type IN ? AND k.key IN ?
+		AND EXISTS (SELECT 1 FROM %q e2 JOIN %q et2 ON et2.id = e2.message_event_type_id WHERE e2.message_id = m.id AND et2.type IN ?)
+		ORDER BY b.height, m.id, e.id, a.index`,
+		TableName(db, &models.MessageEventAttribute{}), TableName(db, &models.MessageEventAttributeKey{}),
+		TableName(db, &models.MessageEvent{}), TableName(db, &models.MessageEventType{}),
+		TableName(db, &models.Message{}), TableName(db, &models.Tx{}), TableName(db, &models.Block{}),
+		TableName(db, &models.MessageEvent{}), TableName(db, &models.MessageEventType{})),
+		chainID, height, delegationEventTypes, delegationKeys, delegationEventTypes[:3]).Scan(&attributes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return applyDelegationEvents(attributes), nil
+}
+
+type delegationKey struct {
+	delegator string
+	validator string
+}
+
+// applyDelegationEvents sums the delegation events, ordered by message, event and attribute index. The events of a
+// message are applied once all its attributes were read, as the sender of the message event may follow them.
+func applyDelegationEvents(attributes []delegationAttribute) []SnapshotDelegation {
+	amounts := map[delegationKey]decimal.Decimal{}
+
+	type delegationEvent struct {
+		eventType string
+		values    map[string]string
+	}
+	var events []*delegationEvent
+	var sender string
+	apply := func() {
+		for _, event := range events {
+			delegator := event.values["delegator"]
+			if delegator == "" {
+				delegator = sender
+			}
+			match := delegationAmountPattern.FindStringSubmatch(event.values["amount"])
+			if delegator == "" || match == nil {
+				continue
+			}
+			amount, err := decimal.NewFromString(match[1])
+			if err != nil {
+				continue
+			}
+
+			switch event.eventType {
+			case "delegate":
+				key := delegationKey{delegator, event.values["validator"]}
+				amounts[key] = amounts[key].Add(amount)
+			case "unbond":
+				key := delegationKey{delegator, event.values["validator"]}
+				amounts[key] = amounts[key].Sub(amount)
+			case "redelegate":
+				source := delegationKey{delegator, event.values["source_validator"]}
+				destination := delegationKey{delegator, event.values["destination_validator"]}
+				amounts[source] = amounts[source].Sub(amount)
+				amounts[destination] = amounts[destination].Add(amount)
+			}
+		}
+		events, sender = nil, ""
+	}
+
+	var messageID, eventID uint
+	var current *delegationEvent
+	for _, attribute := range attributes {
+		if attribute.MessageID != messageID {
+			apply()
+			messageID, current = attribute.MessageID, nil
+		}
+		if attribute.Type == "message" {
+			if attribute.Key == "sender" && sender == "" {
+				sender = attribute.Value
+			}
+			continue
+		}
+		if current == nil || attribute.EventID != eventID {
+			current = &delegationEvent{eventType: attribute.Type, values: map[string]string{}}
+			events = append(events, current)
+			eventID = attribute.EventID
+		}
+		current.values[attribute.Key] = attribute.Value
+	}
+	apply()
+
+	delegations := make([]SnapshotDelegation, 0, len(amounts))
+	for key, amount := range amounts {
+		if key.validator == "" || !amount.IsPositive() {
+			continue
+		}
+		delegations = append(delegations, SnapshotDelegation{Delegator: key.delegator, Validator: key.validator, Amount: amount})
+	}
+	sort.Slice(delegations, func(i, j int) bool {
+		if delegations[i].Delegator != delegations[j].Delegator {
+			return delegations[i].Delegator < delegations[j].Delegator
+		}
+		return delegations[i].Validator < delegations[j].Validator
+	})
+	return delegations
+}
@@ -0,0 +1,44 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/suite"
+)
+
+type SnapshotTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SnapshotTestSuite) TestApplyDelegationEvents() {
+	delegations := applyDelegationEvents([]delegationAttribute{
+		// v0.47 events name the delegator
+		{MessageID: 1, EventID: 1, Type: "delegate", Key: "delegator", Value: "cosmos1a"},
+		{MessageID: 1, EventID: 1, Type: "delegate", Key: "validator", Value: "cosmosvaloper1x"},
+		{MessageID: 1, EventID: 1, Type: "delegate", Key: "amount", Value: "100uatom"},
+		// Older events without a delegator take the sender of the message, which may follow them
+		{MessageID: 2, EventID: 2, Type: "delegate", Key: "validator", Value: "cosmosvaloper1x"},
+		{MessageID: 2, EventID: 2, Type: "delegate", Key: "amount", Value: "50"},
+		{MessageID: 2, EventID: 3, Type: "message", Key: "sender", Value: "cosmos1b"},
+		{MessageID: 3, EventID: 4, Type: "redelegate", Key: "source_validator", Value: "cosmosvaloper1x"},
+		{MessageID: 3, EventID: 4, Type: "redelegate", Key: "destination_validator", Value: "cosmosvaloper1y"},
+		{MessageID: 3, EventID: 4, Type: "redelegate", Key: "amount", Value: "30uatom"},
+		{MessageID: 3, EventID: 4, Type: "redelegate", Key: "delegator", Value: "cosmos1a"},
+		{MessageID: 4, EventID: 5, Type: "unbond", Key: "validator", Value: "cosmosvaloper1x"},
+		{MessageID: 4, EventID: 5, Type: "unbond", Key: "amount", Value: "50"},
+		{MessageID: 4, EventID: 6, Type: "message", Key: "sender", Value: "cosmos1b"},
+	})
+
+	// The fully unbonded delegation is dropped
+	suite.Require().Len(delegations, 2)
+	suite.Require().Equal("cosmos1a", delegations[0].Delegator)
+	suite.Require().Equal("cosmosvaloper1x", delegations[0].Validator)
+	suite.Require().True(decimal.NewFromInt(70).Equal(delegations[0].Amount))
+	suite.Require().Equal("cosmosvaloper1y", delegations[1].Validator)
+	suite.Require().True(decimal.NewFromInt(30).Equal(delegations[1].Amount))
+}
+
+func TestSnapshotTestSuite(t *testing.T) {
+	suite.Run(t, new(SnapshotTestSuite))
+}
@@ -0,0 +1,168 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// builtInAggregates are the continuous aggregates over the fee points managed on every TimescaleDB database. An
+// aggregate of the same name in the aggregates file replaces the built-in one. Continuous aggregates cannot count
+// distinct values, so daily_active_addresses has a row per address and day, counted per day when queried.
+func builtInAggregates(db *gorm.DB) []config.ContinuousAggregate {
+	feePoints := TableName(db, &models.FeePoint{})
+	return []config.ContinuousAggregate{
+		{
+			Name: "hourly_fees",
+			Query: fmt.Sprintf(`SELECT time_bucket(INTERVAL '1 hour', time) AS bucket, chain_id, denom_id,
+				SUM(amount) AS amount, COUNT(*) AS fees
+				FROM %q GROUP BY bucket, chain_id, denom_id`, feePoints),
+			StartOffset: "72h",
+		},
+		{
+			Name: "daily_active_addresses",
+			Query: fmt.Sprintf(`SELECT time_bucket(INTERVAL '1 day', time) AS bucket, chain_id, payer AS address,
+				COUNT(*) AS fees
+				FROM %q GROUP BY bucket, chain_id, payer`, feePoints),
+			StartOffset: "168h",
+		},
+	}
+}
+
+// MigrateTimescale turns the fee points into a hypertable, backfilling it from the indexed fees when it is created,
+// and creates the built-in and declared continuous aggregates with their refresh policies. The TimescaleDB extension
+// must already be installed in the database.
+func MigrateTimescale(db *gorm.DB, declared []config.ContinuousAggregate) error {
+	var installed bool
+	if err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'timescaledb')").Scan(&installed).Error; err != nil {
+		return err
+	}
+	if !installed {
+		return errors.New("the timescaledb extension is not installed, run CREATE EXTENSION timescaledb as a superuser first")
+	}
+
+	created := !db.Migrator().HasTable(&models.FeePoint{})
+	if err := db.AutoMigrate(&models.FeePoint{}); err != nil {
+		return err
+	}
+
+	feePoints := TableName(db, &models.FeePoint{})
+	if err := db.Exec("SELECT create_hypertable(CAST(? AS regclass), 'time', if_not_exists => TRUE, migrate_data => TRUE)", fmt.Sprintf("%q", feePoints)).Error; err != nil {
+		return fmt.Errorf("failed to create the %s hypertable: %w", feePoints, err)
+	}
+
+	if created {
+		config.Log.Infof("Backfilling %s from the indexed fees", feePoints)
+		if err := insertFeePoints(db, "TRUE"); err != nil {
+			return err
+		}
+	}
+
+	aggregates := builtInAggregates(db)
+	for _, aggregate := range declared {
+		replaced := false
+		for i := range aggregates {
+			if aggregates[i].Name == aggregate.Name {
+				aggregates[i], replaced = aggregate, true
+			}
+		}
+		if !replaced {
+			aggregates = append(aggregates, aggregate)
+		}
+	}
+
+	for i := range aggregates {
+		if err := aggregates[i].ParseOffsets(); err != nil {
+			return err
+		}
+		if err := createContinuousAggregate(db, aggregates[i]); err != nil {
+			return fmt.Errorf("failed to create continuous aggregate %s: %w", aggregates[i].Name, err)
+		}
+	}
+
+	return nil
+}
+
+func aggregateDefinitionHash(aggregate config.ContinuousAggregate) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{aggregate.Query, aggregate.Start.String(), aggregate.End.String(), aggregate.Schedule.String()}, "\x00")))
+	return viewCommentPrefix + hex.EncodeToString(hash[:])
+}
+
+// createContinuousAggregate creates the aggregate if it does not exist yet or its definition or policy changed, like
+// the materialized views. Refreshing a continuous aggregate cannot run in a transaction, so the statements run one by
+// one; a failure half way leaves the aggregate without its comment and it is recreated on the next startup.
+func createContinuousAggregate(db *gorm.DB, aggregate config.ContinuousAggregate) error {
+	definition := aggregateDefinitionHash(aggregate)
+
+	var comment *string
+	err := db.Raw("SELECT obj_description(c.oid, 'pg_class') FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE c.relname = ? AND c.relkind IN ('v', 'm') AND n.nspname = current_schema()", aggregate.Name).Scan(&comment).Error
+	if err != nil {
+		return err
+	}
+
+	if comment != nil && *comment == definition {
+		return nil
+	}
+
+	if err := db.Exec(fmt.Sprintf("DROP MATERIALIZED VIEW IF EXISTS %q CASCADE", aggregate.Name)).Error; err != nil {
+		return err
+	}
+
+	if err := db.Exec(fmt.Sprintf("CREATE MATERIALIZED VIEW %q WITH (timescaledb.continuous) AS %s WITH NO DATA", aggregate.Name, aggregate.Query)).Error; err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%q", aggregate.Name)
+	if err := db.Exec("CALL refresh_continuous_aggregate(CAST(? AS regclass), NULL, NULL)", name).Error; err != nil {
+		return err
+	}
+
+	var start any
+	if aggregate.Start != 0 {
+		start = policyInterval(aggregate.Start)
+	}
+	err = db.Exec("SELECT add_continuous_aggregate_policy(CAST(? AS regclass), start_offset => CAST(? AS interval), end_offset => CAST(? AS interval), schedule_interval => CAST(? AS interval))",
+		name, start, policyInterval(aggregate.End), policyInterval(aggregate.Schedule)).Error
+	if err != nil {
+		return err
+	}
+
+	if err := db.Exec(fmt.Sprintf("COMMENT ON MATERIALIZED VIEW %q IS '%s'", aggregate.Name, definition)).Error; err != nil {
+		return err
+	}
+
+	config.Log.Infof("Created continuous aggregate %s", aggregate.Name)
+	return nil
+}
+
+func policyInterval(duration time.Duration) string {
+	return fmt.Sprintf("%d seconds", int64(duration/time.Second))
+}
+
+// replaceFeePoints replaces the fee points of the block with its indexed fees. Must be called inside the indexing
+// transaction, after the fees were written.
+func replaceFeePoints(db *gorm.DB, block *models.Block) error {
+	if err := db.Where("chain_id = ? AND height = ?", block.ChainID, block.Height).Delete(&models.FeePoint{}).Error; err != nil {
+		return err
+	}
+
+	return insertFeePoints(db, "b.id = ?", block.ID)
+}
+
+func insertFeePoints(db *gorm.DB, condition string, args ...any) error {
+	return db.Exec(fmt.Sprintf(`INSERT INTO %q (time, chain_id, height, tx_id, payer, denom_id, amount)
+		SELECT b.time_stamp, b.chain_id, b.height, t.id, a.address, f.denomination_id, f.amount FROM %q f
+		JOIN %q t ON t.id = f.tx_id
+		JOIN %q b ON b.id = t.block_id
+		JOIN %q a ON a.id = f.payer_address_id
+		WHERE %s`,
+		TableName(db, &models.FeePoint{}), TableName(db, &models.Fee{}), TableName(db, &models.Tx{}),
+		TableName(db, &models.Block{}), TableName(db, &models.Address{}), condition), args...).Error
+}
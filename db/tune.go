@@ -0,0 +1,167 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// TuneTargets are the values selected by the configured filters, which are the values queries most likely filter on
+type TuneTargets struct {
+	MessageTypes         []string
+	BlockEventTypes      []string
+	BlockEventAttributes []BlockEventAttributeTarget
+}
+
+type BlockEventAttributeTarget struct {
+	EventType    string
+	AttributeKey string
+}
+
+// IndexRecommendation is an index that is missing for the filters or the queries of the serve datasets
+type IndexRecommendation struct {
+	Name      string
+	Table     string
+	Reason    string
+	Statement string
+}
+
+// The foreign keys the serve datasets join on, an index leading with the column keeps the joins off sequential scans
+var tuneJoinColumns = []struct {
+	model  any
+	column string
+}{
+	{&models.Tx{}, "block_id"},
+	{&models.Message{}, "tx_id"},
+	{&models.Message{}, "message_type_id"},
+	{&models.MessageEvent{}, "message_id"},
+	{&models.MessageEventAttribute{}, "message_event_id"},
+	{&models.BlockEvent{}, "block_id"},
+	{&models.BlockEventAttribute{}, "block_event_id"},
+}
+
+// RecommendIndexes returns the indexes that do not exist yet for the join columns of the serve datasets and the
+// filter targets. Filter targets become partial indexes on the ID of the type or attribute key, targets that were not
+// indexed yet are skipped since their ID is unknown.
+func RecommendIndexes(db *gorm.DB, targets TuneTargets) ([]IndexRecommendation, error) {
+	var recommendations []IndexRecommendation
+
+	for _, join := range tuneJoinColumns {
+		table := TableName(db, join.model)
+		var indexed bool
+		err := db.Raw(`SELECT EXISTS (
+			SELECT 1 FROM pg_index i
+			JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = i.indkey[0]
+			WHERE i.indrelid = to_regclass(?) AND a.attname = ? AND i.indpred IS NULL)`, fmt.Sprintf("%q", table), join.column).Scan(&indexed).Error
+		if err != nil {
+			return nil, err
+		}
+		if indexed {
+			continue
+		}
+
+		name := fmt.Sprintf("tune_%s_%s", table, join.column)
+		recommendations = append(recommendations, IndexRecommendation{
+			Name:      name,
+			Table:     table,
+			Reason:    fmt.Sprintf("%s.%s is joined on by the serve datasets", table, join.column),
+			Statement: fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %q ON %q (%q)", name, table, join.column),
+		})
+	}
+
+	for _, messageType := range targets.MessageTypes {
+		id, err := lookupID(db, &models.MessageType{}, "message_type", messageType)
+		if err != nil {
+			return nil, err
+		}
+		if id == 0 {
+			continue
+		}
+		table := TableName(db, &models.Message{})
+		name := fmt.Sprintf("tune_%s_type_%d", table, id)
+		recommendations = append(recommendations, IndexRecommendation{
+			Name:      name,
+			Table:     table,
+			Reason:    fmt.Sprintf("message type filter %s", messageType),
+			Statement: fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %q ON %q (tx_id) WHERE message_type_id = %d", name, table, id),
+		})
+	}
+
+	for _, eventType := range targets.BlockEventTypes {
+		id, err := lookupID(db, &models.BlockEventType{}, "type", eventType)
+		if err != nil {
+			return nil, err
+		}
+		if id == 0 {
+			continue
+		}
+		table := TableName(db, &models.BlockEvent{})
+		name := fmt.Sprintf("tune_%s_type_%d", table, id)
+		recommendations = append(recommendations, IndexRecommendation{
+			Name:      name,
+			Table:     table,
+			Reason:    fmt.Sprintf("block event type filter %s", eventType),
+			Statement: fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %q ON %q (block_id) WHERE block_event_type_id = %d", name, table, id),
+		})
+	}
+
+	for _, attribute := range targets.BlockEventAttributes {
+		id, err := lookupID(db, &models.BlockEventAttributeKey{}, "key", attribute.AttributeKey)
+		if err != nil {
+			return nil, err
+		}
+		if id == 0 {
+			continue
+		}
+		table := TableName(db, &models.BlockEventAttribute{})
+		name := fmt.Sprintf("tune_%s_key_%d", table, id)
+		recommendations = append(recommendations, IndexRecommendation{
+			Name:      name,
+			Table:     table,
+			Reason:    fmt.Sprintf("block event attribute filter %s.%s", attribute.EventType, attribute.AttributeKey),
+			Statement: fmt.Sprintf("CREATE INDEX CONCURRENTLY IF NOT EXISTS %q ON %q (value) WHERE block_event_attribute_key_id = %d", name, table, id),
+		})
+	}
+
+	return withoutExistingIndexes(db, recommendations)
+}
+
+// lookupID returns the ID of the row of a lookup table with the value, 0 if there is none
+func lookupID(db *gorm.DB, model any, column string, value string) (uint, error) {
+	var ids []uint
+	err := db.Model(model).Where(fmt.Sprintf("%q = ?", column), value).Limit(1).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// withoutExistingIndexes drops the recommendations that were applied before, and duplicates of the same index, e.g.
+// an attribute key used by filters on several event types
+func withoutExistingIndexes(db *gorm.DB, recommendations []IndexRecommendation) ([]IndexRecommendation, error) {
+	seen := make(map[string]struct{}, len(recommendations))
+	missing := make([]IndexRecommendation, 0, len(recommendations))
+
+	for _, recommendation := range recommendations {
+		if _, ok := seen[recommendation.Name]; ok {
+			continue
+		}
+		seen[recommendation.Name] = struct{}{}
+
+		var exists bool
+		if err := db.Raw("SELECT to_regclass(?) IS NOT NULL", fmt.Sprintf("%q", recommendation.Name)).Scan(&exists).Error; err != nil {
+			return nil, err
+		}
+		if !exists {
+			missing = append(missing, recommendation)
+		}
+	}
+
+	return missing, nil
+}
+
+// ApplyIndexRecommendation creates the index without locking the table against writes, so it can run while indexing
+func ApplyIndexRecommendation(db *gorm.DB, recommendation IndexRecommendation) error {
+	return db.Exec(recommendation.Statement).Error
+}
@@ -15,6 +15,10 @@ func FindOrCreateDenomByBase(db *gorm.DB, base string) (models.Denom, error) {
 	denom := models.Denom{
 		Base: base,
 	}
+	if id, ok := dictionaries.lookup(denomDictionary, base); ok {
+		denom.ID = id
+		return denom, nil
+	}
 	err := db.Where(&denom).FirstOrCreate(&denom).Error
 	return denom, err
 }
@@ -27,6 +31,10 @@ func FindOrCreateAddressByAddress(db *gorm.DB, address string) (models.Address,
 	addr := models.Address{
 		Address: address,
 	}
+	if id, ok := dictionaries.lookup(addressDictionary, address); ok {
+		addr.ID = id
+		return addr, nil
+	}
 	err := db.Where(&addr).FirstOrCreate(&addr).Error
 	return addr, err
 }
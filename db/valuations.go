@@ -0,0 +1,203 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Flow is an amount of a denom moved by a fee or an event of a block. MessageType is the type of the message that
+// emitted the event, empty for fees and block events.
+type Flow struct {
+	Kind           models.FlowKind
+	MessageType    string
+	EventType      string
+	FeeID          *uint
+	MessageEventID *uint
+	BlockEventID   *uint
+	Denom          string
+	Amount         decimal.Decimal
+}
+
+// The event types whose amounts are flows, events of other types are not valued
+var flowEventKinds = map[string]models.FlowKind{
+	"transfer":            models.FlowTransfer,
+	"withdraw_rewards":    models.FlowReward,
+	"withdraw_commission": models.FlowReward,
+}
+
+var flowCoinPattern = regexp.MustCompile(`^([0-9]+)([a-zA-Z][a-zA-Z0-9/:._-]*)$`)
+
+type flowAttribute struct {
+	EventID         uint
+	Type            string
+	MessageType     string
+	Value           string `gorm:"serializer:attribute_value"`
+	CompressedValue []byte `gorm:"serializer:attribute_value"`
+}
+
+// BlockFlows returns the fees of the block and the amounts of its transfer and reward events. Events of failed
+// transactions moved nothing and are left out.
+func BlockFlows(db *gorm.DB, blockID uint) ([]Flow, error) {
+	var fees []struct {
+		ID     uint
+		Denom  string
+		Amount decimal.Decimal
+	}
+	err := db.Raw(fmt.Sprintf(`SELECT f.id, d.base AS denom, f.amount FROM %q f
+		JOIN %q t ON t.id = f.tx_id
+		JOIN %q d ON d.id = f.denomination_id
+		WHERE t.block_id = ? ORDER BY f.id`,
+		TableName(db, &models.Fee{}), TableName(db, &models.Tx{}), TableName(db, &models.Denom{})), blockID).Scan(&fees).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var flows []Flow
+	for i := range fees {
+		flows = append(flows, Flow{Kind: models.FlowFee, FeeID: &fees[i].ID, Denom: fees[i].Denom, Amount: fees[i].Amount})
+	}
+
+	eventTypes := make([]string, 0, len(flowEventKinds))
+	for eventType := range flowEventKinds {
+		eventTypes = append(eventTypes, eventType)
+	}
+
+	var messageAttributes []flowAttribute
+	err = db.Raw(fmt.Sprintf(`SELECT e.id AS event_id, et.type, mt.message_type, a.value, a.compressed_value FROM %q a
+		JOIN %q k ON k.id = a.message_event_attribute_key_id
+		JOIN %q e ON e.id = a.message_event_id
+		JOIN %q et ON et.id = e.message_event_type_id
+		JOIN %q m ON m.id = e.message_id
+		JOIN %q mt ON mt.id = m.message_type_id
+		JOIN %q t ON t.id = m.tx_id
+		WHERE t.block_id = ? AND t.code = 0 AND k.key = 'amount' AND et.type IN ?
+		ORDER BY e.id, a.index`,
+		TableName(db, &models.MessageEventAttribute{}), TableName(db, &models.MessageEventAttributeKey{}),
+		TableName(db, &models.MessageEvent{}), TableName(db, &models.MessageEventType{}),
+		TableName(db, &models.Message{}), TableName(db, &models.MessageType{}), TableName(db, &models.Tx{})), blockID, eventTypes).Scan(&messageAttributes).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, attribute := range messageAttributes {
+		eventID := attribute.EventID
+		flows = appendEventFlows(flows, attribute, func(flow *Flow) { flow.MessageEventID = &eventID })
+	}
+
+	var blockAttributes []flowAttribute
+	err = db.Raw(fmt.Sprintf(`SELECT e.id AS event_id, et.type, a.value, a.compressed_value FROM %q a
+		JOIN %q k ON k.id = a.block_event_attribute_key_id
+		JOIN %q e ON e.id = a.block_event_id
+		JOIN %q et ON et.id = e.block_event_type_id
+		WHERE e.block_id = ? AND k.key = 'amount' AND et.type IN ?
+		ORDER BY e.id, a.index`,
+		TableName(db, &models.BlockEventAttribute{}), TableName(db, &models.BlockEventAttributeKey{}),
+		TableName(db, &models.BlockEvent{}), TableName(db, &models.BlockEventType{})), blockID, eventTypes).Scan(&blockAttributes).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, attribute := range blockAttributes {
+		eventID := attribute.EventID
+		flows = appendEventFlows(flows, attribute, func(flow *Flow) { flow.BlockEventID = &eventID })
+	}
+
+	return flows, nil
+}
+
+// appendEventFlows appends a flow for every coin of an amount attribute, e.g. 10uatom,5uosmo. Amounts that are not
+// coins, such as the decimal coins of some events, are skipped.
+func appendEventFlows(flows []Flow, attribute flowAttribute, setEvent func(*Flow)) []Flow {
+	for _, coin := range strings.Split(attribute.Value, ",") {
+		match := flowCoinPattern.FindStringSubmatch(strings.TrimSpace(coin))
+		if match == nil {
+			continue
+		}
+		amount, err := decimal.NewFromString(match[1])
+		if err != nil {
+			continue
+		}
+
+		flow := Flow{
+			Kind:        flowEventKinds[attribute.Type],
+			MessageType: attribute.MessageType,
+			EventType:   attribute.Type,
+			Denom:       match[2],
+			Amount:      amount,
+		}
+		setEvent(&flow)
+		flows = append(flows, flow)
+	}
+	return flows
+}
+
+// DenomExponents returns the exponents of the display units of the denoms by base denom
+func DenomExponents(db *gorm.DB) (map[string]uint32, error) {
+	var units []struct {
+		Base     string
+		Exponent uint32
+	}
+	err := db.Raw(fmt.Sprintf(`SELECT d.base, m.exponent FROM %q m JOIN %q d ON d.id = m.denom_id`,
+		TableName(db, &models.DenomMetadata{}), TableName(db, &models.Denom{}))).Scan(&units).Error
+	if err != nil {
+		return nil, err
+	}
+
+	exponents := make(map[string]uint32, len(units))
+	for _, unit := range units {
+		exponents[unit.Base] = unit.Exponent
+	}
+	return exponents, nil
+}
+
+// UnvaluedBlocks returns up to limit blocks of the chain from a height up to the end height (0 for no end), ordered by
+// height, that were not valued yet. With revalue they are returned whether they were valued or not.
+func UnvaluedBlocks(db *gorm.DB, chainID uint, fromHeight int64, endHeight int64, revalue bool, limit int) ([]models.Block, error) {
+	return blocksWithout(db, "valued", chainID, fromHeight, endHeight, revalue, limit)
+}
+
+// blocksWithout returns up to limit blocks in the height range whose marker is not set, or all of them
+func blocksWithout(db *gorm.DB, marker string, chainID uint, fromHeight int64, endHeight int64, all bool, limit int) ([]models.Block, error) {
+	query := db.Where("chain_id = ? AND height >= ?", chainID, fromHeight)
+	if endHeight > 0 {
+		query = query.Where("height <= ?", endHeight)
+	}
+	if !all {
+		query = query.Where(fmt.Sprintf("%q = ?", marker), false)
+	}
+
+	var blocks []models.Block
+	err := query.Order("height").Limit(limit).Find(&blocks).Error
+	return blocks, err
+}
+
+// StoreValuations replaces the valuations and normalized fees of the block and marks it valued
+func StoreValuations(db *gorm.DB, block *models.Block, valuations []models.Valuation, fees []models.NormalizedFee) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("block_id = ?", block.ID).Delete(&models.Valuation{}).Error; err != nil {
+			return err
+		}
+
+		if len(valuations) != 0 {
+			if err := tx.Omit("Denom").CreateInBatches(valuations, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Where("block_id = ?", block.ID).Delete(&models.NormalizedFee{}).Error; err != nil {
+			return err
+		}
+
+		if len(fees) != 0 {
+			if err := tx.CreateInBatches(fees, 500).Error; err != nil {
+				return err
+			}
+		}
+
+		block.Valued = true
+		return tx.Model(&models.Block{}).Where("id = ?", block.ID).Update("valued", true).Error
+	})
+}
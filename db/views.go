@@ -0,0 +1,77 @@
+package db
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"gorm.io/gorm"
+)
+
+// Views created by the indexer carry this comment prefix followed by a hash of their definition, so a changed
+// definition is detected on startup without comparing the query text Postgres normalized
+const viewCommentPrefix = "cosmos-indexer:"
+
+func viewDefinitionHash(view config.MaterializedView) string {
+	hash := sha256.Sum256([]byte(view.Query + "\x00" + strings.Join(view.UniqueColumns, ",")))
+	return viewCommentPrefix + hex.EncodeToString(hash[:])
+}
+
+// CreateMaterializedViews creates the declared views that do not exist yet and recreates those whose definition changed.
+// New views are populated on creation, which can take a while for aggregates over large tables.
+func CreateMaterializedViews(db *gorm.DB, views []config.MaterializedView) error {
+	for _, view := range views {
+		definition := viewDefinitionHash(view)
+
+		var comment *string
+		err := db.Raw("SELECT obj_description(c.oid, 'pg_class') FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE c.relname = ? AND c.relkind = 'm' AND n.nspname = current_schema()", view.Name).Scan(&comment).Error
+		if err != nil {
+			return err
+		}
+
+		if comment != nil && *comment == definition {
+			continue
+		}
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(fmt.Sprintf("DROP MATERIALIZED VIEW IF EXISTS %q", view.Name)).Error; err != nil {
+				return err
+			}
+
+			if err := tx.Exec(fmt.Sprintf("CREATE MATERIALIZED VIEW %q AS %s", view.Name, view.Query)).Error; err != nil {
+				return err
+			}
+
+			if len(view.UniqueColumns) != 0 {
+				columns := make([]string, len(view.UniqueColumns))
+				for i, column := range view.UniqueColumns {
+					columns[i] = fmt.Sprintf("%q", column)
+				}
+				if err := tx.Exec(fmt.Sprintf("CREATE UNIQUE INDEX %q ON %q (%s)", view.Name+"_unique", view.Name, strings.Join(columns, ", "))).Error; err != nil {
+					return err
+				}
+			}
+
+			return tx.Exec(fmt.Sprintf("COMMENT ON MATERIALIZED VIEW %q IS '%s'", view.Name, definition)).Error
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create materialized view %s: %w", view.Name, err)
+		}
+
+		config.Log.Infof("Created materialized view %s", view.Name)
+	}
+
+	return nil
+}
+
+// RefreshMaterializedView refreshes a view, concurrently if it has unique columns
+func RefreshMaterializedView(db *gorm.DB, view config.MaterializedView) error {
+	statement := "REFRESH MATERIALIZED VIEW %q"
+	if len(view.UniqueColumns) != 0 {
+		statement = "REFRESH MATERIALIZED VIEW CONCURRENTLY %q"
+	}
+
+	return db.Exec(fmt.Sprintf(statement, view.Name)).Error
+}
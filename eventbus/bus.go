@@ -0,0 +1,85 @@
+// Package eventbus fans out in-process events to any number of subscribers.
+package eventbus
+
+import "sync"
+
+// Bus delivers every published event to all subscriptions. Publishing never blocks: a subscriber that does not keep
+// up with its buffer is dropped and its channel closed, so a slow client cannot stall the others.
+type Bus[T any] struct {
+	mu            sync.Mutex
+	subscriptions map[*Subscription[T]]struct{}
+	closed        bool
+}
+
+type Subscription[T any] struct {
+	bus    *Bus[T]
+	events chan T
+	closed bool
+}
+
+func New[T any]() *Bus[T] {
+	return &Bus[T]{subscriptions: map[*Subscription[T]]struct{}{}}
+}
+
+// Subscribe returns a subscription receiving the events published from now on, buffering up to buffer events. The
+// subscriptions of a closed bus are closed right away.
+func (b *Bus[T]) Subscribe(buffer int) *Subscription[T] {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	subscription := &Subscription[T]{bus: b, events: make(chan T, buffer)}
+	if b.closed {
+		subscription.closed = true
+		close(subscription.events)
+		return subscription
+	}
+
+	b.subscriptions[subscription] = struct{}{}
+	return subscription
+}
+
+func (b *Bus[T]) Publish(event T) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for subscription := range b.subscriptions {
+		select {
+		case subscription.events <- event:
+		default:
+			b.remove(subscription)
+		}
+	}
+}
+
+// Close closes every subscription, e.g. when the source of the events stops
+func (b *Bus[T]) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+	for subscription := range b.subscriptions {
+		b.remove(subscription)
+	}
+}
+
+// Must be called with the lock held
+func (b *Bus[T]) remove(subscription *Subscription[T]) {
+	if subscription.closed {
+		return
+	}
+	subscription.closed = true
+	delete(b.subscriptions, subscription)
+	close(subscription.events)
+}
+
+// Events is closed once the subscription is cancelled or dropped for falling behind
+func (s *Subscription[T]) Events() <-chan T {
+	return s.events
+}
+
+func (s *Subscription[T]) Cancel() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+
+	s.bus.remove(s)
+}
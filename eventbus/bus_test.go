@@ -0,0 +1,56 @@
+package eventbus
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type BusTestSuite struct {
+	suite.Suite
+}
+
+func (suite *BusTestSuite) TestPublish() {
+	bus := New[int]()
+	first := bus.Subscribe(2)
+	second := bus.Subscribe(2)
+
+	bus.Publish(1)
+	suite.Require().Equal(1, <-first.Events())
+	suite.Require().Equal(1, <-second.Events())
+
+	second.Cancel()
+	second.Cancel()
+	_, ok := <-second.Events()
+	suite.Require().False(ok)
+
+	bus.Publish(2)
+	suite.Require().Equal(2, <-first.Events())
+}
+
+func (suite *BusTestSuite) TestSlowSubscriberDropped() {
+	bus := New[int]()
+	slow := bus.Subscribe(1)
+	fast := bus.Subscribe(2)
+
+	bus.Publish(1)
+	bus.Publish(2)
+
+	suite.Require().Equal(1, <-slow.Events())
+	_, ok := <-slow.Events()
+	suite.Require().False(ok)
+
+	suite.Require().Equal(1, <-fast.Events())
+	suite.Require().Equal(2, <-fast.Events())
+
+	bus.Close()
+	_, ok = <-fast.Events()
+	suite.Require().False(ok)
+
+	_, ok = <-bus.Subscribe(1).Events()
+	suite.Require().False(ok)
+}
+
+func TestBusTestSuite(t *testing.T) {
+	suite.Run(t, new(BusTestSuite))
+}
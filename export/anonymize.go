@@ -0,0 +1,96 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+)
+
+// bech32Address matches the Bech32 encoded account, validator and consensus addresses of any chain
+const bech32Address = `[a-z][a-z0-9]*1[qpzry9x8gf2tvdw0s3jn54khce6mua7l]{38,}`
+
+var (
+	addressPattern         = regexp.MustCompile("^" + bech32Address + "$")
+	containsAddressPattern = regexp.MustCompile(bech32Address)
+)
+
+// Anonymizer pseudonymizes exported data so it can be shared without identifying users. Text that is an address is
+// replaced with the hex HMAC-SHA256 of the address under the key, so the same address gets the same pseudonym in every
+// table and export made with the key, without the address being recoverable. Text that contains an address among other
+// text, e.g. a list of recipients or an error message, is dropped, as are memos and binary columns: the raw
+// transactions and messages carry the memos and addresses in encoded form.
+//
+// The hashing runs in the export queries, using the HMAC construction on the sha256 function of Postgres 11+, so no
+// extension is required. Values the queries can't read, the compressed event attribute values, are anonymized the same
+// way once decompressed.
+type Anonymizer struct {
+	innerPad []byte
+	outerPad []byte
+}
+
+func NewAnonymizer(key []byte) *Anonymizer {
+	// HMAC keys longer than the block size are hashed, shorter keys are padded with zeros
+	const blockSize = sha256.BlockSize
+	if len(key) > blockSize {
+		sum := sha256.Sum256(key)
+		key = sum[:]
+	}
+
+	anonymizer := &Anonymizer{innerPad: make([]byte, blockSize), outerPad: make([]byte, blockSize)}
+	copy(anonymizer.innerPad, key)
+	copy(anonymizer.outerPad, key)
+	for i := range anonymizer.innerPad {
+		anonymizer.innerPad[i] ^= 0x36
+		anonymizer.outerPad[i] ^= 0x5c
+	}
+	return anonymizer
+}
+
+// pseudonym is the SQL expression of the pseudonym of the text expression
+func (a *Anonymizer) pseudonym(expression string) string {
+	return fmt.Sprintf("encode(sha256(decode('%s', 'hex') || sha256(decode('%s', 'hex') || convert_to(%s, 'UTF8'))), 'hex')",
+		hex.EncodeToString(a.outerPad), hex.EncodeToString(a.innerPad), expression)
+}
+
+// column returns the SQL expression selecting the column anonymized, the column itself for columns that can't
+// identify anyone
+func (a *Anonymizer) column(col tableColumn) string {
+	name := quoteIdentifier(col.ColumnName)
+	if a == nil {
+		return name
+	}
+
+	// Memos are free text written by the users
+	if col.ColumnName == "memo" {
+		return "NULL::text"
+	}
+
+	switch col.DataType {
+	case "text", "character varying":
+		return fmt.Sprintf("CASE WHEN %s ~ '^%s$' THEN %s WHEN %s ~ '%s' THEN NULL ELSE %s END",
+			name, bech32Address, a.pseudonym(name), name, bech32Address, name)
+	case "json", "jsonb":
+		return fmt.Sprintf("CASE WHEN %s::text ~ '%s' THEN NULL ELSE %s END", name, bech32Address, name)
+	case "bytea":
+		return "NULL::bytea"
+	default:
+		return name
+	}
+}
+
+// text returns the text value anonymized as column does for text columns, false when it is dropped
+func (a *Anonymizer) text(value string) (string, bool) {
+	switch {
+	case a == nil:
+		return value, true
+	case addressPattern.MatchString(value):
+		inner := sha256.Sum256(append(append([]byte{}, a.innerPad...), value...))
+		outer := sha256.Sum256(append(append([]byte{}, a.outerPad...), inner[:]...))
+		return hex.EncodeToString(outer[:]), true
+	case containsAddressPattern.MatchString(value):
+		return "", false
+	default:
+		return value, true
+	}
+}
@@ -0,0 +1,68 @@
+package export
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/query"
+	"gorm.io/gorm"
+)
+
+type TimeBucketsOptions struct {
+	ChainID  string
+	Interval query.BucketInterval
+	// Location is the timezone whose calendar the buckets follow
+	Location  *time.Location
+	StartTime time.Time
+	EndTime   time.Time
+	// Output is the path of the CSV file to create
+	Output    string
+	Overwrite bool
+}
+
+// TimeBuckets exports the blocks and transactions of a chain aggregated per time bucket to a CSV file, with the start
+// of every bucket in the timezone of the options
+func TimeBuckets(ctx context.Context, db *gorm.DB, opts TimeBucketsOptions) error {
+	if _, statErr := os.Stat(opts.Output); statErr == nil && !opts.Overwrite {
+		return fmt.Errorf("%s already exists", opts.Output)
+	}
+
+	if _, err := findChain(db, opts.ChainID); err != nil {
+		return err
+	}
+
+	buckets, err := query.New(db).TimeBuckets(ctx, opts.ChainID, opts.Interval, opts.Location, opts.StartTime, opts.EndTime)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(opts.Output)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	_ = writer.Write([]string{"chain_id", "start", "first_height", "last_height", "blocks", "txs", "failed_txs"})
+	for _, bucket := range buckets {
+		_ = writer.Write([]string{
+			bucket.ChainID,
+			bucket.Start.Format(time.RFC3339),
+			strconv.FormatInt(bucket.FirstHeight, 10),
+			strconv.FormatInt(bucket.LastHeight, 10),
+			strconv.FormatInt(bucket.Blocks, 10),
+			strconv.FormatInt(bucket.Txs, 10),
+			strconv.FormatInt(bucket.FailedTxs, 10),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		os.Remove(opts.Output)
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,159 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// compressedValueColumn holds the large event attribute values zstd compressed, with the value column left NULL. It is
+// not exported, its values are written decompressed into the value column instead.
+const compressedValueColumn = "compressed_value"
+
+// markCompressed leaves the compressed value column out of the columns of a table and marks the value column
+func markCompressed(columns []tableColumn) []tableColumn {
+	valueIndex := -1
+	compressedIndex := -1
+	for i, col := range columns {
+		switch col.ColumnName {
+		case "value":
+			valueIndex = i
+		case compressedValueColumn:
+			compressedIndex = i
+		}
+	}
+	if valueIndex < 0 || compressedIndex < 0 {
+		return columns
+	}
+
+	marked := append(append([]tableColumn{}, columns[:compressedIndex]...), columns[compressedIndex+1:]...)
+	if valueIndex > compressedIndex {
+		valueIndex--
+	}
+	marked[valueIndex].Compressed = true
+	return marked
+}
+
+// compressedSelect returns the select to append after the ones of the columns for the compressed values of the
+// compressed column, empty when the table has none
+func compressedSelect(columns []tableColumn) string {
+	for _, col := range columns {
+		if col.Compressed {
+			// encode wraps base64 lines at 76 characters
+			return fmt.Sprintf("translate(encode(%s, 'base64'), E'\\n', '') AS %s", compressedValueColumn, compressedValueColumn)
+		}
+	}
+	return ""
+}
+
+// copyColumnsToCSV streams the result of a query selecting the columns, followed by the compressedSelect, as CSV with a
+// header to the writer. The compressed values are decompressed into their column and the last field is left out.
+func copyColumnsToCSV(ctx context.Context, db *gorm.DB, query string, columns []tableColumn, anonymizer *Anonymizer, output io.Writer) (int64, error) {
+	valueIndex := -1
+	for i, col := range columns {
+		if col.Compressed {
+			valueIndex = i
+		}
+	}
+	if valueIndex < 0 {
+		return copyToCSV(ctx, db, query, output)
+	}
+
+	reader, writer := io.Pipe()
+	decompressed := make(chan error, 1)
+	go func() {
+		err := decompressValues(reader, output, valueIndex, anonymizer)
+		// Unblocks the copy when the values could not be decompressed
+		reader.CloseWithError(err)
+		decompressed <- err
+	}()
+
+	rows, err := copyToCSV(ctx, db, query, writer)
+	writer.CloseWithError(err)
+	if decompressErr := <-decompressed; err == nil {
+		err = decompressErr
+	}
+	return rows, err
+}
+
+// decompressValues copies the CSV records of the input to the output, with the compressed value in the last field of
+// each row decompressed into the field at valueIndex and the last field left out. The other fields are copied as they
+// are, so a NULL stays told apart from an empty text. The decompressed values are anonymized as the text columns are.
+func decompressValues(input io.Reader, output io.Writer, valueIndex int, anonymizer *Anonymizer) error {
+	in := bufio.NewReader(input)
+	out := bufio.NewWriter(output)
+
+	for header := true; ; header = false {
+		fields, err := readCSVRecord(in)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		last := len(fields) - 1
+		if last <= valueIndex {
+			return fmt.Errorf("CSV record has %d fields, the compressed value is expected after field %d", len(fields), valueIndex+1)
+		}
+
+		if compressed := fields[last]; !header && compressed != "" {
+			frame, err := base64.StdEncoding.DecodeString(compressed)
+			if err != nil {
+				return fmt.Errorf("failed to decode compressed value: %w", err)
+			}
+			value, err := models.DecompressBytes(frame)
+			if err != nil {
+				return err
+			}
+
+			// An unquoted empty field is NULL
+			fields[valueIndex] = ""
+			if text, ok := anonymizer.text(string(value)); ok {
+				fields[valueIndex] = `"` + strings.ReplaceAll(text, `"`, `""`) + `"`
+			}
+		}
+
+		if _, err := out.WriteString(strings.Join(fields[:last], ",") + "\n"); err != nil {
+			return err
+		}
+	}
+
+	return out.Flush()
+}
+
+// readCSVRecord reads the fields of a CSV record as they are written, quotes included
+func readCSVRecord(in *bufio.Reader) ([]string, error) {
+	var fields []string
+	var field strings.Builder
+	quoted := false
+
+	for {
+		c, err := in.ReadByte()
+		if err == io.EOF && (len(fields) != 0 || field.Len() != 0) {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case c == '"':
+			// Escaped quotes are doubled, so they toggle twice
+			quoted = !quoted
+		case c == ',' && !quoted:
+			fields = append(fields, field.String())
+			field.Reset()
+			continue
+		case c == '\n' && !quoted:
+			return append(fields, field.String()), nil
+		}
+		field.WriteByte(c)
+	}
+}
@@ -0,0 +1,231 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/gorm"
+)
+
+type DuckDBOptions struct {
+	ChainID     string
+	StartHeight int64
+	EndHeight   int64
+	// Output is the path of the DuckDB database file to create
+	Output string
+	// Binary is the DuckDB CLI used to build the database file
+	Binary    string
+	Overwrite bool
+	// Anonymizer pseudonymizes the exported data, nil exports it as indexed
+	Anonymizer *Anonymizer
+}
+
+type tableColumn struct {
+	ColumnName string
+	DataType   string
+	// Compressed is set on the value column of the event attributes, whose large values are in the compressed value
+	// column. The exports read them with compressedSelect and write them decompressed.
+	Compressed bool `gorm:"-"`
+}
+
+// DuckDB exports the indexed data of a chain and height range into a single DuckDB database file. Every indexer table
+// is exported with its full set of columns, lookup tables are exported whole. The data is streamed out of Postgres
+// with COPY and loaded by the DuckDB CLI in a single transaction, so a failed export leaves no partial file behind.
+func DuckDB(ctx context.Context, db *gorm.DB, opts DuckDBOptions) error {
+	binary, err := exec.LookPath(opts.Binary)
+	if err != nil {
+		return fmt.Errorf("DuckDB CLI %s not found: %w", opts.Binary, err)
+	}
+
+	if _, statErr := os.Stat(opts.Output); statErr == nil {
+		if !opts.Overwrite {
+			return fmt.Errorf("%s already exists", opts.Output)
+		}
+		if err := os.Remove(opts.Output); err != nil {
+			return err
+		}
+	}
+
+	chain, err := findChain(db, opts.ChainID)
+	if err != nil {
+		return err
+	}
+
+	workDir, err := os.MkdirTemp("", "cosmos-indexer-duckdb")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workDir)
+
+	exportScope := scope{chainID: chain.ID, startHeight: opts.StartHeight, endHeight: opts.EndHeight}
+	indexerTables := models.NewTables(db.NamingStrategy)
+
+	var script strings.Builder
+	script.WriteString("BEGIN TRANSACTION;\n")
+
+	for _, exportTable := range tables {
+		tableScript, err := copyTable(ctx, db, indexerTables, exportTable, exportScope, opts.Anonymizer, workDir)
+		if err != nil {
+			return fmt.Errorf("failed to export table %s: %w", exportTable.name, err)
+		}
+		script.WriteString(tableScript)
+	}
+
+	fmt.Fprintf(&script, "CREATE TABLE export_info (chain_id VARCHAR, start_height BIGINT, end_height BIGINT, exported_at TIMESTAMPTZ, anonymized BOOLEAN);\n")
+	fmt.Fprintf(&script, "INSERT INTO export_info VALUES (%s, %d, %d, %s, %t);\n", quoteLiteral(opts.ChainID), opts.StartHeight, opts.EndHeight, quoteLiteral(time.Now().UTC().Format(time.RFC3339)), opts.Anonymizer != nil)
+	script.WriteString("COMMIT;\n")
+
+	cmd := exec.CommandContext(ctx, binary, "-bail", opts.Output)
+	cmd.Stdin = strings.NewReader(script.String())
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		os.Remove(opts.Output)
+		return fmt.Errorf("DuckDB failed to load the export: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// copyTable writes the table rows in scope to a CSV file and returns the DuckDB statements that create and load the table
+func copyTable(ctx context.Context, db *gorm.DB, indexerTables models.Tables, exportTable table, exportScope scope, anonymizer *Anonymizer, workDir string) (string, error) {
+	source := indexerTables.Name(exportTable.name)
+	columns, err := tableColumns(db, source)
+	if err != nil {
+		return "", err
+	}
+
+	// Tables are created by the index command migrations, a database that was never indexed into has none of them
+	if len(columns) == 0 {
+		config.Log.Warnf("Table %s does not exist, skipping it", source)
+		return "", nil
+	}
+
+	selects := make([]string, 0, len(columns))
+	definitions := make([]string, 0, len(columns))
+	loads := make([]string, 0, len(columns))
+	for _, col := range columns {
+		name := quoteIdentifier(col.ColumnName)
+		value := anonymizer.column(col)
+		duckType := duckDBType(col.DataType)
+		definitions = append(definitions, fmt.Sprintf("%s %s", name, duckType))
+
+		if duckType == "BLOB" {
+			// encode wraps base64 lines at 76 characters
+			selects = append(selects, fmt.Sprintf("translate(encode(%s, 'base64'), E'\\n', '') AS %s", value, name))
+			loads = append(loads, fmt.Sprintf("from_base64(%s)", name))
+		} else {
+			selects = append(selects, selectAs(value, name))
+			loads = append(loads, fmt.Sprintf("CAST(%s AS %s)", name, duckType))
+		}
+	}
+
+	if compressed := compressedSelect(columns); compressed != "" {
+		selects = append(selects, compressed)
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(selects, ", "), quoteIdentifier(source))
+	if exportTable.filter != nil {
+		query = fmt.Sprintf("%s WHERE %s", query, indexerTables.Expand(exportTable.filter(exportScope)))
+	}
+
+	csvPath := filepath.Join(workDir, exportTable.name+".csv")
+	file, err := os.Create(csvPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	rows, err := copyColumnsToCSV(ctx, db, query, columns, anonymizer, file)
+	if err != nil {
+		return "", err
+	}
+
+	config.Log.Infof("Exported %d rows of table %s", rows, exportTable.name)
+
+	var tableScript strings.Builder
+	fmt.Fprintf(&tableScript, "CREATE TABLE %s (%s);\n", quoteIdentifier(exportTable.name), strings.Join(definitions, ", "))
+	// Header only files cannot be sniffed by read_csv, empty tables are only created
+	if rows != 0 {
+		fmt.Fprintf(&tableScript, "INSERT INTO %s SELECT %s FROM read_csv(%s, header = true, all_varchar = true);\n",
+			quoteIdentifier(exportTable.name), strings.Join(loads, ", "), quoteLiteral(csvPath))
+	}
+
+	return tableScript.String(), nil
+}
+
+func findChain(db *gorm.DB, chainID string) (models.Chain, error) {
+	var chain models.Chain
+	if err := db.Where("chain_id = ?", chainID).First(&chain).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return chain, fmt.Errorf("chain %s has not been indexed", chainID)
+		}
+		return chain, err
+	}
+
+	return chain, nil
+}
+
+// tableColumns returns the columns of an indexer table in order, none if the table does not exist. The compressed value
+// column is left out and marks the value column as compressed.
+func tableColumns(db *gorm.DB, name string) ([]tableColumn, error) {
+	var columns []tableColumn
+	err := db.Raw("SELECT column_name, data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? ORDER BY ordinal_position", name).Scan(&columns).Error
+	if err != nil {
+		return nil, err
+	}
+	return markCompressed(columns), nil
+}
+
+// copyToCSV streams the query result as CSV with a header to the writer using the Postgres COPY protocol
+func copyToCSV(ctx context.Context, db *gorm.DB, query string, output io.Writer) (int64, error) {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return 0, err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var rows int64
+	err = conn.Raw(func(driverConn any) error {
+		pgxConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("exports require the pgx Postgres driver")
+		}
+
+		tag, err := pgxConn.Conn().PgConn().CopyTo(ctx, output, fmt.Sprintf("COPY (%s) TO STDOUT WITH (FORMAT csv, HEADER)", query))
+		rows = tag.RowsAffected()
+		return err
+	})
+
+	return rows, err
+}
+
+// selectAs selects the value as the column name, the value is the column itself unless it is anonymized
+func selectAs(value string, name string) string {
+	if value == name {
+		return name
+	}
+	return fmt.Sprintf("%s AS %s", value, name)
+}
+
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,267 @@
+package export
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+)
+
+// snowflakeWatermarks is the sink name the watermarks are stored under in the sink checkpoints table
+const snowflakeWatermarks = "snowflake"
+
+// Stage uploads export files to the storage location of an external Snowflake stage
+type Stage interface {
+	Upload(ctx context.Context, path string, file io.Reader) error
+}
+
+type SnowflakeOptions struct {
+	ChainID     string
+	StartHeight int64
+	// EndHeight is the last height loaded, 0 loads up to the latest indexed height
+	EndHeight int64
+	// BatchHeights is the number of heights exported to each staged file
+	BatchHeights int64
+	Snowflake    SnowflakeConfig
+	// StageName is the external stage in Snowflake whose location Stage uploads to
+	StageName string
+	Stage     Stage
+	// Snowpipe queues the staged files with one pipe per table instead of running COPY statements
+	Snowpipe bool
+	// Anonymizer pseudonymizes the loaded data, nil loads it as indexed
+	Anonymizer *Anonymizer
+}
+
+// Snowflake incrementally loads the indexed data of a chain into Snowflake tables named after the default names of the
+// indexer tables, whatever the table naming of the database. Every table keeps a watermark of the last height it was
+// loaded up to, or the last ID for lookup tables, so each run only exports what was indexed since the previous one.
+// Files are named after the range they contain, so a range that is exported again after a failed run is skipped by the
+// COPY and Snowpipe load history instead of being loaded twice.
+func Snowflake(ctx context.Context, db *gorm.DB, opts SnowflakeOptions) error {
+	chain, err := findChain(db, opts.ChainID)
+	if err != nil {
+		return err
+	}
+
+	client, err := newSnowflakeClient(opts.Snowflake)
+	if err != nil {
+		return err
+	}
+
+	indexerTables := models.NewTables(db.NamingStrategy)
+
+	// The target is fixed before the lookup tables are exported, so they contain every ID the exported heights refer to
+	endHeight := opts.EndHeight
+	if endHeight == 0 {
+		if err := db.Raw(indexerTables.Expand("SELECT COALESCE(MAX(height), 0) FROM {blocks} WHERE chain_id = ?"), chain.ID).Scan(&endHeight).Error; err != nil {
+			return err
+		}
+	}
+
+	watermarks, err := dbTypes.GetSinkCheckpoints(db, opts.ChainID, snowflakeWatermarks)
+	if err != nil {
+		return err
+	}
+
+	// Lookup tables without a filter are shared by all chains, so are their watermarks
+	sharedWatermarks, err := dbTypes.GetSinkCheckpoints(db, "", snowflakeWatermarks)
+	if err != nil {
+		return err
+	}
+
+	loader := snowflakeLoader{db: db, tables: indexerTables, client: client, opts: opts, chainID: chain.ID}
+
+	for _, exportTable := range tables {
+		columns, err := tableColumns(db, indexerTables.Name(exportTable.name))
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			config.Log.Warnf("Table %s does not exist, skipping it", indexerTables.Name(exportTable.name))
+			continue
+		}
+
+		if err := loader.createTable(ctx, exportTable, columns); err != nil {
+			return fmt.Errorf("failed to create Snowflake table %s: %w", exportTable.name, err)
+		}
+
+		switch {
+		case exportTable.lookup && exportTable.filter == nil:
+			err = loader.loadLookupTable(ctx, exportTable, columns, "", sharedWatermarks[exportTable.name])
+		case exportTable.lookup:
+			err = loader.loadLookupTable(ctx, exportTable, columns, opts.ChainID, watermarks[exportTable.name])
+		default:
+			err = loader.loadHeights(ctx, exportTable, columns, watermarks[exportTable.name], endHeight)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load table %s: %w", exportTable.name, err)
+		}
+	}
+
+	return nil
+}
+
+type snowflakeLoader struct {
+	db *gorm.DB
+	// tables resolves the indexer tables the rows are read from
+	tables  models.Tables
+	client  *snowflakeClient
+	opts    SnowflakeOptions
+	chainID uint
+}
+
+const snowflakeFileFormat = `FILE_FORMAT = (TYPE = CSV SKIP_HEADER = 1 FIELD_OPTIONALLY_ENCLOSED_BY = '"' COMPRESSION = GZIP ` +
+	`BINARY_FORMAT = BASE64 EMPTY_FIELD_AS_NULL = TRUE NULL_IF = ())`
+
+// Unquoted Snowflake identifiers are stored upper case, quoting the upper case names keeps them usable unquoted
+func snowflakeIdentifier(name string) string {
+	return quoteIdentifier(strings.ToUpper(name))
+}
+
+func snowflakePipe(tableName string) string {
+	return snowflakeIdentifier(tableName + "_pipe")
+}
+
+func (l *snowflakeLoader) stagePath(tableName string) string {
+	return fmt.Sprintf("@%s/%s/", l.opts.StageName, tableName)
+}
+
+func (l *snowflakeLoader) createTable(ctx context.Context, exportTable table, columns []tableColumn) error {
+	definitions := make([]string, 0, len(columns))
+	for _, col := range columns {
+		definitions = append(definitions, fmt.Sprintf("%s %s", snowflakeIdentifier(col.ColumnName), snowflakeType(col.DataType)))
+	}
+
+	err := l.client.execute(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", snowflakeIdentifier(exportTable.name), strings.Join(definitions, ", ")))
+	if err != nil || !l.opts.Snowpipe {
+		return err
+	}
+
+	return l.client.execute(ctx, fmt.Sprintf("CREATE PIPE IF NOT EXISTS %s AS COPY INTO %s FROM %s %s",
+		snowflakePipe(exportTable.name), snowflakeIdentifier(exportTable.name), l.stagePath(exportTable.name), snowflakeFileFormat))
+}
+
+// Lookup tables are append only, so they are loaded incrementally by ID
+func (l *snowflakeLoader) loadLookupTable(ctx context.Context, exportTable table, columns []tableColumn, watermarkChainID string, lastID int64) error {
+	var maxID int64
+	if err := l.db.Raw(fmt.Sprintf("SELECT COALESCE(MAX(id), 0) FROM %s", quoteIdentifier(l.tables.Name(exportTable.name)))).Scan(&maxID).Error; err != nil {
+		return err
+	}
+	if maxID <= lastID {
+		return nil
+	}
+
+	condition := fmt.Sprintf("id > %d AND id <= %d", lastID, maxID)
+	if exportTable.filter != nil {
+		condition = fmt.Sprintf("%s AND %s", condition, exportTable.filter(scope{chainID: l.chainID}))
+	}
+
+	fileName := fmt.Sprintf("id_%d_%d", lastID+1, maxID)
+	if watermarkChainID != "" {
+		fileName = watermarkChainID + "_" + fileName
+	}
+
+	return l.load(ctx, exportTable, columns, condition, fileName, watermarkChainID, maxID)
+}
+
+func (l *snowflakeLoader) loadHeights(ctx context.Context, exportTable table, columns []tableColumn, lastHeight int64, endHeight int64) error {
+	startHeight := lastHeight + 1
+	if startHeight < l.opts.StartHeight {
+		startHeight = l.opts.StartHeight
+	}
+
+	for batchStart := startHeight; batchStart <= endHeight; batchStart += l.opts.BatchHeights {
+		batchEnd := batchStart + l.opts.BatchHeights - 1
+		if batchEnd > endHeight {
+			batchEnd = endHeight
+		}
+
+		batchScope := scope{chainID: l.chainID, startHeight: batchStart, endHeight: batchEnd}
+		err := l.load(ctx, exportTable, columns, exportTable.filter(batchScope), fmt.Sprintf("%s_%d_%d", l.opts.ChainID, batchStart, batchEnd), l.opts.ChainID, batchEnd)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// load exports the rows matching the condition to a staged file, loads it and moves the watermark of the table
+func (l *snowflakeLoader) load(ctx context.Context, exportTable table, columns []tableColumn, condition string, fileName string, watermarkChainID string, watermark int64) error {
+	file, err := os.CreateTemp("", "cosmos-indexer-snowflake")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	compressor := gzip.NewWriter(file)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", snowflakeSelects(columns, l.opts.Anonymizer), quoteIdentifier(l.tables.Name(exportTable.name)),
+		l.tables.Expand(condition))
+	rows, err := copyColumnsToCSV(ctx, l.db, query, columns, l.opts.Anonymizer, compressor)
+	if err != nil {
+		return err
+	}
+	if err := compressor.Close(); err != nil {
+		return err
+	}
+
+	// Ranges without rows only move the watermark
+	if rows != 0 {
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		stagedFile := fileName + ".csv.gz"
+		if err := l.opts.Stage.Upload(ctx, exportTable.name+"/"+stagedFile, file); err != nil {
+			return fmt.Errorf("failed to upload %s to the stage: %w", stagedFile, err)
+		}
+
+		if l.opts.Snowpipe {
+			err = l.client.insertFiles(ctx, strings.Trim(snowflakePipe(exportTable.name), `"`), []string{exportTable.name + "/" + stagedFile})
+		} else {
+			err = l.client.execute(ctx, fmt.Sprintf("COPY INTO %s FROM %s FILES = (%s) %s",
+				snowflakeIdentifier(exportTable.name), l.stagePath(exportTable.name), quoteLiteral(stagedFile), snowflakeFileFormat))
+		}
+		if err != nil {
+			return err
+		}
+
+		config.Log.Infof("Loaded %d rows of table %s into Snowflake from %s", rows, exportTable.name, stagedFile)
+	}
+
+	return dbTypes.UpsertSinkCheckpoint(l.db, watermarkChainID, snowflakeWatermarks, exportTable.name, watermark)
+}
+
+// snowflakeSelects selects the columns in a format Snowflake parses, binary columns are base64 encoded and timestamps
+// are written as ISO 8601 since Snowflake does not recognize the hour only offsets of Postgres. The compressed values
+// are selected last.
+func snowflakeSelects(columns []tableColumn, anonymizer *Anonymizer) string {
+	selects := make([]string, 0, len(columns))
+	for _, col := range columns {
+		name := quoteIdentifier(col.ColumnName)
+		switch col.DataType {
+		case "bytea":
+			// encode wraps base64 lines at 76 characters
+			selects = append(selects, fmt.Sprintf("translate(encode(%s, 'base64'), E'\\n', '') AS %s", anonymizer.column(col), name))
+		case "timestamp with time zone":
+			selects = append(selects, fmt.Sprintf(`to_char(%s AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"') AS %s`, name, name))
+		case "timestamp without time zone":
+			selects = append(selects, fmt.Sprintf(`to_char(%s, 'YYYY-MM-DD"T"HH24:MI:SS.US') AS %s`, name, name))
+		default:
+			selects = append(selects, selectAs(anonymizer.column(col), name))
+		}
+	}
+	if compressed := compressedSelect(columns); compressed != "" {
+		selects = append(selects, compressed)
+	}
+
+	return strings.Join(selects, ", ")
+}
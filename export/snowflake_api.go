@@ -0,0 +1,249 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+type SnowflakeConfig struct {
+	// Account is the account identifier, e.g. myorg-myaccount
+	Account string
+	User    string
+	// PrivateKeyFile is the unencrypted PEM RSA key registered as the RSA_PUBLIC_KEY of the user
+	PrivateKeyFile string
+	Role           string
+	Warehouse      string
+	Database       string
+	Schema         string
+	// Endpoint overrides the account URL, https://<account>.snowflakecomputing.com
+	Endpoint string
+}
+
+// snowflakeClient runs statements with the Snowflake SQL API and queues files with the Snowpipe REST API, both
+// authenticated with key pair JWTs
+type snowflakeClient struct {
+	conf        SnowflakeConfig
+	endpoint    string
+	key         *rsa.PrivateKey
+	fingerprint string
+	client      *http.Client
+	token       string
+	tokenExpiry time.Time
+}
+
+// Tokens are valid for at most an hour
+const snowflakeTokenLifetime = 59 * time.Minute
+
+func newSnowflakeClient(conf SnowflakeConfig) (*snowflakeClient, error) {
+	data, err := os.ReadFile(conf.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := parseSnowflakeKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Snowflake private key %s: %w", conf.PrivateKeyFile, err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256(publicKey)
+
+	client := &snowflakeClient{
+		conf:        conf,
+		endpoint:    strings.TrimSuffix(conf.Endpoint, "/"),
+		key:         key,
+		fingerprint: "SHA256:" + base64.StdEncoding.EncodeToString(digest[:]),
+		client:      &http.Client{Timeout: 2 * time.Minute},
+	}
+	if client.endpoint == "" {
+		client.endpoint = fmt.Sprintf("https://%s.snowflakecomputing.com", strings.ToLower(conf.Account))
+	}
+
+	return client, nil
+}
+
+func parseSnowflakeKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("only RSA keys are supported")
+	}
+
+	return rsaKey, nil
+}
+
+// jwt returns a key pair token, which names the account without its region or cloud suffix
+func (c *snowflakeClient) jwt() (string, error) {
+	if c.token != "" && time.Now().Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+
+	account := strings.ToUpper(strings.Split(c.conf.Account, ".")[0])
+	subject := account + "." + strings.ToUpper(c.conf.User)
+	now := time.Now()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iss": subject + "." + c.fingerprint,
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(snowflakeTokenLifetime).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	c.token = unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+	// Refresh a minute early so requests in flight do not carry an expired token
+	c.tokenExpiry = now.Add(snowflakeTokenLifetime - time.Minute)
+
+	return c.token, nil
+}
+
+func (c *snowflakeClient) do(ctx context.Context, method string, path string, body any) (int, []byte, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, c.endpoint+path, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	token, err := c.jwt()
+	if err != nil {
+		return 0, nil, err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("X-Snowflake-Authorization-Token-Type", "KEYPAIR_JWT")
+	request.Header.Set("Accept", "application/json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	return response.StatusCode, responseBody, err
+}
+
+type snowflakeStatementResponse struct {
+	Code               string `json:"code"`
+	Message            string `json:"message"`
+	StatementHandle    string `json:"statementHandle"`
+	StatementStatusURL string `json:"statementStatusUrl"`
+}
+
+// execute runs a single statement and waits for it to finish
+func (c *snowflakeClient) execute(ctx context.Context, statement string) error {
+	request := map[string]any{
+		"statement": statement,
+		"timeout":   3600,
+		"warehouse": c.conf.Warehouse,
+		"database":  c.conf.Database,
+		"schema":    c.conf.Schema,
+		"role":      c.conf.Role,
+	}
+
+	status, body, err := c.do(ctx, http.MethodPost, "/api/v2/statements", request)
+
+	// Statements running for more than 45 seconds continue asynchronously and are polled
+	for err == nil && status == http.StatusAccepted {
+		var response snowflakeStatementResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+
+		status, body, err = c.do(ctx, http.MethodGet, response.StatementStatusURL, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusOK {
+		var response snowflakeStatementResponse
+		if json.Unmarshal(body, &response) == nil && response.Message != "" {
+			return fmt.Errorf("snowflake statement failed with %d (%s): %s", status, response.Code, response.Message)
+		}
+		return fmt.Errorf("snowflake statement failed with %d: %s", status, string(body))
+	}
+
+	return nil
+}
+
+// insertFiles queues staged files for loading by a Snowpipe, paths are relative to the stage of the pipe
+func (c *snowflakeClient) insertFiles(ctx context.Context, pipe string, paths []string) error {
+	files := make([]map[string]string, 0, len(paths))
+	for _, path := range paths {
+		files = append(files, map[string]string{"path": path})
+	}
+
+	pipeName := strings.Join([]string{c.conf.Database, c.conf.Schema, pipe}, ".")
+	status, body, err := c.do(ctx, http.MethodPost, fmt.Sprintf("/v1/data/pipes/%s/insertFiles", url.PathEscape(pipeName)), map[string]any{"files": files})
+	if err != nil {
+		return err
+	}
+
+	if status != http.StatusOK {
+		return fmt.Errorf("snowpipe %s rejected the files with %d: %s", pipeName, status, string(body))
+	}
+
+	return nil
+}
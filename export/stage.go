@@ -0,0 +1,50 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3Stage uploads files to the S3 location of an external stage, e.g. one created with
+// CREATE STAGE ... URL = 's3://<bucket>/<prefix>/' STORAGE_INTEGRATION = ...
+type S3Stage struct {
+	bucket   string
+	prefix   string
+	uploader *s3manager.Uploader
+}
+
+// NewS3Stage uses the credentials of the shared AWS config, the endpoint is only needed for S3 compatible storage
+func NewS3Stage(region string, endpoint string, bucket string, prefix string) (*S3Stage, error) {
+	awsConfig := aws.NewConfig()
+	if region != "" {
+		awsConfig = awsConfig.WithRegion(region)
+	}
+	if endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsConfig,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return &S3Stage{bucket: bucket, prefix: prefix, uploader: s3manager.NewUploader(sess)}, nil
+}
+
+func (s *S3Stage) Upload(ctx context.Context, filePath string, file io.Reader) error {
+	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(path.Join(s.prefix, filePath)),
+		Body:   file,
+	})
+	return err
+}
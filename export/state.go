@@ -0,0 +1,164 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+type StateOptions struct {
+	ChainID string
+	Height  int64
+	// Output is the path of the JSON file to create
+	Output    string
+	Overwrite bool
+}
+
+// stateSnapshot is laid out like the app state of a genesis file, so tools reading genesis files read most of it
+type stateSnapshot struct {
+	ChainID    string    `json:"chain_id"`
+	Height     int64     `json:"height"`
+	Time       time.Time `json:"time"`
+	ExportedAt time.Time `json:"exported_at"`
+	AppState   struct {
+		Bank struct {
+			Balances []snapshotBalance `json:"balances"`
+		} `json:"bank"`
+		Staking struct {
+			Delegations []snapshotDelegation `json:"delegations"`
+		} `json:"staking"`
+	} `json:"app_state"`
+}
+
+type snapshotCoin struct {
+	Denom  string          `json:"denom"`
+	Amount decimal.Decimal `json:"amount"`
+}
+
+type snapshotBalance struct {
+	Address string         `json:"address"`
+	Coins   []snapshotCoin `json:"coins"`
+}
+
+type snapshotDelegation struct {
+	DelegatorAddress string          `json:"delegator_address"`
+	ValidatorAddress string          `json:"validator_address"`
+	Amount           decimal.Decimal `json:"amount"`
+}
+
+// State exports the balances and delegations of a chain at a height, as reconstructed from the indexed flows, to a
+// JSON file shaped like a genesis file, e.g. for audits and airdrop snapshots. The snapshot is only complete when the
+// genesis state was imported and every block up to the height was indexed, gaps are logged as warnings.
+func State(db *gorm.DB, opts StateOptions) error {
+	if _, statErr := os.Stat(opts.Output); statErr == nil && !opts.Overwrite {
+		return fmt.Errorf("%s already exists", opts.Output)
+	}
+
+	chain, err := findChain(db, opts.ChainID)
+	if err != nil {
+		return err
+	}
+
+	var block models.Block
+	if err := db.Where("chain_id = ? AND height = ? AND tx_indexed = true", chain.ID, opts.Height).First(&block).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("block %d of %s has not been indexed", opts.Height, opts.ChainID)
+		}
+		return err
+	}
+
+	if err := warnIncompleteState(db, chain, opts.Height); err != nil {
+		return err
+	}
+
+	balances, err := dbTypes.SnapshotBalances(db, chain.ID, opts.Height)
+	if err != nil {
+		return fmt.Errorf("failed to reconstruct the balances: %w", err)
+	}
+	delegations, err := dbTypes.SnapshotDelegations(db, chain.ID, opts.Height)
+	if err != nil {
+		return fmt.Errorf("failed to reconstruct the delegations: %w", err)
+	}
+
+	snapshot := stateSnapshot{ChainID: opts.ChainID, Height: opts.Height, Time: block.TimeStamp.UTC(), ExportedAt: time.Now().UTC()}
+	snapshot.AppState.Bank.Balances = []snapshotBalance{}
+	for _, balance := range balances {
+		coin := snapshotCoin{Denom: balance.Denom, Amount: balance.Amount}
+		// The balances are ordered by address
+		if last := len(snapshot.AppState.Bank.Balances) - 1; last >= 0 && snapshot.AppState.Bank.Balances[last].Address == balance.Address {
+			snapshot.AppState.Bank.Balances[last].Coins = append(snapshot.AppState.Bank.Balances[last].Coins, coin)
+			continue
+		}
+		snapshot.AppState.Bank.Balances = append(snapshot.AppState.Bank.Balances, snapshotBalance{Address: balance.Address, Coins: []snapshotCoin{coin}})
+	}
+	snapshot.AppState.Staking.Delegations = make([]snapshotDelegation, 0, len(delegations))
+	for _, delegation := range delegations {
+		snapshot.AppState.Staking.Delegations = append(snapshot.AppState.Staking.Delegations, snapshotDelegation{
+			DelegatorAddress: delegation.Delegator,
+			ValidatorAddress: delegation.Validator,
+			Amount:           delegation.Amount,
+		})
+	}
+
+	return writeJSONFile(opts.Output, snapshot)
+}
+
+// warnIncompleteState logs what the snapshot of the height is missing: a genesis state and indexed blocks
+func warnIncompleteState(db *gorm.DB, chain models.Chain, height int64) error {
+	initialHeight := int64(1)
+	var genesis models.GenesisState
+	err := db.Where("chain_id = ?", chain.ID).First(&genesis).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		config.Log.Warnf("No genesis state was imported for %s, the balances only cover the indexed flows", chain.ChainID)
+	case err != nil:
+		return err
+	default:
+		initialHeight = genesis.InitialHeight
+	}
+
+	var indexed int64
+	if err := db.Model(&models.Block{}).Where("chain_id = ? AND height BETWEEN ? AND ? AND tx_indexed = true", chain.ID, initialHeight, height).Count(&indexed).Error; err != nil {
+		return err
+	}
+	if missing := height - initialHeight + 1 - indexed; missing > 0 {
+		config.Log.Warnf("%d blocks between %d and %d of %s have not been indexed, the snapshot misses their flows", missing, initialHeight, height, chain.ChainID)
+	}
+	return nil
+}
+
+// writeJSONFile writes the value to a file that replaces the path once complete, so a failed export leaves no partial
+// file behind
+func writeJSONFile(path string, value any) error {
+	file, err := os.CreateTemp(filepath.Dir(path), ".cosmos-indexer-state-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		file.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,114 @@
+package export
+
+import "fmt"
+
+// table is an indexer table included in exports. Lookup tables are not scoped by height, the ones without a filter are
+// exported whole. Tables are exported under their default name, the filters refer to other tables with {table}
+// placeholders, see models.Tables.
+type table struct {
+	name   string
+	filter func(scope) string
+	lookup bool
+}
+
+// scope is the chain and inclusive height range of an export
+type scope struct {
+	chainID     uint
+	startHeight int64
+	endHeight   int64
+}
+
+func (s scope) blockIDs() string {
+	return fmt.Sprintf("SELECT id FROM {blocks} WHERE chain_id = %d AND height BETWEEN %d AND %d", s.chainID, s.startHeight, s.endHeight)
+}
+
+func (s scope) txIDs() string {
+	return fmt.Sprintf("SELECT id FROM {txes} WHERE block_id IN (%s)", s.blockIDs())
+}
+
+func (s scope) messageIDs() string {
+	return fmt.Sprintf("SELECT id FROM {messages} WHERE tx_id IN (%s)", s.txIDs())
+}
+
+func (s scope) blockEventIDs() string {
+	return fmt.Sprintf("SELECT id FROM {block_events} WHERE block_id IN (%s)", s.blockIDs())
+}
+
+func (s scope) failedBlocks() string {
+	return fmt.Sprintf("blockchain_id = %d AND height BETWEEN %d AND %d", s.chainID, s.startHeight, s.endHeight)
+}
+
+// tables lists every table of the indexer schema in dependency order
+var tables = []table{
+	{name: "chains", filter: func(s scope) string { return fmt.Sprintf("id = %d", s.chainID) }, lookup: true},
+	{name: "addresses", lookup: true},
+	{name: "denoms", lookup: true},
+	{name: "message_types", lookup: true},
+	{name: "message_event_types", lookup: true},
+	{name: "message_event_attribute_keys", lookup: true},
+	{name: "block_event_types", lookup: true},
+	{name: "block_event_attribute_keys", lookup: true},
+	{name: "block_event_parsers", lookup: true},
+	{name: "message_parsers", lookup: true},
+	{name: "blocks", filter: func(s scope) string { return fmt.Sprintf("id IN (%s)", s.blockIDs()) }},
+	{name: "failed_blocks", filter: scope.failedBlocks},
+	{name: "failed_event_blocks", filter: scope.failedBlocks},
+	{name: "txes", filter: func(s scope) string { return fmt.Sprintf("id IN (%s)", s.txIDs()) }},
+	{name: "failed_txes", filter: func(s scope) string { return fmt.Sprintf("block_id IN (%s)", s.blockIDs()) }},
+	{name: "tx_signer_addresses", filter: func(s scope) string { return fmt.Sprintf("tx_id IN (%s)", s.txIDs()) }},
+	{name: "fees", filter: func(s scope) string { return fmt.Sprintf("tx_id IN (%s)", s.txIDs()) }},
+	{name: "messages", filter: func(s scope) string { return fmt.Sprintf("id IN (%s)", s.messageIDs()) }},
+	{name: "failed_messages", filter: func(s scope) string { return fmt.Sprintf("tx_id IN (%s)", s.txIDs()) }},
+	{name: "message_events", filter: func(s scope) string { return fmt.Sprintf("message_id IN (%s)", s.messageIDs()) }},
+	{name: "message_event_attributes", filter: func(s scope) string {
+		return fmt.Sprintf("message_event_id IN (SELECT id FROM {message_events} WHERE message_id IN (%s))", s.messageIDs())
+	}},
+	{name: "message_parser_errors", filter: func(s scope) string { return fmt.Sprintf("message_id IN (%s)", s.messageIDs()) }},
+	{name: "block_events", filter: func(s scope) string { return fmt.Sprintf("id IN (%s)", s.blockEventIDs()) }},
+	{name: "block_event_attributes", filter: func(s scope) string { return fmt.Sprintf("block_event_id IN (%s)", s.blockEventIDs()) }},
+	{name: "block_event_parser_errors", filter: func(s scope) string { return fmt.Sprintf("block_event_id IN (%s)", s.blockEventIDs()) }},
+}
+
+// snowflakeType maps a Postgres column type to the Snowflake column type it is loaded as
+func snowflakeType(dataType string) string {
+	switch dataType {
+	case "smallint", "integer", "bigint":
+		return "NUMBER(19,0)"
+	case "boolean":
+		return "BOOLEAN"
+	case "double precision", "real":
+		return "FLOAT"
+	case "timestamp with time zone":
+		return "TIMESTAMP_TZ"
+	case "timestamp without time zone":
+		return "TIMESTAMP_NTZ"
+	case "bytea":
+		return "BINARY"
+	default:
+		// numeric(78,0) amounts exceed the 38 digits of Snowflake numbers
+		return "VARCHAR"
+	}
+}
+
+// duckDBType maps a Postgres column type to the DuckDB column type it is exported as
+func duckDBType(dataType string) string {
+	switch dataType {
+	case "smallint", "integer":
+		return "INTEGER"
+	case "bigint":
+		return "BIGINT"
+	case "boolean":
+		return "BOOLEAN"
+	case "double precision", "real":
+		return "DOUBLE"
+	case "timestamp with time zone":
+		return "TIMESTAMPTZ"
+	case "timestamp without time zone":
+		return "TIMESTAMP"
+	case "bytea":
+		return "BLOB"
+	default:
+		// numeric(78,0) amounts exceed the DuckDB decimal precision, so they are kept as text, as is JSON
+		return "VARCHAR"
+	}
+}
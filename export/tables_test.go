@@ -0,0 +1,189 @@
+package export
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm/schema"
+)
+
+type ExportTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ExportTestSuite) TestTableFilters() {
+	exportScope := scope{chainID: 2, startHeight: 10, endHeight: 20}
+
+	names := make(map[string]struct{})
+	for _, exportTable := range tables {
+		names[exportTable.name] = struct{}{}
+		if exportTable.filter != nil {
+			suite.Require().NotEmpty(exportTable.filter(exportScope), exportTable.name)
+		}
+	}
+	suite.Require().Len(names, len(tables))
+
+	suite.Require().Equal("SELECT id FROM {blocks} WHERE chain_id = 2 AND height BETWEEN 10 AND 20", exportScope.blockIDs())
+
+	// The filters read the tables under the naming of the database
+	indexerTables := models.NewTables(schema.NamingStrategy{TablePrefix: "idx_"})
+	suite.Require().Equal(`SELECT id FROM "idx_txes" WHERE block_id IN (SELECT id FROM "idx_blocks" WHERE chain_id = 2 AND height BETWEEN 10 AND 20)`,
+		indexerTables.Expand(exportScope.txIDs()))
+	for _, exportTable := range tables {
+		suite.Require().NotEqual(exportTable.name, indexerTables.Name(exportTable.name))
+		if exportTable.filter != nil {
+			suite.Require().NotContains(indexerTables.Expand(exportTable.filter(exportScope)), "{", exportTable.name)
+		}
+	}
+	suite.Require().Equal("blockchain_id = 2 AND height BETWEEN 10 AND 20", exportScope.failedBlocks())
+}
+
+func (suite *ExportTestSuite) TestDuckDBTypes() {
+	suite.Require().Equal("BIGINT", duckDBType("bigint"))
+	suite.Require().Equal("TIMESTAMPTZ", duckDBType("timestamp with time zone"))
+	suite.Require().Equal("BLOB", duckDBType("bytea"))
+	suite.Require().Equal("VARCHAR", duckDBType("numeric"))
+	suite.Require().Equal(`"a""b"`, quoteIdentifier(`a"b`))
+	suite.Require().Equal(`'it''s'`, quoteLiteral("it's"))
+}
+
+func (suite *ExportTestSuite) TestSnowflakeTypes() {
+	suite.Require().Equal("NUMBER(19,0)", snowflakeType("integer"))
+	suite.Require().Equal("TIMESTAMP_TZ", snowflakeType("timestamp with time zone"))
+	suite.Require().Equal("BINARY", snowflakeType("bytea"))
+	suite.Require().Equal("VARCHAR", snowflakeType("numeric"))
+	suite.Require().Equal(`"TXES"`, snowflakeIdentifier("txes"))
+	suite.Require().Contains(snowflakeSelects([]tableColumn{{ColumnName: "hash", DataType: "bytea"}}, nil), "encode")
+}
+
+func (suite *ExportTestSuite) TestAnonymizer() {
+	key := []byte("research-key")
+	anonymizer := NewAnonymizer(key)
+
+	// The pseudonym expression computes HMAC-SHA256 from the pads
+	inner := sha256.Sum256(append(append([]byte{}, anonymizer.innerPad...), "cosmos1abc"...))
+	outer := sha256.Sum256(append(append([]byte{}, anonymizer.outerPad...), inner[:]...))
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("cosmos1abc"))
+	suite.Require().Equal(mac.Sum(nil), outer[:])
+
+	address := anonymizer.column(tableColumn{ColumnName: "address", DataType: "text"})
+	suite.Require().Contains(address, hex.EncodeToString(anonymizer.outerPad))
+	suite.Require().Contains(address, "THEN NULL")
+	suite.Require().Equal("NULL::bytea", anonymizer.column(tableColumn{ColumnName: "raw", DataType: "bytea"}))
+	suite.Require().Equal("NULL::text", anonymizer.column(tableColumn{ColumnName: "memo", DataType: "text"}))
+	suite.Require().Equal(`"height"`, anonymizer.column(tableColumn{ColumnName: "height", DataType: "bigint"}))
+
+	var none *Anonymizer
+	suite.Require().Equal(`"address"`, none.column(tableColumn{ColumnName: "address", DataType: "text"}))
+	suite.Require().Equal(`"address"`, snowflakeSelects([]tableColumn{{ColumnName: "address", DataType: "text"}}, nil))
+
+	// Decompressed values are anonymized in Go as the queries anonymize the text columns
+	pseudonym, ok := anonymizer.text("cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu")
+	suite.Require().True(ok)
+	mac.Reset()
+	mac.Write([]byte("cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu"))
+	suite.Require().Equal(hex.EncodeToString(mac.Sum(nil)), pseudonym)
+	_, ok = anonymizer.text("sent to cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu")
+	suite.Require().False(ok)
+	text, ok := none.text("sent to cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu")
+	suite.Require().True(ok)
+	suite.Require().Equal("sent to cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu", text)
+
+	pattern := regexp.MustCompile("^" + bech32Address + "$")
+	suite.Require().True(pattern.MatchString("cosmos1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu"))
+	suite.Require().True(pattern.MatchString("osmovaloper1qypqxpq9qcrsszg2pvxq6rs0zqg3yyc5lzv7xu"))
+	suite.Require().False(pattern.MatchString("1000000uatom"))
+	suite.Require().False(pattern.MatchString("ibc/27394FB092D2ECCD56123C74F36E4C1F926001CEADA9CA97EA622B25F41E5EB2"))
+}
+
+func (suite *ExportTestSuite) TestDecompressValues() {
+	columns := markCompressed([]tableColumn{{ColumnName: "id"}, {ColumnName: "value"}, {ColumnName: "compressed_value"}, {ColumnName: "index"}})
+	suite.Require().Equal([]tableColumn{{ColumnName: "id"}, {ColumnName: "value", Compressed: true}, {ColumnName: "index"}}, columns)
+	suite.Require().Contains(snowflakeSelects(columns, nil), "AS compressed_value")
+	suite.Require().Equal([]tableColumn{{ColumnName: "id"}}, markCompressed([]tableColumn{{ColumnName: "id"}}))
+
+	models.SetCompression(64)
+	defer models.SetCompression(0)
+	large := strings.Repeat(`{"action":"claim, all"}`, 10)
+	_, compressed := models.AttributeValueColumns(large)
+	suite.Require().NotNil(compressed)
+
+	// The NULL and empty values and the quoted fields of the other rows are kept as they are
+	input := "id,value,index,compressed_value\n" +
+		"1,,0," + base64.StdEncoding.EncodeToString(compressed.([]byte)) + "\n" +
+		"2,\"\",1,\n" +
+		"3,\"a \"\"b\"\",\nc\",2,\n" +
+		"4,,3,\n"
+	var output strings.Builder
+	suite.Require().NoError(decompressValues(strings.NewReader(input), &output, 1, nil))
+	suite.Require().Equal("id,value,index\n"+
+		"1,\""+strings.ReplaceAll(large, `"`, `""`)+"\",0\n"+
+		"2,\"\",1\n"+
+		"3,\"a \"\"b\"\",\nc\",2\n"+
+		"4,,3\n", output.String())
+
+	err := decompressValues(strings.NewReader("id,value,index,compressed_value\n1,\"unterminated"), io.Discard, 1, nil)
+	suite.Require().ErrorIs(err, io.ErrUnexpectedEOF)
+}
+
+func (suite *ExportTestSuite) TestSnowflakeStatements() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	suite.Require().NoError(err)
+	keyFile := filepath.Join(suite.T().TempDir(), "key.pem")
+	suite.Require().NoError(os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}), 0o600))
+
+	var statements []string
+	var claims map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Require().Equal("KEYPAIR_JWT", r.Header.Get("X-Snowflake-Authorization-Token-Type"))
+		token := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), ".")
+		suite.Require().Len(token, 3)
+		payload, err := base64.RawURLEncoding.DecodeString(token[1])
+		suite.Require().NoError(err)
+		suite.Require().NoError(json.Unmarshal(payload, &claims))
+
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"statementHandle":"1"}`))
+			return
+		}
+
+		var request map[string]any
+		suite.Require().NoError(json.NewDecoder(r.Body).Decode(&request))
+		statements = append(statements, request["statement"].(string))
+		// Long running statements are polled
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"statementHandle":"1","statementStatusUrl":"/api/v2/statements/1"}`))
+	}))
+	defer server.Close()
+
+	client, err := newSnowflakeClient(SnowflakeConfig{Account: "myorg-account.us-east-1", User: "loader", PrivateKeyFile: keyFile, Endpoint: server.URL})
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(client.execute(context.Background(), "SELECT 1"))
+	suite.Require().Equal([]string{"SELECT 1"}, statements)
+	suite.Require().Equal("MYORG-ACCOUNT.LOADER", claims["sub"])
+	suite.Require().True(strings.HasPrefix(claims["iss"].(string), "MYORG-ACCOUNT.LOADER.SHA256:"))
+}
+
+func TestExportTestSuite(t *testing.T) {
+	suite.Run(t, new(ExportTestSuite))
+}
@@ -0,0 +1,277 @@
+// Package genesis parses the exported genesis file of a chain into the genesis state the indexer imports: the initial
+// balances and supply, the validators, the gov params and the CosmWasm contracts.
+package genesis
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cometbft/cometbft/crypto/secp256k1"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/shopspring/decimal"
+)
+
+// Genesis is a parsed genesis file
+type Genesis struct {
+	ChainID string
+	State   dbTypes.GenesisDBWrapper
+}
+
+type coin struct {
+	Denom  string `json:"denom"`
+	Amount string `json:"amount"`
+}
+
+type pubKey struct {
+	Type string `json:"@type"`
+	Key  string `json:"key"`
+}
+
+type description struct {
+	Moniker string `json:"moniker"`
+}
+
+// file holds the parts of a genesis file of a Cosmos SDK chain since Stargate that are imported
+type file struct {
+	GenesisTime   time.Time       `json:"genesis_time"`
+	ChainID       string          `json:"chain_id"`
+	InitialHeight json.RawMessage `json:"initial_height"`
+	AppState      struct {
+		Bank struct {
+			Balances []struct {
+				Address string `json:"address"`
+				Coins   []coin `json:"coins"`
+			} `json:"balances"`
+			Supply []coin `json:"supply"`
+		} `json:"bank"`
+		Staking struct {
+			Validators []struct {
+				OperatorAddress string      `json:"operator_address"`
+				ConsensusPubkey pubKey      `json:"consensus_pubkey"`
+				Jailed          bool        `json:"jailed"`
+				Status          string      `json:"status"`
+				Tokens          string      `json:"tokens"`
+				DelegatorShares string      `json:"delegator_shares"`
+				Description     description `json:"description"`
+				Commission      struct {
+					CommissionRates struct {
+						Rate string `json:"rate"`
+					} `json:"commission_rates"`
+				} `json:"commission"`
+			} `json:"validators"`
+		} `json:"staking"`
+		Gov     map[string]json.RawMessage `json:"gov"`
+		Genutil struct {
+			GenTxs []struct {
+				Body struct {
+					Messages []json.RawMessage `json:"messages"`
+				} `json:"body"`
+			} `json:"gen_txs"`
+		} `json:"genutil"`
+		Wasm struct {
+			Contracts []struct {
+				ContractAddress string `json:"contract_address"`
+				ContractInfo    struct {
+					CodeID  json.Number `json:"code_id"`
+					Creator string      `json:"creator"`
+					Admin   string      `json:"admin"`
+					Label   string      `json:"label"`
+				} `json:"contract_info"`
+			} `json:"contracts"`
+		} `json:"wasm"`
+	} `json:"app_state"`
+}
+
+// createValidator is the message of a genesis transaction creating a validator
+type createValidator struct {
+	Type        string      `json:"@type"`
+	Description description `json:"description"`
+	Commission  struct {
+		Rate string `json:"rate"`
+	} `json:"commission"`
+	ValidatorAddress string `json:"validator_address"`
+	Pubkey           pubKey `json:"pubkey"`
+	Value            coin   `json:"value"`
+}
+
+// govParamKeys are the parameter sets of the gov module, params since v1 and the others before
+var govParamKeys = []string{"params", "deposit_params", "voting_params", "tally_params"}
+
+// Parse reads a genesis file. The parameter sets of gov are kept as JSON, the other modules are reduced to the rows of
+// the genesis tables.
+func Parse(r io.Reader) (*Genesis, error) {
+	var f file
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse the genesis file: %w", err)
+	}
+	if f.ChainID == "" {
+		return nil, errors.New("the genesis file has no chain_id")
+	}
+
+	genesis := &Genesis{ChainID: f.ChainID}
+	genesis.State.State = models.GenesisState{GenesisTime: f.GenesisTime, InitialHeight: 1}
+	if len(f.InitialHeight) > 0 {
+		// The initial height is a string since Stargate, a number before
+		height, err := strconv.ParseInt(strings.Trim(string(f.InitialHeight), `"`), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid initial_height %s: %w", f.InitialHeight, err)
+		}
+		genesis.State.State.InitialHeight = height
+	}
+
+	appState := &f.AppState
+	for _, balance := range appState.Bank.Balances {
+		for _, c := range balance.Coins {
+			amount, err := decimal.NewFromString(c.Amount)
+			if err != nil {
+				return nil, fmt.Errorf("invalid amount %q of %s in the balance of %s: %w", c.Amount, c.Denom, balance.Address, err)
+			}
+			genesis.State.Balances = append(genesis.State.Balances, models.GenesisBalance{
+				Address: models.Address{Address: balance.Address},
+				Denom:   models.Denom{Base: c.Denom},
+				Amount:  amount,
+			})
+		}
+	}
+	for _, c := range appState.Bank.Supply {
+		amount, err := decimal.NewFromString(c.Amount)
+		if err != nil {
+			return nil, fmt.Errorf("invalid supply %q of %s: %w", c.Amount, c.Denom, err)
+		}
+		genesis.State.Supply = append(genesis.State.Supply, models.GenesisSupply{Denom: models.Denom{Base: c.Denom}, Amount: amount})
+	}
+
+	for _, v := range appState.Staking.Validators {
+		validator := models.GenesisValidator{
+			OperatorAddress: v.OperatorAddress,
+			Moniker:         v.Description.Moniker,
+			Status:          v.Status,
+			Jailed:          v.Jailed,
+		}
+		var err error
+		if validator.Tokens, err = parseDecimal(v.Tokens); err != nil {
+			return nil, fmt.Errorf("invalid tokens of validator %s: %w", v.OperatorAddress, err)
+		}
+		if validator.DelegatorShares, err = parseDecimal(v.DelegatorShares); err != nil {
+			return nil, fmt.Errorf("invalid delegator shares of validator %s: %w", v.OperatorAddress, err)
+		}
+		if validator.CommissionRate, err = parseDecimal(v.Commission.CommissionRates.Rate); err != nil {
+			return nil, fmt.Errorf("invalid commission rate of validator %s: %w", v.OperatorAddress, err)
+		}
+		if validator.ConsAddress, err = consAddress(v.OperatorAddress, v.ConsensusPubkey); err != nil {
+			return nil, fmt.Errorf("invalid consensus key of validator %s: %w", v.OperatorAddress, err)
+		}
+		genesis.State.Validators = append(genesis.State.Validators, validator)
+	}
+
+	// A validator created by a genesis transaction is only in the staking state of an exported genesis
+	operators := make(map[string]struct{}, len(genesis.State.Validators))
+	for _, validator := range genesis.State.Validators {
+		operators[validator.OperatorAddress] = struct{}{}
+	}
+	for _, tx := range appState.Genutil.GenTxs {
+		for _, raw := range tx.Body.Messages {
+			var msg createValidator
+			if err := json.Unmarshal(raw, &msg); err != nil {
+				return nil, fmt.Errorf("failed to parse a genesis transaction: %w", err)
+			}
+			if !strings.HasSuffix(msg.Type, ".MsgCreateValidator") {
+				continue
+			}
+			if _, ok := operators[msg.ValidatorAddress]; ok {
+				continue
+			}
+			operators[msg.ValidatorAddress] = struct{}{}
+
+			validator := models.GenesisValidator{
+				OperatorAddress: msg.ValidatorAddress,
+				Moniker:         msg.Description.Moniker,
+				Gentx:           true,
+			}
+			var err error
+			if validator.Tokens, err = parseDecimal(msg.Value.Amount); err != nil {
+				return nil, fmt.Errorf("invalid self delegation of validator %s: %w", msg.ValidatorAddress, err)
+			}
+			validator.DelegatorShares = validator.Tokens
+			if validator.CommissionRate, err = parseDecimal(msg.Commission.Rate); err != nil {
+				return nil, fmt.Errorf("invalid commission rate of validator %s: %w", msg.ValidatorAddress, err)
+			}
+			if validator.ConsAddress, err = consAddress(msg.ValidatorAddress, msg.Pubkey); err != nil {
+				return nil, fmt.Errorf("invalid consensus key of validator %s: %w", msg.ValidatorAddress, err)
+			}
+			genesis.State.Validators = append(genesis.State.Validators, validator)
+		}
+	}
+
+	for _, key := range govParamKeys {
+		params, ok := appState.Gov[key]
+		if !ok || string(params) == "null" {
+			continue
+		}
+		genesis.State.Params = append(genesis.State.Params, models.GenesisParam{Module: "gov", Key: key, Value: string(params)})
+	}
+
+	for _, c := range appState.Wasm.Contracts {
+		codeID, err := strconv.ParseUint(c.ContractInfo.CodeID.String(), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid code id of contract %s: %w", c.ContractAddress, err)
+		}
+		genesis.State.Contracts = append(genesis.State.Contracts, models.GenesisContract{
+			Address: models.Address{Address: c.ContractAddress},
+			CodeID:  codeID,
+			Creator: c.ContractInfo.Creator,
+			Admin:   c.ContractInfo.Admin,
+			Label:   c.ContractInfo.Label,
+		})
+	}
+
+	return genesis, nil
+}
+
+func parseDecimal(value string) (decimal.Decimal, error) {
+	if value == "" {
+		return decimal.Zero, nil
+	}
+	return decimal.NewFromString(value)
+}
+
+// consAddress returns the consensus address of a validator key, with the valcons prefix of the chain derived from the
+// valoper prefix of the operator address
+func consAddress(operatorAddress string, key pubKey) (*models.Address, error) {
+	if key.Key == "" {
+		return nil, nil
+	}
+	keyBytes, err := base64.StdEncoding.DecodeString(key.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	var address []byte
+	switch key.Type {
+	case "/cosmos.crypto.ed25519.PubKey":
+		address = ed25519.PubKey(keyBytes).Address()
+	case "/cosmos.crypto.secp256k1.PubKey":
+		address = secp256k1.PubKey(keyBytes).Address()
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", key.Type)
+	}
+
+	prefix, _, err := bech32.DecodeAndConvert(operatorAddress)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := bech32.ConvertAndEncode(strings.TrimSuffix(prefix, "valoper")+"valcons", address)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Address{Address: encoded}, nil
+}
@@ -0,0 +1,91 @@
+package genesis
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cometbft/cometbft/crypto/ed25519"
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/suite"
+)
+
+type GenesisTestSuite struct {
+	suite.Suite
+}
+
+func (suite *GenesisTestSuite) TestParse() {
+	operator, err := bech32.ConvertAndEncode("cosmosvaloper", bytes.Repeat([]byte{1}, 20))
+	suite.Require().NoError(err)
+	gentxOperator, err := bech32.ConvertAndEncode("cosmosvaloper", bytes.Repeat([]byte{2}, 20))
+	suite.Require().NoError(err)
+	key := bytes.Repeat([]byte{3}, ed25519.PubKeySize)
+	consAddress, err := bech32.ConvertAndEncode("cosmosvalcons", ed25519.PubKey(key).Address())
+	suite.Require().NoError(err)
+	pubkey := fmt.Sprintf(`{"@type": "/cosmos.crypto.ed25519.PubKey", "key": "%s"}`, base64.StdEncoding.EncodeToString(key))
+
+	parsed, err := Parse(strings.NewReader(fmt.Sprintf(`{
+		"genesis_time": "2021-02-18T06:00:00Z",
+		"chain_id": "testchain-1",
+		"initial_height": "5200791",
+		"app_state": {
+			"bank": {
+				"balances": [{"address": "cosmos1a", "coins": [{"denom": "uatom", "amount": "1000"}, {"denom": "ustake", "amount": "5"}]}],
+				"supply": [{"denom": "uatom", "amount": "1000"}]
+			},
+			"staking": {
+				"validators": [{"operator_address": "%[1]s", "consensus_pubkey": %[3]s, "jailed": false, "status": "BOND_STATUS_BONDED",
+					"tokens": "500", "delegator_shares": "500.000000000000000000", "description": {"moniker": "genesis"},
+					"commission": {"commission_rates": {"rate": "0.100000000000000000"}}}]
+			},
+			"genutil": {
+				"gen_txs": [{"body": {"messages": [
+					{"@type": "/cosmos.staking.v1beta1.MsgCreateValidator", "description": {"moniker": "duplicate"}, "commission": {"rate": "0.1"},
+						"validator_address": "%[1]s", "pubkey": %[3]s, "value": {"denom": "uatom", "amount": "1"}},
+					{"@type": "/cosmos.staking.v1beta1.MsgCreateValidator", "description": {"moniker": "gentx"}, "commission": {"rate": "0.05"},
+						"validator_address": "%[2]s", "pubkey": %[3]s, "value": {"denom": "uatom", "amount": "250"}}
+				]}}]
+			},
+			"gov": {"params": {"min_deposit": [{"denom": "uatom", "amount": "10"}]}, "deposit_params": null},
+			"wasm": {"contracts": [{"contract_address": "cosmos1contract", "contract_info": {"code_id": "7", "creator": "cosmos1a", "label": "pool"}}]}
+		}
+	}`, operator, gentxOperator, pubkey)))
+	suite.Require().NoError(err)
+
+	suite.Require().Equal("testchain-1", parsed.ChainID)
+	suite.Require().Equal(int64(5200791), parsed.State.State.InitialHeight)
+	suite.Require().Equal(2021, parsed.State.State.GenesisTime.Year())
+
+	suite.Require().Len(parsed.State.Balances, 2)
+	suite.Require().Equal("cosmos1a", parsed.State.Balances[1].Address.Address)
+	suite.Require().Equal("ustake", parsed.State.Balances[1].Denom.Base)
+	suite.Require().True(decimal.NewFromInt(5).Equal(parsed.State.Balances[1].Amount))
+	suite.Require().Len(parsed.State.Supply, 1)
+
+	// The genesis transaction of a validator in the staking state is not imported twice
+	suite.Require().Len(parsed.State.Validators, 2)
+	suite.Require().Equal("genesis", parsed.State.Validators[0].Moniker)
+	suite.Require().Equal(consAddress, parsed.State.Validators[0].ConsAddress.Address)
+	suite.Require().False(parsed.State.Validators[0].Gentx)
+	suite.Require().Equal("gentx", parsed.State.Validators[1].Moniker)
+	suite.Require().True(parsed.State.Validators[1].Gentx)
+	suite.Require().True(decimal.NewFromInt(250).Equal(parsed.State.Validators[1].Tokens))
+
+	suite.Require().Len(parsed.State.Params, 1)
+	suite.Require().Equal("params", parsed.State.Params[0].Key)
+	suite.Require().JSONEq(`{"min_deposit": [{"denom": "uatom", "amount": "10"}]}`, parsed.State.Params[0].Value)
+
+	suite.Require().Len(parsed.State.Contracts, 1)
+	suite.Require().Equal(uint64(7), parsed.State.Contracts[0].CodeID)
+	suite.Require().Equal("pool", parsed.State.Contracts[0].Label)
+
+	_, err = Parse(strings.NewReader(`{"app_state": {}}`))
+	suite.Require().ErrorContains(err, "chain_id")
+}
+
+func TestGenesisTestSuite(t *testing.T) {
+	suite.Run(t, new(GenesisTestSuite))
+}
@@ -0,0 +1,397 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/DefiantLabs/cosmos-indexer/auth"
+	"github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/eventbus"
+	"github.com/DefiantLabs/cosmos-indexer/query"
+	"github.com/graph-gophers/graphql-go"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// resolver is the root resolver of queries and subscriptions, GraphQL Int is 32 bit so heights and indexes are
+// converted to int32
+type resolver struct {
+	client *query.Client
+	bus    *eventbus.Bus[db.CDCEvent]
+}
+
+// pageArgs are the arguments shared by the list queries
+type pageArgs struct {
+	ChainID     *string
+	StartHeight *int32
+	EndHeight   *int32
+	Limit       *int32
+	Cursor      *string
+	Descending  *bool
+}
+
+// page is the validated form of pageArgs
+type page struct {
+	chainID     string
+	startHeight int64
+	endHeight   int64
+	limit       int
+	cursor      string
+	descending  bool
+}
+
+func (args pageArgs) page() (page, error) {
+	p := page{chainID: deref(args.ChainID), cursor: deref(args.Cursor), descending: deref(args.Descending), limit: defaultLimit}
+
+	p.startHeight = int64(deref(args.StartHeight))
+	p.endHeight = int64(deref(args.EndHeight))
+	if p.startHeight < 0 || p.endHeight < 0 {
+		return p, errors.New("startHeight and endHeight must not be negative")
+	}
+	if p.endHeight > 0 && p.endHeight < p.startHeight {
+		return p, errors.New("endHeight must not be below startHeight")
+	}
+
+	if args.Limit != nil {
+		if *args.Limit < 1 || *args.Limit > maxLimit {
+			return p, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+		p.limit = int(*args.Limit)
+	}
+
+	return p, nil
+}
+
+func deref[T any](value *T) T {
+	var zero T
+	if value == nil {
+		return zero
+	}
+	return *value
+}
+
+// The query resolvers require the read scope, the subscription resolvers the stream scope
+
+func (r *resolver) Chains(ctx context.Context) ([]*chainResolver, error) {
+	if err := auth.Check(ctx, auth.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	chains, err := r.client.Chains(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return wrap(chains, func(chain query.Chain) *chainResolver { return &chainResolver{chain} }), nil
+}
+
+func (r *resolver) Blocks(ctx context.Context, args pageArgs) (*pageResolver[*blockResolver], error) {
+	if err := auth.Check(ctx, auth.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	p, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+
+	q := r.client.BlocksInRange(p.startHeight, p.endHeight).Chain(p.chainID).Limit(p.limit)
+	if p.descending {
+		q.Descending()
+	}
+	if p.cursor != "" {
+		q.After(p.cursor)
+	}
+
+	blocks, next, err := q.Page(ctx)
+	return newPage(blocks, next, newBlockResolver), err
+}
+
+func (r *resolver) Tx(ctx context.Context, args struct{ Hash string }) (*txResolver, error) {
+	if err := auth.Check(ctx, auth.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	tx, err := r.client.TxByHash(ctx, args.Hash)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	return &txResolver{*tx}, nil
+}
+
+func (r *resolver) Txs(ctx context.Context, args struct {
+	Address     *string
+	MessageType *string
+	pageArgs
+}) (*pageResolver[*txResolver], error) {
+	if err := auth.Check(ctx, auth.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	p, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+
+	q := r.client.TxsInRange(p.startHeight, p.endHeight)
+	if args.Address != nil {
+		q = r.client.TxsByAddress(*args.Address).Heights(p.startHeight, p.endHeight)
+	}
+	q.Chain(p.chainID).Limit(p.limit)
+	if args.MessageType != nil {
+		q.WithMessageType(*args.MessageType)
+	}
+	if p.descending {
+		q.Descending()
+	}
+	if p.cursor != "" {
+		q.After(p.cursor)
+	}
+
+	txs, next, err := q.Page(ctx)
+	return newPage(txs, next, newTxResolver), err
+}
+
+func (r *resolver) Messages(ctx context.Context, args struct {
+	Type    string
+	Address *string
+	pageArgs
+}) (*pageResolver[*messageResolver], error) {
+	if err := auth.Check(ctx, auth.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	p, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+
+	q := r.client.MessagesByType(args.Type).Chain(p.chainID).Heights(p.startHeight, p.endHeight).Limit(p.limit)
+	if args.Address != nil {
+		q.SignedBy(*args.Address)
+	}
+	if p.descending {
+		q.Descending()
+	}
+	if p.cursor != "" {
+		q.After(p.cursor)
+	}
+
+	messages, next, err := q.Page(ctx)
+	return newPage(messages, next, newMessageResolver), err
+}
+
+// attributeFilter is the AttributeFilter input
+type attributeFilter struct {
+	Key   string
+	Value *string
+}
+
+func (r *resolver) Events(ctx context.Context, args struct {
+	Type       string
+	Source     *string
+	Attributes *[]attributeFilter
+	pageArgs
+}) (*pageResolver[*eventResolver], error) {
+	if err := auth.Check(ctx, auth.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	p, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+
+	q := r.client.EventsByType(args.Type).Chain(p.chainID).Heights(p.startHeight, p.endHeight).Limit(p.limit)
+	switch deref(args.Source) {
+	case "":
+	case "block":
+		q.BlockEventsOnly()
+	case "message":
+		q.MessageEventsOnly()
+	default:
+		return nil, errors.New("source must be block or message")
+	}
+	for _, filter := range deref(args.Attributes) {
+		if filter.Value == nil {
+			q.WithAttributeKey(filter.Key)
+		} else {
+			q.WithAttribute(filter.Key, *filter.Value)
+		}
+	}
+	if p.descending {
+		q.Descending()
+	}
+	if p.cursor != "" {
+		q.After(p.cursor)
+	}
+
+	events, next, err := q.Page(ctx)
+	return newPage(events, next, newEventResolver), err
+}
+
+func (r *resolver) Datasets(ctx context.Context) ([]string, error) {
+	if err := auth.Check(ctx, auth.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	datasets := r.client.Datasets()
+	if datasets == nil {
+		return []string{}, nil
+	}
+	return datasets, nil
+}
+
+func (r *resolver) Dataset(ctx context.Context, args struct {
+	Name string
+	pageArgs
+}) (*pageResolver[*datasetRowResolver], error) {
+	if err := auth.Check(ctx, auth.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	p, err := args.page()
+	if err != nil {
+		return nil, err
+	}
+
+	q := r.client.Dataset(args.Name).Chain(p.chainID).Heights(p.startHeight, p.endHeight).Limit(p.limit)
+	if p.descending {
+		q.Descending()
+	}
+	if p.cursor != "" {
+		q.After(p.cursor)
+	}
+
+	rows, next, err := q.Page(ctx)
+	return newPage(rows, next, newDatasetRowResolver), err
+}
+
+func wrap[T any, R any](results []T, resolve func(T) R) []R {
+	resolvers := make([]R, len(results))
+	for i, result := range results {
+		resolvers[i] = resolve(result)
+	}
+	return resolvers
+}
+
+type pageResolver[R any] struct {
+	nodes      []R
+	nextCursor string
+}
+
+func newPage[T any, R any](results []T, next string, resolve func(T) R) *pageResolver[R] {
+	return &pageResolver[R]{nodes: wrap(results, resolve), nextCursor: next}
+}
+
+func (p *pageResolver[R]) Nodes() []R {
+	return p.nodes
+}
+
+func (p *pageResolver[R]) NextCursor() *string {
+	if p.nextCursor == "" {
+		return nil
+	}
+	return &p.nextCursor
+}
+
+type chainResolver struct{ chain query.Chain }
+
+func (c *chainResolver) ChainID() string     { return c.chain.ChainID }
+func (c *chainResolver) Name() string        { return c.chain.Name }
+func (c *chainResolver) LatestHeight() int32 { return int32(c.chain.LatestHeight) }
+
+type blockResolver struct{ block query.Block }
+
+func newBlockResolver(block query.Block) *blockResolver { return &blockResolver{block} }
+
+func (b *blockResolver) ChainID() string             { return b.block.ChainID }
+func (b *blockResolver) Height() int32               { return int32(b.block.Height) }
+func (b *blockResolver) Time() graphql.Time          { return graphql.Time{Time: b.block.Time} }
+func (b *blockResolver) ProposerConsAddress() string { return b.block.ProposerConsAddress }
+func (b *blockResolver) TxIndexed() bool             { return b.block.TxIndexed }
+func (b *blockResolver) BlockEventsIndexed() bool    { return b.block.BlockEventsIndexed }
+func (b *blockResolver) CustomParsersApplied() bool  { return b.block.CustomParsersApplied }
+func (b *blockResolver) Verified() bool              { return b.block.Verified }
+
+type txResolver struct{ tx query.Tx }
+
+func newTxResolver(tx query.Tx) *txResolver { return &txResolver{tx} }
+
+func (t *txResolver) ChainID() string    { return t.tx.ChainID }
+func (t *txResolver) Height() int32      { return int32(t.tx.Height) }
+func (t *txResolver) Time() graphql.Time { return graphql.Time{Time: t.tx.Time} }
+func (t *txResolver) Hash() string       { return t.tx.Hash }
+func (t *txResolver) Code() int32        { return int32(t.tx.Code) }
+
+func (t *txResolver) Signers() []string {
+	if t.tx.Signers == nil {
+		return []string{}
+	}
+	return t.tx.Signers
+}
+
+type messageResolver struct{ message query.Message }
+
+func newMessageResolver(message query.Message) *messageResolver { return &messageResolver{message} }
+
+func (m *messageResolver) ChainID() string    { return m.message.ChainID }
+func (m *messageResolver) Height() int32      { return int32(m.message.Height) }
+func (m *messageResolver) Time() graphql.Time { return graphql.Time{Time: m.message.Time} }
+func (m *messageResolver) TxHash() string     { return m.message.TxHash }
+func (m *messageResolver) Index() int32       { return int32(m.message.Index) }
+func (m *messageResolver) Type() string       { return m.message.Type }
+
+type datasetRowResolver struct{ row query.DatasetRow }
+
+func newDatasetRowResolver(row query.DatasetRow) *datasetRowResolver { return &datasetRowResolver{row} }
+
+func (d *datasetRowResolver) ID() graphql.ID { return graphql.ID(strconv.FormatInt(d.row.ID, 10)) }
+func (d *datasetRowResolver) Data() string   { return string(d.row.Data) }
+
+func (d *datasetRowResolver) ChainID() *string {
+	if d.row.ChainID == "" {
+		return nil
+	}
+	return &d.row.ChainID
+}
+
+func (d *datasetRowResolver) Height() *int32 {
+	if d.row.ChainID == "" {
+		return nil
+	}
+	height := int32(d.row.Height)
+	return &height
+}
+
+type eventResolver struct{ event query.Event }
+
+func newEventResolver(event query.Event) *eventResolver { return &eventResolver{event} }
+
+func (e *eventResolver) ChainID() string     { return e.event.ChainID }
+func (e *eventResolver) Height() int32       { return int32(e.event.Height) }
+func (e *eventResolver) Time() graphql.Time  { return graphql.Time{Time: e.event.Time} }
+func (e *eventResolver) Source() string      { return e.event.Source }
+func (e *eventResolver) MessageIndex() int32 { return int32(e.event.MessageIndex) }
+func (e *eventResolver) Index() int32        { return int32(e.event.Index) }
+func (e *eventResolver) Type() string        { return e.event.Type }
+
+func (e *eventResolver) TxHash() *string {
+	if e.event.TxHash == "" {
+		return nil
+	}
+	return &e.event.TxHash
+}
+
+func (e *eventResolver) Attributes() []*attributeResolver {
+	return wrap(e.event.Attributes, func(attribute query.Attribute) *attributeResolver { return &attributeResolver{attribute} })
+}
+
+type attributeResolver struct{ attribute query.Attribute }
+
+func (a *attributeResolver) Key() string   { return a.attribute.Key }
+func (a *attributeResolver) Value() string { return a.attribute.Value }
@@ -0,0 +1,125 @@
+// Package graphqlapi serves the indexed data over GraphQL. Queries are answered over HTTP POST, queries and
+// subscriptions over WebSocket with the graphql-transport-ws protocol.
+package graphqlapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/auth"
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/eventbus"
+	"github.com/DefiantLabs/cosmos-indexer/query"
+	"github.com/DefiantLabs/cosmos-indexer/ratelimit"
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// Server serves the GraphQL API at /graphql
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+}
+
+// NewServer creates a server listening on address, it starts serving once Serve is called. Subscriptions are fed by
+// the CDC events published on the bus, a nil bus disables them. A nil authenticator disables authentication
+// and a nil limiter rate limiting.
+func NewServer(address string, client *query.Client, events *eventbus.Bus[db.CDCEvent], authenticator *auth.Authenticator, limiter *ratelimit.Limiter) (*Server, error) {
+	handler, err := NewHandler(client, events, authenticator, limiter)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		server:   &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second},
+		listener: listener,
+	}, nil
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *Server) Serve() error {
+	err := s.server.Serve(s.listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting requests and waits up to 10 seconds for the running requests to finish. Hijacked
+// WebSocket connections are not tracked by the HTTP server, they end with their request contexts.
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		config.Log.Error("Error shutting down the GraphQL server", err)
+	}
+}
+
+type handler struct {
+	schema *graphql.Schema
+}
+
+// NewHandler parses the schema and returns the handler of /graphql. Every request must be authenticated, the
+// resolvers check the scopes of the operations.
+func NewHandler(client *query.Client, events *eventbus.Bus[db.CDCEvent], authenticator *auth.Authenticator, limiter *ratelimit.Limiter) (http.Handler, error) {
+	schema, err := graphql.ParseSchema(schemaSDL, &resolver{client: client, bus: events}, graphql.UseStringDescriptions())
+	if err != nil {
+		return nil, err
+	}
+	return authenticator.Middleware(limiter.Middleware("graphql", &handler{schema: schema})), nil
+}
+
+// request is a GraphQL request, sent as the body of POST requests and as the payload of subscribe messages
+type request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/graphql" {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	if auth.FromContext(r.Context()) == nil {
+		auth.Unauthorized(w, auth.ErrUnauthenticated.Error())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		h.serveWebSocket(w, r)
+	case http.MethodPost:
+		var req request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid GraphQL request", http.StatusBadRequest)
+			return
+		}
+
+		response := h.schema.Exec(r.Context(), req.Query, req.OperationName, req.Variables)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			config.Log.Error("Error writing GraphQL response", err)
+		}
+	default:
+		http.Error(w, "only GET WebSocket upgrades and POST requests are supported", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,86 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/eventbus"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/suite"
+)
+
+type GraphQLTestSuite struct {
+	suite.Suite
+}
+
+func (suite *GraphQLTestSuite) TestQueryValidation() {
+	handler, err := NewHandler(nil, nil, nil, nil)
+	suite.Require().NoError(err)
+
+	recorder := httptest.NewRecorder()
+	body := `{"query": "{ blocks(limit: 0) { nodes { height } } }"}`
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+	suite.Require().Equal(http.StatusOK, recorder.Code)
+
+	var response struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Require().Len(response.Errors, 1)
+	suite.Require().Equal("limit must be between 1 and 1000", response.Errors[0].Message)
+
+	recorder = httptest.NewRecorder()
+	body = `{"query": "{ events(type: \"transfer\", source: \"tx\") { nodes { height } } }"}`
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body)))
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Require().Len(response.Errors, 1)
+	suite.Require().Equal("source must be block or message", response.Errors[0].Message)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/graphql", nil))
+	suite.Require().Equal(http.StatusBadRequest, recorder.Code)
+}
+
+func (suite *GraphQLTestSuite) TestSubscription() {
+	bus := eventbus.New[db.CDCEvent]()
+	handler, err := NewHandler(nil, bus, nil, nil)
+	suite.Require().NoError(err)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	dialer := websocket.Dialer{Subprotocols: []string{protocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+	suite.Require().NoError(err)
+	defer conn.Close()
+
+	var msg message
+	suite.Require().NoError(conn.WriteJSON(message{Type: messageConnectionInit}))
+	suite.Require().NoError(conn.ReadJSON(&msg))
+	suite.Require().Equal(messageConnectionAck, msg.Type)
+
+	payload, err := json.Marshal(request{Query: `subscription { newBlocks(chainId: "cosmoshub-4") { height } }`})
+	suite.Require().NoError(err)
+	suite.Require().NoError(conn.WriteJSON(message{ID: "1", Type: messageSubscribe, Payload: payload}))
+
+	suite.Require().NoError(conn.WriteJSON(message{Type: messagePing}))
+	suite.Require().NoError(conn.ReadJSON(&msg))
+	suite.Require().Equal(messagePong, msg.Type)
+
+	// Events of other chains are skipped without querying, closing the bus ends the subscription
+	bus.Publish(db.CDCEvent{ChainID: "osmosis-1", Height: 1, Kind: db.CDCKindTxs})
+	bus.Close()
+
+	suite.Require().NoError(conn.ReadJSON(&msg))
+	suite.Require().Equal(message{ID: "1", Type: messageComplete}, msg)
+}
+
+func TestGraphQLTestSuite(t *testing.T) {
+	suite.Run(t, new(GraphQLTestSuite))
+}
@@ -0,0 +1,113 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DefiantLabs/cosmos-indexer/auth"
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db"
+)
+
+// Number of committed block datasets buffered per subscription before it is ended for falling behind
+const subscriptionBuffer = 256
+
+var errSubscriptionsDisabled = errors.New("subscriptions are not enabled")
+
+func (r *resolver) NewBlocks(ctx context.Context, args struct{ ChainID *string }) (<-chan *blockResolver, error) {
+	// A block is announced by both its transactions and its block events, only the first announcement is pushed
+	sent := map[string]int64{}
+
+	return subscribe(ctx, r, deref(args.ChainID), func(ctx context.Context, event db.CDCEvent) ([]*blockResolver, error) {
+		if sent[event.ChainID] >= event.Height {
+			return nil, nil
+		}
+		sent[event.ChainID] = event.Height
+
+		blocks, err := r.client.BlocksInRange(event.Height, event.Height).Chain(event.ChainID).All(ctx)
+		return wrap(blocks, newBlockResolver), err
+	})
+}
+
+func (r *resolver) TxsByAddress(ctx context.Context, args struct {
+	Address string
+	ChainID *string
+}) (<-chan *txResolver, error) {
+	return subscribe(ctx, r, deref(args.ChainID), func(ctx context.Context, event db.CDCEvent) ([]*txResolver, error) {
+		if event.Kind != db.CDCKindTxs || event.Txs == 0 {
+			return nil, nil
+		}
+
+		txs, err := r.client.TxsByAddress(args.Address).Chain(event.ChainID).Heights(event.Height, event.Height).All(ctx)
+		return wrap(txs, newTxResolver), err
+	})
+}
+
+func (r *resolver) EventsByType(ctx context.Context, args struct {
+	Type    string
+	ChainID *string
+}) (<-chan *eventResolver, error) {
+	return subscribe(ctx, r, deref(args.ChainID), func(ctx context.Context, event db.CDCEvent) ([]*eventResolver, error) {
+		q := r.client.EventsByType(args.Type).Chain(event.ChainID).Heights(event.Height, event.Height)
+		// The message and block events of a height are committed separately, each with its own notification
+		if event.Kind == db.CDCKindTxs {
+			q.MessageEventsOnly()
+		} else {
+			q.BlockEventsOnly()
+		}
+
+		events, err := q.All(ctx)
+		return wrap(events, newEventResolver), err
+	})
+}
+
+// subscribe fetches the results of every CDC event of the chain, or of all chains, and sends them on the returned
+// channel. The channel is closed once the context is done, the subscriber falls behind or a query fails.
+func subscribe[R any](ctx context.Context, r *resolver, chainID string, fetch func(context.Context, db.CDCEvent) ([]R, error)) (<-chan R, error) {
+	if err := auth.Check(ctx, auth.ScopeStream); err != nil {
+		return nil, err
+	}
+	if r.bus == nil {
+		return nil, errSubscriptionsDisabled
+	}
+
+	subscription := r.bus.Subscribe(subscriptionBuffer)
+	results := make(chan R)
+
+	go func() {
+		defer close(results)
+		defer subscription.Cancel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-subscription.Events():
+				if !ok {
+					return
+				}
+				if chainID != "" && event.ChainID != chainID {
+					continue
+				}
+
+				fetched, err := fetch(ctx, event)
+				if err != nil {
+					if ctx.Err() == nil {
+						config.Log.Error("Error resolving a GraphQL subscription", err)
+					}
+					return
+				}
+
+				for _, result := range fetched {
+					select {
+					case results <- result:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return results, nil
+}
@@ -0,0 +1,234 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// Message types and close codes of https://github.com/enisdenjo/graphql-ws/blob/master/PROTOCOL.md
+const (
+	protocol = "graphql-transport-ws"
+
+	messageConnectionInit = "connection_init"
+	messageConnectionAck  = "connection_ack"
+	messagePing           = "ping"
+	messagePong           = "pong"
+	messageSubscribe      = "subscribe"
+	messageNext           = "next"
+	messageError          = "error"
+	messageComplete       = "complete"
+
+	closeBadRequest         = 4400
+	closeUnauthorized       = 4401
+	closeInitTimeout        = 4408
+	closeSubscriberExists   = 4409
+	closeTooManyInitRequest = 4429
+
+	connectionInitTimeout = 10 * time.Second
+	writeTimeout          = 10 * time.Second
+)
+
+type message struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+var upgrader = websocket.Upgrader{
+	Subprotocols: []string{protocol},
+	// The API is read-only and unauthenticated, so browser clients of any origin may subscribe
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// connection is a graphql-transport-ws connection, it runs every operation the client subscribes to until the
+// operation completes or the client completes it
+type connection struct {
+	schema     *graphql.Schema
+	conn       *websocket.Conn
+	writeMu    sync.Mutex
+	mu         sync.Mutex
+	operations map[string]context.CancelFunc
+}
+
+func (h *handler) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !websocket.IsWebSocketUpgrade(r) {
+		http.Error(w, "POST queries or upgrade to a "+protocol+" WebSocket", http.StatusBadRequest)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already responded with the error
+		return
+	}
+	defer conn.Close()
+
+	c := &connection{schema: h.schema, conn: conn, operations: map[string]context.CancelFunc{}}
+	if conn.Subprotocol() != protocol {
+		c.close(closeBadRequest, "unsupported subprotocol, use "+protocol)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	c.run(ctx)
+}
+
+func (c *connection) run(ctx context.Context) {
+	initialized := false
+	_ = c.conn.SetReadDeadline(time.Now().Add(connectionInitTimeout))
+
+	for {
+		var msg message
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			if !initialized && isTimeout(err) {
+				c.close(closeInitTimeout, "connection initialisation timeout")
+			}
+			return
+		}
+
+		switch msg.Type {
+		case messageConnectionInit:
+			if initialized {
+				c.close(closeTooManyInitRequest, "too many initialisation requests")
+				return
+			}
+			initialized = true
+			_ = c.conn.SetReadDeadline(time.Time{})
+			if err := c.write(message{Type: messageConnectionAck}); err != nil {
+				return
+			}
+		case messagePing:
+			if err := c.write(message{Type: messagePong}); err != nil {
+				return
+			}
+		case messagePong:
+		case messageSubscribe:
+			if !initialized {
+				c.close(closeUnauthorized, "unauthorized")
+				return
+			}
+
+			var req request
+			if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil {
+				c.close(closeBadRequest, "invalid subscribe message")
+				return
+			}
+			if !c.start(ctx, msg.ID, req) {
+				c.close(closeSubscriberExists, fmt.Sprintf("subscriber for %s already exists", msg.ID))
+				return
+			}
+		case messageComplete:
+			c.stop(msg.ID)
+		default:
+			c.close(closeBadRequest, fmt.Sprintf("invalid message type %q", msg.Type))
+			return
+		}
+	}
+}
+
+// start runs the operation in the background, false if an operation with the ID is already running
+func (c *connection) start(ctx context.Context, id string, req request) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.operations[id]; ok {
+		return false
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.operations[id] = cancel
+
+	go func() {
+		defer cancel()
+
+		responses, err := c.schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+		if err != nil {
+			c.finish(id, messageError, []map[string]string{{"message": err.Error()}})
+			return
+		}
+
+		for response := range responses {
+			response := response.(*graphql.Response)
+			// Errors without data mean the operation could not be executed at all
+			if response.Data == nil && len(response.Errors) > 0 {
+				c.finish(id, messageError, response.Errors)
+				return
+			}
+			if err := c.writePayload(id, messageNext, response); err != nil {
+				return
+			}
+		}
+
+		c.finish(id, messageComplete, nil)
+	}()
+
+	return true
+}
+
+// stop cancels an operation the client completed, the server must not send its complete message
+func (c *connection) stop(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cancel, ok := c.operations[id]; ok {
+		cancel()
+		delete(c.operations, id)
+	}
+}
+
+// finish sends the final message of an operation unless the client completed it first
+func (c *connection) finish(id string, messageType string, payload any) {
+	c.mu.Lock()
+	_, running := c.operations[id]
+	delete(c.operations, id)
+	c.mu.Unlock()
+
+	if running {
+		_ = c.writePayload(id, messageType, payload)
+	}
+}
+
+func (c *connection) writePayload(id string, messageType string, payload any) error {
+	msg := message{ID: id, Type: messageType}
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			config.Log.Error("Error encoding GraphQL response", err)
+			return err
+		}
+		msg.Payload = encoded
+	}
+	return c.write(msg)
+}
+
+func (c *connection) write(msg message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if err := c.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	return c.conn.WriteJSON(msg)
+}
+
+func (c *connection) close(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_ = c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeTimeout))
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(interface{ Timeout() bool })
+	return ok && netErr.Timeout()
+}
@@ -0,0 +1,220 @@
+// Package jobs runs user-defined aggregation jobs inside the indexer, every N blocks or on a cron schedule, and records
+// their runs in the job_runs table.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/schedule"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+const (
+	TriggerBlocks = "blocks"
+	TriggerCron   = "cron"
+)
+
+// Func is the work of a job, height is the height of the last indexed block
+type Func func(ctx context.Context, db *gorm.DB, height int64) error
+
+// Job is an aggregation job run every EveryBlocks indexed blocks, on the Cron spec evaluated in Timezone, or both
+type Job struct {
+	Name        string
+	EveryBlocks int64
+	Cron        string
+	Timezone    string
+	Run         Func
+}
+
+// SQLJob creates the job of a SQL job declared in the jobs file. The SQL runs in a transaction, @height is bound to the
+// height of the last indexed block.
+func SQLJob(declared config.AggregationJob) Job {
+	query := declared.SQL
+	return Job{
+		Name:        declared.Name,
+		EveryBlocks: declared.EveryBlocks,
+		Cron:        declared.Cron,
+		Timezone:    declared.Timezone,
+		Run: func(ctx context.Context, db *gorm.DB, height int64) error {
+			return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				if strings.Contains(query, "@height") {
+					return tx.Exec(query, sql.Named("height", height)).Error
+				}
+				return tx.Exec(query).Error
+			})
+		},
+	}
+}
+
+type scheduledJob struct {
+	Job
+	schedule cron.Schedule
+	blocks   int64
+	running  atomic.Bool
+}
+
+// Scheduler runs the jobs of a chain. Block cadences are counted by BlockIndexed, cron schedules are followed by Start.
+// A job is never run twice at the same time, a run that is due while the previous one is still running is skipped.
+type Scheduler struct {
+	db      *gorm.DB
+	chainID string
+	jobs    []*scheduledJob
+	height  atomic.Int64
+	wg      sync.WaitGroup
+	// record stores the run history, in the job_runs table unless replaced by tests
+	record func(run models.JobRun) error
+}
+
+func NewScheduler(db *gorm.DB, chainID string, jobs []Job) (*Scheduler, error) {
+	s := &Scheduler{db: db, chainID: chainID}
+	s.record = func(run models.JobRun) error {
+		return s.db.Create(&run).Error
+	}
+
+	names := map[string]struct{}{}
+	for _, job := range jobs {
+		if job.Name == "" || job.Run == nil {
+			return nil, errors.New("aggregation jobs need a name and a function to run")
+		}
+		if _, ok := names[job.Name]; ok {
+			return nil, fmt.Errorf("aggregation job %s is registered twice", job.Name)
+		}
+		names[job.Name] = struct{}{}
+
+		scheduled := &scheduledJob{Job: job}
+		if job.Cron != "" {
+			timezone := job.Timezone
+			if timezone == "" {
+				timezone = "UTC"
+			}
+			var err error
+			if scheduled.schedule, err = schedule.ParseCron(job.Cron, timezone); err != nil {
+				return nil, fmt.Errorf("aggregation job %s: %w", job.Name, err)
+			}
+		}
+		if job.EveryBlocks <= 0 && scheduled.schedule == nil {
+			return nil, fmt.Errorf("aggregation job %s needs a block or cron cadence", job.Name)
+		}
+
+		s.jobs = append(s.jobs, scheduled)
+	}
+
+	return s, nil
+}
+
+// BlockIndexed runs the jobs that are due after another block was committed, in the DB writer like the refresh of the
+// materialized views. A failed run is recorded and logged, it does not stop indexing.
+func (s *Scheduler) BlockIndexed(ctx context.Context, height int64) {
+	if s == nil {
+		return
+	}
+
+	if height > s.height.Load() {
+		s.height.Store(height)
+	}
+
+	for _, job := range s.jobs {
+		if job.EveryBlocks <= 0 {
+			continue
+		}
+
+		job.blocks++
+		if job.blocks < job.EveryBlocks {
+			continue
+		}
+		job.blocks = 0
+
+		s.run(ctx, job, TriggerBlocks)
+	}
+}
+
+// Start follows the cron schedules of the jobs in the background until ctx is done, Wait waits for the runs in
+// progress to finish
+func (s *Scheduler) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+
+	for _, job := range s.jobs {
+		if job.schedule == nil {
+			continue
+		}
+
+		s.wg.Add(1)
+		go func(job *scheduledJob) {
+			defer s.wg.Done()
+			for {
+				next := job.schedule.Next(time.Now())
+				if next.IsZero() {
+					return
+				}
+
+				timer := time.NewTimer(time.Until(next))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return
+				case <-timer.C:
+				}
+
+				s.run(ctx, job, TriggerCron)
+			}
+		}(job)
+	}
+}
+
+func (s *Scheduler) Wait() {
+	if s == nil {
+		return
+	}
+	s.wg.Wait()
+}
+
+func (s *Scheduler) run(ctx context.Context, job *scheduledJob, trigger string) {
+	if !job.running.CompareAndSwap(false, true) {
+		config.Log.Warnf("Skipping the %s run of aggregation job %s, the previous run is still in progress", trigger, job.Name)
+		return
+	}
+	defer job.running.Store(false)
+
+	run := models.JobRun{
+		ChainID:   s.chainID,
+		Job:       job.Name,
+		Trigger:   trigger,
+		Height:    s.height.Load(),
+		StartedAt: time.Now(),
+	}
+
+	err := job.Run.call(ctx, s.db, run.Height)
+	run.FinishedAt = time.Now()
+	if err != nil {
+		run.Error = err.Error()
+		config.Log.Error("Error running aggregation job "+job.Name, err)
+	} else {
+		config.Log.Debugf("Ran aggregation job %s in %s", job.Name, run.FinishedAt.Sub(run.StartedAt))
+	}
+
+	if err := s.record(run); err != nil {
+		config.Log.Error("Error recording the run of aggregation job "+job.Name, err)
+	}
+}
+
+// call runs the job, turning a panic into an error so a broken job can't take the indexer down
+func (f Func) call(ctx context.Context, db *gorm.DB, height int64) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return f(ctx, db, height)
+}
@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+type JobsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *JobsTestSuite) TestBlockCadence() {
+	var heights []int64
+	scheduler, err := NewScheduler(nil, "testchain-1", []Job{
+		{Name: "every_two", EveryBlocks: 2, Run: func(ctx context.Context, db *gorm.DB, height int64) error {
+			heights = append(heights, height)
+			return nil
+		}},
+		{Name: "broken", EveryBlocks: 3, Run: func(ctx context.Context, db *gorm.DB, height int64) error {
+			panic("broken job")
+		}},
+		{Name: "failing", EveryBlocks: 3, Run: func(ctx context.Context, db *gorm.DB, height int64) error {
+			return errors.New("failing job")
+		}},
+	})
+	suite.Require().NoError(err)
+
+	var runs []models.JobRun
+	scheduler.record = func(run models.JobRun) error {
+		runs = append(runs, run)
+		return nil
+	}
+
+	for height := int64(1); height <= 6; height++ {
+		scheduler.BlockIndexed(context.Background(), height)
+	}
+
+	suite.Require().Equal([]int64{2, 4, 6}, heights)
+	suite.Require().Len(runs, 7)
+	for _, run := range runs {
+		suite.Require().Equal(TriggerBlocks, run.Trigger)
+		switch run.Job {
+		case "every_two":
+			suite.Require().Empty(run.Error)
+		case "broken":
+			suite.Require().Equal("panic: broken job", run.Error)
+		case "failing":
+			suite.Require().Equal("failing job", run.Error)
+		}
+	}
+}
+
+func (suite *JobsTestSuite) TestNewScheduler() {
+	noop := func(ctx context.Context, db *gorm.DB, height int64) error { return nil }
+
+	invalid := [][]Job{
+		{{Name: "no_cadence", Run: noop}},
+		{{Name: "no_func", EveryBlocks: 1}},
+		{{Name: "bad_cron", Cron: "every hour", Run: noop}},
+		{{Name: "twice", EveryBlocks: 1, Run: noop}, {Name: "twice", Cron: "@hourly", Run: noop}},
+	}
+	for _, jobs := range invalid {
+		_, err := NewScheduler(nil, "testchain-1", jobs)
+		suite.Require().Error(err, jobs[0].Name)
+	}
+
+	scheduler, err := NewScheduler(nil, "testchain-1", []Job{SQLJob(config.AggregationJob{Name: "hourly", SQL: "SELECT 1", Cron: "@hourly"})})
+	suite.Require().NoError(err)
+	suite.Require().NotNil(scheduler.jobs[0].schedule)
+}
+
+func TestJobsTestSuite(t *testing.T) {
+	suite.Run(t, new(JobsTestSuite))
+}
@@ -0,0 +1,24 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "api",
+	Name:      "requests_total",
+	Help:      "Number of API requests by client, which is the API key name, jwt or ip, and whether they were allowed or rate limited.",
+}, []string{"api", "client", "result"})
+
+func init() {
+	Registry.MustRegister(apiRequests)
+}
+
+func ObserveAPIRequest(api string, client string, allowed bool) {
+	result := "allowed"
+	if !allowed {
+		result = "limited"
+	}
+	apiRequests.WithLabelValues(api, client, result).Inc()
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	chainHeadHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "chain",
+		Name:      "head_height",
+		Help:      "Latest block height of the chain reported by the node.",
+	}, []string{"chain_id"})
+
+	chainIndexedHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "chain",
+		Name:      "indexed_height",
+		Help:      "Highest indexed block height of the chain.",
+	}, []string{"chain_id"})
+
+	chainLagBlocks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "chain",
+		Name:      "lag_blocks",
+		Help:      "Number of blocks the highest indexed block is behind the chain head.",
+	}, []string{"chain_id"})
+
+	chainLagSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "chain",
+		Name:      "lag_seconds",
+		Help:      "Block time difference between the chain head and the highest indexed block.",
+	}, []string{"chain_id"})
+
+	chainHalted = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "chain",
+		Name:      "halted",
+		Help:      "1 while the chain head has not advanced for the halt threshold, 0 otherwise.",
+	}, []string{"chain_id"})
+)
+
+func init() {
+	Registry.MustRegister(chainHeadHeight, chainIndexedHeight, chainLagBlocks, chainLagSeconds, chainHalted)
+}
+
+func ObserveChainHead(chainID string, headHeight int64, indexedHeight int64, lagBlocks int64, lagSeconds float64, halted bool) {
+	chainHeadHeight.WithLabelValues(chainID).Set(float64(headHeight))
+	chainIndexedHeight.WithLabelValues(chainID).Set(float64(indexedHeight))
+	chainLagBlocks.WithLabelValues(chainID).Set(float64(lagBlocks))
+	chainLagSeconds.WithLabelValues(chainID).Set(lagSeconds)
+	if halted {
+		chainHalted.WithLabelValues(chainID).Set(1)
+	} else {
+		chainHalted.WithLabelValues(chainID).Set(0)
+	}
+}
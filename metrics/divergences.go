@@ -0,0 +1,20 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var divergences = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Subsystem: "rpc_consistency",
+	Name:      "divergences_total",
+	Help:      "Number of responses of the consistency endpoint that diverged from the indexed endpoint, by request.",
+}, []string{"endpoint", "request"})
+
+func init() {
+	Registry.MustRegister(divergences)
+}
+
+func ObserveDivergence(endpoint string, request string) {
+	divergences.WithLabelValues(endpoint, request).Inc()
+}
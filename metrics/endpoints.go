@@ -0,0 +1,63 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	endpointRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rpc_endpoint",
+		Name:      "requests_total",
+		Help:      "Number of requests sent to an RPC endpoint, by result.",
+	}, []string{"endpoint", "result"})
+
+	endpointRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "rpc_endpoint",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the requests sent to an RPC endpoint.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint"})
+
+	endpointHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "rpc_endpoint",
+		Name:      "healthy",
+		Help:      "1 while an RPC endpoint is in the rotation, 0 while it is blacklisted.",
+	}, []string{"endpoint"})
+
+	endpointLowestHeight = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "rpc_endpoint",
+		Name:      "lowest_height",
+		Help:      "Lowest height an RPC endpoint serves, learned from its pruned height errors.",
+	}, []string{"endpoint"})
+)
+
+func init() {
+	Registry.MustRegister(endpointRequests, endpointRequestDuration, endpointHealthy, endpointLowestHeight)
+}
+
+func ObserveEndpointRequest(endpoint string, failed bool, duration time.Duration) {
+	endpointRequestDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+	if failed {
+		endpointRequests.WithLabelValues(endpoint, "error").Inc()
+		return
+	}
+	endpointRequests.WithLabelValues(endpoint, "ok").Inc()
+}
+
+func SetEndpointHealthy(endpoint string, healthy bool) {
+	if healthy {
+		endpointHealthy.WithLabelValues(endpoint).Set(1)
+	} else {
+		endpointHealthy.WithLabelValues(endpoint).Set(0)
+	}
+}
+
+func SetEndpointLowestHeight(endpoint string, height int64) {
+	endpointLowestHeight.WithLabelValues(endpoint).Set(float64(height))
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	blocksIndexed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "index",
+		Name:      "blocks_total",
+		Help:      "Number of blocks whose transactions or block events were written, its rate is the indexing throughput.",
+	}, []string{"chain_id"})
+
+	failedBlocks = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "index",
+		Name:      "failed_blocks_total",
+		Help:      "Number of block failures recorded, by failed part (block or events), component and error code.",
+	}, []string{"part", "component", "code"})
+
+	stageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "index",
+		Name:      "stage_duration_seconds",
+		Help:      "Duration of a stage of the indexing pipeline for a block: RPC requests, parsing and database writes.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"stage"})
+
+	channelDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "index",
+		Name:      "channel_depth",
+		Help:      "Number of blocks waiting in a buffered channel between two stages of the indexing pipeline.",
+	}, []string{"channel"})
+)
+
+func init() {
+	Registry.MustRegister(blocksIndexed, failedBlocks, stageDuration, channelDepth)
+}
+
+func AddBlockIndexed(chainID string) {
+	blocksIndexed.WithLabelValues(chainID).Inc()
+}
+
+// AddFailedBlock counts a recorded failure, part is block for a failed block and events for failed block events
+func AddFailedBlock(part string, component string, code string) {
+	failedBlocks.WithLabelValues(part, component, code).Inc()
+}
+
+func ObserveStage(stage string, duration time.Duration) {
+	stageDuration.WithLabelValues(stage).Observe(duration.Seconds())
+}
+
+func SetChannelDepth(channel string, depth int) {
+	channelDepth.WithLabelValues(channel).Set(float64(depth))
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/suite"
+)
+
+type IndexingMetricsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *IndexingMetricsTestSuite) TestIndexingMetrics() {
+	AddBlockIndexed("test-1")
+	AddBlockIndexed("test-1")
+	suite.Require().Equal(float64(2), testutil.ToFloat64(blocksIndexed.WithLabelValues("test-1")))
+
+	AddFailedBlock("events", "rpc_worker", "query_error")
+	suite.Require().Equal(float64(1), testutil.ToFloat64(failedBlocks.WithLabelValues("events", "rpc_worker", "query_error")))
+	suite.Require().Zero(testutil.ToFloat64(failedBlocks.WithLabelValues("block", "rpc_worker", "query_error")))
+
+	SetChannelDepth("fetched", 7)
+	SetChannelDepth("fetched", 3)
+	suite.Require().Equal(float64(3), testutil.ToFloat64(channelDepth.WithLabelValues("fetched")))
+
+	ObserveStage("rpc.block", 20*time.Millisecond)
+	suite.Require().Equal(1, testutil.CollectAndCount(stageDuration))
+}
+
+func TestIndexingMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(IndexingMetricsTestSuite))
+}
@@ -0,0 +1,205 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ParserStage identifies which part of the custom parser lifecycle was executed
+type ParserStage string
+
+const (
+	ParserStageParse ParserStage = "parse"
+	ParserStageIndex ParserStage = "index"
+)
+
+// Number of recent durations kept per parser stage for calculating percentiles outside of Prometheus
+const parserDurationWindowSize = 1024
+
+var (
+	parserInvocations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "parser",
+		Name:      "invocations_total",
+		Help:      "Number of times a custom parser has been invoked.",
+	}, []string{"parser", "stage"})
+
+	parserErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "parser",
+		Name:      "errors_total",
+		Help:      "Number of custom parser invocations that returned an error.",
+	}, []string{"parser", "stage"})
+
+	parserDuration = prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Namespace:  namespace,
+		Subsystem:  "parser",
+		Name:       "duration_seconds",
+		Help:       "Custom parser execution duration.",
+		Objectives: map[float64]float64{0.5: 0.05, 0.99: 0.001},
+		MaxAge:     10 * time.Minute,
+	}, []string{"parser", "stage"})
+
+	parserRowsWritten = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "parser",
+		Name:      "rows_written_total",
+		Help:      "Number of database rows written by a custom parser.",
+	}, []string{"parser"})
+)
+
+func init() {
+	Registry.MustRegister(parserInvocations, parserErrors, parserDuration, parserRowsWritten)
+}
+
+// ParserStats is a point-in-time view of the execution statistics for a single parser stage
+type ParserStats struct {
+	Identifier  string
+	Stage       ParserStage
+	Invocations uint64
+	Errors      uint64
+	P50         time.Duration
+	P99         time.Duration
+	RowsWritten uint64
+}
+
+type parserStatsKey struct {
+	identifier string
+	stage      ParserStage
+}
+
+type parserStatsTracker struct {
+	invocations uint64
+	errors      uint64
+	rowsWritten uint64
+	durations   []time.Duration
+	next        int
+}
+
+var (
+	parserStatsMu sync.Mutex
+	parserStats   = make(map[parserStatsKey]*parserStatsTracker)
+)
+
+func getParserStatsTracker(identifier string, stage ParserStage) *parserStatsTracker {
+	key := parserStatsKey{identifier: identifier, stage: stage}
+	tracker, ok := parserStats[key]
+	if !ok {
+		tracker = &parserStatsTracker{}
+		parserStats[key] = tracker
+	}
+	return tracker
+}
+
+// ObserveParserExecution records a single custom parser invocation
+func ObserveParserExecution(identifier string, stage ParserStage, duration time.Duration, err error) {
+	parserInvocations.WithLabelValues(identifier, string(stage)).Inc()
+	parserDuration.WithLabelValues(identifier, string(stage)).Observe(duration.Seconds())
+	if err != nil {
+		parserErrors.WithLabelValues(identifier, string(stage)).Inc()
+	}
+
+	parserStatsMu.Lock()
+	defer parserStatsMu.Unlock()
+
+	tracker := getParserStatsTracker(identifier, stage)
+	tracker.invocations++
+	if err != nil {
+		tracker.errors++
+	}
+
+	if len(tracker.durations) < parserDurationWindowSize {
+		tracker.durations = append(tracker.durations, duration)
+	} else {
+		tracker.durations[tracker.next] = duration
+		tracker.next = (tracker.next + 1) % parserDurationWindowSize
+	}
+}
+
+// AddParserRowsWritten records the number of rows a custom parser wrote during its index stage
+func AddParserRowsWritten(identifier string, rows int64) {
+	if rows <= 0 {
+		return
+	}
+
+	parserRowsWritten.WithLabelValues(identifier).Add(float64(rows))
+
+	parserStatsMu.Lock()
+	defer parserStatsMu.Unlock()
+
+	getParserStatsTracker(identifier, ParserStageIndex).rowsWritten += uint64(rows)
+}
+
+// GetParserStats returns the current execution statistics for every parser stage that has been observed
+func GetParserStats() []ParserStats {
+	parserStatsMu.Lock()
+	defer parserStatsMu.Unlock()
+
+	stats := make([]ParserStats, 0, len(parserStats))
+	for key, tracker := range parserStats {
+		sorted := make([]time.Duration, len(tracker.durations))
+		copy(sorted, tracker.durations)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+		stats = append(stats, ParserStats{
+			Identifier:  key.identifier,
+			Stage:       key.stage,
+			Invocations: tracker.invocations,
+			Errors:      tracker.errors,
+			P50:         percentile(sorted, 0.5),
+			P99:         percentile(sorted, 0.99),
+			RowsWritten: tracker.rowsWritten,
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Identifier == stats[j].Identifier {
+			return stats[i].Stage < stats[j].Stage
+		}
+		return stats[i].Identifier < stats[j].Identifier
+	})
+
+	return stats
+}
+
+// ParserStatsSince returns the counts of the current stats added since the previous stats, so they can be added to the
+// counts stored by other indexers. Stages without new invocations or rows are left out, the percentiles are those of
+// the current stats.
+func ParserStatsSince(current []ParserStats, previous []ParserStats) []ParserStats {
+	previousStats := make(map[parserStatsKey]ParserStats, len(previous))
+	for _, stat := range previous {
+		previousStats[parserStatsKey{identifier: stat.Identifier, stage: stat.Stage}] = stat
+	}
+
+	var added []ParserStats
+	for _, stat := range current {
+		last := previousStats[parserStatsKey{identifier: stat.Identifier, stage: stat.Stage}]
+		stat.Invocations -= last.Invocations
+		stat.Errors -= last.Errors
+		stat.RowsWritten -= last.RowsWritten
+		if stat.Invocations > 0 || stat.RowsWritten > 0 {
+			added = append(added, stat)
+		}
+	}
+
+	return added
+}
+
+// Nearest-rank percentile over an already sorted slice
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	if rank < 0 {
+		rank = 0
+	} else if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+
+	return sorted[rank]
+}
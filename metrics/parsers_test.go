@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ParserMetricsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ParserMetricsTestSuite) TestGetParserStats() {
+	for i := 1; i <= 100; i++ {
+		var err error
+		if i%10 == 0 {
+			err = errors.New("parser failure")
+		}
+		ObserveParserExecution("test-parser", ParserStageParse, time.Duration(i)*time.Millisecond, err)
+	}
+	AddParserRowsWritten("test-parser", 5)
+	AddParserRowsWritten("test-parser", 0)
+
+	var parseStats, indexStats *ParserStats
+	for _, stat := range GetParserStats() {
+		stat := stat
+		if stat.Identifier != "test-parser" {
+			continue
+		}
+		switch stat.Stage {
+		case ParserStageParse:
+			parseStats = &stat
+		case ParserStageIndex:
+			indexStats = &stat
+		}
+	}
+
+	suite.Require().NotNil(parseStats)
+	suite.Require().Equal(uint64(100), parseStats.Invocations)
+	suite.Require().Equal(uint64(10), parseStats.Errors)
+	suite.Require().Equal(50*time.Millisecond, parseStats.P50)
+	suite.Require().Equal(99*time.Millisecond, parseStats.P99)
+
+	suite.Require().NotNil(indexStats)
+	suite.Require().Equal(uint64(5), indexStats.RowsWritten)
+	suite.Require().Zero(indexStats.Invocations)
+}
+
+func (suite *ParserMetricsTestSuite) TestParserStatsSince() {
+	previous := []ParserStats{
+		{Identifier: "a", Stage: ParserStageParse, Invocations: 10, Errors: 2, P99: time.Second},
+		{Identifier: "a", Stage: ParserStageIndex, Invocations: 4, RowsWritten: 8},
+	}
+	current := []ParserStats{
+		{Identifier: "a", Stage: ParserStageParse, Invocations: 15, Errors: 3, P99: 2 * time.Second},
+		{Identifier: "a", Stage: ParserStageIndex, Invocations: 4, RowsWritten: 8},
+		{Identifier: "b", Stage: ParserStageParse, Invocations: 1},
+	}
+
+	added := ParserStatsSince(current, previous)
+	suite.Require().Equal([]ParserStats{
+		{Identifier: "a", Stage: ParserStageParse, Invocations: 5, Errors: 1, P99: 2 * time.Second},
+		{Identifier: "b", Stage: ParserStageParse, Invocations: 1},
+	}, added)
+	suite.Require().Empty(ParserStatsSince(current, current))
+}
+
+func (suite *ParserMetricsTestSuite) TestPercentile() {
+	suite.Require().Zero(percentile(nil, 0.5))
+	suite.Require().Equal(time.Second, percentile([]time.Duration{time.Second}, 0.99))
+}
+
+func TestParserMetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(ParserMetricsTestSuite))
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "cosmos_indexer"
+
+// Registry holds all indexer collectors. A dedicated registry is used so that collectors registered by imported
+// Cosmos SDK packages on the default registry do not leak into the indexer metrics.
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// StartServer serves the Prometheus metrics endpoint on the given port. It runs in the background, errors are logged.
+func StartServer(port string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%s", port),
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		config.Log.Infof("Serving Prometheus metrics on port %s", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			config.Log.Error("Prometheus metrics server failed", err)
+		}
+	}()
+
+	return server
+}
@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	sinkRecords = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sink",
+		Name:      "records_total",
+		Help:      "Number of records successfully written to a sink.",
+	}, []string{"sink"})
+
+	sinkErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "sink",
+		Name:      "errors_total",
+		Help:      "Number of failed sink writes.",
+	}, []string{"sink"})
+
+	sinkWriteDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "sink",
+		Name:      "write_duration_seconds",
+		Help:      "Duration of a single batched sink write.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"sink"})
+)
+
+func init() {
+	Registry.MustRegister(sinkRecords, sinkErrors, sinkWriteDuration)
+}
+
+func ObserveSinkWrite(sink string, records int, duration time.Duration, err error) {
+	sinkWriteDuration.WithLabelValues(sink).Observe(duration.Seconds())
+	if err != nil {
+		sinkErrors.WithLabelValues(sink).Inc()
+		return
+	}
+	sinkRecords.WithLabelValues(sink).Add(float64(records))
+}
@@ -0,0 +1,93 @@
+package parsers
+
+import (
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	txtypes "github.com/DefiantLabs/cosmos-indexer/cosmos/modules/tx"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/probe/client"
+	probeQuery "github.com/DefiantLabs/probe/query"
+	abci "github.com/cometbft/cometbft/abci/types"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	sdkTypes "github.com/cosmos/cosmos-sdk/types"
+	"gorm.io/gorm"
+)
+
+// BlockContext holds the block level data shared by every contextual parser run for a block
+type BlockContext struct {
+	Header cmttypes.Header
+	// Query is pinned to the height of the block being parsed, so module state can be read as it was at that block
+	Query *probeQuery.Query
+	// ModuleVersions are the consensus versions of the modules at the block, nil unless the indexer tracks them
+	ModuleVersions map[string]uint64
+}
+
+func NewBlockContext(cl *client.ChainClient, block *ctypes.ResultBlock) BlockContext {
+	var blockContext BlockContext
+	if block == nil || block.Block == nil {
+		return blockContext
+	}
+
+	blockContext.Header = block.Block.Header
+	if cl != nil {
+		blockContext.Query = &probeQuery.Query{Client: cl, Options: &probeQuery.QueryOptions{Height: block.Block.Height}}
+	}
+
+	return blockContext
+}
+
+// MessageParseContext is passed to ContextualMessageParser implementations in place of the bare message
+type MessageParseContext struct {
+	Config       config.IndexConfig
+	Block        BlockContext
+	TxResponse   txtypes.Response
+	MessageIndex int
+	// All messages of the transaction in order, entries for messages skipped by the message type filters are nil
+	Messages []sdkTypes.Msg
+}
+
+// MessageIndexContext is passed to ContextualMessageParser implementations at indexing time
+type MessageIndexContext struct {
+	Config config.IndexConfig
+	// DB is scoped to the parser, rolling it back does not affect the rest of the block
+	DB            *gorm.DB
+	Tx            models.Tx
+	Message       models.Message
+	MessageEvents []MessageEventWithAttributes
+}
+
+// ContextualMessageParser is an optional extension of MessageParser. If a registered parser implements it,
+// the WithContext methods are called instead of ParseMessage and IndexMessage.
+type ContextualMessageParser interface {
+	MessageParser
+	ParseMessageWithContext(sdkTypes.Msg, *txtypes.LogMessage, MessageParseContext) (*any, error)
+	IndexMessageWithContext(*any, MessageIndexContext) error
+}
+
+// BlockEventParseContext is passed to ContextualBlockEventParser implementations in place of the bare event
+type BlockEventParseContext struct {
+	Config            config.IndexConfig
+	Block             BlockContext
+	LifecyclePosition models.BlockLifecyclePosition
+	EventIndex        int
+	// All events of the same lifecycle position in order
+	Events []abci.Event
+}
+
+// BlockEventIndexContext is passed to ContextualBlockEventParser implementations at indexing time
+type BlockEventIndexContext struct {
+	Config config.IndexConfig
+	// DB is scoped to the parser, rolling it back does not affect the rest of the block
+	DB         *gorm.DB
+	Block      models.Block
+	BlockEvent models.BlockEvent
+	Attributes []models.BlockEventAttribute
+}
+
+// ContextualBlockEventParser is an optional extension of BlockEventParser. If a registered parser implements it,
+// the WithContext methods are called instead of ParseBlockEvent and IndexBlockEvent.
+type ContextualBlockEventParser interface {
+	BlockEventParser
+	ParseBlockEventWithContext(abci.Event, BlockEventParseContext) (*any, error)
+	IndexBlockEventWithContext(*any, BlockEventIndexContext) error
+}
@@ -0,0 +1,120 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// ErrParserTimeout is returned in place of parsed data when a custom parser does not finish within the configured timeout
+var ErrParserTimeout = errors.New("custom parser timed out")
+
+// PanicError wraps a panic raised inside a custom parser so it can be recorded like any other parser error
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("custom parser panicked: %v", e.Value)
+}
+
+// IsIsolatedFailure reports whether the error was produced by the parser isolation layer (panic or timeout)
+// rather than returned by the parser itself.
+func IsIsolatedFailure(err error) bool {
+	var panicErr *PanicError
+	return errors.Is(err, ErrParserTimeout) || errors.As(err, &panicErr)
+}
+
+// RecoverParser runs fn and converts any panic into a PanicError
+func RecoverParser(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+
+	return fn()
+}
+
+// RunParseWithTimeout runs a parse function with panic recovery. If timeout is greater than 0 and the function has not
+// returned in time, ErrParserTimeout is returned. A timed out parser cannot be forcibly stopped, its goroutine is
+// abandoned and its eventual result discarded.
+func RunParseWithTimeout(timeout time.Duration, parse func() (*any, error)) (*any, error) {
+	type parseResult struct {
+		data *any
+		err  error
+	}
+
+	run := func() parseResult {
+		var result parseResult
+		result.err = RecoverParser(func() error {
+			var err error
+			result.data, err = parse()
+			return err
+		})
+		return result
+	}
+
+	if timeout <= 0 {
+		result := run()
+		return result.data, result.err
+	}
+
+	// Buffered so an abandoned parser goroutine can still complete its send and exit
+	resultChan := make(chan parseResult, 1)
+	go func() {
+		resultChan <- run()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case result := <-resultChan:
+		return result.data, result.err
+	case <-timer.C:
+		return nil, fmt.Errorf("%w after %s", ErrParserTimeout, timeout)
+	}
+}
+
+// DeferredIndexer can be implemented by custom parsers whose index step is too expensive to run while the block
+// transaction is held open. Parsers returning true are indexed in a separate transaction after the core block rows
+// are committed, so their tables may briefly lag behind (or, after a crash, fall behind) the core tables.
+type DeferredIndexer interface {
+	DeferIndexing() bool
+}
+
+// IndexesAtomically reports whether a custom parser's index writes are committed in the same database transaction
+// as the core block, tx and event rows they belong to. This is the default for all parsers.
+func IndexesAtomically(parser any) bool {
+	if deferred, ok := parser.(DeferredIndexer); ok {
+		return !deferred.DeferIndexing()
+	}
+	return true
+}
+
+// ModuleVersionedParser can be implemented by custom parsers written for a range of versions of a module, e.g. a
+// parser of a message that changed shape in an upgrade. It returns the module and the lowest and highest consensus
+// version it supports, a highest version of 0 supports every later version.
+type ModuleVersionedParser interface {
+	ModuleVersions() (module string, min uint64, max uint64)
+}
+
+// SupportsModuleVersions reports whether a custom parser runs for a block with the module versions. Parsers run when
+// they don't declare a version range, when the module versions of the block are unknown, which they are unless the
+// indexer tracks them, and when the block has no version of the module.
+func SupportsModuleVersions(parser any, versions map[string]uint64) bool {
+	versioned, ok := parser.(ModuleVersionedParser)
+	if !ok || versions == nil {
+		return true
+	}
+
+	module, min, max := versioned.ModuleVersions()
+	version, ok := versions[module]
+	if !ok {
+		return true
+	}
+	return version >= min && (max == 0 || version <= max)
+}
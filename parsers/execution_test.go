@@ -0,0 +1,94 @@
+package parsers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ExecutionTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ExecutionTestSuite) TestRunParseWithTimeoutSuccess() {
+	var value any = "parsed"
+	data, err := RunParseWithTimeout(time.Second, func() (*any, error) {
+		return &value, nil
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("parsed", *data)
+}
+
+func (suite *ExecutionTestSuite) TestRunParseWithTimeoutPassesThroughErrors() {
+	parseErr := errors.New("bad message")
+	_, err := RunParseWithTimeout(0, func() (*any, error) {
+		return nil, parseErr
+	})
+	suite.Require().ErrorIs(err, parseErr)
+	suite.Require().False(IsIsolatedFailure(err))
+}
+
+func (suite *ExecutionTestSuite) TestRunParseWithTimeoutRecoversPanic() {
+	for _, timeout := range []time.Duration{0, time.Second} {
+		_, err := RunParseWithTimeout(timeout, func() (*any, error) {
+			panic("boom")
+		})
+		var panicErr *PanicError
+		suite.Require().ErrorAs(err, &panicErr)
+		suite.Require().Equal("boom", panicErr.Value)
+		suite.Require().True(IsIsolatedFailure(err))
+	}
+}
+
+func (suite *ExecutionTestSuite) TestRunParseWithTimeoutTimesOut() {
+	release := make(chan struct{})
+	defer close(release)
+
+	_, err := RunParseWithTimeout(10*time.Millisecond, func() (*any, error) {
+		<-release
+		return nil, nil
+	})
+	suite.Require().ErrorIs(err, ErrParserTimeout)
+	suite.Require().True(IsIsolatedFailure(err))
+}
+
+type deferredParser struct {
+	deferIndexing bool
+}
+
+func (p deferredParser) DeferIndexing() bool {
+	return p.deferIndexing
+}
+
+func (suite *ExecutionTestSuite) TestIndexesAtomically() {
+	suite.Require().True(IndexesAtomically(struct{}{}))
+	suite.Require().True(IndexesAtomically(deferredParser{deferIndexing: false}))
+	suite.Require().False(IndexesAtomically(deferredParser{deferIndexing: true}))
+}
+
+type versionedParser struct {
+	min uint64
+	max uint64
+}
+
+func (p versionedParser) ModuleVersions() (string, uint64, uint64) {
+	return "bank", p.min, p.max
+}
+
+func (suite *ExecutionTestSuite) TestSupportsModuleVersions() {
+	versions := map[string]uint64{"bank": 3, "staking": 4}
+
+	suite.Require().True(SupportsModuleVersions(struct{}{}, versions))
+	suite.Require().True(SupportsModuleVersions(versionedParser{min: 4}, nil))
+	suite.Require().True(SupportsModuleVersions(versionedParser{min: 4}, map[string]uint64{"staking": 4}))
+	suite.Require().True(SupportsModuleVersions(versionedParser{min: 2, max: 3}, versions))
+	suite.Require().True(SupportsModuleVersions(versionedParser{min: 3}, versions))
+	suite.Require().False(SupportsModuleVersions(versionedParser{min: 4}, versions))
+	suite.Require().False(SupportsModuleVersions(versionedParser{min: 1, max: 2}, versions))
+}
+
+func TestExecutionTestSuite(t *testing.T) {
+	suite.Run(t, new(ExecutionTestSuite))
+}
@@ -0,0 +1,35 @@
+package wasm
+
+import "github.com/DefiantLabs/cosmos-indexer/db/models"
+
+// Contract is a contract instantiated or migrated by an indexed message. CodeID is the code it runs as of Message, the
+// last message indexed that instantiated or migrated it.
+type Contract struct {
+	ID        uint
+	AddressID uint `gorm:"uniqueIndex"`
+	Address   models.Address
+	CodeID    uint64 `gorm:"index"`
+	MessageID uint
+	Message   models.Message
+}
+
+// ContractEvent is an event emitted by a contract while executing, instantiating or migrating it. EventIndex is the
+// position of the event in the events of its message, the index of its message event. Attributes holds the attributes
+// other than the contract address as a JSON object, a key emitted more than once holds the array of its values.
+type ContractEvent struct {
+	ID                uint
+	MessageID         uint `gorm:"uniqueIndex:idx_contract_event,priority:1"`
+	Message           models.Message
+	EventIndex        uint64 `gorm:"uniqueIndex:idx_contract_event,priority:2"`
+	Operation         Operation
+	Type              string
+	ContractAddressID uint `gorm:"index"`
+	ContractAddress   models.Address
+	Action            string `gorm:"index"`
+	Attributes        string `gorm:"type:jsonb"`
+}
+
+// Models are the tables written by the parsers, migrated when the parsers are registered
+func Models() []any {
+	return []any{&Contract{}, &ContractEvent{}}
+}
@@ -0,0 +1,236 @@
+// Package wasm is the built-in parser of the events of CosmWasm contracts. It indexes the events the contracts emit
+// while executing, instantiating and migrating them into the contract_events table and the code of the contracts into
+// the contracts table. The index command registers the parsers with the custom message parsers when wasm.enabled is
+// set, the chain codec has to decode the wasm messages, e.g. through cmd.RegisterCustomModuleBasics.
+package wasm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	txtypes "github.com/DefiantLabs/cosmos-indexer/cosmos/modules/tx"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/parsers"
+	sdkTypes "github.com/cosmos/cosmos-sdk/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Message types of the wasm module whose events are parsed
+const (
+	MsgExecuteContract      = "/cosmwasm.wasm.v1.MsgExecuteContract"
+	MsgInstantiateContract  = "/cosmwasm.wasm.v1.MsgInstantiateContract"
+	MsgInstantiateContract2 = "/cosmwasm.wasm.v1.MsgInstantiateContract2"
+	MsgMigrateContract      = "/cosmwasm.wasm.v1.MsgMigrateContract"
+)
+
+// Operation is the kind of message the contract events were emitted by
+type Operation string
+
+const (
+	OperationExecute     Operation = "execute"
+	OperationInstantiate Operation = "instantiate"
+	OperationMigrate     Operation = "migrate"
+)
+
+const contractAddressKey = "_contract_address"
+
+// MessageParsers returns the parsers by the message type they parse
+func MessageParsers() map[string]parsers.MessageParser {
+	return map[string]parsers.MessageParser{
+		MsgExecuteContract:      &MessageParser{ID: "wasm-execute", Operation: OperationExecute},
+		MsgInstantiateContract:  &MessageParser{ID: "wasm-instantiate", Operation: OperationInstantiate},
+		MsgInstantiateContract2: &MessageParser{ID: "wasm-instantiate2", Operation: OperationInstantiate},
+		MsgMigrateContract:      &MessageParser{ID: "wasm-migrate", Operation: OperationMigrate},
+	}
+}
+
+// MessageParser parses the contract events of the messages of an operation. The events are read from the message log,
+// so the messages themselves are not inspected.
+type MessageParser struct {
+	ID        string
+	Operation Operation
+}
+
+type parsedContract struct {
+	address string
+	codeID  uint64
+}
+
+type parsedEvent struct {
+	index      uint64
+	eventType  string
+	contract   string
+	action     string
+	attributes string
+}
+
+type parsedMessage struct {
+	contracts []parsedContract
+	events    []parsedEvent
+}
+
+func (p *MessageParser) Identifier() string {
+	return p.ID
+}
+
+// ParseMessage parses the events of the contracts in wasm.contracts, or of every contract when it is empty. The
+// execute, instantiate and migrate events, and the wasm events including the custom wasm-* events, are contract events.
+// The code of the contracts is taken from the instantiate and migrate events.
+func (p *MessageParser) ParseMessage(_ sdkTypes.Msg, log *txtypes.LogMessage, cfg config.IndexConfig) (*any, error) {
+	var parsed parsedMessage
+	if log == nil {
+		// Still returned, so the contract events indexed for the message before are cleared
+		data := any(parsed)
+		return &data, nil
+	}
+
+	allowed := make(map[string]bool, len(cfg.Wasm.Contracts))
+	for _, contract := range cfg.Wasm.Contracts {
+		allowed[strings.TrimSpace(contract)] = true
+	}
+
+	for index, event := range log.Events {
+		if !isContractEvent(event.Type) {
+			continue
+		}
+
+		contract := attributeValue(event, contractAddressKey)
+		if contract == "" || (len(allowed) != 0 && !allowed[contract]) {
+			continue
+		}
+
+		attributes, err := attributesJSON(event.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		parsed.events = append(parsed.events, parsedEvent{
+			index:      uint64(index),
+			eventType:  event.Type,
+			contract:   contract,
+			action:     attributeValue(event, "action"),
+			attributes: attributes,
+		})
+
+		if event.Type == "instantiate" || event.Type == "migrate" {
+			codeID, err := strconv.ParseUint(attributeValue(event, "code_id"), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid code_id of the %s event of contract %s: %w", event.Type, contract, err)
+			}
+			parsed.contracts = append(parsed.contracts, parsedContract{address: contract, codeID: codeID})
+		}
+	}
+
+	data := any(parsed)
+	return &data, nil
+}
+
+// IndexMessage replaces the contract events of the message and records the code of the contracts instantiated or
+// migrated by it
+func (p *MessageParser) IndexMessage(dataset *any, db *gorm.DB, message models.Message, _ []parsers.MessageEventWithAttributes, _ config.IndexConfig) error {
+	parsed, ok := (*dataset).(parsedMessage)
+	if !ok {
+		return errors.New("invalid wasm dataset type")
+	}
+
+	err := db.Where("message_id = ?", message.ID).Delete(&ContractEvent{}).Error
+	if err != nil {
+		return err
+	}
+
+	addresses := make(map[string]models.Address)
+	address := func(contract string) (models.Address, error) {
+		if found, ok := addresses[contract]; ok {
+			return found, nil
+		}
+		found, err := dbTypes.FindOrCreateAddressByAddress(db, contract)
+		if err != nil {
+			return found, err
+		}
+		addresses[contract] = found
+		return found, nil
+	}
+
+	for _, parsedContract := range parsed.contracts {
+		contractAddress, err := address(parsedContract.address)
+		if err != nil {
+			return err
+		}
+		contract := Contract{AddressID: contractAddress.ID, CodeID: parsedContract.codeID, MessageID: message.ID}
+		err = db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "address_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"code_id", "message_id"}),
+		}).Omit("Address", "Message").Create(&contract).Error
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(parsed.events) == 0 {
+		return nil
+	}
+	events := make([]ContractEvent, 0, len(parsed.events))
+	for _, event := range parsed.events {
+		contractAddress, err := address(event.contract)
+		if err != nil {
+			return err
+		}
+		events = append(events, ContractEvent{
+			MessageID:         message.ID,
+			EventIndex:        event.index,
+			Operation:         p.Operation,
+			Type:              event.eventType,
+			ContractAddressID: contractAddress.ID,
+			Action:            event.action,
+			Attributes:        event.attributes,
+		})
+	}
+	return db.Omit("Message", "ContractAddress").Create(&events).Error
+}
+
+func isContractEvent(eventType string) bool {
+	switch eventType {
+	case "execute", "instantiate", "migrate", "wasm":
+		return true
+	}
+	return strings.HasPrefix(eventType, "wasm-")
+}
+
+// attributeValue returns the first value of the attribute, empty when the event does not have it
+func attributeValue(event txtypes.LogMessageEvent, key string) string {
+	for _, attribute := range event.Attributes {
+		if attribute.Key == key {
+			return attribute.Value
+		}
+	}
+	return ""
+}
+
+// attributesJSON encodes the attributes other than the contract address as a JSON object, the values of a key emitted
+// more than once as an array
+func attributesJSON(attributes []txtypes.Attribute) (string, error) {
+	values := make(map[string][]string)
+	for _, attribute := range attributes {
+		if attribute.Key == contractAddressKey {
+			continue
+		}
+		values[attribute.Key] = append(values[attribute.Key], attribute.Value)
+	}
+
+	object := make(map[string]any, len(values))
+	for key, keyValues := range values {
+		if len(keyValues) == 1 {
+			object[key] = keyValues[0]
+		} else {
+			object[key] = keyValues
+		}
+	}
+
+	encoded, err := json.Marshal(object)
+	return string(encoded), err
+}
@@ -0,0 +1,67 @@
+package wasm
+
+import (
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	txtypes "github.com/DefiantLabs/cosmos-indexer/cosmos/modules/tx"
+	"github.com/stretchr/testify/suite"
+)
+
+type WasmTestSuite struct {
+	suite.Suite
+}
+
+func instantiateLog() *txtypes.LogMessage {
+	return &txtypes.LogMessage{Events: []txtypes.LogMessageEvent{
+		{Type: "message", Attributes: []txtypes.Attribute{{Key: "module", Value: "wasm"}}},
+		{Type: "instantiate", Attributes: []txtypes.Attribute{{Key: "_contract_address", Value: "contract1"}, {Key: "code_id", Value: "42"}}},
+		{Type: "wasm", Attributes: []txtypes.Attribute{
+			{Key: "_contract_address", Value: "contract1"}, {Key: "action", Value: "mint"}, {Key: "amount", Value: "1"}, {Key: "amount", Value: "2"},
+		}},
+		{Type: "wasm-transfer", Attributes: []txtypes.Attribute{{Key: "_contract_address", Value: "contract2"}, {Key: "to", Value: "address1"}}},
+	}}
+}
+
+func (suite *WasmTestSuite) TestParseMessage() {
+	parser := MessageParsers()[MsgInstantiateContract]
+	data, err := parser.ParseMessage(nil, instantiateLog(), config.IndexConfig{})
+	suite.Require().NoError(err)
+
+	parsed, ok := (*data).(parsedMessage)
+	suite.Require().True(ok)
+	suite.Require().Equal([]parsedContract{{address: "contract1", codeID: 42}}, parsed.contracts)
+	suite.Require().Equal([]parsedEvent{
+		{index: 1, eventType: "instantiate", contract: "contract1", attributes: `{"code_id":"42"}`},
+		{index: 2, eventType: "wasm", contract: "contract1", action: "mint", attributes: `{"action":"mint","amount":["1","2"]}`},
+		{index: 3, eventType: "wasm-transfer", contract: "contract2", attributes: `{"to":"address1"}`},
+	}, parsed.events)
+}
+
+func (suite *WasmTestSuite) TestParseMessageAllowlist() {
+	var cfg config.IndexConfig
+	cfg.Wasm.Contracts = []string{"contract2"}
+
+	parser := MessageParsers()[MsgInstantiateContract]
+	data, err := parser.ParseMessage(nil, instantiateLog(), cfg)
+	suite.Require().NoError(err)
+
+	parsed, ok := (*data).(parsedMessage)
+	suite.Require().True(ok)
+	suite.Require().Empty(parsed.contracts)
+	suite.Require().Len(parsed.events, 1)
+	suite.Require().Equal("contract2", parsed.events[0].contract)
+}
+
+func (suite *WasmTestSuite) TestParseMessageInvalidCodeID() {
+	log := &txtypes.LogMessage{Events: []txtypes.LogMessageEvent{
+		{Type: "migrate", Attributes: []txtypes.Attribute{{Key: "_contract_address", Value: "contract1"}}},
+	}}
+
+	_, err := MessageParsers()[MsgMigrateContract].ParseMessage(nil, log, config.IndexConfig{})
+	suite.Require().Error(err)
+}
+
+func TestWasmTestSuite(t *testing.T) {
+	suite.Run(t, new(WasmTestSuite))
+}
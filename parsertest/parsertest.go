@@ -0,0 +1,287 @@
+// Package parsertest runs custom parsers over recorded blocks through the indexer's block processing and compares
+// what they parsed with golden JSON files, so parser regressions fail the tests of the repositories defining them.
+package parsertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/core"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/parsers"
+	"github.com/DefiantLabs/cosmos-indexer/rpc"
+	probeClient "github.com/DefiantLabs/probe/client"
+	"github.com/cosmos/cosmos-sdk/types/module"
+)
+
+// UpdateEnv is the environment variable that makes AssertGolden write the golden files instead of comparing with them
+const UpdateEnv = "PARSERTEST_UPDATE"
+
+type Options struct {
+	// Fixtures is a directory recorded with base.record-fixtures, the blocks and block results of the heights run are read from it
+	Fixtures string
+	// Client serves the blocks instead of Fixtures
+	Client rpc.Client
+	// AccountPrefix is the bech32 prefix of the chain, cosmos by default. The prefix is global to the SDK, so it can
+	// only be set once per test binary.
+	AccountPrefix string
+	// ModuleBasics extend the codec like cmd.RegisterCustomModuleBasics
+	ModuleBasics []module.AppModuleBasic
+	// Config is passed to the parsers
+	Config config.IndexConfig
+}
+
+// Harness holds the parsers run over the blocks, they are registered like in the cmd package
+type Harness struct {
+	t                 testing.TB
+	cfg               config.IndexConfig
+	codec             probeClient.Codec
+	client            rpc.Client
+	messageParsers    map[string][]parsers.MessageParser
+	beginBlockParsers map[string][]parsers.BlockEventParser
+	endBlockParsers   map[string][]parsers.BlockEventParser
+}
+
+var (
+	prefixOnce sync.Once
+	prefix     string
+)
+
+func New(t testing.TB, opts Options) *Harness {
+	t.Helper()
+
+	accountPrefix := opts.AccountPrefix
+	if accountPrefix == "" {
+		accountPrefix = "cosmos"
+	}
+	prefixOnce.Do(func() {
+		prefix = accountPrefix
+		config.SetChainConfig(accountPrefix)
+	})
+	if prefix != accountPrefix {
+		t.Fatalf("account prefix %s was already set to %s in this test binary", accountPrefix, prefix)
+	}
+
+	moduleBasics := append([]module.AppModuleBasic{}, probeClient.DefaultModuleBasics...)
+	h := &Harness{
+		t:                 t,
+		cfg:               opts.Config,
+		codec:             probeClient.MakeCodec(append(moduleBasics, opts.ModuleBasics...)),
+		client:            opts.Client,
+		messageParsers:    make(map[string][]parsers.MessageParser),
+		beginBlockParsers: make(map[string][]parsers.BlockEventParser),
+		endBlockParsers:   make(map[string][]parsers.BlockEventParser),
+	}
+	if h.client == nil {
+		if opts.Fixtures == "" {
+			t.Fatal("parsertest: one of Fixtures or Client must be set")
+		}
+		h.client = rpc.NewFixtureClient(opts.Fixtures, h.codec.InterfaceRegistry)
+	}
+
+	return h
+}
+
+// Codec is the codec the transactions are decoded with, e.g. to build transactions for a fake Client
+func (h *Harness) Codec() probeClient.Codec {
+	return h.codec
+}
+
+func (h *Harness) RegisterMessageParser(messageType string, parser parsers.MessageParser) {
+	h.messageParsers[messageType] = append(h.messageParsers[messageType], parser)
+}
+
+func (h *Harness) RegisterBeginBlockEventParser(eventType string, parser parsers.BlockEventParser) {
+	h.beginBlockParsers[eventType] = append(h.beginBlockParsers[eventType], parser)
+}
+
+func (h *Harness) RegisterEndBlockEventParser(eventType string, parser parsers.BlockEventParser) {
+	h.endBlockParsers[eventType] = append(h.endBlockParsers[eventType], parser)
+}
+
+// Output is what the parsers parsed in a block, in the order the indexer processes it
+type Output struct {
+	Height      int64              `json:"height"`
+	BlockEvents []BlockEventOutput `json:"block_events"`
+	Messages    []MessageOutput    `json:"messages"`
+}
+
+type BlockEventOutput struct {
+	LifecyclePosition string `json:"lifecycle_position"`
+	EventIndex        uint64 `json:"event_index"`
+	EventType         string `json:"event_type"`
+	Parser            string `json:"parser"`
+	Data              any    `json:"data,omitempty"`
+	Error             string `json:"error,omitempty"`
+}
+
+type MessageOutput struct {
+	TxHash       string `json:"tx_hash"`
+	MessageIndex int    `json:"message_index"`
+	MessageType  string `json:"message_type"`
+	Parser       string `json:"parser"`
+	Data         any    `json:"data,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Run processes the blocks at heights and returns the data the registered parsers parsed, like the index command
+// does before writing to the database. Parser errors are part of the output, processing errors fail the test.
+func (h *Harness) Run(heights ...int64) []Output {
+	h.t.Helper()
+
+	outputs := make([]Output, 0, len(heights))
+	for _, height := range heights {
+		outputs = append(outputs, h.runBlock(height))
+	}
+	return outputs
+}
+
+func (h *Harness) runBlock(height int64) Output {
+	h.t.Helper()
+
+	blockData, err := rpc.GetBlock(context.Background(), h.client, height)
+	if err != nil {
+		h.t.Fatalf("parsertest: block %d: %v", height, err)
+	}
+	blockResults, err := rpc.GetBlockResult(context.Background(), h.client, height)
+	if err != nil {
+		h.t.Fatalf("parsertest: block results %d: %v", height, err)
+	}
+
+	block, err := core.ProcessBlock(blockData, blockResults, 0)
+	if err != nil {
+		h.t.Fatalf("parsertest: processing block %d: %v", height, err)
+	}
+	blockContext := parsers.NewBlockContext(nil, blockData)
+
+	output := Output{Height: height, BlockEvents: []BlockEventOutput{}, Messages: []MessageOutput{}}
+
+	blockDBWrapper, err := core.ProcessRPCBlockResults(h.cfg, block, blockResults, h.beginBlockParsers, h.endBlockParsers, blockContext)
+	if err != nil {
+		h.t.Fatalf("parsertest: processing the block events of block %d: %v", height, err)
+	}
+	for _, events := range [][]dbTypes.BlockEventDBWrapper{blockDBWrapper.BeginBlockEvents, blockDBWrapper.EndBlockEvents} {
+		for _, event := range events {
+			for _, parsed := range event.BlockEventParsedDatasets {
+				output.BlockEvents = append(output.BlockEvents, BlockEventOutput{
+					LifecyclePosition: lifecyclePosition(event.BlockEvent.LifecyclePosition),
+					EventIndex:        event.BlockEvent.Index,
+					EventType:         event.BlockEvent.BlockEventType.Type,
+					Parser:            (*parsed.Parser).Identifier(),
+					Data:              h.data(parsed.Data),
+					Error:             errorString(parsed.Error),
+				})
+			}
+		}
+	}
+
+	txDBWrappers, _, _, err := core.ProcessRPCBlockByHeightTXs(&h.cfg, nil, h.codec, nil, blockData, blockResults, h.messageParsers, blockContext)
+	if err != nil {
+		h.t.Fatalf("parsertest: processing the transactions of block %d: %v", height, err)
+	}
+	for _, tx := range txDBWrappers {
+		for _, message := range tx.Messages {
+			for _, parsed := range message.MessageParsedDatasets {
+				output.Messages = append(output.Messages, MessageOutput{
+					TxHash:       tx.Tx.Hash,
+					MessageIndex: message.Message.MessageIndex,
+					MessageType:  message.Message.MessageType.MessageType,
+					Parser:       (*parsed.Parser).Identifier(),
+					Data:         h.data(parsed.Data),
+					Error:        errorString(parsed.Error),
+				})
+			}
+		}
+	}
+
+	return output
+}
+
+// data returns parsed data as its JSON, so the outputs compare like the golden files they are read from
+func (h *Harness) data(data *any) any {
+	if data == nil || *data == nil {
+		return nil
+	}
+
+	encoded, err := json.Marshal(*data)
+	if err != nil {
+		h.t.Fatalf("parsertest: parsed data cannot be encoded as JSON: %v", err)
+	}
+	var decoded any
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		h.t.Fatalf("parsertest: parsed data cannot be decoded from JSON: %v", err)
+	}
+	return decoded
+}
+
+func lifecyclePosition(position models.BlockLifecyclePosition) string {
+	if position == models.EndBlockEvent {
+		return "end_block"
+	}
+	return "begin_block"
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// AssertGolden runs the blocks at heights and compares the output with the golden file testdata/<name>.golden.json.
+// With PARSERTEST_UPDATE=1 set the golden file is written instead.
+func (h *Harness) AssertGolden(name string, heights ...int64) {
+	h.t.Helper()
+
+	actual, err := json.MarshalIndent(h.Run(heights...), "", "  ")
+	if err != nil {
+		h.t.Fatalf("parsertest: encoding the output: %v", err)
+	}
+	actual = append(actual, '\n')
+
+	path := filepath.Join("testdata", name+".golden.json")
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			h.t.Fatalf("parsertest: %v", err)
+		}
+		if err := os.WriteFile(path, actual, 0o600); err != nil {
+			h.t.Fatalf("parsertest: writing golden file: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if err != nil {
+		h.t.Fatalf("parsertest: reading golden file, run with %s=1 to create it: %v", UpdateEnv, err)
+	}
+	if !bytes.Equal(expected, actual) {
+		h.t.Errorf("parsertest: output of %s differs from %s, run with %s=1 to update it:\n%s", name, path, UpdateEnv, diff(string(expected), string(actual)))
+	}
+}
+
+// diff lists the lines of the first difference between the expected and actual output
+func diff(expected, actual string) string {
+	expectedLines, actualLines := strings.Split(expected, "\n"), strings.Split(actual, "\n")
+	for i := 0; i < len(expectedLines) || i < len(actualLines); i++ {
+		var expectedLine, actualLine string
+		if i < len(expectedLines) {
+			expectedLine = expectedLines[i]
+		}
+		if i < len(actualLines) {
+			actualLine = actualLines[i]
+		}
+		if expectedLine != actualLine {
+			return fmt.Sprintf("line %d:\n- %s\n+ %s", i+1, expectedLine, actualLine)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,143 @@
+package parsertest
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	txtypes "github.com/DefiantLabs/cosmos-indexer/cosmos/modules/tx"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/DefiantLabs/cosmos-indexer/parsers"
+	abci "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	sdkTypes "github.com/cosmos/cosmos-sdk/types"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+	bankTypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+)
+
+// blockClient serves blocks and block results from memory
+type blockClient struct {
+	blocks       map[int64]*coretypes.ResultBlock
+	blockResults map[int64]*coretypes.ResultBlockResults
+}
+
+func (c *blockClient) Status(ctx context.Context) (*coretypes.ResultStatus, error) {
+	return &coretypes.ResultStatus{}, nil
+}
+
+func (c *blockClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	if block, ok := c.blocks[height]; ok {
+		return block, nil
+	}
+	return nil, errors.New("block not found")
+}
+
+func (c *blockClient) BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error) {
+	if blockResults, ok := c.blockResults[height]; ok {
+		return blockResults, nil
+	}
+	return nil, errors.New("block results not found")
+}
+
+func (c *blockClient) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+type sendParser struct{}
+
+func (p *sendParser) Identifier() string {
+	return "send"
+}
+
+func (p *sendParser) ParseMessage(msg sdkTypes.Msg, log *txtypes.LogMessage, cfg config.IndexConfig) (*any, error) {
+	send := msg.(*bankTypes.MsgSend)
+	if send.Amount.IsZero() {
+		return nil, errors.New("empty send")
+	}
+
+	var data any = map[string]string{"from": send.FromAddress, "to": send.ToAddress, "amount": send.Amount.String()}
+	return &data, nil
+}
+
+func (p *sendParser) IndexMessage(*any, *gorm.DB, models.Message, []parsers.MessageEventWithAttributes, config.IndexConfig) error {
+	return nil
+}
+
+type transferParser struct{}
+
+func (p *transferParser) Identifier() string {
+	return "transfer"
+}
+
+func (p *transferParser) ParseBlockEvent(event abci.Event, cfg config.IndexConfig) (*any, error) {
+	var data any = map[string]int{"attributes": len(event.Attributes)}
+	return &data, nil
+}
+
+func (p *transferParser) IndexBlockEvent(*any, *gorm.DB, models.Block, models.BlockEvent, []models.BlockEventAttribute, config.IndexConfig) error {
+	return nil
+}
+
+type ParserTestSuite struct {
+	suite.Suite
+}
+
+// attribute encodes a block event attribute like the RPC servers of the chains the indexer supports
+func attribute(key, value string) abci.EventAttribute {
+	return abci.EventAttribute{Key: base64.StdEncoding.EncodeToString([]byte(key)), Value: base64.StdEncoding.EncodeToString([]byte(value))}
+}
+
+func (suite *ParserTestSuite) encodeSend(h *Harness, amount sdkTypes.Coins) cmttypes.Tx {
+	from := sdkTypes.AccAddress(make([]byte, 20))
+	to := sdkTypes.AccAddress(append(make([]byte, 19), 1))
+
+	builder := h.Codec().TxConfig.NewTxBuilder()
+	suite.Require().NoError(builder.SetMsgs(bankTypes.NewMsgSend(from, to, amount)))
+	tx, err := h.Codec().TxConfig.TxEncoder()(builder.GetTx())
+	suite.Require().NoError(err)
+	return tx
+}
+
+func (suite *ParserTestSuite) TestGolden() {
+	client := &blockClient{blocks: map[int64]*coretypes.ResultBlock{}, blockResults: map[int64]*coretypes.ResultBlockResults{}}
+	h := New(suite.T(), Options{Client: client})
+	h.RegisterMessageParser("/cosmos.bank.v1beta1.MsgSend", &sendParser{})
+	h.RegisterBeginBlockEventParser("transfer", &transferParser{})
+
+	txs := cmttypes.Txs{
+		suite.encodeSend(h, sdkTypes.NewCoins(sdkTypes.NewInt64Coin("stake", 10))),
+		suite.encodeSend(h, sdkTypes.NewCoins()),
+	}
+	messageLog := `[{"msg_index":0,"events":[{"type":"message","attributes":[{"key":"module","value":"bank"}]}]}]`
+	client.blocks[5] = &coretypes.ResultBlock{Block: &cmttypes.Block{
+		Header: cmttypes.Header{ChainID: "test-1", Height: 5, Time: time.Unix(1700000000, 0).UTC(), ProposerAddress: make([]byte, 20)},
+		Data:   cmttypes.Data{Txs: txs},
+	}}
+	client.blockResults[5] = &coretypes.ResultBlockResults{
+		Height:     5,
+		TxsResults: []*abci.ResponseDeliverTx{{Log: messageLog}, {Log: messageLog}},
+		BeginBlockEvents: []abci.Event{
+			{Type: "mint", Attributes: []abci.EventAttribute{attribute("amount", "1")}},
+			{Type: "transfer", Attributes: []abci.EventAttribute{attribute("recipient", "cosmos1"), attribute("amount", "1stake")}},
+		},
+	}
+
+	outputs := h.Run(5)
+	suite.Require().Len(outputs, 1)
+	suite.Require().Len(outputs[0].BlockEvents, 1)
+	suite.Require().Equal(uint64(1), outputs[0].BlockEvents[0].EventIndex)
+	suite.Require().Len(outputs[0].Messages, 2)
+	suite.Require().Equal("empty send", outputs[0].Messages[1].Error)
+
+	h.AssertGolden("bank", 5)
+}
+
+func TestParserTestSuite(t *testing.T) {
+	suite.Run(t, new(ParserTestSuite))
+}
@@ -0,0 +1,58 @@
+package prices
+
+import (
+	"regexp"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/shopspring/decimal"
+)
+
+// defaultRule names the classification of flows no rule matched
+const defaultRule = "default"
+
+// defaultClassifications classify the flows no rule matched by their kind
+var defaultClassifications = map[models.FlowKind]models.Classification{
+	models.FlowFee:      models.ClassificationFee,
+	models.FlowReward:   models.ClassificationIncome,
+	models.FlowTransfer: models.ClassificationTransfer,
+}
+
+// Classifier classifies flows as taxable events by the rules of the rules file, in order
+type Classifier struct {
+	rules []config.ClassificationRule
+}
+
+func NewClassifier(rules []config.ClassificationRule) *Classifier {
+	return &Classifier{rules: rules}
+}
+
+// Classify returns the classification of a flow valued at value and the name of the rule that classified it
+func (c *Classifier) Classify(flow dbTypes.Flow, value decimal.NullDecimal) (models.Classification, string) {
+	for _, rule := range c.rules {
+		if ruleMatches(rule, flow, value) {
+			return models.Classification(rule.Classification), rule.Name
+		}
+	}
+	return defaultClassifications[flow.Kind], defaultRule
+}
+
+func ruleMatches(rule config.ClassificationRule, flow dbTypes.Flow, value decimal.NullDecimal) bool {
+	if rule.Kind != "" && rule.Kind != string(flow.Kind) {
+		return false
+	}
+	if !patternMatches(rule.MessageTypePattern, flow.MessageType) ||
+		!patternMatches(rule.EventTypePattern, flow.EventType) ||
+		!patternMatches(rule.DenomPattern, flow.Denom) {
+		return false
+	}
+	if rule.MaxValueUSD != nil && (!value.Valid || value.Decimal.GreaterThan(*rule.MaxValueUSD)) {
+		return false
+	}
+	return true
+}
+
+func patternMatches(pattern *regexp.Regexp, value string) bool {
+	return pattern == nil || pattern.MatchString(value)
+}
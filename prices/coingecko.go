@@ -0,0 +1,125 @@
+package prices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const coinGeckoURL = "https://api.coingecko.com/api/v3"
+
+// CoinGeckoSource looks up the daily USD prices of the coins mapped to the denoms, e.g. cosmos for uatom. CoinGecko has
+// one historical price per coin and day, which is cached, so a day of blocks takes a single request per coin.
+type CoinGeckoSource struct {
+	url    string
+	apiKey string
+	ids    map[string]string
+	client *http.Client
+
+	mu    sync.Mutex
+	cache map[string]decimal.NullDecimal
+}
+
+// NewCoinGeckoSource creates a source querying the API at baseURL, the public API when empty. Keys of the Pro API are
+// sent when the base URL is the Pro API, keys of the demo plan otherwise.
+func NewCoinGeckoSource(baseURL string, apiKey string, ids map[string]string) *CoinGeckoSource {
+	if baseURL == "" {
+		baseURL = coinGeckoURL
+	}
+	return &CoinGeckoSource{
+		url:    strings.TrimSuffix(baseURL, "/"),
+		apiKey: apiKey,
+		ids:    ids,
+		client: &http.Client{Timeout: 30 * time.Second},
+		cache:  map[string]decimal.NullDecimal{},
+	}
+}
+
+func (s *CoinGeckoSource) Name() string {
+	return "coingecko"
+}
+
+func (s *CoinGeckoSource) Price(ctx context.Context, denom string, height int64, at time.Time) (decimal.Decimal, error) {
+	id, ok := s.ids[denom]
+	if !ok {
+		return decimal.Decimal{}, ErrNoPrice
+	}
+
+	date := at.UTC().Format("02-01-2006")
+	key := id + "/" + date
+
+	s.mu.Lock()
+	cached, ok := s.cache[key]
+	s.mu.Unlock()
+	if !ok {
+		var err error
+		cached, err = s.fetch(ctx, id, date)
+		if err != nil {
+			return decimal.Decimal{}, err
+		}
+		s.mu.Lock()
+		s.cache[key] = cached
+		s.mu.Unlock()
+	}
+
+	if !cached.Valid {
+		return decimal.Decimal{}, ErrNoPrice
+	}
+	return cached.Decimal, nil
+}
+
+// fetch queries the price of a coin on a date, coins without market data on the date have no price
+func (s *CoinGeckoSource) fetch(ctx context.Context, id string, date string) (decimal.NullDecimal, error) {
+	endpoint := fmt.Sprintf("%s/coins/%s/history?date=%s&localization=false", s.url, url.PathEscape(id), date)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return decimal.NullDecimal{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if s.apiKey != "" {
+		header := "x-cg-demo-api-key"
+		if strings.Contains(s.url, "pro-api") {
+			header = "x-cg-pro-api-key"
+		}
+		req.Header.Set(header, s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return decimal.NullDecimal{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return decimal.NullDecimal{}, fmt.Errorf("price of %s on %s: unexpected status %s", id, date, resp.Status)
+	}
+
+	var history struct {
+		MarketData *struct {
+			CurrentPrice map[string]json.Number `json:"current_price"`
+		} `json:"market_data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&history); err != nil {
+		return decimal.NullDecimal{}, fmt.Errorf("price of %s on %s: %w", id, date, err)
+	}
+	if history.MarketData == nil {
+		return decimal.NullDecimal{}, nil
+	}
+	usd, ok := history.MarketData.CurrentPrice["usd"]
+	if !ok {
+		return decimal.NullDecimal{}, nil
+	}
+
+	price, err := decimal.NewFromString(usd.String())
+	if err != nil {
+		return decimal.NullDecimal{}, fmt.Errorf("price of %s on %s: %w", id, date, err)
+	}
+	return decimal.NewNullDecimal(price), nil
+}
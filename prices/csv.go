@@ -0,0 +1,93 @@
+package prices
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type csvPrice struct {
+	at    time.Time
+	price decimal.Decimal
+}
+
+// CSVSource serves fixed prices from a CSV file with the columns denom, time and price_usd. The time is RFC 3339 or a
+// date, and a price holds from its time until the next price of the denom, so a file can hold daily closes as well as
+// a single price per denom.
+type CSVSource struct {
+	prices map[string][]csvPrice
+}
+
+func NewCSVSource(path string) (*CSVSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseCSV(f)
+}
+
+func ParseCSV(r io.Reader) (*CSVSource, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the price CSV header: %w", err)
+	}
+	if header[0] != "denom" || header[1] != "time" || header[2] != "price_usd" {
+		return nil, errors.New("the price CSV must have the columns denom, time and price_usd")
+	}
+
+	source := &CSVSource{prices: map[string][]csvPrice{}}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the price CSV: %w", err)
+		}
+
+		at, err := time.Parse(time.RFC3339, record[1])
+		if err != nil {
+			at, err = time.Parse("2006-01-02", record[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid time %s of denom %s in the price CSV", record[1], record[0])
+			}
+		}
+		price, err := decimal.NewFromString(record[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid price %s of denom %s in the price CSV", record[2], record[0])
+		}
+
+		source.prices[record[0]] = append(source.prices[record[0]], csvPrice{at: at, price: price})
+	}
+
+	for _, prices := range source.prices {
+		sort.Slice(prices, func(i, j int) bool { return prices[i].at.Before(prices[j].at) })
+	}
+	return source, nil
+}
+
+func (s *CSVSource) Name() string {
+	return "csv"
+}
+
+// Price returns the latest price of the denom at or before the time
+func (s *CSVSource) Price(ctx context.Context, denom string, height int64, at time.Time) (decimal.Decimal, error) {
+	prices := s.prices[denom]
+	i := sort.Search(len(prices), func(i int) bool { return prices[i].at.After(at) })
+	if i == 0 {
+		return decimal.Decimal{}, ErrNoPrice
+	}
+	return prices[i-1].price, nil
+}
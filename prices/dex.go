@@ -0,0 +1,230 @@
+package prices
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Swap is a swap of Amount of Denom for QuoteAmount of QuoteDenom made in a pool, both amounts in base units
+type Swap struct {
+	MessageEventID uint
+	Pool           string
+	Denom          string
+	Amount         decimal.Decimal
+	QuoteDenom     string
+	QuoteAmount    decimal.Decimal
+}
+
+var swapCoinPattern = regexp.MustCompile(`^([0-9]+)([a-zA-Z][a-zA-Z0-9/:._-]*)$`)
+
+// ParseSwaps parses the swaps of the attributes of swap events, ordered by event and attribute index:
+//
+//   - token_swapped events of Osmosis with pool_id, tokens_in and tokens_out
+//   - wasm events of pair contracts with action swap, offer_asset, ask_asset, offer_amount and return_amount, which
+//     Astroport and the DEXes forked from it emit
+//
+// Older SDK versions merged the events of a type emitted by a message into one event, so an attribute seen again
+// starts the next swap. Swaps of a zero amount have no price and are skipped.
+func ParseSwaps(attributes []dbTypes.EventAttribute) []Swap {
+	var swaps []Swap
+	var current map[string]string
+	var currentEvent uint
+	var currentType string
+
+	flush := func() {
+		if swap, ok := parseSwap(currentEvent, currentType, current); ok {
+			swaps = append(swaps, swap)
+		}
+	}
+
+	for _, attribute := range attributes {
+		_, repeated := current[attribute.Key]
+		if current == nil || attribute.EventID != currentEvent || repeated {
+			flush()
+			current, currentEvent, currentType = map[string]string{}, attribute.EventID, attribute.Type
+		}
+		current[attribute.Key] = attribute.Value
+	}
+	flush()
+
+	return swaps
+}
+
+func parseSwap(eventID uint, eventType string, attributes map[string]string) (Swap, bool) {
+	swap := Swap{MessageEventID: eventID}
+
+	switch eventType {
+	case "token_swapped":
+		in := swapCoinPattern.FindStringSubmatch(strings.TrimSpace(attributes["tokens_in"]))
+		out := swapCoinPattern.FindStringSubmatch(strings.TrimSpace(attributes["tokens_out"]))
+		if in == nil || out == nil {
+			return Swap{}, false
+		}
+		swap.Pool = attributes["pool_id"]
+		swap.Denom, swap.QuoteDenom = in[2], out[2]
+		swap.Amount, _ = decimal.NewFromString(in[1])
+		swap.QuoteAmount, _ = decimal.NewFromString(out[1])
+	case "wasm":
+		if attributes["action"] != "swap" || attributes["offer_asset"] == "" || attributes["ask_asset"] == "" {
+			return Swap{}, false
+		}
+		var err error
+		swap.Pool = attributes["_contract_address"]
+		swap.Denom, swap.QuoteDenom = attributes["offer_asset"], attributes["ask_asset"]
+		if swap.Amount, err = decimal.NewFromString(attributes["offer_amount"]); err != nil {
+			return Swap{}, false
+		}
+		if swap.QuoteAmount, err = decimal.NewFromString(attributes["return_amount"]); err != nil {
+			return Swap{}, false
+		}
+	default:
+		return Swap{}, false
+	}
+
+	if !swap.Amount.IsPositive() || !swap.QuoteAmount.IsPositive() || swap.Denom == swap.QuoteDenom {
+		return Swap{}, false
+	}
+	return swap, true
+}
+
+// DeriveDexPrices replaces the swap prices of the block with the rates of its swaps and returns how many there were
+func DeriveDexPrices(db *gorm.DB, block *models.Block) (int, error) {
+	attributes, err := dbTypes.BlockSwapAttributes(db, block.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	swaps := ParseSwaps(attributes)
+	dexPrices := make([]models.DexPrice, 0, len(swaps))
+	for _, swap := range swaps {
+		denom, err := dbTypes.FindOrCreateDenomByBase(db, swap.Denom)
+		if err != nil {
+			return 0, err
+		}
+		quoteDenom, err := dbTypes.FindOrCreateDenomByBase(db, swap.QuoteDenom)
+		if err != nil {
+			return 0, err
+		}
+
+		dexPrices = append(dexPrices, models.DexPrice{
+			BlockID:        block.ID,
+			MessageEventID: swap.MessageEventID,
+			Time:           block.TimeStamp,
+			DenomID:        denom.ID,
+			QuoteDenomID:   quoteDenom.ID,
+			Pool:           swap.Pool,
+			Price:          swap.QuoteAmount.DivRound(swap.Amount, 36),
+		})
+	}
+
+	return len(dexPrices), dbTypes.StoreDexPrices(db, block, dexPrices)
+}
+
+// DexSource prices denoms by the rates of the swaps derived from the indexed DEX events, of any chain indexed in the
+// database. A denom is priced by its latest swap against a USD stablecoin in the window before the time, or else by
+// its latest swap against another denom that has such a swap itself. Stablecoins are taken to be worth a dollar.
+type DexSource struct {
+	db          *gorm.DB
+	window      time.Duration
+	stablecoins map[uint]string
+	exponents   map[string]uint32
+}
+
+// NewDexSource creates a source quoting the denoms against the stablecoins, by the swaps made in the window before the
+// time of a block
+func NewDexSource(db *gorm.DB, stablecoins []string, window time.Duration) (*DexSource, error) {
+	exponents, err := dbTypes.DenomExponents(db)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &DexSource{db: db, window: window, stablecoins: map[uint]string{}, exponents: exponents}
+	for _, stablecoin := range stablecoins {
+		denom, err := dbTypes.FindOrCreateDenomByBase(db, stablecoin)
+		if err != nil {
+			return nil, err
+		}
+		source.stablecoins[denom.ID] = stablecoin
+	}
+	return source, nil
+}
+
+func (s *DexSource) Name() string {
+	return "dex"
+}
+
+func (s *DexSource) Price(ctx context.Context, denom string, height int64, at time.Time) (decimal.Decimal, error) {
+	exponent, ok := s.exponents[denom]
+	if !ok {
+		return decimal.Decimal{}, ErrNoPrice
+	}
+
+	db := s.db.WithContext(ctx)
+	denomRow, err := dbTypes.FindOrCreateDenomByBase(db, denom)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	if _, ok := s.stablecoins[denomRow.ID]; ok {
+		return decimal.NewFromInt(1), nil
+	}
+
+	from := at.Add(-s.window)
+	stablecoinIDs := make([]uint, 0, len(s.stablecoins))
+	for id := range s.stablecoins {
+		stablecoinIDs = append(stablecoinIDs, id)
+	}
+
+	usdPerBaseUnit, found, err := s.usdPerBaseUnit(db, denomRow.ID, stablecoinIDs, from, at)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	if !found {
+		// One hop through the denom it was last swapped against
+		rate, other, swapped, err := dbTypes.LatestDexRate(db, denomRow.ID, nil, from, at)
+		if err != nil || !swapped {
+			return decimal.Decimal{}, orNoPrice(err)
+		}
+		otherUSD, found, err := s.usdPerBaseUnit(db, other, stablecoinIDs, from, at)
+		if err != nil || !found {
+			return decimal.Decimal{}, orNoPrice(err)
+		}
+		usdPerBaseUnit = rate.Mul(otherUSD)
+	}
+
+	return usdPerBaseUnit.Shift(int32(exponent)), nil
+}
+
+// usdPerBaseUnit returns the USD price of a base unit of the denom by its latest swap against a stablecoin
+func (s *DexSource) usdPerBaseUnit(db *gorm.DB, denomID uint, stablecoinIDs []uint, from time.Time, to time.Time) (decimal.Decimal, bool, error) {
+	if stablecoin, ok := s.stablecoins[denomID]; ok {
+		exponent, ok := s.exponents[stablecoin]
+		return decimal.New(1, -int32(exponent)), ok, nil
+	}
+	if len(stablecoinIDs) == 0 {
+		return decimal.Decimal{}, false, nil
+	}
+
+	rate, stablecoinID, found, err := dbTypes.LatestDexRate(db, denomID, stablecoinIDs, from, to)
+	if err != nil || !found {
+		return decimal.Decimal{}, false, err
+	}
+	exponent, ok := s.exponents[s.stablecoins[stablecoinID]]
+	if !ok {
+		return decimal.Decimal{}, false, nil
+	}
+	return rate.Shift(-int32(exponent)), true, nil
+}
+
+func orNoPrice(err error) error {
+	if err != nil {
+		return err
+	}
+	return ErrNoPrice
+}
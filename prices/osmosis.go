@@ -0,0 +1,80 @@
+package prices
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/shopspring/decimal"
+)
+
+// OsmosisPoolSource prices denoms by the spot price of an Osmosis pool pairing them with a USD stablecoin, queried from
+// the REST API of an Osmosis node at the height of the block. It prices the blocks of Osmosis itself, the heights of
+// other chains don't match the pool history, and needs an archive node to value old blocks.
+type OsmosisPoolSource struct {
+	url    string
+	pools  map[string]config.OsmosisPool
+	client *http.Client
+}
+
+func NewOsmosisPoolSource(restURL string, pools []config.OsmosisPool) *OsmosisPoolSource {
+	source := &OsmosisPoolSource{
+		url:    strings.TrimSuffix(restURL, "/"),
+		pools:  make(map[string]config.OsmosisPool, len(pools)),
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, pool := range pools {
+		source.pools[pool.Denom] = pool
+	}
+	return source
+}
+
+func (s *OsmosisPoolSource) Name() string {
+	return "osmosis"
+}
+
+func (s *OsmosisPoolSource) Price(ctx context.Context, denom string, height int64, at time.Time) (decimal.Decimal, error) {
+	pool, ok := s.pools[denom]
+	if !ok {
+		return decimal.Decimal{}, ErrNoPrice
+	}
+
+	endpoint := fmt.Sprintf("%s/osmosis/poolmanager/v1beta1/pools/%d/prices?base_asset_denom=%s&quote_asset_denom=%s",
+		s.url, pool.PoolID, url.QueryEscape(pool.Denom), url.QueryEscape(pool.QuoteDenom))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	req.Header.Set("x-cosmos-block-height", strconv.FormatInt(height, 10))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return decimal.Decimal{}, fmt.Errorf("spot price of pool %d at height %d: unexpected status %s", pool.PoolID, height, resp.Status)
+	}
+
+	var spot struct {
+		SpotPrice string `json:"spot_price"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&spot); err != nil {
+		return decimal.Decimal{}, fmt.Errorf("spot price of pool %d at height %d: %w", pool.PoolID, height, err)
+	}
+	price, err := decimal.NewFromString(spot.SpotPrice)
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("spot price of pool %d at height %d: %w", pool.PoolID, height, err)
+	}
+
+	// The spot price is in base units of the quote per base unit of the denom, one display unit of the stablecoin
+	// is a dollar
+	return price.Shift(int32(pool.BaseExponent) - int32(pool.QuoteExponent)), nil
+}
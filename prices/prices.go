@@ -0,0 +1,73 @@
+// Package prices looks up the USD prices of denoms, values the flows of indexed blocks with them and classifies the
+// flows as taxable events
+package prices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ErrNoPrice is returned by sources that do not cover a denom at a time
+var ErrNoPrice = errors.New("no price")
+
+// Source looks up the USD price of one display unit of a denom, e.g. of one atom for uatom
+type Source interface {
+	// Name identifies the source in the stored valuations
+	Name() string
+	// Price returns the price at the time of the block at height, ErrNoPrice when the source does not cover the denom
+	// at that time
+	Price(ctx context.Context, denom string, height int64, at time.Time) (decimal.Decimal, error)
+}
+
+// Sources asks the sources in order and answers with the first price found
+type Sources []Source
+
+// Price returns the price and the name of the source it was found in
+func (s Sources) Price(ctx context.Context, denom string, height int64, at time.Time) (decimal.Decimal, string, error) {
+	for _, source := range s {
+		price, err := source.Price(ctx, denom, height, at)
+		if errors.Is(err, ErrNoPrice) {
+			continue
+		}
+		if err != nil {
+			return decimal.Decimal{}, "", fmt.Errorf("%s: %w", source.Name(), err)
+		}
+		return price, source.Name(), nil
+	}
+	return decimal.Decimal{}, "", ErrNoPrice
+}
+
+// NewSources creates the sources declared in the price sources file, in their order of precedence. dex sources read
+// the swap prices from the database.
+func NewSources(db *gorm.DB, declared []config.PriceSource) (Sources, error) {
+	sources := make(Sources, 0, len(declared))
+	for _, source := range declared {
+		switch source.Type {
+		case config.PriceSourceCSV:
+			csvSource, err := NewCSVSource(source.File)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, csvSource)
+		case config.PriceSourceCoinGecko:
+			sources = append(sources, NewCoinGeckoSource(source.URL, source.APIKey, source.IDs))
+		case config.PriceSourceOsmosis:
+			sources = append(sources, NewOsmosisPoolSource(source.URL, source.Pools))
+		case config.PriceSourceDex:
+			dexSource, err := NewDexSource(db, source.Stablecoins, source.Window())
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, dexSource)
+		default:
+			return nil, fmt.Errorf("unknown price source type %s", source.Type)
+		}
+	}
+	return sources, nil
+}
@@ -0,0 +1,195 @@
+package prices
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/suite"
+)
+
+type PricesTestSuite struct {
+	suite.Suite
+}
+
+func (suite *PricesTestSuite) TestCSVSource() {
+	source, err := ParseCSV(strings.NewReader("denom,time,price_usd\nuatom,2024-01-02,10.5\nuatom,2024-01-01,9\nuosmo,2024-01-01T12:00:00Z,0.8\n"))
+	suite.Require().NoError(err)
+
+	price, err := source.Price(context.Background(), "uatom", 1, time.Date(2024, 1, 1, 18, 0, 0, 0, time.UTC))
+	suite.Require().NoError(err)
+	suite.Require().Equal("9", price.String())
+
+	price, err = source.Price(context.Background(), "uatom", 1, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))
+	suite.Require().NoError(err)
+	suite.Require().Equal("10.5", price.String())
+
+	// Before the first price and for unknown denoms there is no price
+	_, err = source.Price(context.Background(), "uosmo", 1, time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC))
+	suite.Require().ErrorIs(err, ErrNoPrice)
+	_, err = source.Price(context.Background(), "ujuno", 1, time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC))
+	suite.Require().ErrorIs(err, ErrNoPrice)
+
+	_, err = ParseCSV(strings.NewReader("denom,price\nuatom,1\n"))
+	suite.Require().Error(err)
+	_, err = ParseCSV(strings.NewReader("denom,time,price_usd\nuatom,yesterday,1\n"))
+	suite.Require().Error(err)
+}
+
+type fixedSource struct {
+	name   string
+	prices map[string]decimal.Decimal
+}
+
+func (s fixedSource) Name() string { return s.name }
+
+func (s fixedSource) Price(ctx context.Context, denom string, height int64, at time.Time) (decimal.Decimal, error) {
+	price, ok := s.prices[denom]
+	if !ok {
+		return decimal.Decimal{}, ErrNoPrice
+	}
+	return price, nil
+}
+
+func (suite *PricesTestSuite) TestSourcesPrecedence() {
+	sources := Sources{
+		fixedSource{name: "first", prices: map[string]decimal.Decimal{"uatom": decimal.NewFromInt(10)}},
+		fixedSource{name: "second", prices: map[string]decimal.Decimal{"uatom": decimal.NewFromInt(11), "uosmo": decimal.NewFromInt(1)}},
+	}
+
+	price, source, err := sources.Price(context.Background(), "uatom", 1, time.Now())
+	suite.Require().NoError(err)
+	suite.Require().Equal("first", source)
+	suite.Require().True(price.Equal(decimal.NewFromInt(10)))
+
+	_, source, err = sources.Price(context.Background(), "uosmo", 1, time.Now())
+	suite.Require().NoError(err)
+	suite.Require().Equal("second", source)
+
+	_, _, err = sources.Price(context.Background(), "ujuno", 1, time.Now())
+	suite.Require().ErrorIs(err, ErrNoPrice)
+}
+
+func (suite *PricesTestSuite) TestNormalizeFee() {
+	valuer := &Valuer{
+		sources:     Sources{fixedSource{name: "fixed", prices: map[string]decimal.Decimal{"uatom": decimal.NewFromInt(10), "uosmo": decimal.NewFromInt(1)}}},
+		exponents:   map[string]uint32{"uatom": 6, "uosmo": 6},
+		traces:      map[string]config.DenomTrace{"ibc/ABCD": {Denom: "ibc/ABCD", Path: "transfer/channel-0", BaseDenom: "uosmo"}},
+		reference:   "uatom",
+		referenceID: 1,
+	}
+	block := &models.Block{ID: 7, Height: 1, TimeStamp: time.Now()}
+	quoteOf := func(denom string) (quote, error) {
+		return valuer.quote(context.Background(), denom, block)
+	}
+	normalize := func(denom string, amount int64) models.NormalizedFee {
+		feeID := uint(1)
+		fee, err := valuer.normalizeFee(block, dbTypes.Flow{Kind: models.FlowFee, FeeID: &feeID, Denom: denom, Amount: decimal.NewFromInt(amount)}, quoteOf)
+		suite.Require().NoError(err)
+		return fee
+	}
+
+	// 1 osmo at $1 is 0.1 atom at $10
+	fee := normalize("uosmo", 1000000)
+	suite.Require().Equal("uosmo", fee.BaseDenom)
+	suite.Require().Equal("100000", fee.ReferenceAmount.Decimal.String())
+	suite.Require().Equal("fixed", fee.PriceSource)
+
+	// The voucher has no price of its own and is priced as the denom it was issued as
+	fee = normalize("ibc/ABCD", 500)
+	suite.Require().Equal("uosmo", fee.BaseDenom)
+	suite.Require().Equal("transfer/channel-0", fee.TracePath)
+	suite.Require().Equal("50", fee.ReferenceAmount.Decimal.String())
+
+	fee = normalize("uatom", 7)
+	suite.Require().Equal("7", fee.ReferenceAmount.Decimal.String())
+	suite.Require().Empty(fee.PriceSource)
+
+	fee = normalize("ujuno", 7)
+	suite.Require().False(fee.ReferenceAmount.Valid)
+	suite.Require().Equal(uint(1), *fee.ReferenceDenomID)
+
+	// Without a reference denom the fees are only normalized
+	valuer.reference = ""
+	fee = normalize("ibc/ABCD", 500)
+	suite.Require().Equal("uosmo", fee.BaseDenom)
+	suite.Require().Nil(fee.ReferenceDenomID)
+}
+
+func (suite *PricesTestSuite) TestParseSwaps() {
+	attributes := []dbTypes.EventAttribute{
+		// Two merged swaps of a multi-hop route
+		{EventID: 1, Type: "token_swapped", Key: "pool_id", Value: "1"},
+		{EventID: 1, Type: "token_swapped", Key: "tokens_in", Value: "1000000uatom"},
+		{EventID: 1, Type: "token_swapped", Key: "tokens_out", Value: "8000000uosmo"},
+		{EventID: 1, Type: "token_swapped", Key: "pool_id", Value: "678"},
+		{EventID: 1, Type: "token_swapped", Key: "tokens_in", Value: "8000000uosmo"},
+		{EventID: 1, Type: "token_swapped", Key: "tokens_out", Value: "4000000ibc/D189335C6E4A68B513C10AB227BF1C1D38C746766278BA3EEB4FB14124F1D858"},
+		{EventID: 2, Type: "wasm", Key: "_contract_address", Value: "neutron1pair"},
+		{EventID: 2, Type: "wasm", Key: "action", Value: "swap"},
+		{EventID: 2, Type: "wasm", Key: "offer_asset", Value: "untrn"},
+		{EventID: 2, Type: "wasm", Key: "ask_asset", Value: "uusdc"},
+		{EventID: 2, Type: "wasm", Key: "offer_amount", Value: "2000000"},
+		{EventID: 2, Type: "wasm", Key: "return_amount", Value: "500000"},
+		// Not a swap and a swap of nothing
+		{EventID: 3, Type: "wasm", Key: "action", Value: "provide_liquidity"},
+		{EventID: 4, Type: "token_swapped", Key: "tokens_in", Value: "0uatom"},
+		{EventID: 4, Type: "token_swapped", Key: "tokens_out", Value: "0uosmo"},
+	}
+
+	swaps := ParseSwaps(attributes)
+	suite.Require().Len(swaps, 3)
+
+	suite.Require().Equal("1", swaps[0].Pool)
+	suite.Require().Equal("uatom", swaps[0].Denom)
+	suite.Require().Equal("uosmo", swaps[0].QuoteDenom)
+	suite.Require().Equal("678", swaps[1].Pool)
+	suite.Require().Equal("ibc/D189335C6E4A68B513C10AB227BF1C1D38C746766278BA3EEB4FB14124F1D858", swaps[1].QuoteDenom)
+
+	suite.Require().Equal(uint(2), swaps[2].MessageEventID)
+	suite.Require().Equal("neutron1pair", swaps[2].Pool)
+	suite.Require().Equal("untrn", swaps[2].Denom)
+	suite.Require().True(swaps[2].QuoteAmount.Equal(decimal.NewFromInt(500000)))
+}
+
+func (suite *PricesTestSuite) TestClassifier() {
+	rules, err := config.ParseClassificationRules([]byte(`{"rules": [
+		{"name": "spam denoms", "denom": "^factory/", "classification": "spam"},
+		{"name": "swaps", "kind": "transfer", "message_type": "MsgSwap", "classification": "trade"},
+		{"name": "dust", "kind": "transfer", "max_value_usd": 0.01, "classification": "spam"}
+	]}`))
+	suite.Require().NoError(err)
+	classifier := NewClassifier(rules)
+
+	cents := decimal.NewNullDecimal(decimal.RequireFromString("0.005"))
+	dollars := decimal.NewNullDecimal(decimal.NewFromInt(5))
+
+	cases := []struct {
+		flow           dbTypes.Flow
+		value          decimal.NullDecimal
+		classification models.Classification
+		rule           string
+	}{
+		{dbTypes.Flow{Kind: models.FlowTransfer, Denom: "factory/osmo1spam/airdrop"}, dollars, models.ClassificationSpam, "spam denoms"},
+		{dbTypes.Flow{Kind: models.FlowTransfer, MessageType: "/osmosis.poolmanager.v1beta1.MsgSwapExactAmountIn", Denom: "uosmo"}, cents, models.ClassificationTrade, "swaps"},
+		{dbTypes.Flow{Kind: models.FlowTransfer, MessageType: "/cosmos.bank.v1beta1.MsgSend", Denom: "uatom"}, cents, models.ClassificationSpam, "dust"},
+		// Flows without a value are not dust
+		{dbTypes.Flow{Kind: models.FlowTransfer, MessageType: "/cosmos.bank.v1beta1.MsgSend", Denom: "uatom"}, decimal.NullDecimal{}, models.ClassificationTransfer, "default"},
+		{dbTypes.Flow{Kind: models.FlowReward, Denom: "uatom"}, cents, models.ClassificationIncome, "default"},
+		{dbTypes.Flow{Kind: models.FlowFee, Denom: "uatom"}, dollars, models.ClassificationFee, "default"},
+	}
+	for _, c := range cases {
+		classification, rule := classifier.Classify(c.flow, c.value)
+		suite.Require().Equal(c.classification, classification, c.flow)
+		suite.Require().Equal(c.rule, rule, c.flow)
+	}
+}
+
+func TestPricesTestSuite(t *testing.T) {
+	suite.Run(t, new(PricesTestSuite))
+}
@@ -0,0 +1,193 @@
+package prices
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	dbTypes "github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Valuer values the fees, transfers and rewards of indexed blocks in USD at the prices of their time and classifies
+// them as taxable events. Fees are also normalized to their base denoms and converted to the reference denom, if any.
+type Valuer struct {
+	db         *gorm.DB
+	sources    Sources
+	classifier *Classifier
+	// exponents of the display units, which the prices are quoted in
+	exponents map[string]uint32
+	// traces are the origins of the IBC voucher denoms by voucher denom
+	traces map[string]config.DenomTrace
+	// reference is the base denom the fees are converted to, none when empty
+	reference   string
+	referenceID uint
+}
+
+func NewValuer(db *gorm.DB, sources Sources, classifier *Classifier, reference string) (*Valuer, error) {
+	exponents, err := dbTypes.DenomExponents(db)
+	if err != nil {
+		return nil, err
+	}
+	traces, err := dbTypes.DenomTraces(db)
+	if err != nil {
+		return nil, err
+	}
+
+	valuer := &Valuer{db: db, sources: sources, classifier: classifier, exponents: exponents, traces: traces}
+	if reference != "" {
+		if _, ok := exponents[reference]; !ok {
+			return nil, fmt.Errorf("the display unit of the reference denom %s is unknown", reference)
+		}
+		denom, err := dbTypes.FindOrCreateDenomByBase(db, reference)
+		if err != nil {
+			return nil, err
+		}
+		valuer.reference, valuer.referenceID = reference, denom.ID
+	}
+	return valuer, nil
+}
+
+type quote struct {
+	price  decimal.NullDecimal
+	source string
+}
+
+// ValueBlock replaces the valuations of the flows of the block and returns how many flows were valued. Flows of
+// denoms without a price or without a display unit are stored without a value, so the gaps show up in reports. A
+// failing source fails the block, which is valued again on the next run.
+func (v *Valuer) ValueBlock(ctx context.Context, block *models.Block) (int, error) {
+	flows, err := dbTypes.BlockFlows(v.db, block.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	quotes := map[string]quote{}
+	quoteOf := func(denom string) (quote, error) {
+		if q, ok := quotes[denom]; ok {
+			return q, nil
+		}
+		q, err := v.quote(ctx, denom, block)
+		if err != nil {
+			return quote{}, err
+		}
+		quotes[denom] = q
+		return q, nil
+	}
+
+	denomIDs := map[string]uint{}
+	valuations := make([]models.Valuation, 0, len(flows))
+	var fees []models.NormalizedFee
+	valued := 0
+
+	for _, flow := range flows {
+		q, err := quoteOf(flow.Denom)
+		if err != nil {
+			return 0, err
+		}
+
+		if _, ok := denomIDs[flow.Denom]; !ok {
+			// Denoms of events are only stored as text, they may not have a row yet
+			denom, err := dbTypes.FindOrCreateDenomByBase(v.db, flow.Denom)
+			if err != nil {
+				return 0, err
+			}
+			denomIDs[flow.Denom] = denom.ID
+		}
+
+		valuation := models.Valuation{
+			BlockID:        block.ID,
+			Kind:           flow.Kind,
+			FeeID:          flow.FeeID,
+			MessageEventID: flow.MessageEventID,
+			BlockEventID:   flow.BlockEventID,
+			DenomID:        denomIDs[flow.Denom],
+			Amount:         flow.Amount,
+			PriceUSD:       q.price,
+			PriceSource:    q.source,
+		}
+		if q.price.Valid {
+			displayAmount := flow.Amount.Shift(-int32(v.exponents[flow.Denom]))
+			valuation.ValueUSD = decimal.NewNullDecimal(displayAmount.Mul(q.price.Decimal))
+			valued++
+		}
+		valuation.Classification, valuation.ClassificationRule = v.classifier.Classify(flow, valuation.ValueUSD)
+		valuations = append(valuations, valuation)
+
+		if flow.Kind == models.FlowFee && flow.FeeID != nil {
+			fee, err := v.normalizeFee(block, flow, quoteOf)
+			if err != nil {
+				return 0, err
+			}
+			fees = append(fees, fee)
+		}
+	}
+
+	return valued, dbTypes.StoreValuations(v.db, block, valuations, fees)
+}
+
+// normalizeFee traces the denom of a fee to its base denom and converts the fee to the reference denom at the prices
+// of the block. IBC vouchers without a price of their own are priced as their base denom.
+func (v *Valuer) normalizeFee(block *models.Block, flow dbTypes.Flow, quoteOf func(string) (quote, error)) (models.NormalizedFee, error) {
+	fee := models.NormalizedFee{FeeID: *flow.FeeID, BlockID: block.ID, BaseDenom: flow.Denom}
+	trace, traced := v.traces[flow.Denom]
+	if traced {
+		fee.BaseDenom, fee.TracePath = trace.BaseDenom, trace.Path
+	}
+
+	if v.reference == "" {
+		return fee, nil
+	}
+	fee.ReferenceDenomID = &v.referenceID
+
+	// Vouchers carry the amounts of the denom they were issued as
+	if flow.Denom == v.reference || fee.BaseDenom == v.reference {
+		fee.ReferenceAmount = decimal.NewNullDecimal(flow.Amount)
+		return fee, nil
+	}
+
+	denom := flow.Denom
+	q, err := quoteOf(denom)
+	if err != nil {
+		return fee, err
+	}
+	if !q.price.Valid && traced {
+		denom = fee.BaseDenom
+		if q, err = quoteOf(denom); err != nil {
+			return fee, err
+		}
+	}
+
+	reference, err := quoteOf(v.reference)
+	if err != nil {
+		return fee, err
+	}
+	if !q.price.Valid || !reference.price.Valid || reference.price.Decimal.IsZero() {
+		return fee, nil
+	}
+
+	valueUSD := flow.Amount.Shift(-int32(v.exponents[denom])).Mul(q.price.Decimal)
+	fee.ReferenceAmount = decimal.NewNullDecimal(valueUSD.DivRound(reference.price.Decimal, 18).Shift(int32(v.exponents[v.reference])))
+	fee.PriceSource = q.source
+	return fee, nil
+}
+
+// quote looks up the price of a denom at the time of the block. Denoms without a display unit are not priced, there
+// is no telling which unit a price would be for.
+func (v *Valuer) quote(ctx context.Context, denom string, block *models.Block) (quote, error) {
+	if _, ok := v.exponents[denom]; !ok {
+		return quote{}, nil
+	}
+
+	price, source, err := v.sources.Price(ctx, denom, block.Height, block.TimeStamp)
+	if errors.Is(err, ErrNoPrice) {
+		return quote{}, nil
+	}
+	if err != nil {
+		return quote{}, err
+	}
+	return quote{price: decimal.NewNullDecimal(price), source: source}, nil
+}
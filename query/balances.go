@@ -0,0 +1,213 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrHeightNotIndexed = errors.New("height is not indexed")
+
+type Balance struct {
+	Denom string `json:"denom"`
+	// Amount is an integer in the base unit of the denom, encoded as string since it can exceed 64 bits
+	Amount string `json:"amount"`
+}
+
+type Balances struct {
+	ChainID  string    `json:"chain_id"`
+	Address  string    `json:"address"`
+	Height   int64     `json:"height"`
+	Balances []Balance `json:"balances"`
+}
+
+var coinPattern = regexp.MustCompile(`^([0-9]+)([a-zA-Z][a-zA-Z0-9/:._-]*)$`)
+
+// BalancesAt computes the balances of an address at a height from the indexed transfer events and fees, 0 selects the
+// latest indexed height. Only the transfers that were indexed are counted, so the balances are exact if the chain was
+// indexed with block events from genesis and the genesis allocation is empty, and are the net flows since the first
+// indexed block otherwise. Denoms whose balance is zero are left out.
+func (c *Client) BalancesAt(ctx context.Context, chainID string, address string, height int64) (*Balances, error) {
+	db := c.db.WithContext(ctx)
+
+	var latest int64
+	err := db.Raw(c.tables.Expand(`SELECT COALESCE(MAX(blocks.height), 0) FROM {blocks} blocks JOIN {chains} chains ON chains.id = blocks.chain_id WHERE chains.chain_id = ?`), chainID).Scan(&latest).Error
+	if err != nil {
+		return nil, err
+	}
+	if latest == 0 || height > latest {
+		return nil, ErrHeightNotIndexed
+	}
+	if height == 0 {
+		height = latest
+	}
+
+	amounts := map[string]*big.Int{}
+	add := func(denom string, amount *big.Int) {
+		if amounts[denom] == nil {
+			amounts[denom] = new(big.Int)
+		}
+		amounts[denom].Add(amounts[denom], amount)
+	}
+
+	for _, transfers := range []string{messageTransfersQuery, blockTransfersQuery} {
+		if err := addTransfers(db, c.tables.Expand(transfers), chainID, address, height, add); err != nil {
+			return nil, err
+		}
+	}
+
+	var fees []struct {
+		Denom  string
+		Amount string
+	}
+	err = db.Raw(c.tables.Expand(`SELECT denoms.base AS denom, SUM(fees.amount)::text AS amount
+		FROM {fees} fees
+		JOIN {denoms} denoms ON denoms.id = fees.denomination_id
+		JOIN {addresses} addresses ON addresses.id = fees.payer_address_id
+		JOIN {txes} txes ON txes.id = fees.tx_id
+		JOIN {blocks} blocks ON blocks.id = txes.block_id
+		JOIN {chains} chains ON chains.id = blocks.chain_id
+		WHERE chains.chain_id = ? AND blocks.height <= ? AND addresses.address = ?
+		GROUP BY denoms.base`), chainID, height, address).Scan(&fees).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, fee := range fees {
+		if amount, ok := new(big.Int).SetString(fee.Amount, 10); ok {
+			add(fee.Denom, amount.Neg(amount))
+		}
+	}
+
+	balances := &Balances{ChainID: chainID, Address: address, Height: height, Balances: []Balance{}}
+	for denom, amount := range amounts {
+		if amount.Sign() != 0 {
+			balances.Balances = append(balances.Balances, Balance{Denom: denom, Amount: amount.String()})
+		}
+	}
+	sort.Slice(balances.Balances, func(i, j int) bool { return balances.Balances[i].Denom < balances.Balances[j].Denom })
+
+	return balances, nil
+}
+
+// The transfer queries return the attributes of the successful transfer events up to a height that the address sent
+// or received, ordered by event and attribute index
+const messageTransfersQuery = `SELECT message_event_attributes.message_event_id AS event_id, message_event_attribute_keys.key, message_event_attributes.value,
+	message_event_attributes.compressed_value
+	FROM {message_event_attributes} message_event_attributes
+	JOIN {message_event_attribute_keys} message_event_attribute_keys ON message_event_attribute_keys.id = message_event_attributes.message_event_attribute_key_id
+	WHERE message_event_attributes.message_event_id IN (
+		SELECT message_events.id
+		FROM {message_events} message_events
+		JOIN {message_event_types} message_event_types ON message_event_types.id = message_events.message_event_type_id
+		JOIN {messages} messages ON messages.id = message_events.message_id
+		JOIN {txes} txes ON txes.id = messages.tx_id
+		JOIN {blocks} blocks ON blocks.id = txes.block_id
+		JOIN {chains} chains ON chains.id = blocks.chain_id
+		JOIN {message_event_attributes} party ON party.message_event_id = message_events.id
+		JOIN {message_event_attribute_keys} party_key ON party_key.id = party.message_event_attribute_key_id
+		WHERE message_event_types.type = 'transfer' AND txes.code = 0 AND chains.chain_id = ? AND blocks.height <= ?
+			AND party_key.key IN ('recipient', 'sender') AND party.value = ?)
+	ORDER BY message_event_attributes.message_event_id, message_event_attributes.index`
+
+const blockTransfersQuery = `SELECT block_event_attributes.block_event_id AS event_id, block_event_attribute_keys.key, block_event_attributes.value,
+	block_event_attributes.compressed_value
+	FROM {block_event_attributes} block_event_attributes
+	JOIN {block_event_attribute_keys} block_event_attribute_keys ON block_event_attribute_keys.id = block_event_attributes.block_event_attribute_key_id
+	WHERE block_event_attributes.block_event_id IN (
+		SELECT block_events.id
+		FROM {block_events} block_events
+		JOIN {block_event_types} block_event_types ON block_event_types.id = block_events.block_event_type_id
+		JOIN {blocks} blocks ON blocks.id = block_events.block_id
+		JOIN {chains} chains ON chains.id = blocks.chain_id
+		JOIN {block_event_attributes} party ON party.block_event_id = block_events.id
+		JOIN {block_event_attribute_keys} party_key ON party_key.id = party.block_event_attribute_key_id
+		WHERE block_event_types.type = 'transfer' AND chains.chain_id = ? AND blocks.height <= ?
+			AND party_key.key IN ('recipient', 'sender') AND party.value = ?)
+	ORDER BY block_event_attributes.block_event_id, block_event_attributes.index`
+
+type transferAttribute struct {
+	EventID         uint
+	Key             string
+	Value           string `gorm:"serializer:attribute_value"`
+	CompressedValue []byte `gorm:"serializer:attribute_value"`
+}
+
+func addTransfers(db *gorm.DB, query string, chainID string, address string, height int64, add func(string, *big.Int)) error {
+	var attributes []transferAttribute
+	if err := db.Raw(query, chainID, height, address).Scan(&attributes).Error; err != nil {
+		return err
+	}
+
+	for _, transfer := range parseTransfers(attributes) {
+		sign := 0
+		if transfer.recipient == address {
+			sign++
+		}
+		if transfer.sender == address {
+			sign--
+		}
+		if sign == 0 {
+			continue
+		}
+
+		for _, coin := range strings.Split(transfer.amount, ",") {
+			match := coinPattern.FindStringSubmatch(strings.TrimSpace(coin))
+			if match == nil {
+				continue
+			}
+			amount, _ := new(big.Int).SetString(match[1], 10)
+			if sign < 0 {
+				amount.Neg(amount)
+			}
+			add(match[2], amount)
+		}
+	}
+
+	return nil
+}
+
+type transfer struct {
+	recipient string
+	sender    string
+	amount    string
+}
+
+// parseTransfers splits the attributes of transfer events into transfers. Older SDK versions merged the transfers of a
+// message into one event with repeated recipient, sender and amount attributes, so a transfer is complete once all
+// three were seen.
+func parseTransfers(attributes []transferAttribute) []transfer {
+	var transfers []transfer
+	var current transfer
+	var currentEvent uint
+	var seen int
+
+	for _, attribute := range attributes {
+		if attribute.EventID != currentEvent {
+			current, currentEvent, seen = transfer{}, attribute.EventID, 0
+		}
+
+		switch attribute.Key {
+		case "recipient":
+			current.recipient = attribute.Value
+		case "sender":
+			current.sender = attribute.Value
+		case "amount":
+			current.amount = attribute.Value
+		default:
+			continue
+		}
+
+		seen++
+		if seen == 3 {
+			transfers = append(transfers, current)
+			current, seen = transfer{}, 0
+		}
+	}
+
+	return transfers
+}
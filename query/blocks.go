@@ -0,0 +1,98 @@
+package query
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+type Block struct {
+	ChainID              string    `json:"chain_id"`
+	Height               int64     `json:"height"`
+	Time                 time.Time `json:"time"`
+	ProposerConsAddress  string    `json:"proposer_cons_address"`
+	TxIndexed            bool      `json:"tx_indexed"`
+	BlockEventsIndexed   bool      `json:"block_events_indexed"`
+	CustomParsersApplied bool      `json:"custom_parsers_applied"`
+	Verified             bool      `json:"verified"`
+}
+
+var blockKeys = []string{"chains.chain_id", "blocks.height"}
+
+// BlocksQuery selects the indexed blocks of a height range
+type BlocksQuery struct {
+	client *Client
+	scope  scope
+}
+
+// BlocksInRange selects the blocks between the start and end height, both inclusive. An end height of 0 selects up to
+// the latest indexed block.
+func (c *Client) BlocksInRange(startHeight int64, endHeight int64) *BlocksQuery {
+	return &BlocksQuery{client: c, scope: scope{startHeight: startHeight, endHeight: endHeight}}
+}
+
+func (q *BlocksQuery) Chain(chainID string) *BlocksQuery {
+	q.scope.chainID = chainID
+	return q
+}
+
+func (q *BlocksQuery) Limit(limit int) *BlocksQuery {
+	q.scope.limit = limit
+	return q
+}
+
+func (q *BlocksQuery) Offset(offset int) *BlocksQuery {
+	q.scope.offset = offset
+	return q
+}
+
+// After continues after the cursor returned with a previous page of the query
+func (q *BlocksQuery) After(cursor string) *BlocksQuery {
+	q.scope.continueAfter("blocks", blockKeys, cursor)
+	return q
+}
+
+// Descending returns the highest blocks first
+func (q *BlocksQuery) Descending() *BlocksQuery {
+	q.scope.descending = true
+	return q
+}
+
+func (q *BlocksQuery) sql() (string, []any) {
+	var query strings.Builder
+	query.WriteString(`SELECT chains.chain_id, blocks.height, blocks.time_stamp AS time, COALESCE(addresses.address, '') AS proposer_cons_address,
+			blocks.tx_indexed, blocks.block_events_indexed, blocks.custom_parsers_applied, blocks.verified
+		FROM {blocks} blocks
+		JOIN {chains} chains ON chains.id = blocks.chain_id
+		LEFT JOIN {addresses} addresses ON addresses.id = blocks.proposer_cons_address_id
+		WHERE TRUE`)
+	args := q.scope.where(&query, nil)
+	args = q.scope.page(&query, args, blockKeys...)
+
+	return q.client.tables.Expand(query.String()), args
+}
+
+func (q *BlocksQuery) All(ctx context.Context) ([]Block, error) {
+	blocks, _, err := q.Page(ctx)
+	return blocks, err
+}
+
+// Page returns the blocks and the cursor of the next page, which is empty once there are no more blocks
+func (q *BlocksQuery) Page(ctx context.Context) ([]Block, string, error) {
+	if q.scope.err != nil {
+		return nil, "", q.scope.err
+	}
+
+	query, args := q.sql()
+
+	var blocks []Block
+	if err := q.client.db.WithContext(ctx).Raw(query, args...).Scan(&blocks).Error; err != nil {
+		return nil, "", err
+	}
+
+	if len(blocks) == 0 {
+		return blocks, "", nil
+	}
+	last := blocks[len(blocks)-1]
+	return blocks, q.scope.nextCursor(len(blocks), "blocks", last.ChainID, last.Height), nil
+}
@@ -0,0 +1,105 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BucketInterval is the length of the time buckets of an aggregate
+type BucketInterval string
+
+const (
+	BucketHour  BucketInterval = "hour"
+	BucketDay   BucketInterval = "day"
+	BucketWeek  BucketInterval = "week"
+	BucketMonth BucketInterval = "month"
+)
+
+// maxTimeBuckets caps the buckets of a single aggregate, wider ranges must be queried with a longer interval
+const maxTimeBuckets = 10000
+
+var ErrTooManyBuckets = fmt.Errorf("the time range spans more than %d buckets", maxTimeBuckets)
+
+// TimeBucket aggregates the blocks whose time falls into a bucket of the calendar of a timezone
+type TimeBucket struct {
+	ChainID string `json:"chain_id"`
+	// Start is the start of the bucket in the timezone of the query, e.g. midnight for day buckets
+	Start       time.Time `json:"start"`
+	FirstHeight int64     `json:"first_height"`
+	LastHeight  int64     `json:"last_height"`
+	Blocks      int64     `json:"blocks"`
+	Txs         int64     `json:"txs"`
+	FailedTxs   int64     `json:"failed_txs"`
+}
+
+// ParseBucketInterval returns the interval of its name, hour, day, week or month
+func ParseBucketInterval(name string) (BucketInterval, error) {
+	switch interval := BucketInterval(name); interval {
+	case BucketHour, BucketDay, BucketWeek, BucketMonth:
+		return interval, nil
+	}
+	return "", errors.New("interval must be hour, day, week or month")
+}
+
+// ParseTime parses an RFC 3339 time, or a date that starts at midnight in the location
+func ParseTime(value string, location *time.Location) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed, nil
+	}
+	if parsed, err := time.ParseInLocation("2006-01-02", value, location); err == nil {
+		return parsed, nil
+	}
+	return time.Time{}, errors.New("must be an RFC 3339 time or a date, e.g. 2024-01-31")
+}
+
+// approximate is the shortest length of a bucket of the interval, used to bound the number of buckets of a range
+func (i BucketInterval) approximate() time.Duration {
+	switch i {
+	case BucketHour:
+		return time.Hour
+	case BucketWeek:
+		return 7 * 24 * time.Hour
+	case BucketMonth:
+		return 28 * 24 * time.Hour
+	default:
+		return 23 * time.Hour
+	}
+}
+
+// TimeBuckets aggregates the indexed blocks of a chain with a time from start up to end, exclusive, into buckets of the
+// interval in the calendar of the location. Buckets follow the local time of the location, so days start at local
+// midnight and days with a daylight saving time change are 23 or 25 hours long. Weeks start on Monday. Buckets without
+// indexed blocks are left out.
+func (c *Client) TimeBuckets(ctx context.Context, chainID string, interval BucketInterval, location *time.Location, start time.Time, end time.Time) ([]TimeBucket, error) {
+	if !end.After(start) {
+		return nil, errors.New("the end of the time range must be after its start")
+	}
+	if end.Sub(start)/interval.approximate() > maxTimeBuckets {
+		return nil, ErrTooManyBuckets
+	}
+
+	// Truncating the local time and converting the result back handles the offset changes within the range
+	var buckets []TimeBucket
+	err := c.db.WithContext(ctx).Raw(c.tables.Expand(`SELECT chains.chain_id, date_trunc(?, blocks.time_stamp AT TIME ZONE ?) AT TIME ZONE ? AS start,
+			MIN(blocks.height) AS first_height, MAX(blocks.height) AS last_height, COUNT(*) AS blocks,
+			COALESCE(SUM(block_txes.txs), 0) AS txs, COALESCE(SUM(block_txes.failed_txs), 0) AS failed_txs
+		FROM {blocks} blocks
+		JOIN {chains} chains ON chains.id = blocks.chain_id
+		LEFT JOIN LATERAL (
+			SELECT COUNT(*) AS txs, COUNT(*) FILTER (WHERE txes.code <> 0) AS failed_txs FROM {txes} txes WHERE txes.block_id = blocks.id
+		) block_txes ON TRUE
+		WHERE chains.chain_id = ? AND blocks.time_stamp >= ? AND blocks.time_stamp < ?
+		GROUP BY chains.chain_id, 2
+		ORDER BY 2`),
+		string(interval), location.String(), location.String(), chainID, start, end).Scan(&buckets).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range buckets {
+		buckets[i].Start = buckets[i].Start.In(location)
+	}
+	return buckets, nil
+}
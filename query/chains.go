@@ -0,0 +1,21 @@
+package query
+
+import "context"
+
+type Chain struct {
+	ChainID string `json:"chain_id"`
+	Name    string `json:"name"`
+	// LatestHeight is the highest indexed block of the chain, 0 if none was indexed yet
+	LatestHeight int64 `json:"latest_height"`
+}
+
+// Chains returns the indexed chains ordered by chain ID
+func (c *Client) Chains(ctx context.Context) ([]Chain, error) {
+	var chains []Chain
+	err := c.db.WithContext(ctx).Raw(c.tables.Expand(`SELECT chains.chain_id, chains.name, COALESCE(MAX(blocks.height), 0) AS latest_height
+		FROM {chains} chains
+		LEFT JOIN {blocks} blocks ON blocks.chain_id = chains.id
+		GROUP BY chains.id
+		ORDER BY chains.chain_id`)).Scan(&chains).Error
+	return chains, err
+}
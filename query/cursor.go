@@ -0,0 +1,54 @@
+package query
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursor is the position of the last result of a page. It is handed to clients as an opaque string and holds the
+// values of the order columns of the query, so the next page continues right after it with an index range scan
+// instead of an OFFSET scan, and is not shifted by rows indexed in the meantime.
+type cursor struct {
+	Query  string `json:"q"`
+	Values []any  `json:"v"`
+}
+
+func encodeCursor(query string, values ...any) string {
+	data, _ := json.Marshal(cursor{Query: query, Values: values})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the values of a cursor of the query, integers are decoded as int64
+func decodeCursor(query string, keys int, encoded string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var decoded cursor
+	if err := decoder.Decode(&decoded); err != nil || decoded.Query != query || len(decoded.Values) != keys {
+		return nil, ErrInvalidCursor
+	}
+
+	for i, value := range decoded.Values {
+		switch v := value.(type) {
+		case json.Number:
+			if decoded.Values[i], err = v.Int64(); err != nil {
+				return nil, ErrInvalidCursor
+			}
+		case string:
+		default:
+			return nil, fmt.Errorf("%w: unexpected value %v", ErrInvalidCursor, value)
+		}
+	}
+
+	return decoded.Values, nil
+}
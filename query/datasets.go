@@ -0,0 +1,226 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownDataset is returned for tables that were not registered as datasets
+var ErrUnknownDataset = errors.New("unknown dataset")
+
+// DatasetRow is a row of a table written by custom parsers. ChainID and Height are those of the block the row belongs
+// to, empty for tables that do not reference the indexed blocks. ID is the primary key of the row, whatever the name
+// of its column.
+type DatasetRow struct {
+	ChainID string `json:"chain_id,omitempty"`
+	Height  int64  `json:"height,omitempty"`
+	ID      int64  `json:"id"`
+	// Data holds the columns of the row as JSON object
+	Data json.RawMessage `json:"data"`
+}
+
+// datasetJoins join the block of a dataset row through the table its foreign key references, keyed by the default
+// name of the table. The placeholder is the referencing column. A table referencing several of them is joined through
+// the first one in datasetReferences.
+var datasetJoins = map[string]string{
+	"blocks": "JOIN {blocks} blocks ON blocks.id = d.%q",
+	"txes":   "JOIN {txes} txes ON txes.id = d.%q JOIN {blocks} blocks ON blocks.id = txes.block_id",
+	"messages": `JOIN {messages} messages ON messages.id = d.%q JOIN {txes} txes ON txes.id = messages.tx_id
+		JOIN {blocks} blocks ON blocks.id = txes.block_id`,
+	"block_events": "JOIN {block_events} block_events ON block_events.id = d.%q JOIN {blocks} blocks ON blocks.id = block_events.block_id",
+	"message_events": `JOIN {message_events} message_events ON message_events.id = d.%q
+		JOIN {messages} messages ON messages.id = message_events.message_id
+		JOIN {txes} txes ON txes.id = messages.tx_id JOIN {blocks} blocks ON blocks.id = txes.block_id`,
+}
+
+var datasetReferences = []string{"blocks", "txes", "messages", "block_events", "message_events"}
+
+// ErrNoDatasetKey is returned for dataset tables without a single column integer primary key, which orders the rows
+var ErrNoDatasetKey = errors.New("dataset has no single column integer primary key")
+
+// datasetReference is a foreign key of a dataset table, the referenced table is its default name
+type datasetReference struct {
+	ReferencingColumn string
+	ReferencedTable   string
+}
+
+// WithDatasets registers the tables of custom parsers that Dataset may query, so clients cannot read other tables
+func (c *Client) WithDatasets(tables []string) *Client {
+	c.datasets = tables
+	return c
+}
+
+// Datasets returns the registered dataset tables
+func (c *Client) Datasets() []string {
+	return c.datasets
+}
+
+// DatasetQuery selects the rows of a dataset table ordered by block and primary key, or by primary key for tables that
+// do not reference the indexed blocks
+type DatasetQuery struct {
+	client *Client
+	table  string
+	cursor string
+	scope  scope
+}
+
+// Dataset selects the rows of a table registered with WithDatasets. The rows are filtered by chain and height through
+// the first foreign key of the table referencing blocks, txes, messages, block_events or message_events.
+func (c *Client) Dataset(table string) *DatasetQuery {
+	return &DatasetQuery{client: c, table: table}
+}
+
+func (q *DatasetQuery) Chain(chainID string) *DatasetQuery {
+	q.scope.chainID = chainID
+	return q
+}
+
+// Heights limits the rows to a height range, 0 leaves a bound open
+func (q *DatasetQuery) Heights(startHeight int64, endHeight int64) *DatasetQuery {
+	q.scope.startHeight = startHeight
+	q.scope.endHeight = endHeight
+	return q
+}
+
+func (q *DatasetQuery) Limit(limit int) *DatasetQuery {
+	q.scope.limit = limit
+	return q
+}
+
+func (q *DatasetQuery) Offset(offset int) *DatasetQuery {
+	q.scope.offset = offset
+	return q
+}
+
+// After continues after the cursor returned with a previous page of the query
+func (q *DatasetQuery) After(cursor string) *DatasetQuery {
+	q.cursor = cursor
+	return q
+}
+
+// Descending returns the latest rows first
+func (q *DatasetQuery) Descending() *DatasetQuery {
+	q.scope.descending = true
+	return q
+}
+
+// sql builds the query of the rows ordered by the primary key column, joining their block through the reference, nil
+// for tables without one
+func (q *DatasetQuery) sql(primaryKey string, reference *datasetReference) (string, []any, error) {
+	id := fmt.Sprintf("d.%q", primaryKey)
+	keys := []string{id}
+	var query strings.Builder
+	if reference != nil {
+		keys = []string{"chains.chain_id", "blocks.height", id}
+		fmt.Fprintf(&query, "SELECT chains.chain_id, blocks.height, %s AS id, row_to_json(d)::text AS data FROM %q d ", id, q.table)
+		fmt.Fprintf(&query, datasetJoins[reference.ReferencedTable], reference.ReferencingColumn)
+		query.WriteString(" JOIN {chains} chains ON chains.id = blocks.chain_id WHERE TRUE")
+	} else {
+		if q.scope.chainID != "" || q.scope.startHeight > 0 || q.scope.endHeight > 0 {
+			return "", nil, fmt.Errorf("dataset %s does not reference the indexed blocks, it cannot be filtered by chain or height", q.table)
+		}
+		fmt.Fprintf(&query, "SELECT '' AS chain_id, 0 AS height, %s AS id, row_to_json(d)::text AS data FROM %q d WHERE TRUE", id, q.table)
+	}
+
+	// The order columns depend on the reference, so the cursor is only decoded now
+	if q.cursor != "" {
+		q.scope.continueAfter("dataset:"+q.table, keys, q.cursor)
+		if q.scope.err != nil {
+			return "", nil, q.scope.err
+		}
+	}
+
+	args := q.scope.where(&query, nil)
+	args = q.scope.page(&query, args, keys...)
+	return q.client.tables.Expand(query.String()), args, nil
+}
+
+func (q *DatasetQuery) All(ctx context.Context) ([]DatasetRow, error) {
+	rows, _, err := q.Page(ctx)
+	return rows, err
+}
+
+// Page returns the rows and the cursor of the next page, which is empty once there are no more rows
+func (q *DatasetQuery) Page(ctx context.Context) ([]DatasetRow, string, error) {
+	registered := false
+	for _, table := range q.client.datasets {
+		registered = registered || table == q.table
+	}
+	if !registered {
+		return nil, "", fmt.Errorf("%w: %s", ErrUnknownDataset, q.table)
+	}
+
+	db := q.client.db.WithContext(ctx)
+
+	var primaryKeys []string
+	err := db.Raw(`SELECT a.attname
+		FROM pg_index i
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = i.indkey[0]
+		WHERE i.indrelid = to_regclass(?) AND i.indisprimary AND i.indnatts = 1
+			AND a.atttypid IN ('smallint'::regtype, 'integer'::regtype, 'bigint'::regtype)`, fmt.Sprintf("%q", q.table)).Scan(&primaryKeys).Error
+	if err != nil {
+		return nil, "", err
+	}
+	if len(primaryKeys) == 0 {
+		return nil, "", fmt.Errorf("%w: %s", ErrNoDatasetKey, q.table)
+	}
+
+	var references []datasetReference
+	err = db.Raw(`SELECT child_column.attname AS referencing_column, parent.relname AS referenced_table
+		FROM pg_constraint con
+		JOIN pg_class child ON child.oid = con.conrelid
+		JOIN pg_class parent ON parent.oid = con.confrelid
+		JOIN pg_namespace ns ON ns.oid = child.relnamespace
+		JOIN pg_attribute child_column ON child_column.attrelid = con.conrelid AND child_column.attnum = con.conkey[1]
+		WHERE con.contype = 'f' AND ns.nspname = current_schema() AND child.relname = ? AND cardinality(con.conkey) = 1
+		ORDER BY child_column.attnum`, q.table).Scan(&references).Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	// The referenced tables are named by the naming strategy of the database
+	for i := range references {
+		references[i].ReferencedTable = q.client.tables.Default(references[i].ReferencedTable)
+	}
+
+	var reference *datasetReference
+	for _, table := range datasetReferences {
+		for i := range references {
+			if reference == nil && references[i].ReferencedTable == table {
+				reference = &references[i]
+			}
+		}
+	}
+
+	query, args, err := q.sql(primaryKeys[0], reference)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var scanned []struct {
+		ChainID string
+		Height  int64
+		ID      int64
+		Data    string
+	}
+	if err := db.Raw(query, args...).Scan(&scanned).Error; err != nil {
+		return nil, "", err
+	}
+
+	rows := make([]DatasetRow, len(scanned))
+	for i, row := range scanned {
+		rows[i] = DatasetRow{ChainID: row.ChainID, Height: row.Height, ID: row.ID, Data: json.RawMessage(row.Data)}
+	}
+
+	if len(rows) == 0 {
+		return rows, "", nil
+	}
+	last := rows[len(rows)-1]
+	if reference == nil {
+		return rows, q.scope.nextCursor(len(rows), "dataset:"+q.table, last.ID), nil
+	}
+	return rows, q.scope.nextCursor(len(rows), "dataset:"+q.table, last.ChainID, last.Height, last.ID), nil
+}
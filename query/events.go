@@ -0,0 +1,390 @@
+package query
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sources of events
+const (
+	SourceBeginBlock = "begin_block"
+	SourceEndBlock   = "end_block"
+	SourceMessage    = "message"
+)
+
+type Event struct {
+	ChainID string    `json:"chain_id"`
+	Height  int64     `json:"height"`
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	// TxHash and MessageIndex identify the message of message events
+	TxHash       string      `json:"tx_hash,omitempty"`
+	MessageIndex int         `json:"message_index"`
+	Index        uint64      `json:"index"`
+	Type         string      `json:"type"`
+	Attributes   []Attribute `json:"attributes"`
+	// Coins are the coins of the attributes holding coins, one per denom and attribute, indexed with coins.enabled
+	Coins []Coin `json:"coins,omitempty"`
+}
+
+type Attribute struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Coin is a coin of an event attribute, e.g. 5uosmo of amount 10uatom,5uosmo
+type Coin struct {
+	Key   string `json:"key"`
+	Denom string `json:"denom"`
+	// Amount is a decimal in the base unit of the denom, encoded as string since it can exceed 64 bits
+	Amount string `json:"amount"`
+}
+
+var eventKeys = []string{"chain_id", "height", "ordinal", "tx_id", "message_index", "index"}
+
+// EventsQuery selects the block and message events of a type, or of any type when it is empty
+type EventsQuery struct {
+	client        *Client
+	eventType     string
+	search        string
+	blockEvents   bool
+	messageEvents bool
+	attributes    []attributeFilter
+	scope         scope
+}
+
+// attributeFilter matches the events having an attribute with the key, and with the value unless any value matches
+type attributeFilter struct {
+	key      string
+	value    string
+	anyValue bool
+}
+
+// EventsByType selects the BeginBlock, EndBlock and message events of the type in execution order
+func (c *Client) EventsByType(eventType string) *EventsQuery {
+	return &EventsQuery{client: c, eventType: eventType, blockEvents: true, messageEvents: true}
+}
+
+// SearchEvents selects the message events of any type whose attribute values match the search text, see Matching
+func (c *Client) SearchEvents(text string) *EventsQuery {
+	return (&EventsQuery{client: c, messageEvents: true}).Matching(text)
+}
+
+func (q *EventsQuery) Chain(chainID string) *EventsQuery {
+	q.scope.chainID = chainID
+	return q
+}
+
+// Heights limits the events to a height range, 0 leaves a bound open
+func (q *EventsQuery) Heights(startHeight int64, endHeight int64) *EventsQuery {
+	q.scope.startHeight = startHeight
+	q.scope.endHeight = endHeight
+	return q
+}
+
+// BlockEventsOnly skips message events
+func (q *EventsQuery) BlockEventsOnly() *EventsQuery {
+	q.blockEvents, q.messageEvents = true, false
+	return q
+}
+
+// MessageEventsOnly skips BeginBlock and EndBlock events
+func (q *EventsQuery) MessageEventsOnly() *EventsQuery {
+	q.blockEvents, q.messageEvents = false, true
+	return q
+}
+
+// Matching limits the events to the message events whose attribute values match the search text, parsed like a web
+// search. Only the values of the keys in search.attribute-keys are searchable, block events never match.
+func (q *EventsQuery) Matching(text string) *EventsQuery {
+	q.search = text
+	return q
+}
+
+// WithAttribute limits the events to those having an attribute with the key and value. Values stored compressed, see
+// database.compression, do not match.
+func (q *EventsQuery) WithAttribute(key string, value string) *EventsQuery {
+	q.attributes = append(q.attributes, attributeFilter{key: key, value: value})
+	return q
+}
+
+// WithAttributeKey limits the events to those having an attribute with the key, whatever its value
+func (q *EventsQuery) WithAttributeKey(key string) *EventsQuery {
+	q.attributes = append(q.attributes, attributeFilter{key: key, anyValue: true})
+	return q
+}
+
+func (q *EventsQuery) Limit(limit int) *EventsQuery {
+	q.scope.limit = limit
+	return q
+}
+
+func (q *EventsQuery) Offset(offset int) *EventsQuery {
+	q.scope.offset = offset
+	return q
+}
+
+// After continues after the cursor returned with a previous page of the query
+func (q *EventsQuery) After(cursor string) *EventsQuery {
+	q.scope.continueAfter("events", eventKeys, cursor)
+	return q
+}
+
+// Descending returns the latest events first
+func (q *EventsQuery) Descending() *EventsQuery {
+	q.scope.descending = true
+	return q
+}
+
+// eventRow is a scanned event, the ID references the block_events or message_events table depending on the source
+type eventRow struct {
+	ID           uint
+	ChainID      string
+	Height       int64
+	Time         time.Time
+	Source       string
+	Ordinal      int
+	TxID         uint
+	TxHash       string
+	MessageIndex int
+	Index        uint64
+	Type         string
+}
+
+func (q *EventsQuery) sql() (string, []any) {
+	var query strings.Builder
+	var args []any
+	var selects []string
+
+	// Block events have no search documents
+	if q.blockEvents && q.search == "" {
+		var blockEvents strings.Builder
+		// The ordinal orders BeginBlock events before the messages of the block and EndBlock events after them
+		blockEvents.WriteString(`SELECT block_events.id, chains.chain_id, blocks.height, blocks.time_stamp AS time,
+				CASE block_events.lifecycle_position WHEN 0 THEN 'begin_block' ELSE 'end_block' END AS source,
+				CASE block_events.lifecycle_position WHEN 0 THEN 0 ELSE 2 END AS ordinal,
+				0 AS tx_id, '' AS tx_hash, 0 AS message_index, block_events.index, block_event_types.type
+			FROM {block_events} block_events
+			JOIN {block_event_types} block_event_types ON block_event_types.id = block_events.block_event_type_id
+			JOIN {blocks} blocks ON blocks.id = block_events.block_id
+			JOIN {chains} chains ON chains.id = blocks.chain_id
+			WHERE TRUE`)
+		if q.eventType != "" {
+			blockEvents.WriteString(" AND block_event_types.type = ?")
+			args = append(args, q.eventType)
+		}
+		args = q.whereAttributes(&blockEvents, args, "block_event")
+		args = q.scope.where(&blockEvents, args)
+		selects = append(selects, blockEvents.String())
+	}
+
+	if q.messageEvents {
+		var messageEvents strings.Builder
+		messageEvents.WriteString(`SELECT message_events.id, chains.chain_id, blocks.height, blocks.time_stamp AS time,
+				'message' AS source, 1 AS ordinal,
+				txes.id AS tx_id, txes.hash AS tx_hash, messages.message_index, message_events.index, message_event_types.type
+			FROM {message_events} message_events
+			JOIN {message_event_types} message_event_types ON message_event_types.id = message_events.message_event_type_id
+			JOIN {messages} messages ON messages.id = message_events.message_id
+			JOIN {txes} txes ON txes.id = messages.tx_id
+			JOIN {blocks} blocks ON blocks.id = txes.block_id
+			JOIN {chains} chains ON chains.id = blocks.chain_id
+			WHERE TRUE`)
+		if q.eventType != "" {
+			messageEvents.WriteString(" AND message_event_types.type = ?")
+			args = append(args, q.eventType)
+		}
+		args = q.whereAttributes(&messageEvents, args, "message_event")
+		if q.search != "" {
+			messageEvents.WriteString(`
+			AND message_events.id IN (
+				SELECT message_event_searches.message_event_id
+				FROM {message_event_searches} message_event_searches
+				WHERE message_event_searches.document @@ websearch_to_tsquery('simple', ?))`)
+			args = append(args, q.search)
+		}
+		args = q.scope.where(&messageEvents, args)
+		selects = append(selects, messageEvents.String())
+	}
+
+	if len(selects) == 0 {
+		return "", nil
+	}
+
+	query.WriteString("SELECT * FROM (")
+	query.WriteString(strings.Join(selects, " UNION ALL "))
+	query.WriteString(") events WHERE TRUE")
+	args = q.scope.page(&query, args, eventKeys...)
+
+	return q.client.tables.Expand(query.String()), args
+}
+
+// whereAttributes appends the attribute conditions for the events of the table prefix, block_event or message_event
+func (q *EventsQuery) whereAttributes(query *strings.Builder, args []any, prefix string) []any {
+	for _, filter := range q.attributes {
+		fmt.Fprintf(query, `
+			AND EXISTS (
+				SELECT 1 FROM {%[1]s_attributes} %[1]s_attributes
+				JOIN {%[1]s_attribute_keys} %[1]s_attribute_keys ON %[1]s_attribute_keys.id = %[1]s_attributes.%[1]s_attribute_key_id
+				WHERE %[1]s_attributes.%[1]s_id = %[1]ss.id AND %[1]s_attribute_keys.key = ?`, prefix)
+		args = append(args, filter.key)
+		if !filter.anyValue {
+			fmt.Fprintf(query, " AND %s_attributes.value = ?", prefix)
+			args = append(args, filter.value)
+		}
+		query.WriteString(")")
+	}
+	return args
+}
+
+func (q *EventsQuery) All(ctx context.Context) ([]Event, error) {
+	events, _, err := q.Page(ctx)
+	return events, err
+}
+
+// Page returns the events and the cursor of the next page, which is empty once there are no more events
+func (q *EventsQuery) Page(ctx context.Context) ([]Event, string, error) {
+	if q.scope.err != nil {
+		return nil, "", q.scope.err
+	}
+
+	query, args := q.sql()
+	if query == "" {
+		return nil, "", nil
+	}
+
+	db := q.client.db.WithContext(ctx)
+
+	var rows []eventRow
+	if err := db.Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	var blockEventIDs, messageEventIDs []uint
+	for _, row := range rows {
+		if row.Source == SourceMessage {
+			messageEventIDs = append(messageEventIDs, row.ID)
+		} else {
+			blockEventIDs = append(blockEventIDs, row.ID)
+		}
+	}
+
+	blockAttributes, err := attributes(db, q.client.tables.Expand(`SELECT block_event_attributes.block_event_id AS event_id, block_event_attribute_keys.key, block_event_attributes.value,
+			block_event_attributes.compressed_value
+		FROM {block_event_attributes} block_event_attributes
+		JOIN {block_event_attribute_keys} block_event_attribute_keys ON block_event_attribute_keys.id = block_event_attributes.block_event_attribute_key_id
+		WHERE block_event_attributes.block_event_id IN ?
+		ORDER BY block_event_attributes.block_event_id, block_event_attributes.index`), blockEventIDs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	messageAttributes, err := attributes(db, q.client.tables.Expand(`SELECT message_event_attributes.message_event_id AS event_id, message_event_attribute_keys.key, message_event_attributes.value,
+			message_event_attributes.compressed_value
+		FROM {message_event_attributes} message_event_attributes
+		JOIN {message_event_attribute_keys} message_event_attribute_keys ON message_event_attribute_keys.id = message_event_attributes.message_event_attribute_key_id
+		WHERE message_event_attributes.message_event_id IN ?
+		ORDER BY message_event_attributes.message_event_id, message_event_attributes.index`), messageEventIDs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	blockCoins, err := coins(db, q.client.tables.Expand(`SELECT block_event_attribute_coins.block_event_id AS event_id, block_event_attribute_keys.key, denoms.base AS denom, block_event_attribute_coins.amount::text AS amount
+		FROM {block_event_attribute_coins} block_event_attribute_coins
+		JOIN {block_event_attributes} block_event_attributes ON block_event_attributes.id = block_event_attribute_coins.block_event_attribute_id
+		JOIN {block_event_attribute_keys} block_event_attribute_keys ON block_event_attribute_keys.id = block_event_attributes.block_event_attribute_key_id
+		JOIN {denoms} denoms ON denoms.id = block_event_attribute_coins.denom_id
+		WHERE block_event_attribute_coins.block_event_id IN ?
+		ORDER BY block_event_attribute_coins.block_event_id, block_event_attributes.index, block_event_attribute_coins.position`), blockEventIDs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	messageCoins, err := coins(db, q.client.tables.Expand(`SELECT message_event_attribute_coins.message_event_id AS event_id, message_event_attribute_keys.key, denoms.base AS denom, message_event_attribute_coins.amount::text AS amount
+		FROM {message_event_attribute_coins} message_event_attribute_coins
+		JOIN {message_event_attributes} message_event_attributes ON message_event_attributes.id = message_event_attribute_coins.message_event_attribute_id
+		JOIN {message_event_attribute_keys} message_event_attribute_keys ON message_event_attribute_keys.id = message_event_attributes.message_event_attribute_key_id
+		JOIN {denoms} denoms ON denoms.id = message_event_attribute_coins.denom_id
+		WHERE message_event_attribute_coins.message_event_id IN ?
+		ORDER BY message_event_attribute_coins.message_event_id, message_event_attributes.index, message_event_attribute_coins.position`), messageEventIDs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	events := make([]Event, len(rows))
+	for i, row := range rows {
+		events[i] = Event{
+			ChainID:      row.ChainID,
+			Height:       row.Height,
+			Time:         row.Time,
+			Source:       row.Source,
+			TxHash:       row.TxHash,
+			MessageIndex: row.MessageIndex,
+			Index:        row.Index,
+			Type:         row.Type,
+		}
+		if row.Source == SourceMessage {
+			events[i].Attributes = messageAttributes[row.ID]
+			events[i].Coins = messageCoins[row.ID]
+		} else {
+			events[i].Attributes = blockAttributes[row.ID]
+			events[i].Coins = blockCoins[row.ID]
+		}
+	}
+
+	if len(rows) == 0 {
+		return events, "", nil
+	}
+	last := rows[len(rows)-1]
+	return events, q.scope.nextCursor(len(rows), "events", last.ChainID, last.Height, last.Ordinal, last.TxID, last.MessageIndex, last.Index), nil
+}
+
+// attributes runs an attribute query for the event IDs and groups the attributes by event
+func attributes(db *gorm.DB, query string, eventIDs []uint) (map[uint][]Attribute, error) {
+	grouped := make(map[uint][]Attribute, len(eventIDs))
+	if len(eventIDs) == 0 {
+		return grouped, nil
+	}
+
+	var rows []struct {
+		EventID         uint
+		Key             string
+		Value           string `gorm:"serializer:attribute_value"`
+		CompressedValue []byte `gorm:"serializer:attribute_value"`
+	}
+	if err := db.Raw(query, eventIDs).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		grouped[row.EventID] = append(grouped[row.EventID], Attribute{Key: row.Key, Value: row.Value})
+	}
+
+	return grouped, nil
+}
+
+// coins runs a coin query for the event IDs and groups the coins by event
+func coins(db *gorm.DB, query string, eventIDs []uint) (map[uint][]Coin, error) {
+	grouped := make(map[uint][]Coin, len(eventIDs))
+	if len(eventIDs) == 0 {
+		return grouped, nil
+	}
+
+	var rows []struct {
+		EventID uint
+		Coin
+	}
+	if err := db.Raw(query, eventIDs).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		grouped[row.EventID] = append(grouped[row.EventID], row.Coin)
+	}
+
+	return grouped, nil
+}
@@ -0,0 +1,134 @@
+package query
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+type Message struct {
+	ChainID string    `json:"chain_id"`
+	Height  int64     `json:"height"`
+	Time    time.Time `json:"time"`
+	TxHash  string    `json:"tx_hash"`
+	// Index is the position of the message in the transaction
+	Index int    `json:"index"`
+	Type  string `json:"type"`
+}
+
+// messageRow is a scanned message with the ID of its transaction, which orders the messages of a block
+type messageRow struct {
+	Message
+	TxID uint
+}
+
+var messageKeys = []string{"chains.chain_id", "blocks.height", "txes.id", "messages.message_index"}
+
+// MessagesQuery selects the messages of a type
+type MessagesQuery struct {
+	client      *Client
+	messageType string
+	signer      string
+	scope       scope
+}
+
+// MessagesByType selects the messages with the type URL, e.g. /cosmos.bank.v1beta1.MsgSend
+func (c *Client) MessagesByType(messageType string) *MessagesQuery {
+	return &MessagesQuery{client: c, messageType: messageType}
+}
+
+func (q *MessagesQuery) Chain(chainID string) *MessagesQuery {
+	q.scope.chainID = chainID
+	return q
+}
+
+// Heights limits the messages to a height range, 0 leaves a bound open
+func (q *MessagesQuery) Heights(startHeight int64, endHeight int64) *MessagesQuery {
+	q.scope.startHeight = startHeight
+	q.scope.endHeight = endHeight
+	return q
+}
+
+// SignedBy limits the messages to those of transactions the address signed
+func (q *MessagesQuery) SignedBy(address string) *MessagesQuery {
+	q.signer = address
+	return q
+}
+
+func (q *MessagesQuery) Limit(limit int) *MessagesQuery {
+	q.scope.limit = limit
+	return q
+}
+
+func (q *MessagesQuery) Offset(offset int) *MessagesQuery {
+	q.scope.offset = offset
+	return q
+}
+
+// After continues after the cursor returned with a previous page of the query
+func (q *MessagesQuery) After(cursor string) *MessagesQuery {
+	q.scope.continueAfter("messages", messageKeys, cursor)
+	return q
+}
+
+// Descending returns the latest messages first
+func (q *MessagesQuery) Descending() *MessagesQuery {
+	q.scope.descending = true
+	return q
+}
+
+func (q *MessagesQuery) sql() (string, []any) {
+	var query strings.Builder
+	query.WriteString(`SELECT chains.chain_id, blocks.height, blocks.time_stamp AS time, txes.id AS tx_id, txes.hash AS tx_hash,
+			messages.message_index AS index, message_types.message_type AS type
+		FROM {messages} messages
+		JOIN {message_types} message_types ON message_types.id = messages.message_type_id
+		JOIN {txes} txes ON txes.id = messages.tx_id
+		JOIN {blocks} blocks ON blocks.id = txes.block_id
+		JOIN {chains} chains ON chains.id = blocks.chain_id
+		WHERE message_types.message_type = ?`)
+	args := []any{q.messageType}
+	if q.signer != "" {
+		query.WriteString(`
+		AND txes.id IN (
+			SELECT tx_signer_addresses.tx_id
+			FROM {tx_signer_addresses} tx_signer_addresses
+			JOIN {addresses} addresses ON addresses.id = tx_signer_addresses.address_id
+			WHERE addresses.address = ?)`)
+		args = append(args, q.signer)
+	}
+	args = q.scope.where(&query, args)
+	args = q.scope.page(&query, args, messageKeys...)
+
+	return q.client.tables.Expand(query.String()), args
+}
+
+func (q *MessagesQuery) All(ctx context.Context) ([]Message, error) {
+	messages, _, err := q.Page(ctx)
+	return messages, err
+}
+
+// Page returns the messages and the cursor of the next page, which is empty once there are no more messages
+func (q *MessagesQuery) Page(ctx context.Context) ([]Message, string, error) {
+	if q.scope.err != nil {
+		return nil, "", q.scope.err
+	}
+
+	query, args := q.sql()
+
+	var rows []messageRow
+	if err := q.client.db.WithContext(ctx).Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	messages := make([]Message, len(rows))
+	for i, row := range rows {
+		messages[i] = row.Message
+	}
+
+	if len(rows) == 0 {
+		return messages, "", nil
+	}
+	last := rows[len(rows)-1]
+	return messages, q.scope.nextCursor(len(rows), "messages", last.ChainID, last.Height, last.TxID, last.Index), nil
+}
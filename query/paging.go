@@ -0,0 +1,45 @@
+package query
+
+import (
+	"context"
+	"errors"
+)
+
+// pager is implemented by the queries that return their results in pages
+type pager[T any, Q any] interface {
+	Limit(limit int) Q
+	After(cursor string) Q
+	Page(ctx context.Context) ([]T, string, error)
+}
+
+// Each runs the query page by page and calls fn with every result, so large result sets can be iterated without
+// loading them at once. Pages are continued with cursors, so results indexed during the iteration neither shift nor
+// repeat results. Iteration stops at the first error returned by fn.
+//
+//	err := query.Each(ctx, client.TxsByAddress(address), 500, func(tx query.Tx) error {
+//		return process(tx)
+//	})
+func Each[T any, Q pager[T, Q]](ctx context.Context, query Q, pageSize int, fn func(T) error) error {
+	if pageSize < 1 {
+		return errors.New("the page size must be at least 1")
+	}
+
+	query.Limit(pageSize)
+	for {
+		results, next, err := query.Page(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, result := range results {
+			if err := fn(result); err != nil {
+				return err
+			}
+		}
+
+		if next == "" {
+			return nil
+		}
+		query.After(next)
+	}
+}
@@ -0,0 +1,106 @@
+// Package query provides typed, read-only queries over the indexed schema. Downstream Go services should use it instead
+// of querying the indexer tables directly, the tables and their wrappers may change between releases while the result
+// types of this package stay stable.
+//
+// The result types are versioned independently of the internal db/models package: migrations of the indexer tables are
+// absorbed by the queries, and the types are only ever extended with new fields, so existing fields keep their meaning.
+package query
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Client runs queries against the database of the indexer
+type Client struct {
+	db *gorm.DB
+	// tables resolves the {table} placeholders of the queries through the naming strategy of the database
+	tables models.Tables
+	// datasets are the custom parser tables Dataset may query
+	datasets []string
+}
+
+func New(db *gorm.DB) *Client {
+	var namer schema.Namer = schema.NamingStrategy{}
+	if db != nil {
+		namer = db.NamingStrategy
+	}
+	return &Client{db: db, tables: models.NewTables(namer)}
+}
+
+// scope holds the chain, height range and paging shared by all queries
+type scope struct {
+	chainID     string
+	startHeight int64
+	endHeight   int64
+	limit       int
+	offset      int
+	descending  bool
+	// after holds the position of a cursor, err is set if the cursor could not be decoded
+	after []any
+	err   error
+}
+
+// continueAfter sets the cursor of the query, which must have been returned by the same kind of query
+func (s *scope) continueAfter(query string, keys []string, encoded string) {
+	s.after, s.err = decodeCursor(query, len(keys), encoded)
+}
+
+// nextCursor returns the cursor of the last result if the page is full, so there may be more results
+func (s scope) nextCursor(results int, query string, values ...any) string {
+	if s.limit <= 0 || results < s.limit {
+		return ""
+	}
+	return encodeCursor(query, values...)
+}
+
+// where appends the chain and height conditions, the query must join chains and blocks
+func (s scope) where(query *strings.Builder, args []any) []any {
+	if s.chainID != "" {
+		query.WriteString(" AND chains.chain_id = ?")
+		args = append(args, s.chainID)
+	}
+	if s.startHeight > 0 {
+		query.WriteString(" AND blocks.height >= ?")
+		args = append(args, s.startHeight)
+	}
+	if s.endHeight > 0 {
+		query.WriteString(" AND blocks.height <= ?")
+		args = append(args, s.endHeight)
+	}
+	return args
+}
+
+// page appends the cursor condition, order and paging clauses. The keys are the order columns, every one of them is
+// reversed for descending queries.
+func (s scope) page(query *strings.Builder, args []any, keys ...string) []any {
+	direction, comparison := "ASC", ">"
+	if s.descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if s.after != nil {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+		fmt.Fprintf(query, " AND (%s) %s (%s)", strings.Join(keys, ", "), comparison, placeholders)
+		args = append(args, s.after...)
+	}
+
+	ordered := make([]string, len(keys))
+	for i, column := range keys {
+		ordered[i] = column + " " + direction
+	}
+	fmt.Fprintf(query, " ORDER BY %s", strings.Join(ordered, ", "))
+
+	if s.limit > 0 {
+		fmt.Fprintf(query, " LIMIT %d", s.limit)
+	}
+	if s.offset > 0 {
+		fmt.Fprintf(query, " OFFSET %d", s.offset)
+	}
+
+	return args
+}
@@ -0,0 +1,272 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+type QueryTestSuite struct {
+	suite.Suite
+}
+
+func (suite *QueryTestSuite) TestBlocksInRange() {
+	query, args := New(nil).BlocksInRange(10, 20).Chain("cosmoshub-4").Limit(5).Descending().sql()
+	suite.Require().Contains(query, "AND chains.chain_id = ? AND blocks.height >= ? AND blocks.height <= ? ORDER BY chains.chain_id DESC, blocks.height DESC LIMIT 5")
+	suite.Require().Equal([]any{"cosmoshub-4", int64(10), int64(20)}, args)
+
+	query, args = New(nil).BlocksInRange(10, 0).sql()
+	suite.Require().NotContains(query, "blocks.height <= ?")
+	suite.Require().NotContains(query, "LIMIT")
+	suite.Require().Equal([]any{int64(10)}, args)
+}
+
+func (suite *QueryTestSuite) TestTableNaming() {
+	db := &gorm.DB{Config: &gorm.Config{NamingStrategy: schema.NamingStrategy{TablePrefix: "idx_", SingularTable: true}}}
+
+	// The tables are aliased to their default names, which the conditions refer to
+	query, _ := New(db).TxsByAddress("cosmos1abc").Chain("cosmoshub-4").sql()
+	suite.Require().Contains(query, `FROM "idx_tx" txes
+	JOIN "idx_block" blocks ON blocks.id = txes.block_id
+	JOIN "idx_chain" chains ON chains.id = blocks.chain_id`)
+	suite.Require().Contains(query, `FROM "idx_tx_signer_addresses" tx_signer_addresses`)
+	suite.Require().Contains(query, "AND chains.chain_id = ?")
+	suite.Require().NotContains(query, "{")
+
+	query, _ = New(db).EventsByType("transfer").WithAttributeKey("amount").sql()
+	suite.Require().Contains(query, `SELECT 1 FROM "idx_block_event_attribute" block_event_attributes`)
+	suite.Require().Contains(query, `FROM "idx_message_event" message_events`)
+}
+
+func (suite *QueryTestSuite) TestTxsByAddress() {
+	query, args := New(nil).TxsByAddress("cosmos1abc").Heights(0, 100).Offset(50).sql()
+	suite.Require().Contains(query, "WHERE addresses.address = ?) AND blocks.height <= ? ORDER BY chains.chain_id ASC, blocks.height ASC, txes.id ASC OFFSET 50")
+	suite.Require().Equal([]any{"cosmos1abc", int64(100)}, args)
+}
+
+func (suite *QueryTestSuite) TestTxsInvolving() {
+	query, args := New(nil).TxsInvolving("cosmos1abc").Chain("cosmoshub-4").sql()
+	suite.Require().Contains(query, `FROM "address_involvements"`)
+	suite.Require().NotContains(query, "SELECT tx_signer_addresses.tx_id")
+	suite.Require().Equal([]any{"cosmos1abc", "cosmoshub-4"}, args)
+}
+
+func (suite *QueryTestSuite) TestEventsByType() {
+	query, args := New(nil).EventsByType("transfer").Chain("cosmoshub-4").sql()
+	suite.Require().Contains(query, "UNION ALL")
+	suite.Require().Equal([]any{"transfer", "cosmoshub-4", "transfer", "cosmoshub-4"}, args)
+
+	query, args = New(nil).EventsByType("transfer").BlockEventsOnly().sql()
+	suite.Require().NotContains(query, "UNION ALL")
+	suite.Require().Contains(query, `FROM "block_events"`)
+	suite.Require().Equal([]any{"transfer"}, args)
+
+	query, _ = New(nil).EventsByType("transfer").MessageEventsOnly().sql()
+	suite.Require().NotContains(query, `FROM "block_events"`)
+	suite.Require().Contains(query, `FROM "message_events"`)
+}
+
+func (suite *QueryTestSuite) TestSearch() {
+	query, args := New(nil).SearchTxs("airdrop claim").Chain("cosmoshub-4").sql()
+	suite.Require().Contains(query, "AND to_tsvector('simple', txes.memo) @@ websearch_to_tsquery('simple', ?) AND chains.chain_id = ?")
+	suite.Require().Equal([]any{"airdrop claim", "cosmoshub-4"}, args)
+
+	query, args = New(nil).SearchEvents("cosmos1abc").sql()
+	suite.Require().NotContains(query, `FROM "block_events"`)
+	suite.Require().NotContains(query, "message_event_types.type = ?")
+	suite.Require().Contains(query, `FROM "message_event_searches"`)
+	suite.Require().Equal([]any{"cosmos1abc"}, args)
+
+	// Block events are never searchable
+	query, args = New(nil).EventsByType("transfer").Matching("cosmos1abc").sql()
+	suite.Require().NotContains(query, `FROM "block_events"`)
+	suite.Require().Equal([]any{"transfer", "cosmos1abc"}, args)
+
+	query, _ = New(nil).EventsByType("transfer").BlockEventsOnly().Matching("cosmos1abc").sql()
+	suite.Require().Empty(query)
+}
+
+func (suite *QueryTestSuite) TestCursors() {
+	cursor := encodeCursor("txs", "cosmoshub-4", int64(100), uint(7))
+
+	query, args := New(nil).TxsByAddress("cosmos1abc").After(cursor).sql()
+	suite.Require().Contains(query, "AND (chains.chain_id, blocks.height, txes.id) > (?, ?, ?) ORDER BY")
+	suite.Require().Equal([]any{"cosmos1abc", "cosmoshub-4", int64(100), int64(7)}, args)
+
+	query, _ = New(nil).TxsByAddress("cosmos1abc").After(cursor).Descending().sql()
+	suite.Require().Contains(query, "AND (chains.chain_id, blocks.height, txes.id) < (?, ?, ?) ORDER BY")
+
+	// Cursors of other queries and garbage are rejected
+	_, _, err := New(nil).BlocksInRange(1, 0).After(cursor).Page(context.Background())
+	suite.Require().ErrorIs(err, ErrInvalidCursor)
+	_, _, err = New(nil).BlocksInRange(1, 0).After("not a cursor").Page(context.Background())
+	suite.Require().ErrorIs(err, ErrInvalidCursor)
+
+	suite.Require().Empty(scope{limit: 10}.nextCursor(9, "txs", "cosmoshub-4", int64(1), uint(1)))
+	suite.Require().NotEmpty(scope{limit: 10}.nextCursor(10, "txs", "cosmoshub-4", int64(1), uint(1)))
+}
+
+func (suite *QueryTestSuite) TestMessagesByType() {
+	query, args := New(nil).MessagesByType("/cosmos.bank.v1beta1.MsgSend").Chain("cosmoshub-4").sql()
+	suite.Require().Contains(query, "WHERE message_types.message_type = ? AND chains.chain_id = ? ORDER BY")
+	suite.Require().Equal([]any{"/cosmos.bank.v1beta1.MsgSend", "cosmoshub-4"}, args)
+
+	query, args = New(nil).MessagesByType("/cosmos.bank.v1beta1.MsgSend").SignedBy("cosmos1abc").sql()
+	suite.Require().Contains(query, `FROM "tx_signer_addresses"`)
+	suite.Require().Equal([]any{"/cosmos.bank.v1beta1.MsgSend", "cosmos1abc"}, args)
+}
+
+func (suite *QueryTestSuite) TestTxsWithMessageType() {
+	query, args := New(nil).TxsInRange(10, 0).WithMessageType("/cosmos.bank.v1beta1.MsgSend").sql()
+	suite.Require().Contains(query, "WHERE message_types.message_type = ?) AND blocks.height >= ?")
+	suite.Require().Equal([]any{"/cosmos.bank.v1beta1.MsgSend", int64(10)}, args)
+}
+
+func (suite *QueryTestSuite) TestEventAttributes() {
+	query, args := New(nil).EventsByType("transfer").WithAttribute("recipient", "cosmos1abc").WithAttributeKey("amount").sql()
+	suite.Require().Contains(query, "WHERE block_event_attributes.block_event_id = block_events.id AND block_event_attribute_keys.key = ? AND block_event_attributes.value = ?)")
+	suite.Require().Contains(query, "WHERE message_event_attributes.message_event_id = message_events.id AND message_event_attribute_keys.key = ?)")
+	suite.Require().Equal([]any{"transfer", "recipient", "cosmos1abc", "amount", "transfer", "recipient", "cosmos1abc", "amount"}, args)
+}
+
+func (suite *QueryTestSuite) TestDataset() {
+	reference := &datasetReference{ReferencingColumn: "message_id", ReferencedTable: "messages"}
+	query, args, err := New(nil).Dataset("contract_events").Chain("cosmoshub-4").Heights(10, 20).sql("id", reference)
+	suite.Require().NoError(err)
+	suite.Require().Contains(query, `FROM "contract_events" d JOIN "messages" messages ON messages.id = d."message_id"`)
+	suite.Require().Contains(query, `ORDER BY chains.chain_id ASC, blocks.height ASC, d."id" ASC`)
+	suite.Require().Equal([]any{"cosmoshub-4", int64(10), int64(20)}, args)
+
+	// The rows are ordered by their primary key, whatever its name
+	query, args, err = New(nil).Dataset("prices").Limit(10).sql("price_id", nil)
+	suite.Require().NoError(err)
+	suite.Require().Contains(query, `SELECT '' AS chain_id, 0 AS height, d."price_id" AS id`)
+	suite.Require().Contains(query, `ORDER BY d."price_id" ASC LIMIT 10`)
+	suite.Require().Empty(args)
+
+	// The joined tables are named by the naming strategy of the database
+	db := &gorm.DB{Config: &gorm.Config{NamingStrategy: schema.NamingStrategy{TablePrefix: "idx_"}}}
+	query, _, err = New(db).Dataset("idx_contract_events").sql("id", reference)
+	suite.Require().NoError(err)
+	suite.Require().Contains(query, `JOIN "idx_messages" messages ON messages.id = d."message_id" JOIN "idx_txes" txes ON txes.id = messages.tx_id`)
+	suite.Require().Contains(query, `JOIN "idx_chains" chains ON chains.id = blocks.chain_id`)
+
+	_, _, err = New(nil).Dataset("prices").Chain("cosmoshub-4").sql("id", nil)
+	suite.Require().Error(err)
+
+	_, _, err = New(nil).Dataset("prices").Page(context.Background())
+	suite.Require().ErrorIs(err, ErrUnknownDataset)
+}
+
+// fakeQuery pages through the numbers up to total, its cursor is the last returned number
+type fakeQuery struct {
+	total int
+	limit int
+	after int
+	pages int
+}
+
+func (q *fakeQuery) Limit(limit int) *fakeQuery {
+	q.limit = limit
+	return q
+}
+
+func (q *fakeQuery) After(cursor string) *fakeQuery {
+	q.after, _ = strconv.Atoi(cursor)
+	return q
+}
+
+func (q *fakeQuery) Page(context.Context) ([]int, string, error) {
+	q.pages++
+	var results []int
+	for i := q.after + 1; i <= q.total && len(results) < q.limit; i++ {
+		results = append(results, i)
+	}
+	if len(results) < q.limit {
+		return results, "", nil
+	}
+	return results, strconv.Itoa(results[len(results)-1]), nil
+}
+
+func (suite *QueryTestSuite) TestEach() {
+	query := &fakeQuery{total: 25}
+	var seen []int
+	err := Each(context.Background(), query, 10, func(result int) error {
+		seen = append(seen, result)
+		return nil
+	})
+	suite.Require().NoError(err)
+	suite.Require().Len(seen, 25)
+	suite.Require().Equal(25, seen[24])
+	suite.Require().Equal(3, query.pages)
+
+	stop := errors.New("stop")
+	err = Each(context.Background(), &fakeQuery{total: 25}, 10, func(result int) error {
+		return stop
+	})
+	suite.Require().ErrorIs(err, stop)
+
+	err = Each(context.Background(), &fakeQuery{total: 25}, 0, func(result int) error { return nil })
+	suite.Require().Error(err)
+}
+
+func (suite *QueryTestSuite) TestParseTransfers() {
+	attributes := []transferAttribute{
+		// A merged event of an older SDK version holding two transfers
+		{EventID: 1, Key: "recipient", Value: "cosmos1a"},
+		{EventID: 1, Key: "sender", Value: "cosmos1b"},
+		{EventID: 1, Key: "amount", Value: "10uatom"},
+		{EventID: 1, Key: "recipient", Value: "cosmos1c"},
+		{EventID: 1, Key: "sender", Value: "cosmos1a"},
+		{EventID: 1, Key: "amount", Value: "3uatom,1ibc/ABC"},
+		// An incomplete event does not leak into the next one
+		{EventID: 2, Key: "recipient", Value: "cosmos1a"},
+		{EventID: 3, Key: "recipient", Value: "cosmos1a"},
+		{EventID: 3, Key: "msg_index", Value: "0"},
+		{EventID: 3, Key: "sender", Value: "cosmos1d"},
+		{EventID: 3, Key: "amount", Value: "5uatom"},
+	}
+
+	transfers := parseTransfers(attributes)
+	suite.Require().Equal([]transfer{
+		{recipient: "cosmos1a", sender: "cosmos1b", amount: "10uatom"},
+		{recipient: "cosmos1c", sender: "cosmos1a", amount: "3uatom,1ibc/ABC"},
+		{recipient: "cosmos1a", sender: "cosmos1d", amount: "5uatom"},
+	}, transfers)
+}
+
+func (suite *QueryTestSuite) TestParseTime() {
+	location, err := time.LoadLocation("America/New_York")
+	suite.Require().NoError(err)
+
+	// Dates start at midnight in the location, times carry their own offset
+	parsed, err := ParseTime("2024-03-10", location)
+	suite.Require().NoError(err)
+	suite.Require().Equal(time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC), parsed.UTC())
+
+	parsed, err = ParseTime("2024-03-10T12:00:00Z", location)
+	suite.Require().NoError(err)
+	suite.Require().Equal(time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC), parsed.UTC())
+
+	_, err = ParseTime("03/10/2024", location)
+	suite.Require().Error(err)
+
+	interval, err := ParseBucketInterval("week")
+	suite.Require().NoError(err)
+	suite.Require().Equal(BucketWeek, interval)
+	_, err = ParseBucketInterval("minute")
+	suite.Require().Error(err)
+
+	_, err = New(nil).TimeBuckets(context.Background(), "cosmoshub-4", BucketHour, location, parsed, parsed.AddDate(2, 0, 0))
+	suite.Require().ErrorIs(err, ErrTooManyBuckets)
+}
+
+func TestQueryTestSuite(t *testing.T) {
+	suite.Run(t, new(QueryTestSuite))
+}
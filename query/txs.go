@@ -0,0 +1,213 @@
+package query
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+type Tx struct {
+	ChainID string    `json:"chain_id"`
+	Height  int64     `json:"height"`
+	Time    time.Time `json:"time"`
+	Hash    string    `json:"hash"`
+	Code    uint32    `json:"code"`
+	Signers []string  `json:"signers"`
+}
+
+// txRow is a scanned transaction, the signers are aggregated to a comma separated list
+type txRow struct {
+	ID      uint
+	ChainID string
+	Height  int64
+	Time    time.Time
+	Hash    string
+	Code    uint32
+	Signers string
+}
+
+var txKeys = []string{"chains.chain_id", "blocks.height", "txes.id"}
+
+// TxsQuery selects transactions, optionally only those signed by or involving an address
+type TxsQuery struct {
+	client      *Client
+	address     string
+	involved    bool
+	search      string
+	messageType string
+	scope       scope
+}
+
+// TxsByAddress selects the transactions the address signed
+func (c *Client) TxsByAddress(address string) *TxsQuery {
+	return &TxsQuery{client: c, address: address}
+}
+
+// TxsInvolving selects the transactions that touched the address: signed by it, paid for by it or naming it in a
+// message event attribute. They are read from the address_involvements table, which is only filled for blocks indexed
+// with flags.index-address-involvements.
+func (c *Client) TxsInvolving(address string) *TxsQuery {
+	return &TxsQuery{client: c, address: address, involved: true}
+}
+
+// SearchTxs selects the transactions whose memo matches the search text, see Matching
+func (c *Client) SearchTxs(text string) *TxsQuery {
+	return (&TxsQuery{client: c}).Matching(text)
+}
+
+// TxsInRange selects the transactions of a height range, 0 leaves a bound open
+func (c *Client) TxsInRange(startHeight int64, endHeight int64) *TxsQuery {
+	return (&TxsQuery{client: c}).Heights(startHeight, endHeight)
+}
+
+func (q *TxsQuery) Chain(chainID string) *TxsQuery {
+	q.scope.chainID = chainID
+	return q
+}
+
+// Heights limits the transactions to a height range, 0 leaves a bound open
+func (q *TxsQuery) Heights(startHeight int64, endHeight int64) *TxsQuery {
+	q.scope.startHeight = startHeight
+	q.scope.endHeight = endHeight
+	return q
+}
+
+// Matching limits the transactions to those whose memo matches the search text. The text is parsed like a web search:
+// quoted phrases, "or" and "-" to exclude a word. Memos are searchable once the indexer ran with search.enabled.
+func (q *TxsQuery) Matching(text string) *TxsQuery {
+	q.search = text
+	return q
+}
+
+// WithMessageType limits the transactions to those with a message of the type URL, e.g. /cosmos.bank.v1beta1.MsgSend
+func (q *TxsQuery) WithMessageType(messageType string) *TxsQuery {
+	q.messageType = messageType
+	return q
+}
+
+func (q *TxsQuery) Limit(limit int) *TxsQuery {
+	q.scope.limit = limit
+	return q
+}
+
+func (q *TxsQuery) Offset(offset int) *TxsQuery {
+	q.scope.offset = offset
+	return q
+}
+
+// After continues after the cursor returned with a previous page of the query
+func (q *TxsQuery) After(cursor string) *TxsQuery {
+	q.scope.continueAfter("txs", txKeys, cursor)
+	return q
+}
+
+// Descending returns the latest transactions first
+func (q *TxsQuery) Descending() *TxsQuery {
+	q.scope.descending = true
+	return q
+}
+
+const txSelect = `SELECT txes.id, chains.chain_id, blocks.height, blocks.time_stamp AS time, txes.hash, txes.code,
+		COALESCE((SELECT string_agg(signers.address, ',' ORDER BY signers.address)
+			FROM {tx_signer_addresses} tx_signer_addresses
+			JOIN {addresses} signers ON signers.id = tx_signer_addresses.address_id
+			WHERE tx_signer_addresses.tx_id = txes.id), '') AS signers
+	FROM {txes} txes
+	JOIN {blocks} blocks ON blocks.id = txes.block_id
+	JOIN {chains} chains ON chains.id = blocks.chain_id`
+
+func (q *TxsQuery) sql() (string, []any) {
+	var query strings.Builder
+	query.WriteString(txSelect)
+	query.WriteString(" WHERE TRUE")
+
+	var args []any
+	if q.address != "" && q.involved {
+		query.WriteString(`
+		AND txes.id IN (
+			SELECT address_involvements.tx_id
+			FROM {address_involvements} address_involvements
+			JOIN {addresses} addresses ON addresses.id = address_involvements.address_id
+			WHERE addresses.address = ?)`)
+		args = append(args, q.address)
+	} else if q.address != "" {
+		query.WriteString(`
+		AND txes.id IN (
+			SELECT tx_signer_addresses.tx_id
+			FROM {tx_signer_addresses} tx_signer_addresses
+			JOIN {addresses} addresses ON addresses.id = tx_signer_addresses.address_id
+			WHERE addresses.address = ?)`)
+		args = append(args, q.address)
+	}
+	if q.messageType != "" {
+		query.WriteString(`
+		AND txes.id IN (
+			SELECT messages.tx_id
+			FROM {messages} messages
+			JOIN {message_types} message_types ON message_types.id = messages.message_type_id
+			WHERE message_types.message_type = ?)`)
+		args = append(args, q.messageType)
+	}
+	if q.search != "" {
+		query.WriteString(`
+		AND to_tsvector('simple', txes.memo) @@ websearch_to_tsquery('simple', ?)`)
+		args = append(args, q.search)
+	}
+	args = q.scope.where(&query, args)
+	args = q.scope.page(&query, args, txKeys...)
+
+	return q.client.tables.Expand(query.String()), args
+}
+
+func (q *TxsQuery) All(ctx context.Context) ([]Tx, error) {
+	txs, _, err := q.Page(ctx)
+	return txs, err
+}
+
+// Page returns the transactions and the cursor of the next page, which is empty once there are no more transactions
+func (q *TxsQuery) Page(ctx context.Context) ([]Tx, string, error) {
+	if q.scope.err != nil {
+		return nil, "", q.scope.err
+	}
+
+	query, args := q.sql()
+
+	var rows []txRow
+	if err := q.client.db.WithContext(ctx).Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	txs := make([]Tx, len(rows))
+	for i, row := range rows {
+		txs[i] = row.tx()
+	}
+
+	if len(rows) == 0 {
+		return txs, "", nil
+	}
+	last := rows[len(rows)-1]
+	return txs, q.scope.nextCursor(len(rows), "txs", last.ChainID, last.Height, last.ID), nil
+}
+
+// TxByHash returns the transaction with the hash, nil if it was not indexed
+func (c *Client) TxByHash(ctx context.Context, hash string) (*Tx, error) {
+	var rows []txRow
+	if err := c.db.WithContext(ctx).Raw(c.tables.Expand(txSelect)+" WHERE txes.hash = ?", hash).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	tx := rows[0].tx()
+	return &tx, nil
+}
+
+func (row txRow) tx() Tx {
+	tx := Tx{ChainID: row.ChainID, Height: row.Height, Time: row.Time, Hash: row.Hash, Code: row.Code}
+	if row.Signers != "" {
+		tx.Signers = strings.Split(row.Signers, ",")
+	}
+	return tx
+}
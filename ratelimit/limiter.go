@@ -0,0 +1,159 @@
+// Package ratelimit limits the request rate of API clients with token buckets.
+package ratelimit
+
+import (
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/auth"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
+)
+
+// Idle buckets are swept at most this often, a bucket is idle once it refilled completely
+const sweepInterval = time.Minute
+
+// Limit is a sustained rate of requests per second and the burst of requests allowed on top of it
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// Limiter keeps a token bucket per API key, or per IP address for requests without a key. A nil Limiter does not
+// limit anything.
+type Limiter struct {
+	limit          Limit
+	trustedProxies int
+	now            func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New creates a limiter applying the limit to every client, unless an API key overrides it. With trustedProxies above
+// 0 the client IP is taken from the X-Forwarded-For header appended to by that many proxies in front of the server, 0
+// takes the address of the peer.
+func New(limit Limit, trustedProxies int) *Limiter {
+	return &Limiter{limit: limit, trustedProxies: trustedProxies, now: time.Now, buckets: map[string]*bucket{}}
+}
+
+// allow takes a token from the bucket of the client, or returns how long until the next token is available
+func (l *Limiter) allow(client string, limit Limit) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[client]
+	if !ok || b.limit != limit {
+		b = &bucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		l.buckets[client] = b
+	}
+
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep removes the buckets that refilled completely, they behave like new ones. Must be called with the lock held.
+func (l *Limiter) sweep(now time.Time) {
+	for client, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+			delete(l.buckets, client)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Middleware limits the requests to the api, it must run after the auth middleware so requests are counted against
+// their API key. Limited requests are answered with 429 and a Retry-After header.
+func (l *Limiter) Middleware(api string, next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		client, metricsClient, limit := l.client(r)
+
+		allowed, retryAfter := l.allow(client, limit)
+		metrics.ObserveAPIRequest(api, metricsClient, allowed)
+		if !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "rate limit exceeded"})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// client returns the bucket name of the request, its metrics label and its limit. Authenticated requests are limited
+// per API key or token subject, with the limit of the key if it sets one, everything else per IP address.
+func (l *Limiter) client(r *http.Request) (string, string, Limit) {
+	if principal := auth.FromContext(r.Context()); principal != nil && !principal.Anonymous() {
+		limit := l.limit
+		if principal.Rate > 0 {
+			limit.Rate = principal.Rate
+		}
+		if principal.Burst > 0 {
+			limit.Burst = principal.Burst
+		}
+		// Token subjects are not used as metrics label, there may be any number of them
+		if principal.JWT {
+			return "jwt:" + principal.Name, "jwt", limit
+		}
+		return "key:" + principal.Name, principal.Name, limit
+	}
+
+	return "ip:" + l.clientIP(r), "ip", l.limit
+}
+
+// clientIP returns the address the trusted proxy farthest from the server received the request from. Every proxy
+// appends its peer to the X-Forwarded-For header the client sent, so only the entries appended by the trusted proxies,
+// counted from the right, are not made up by the client.
+func (l *Limiter) clientIP(r *http.Request) string {
+	if l.trustedProxies > 0 {
+		var forwarded []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, address := range strings.Split(header, ",") {
+				if address = strings.TrimSpace(address); address != "" {
+					forwarded = append(forwarded, address)
+				}
+			}
+		}
+		// Fewer entries than trusted proxies were all appended by the proxies, the first is the closest to the client
+		if len(forwarded) >= l.trustedProxies {
+			return forwarded[len(forwarded)-l.trustedProxies]
+		} else if len(forwarded) != 0 {
+			return forwarded[0]
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
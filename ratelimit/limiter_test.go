@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/auth"
+	"github.com/stretchr/testify/suite"
+)
+
+type LimiterTestSuite struct {
+	suite.Suite
+}
+
+func (suite *LimiterTestSuite) TestAllow() {
+	now := time.Unix(0, 0)
+	limiter := New(Limit{Rate: 2, Burst: 3}, 0)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		allowed, _ := limiter.allow("ip:10.0.0.1", limiter.limit)
+		suite.Require().True(allowed)
+	}
+	allowed, retryAfter := limiter.allow("ip:10.0.0.1", limiter.limit)
+	suite.Require().False(allowed)
+	suite.Require().Equal(500*time.Millisecond, retryAfter)
+
+	// Other clients have their own bucket
+	allowed, _ = limiter.allow("ip:10.0.0.2", limiter.limit)
+	suite.Require().True(allowed)
+
+	now = now.Add(500 * time.Millisecond)
+	allowed, _ = limiter.allow("ip:10.0.0.1", limiter.limit)
+	suite.Require().True(allowed)
+
+	// Full buckets are swept
+	now = now.Add(sweepInterval)
+	_, _ = limiter.allow("ip:10.0.0.3", limiter.limit)
+	suite.Require().Len(limiter.buckets, 1)
+}
+
+func (suite *LimiterTestSuite) TestMiddleware() {
+	limiter := New(Limit{Rate: 1, Burst: 1}, 1)
+	handler := limiter.Middleware("rest", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serve := func(principal *auth.Principal, forwardedFor string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/blocks", nil)
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		if principal != nil {
+			r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	suite.Require().Equal(http.StatusOK, serve(nil, "192.168.0.1, 10.0.0.1").Code)
+	limited := serve(nil, "10.0.0.1")
+	suite.Require().Equal(http.StatusTooManyRequests, limited.Code)
+	suite.Require().Equal("1", limited.Header().Get("Retry-After"))
+	suite.Require().Equal(http.StatusOK, serve(nil, "10.0.0.2").Code)
+
+	// Keys are limited by name regardless of the address, with their own limit
+	key := &auth.Principal{Name: "dashboard", Burst: 2}
+	suite.Require().Equal(http.StatusOK, serve(key, "10.0.0.1").Code)
+	suite.Require().Equal(http.StatusOK, serve(key, "10.0.0.1").Code)
+	suite.Require().Equal(http.StatusTooManyRequests, serve(key, "10.0.0.1").Code)
+}
+
+func (suite *LimiterTestSuite) TestSpoofedForwardedFor() {
+	limiter := New(Limit{Rate: 1, Burst: 1}, 1)
+	handler := limiter.Middleware("rest", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	// The client makes up the first entries, the proxy appends the address it received the request from
+	serve := func(spoofed string) int {
+		r := httptest.NewRequest(http.MethodGet, "/blocks", nil)
+		r.Header.Set("X-Forwarded-For", spoofed+", 10.0.0.1")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		return recorder.Code
+	}
+	suite.Require().Equal(http.StatusOK, serve("1.1.1.1"))
+	suite.Require().Equal(http.StatusTooManyRequests, serve("2.2.2.2"))
+	suite.Require().Equal(http.StatusTooManyRequests, serve("3.3.3.3, 4.4.4.4"))
+
+	// Behind two proxies the entry appended by the outer one is the client, also when the inner one adds a header line
+	limiter = New(Limit{Rate: 1, Burst: 1}, 2)
+	r := httptest.NewRequest(http.MethodGet, "/blocks", nil)
+	r.Header.Add("X-Forwarded-For", "1.1.1.1, 10.0.0.2")
+	r.Header.Add("X-Forwarded-For", "172.16.0.1")
+	suite.Require().Equal("10.0.0.2", limiter.clientIP(r))
+
+	// Without the header the peer is the client
+	r = httptest.NewRequest(http.MethodGet, "/blocks", nil)
+	r.RemoteAddr = "10.0.0.3:4321"
+	suite.Require().Equal("10.0.0.3", limiter.clientIP(r))
+}
+
+func TestLimiterTestSuite(t *testing.T) {
+	suite.Run(t, new(LimiterTestSuite))
+}
@@ -0,0 +1,145 @@
+package restapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Version of the API, reported in the OpenAPI spec
+const Version = "1.4.0"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Spec builds the OpenAPI 3 spec of the routes. It is written to openapi.json by go generate, clients are generated
+// from that file.
+func Spec() ([]byte, error) {
+	schemas := map[string]any{
+		"Error": map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"error": map[string]any{"type": "string"}},
+			"required":   []string{"error"},
+		},
+	}
+
+	paths := map[string]any{}
+	for _, route := range routes {
+		responseSchema := schemaRef(route.response, schemas)
+		if route.list {
+			pageName := route.response.Name() + "Page"
+			schemas[pageName] = map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"data":        map[string]any{"type": "array", "items": responseSchema},
+					"next_cursor": map[string]any{"type": "string", "description": "cursor of the next page, absent on the last page"},
+				},
+				"required": []string{"data"},
+			}
+			responseSchema = map[string]any{"$ref": "#/components/schemas/" + pageName}
+		}
+
+		parameters := make([]any, 0, len(route.parameters))
+		for _, param := range route.parameters {
+			parameters = append(parameters, map[string]any{
+				"name":        param.name,
+				"in":          param.in,
+				"description": param.description,
+				"required":    param.required,
+				"schema":      map[string]any{"type": param.kind},
+			})
+		}
+
+		errorResponse := map[string]any{
+			"description": "error",
+			"content":     map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}}},
+		}
+
+		paths[route.path] = map[string]any{
+			"get": map[string]any{
+				"operationId": route.operationID,
+				"summary":     route.summary,
+				"parameters":  parameters,
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "success",
+						"content":     map[string]any{"application/json": map[string]any{"schema": responseSchema}},
+					},
+					"default": errorResponse,
+				},
+			},
+		}
+	}
+
+	spec := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "Cosmos Indexer API",
+			"description": "Read-only access to the data indexed by the cosmos-indexer.",
+			"version":     Version,
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": schemas,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "description": "API key or JWT"},
+				"apiKeyAuth": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			},
+		},
+		// Authentication is only required when the server enables it, hence the empty requirement
+		"security": []any{
+			map[string]any{"bearerAuth": []string{}},
+			map[string]any{"apiKeyAuth": []string{}},
+			map[string]any{},
+		},
+	}
+
+	data, err := json.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// schemaRef returns the schema of a Go type, structs are added to the component schemas and referenced
+func schemaRef(t reflect.Type, schemas map[string]any) map[string]any {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Slice:
+		return map[string]any{"type": "array", "items": schemaRef(t.Elem(), schemas)}
+	case t.Kind() == reflect.Struct:
+		if _, ok := schemas[t.Name()]; !ok {
+			// Registered before the fields are walked so recursive types terminate
+			schemas[t.Name()] = nil
+			schemas[t.Name()] = structSchema(t, schemas)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	case t.Kind() == reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	properties := map[string]any{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" || name == "" {
+			continue
+		}
+
+		properties[name] = schemaRef(field.Type, schemas)
+		if options != "omitempty" {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]any{"type": "object", "properties": properties, "required": required}
+}
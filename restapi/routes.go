@@ -0,0 +1,134 @@
+package restapi
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/DefiantLabs/cosmos-indexer/query"
+)
+
+type parameterIn string
+
+const (
+	inPath  parameterIn = "path"
+	inQuery parameterIn = "query"
+)
+
+type parameter struct {
+	name        string
+	in          parameterIn
+	kind        string
+	description string
+	required    bool
+}
+
+// route is an endpoint of the API. The routes are the single source of the router and the OpenAPI spec, so the spec
+// cannot drift from what is served.
+type route struct {
+	path        string
+	operationID string
+	summary     string
+	parameters  []parameter
+	// response is the type of the returned entity, list routes return a page of them
+	response reflect.Type
+	list     bool
+	handle   func(s *handler, w http.ResponseWriter, r *http.Request, pathParams map[string]string)
+}
+
+var (
+	chainParameter       = parameter{name: "chain_id", in: inQuery, kind: "string", description: "only return results of this chain"}
+	startHeightParameter = parameter{name: "start_height", in: inQuery, kind: "integer", description: "first height to return, inclusive"}
+	endHeightParameter   = parameter{name: "end_height", in: inQuery, kind: "integer", description: "last height to return, inclusive"}
+	limitParameter       = parameter{name: "limit", in: inQuery, kind: "integer", description: "maximum number of results, defaults to 100 and is capped at 1000"}
+	offsetParameter      = parameter{name: "offset", in: inQuery, kind: "integer", description: "number of results to skip, prefer cursor for large result sets"}
+	cursorParameter      = parameter{name: "cursor", in: inQuery, kind: "string", description: "next_cursor of the previous page, continues right after its last result"}
+	orderParameter       = parameter{name: "order", in: inQuery, kind: "string", description: "asc (default) or desc by height"}
+)
+
+// pageParameters are the parameters of every list route
+var pageParameters = []parameter{chainParameter, startHeightParameter, endHeightParameter, limitParameter, offsetParameter, cursorParameter, orderParameter}
+
+var routes = []route{
+	{
+		path:        "/chains",
+		operationID: "listChains",
+		summary:     "List the indexed chains",
+		response:    reflect.TypeOf(query.Chain{}),
+		list:        true,
+		handle:      (*handler).chains,
+	},
+	{
+		path:        "/blocks",
+		operationID: "listBlocks",
+		summary:     "List the indexed blocks of a height range",
+		parameters:  pageParameters,
+		response:    reflect.TypeOf(query.Block{}),
+		list:        true,
+		handle:      (*handler).blocks,
+	},
+	{
+		path:        "/txs/{hash}",
+		operationID: "getTx",
+		summary:     "Get a transaction by hash",
+		parameters:  []parameter{{name: "hash", in: inPath, kind: "string", description: "transaction hash", required: true}},
+		response:    reflect.TypeOf(query.Tx{}),
+		handle:      (*handler).tx,
+	},
+	{
+		path:        "/addresses/{address}/txs",
+		operationID: "listAddressTxs",
+		summary:     "List the transactions signed by an address",
+		parameters:  append([]parameter{{name: "address", in: inPath, kind: "string", description: "signer address", required: true}}, pageParameters...),
+		response:    reflect.TypeOf(query.Tx{}),
+		list:        true,
+		handle:      (*handler).addressTxs,
+	},
+	{
+		path:        "/addresses/{address}/balances",
+		operationID: "getAddressBalances",
+		summary:     "Get the balances of an address at an indexed height, computed from the indexed transfers and fees",
+		parameters: []parameter{
+			{name: "address", in: inPath, kind: "string", description: "account address", required: true},
+			{name: "chain_id", in: inQuery, kind: "string", description: "chain of the address", required: true},
+			{name: "height", in: inQuery, kind: "integer", description: "height of the balances, defaults to the latest indexed height"},
+		},
+		response: reflect.TypeOf(query.Balances{}),
+		handle:   (*handler).addressBalances,
+	},
+	{
+		path:        "/messages",
+		operationID: "listMessages",
+		summary:     "List the messages of a type",
+		parameters:  append([]parameter{{name: "type", in: inQuery, kind: "string", description: "message type URL, e.g. /cosmos.bank.v1beta1.MsgSend", required: true}}, pageParameters...),
+		response:    reflect.TypeOf(query.Message{}),
+		list:        true,
+		handle:      (*handler).messages,
+	},
+	{
+		path:        "/events",
+		operationID: "listEvents",
+		summary:     "List the block and message events of a type in execution order",
+		parameters: append([]parameter{
+			{name: "type", in: inQuery, kind: "string", description: "event type, e.g. transfer", required: true},
+			{name: "source", in: inQuery, kind: "string", description: "block to only return BeginBlock and EndBlock events, message to only return message events"},
+		}, pageParameters...),
+		response: reflect.TypeOf(query.Event{}),
+		list:     true,
+		handle:   (*handler).events,
+	},
+	{
+		path:        "/time-buckets",
+		operationID: "listTimeBuckets",
+		summary:     "Aggregate the indexed blocks and transactions of a chain per hour, day, week or month in a timezone",
+		parameters: []parameter{
+			{name: "chain_id", in: inQuery, kind: "string", description: "chain to aggregate", required: true},
+			{name: "start_time", in: inQuery, kind: "string", description: "start of the time range, inclusive, as RFC 3339 time or as date starting at midnight in the timezone", required: true},
+			{name: "end_time", in: inQuery, kind: "string", description: "end of the time range, exclusive, as RFC 3339 time or as date starting at midnight in the timezone", required: true},
+			{name: "interval", in: inQuery, kind: "string", description: "hour, day (default), week or month"},
+			{name: "timezone", in: inQuery, kind: "string", description: "IANA time zone of the calendar the buckets follow, e.g. America/New_York, defaults to UTC"},
+		},
+		response: reflect.TypeOf(query.TimeBucket{}),
+		list:     true,
+		handle:   (*handler).timeBuckets,
+	},
+}
@@ -0,0 +1,470 @@
+package restapi
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/auth"
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/eventbus"
+	"github.com/DefiantLabs/cosmos-indexer/query"
+	"github.com/DefiantLabs/cosmos-indexer/ratelimit"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+)
+
+// openAPISpec is generated from the routes by go generate, TestSpecUpToDate fails if it is stale
+//
+//go:generate go run ../tools/openapi openapi.json
+//go:embed openapi.json
+var openAPISpec []byte
+
+// Page is the response of list routes
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// Server serves the query API over HTTP
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+}
+
+// NewServer creates a server listening on address, it starts serving once Serve is called. Clients of /stream are
+// pushed the data of the CDC events published on the bus, a nil bus disables streaming. A nil authenticator disables
+// authentication and a nil limiter rate limiting.
+func NewServer(address string, client *query.Client, events *eventbus.Bus[db.CDCEvent], authenticator *auth.Authenticator, limiter *ratelimit.Limiter) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		server:   &http.Server{Handler: NewHandler(client, events, authenticator, limiter), ReadHeaderTimeout: 10 * time.Second},
+		listener: listener,
+	}, nil
+}
+
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+func (s *Server) Serve() error {
+	err := s.server.Serve(s.listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting requests and waits up to 10 seconds for the running requests to finish
+func (s *Server) Shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		config.Log.Error("Error shutting down the REST API server", err)
+	}
+}
+
+type handler struct {
+	client *query.Client
+	bus    *eventbus.Bus[db.CDCEvent]
+}
+
+// NewHandler returns the handler of the API routes, /openapi.json and the /stream WebSocket. The routes require the
+// read scope and /stream the stream scope, the spec is public.
+func NewHandler(client *query.Client, events *eventbus.Bus[db.CDCEvent], authenticator *auth.Authenticator, limiter *ratelimit.Limiter) http.Handler {
+	return authenticator.Middleware(limiter.Middleware("rest", &handler{client: client, bus: events}))
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "only GET requests are supported")
+		return
+	}
+
+	if r.URL.Path == "/openapi.json" {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(openAPISpec)
+		return
+	}
+
+	if r.URL.Path == "/stream" {
+		if authorize(w, r, auth.ScopeStream) {
+			h.stream(w, r)
+		}
+		return
+	}
+
+	for _, route := range routes {
+		if pathParams, ok := matchPath(route.path, r.URL.Path); ok {
+			if authorize(w, r, auth.ScopeRead) {
+				route.handle(h, w, r, pathParams)
+			}
+			return
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "not found")
+}
+
+// authorize responds with 401 or 403 and returns false unless the client was granted the scope
+func authorize(w http.ResponseWriter, r *http.Request, scope auth.Scope) bool {
+	err := auth.Check(r.Context(), scope)
+	switch {
+	case errors.Is(err, auth.ErrUnauthenticated):
+		auth.Unauthorized(w, err.Error())
+		return false
+	case err != nil:
+		writeError(w, http.StatusForbidden, fmt.Sprintf("the %s scope is required", scope))
+		return false
+	}
+	return true
+}
+
+// matchPath matches a request path against a route path, {name} segments match any segment and are returned by name
+func matchPath(pattern string, path string) (map[string]string, bool) {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+	if len(patternSegments) != len(pathSegments) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+	for i, segment := range patternSegments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if pathSegments[i] == "" {
+				return nil, false
+			}
+			params[strings.Trim(segment, "{}")] = pathSegments[i]
+		} else if segment != pathSegments[i] {
+			return nil, false
+		}
+	}
+
+	return params, true
+}
+
+// pageRequest holds the parameters shared by the list routes
+type pageRequest struct {
+	chainID     string
+	startHeight int64
+	endHeight   int64
+	limit       int
+	offset      int
+	cursor      string
+	descending  bool
+}
+
+func parsePageRequest(r *http.Request) (pageRequest, error) {
+	values := r.URL.Query()
+	request := pageRequest{chainID: values.Get("chain_id"), cursor: values.Get("cursor"), limit: defaultLimit}
+
+	var err error
+	if request.startHeight, err = int64Parameter(values.Get("start_height"), "start_height"); err != nil {
+		return request, err
+	}
+	if request.endHeight, err = int64Parameter(values.Get("end_height"), "end_height"); err != nil {
+		return request, err
+	}
+	if request.endHeight > 0 && request.endHeight < request.startHeight {
+		return request, errors.New("end_height must not be below start_height")
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		parsed, err := int64Parameter(limit, "limit")
+		if err != nil {
+			return request, err
+		}
+		if parsed < 1 || parsed > maxLimit {
+			return request, fmt.Errorf("limit must be between 1 and %d", maxLimit)
+		}
+		request.limit = int(parsed)
+	}
+
+	offset, err := int64Parameter(values.Get("offset"), "offset")
+	if err != nil {
+		return request, err
+	}
+	request.offset = int(offset)
+
+	switch values.Get("order") {
+	case "", "asc":
+	case "desc":
+		request.descending = true
+	default:
+		return request, errors.New("order must be asc or desc")
+	}
+
+	return request, nil
+}
+
+func int64Parameter(value string, name string) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("%s must be a positive integer", name)
+	}
+	return parsed, nil
+}
+
+func (h *handler) chains(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	chains, err := h.client.Chains(r.Context())
+	writeResults(w, chains, "", err)
+}
+
+func (h *handler) blocks(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := h.client.BlocksInRange(page.startHeight, page.endHeight).Chain(page.chainID).Limit(page.limit).Offset(page.offset)
+	if page.descending {
+		q.Descending()
+	}
+
+	if page.cursor != "" {
+		q.After(page.cursor)
+	}
+
+	blocks, next, err := q.Page(r.Context())
+	writeResults(w, blocks, next, err)
+}
+
+func (h *handler) tx(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	tx, err := h.client.TxByHash(r.Context(), pathParams["hash"])
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	if tx == nil {
+		writeError(w, http.StatusNotFound, "transaction not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tx)
+}
+
+func (h *handler) addressTxs(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := h.client.TxsByAddress(pathParams["address"]).Chain(page.chainID).Heights(page.startHeight, page.endHeight).Limit(page.limit).Offset(page.offset)
+	if page.descending {
+		q.Descending()
+	}
+
+	if page.cursor != "" {
+		q.After(page.cursor)
+	}
+
+	txs, next, err := q.Page(r.Context())
+	writeResults(w, txs, next, err)
+}
+
+func (h *handler) addressBalances(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+	chainID := r.URL.Query().Get("chain_id")
+	if chainID == "" {
+		writeError(w, http.StatusBadRequest, "chain_id is required")
+		return
+	}
+
+	height, err := int64Parameter(r.URL.Query().Get("height"), "height")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	balances, err := h.client.BalancesAt(r.Context(), chainID, pathParams["address"], height)
+	if errors.Is(err, query.ErrHeightNotIndexed) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, balances)
+}
+
+func (h *handler) messages(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	messageType := r.URL.Query().Get("type")
+	if messageType == "" {
+		writeError(w, http.StatusBadRequest, "type is required")
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := h.client.MessagesByType(messageType).Chain(page.chainID).Heights(page.startHeight, page.endHeight).Limit(page.limit).Offset(page.offset)
+	if page.descending {
+		q.Descending()
+	}
+
+	if page.cursor != "" {
+		q.After(page.cursor)
+	}
+
+	messages, next, err := q.Page(r.Context())
+	writeResults(w, messages, next, err)
+}
+
+func (h *handler) events(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	eventType := r.URL.Query().Get("type")
+	if eventType == "" {
+		writeError(w, http.StatusBadRequest, "type is required")
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := h.client.EventsByType(eventType).Chain(page.chainID).Heights(page.startHeight, page.endHeight).Limit(page.limit).Offset(page.offset)
+	switch r.URL.Query().Get("source") {
+	case "":
+	case "block":
+		q.BlockEventsOnly()
+	case "message":
+		q.MessageEventsOnly()
+	default:
+		writeError(w, http.StatusBadRequest, "source must be block or message")
+		return
+	}
+	if page.descending {
+		q.Descending()
+	}
+
+	if page.cursor != "" {
+		q.After(page.cursor)
+	}
+
+	events, next, err := q.Page(r.Context())
+	writeResults(w, events, next, err)
+}
+
+func (h *handler) timeBuckets(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+	values := r.URL.Query()
+	chainID := values.Get("chain_id")
+	if chainID == "" {
+		writeError(w, http.StatusBadRequest, "chain_id is required")
+		return
+	}
+
+	interval := query.BucketDay
+	if name := values.Get("interval"); name != "" {
+		var err error
+		if interval, err = query.ParseBucketInterval(name); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	location, err := time.LoadLocation(values.Get("timezone"))
+	if err != nil || location == time.Local {
+		writeError(w, http.StatusBadRequest, "timezone must be an IANA time zone name, e.g. America/New_York")
+		return
+	}
+
+	start, err := timeParameter(values.Get("start_time"), "start_time", location)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	end, err := timeParameter(values.Get("end_time"), "end_time", location)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if !end.After(start) {
+		writeError(w, http.StatusBadRequest, "end_time must be after start_time")
+		return
+	}
+
+	buckets, err := h.client.TimeBuckets(r.Context(), chainID, interval, location, start, end)
+	if errors.Is(err, query.ErrTooManyBuckets) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeResults(w, buckets, "", err)
+}
+
+// timeParameter parses a required RFC 3339 time, or a date that starts at midnight in the location
+func timeParameter(value string, name string, location *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("%s is required", name)
+	}
+	parsed, err := query.ParseTime(value, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s %w", name, err)
+	}
+	return parsed, nil
+}
+
+func writeResults[T any](w http.ResponseWriter, results []T, next string, err error) {
+	if errors.Is(err, query.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	if results == nil {
+		results = []T{}
+	}
+	writeJSON(w, http.StatusOK, Page[T]{Data: results, NextCursor: next})
+}
+
+// writeQueryError logs database errors and hides their details from clients
+func writeQueryError(w http.ResponseWriter, err error) {
+	config.Log.Error("Error running REST API query", err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		config.Log.Error("Error writing REST API response", err)
+	}
+}
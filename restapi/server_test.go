@@ -0,0 +1,145 @@
+package restapi
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/auth"
+	"github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/eventbus"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/suite"
+)
+
+type RESTAPITestSuite struct {
+	suite.Suite
+}
+
+func (suite *RESTAPITestSuite) TestSpecUpToDate() {
+	spec, err := Spec()
+	suite.Require().NoError(err)
+	suite.Require().Equal(string(spec), string(openAPISpec), "openapi.json is stale, run go generate ./restapi")
+
+	var parsed struct {
+		Paths map[string]any `json:"paths"`
+	}
+	suite.Require().NoError(json.Unmarshal(spec, &parsed))
+	suite.Require().Len(parsed.Paths, len(routes))
+}
+
+func (suite *RESTAPITestSuite) TestMatchPath() {
+	params, ok := matchPath("/addresses/{address}/txs", "/addresses/cosmos1abc/txs")
+	suite.Require().True(ok)
+	suite.Require().Equal("cosmos1abc", params["address"])
+
+	_, ok = matchPath("/addresses/{address}/txs", "/addresses//txs")
+	suite.Require().False(ok)
+
+	_, ok = matchPath("/txs/{hash}", "/txs/ABC/extra")
+	suite.Require().False(ok)
+}
+
+func (suite *RESTAPITestSuite) TestHandler() {
+	handler := NewHandler(nil, nil, nil, nil)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	suite.Require().Equal(http.StatusOK, recorder.Code)
+	suite.Require().Equal(string(openAPISpec), recorder.Body.String())
+
+	badRequests := []string{
+		"/blocks?limit=0",
+		"/blocks?limit=1001",
+		"/blocks?start_height=10&end_height=5",
+		"/blocks?order=sideways",
+		"/messages",
+		"/events?type=transfer&source=everything",
+		"/blocks?cursor=garbage",
+		"/addresses/cosmos1abc/balances",
+		"/addresses/cosmos1abc/balances?chain_id=cosmoshub-4&height=-1",
+		"/time-buckets?start_time=2024-01-01&end_time=2024-02-01",
+		"/time-buckets?chain_id=cosmoshub-4&start_time=2024-01-01&end_time=2024-02-01&interval=minute",
+		"/time-buckets?chain_id=cosmoshub-4&start_time=2024-01-01&end_time=2024-02-01&timezone=Mars/Olympus_Mons",
+		"/time-buckets?chain_id=cosmoshub-4&start_time=yesterday&end_time=2024-02-01",
+		"/time-buckets?chain_id=cosmoshub-4&start_time=2024-02-01&end_time=2024-01-01",
+		"/time-buckets?chain_id=cosmoshub-4&start_time=2000-01-01&end_time=2024-01-01&interval=hour",
+	}
+	for _, target := range badRequests {
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		suite.Require().Equal(http.StatusBadRequest, recorder.Code, target)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	suite.Require().Equal(http.StatusNotFound, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/blocks", nil))
+	suite.Require().Equal(http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func (suite *RESTAPITestSuite) TestStream() {
+	recorder := httptest.NewRecorder()
+	NewHandler(nil, nil, nil, nil).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/stream?blocks=true", nil))
+	suite.Require().Equal(http.StatusNotFound, recorder.Code)
+
+	bus := eventbus.New[db.CDCEvent]()
+	handler := NewHandler(nil, bus, nil, nil)
+
+	for _, target := range []string{"/stream", "/stream?blocks=yes", "/stream?chain_id=cosmoshub-4"} {
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		suite.Require().Equal(http.StatusBadRequest, recorder.Code, target)
+	}
+
+	filter, err := parseStreamFilter(httptest.NewRequest(http.MethodGet, "/stream?address=cosmos1abc&event_type=transfer", nil))
+	suite.Require().NoError(err)
+	suite.Require().Equal(streamFilter{txs: true, address: "cosmos1abc", eventType: "transfer"}, filter)
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/stream?chain_id=cosmoshub-4&blocks=true", nil)
+	suite.Require().NoError(err)
+	defer conn.Close()
+
+	// Events of other chains are skipped without querying, closing the bus disconnects the client
+	bus.Publish(db.CDCEvent{ChainID: "osmosis-1", Height: 1, Kind: db.CDCKindTxs})
+	bus.Close()
+
+	_, _, err = conn.ReadMessage()
+	suite.Require().True(websocket.IsCloseError(err, websocket.CloseTryAgainLater), err)
+}
+
+func (suite *RESTAPITestSuite) TestAuth() {
+	authenticator := auth.New(auth.Options{Keys: []auth.Key{{Name: "streamer", Hash: sha256.Sum256([]byte("stream-key")), Scopes: []auth.Scope{auth.ScopeStream}}}})
+	handler := NewHandler(nil, eventbus.New[db.CDCEvent](), authenticator, nil)
+
+	requests := map[string]int{
+		"/openapi.json":                 http.StatusOK,
+		"/blocks":                       http.StatusUnauthorized,
+		"/blocks?limit=0|wrong-key":     http.StatusUnauthorized,
+		"/blocks?limit=0|stream-key":    http.StatusForbidden,
+		"/stream?blocks=yes|stream-key": http.StatusBadRequest,
+	}
+	for target, status := range requests {
+		path, key, _ := strings.Cut(target, "|")
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			request.Header.Set("X-API-Key", key)
+		}
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		suite.Require().Equal(status, recorder.Code, target)
+	}
+}
+
+func TestRESTAPITestSuite(t *testing.T) {
+	suite.Run(t, new(RESTAPITestSuite))
+}
@@ -0,0 +1,218 @@
+package restapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/query"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// Number of committed block datasets buffered per client before it is dropped for falling behind
+	streamBuffer       = 256
+	streamWriteTimeout = 10 * time.Second
+	streamPingInterval = 30 * time.Second
+)
+
+// Kinds of the messages pushed to stream clients
+const (
+	streamBlock = "block"
+	streamTx    = "tx"
+	streamEvent = "event"
+)
+
+// streamMessage is a single pushed result, data is a Block, Tx or Event
+type streamMessage struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+// streamFilter is what a client subscribes to, taken from the query parameters of the upgrade request
+type streamFilter struct {
+	chainID   string
+	blocks    bool
+	txs       bool
+	address   string
+	eventType string
+}
+
+var upgrader = websocket.Upgrader{
+	// The API is read-only and unauthenticated, so browser clients of any origin may subscribe just like they may
+	// fetch the REST routes
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+func parseStreamFilter(r *http.Request) (streamFilter, error) {
+	values := r.URL.Query()
+	filter := streamFilter{
+		chainID:   values.Get("chain_id"),
+		address:   values.Get("address"),
+		eventType: values.Get("event_type"),
+	}
+
+	for name, target := range map[string]*bool{"blocks": &filter.blocks, "txs": &filter.txs} {
+		switch values.Get(name) {
+		case "", "false":
+		case "true":
+			*target = true
+		default:
+			return filter, errors.New(name + " must be true or false")
+		}
+	}
+
+	// An address only makes sense for transactions
+	if filter.address != "" {
+		filter.txs = true
+	}
+
+	if !filter.blocks && !filter.txs && filter.eventType == "" {
+		return filter, errors.New("at least one of blocks, txs, address or event_type must be set")
+	}
+
+	return filter, nil
+}
+
+func (h *handler) stream(w http.ResponseWriter, r *http.Request) {
+	if h.bus == nil {
+		writeError(w, http.StatusNotFound, "streaming is not enabled")
+		return
+	}
+
+	filter, err := parseStreamFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Subscribe before the upgrade so nothing committed after the handshake is missed
+	subscription := h.bus.Subscribe(streamBuffer)
+	defer subscription.Cancel()
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already responded with the error
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Clients only send control frames, reading is needed to process them and to notice a closed connection
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	streamer := &streamer{client: h.client, conn: conn, filter: filter, sentBlocks: map[string]int64{}}
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		case event, ok := <-subscription.Events():
+			if !ok {
+				streamer.close(websocket.CloseTryAgainLater, "client fell behind")
+				return
+			}
+			if err := streamer.push(ctx, event); err != nil {
+				if ctx.Err() == nil {
+					config.Log.Error("Error streaming to a REST API client", err)
+					streamer.close(websocket.CloseInternalServerErr, "internal error")
+				}
+				return
+			}
+		}
+	}
+}
+
+// streamer pushes the results matching the filter of a client for every committed block dataset
+type streamer struct {
+	client *query.Client
+	conn   *websocket.Conn
+	filter streamFilter
+	// sentBlocks is the last block pushed per chain, a block is announced by its transactions and its block events
+	sentBlocks map[string]int64
+}
+
+func (s *streamer) push(ctx context.Context, event db.CDCEvent) error {
+	if s.filter.chainID != "" && event.ChainID != s.filter.chainID {
+		return nil
+	}
+
+	if s.filter.blocks && s.sentBlocks[event.ChainID] < event.Height {
+		blocks, err := s.client.BlocksInRange(event.Height, event.Height).Chain(event.ChainID).All(ctx)
+		if err != nil {
+			return err
+		}
+		if err := writeStream(s, streamBlock, blocks); err != nil {
+			return err
+		}
+		s.sentBlocks[event.ChainID] = event.Height
+	}
+
+	if s.filter.txs && event.Kind == db.CDCKindTxs && event.Txs > 0 {
+		q := s.client.TxsInRange(event.Height, event.Height).Chain(event.ChainID)
+		if s.filter.address != "" {
+			q = s.client.TxsByAddress(s.filter.address).Chain(event.ChainID).Heights(event.Height, event.Height)
+		}
+		txs, err := q.All(ctx)
+		if err != nil {
+			return err
+		}
+		if err := writeStream(s, streamTx, txs); err != nil {
+			return err
+		}
+	}
+
+	if s.filter.eventType != "" {
+		q := s.client.EventsByType(s.filter.eventType).Chain(event.ChainID).Heights(event.Height, event.Height)
+		// Each notification covers either the message or the block events of the height, so none is pushed twice
+		if event.Kind == db.CDCKindTxs {
+			q.MessageEventsOnly()
+		} else {
+			q.BlockEventsOnly()
+		}
+		events, err := q.All(ctx)
+		if err != nil {
+			return err
+		}
+		if err := writeStream(s, streamEvent, events); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeStream[T any](s *streamer, kind string, results []T) error {
+	for _, result := range results {
+		if err := s.conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout)); err != nil {
+			return err
+		}
+		if err := s.conn.WriteJSON(streamMessage{Type: kind, Data: result}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *streamer) close(code int, reason string) {
+	_ = s.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(streamWriteTimeout))
+}
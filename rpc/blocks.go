@@ -57,6 +57,16 @@ func argsToJSON(args map[string]interface{}) error {
 }
 
 func (c *URIClient) DoHTTPGet(ctx context.Context, method string, params map[string]interface{}, result interface{}) (interface{}, error) {
+	responseBytes, err := c.get(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return unmarshalResponseBytes(responseBytes, jsonrpc.URIClientRequestID, result)
+}
+
+// get requests method with the params encoded in the URL and returns the undecoded response
+func (c *URIClient) get(ctx context.Context, method string, params map[string]interface{}) ([]byte, error) {
 	values, err := argsToURLValues(params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode params: %w", err)
@@ -86,7 +96,7 @@ func (c *URIClient) DoHTTPGet(ctx context.Context, method string, params map[str
 		return nil, fmt.Errorf("read response body: %w", err)
 	}
 
-	return unmarshalResponseBytes(responseBytes, jsonrpc.URIClientRequestID, result)
+	return responseBytes, nil
 }
 
 type URIClient struct {
@@ -96,6 +106,21 @@ type URIClient struct {
 }
 
 func unmarshalResponseBytes(responseBytes []byte, expectedID types.JSONRPCIntID, result interface{}) (interface{}, error) {
+	raw, err := responseResult(responseBytes, expectedID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal the RawMessage into the result.
+	if err := tmjson.Unmarshal(raw, result); err != nil {
+		return nil, fmt.Errorf("error unmarshalling result: %w", err)
+	}
+
+	return result, nil
+}
+
+// responseResult returns the undecoded result of a JSON-RPC response, or the error the response holds
+func responseResult(responseBytes []byte, expectedID types.JSONRPCIntID) (json.RawMessage, error) {
 	// Read response.  If rpc/core/types is imported, the result will unmarshal
 	// into the correct type.
 	response := &types.RPCResponse{}
@@ -111,12 +136,7 @@ func unmarshalResponseBytes(responseBytes []byte, expectedID types.JSONRPCIntID,
 		return nil, fmt.Errorf("wrong ID: %w", err)
 	}
 
-	// Unmarshal the RawMessage into the result.
-	if err := tmjson.Unmarshal(response.Result, result); err != nil {
-		return nil, fmt.Errorf("error unmarshalling result: %w", err)
-	}
-
-	return result, nil
+	return response.Result, nil
 }
 
 func validateAndVerifyID(res *types.RPCResponse, expectedID types.JSONRPCIntID) error {
@@ -140,36 +160,27 @@ func validateResponseID(id interface{}) error {
 	return nil
 }
 
+// DoBlockResults requests the block results at height. The response is decoded while it is read instead of being
+// buffered first, but the whole result is materialized for the callers of the Client interface. Use
+// StreamBlockResults to process the parts of a response without materializing it.
 func (c *URIClient) DoBlockResults(ctx context.Context, height *int64) (*ctypes.ResultBlockResults, error) {
 	result := new(ctypes.ResultBlockResults)
-	params := make(map[string]interface{})
-	if height != nil {
-		params["height"] = height
-	}
-
-	_, err := c.DoHTTPGet(ctx, "block_results", params, result)
-	if err != nil {
+	if err := c.StreamBlockResults(ctx, height, collectBlockResults(result)); err != nil {
 		return nil, err
 	}
 
 	return result, nil
 }
 
-func GetBlockResult(client URIClient, height int64) (*ctypes.ResultBlockResults, error) {
-	brctx, cancel := context.WithTimeout(context.Background(), 100*time.Second)
-	defer cancel()
-
-	bresults, err := client.DoBlockResults(brctx, &height)
-	if err != nil {
-		return nil, err
-	}
-
-	return bresults, nil
+func GetBlockResult(ctx context.Context, client Client, height int64) (*ctypes.ResultBlockResults, error) {
+	return client.BlockResults(ctx, height)
 }
 
-func GetBlockResultWithRetry(client URIClient, height int64, retryMaxAttempts int64, retryMaxWaitSeconds uint64) (*ctypes.ResultBlockResults, error) {
+// GetBlockResultWithRetry retries failed block results requests with a backoff until the attempts are used up or ctx is
+// done
+func GetBlockResultWithRetry(ctx context.Context, client Client, height int64, retryMaxAttempts int64, retryMaxWaitSeconds uint64) (*ctypes.ResultBlockResults, error) {
 	if retryMaxAttempts == 0 {
-		return GetBlockResult(client, height)
+		return GetBlockResult(ctx, client, height)
 	}
 
 	if retryMaxWaitSeconds < 2 {
@@ -186,12 +197,18 @@ func GetBlockResultWithRetry(client URIClient, height int64, retryMaxAttempts in
 	currentBackoffDuration, maxReached := GetBackoffDurationForAttempts(attempts, maxRetryTime)
 
 	for {
-		resp, err := GetBlockResult(client, height)
+		resp, err := GetBlockResult(ctx, client, height)
 		attempts++
-		if err != nil && (retryMaxAttempts < 0 || (attempts <= retryMaxAttempts)) {
+		if err != nil && ctx.Err() == nil && (retryMaxAttempts < 0 || (attempts <= retryMaxAttempts)) {
 			config.Log.Error("Error getting RPC response, backing off and trying again", err)
 			config.Log.Debugf("Attempt %d with wait time %+v", attempts, currentBackoffDuration)
-			time.Sleep(currentBackoffDuration)
+			timer := time.NewTimer(currentBackoffDuration)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, ctx.Err()
+			case <-timer.C:
+			}
 
 			// guard against overflow
 			if !maxReached {
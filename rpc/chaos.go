@@ -0,0 +1,122 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// ChaosOptions are the rates, between 0 and 1, at which a ChaosClient injects faults
+type ChaosOptions struct {
+	TimeoutRate   float64
+	MalformedRate float64
+	// Seed makes the faults repeatable, 0 seeds them randomly
+	Seed int64
+}
+
+// ChaosClient injects faults into the block, block results and transaction requests of a Client to test the
+// resilience of the indexer. A request times out at the timeout rate and is answered with a malformed response at the
+// malformed rate. The status is never faulted, the indexer can't start without it.
+type ChaosClient struct {
+	Client
+	opts ChaosOptions
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func NewChaosClient(client Client, opts ChaosOptions) *ChaosClient {
+	seed := opts.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &ChaosClient{Client: client, opts: opts, rng: rand.New(rand.NewSource(seed))} //nolint:gosec
+}
+
+type chaosFault int
+
+const (
+	noFault chaosFault = iota
+	timeoutFault
+	malformedFault
+)
+
+func (c *ChaosClient) fault() chaosFault {
+	c.mu.Lock()
+	roll := c.rng.Float64()
+	c.mu.Unlock()
+
+	switch {
+	case roll < c.opts.TimeoutRate:
+		return timeoutFault
+	case roll < c.opts.TimeoutRate+c.opts.MalformedRate:
+		return malformedFault
+	}
+	return noFault
+}
+
+// errChaosTimeout wraps context.DeadlineExceeded, so it is classified like a request running out of time
+func errChaosTimeout(request string, height int64) error {
+	return fmt.Errorf("chaos: %s request for height %d timed out: %w", request, height, context.DeadlineExceeded)
+}
+
+// Block answers malformed requests with a response without a block, which the indexer can't process at all
+func (c *ChaosClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	switch c.fault() {
+	case timeoutFault:
+		return nil, errChaosTimeout("block", height)
+	case malformedFault:
+		return &coretypes.ResultBlock{}, nil
+	}
+	return c.Client.Block(ctx, height)
+}
+
+// BlockResults answers malformed requests with a begin block event whose attribute is not base64 encoded
+func (c *ChaosClient) BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error) {
+	fault := c.fault()
+	if fault == timeoutFault {
+		return nil, errChaosTimeout("block results", height)
+	}
+
+	resp, err := c.Client.BlockResults(ctx, height)
+	if err != nil || fault != malformedFault {
+		return resp, err
+	}
+
+	malformed := *resp
+	malformed.BeginBlockEvents = append([]abci.Event{{
+		Type:       "chaos",
+		Attributes: []abci.EventAttribute{{Key: "not base64!", Value: "not base64!"}},
+	}}, resp.BeginBlockEvents...)
+	return &malformed, nil
+}
+
+// TxsEvent answers malformed requests with a first transaction whose first message can't be decoded
+func (c *ChaosClient) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	fault := c.fault()
+	if fault == timeoutFault {
+		return nil, errChaosTimeout("transactions", height)
+	}
+
+	resp, err := c.Client.TxsEvent(ctx, height, req)
+	if err != nil || fault != malformedFault || len(resp.Txs) == 0 || resp.Txs[0].Body == nil || len(resp.Txs[0].Body.Messages) == 0 {
+		return resp, err
+	}
+
+	// The response is copied down to the replaced message, clients may hand out the same response again
+	tx := *resp.Txs[0]
+	body := *tx.Body
+	body.Messages = append([]*codectypes.Any{{TypeUrl: "/chaos.MalformedMsg", Value: []byte("not protobuf")}}, body.Messages[1:]...)
+	tx.Body = &body
+
+	malformed := *resp
+	malformed.Txs = append([]*txTypes.Tx{&tx}, resp.Txs[1:]...)
+	return &malformed, nil
+}
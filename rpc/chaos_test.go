@@ -0,0 +1,49 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+func (suite *RequestsTestSuite) TestChaosClient() {
+	ctx := context.Background()
+	live := &fakeClient{txs: []string{"A", "B"}, pageSize: 100}
+	live.block = &coretypes.ResultBlock{Block: &cmttypes.Block{Header: cmttypes.Header{Height: 10}}}
+	live.blockResults = &coretypes.ResultBlockResults{Height: 10}
+
+	timeouts := NewChaosClient(live, ChaosOptions{TimeoutRate: 1, Seed: 1})
+	_, err := GetBlock(ctx, timeouts, 10)
+	suite.Require().True(errors.Is(err, context.DeadlineExceeded))
+	_, err = GetTxsByBlockHeight(ctx, timeouts, 10)
+	suite.Require().True(errors.Is(err, context.DeadlineExceeded))
+
+	// The status is never faulted
+	_, err = GetLatestBlockHeight(timeouts)
+	suite.Require().NoError(err)
+
+	malformed := NewChaosClient(live, ChaosOptions{MalformedRate: 1, Seed: 1})
+	block, err := GetBlock(ctx, malformed, 10)
+	suite.Require().NoError(err)
+	suite.Require().Nil(block.Block)
+
+	blockResults, err := GetBlockResult(ctx, malformed, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(blockResults.BeginBlockEvents, 1)
+	suite.Require().Empty(live.blockResults.BeginBlockEvents)
+
+	live.tx = &txTypes.Tx{Body: &txTypes.TxBody{Messages: []*codectypes.Any{{TypeUrl: "/cosmos.bank.v1beta1.MsgSend"}}}}
+	txs, err := GetTxsByBlockHeight(ctx, malformed, 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal("/chaos.MalformedMsg", txs.Txs[0].Body.Messages[0].TypeUrl)
+	suite.Require().Equal("/cosmos.bank.v1beta1.MsgSend", live.tx.Body.Messages[0].TypeUrl)
+
+	none := NewChaosClient(live, ChaosOptions{Seed: 1})
+	block, err = GetBlock(ctx, none, 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal(live.block, block)
+}
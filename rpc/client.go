@@ -0,0 +1,84 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	probeClient "github.com/DefiantLabs/probe/client"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	grpctypes "github.com/cosmos/cosmos-sdk/types/grpc"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"google.golang.org/grpc/metadata"
+)
+
+// Client is the node API the indexer fetches chain data through. ChainClient implements it with the probe client, tests
+// and embedders implement it to serve blocks from fakes or other sources.
+type Client interface {
+	Status(ctx context.Context) (*coretypes.ResultStatus, error)
+	Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error)
+	BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error)
+	// TxsEvent returns a page of the transactions of the block at height matching the request, with their messages
+	// unpacked where the codec knows them
+	TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error)
+}
+
+// ChainClient queries the node of a probe client. Block results are fetched from the RPC server directly, the probe
+// client decodes them with types that do not match every chain.
+type ChainClient struct {
+	cl           *probeClient.ChainClient
+	blockResults URIClient
+}
+
+func NewChainClient(cl *probeClient.ChainClient) *ChainClient {
+	return &ChainClient{
+		cl:           cl,
+		blockResults: URIClient{Address: cl.Config.RPCAddr, Client: &http.Client{}},
+	}
+}
+
+// queryContext bounds a query at height by the client timeout, like the probe queries, and by ctx
+func (c *ChainClient) queryContext(ctx context.Context, height int64) (context.Context, context.CancelFunc) {
+	timeout, _ := time.ParseDuration(c.cl.Config.Timeout) // Timeout is validated in the probe config
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return metadata.AppendToOutgoingContext(ctx, grpctypes.GRPCBlockHeightHeader, strconv.FormatInt(height, 10)), cancel
+}
+
+func (c *ChainClient) Status(ctx context.Context) (*coretypes.ResultStatus, error) {
+	ctx, cancel := c.queryContext(ctx, 0)
+	defer cancel()
+
+	return c.cl.RPCClient.Status(ctx)
+}
+
+func (c *ChainClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	ctx, cancel := c.queryContext(ctx, height)
+	defer cancel()
+
+	return c.cl.RPCClient.Block(ctx, &height)
+}
+
+func (c *ChainClient) BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error) {
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Second)
+	defer cancel()
+
+	return c.blockResults.DoBlockResults(ctx, &height)
+}
+
+func (c *ChainClient) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	ctx, cancel := c.queryContext(ctx, height)
+	defer cancel()
+
+	resp, err := txTypes.NewServiceClient(c.cl).GetTxsEvent(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tx := range resp.GetTxs() {
+		// Like the probe query, messages that fail to unpack are left packed instead of failing the block
+		tx.UnpackInterfaces(c.cl.Codec.InterfaceRegistry) //nolint:errcheck
+	}
+
+	return resp, nil
+}
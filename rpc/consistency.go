@@ -0,0 +1,199 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
+	abci "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// ErrDivergence is wrapped by the errors of requests two endpoints answered differently
+var ErrDivergence = errors.New("the endpoints returned divergent responses")
+
+// ConsistencyOptions set up a ConsistencyClient. Name identifies the second endpoint in logs and metrics. Warn only
+// logs and counts divergences, the response of the wrapped client is used then.
+type ConsistencyOptions struct {
+	Name string
+	Warn bool
+}
+
+// ConsistencyClient sends the block, block results and transaction requests of a Client to a second, independent
+// endpoint too and compares the responses, so a malicious or corrupted node can't feed the index unnoticed. Blocks are
+// compared by their hash, block results by the hash of their transaction results and the digest of their events and
+// validator updates, and transaction searches by the hashes of the transactions found. The status is not compared,
+// the endpoints may be at different heights.
+type ConsistencyClient struct {
+	Client
+	second Client
+	opts   ConsistencyOptions
+}
+
+func NewConsistencyClient(cl Client, second Client, opts ConsistencyOptions) *ConsistencyClient {
+	return &ConsistencyClient{Client: cl, second: second, opts: opts}
+}
+
+type response[T any] struct {
+	resp T
+	err  error
+}
+
+// both sends the request to both endpoints concurrently. A failure of the second endpoint fails the request, the
+// response can't be checked without it, unless divergences only warn.
+func both[T any](c *ConsistencyClient, requestName string, height int64, request func(Client) (T, error)) (T, T, bool, error) {
+	secondCh := make(chan response[T], 1)
+	go func() {
+		resp, err := request(c.second)
+		secondCh <- response[T]{resp, err}
+	}()
+
+	resp, err := request(c.Client)
+	second := <-secondCh
+	if err != nil {
+		return resp, second.resp, false, err
+	}
+	if second.err != nil {
+		if c.opts.Warn {
+			config.Log.Warnf("Failed to check the %s of height %d with %s: %v", requestName, height, c.opts.Name, second.err)
+			return resp, second.resp, false, nil
+		}
+		return resp, second.resp, false, fmt.Errorf("failed to check the %s of height %d with %s: %w", requestName, height, c.opts.Name, second.err)
+	}
+	return resp, second.resp, true, nil
+}
+
+// diverged flags a divergence and returns the error failing the request, nil when divergences only warn
+func (c *ConsistencyClient) diverged(requestName string, height int64, detail string) error {
+	metrics.ObserveDivergence(c.opts.Name, requestName)
+	err := fmt.Errorf("%w: the %s of height %d from %s %s", ErrDivergence, requestName, height, c.opts.Name, detail)
+	if c.opts.Warn {
+		config.Log.Warn(err.Error())
+		return nil
+	}
+	return err
+}
+
+func (c *ConsistencyClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	block, second, ok, err := both(c, "block", height, func(cl Client) (*coretypes.ResultBlock, error) {
+		return cl.Block(ctx, height)
+	})
+	if err != nil || !ok {
+		return block, err
+	}
+
+	hash, secondHash := resultBlockHash(block), resultBlockHash(second)
+	if !bytes.Equal(hash, secondHash) {
+		if err := c.diverged("block", height, fmt.Sprintf("hashes to %X instead of %X", secondHash, hash)); err != nil {
+			return nil, err
+		}
+	}
+	return block, nil
+}
+
+func (c *ConsistencyClient) BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error) {
+	results, second, ok, err := both(c, "block results", height, func(cl Client) (*coretypes.ResultBlockResults, error) {
+		return cl.BlockResults(ctx, height)
+	})
+	if err != nil || !ok {
+		return results, err
+	}
+
+	digest, err := blockResultsDigest(results)
+	if err != nil {
+		return nil, err
+	}
+	secondDigest, err := blockResultsDigest(second)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(digest, secondDigest) {
+		if err := c.diverged("block results", height, fmt.Sprintf("digest to %X instead of %X", secondDigest, digest)); err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+func (c *ConsistencyClient) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	resp, second, ok, err := both(c, "transactions", height, func(cl Client) (*txTypes.GetTxsEventResponse, error) {
+		return cl.TxsEvent(ctx, height, req)
+	})
+	if err != nil || !ok {
+		return resp, err
+	}
+
+	if detail := txsDivergence(txHashes(resp), txHashes(second)); detail != "" {
+		if err := c.diverged("transactions", height, detail); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+func resultBlockHash(block *coretypes.ResultBlock) []byte {
+	if block == nil || block.Block == nil {
+		return nil
+	}
+	return block.Block.Hash()
+}
+
+// blockResultsDigest hashes the deterministic fields of the transaction results, like the last results hash of the
+// next header, and the events and validator updates of the block
+func blockResultsDigest(results *coretypes.ResultBlockResults) ([]byte, error) {
+	if results == nil {
+		return nil, nil
+	}
+
+	digest := sha256.New()
+	digest.Write(cmttypes.NewResults(results.TxsResults).Hash())
+	for _, events := range [][]abci.Event{results.BeginBlockEvents, results.EndBlockEvents} {
+		for i := range events {
+			bz, err := events[i].Marshal()
+			if err != nil {
+				return nil, err
+			}
+			digest.Write(bz)
+		}
+		// Separates the begin block from the end block events
+		digest.Write([]byte{0})
+	}
+	for i := range results.ValidatorUpdates {
+		bz, err := results.ValidatorUpdates[i].Marshal()
+		if err != nil {
+			return nil, err
+		}
+		digest.Write(bz)
+	}
+	return digest.Sum(nil), nil
+}
+
+// txsDivergence describes how the transactions found by the second endpoint differ, empty if they don't
+func txsDivergence(hashes []string, secondHashes []string) string {
+	if len(hashes) != len(secondHashes) {
+		return fmt.Sprintf("has %d transactions instead of %d", len(secondHashes), len(hashes))
+	}
+	for i := range hashes {
+		if hashes[i] != secondHashes[i] {
+			return fmt.Sprintf("has transaction %s instead of %s", secondHashes[i], hashes[i])
+		}
+	}
+	return ""
+}
+
+func txHashes(resp *txTypes.GetTxsEventResponse) []string {
+	if resp == nil {
+		return nil
+	}
+	hashes := make([]string, 0, len(resp.TxResponses))
+	for _, txResp := range resp.TxResponses {
+		hashes = append(hashes, txResp.TxHash)
+	}
+	return hashes
+}
@@ -0,0 +1,55 @@
+package rpc
+
+import (
+	"context"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+)
+
+func (suite *RequestsTestSuite) TestConsistencyClient() {
+	ctx := context.Background()
+	block := func(chainID string) *coretypes.ResultBlock {
+		return &coretypes.ResultBlock{Block: &cmttypes.Block{Header: cmttypes.Header{ChainID: chainID, Height: 10}}}
+	}
+	results := func(gasUsed int64) *coretypes.ResultBlockResults {
+		return &coretypes.ResultBlockResults{
+			Height:           10,
+			TxsResults:       []*abci.ResponseDeliverTx{{GasUsed: gasUsed, Log: "logs are not compared"}},
+			BeginBlockEvents: []abci.Event{{Type: "mint"}},
+		}
+	}
+
+	node := &fakeClient{block: block("cosmoshub-4"), blockResults: results(100), txs: []string{"AB01"}, pageSize: 100}
+	second := &fakeClient{block: block("cosmoshub-4"), blockResults: results(100), txs: []string{"AB01"}, pageSize: 100}
+	second.blockResults.TxsResults[0].Log = "another log"
+	client := NewConsistencyClient(node, second, ConsistencyOptions{Name: "second"})
+
+	_, err := client.Block(ctx, 10)
+	suite.Require().NoError(err)
+	_, err = client.BlockResults(ctx, 10)
+	suite.Require().NoError(err)
+	_, err = client.TxsEvent(ctx, 10, txsRequest(10, 100))
+	suite.Require().NoError(err)
+
+	second.block, second.blockResults, second.txs = block("forged-1"), results(200), []string{"CD01"}
+	_, err = client.Block(ctx, 10)
+	suite.Require().ErrorIs(err, ErrDivergence)
+	_, err = client.BlockResults(ctx, 10)
+	suite.Require().ErrorIs(err, ErrDivergence)
+	_, err = client.TxsEvent(ctx, 10, txsRequest(10, 100))
+	suite.Require().ErrorIs(err, ErrDivergence)
+
+	// The events are compared too
+	second.blockResults = results(100)
+	second.blockResults.BeginBlockEvents = nil
+	second.blockResults.EndBlockEvents = []abci.Event{{Type: "mint"}}
+	_, err = client.BlockResults(ctx, 10)
+	suite.Require().ErrorIs(err, ErrDivergence)
+
+	// The response can't be checked when the second node fails
+	second.block = nil
+	_, err = client.Block(ctx, 10)
+	suite.Require().ErrorContains(err, "block not found")
+}
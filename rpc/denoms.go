@@ -0,0 +1,86 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	probeClient "github.com/DefiantLabs/probe/client"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	bankTypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+	transferTypes "github.com/cosmos/ibc-go/v7/modules/apps/transfer/types"
+)
+
+// BankDenomDisplays returns the display units of the denom metadata registered in the bank module of the chain.
+// Metadata whose display denom is not one of its units is skipped.
+func BankDenomDisplays(ctx context.Context, cl *probeClient.ChainClient) ([]config.DenomDisplay, error) {
+	timeout, _ := time.ParseDuration(cl.Config.Timeout) // Timeout is validated in the probe config
+	client := bankTypes.NewQueryClient(cl)
+
+	var displays []config.DenomDisplay
+	var nextKey []byte
+	for {
+		queryCtx, cancel := context.WithTimeout(ctx, timeout)
+		resp, err := client.DenomsMetadata(queryCtx, &bankTypes.QueryDenomsMetadataRequest{Pagination: &query.PageRequest{Key: nextKey, Limit: 100}})
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, metadata := range resp.Metadatas {
+			if display, ok := denomDisplay(metadata); ok {
+				displays = append(displays, display)
+			}
+		}
+
+		if resp.Pagination == nil || len(resp.Pagination.NextKey) == 0 {
+			return displays, nil
+		}
+		nextKey = resp.Pagination.NextKey
+	}
+}
+
+func denomDisplay(metadata bankTypes.Metadata) (config.DenomDisplay, bool) {
+	for _, unit := range metadata.DenomUnits {
+		if unit == nil {
+			continue
+		}
+
+		names := append([]string{unit.Denom}, unit.Aliases...)
+		for _, name := range names {
+			if name == metadata.Display {
+				return config.DenomDisplay{Base: metadata.Base, Display: metadata.Display, Exponent: unit.Exponent}, true
+			}
+		}
+	}
+	return config.DenomDisplay{}, false
+}
+
+// IBCDenomTraces returns the origins of the IBC voucher denoms registered in the transfer module of the chain
+func IBCDenomTraces(ctx context.Context, cl *probeClient.ChainClient) ([]config.DenomTrace, error) {
+	timeout, _ := time.ParseDuration(cl.Config.Timeout) // Timeout is validated in the probe config
+	client := transferTypes.NewQueryClient(cl)
+
+	var traces []config.DenomTrace
+	var nextKey []byte
+	for {
+		queryCtx, cancel := context.WithTimeout(ctx, timeout)
+		resp, err := client.DenomTraces(queryCtx, &transferTypes.QueryDenomTracesRequest{Pagination: &query.PageRequest{Key: nextKey, Limit: 100}})
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, trace := range resp.DenomTraces {
+			if trace.IsNativeDenom() {
+				continue
+			}
+			traces = append(traces, config.DenomTrace{Denom: trace.IBCDenom(), Path: trace.Path, BaseDenom: trace.BaseDenom})
+		}
+
+		if resp.Pagination == nil || len(resp.Pagination.NextKey) == 0 {
+			return traces, nil
+		}
+		nextKey = resp.Pagination.NextKey
+	}
+}
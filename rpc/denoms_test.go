@@ -0,0 +1,26 @@
+package rpc
+
+import (
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	bankTypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+func (suite *RequestsTestSuite) TestDenomDisplay() {
+	metadata := bankTypes.Metadata{
+		Base:    "uatom",
+		Display: "atom",
+		DenomUnits: []*bankTypes.DenomUnit{
+			{Denom: "uatom", Exponent: 0, Aliases: []string{"microatom"}},
+			{Denom: "matom", Exponent: 3},
+			{Denom: "ATOM", Exponent: 6, Aliases: []string{"atom"}},
+		},
+	}
+	display, ok := denomDisplay(metadata)
+	suite.Require().True(ok)
+	suite.Require().Equal(config.DenomDisplay{Base: "uatom", Display: "atom", Exponent: 6}, display)
+
+	// Without a unit of the display denom the exponent is unknown
+	metadata.Display = "kiloatom"
+	_, ok = denomDisplay(metadata)
+	suite.Require().False(ok)
+}
@@ -0,0 +1,449 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// EndpointRole is the share of the requests an endpoint of an EndpointPool serves
+type EndpointRole string
+
+const (
+	// RoleAny serves every request
+	RoleAny EndpointRole = "any"
+	// RoleArchive serves the backfill of heights behind the head window, and the head only when no other endpoint can
+	RoleArchive EndpointRole = "archive"
+	// RoleHead serves the heights within the head window, e.g. a pruned node
+	RoleHead EndpointRole = "head"
+	// RoleFallback serves requests only when no other endpoint for them is healthy
+	RoleFallback EndpointRole = "fallback"
+)
+
+// Endpoint is a node the requests of an EndpointPool are sent to, Name identifies it in logs and metrics. Endpoints of
+// the same role share the requests in proportion to their Weight, 1 if unset. An endpoint with a StartHeight or
+// EndHeight only serves the heights in that range, e.g. a provider holding part of the history; an EndHeight of 0
+// leaves the range open ended. The status is requested from endpoints with an open ended range.
+type Endpoint struct {
+	Name        string
+	Client      Client
+	Role        EndpointRole
+	Weight      int
+	StartHeight int64
+	EndHeight   int64
+}
+
+// HealthOptions score the endpoints of an EndpointPool. An endpoint is blacklisted once more than MaxErrorRate of its
+// last Window requests failed or their average latency exceeds MaxLatency, 0 disables the latency check, and right
+// away after MaxConsecutiveFailures failed requests in a row, 0 disables it. It is probed again after Blacklist. A
+// failed request is sent again to up to Failover other healthy endpoints. Heights more than HeadWindow blocks behind
+// the chain head are backfill.
+type HealthOptions struct {
+	Window                 int
+	MaxErrorRate           float64
+	MaxLatency             time.Duration
+	MaxConsecutiveFailures int
+	Blacklist              time.Duration
+	Failover               int
+	HeadWindow             int64
+}
+
+type requestResult struct {
+	failed  bool
+	latency time.Duration
+}
+
+type endpointHealth struct {
+	Endpoint
+
+	mu sync.Mutex
+	// results is a ring of the last requests, filled up to Window
+	results     []requestResult
+	next        int
+	consecutive int
+	blacklisted bool
+	probeAt     time.Time
+	// current is the running weight of the smooth weighted round robin, guarded by the lock of the pool
+	current int
+	// lowest is the lowest height the endpoint serves as learned from its pruned errors, 0 until it returned one
+	lowest atomic.Int64
+}
+
+// serves tells if the endpoint may serve the height, or the head for height 0, by its range and the pruned heights
+func (e *endpointHealth) serves(height int64) bool {
+	if height <= 0 {
+		return e.EndHeight == 0
+	}
+	return height >= e.StartHeight && (e.EndHeight == 0 || height <= e.EndHeight) && e.lowest.Load() <= height
+}
+
+// EndpointPool is a Client spreading the requests over the healthy endpoints by their role and weight, so a degraded
+// node stops receiving a share of the block requests. Requests of the head go to the head and any endpoints, backfill
+// requests to the archive and any endpoints; fallback endpoints, and archive endpoints for the head, are only used
+// when none of those is healthy. When every endpoint is blacklisted the requests go to the one probed again first
+// rather than failing outright. Blacklisted endpoints are probed in the background once Start was called.
+//
+// A request that fails is sent again to another healthy endpoint that may serve it, up to HealthOptions.Failover
+// times, so a single degraded node does not fail the requests routed to it before it is blacklisted.
+//
+// An endpoint that answers a request with a pruned height error, see IsPrunedError, teaches the pool the lowest height
+// it serves. The request is sent again to an endpoint that may serve the height, e.g. an archive endpoint, and the
+// lower heights are no longer routed to the endpoint. A pruned error does not count against the health of an endpoint.
+type EndpointPool struct {
+	endpoints []*endpointHealth
+	opts      HealthOptions
+	// head is the latest chain head reported by a status request
+	head atomic.Int64
+
+	mu sync.Mutex
+}
+
+func NewEndpointPool(endpoints []Endpoint, opts HealthOptions) *EndpointPool {
+	pool := &EndpointPool{opts: opts}
+	for _, endpoint := range endpoints {
+		if endpoint.Role == "" {
+			endpoint.Role = RoleAny
+		}
+		if endpoint.Weight <= 0 {
+			endpoint.Weight = 1
+		}
+		pool.endpoints = append(pool.endpoints, &endpointHealth{Endpoint: endpoint})
+		metrics.SetEndpointHealthy(endpoint.Name, true)
+	}
+	return pool
+}
+
+func (p *EndpointPool) Status(ctx context.Context) (*coretypes.ResultStatus, error) {
+	resp, err := poolRequest(ctx, p, 0, func(client Client) (*coretypes.ResultStatus, error) {
+		return client.Status(ctx)
+	})
+	if err == nil && resp.SyncInfo.LatestBlockHeight > p.head.Load() {
+		p.head.Store(resp.SyncInfo.LatestBlockHeight)
+	}
+	return resp, err
+}
+
+func (p *EndpointPool) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	return poolRequest(ctx, p, height, func(client Client) (*coretypes.ResultBlock, error) {
+		return client.Block(ctx, height)
+	})
+}
+
+func (p *EndpointPool) BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error) {
+	return poolRequest(ctx, p, height, func(client Client) (*coretypes.ResultBlockResults, error) {
+		return client.BlockResults(ctx, height)
+	})
+}
+
+func (p *EndpointPool) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	return poolRequest(ctx, p, height, func(client Client) (*txTypes.GetTxsEventResponse, error) {
+		return client.TxsEvent(ctx, height, req)
+	})
+}
+
+// poolRequest sends a request for the block at height, 0 for requests of the head such as the status. A request of a
+// pruned height is sent again until an endpoint serves it or no endpoint is left that may, a failed request to the
+// healthy endpoints not tried yet.
+func poolRequest[T any](ctx context.Context, p *EndpointPool, height int64, request func(Client) (T, error)) (T, error) {
+	endpoint := p.pick(height)
+	tried := map[*endpointHealth]bool{}
+	failovers := 0
+	for {
+		start := time.Now()
+		resp, err := request(endpoint.Client)
+		pruned := height > 0 && IsPrunedError(err)
+		// A request canceled by the indexer, e.g. on shutdown, says nothing about the endpoint
+		if err == nil || ctx.Err() == nil {
+			p.record(endpoint, requestResult{failed: err != nil && !pruned, latency: time.Since(start)})
+		}
+		if err == nil || ctx.Err() != nil {
+			return resp, err
+		}
+		tried[endpoint] = true
+
+		if pruned {
+			p.pruned(endpoint, height, err)
+			endpoint = p.pick(height)
+			if !endpoint.serves(height) {
+				return resp, err
+			}
+			continue
+		}
+
+		if failovers >= p.opts.Failover {
+			return resp, err
+		}
+		next := p.failover(height, tried)
+		if next == nil {
+			return resp, err
+		}
+		config.Log.Debugf("Request to RPC endpoint %s failed, failing over to %s: %v", endpoint.Name, next.Name, err)
+		endpoint = next
+		failovers++
+	}
+}
+
+// pruned raises the lowest height the endpoint serves after it answered a request at height with a pruned error
+func (p *EndpointPool) pruned(endpoint *endpointHealth, height int64, err error) {
+	lowest := prunedLowestHeight(height, err)
+	for {
+		current := endpoint.lowest.Load()
+		if lowest <= current {
+			return
+		}
+		if endpoint.lowest.CompareAndSwap(current, lowest) {
+			break
+		}
+	}
+
+	metrics.SetEndpointLowestHeight(endpoint.Name, lowest)
+	config.Log.Infof("RPC endpoint %s pruned the heights below %d, they are routed to the other endpoints", endpoint.Name, lowest)
+}
+
+// tiers returns the roles of the endpoints serving a request at height, in the order they are tried
+func (p *EndpointPool) tiers(height int64) [][]EndpointRole {
+	head := p.head.Load()
+	if height > 0 && head > 0 && head-height > p.opts.HeadWindow {
+		return [][]EndpointRole{{RoleArchive, RoleAny}, {RoleFallback}}
+	}
+	return [][]EndpointRole{{RoleHead, RoleAny}, {RoleFallback}, {RoleArchive}}
+}
+
+func (p *EndpointPool) pick(height int64) *endpointHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	healthy := make(map[*endpointHealth]bool, len(p.endpoints))
+	var fallback *endpointHealth
+	var fallbackProbeAt time.Time
+	for _, endpoint := range p.endpoints {
+		endpoint.mu.Lock()
+		blacklisted, probeAt := endpoint.blacklisted, endpoint.probeAt
+		endpoint.mu.Unlock()
+
+		healthy[endpoint] = !blacklisted
+		if blacklisted && (fallback == nil || probeAt.Before(fallbackProbeAt)) {
+			fallback, fallbackProbeAt = endpoint, probeAt
+		}
+	}
+
+	// Endpoints whose range does not cover the height or that pruned it are left out, unless no endpoint may serve it
+	anyServes := false
+	for _, endpoint := range p.endpoints {
+		anyServes = anyServes || endpoint.serves(height)
+	}
+	usable := func(endpoint *endpointHealth) bool {
+		return healthy[endpoint] && (!anyServes || endpoint.serves(height))
+	}
+
+	for _, roles := range p.tiers(height) {
+		var candidates []*endpointHealth
+		for _, endpoint := range p.endpoints {
+			if usable(endpoint) && hasRole(roles, endpoint.Role) {
+				candidates = append(candidates, endpoint)
+			}
+		}
+		if len(candidates) > 0 {
+			return pickWeighted(candidates)
+		}
+	}
+
+	// Every endpoint that can serve the request is blacklisted, a healthy endpoint of another role may still have it
+	for _, endpoint := range p.endpoints {
+		if usable(endpoint) {
+			return endpoint
+		}
+	}
+	if anyServes && !fallback.serves(height) {
+		// A blacklisted endpoint that may serve the height is preferred over one that pruned it
+		for _, endpoint := range p.endpoints {
+			if endpoint.serves(height) {
+				return endpoint
+			}
+		}
+	}
+	return fallback
+}
+
+// failover picks a healthy endpoint that may serve the height and was not tried yet, by the tiers of the height, nil
+// when none is left
+func (p *EndpointPool) failover(height int64, tried map[*endpointHealth]bool) *endpointHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	anyServes := false
+	for _, endpoint := range p.endpoints {
+		anyServes = anyServes || endpoint.serves(height)
+	}
+
+	for _, roles := range p.tiers(height) {
+		var candidates []*endpointHealth
+		for _, endpoint := range p.endpoints {
+			if tried[endpoint] || !hasRole(roles, endpoint.Role) || (anyServes && !endpoint.serves(height)) {
+				continue
+			}
+			endpoint.mu.Lock()
+			blacklisted := endpoint.blacklisted
+			endpoint.mu.Unlock()
+			if !blacklisted {
+				candidates = append(candidates, endpoint)
+			}
+		}
+		if len(candidates) > 0 {
+			return pickWeighted(candidates)
+		}
+	}
+	return nil
+}
+
+// pickWeighted picks an endpoint by smooth weighted round robin, which interleaves the endpoints in proportion to
+// their weights instead of sending runs of requests to the heaviest one
+func pickWeighted(candidates []*endpointHealth) *endpointHealth {
+	total := 0
+	var picked *endpointHealth
+	for _, endpoint := range candidates {
+		endpoint.current += endpoint.Weight
+		total += endpoint.Weight
+		if picked == nil || endpoint.current > picked.current {
+			picked = endpoint
+		}
+	}
+	picked.current -= total
+	return picked
+}
+
+func hasRole(roles []EndpointRole, role EndpointRole) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// record adds the result of a request to the window of the endpoint and blacklists it once the full window is
+// unhealthy or too many requests failed in a row
+func (p *EndpointPool) record(endpoint *endpointHealth, result requestResult) {
+	metrics.ObserveEndpointRequest(endpoint.Name, result.failed, result.latency)
+
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+
+	if endpoint.blacklisted {
+		return
+	}
+
+	endpoint.consecutive++
+	if !result.failed {
+		endpoint.consecutive = 0
+	}
+	if p.opts.MaxConsecutiveFailures > 0 && endpoint.consecutive >= p.opts.MaxConsecutiveFailures {
+		p.blacklist(endpoint)
+		config.Log.Warnf("Blacklisted RPC endpoint %s for %s, its last %d requests failed", endpoint.Name, p.opts.Blacklist, p.opts.MaxConsecutiveFailures)
+		return
+	}
+
+	if len(endpoint.results) < p.opts.Window {
+		endpoint.results = append(endpoint.results, result)
+	} else {
+		endpoint.results[endpoint.next] = result
+	}
+	endpoint.next = (endpoint.next + 1) % p.opts.Window
+	if len(endpoint.results) < p.opts.Window {
+		return
+	}
+
+	var failed int
+	var latency time.Duration
+	for _, result := range endpoint.results {
+		if result.failed {
+			failed++
+		}
+		latency += result.latency
+	}
+	errorRate := float64(failed) / float64(len(endpoint.results))
+	latency /= time.Duration(len(endpoint.results))
+
+	if errorRate <= p.opts.MaxErrorRate && (p.opts.MaxLatency <= 0 || latency <= p.opts.MaxLatency) {
+		return
+	}
+
+	p.blacklist(endpoint)
+	config.Log.Warnf("Blacklisted RPC endpoint %s for %s, %.0f%% of its last %d requests failed and they took %s on average",
+		endpoint.Name, p.opts.Blacklist, errorRate*100, p.opts.Window, latency.Round(time.Millisecond))
+}
+
+// blacklist takes the endpoint out of the rotation until it is probed, its results start over once it rejoins. Must be
+// called with the lock of the endpoint held.
+func (p *EndpointPool) blacklist(endpoint *endpointHealth) {
+	endpoint.blacklisted = true
+	endpoint.probeAt = time.Now().Add(p.opts.Blacklist)
+	endpoint.results, endpoint.next, endpoint.consecutive = nil, 0, 0
+	metrics.SetEndpointHealthy(endpoint.Name, false)
+}
+
+// Start probes the blacklisted endpoints in the background until ctx is done. An endpoint whose status is answered
+// in time rejoins the rotation, one that fails stays out for another blacklist period.
+func (p *EndpointPool) Start(ctx context.Context) {
+	interval := p.opts.Blacklist / 4
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			for _, endpoint := range p.endpoints {
+				p.probe(ctx, endpoint)
+			}
+		}
+	}()
+}
+
+func (p *EndpointPool) probe(ctx context.Context, endpoint *endpointHealth) {
+	endpoint.mu.Lock()
+	due := endpoint.blacklisted && !time.Now().Before(endpoint.probeAt)
+	endpoint.mu.Unlock()
+	if !due {
+		return
+	}
+
+	timeout := p.opts.MaxLatency
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	_, err := endpoint.Client.Status(probeCtx)
+	if ctx.Err() != nil {
+		return
+	}
+
+	endpoint.mu.Lock()
+	defer endpoint.mu.Unlock()
+
+	if err != nil {
+		endpoint.probeAt = time.Now().Add(p.opts.Blacklist)
+		config.Log.Debugf("RPC endpoint %s is still unhealthy: %v", endpoint.Name, err)
+		return
+	}
+
+	endpoint.blacklisted = false
+	metrics.SetEndpointHealthy(endpoint.Name, true)
+	config.Log.Infof("RPC endpoint %s answered its probe and rejoins the rotation", endpoint.Name)
+}
@@ -0,0 +1,188 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+)
+
+func (suite *RequestsTestSuite) TestEndpointPool() {
+	ctx := context.Background()
+	healthy := &fakeClient{block: &coretypes.ResultBlock{Block: &cmttypes.Block{Header: cmttypes.Header{Height: 10}}}}
+	degraded := &fakeClient{}
+
+	pool := NewEndpointPool([]Endpoint{{Name: "healthy", Client: healthy}, {Name: "degraded", Client: degraded}}, HealthOptions{
+		Window:       2,
+		MaxErrorRate: 0.5,
+		Blacklist:    time.Minute,
+	})
+
+	// The requests alternate until the window of the degraded endpoint is full of failures
+	var failed int
+	for i := 0; i < 4; i++ {
+		if _, err := pool.Block(ctx, 10); err != nil {
+			failed++
+		}
+	}
+	suite.Require().Equal(2, failed)
+
+	for i := 0; i < 4; i++ {
+		_, err := pool.Block(ctx, 10)
+		suite.Require().NoError(err)
+	}
+
+	// A probe that is due and answered puts the endpoint back into the rotation
+	blacklisted := pool.endpoints[1]
+	suite.Require().True(blacklisted.blacklisted)
+	pool.probe(ctx, blacklisted)
+	suite.Require().True(blacklisted.blacklisted)
+
+	blacklisted.probeAt = time.Now()
+	pool.probe(ctx, blacklisted)
+	suite.Require().False(blacklisted.blacklisted)
+}
+
+func (suite *RequestsTestSuite) TestEndpointPoolRoles() {
+	ctx := context.Background()
+	endpointClient := func(id int64) *fakeClient {
+		client := &fakeClient{block: &coretypes.ResultBlock{Block: &cmttypes.Block{Header: cmttypes.Header{Height: id}}}}
+		client.status.SyncInfo.LatestBlockHeight = 1000
+		return client
+	}
+
+	pool := NewEndpointPool([]Endpoint{
+		{Name: "archive", Client: endpointClient(1), Role: RoleArchive},
+		{Name: "public-a", Client: endpointClient(2), Role: RoleHead, Weight: 2},
+		{Name: "public-b", Client: endpointClient(3), Role: RoleHead},
+	}, HealthOptions{Window: 10, MaxErrorRate: 0.5, Blacklist: time.Minute, HeadWindow: 100})
+
+	served := func(height int64) []int64 {
+		var ids []int64
+		for i := 0; i < 3; i++ {
+			block, err := pool.Block(ctx, height)
+			suite.Require().NoError(err)
+			ids = append(ids, block.Block.Height)
+		}
+		return ids
+	}
+
+	// The head goes to the head endpoints by weight, the status teaches the pool where the head is
+	_, err := pool.Status(ctx)
+	suite.Require().NoError(err)
+	suite.Require().ElementsMatch([]int64{2, 2, 3}, served(950))
+	suite.Require().Equal([]int64{1, 1, 1}, served(10))
+
+	// Without a healthy head endpoint the archive serves the head as well
+	for _, endpoint := range pool.endpoints[1:] {
+		endpoint.blacklisted = true
+	}
+	suite.Require().Equal([]int64{1, 1, 1}, served(950))
+}
+
+// prunedClient answers the block requests below its lowest height like a pruned node
+type prunedClient struct {
+	*fakeClient
+	lowest   int64
+	requests int
+}
+
+func (c *prunedClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	c.requests++
+	if height < c.lowest {
+		return nil, fmt.Errorf("RPC error -32603 - Internal error: height %d is not available, lowest height is %d", height, c.lowest)
+	}
+	return c.fakeClient.Block(ctx, height)
+}
+
+func (suite *RequestsTestSuite) TestEndpointPoolPrunedHeights() {
+	ctx := context.Background()
+	pruned := &prunedClient{fakeClient: &fakeClient{block: &coretypes.ResultBlock{Block: &cmttypes.Block{Header: cmttypes.Header{Height: 1}}}}, lowest: 500}
+	archive := &prunedClient{fakeClient: &fakeClient{block: &coretypes.ResultBlock{Block: &cmttypes.Block{Header: cmttypes.Header{Height: 2}}}}}
+
+	pool := NewEndpointPool([]Endpoint{
+		{Name: "pruned", Client: pruned, Weight: 10},
+		{Name: "archive", Client: archive, Role: RoleArchive},
+	}, HealthOptions{Window: 2, MaxErrorRate: 0.5, Blacklist: time.Minute})
+
+	// The pruned node is asked first, the archive answers once the pool learned the lowest height of the pruned node
+	block, err := pool.Block(ctx, 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(2), block.Block.Height)
+	suite.Require().Equal(int64(500), pool.endpoints[0].lowest.Load())
+	suite.Require().False(pool.endpoints[0].blacklisted)
+
+	// Lower heights go to the archive straight away, the others still to the pruned node
+	for i := 0; i < 3; i++ {
+		_, err = pool.Block(ctx, 20)
+		suite.Require().NoError(err)
+	}
+	suite.Require().Equal(1, pruned.requests)
+	block, err = pool.Block(ctx, 600)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(1), block.Block.Height)
+
+	// Without an endpoint serving the height the pruned error is returned
+	archive.lowest = 100
+	_, err = pool.Block(ctx, 50)
+	suite.Require().True(IsPrunedError(err))
+	suite.Require().Equal(int64(100), pool.endpoints[1].lowest.Load())
+}
+
+func (suite *RequestsTestSuite) TestEndpointPoolHeightRanges() {
+	ctx := context.Background()
+	endpointClient := func(id int64) *fakeClient {
+		client := &fakeClient{block: &coretypes.ResultBlock{Block: &cmttypes.Block{Header: cmttypes.Header{Height: id}}}}
+		client.status.SyncInfo.LatestBlockHeight = id
+		return client
+	}
+
+	pool := NewEndpointPool([]Endpoint{
+		{Name: "archive", Client: endpointClient(1), EndHeight: 4999},
+		{Name: "local", Client: endpointClient(2), StartHeight: 5000},
+	}, HealthOptions{Window: 10, MaxErrorRate: 0.5, Blacklist: time.Minute})
+
+	for height, id := range map[int64]int64{1: 1, 4999: 1, 5000: 2, 6000: 2} {
+		for i := 0; i < 2; i++ {
+			block, err := pool.Block(ctx, height)
+			suite.Require().NoError(err)
+			suite.Require().Equal(id, block.Block.Height, height)
+		}
+	}
+
+	// The status comes from the endpoint following the head
+	status, err := pool.Status(ctx)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(2), status.SyncInfo.LatestBlockHeight)
+}
+
+func (suite *RequestsTestSuite) TestEndpointPoolFailover() {
+	ctx := context.Background()
+	healthy := &fakeClient{block: &coretypes.ResultBlock{Block: &cmttypes.Block{Header: cmttypes.Header{Height: 10}}}}
+	degraded := &fakeClient{}
+
+	pool := NewEndpointPool([]Endpoint{{Name: "degraded", Client: degraded}, {Name: "healthy", Client: healthy}}, HealthOptions{
+		Window:                 20,
+		MaxErrorRate:           0.5,
+		MaxConsecutiveFailures: 3,
+		Blacklist:              time.Minute,
+		Failover:               1,
+	})
+
+	// The requests failing on the degraded endpoint are answered by the healthy one, until it is blacklisted for
+	// failing in a row long before its window filled up
+	for i := 0; i < 6; i++ {
+		block, err := pool.Block(ctx, 10)
+		suite.Require().NoError(err)
+		suite.Require().Equal(int64(10), block.Block.Height)
+	}
+	suite.Require().True(pool.endpoints[0].blacklisted)
+	suite.Require().False(pool.endpoints[1].blacklisted)
+
+	// Without another healthy endpoint the failure is returned
+	pool = NewEndpointPool([]Endpoint{{Name: "degraded", Client: degraded}}, HealthOptions{Window: 20, MaxErrorRate: 0.5, Blacklist: time.Minute, Failover: 1})
+	_, err := pool.Block(ctx, 10)
+	suite.Require().Error(err)
+}
@@ -0,0 +1,52 @@
+package rpc
+
+import (
+	"context"
+
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// Era is a range of heights of a chain served by a client of its own, e.g. a node of the software version the chain
+// ran then. EndHeight 0 leaves the era open ended.
+type Era struct {
+	StartHeight int64
+	EndHeight   int64
+	Client      Client
+}
+
+func (e Era) covers(height int64) bool {
+	return height >= e.StartHeight && (e.EndHeight == 0 || height <= e.EndHeight)
+}
+
+// EraClient sends the requests for a height to the client of the era covering it, and the requests of heights outside
+// every era as well as the status requests to the client it wraps
+type EraClient struct {
+	Client
+	eras []Era
+}
+
+func NewEraClient(cl Client, eras []Era) *EraClient {
+	return &EraClient{Client: cl, eras: eras}
+}
+
+func (c *EraClient) client(height int64) Client {
+	for _, era := range c.eras {
+		if era.covers(height) {
+			return era.Client
+		}
+	}
+	return c.Client
+}
+
+func (c *EraClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	return c.client(height).Block(ctx, height)
+}
+
+func (c *EraClient) BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error) {
+	return c.client(height).BlockResults(ctx, height)
+}
+
+func (c *EraClient) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	return c.client(height).TxsEvent(ctx, height, req)
+}
@@ -0,0 +1,241 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	tmjson "github.com/cometbft/cometbft/libs/json"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// Fixtures are stored one file per response in a directory, or below a prefix of an S3 bucket the directory was
+// uploaded to:
+//
+//	status.json                  the last node status
+//	blocks/<height>.json         blocks
+//	block_results/<height>.json  block results
+//	txs/<height>-<offset>.pb     pages of the transactions of a block, starting at offset
+//
+// The RPC responses are stored in the JSON encoding of the RPC server. The transaction pages are stored as protobuf,
+// messages of types the codec does not know can't be encoded as JSON.
+
+func statusFixture() string {
+	return "status.json"
+}
+
+func blockFixture(height int64) string {
+	return fmt.Sprintf("blocks/%d.json", height)
+}
+
+func blockResultsFixture(height int64) string {
+	return fmt.Sprintf("block_results/%d.json", height)
+}
+
+func txsFixture(height int64, req *txTypes.GetTxsEventRequest) string {
+	var offset uint64
+	if req.Pagination != nil {
+		offset = req.Pagination.Offset
+	}
+	return fmt.Sprintf("txs/%d-%d.pb", height, offset)
+}
+
+// RecordingClient writes every response of a Client to a fixtures directory, which a FixtureClient replays
+type RecordingClient struct {
+	Client
+	dir string
+}
+
+func NewRecordingClient(client Client, dir string) (*RecordingClient, error) {
+	for _, subdir := range []string{"blocks", "block_results", "txs"} {
+		if err := os.MkdirAll(filepath.Join(dir, subdir), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &RecordingClient{Client: client, dir: dir}, nil
+}
+
+// record writes a fixture through a temporary file, so a replay never reads a partial response. Failing to record
+// doesn't fail the request.
+func (c *RecordingClient) record(key string, data []byte, err error) {
+	file := filepath.Join(c.dir, filepath.FromSlash(key))
+	if err == nil {
+		tmp := file + ".tmp"
+		err = os.WriteFile(tmp, data, 0o600)
+		if err == nil {
+			err = os.Rename(tmp, file)
+		}
+	}
+	if err != nil {
+		config.Log.Warnf("Failed to record RPC fixture %s: %v", file, err)
+	}
+}
+
+func (c *RecordingClient) Status(ctx context.Context) (*coretypes.ResultStatus, error) {
+	resp, err := c.Client.Status(ctx)
+	if err == nil {
+		data, err := tmjson.Marshal(resp)
+		c.record(statusFixture(), data, err)
+	}
+	return resp, err
+}
+
+func (c *RecordingClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	resp, err := c.Client.Block(ctx, height)
+	if err == nil {
+		data, err := tmjson.Marshal(resp)
+		c.record(blockFixture(height), data, err)
+	}
+	return resp, err
+}
+
+func (c *RecordingClient) BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error) {
+	resp, err := c.Client.BlockResults(ctx, height)
+	if err == nil {
+		data, err := tmjson.Marshal(resp)
+		c.record(blockResultsFixture(height), data, err)
+	}
+	return resp, err
+}
+
+func (c *RecordingClient) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	resp, err := c.Client.TxsEvent(ctx, height, req)
+	if err == nil {
+		data, err := resp.Marshal()
+		c.record(txsFixture(height, req), data, err)
+	}
+	return resp, err
+}
+
+// fixtureSource reads the recorded responses by their key in the fixtures layout
+type fixtureSource interface {
+	read(ctx context.Context, key string) ([]byte, error)
+}
+
+type dirFixtures string
+
+func (dir dirFixtures) read(_ context.Context, key string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(string(dir), filepath.FromSlash(key)))
+}
+
+type s3Fixtures struct {
+	client *s3.S3
+	bucket string
+	prefix string
+}
+
+func (f *s3Fixtures) read(ctx context.Context, key string) ([]byte, error) {
+	resp, err := f.client.GetObjectWithContext(ctx, &s3.GetObjectInput{Bucket: aws.String(f.bucket), Key: aws.String(path.Join(f.prefix, key))})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// FixtureClient serves the responses recorded by a RecordingClient, requests that were not recorded fail
+type FixtureClient struct {
+	source   fixtureSource
+	registry codectypes.InterfaceRegistry
+}
+
+// NewFixtureClient replays the fixtures in dir, transaction messages are unpacked with the registry like the probe client does
+func NewFixtureClient(dir string, registry codectypes.InterfaceRegistry) *FixtureClient {
+	return &FixtureClient{source: dirFixtures(dir), registry: registry}
+}
+
+// NewS3FixtureClient replays the fixtures uploaded to an s3://bucket/prefix location. The region and endpoint are
+// optional, the AWS credentials and region are otherwise taken from the environment and shared config.
+func NewS3FixtureClient(location string, region string, endpoint string, registry codectypes.InterfaceRegistry) (*FixtureClient, error) {
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(location, "s3://"), "/")
+	if !strings.HasPrefix(location, "s3://") || bucket == "" {
+		return nil, fmt.Errorf("invalid S3 location %s", location)
+	}
+
+	awsConfig := aws.NewConfig()
+	if region != "" {
+		awsConfig = awsConfig.WithRegion(region)
+	}
+	if endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsConfig,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	source := &s3Fixtures{client: s3.New(sess), bucket: bucket, prefix: strings.Trim(prefix, "/")}
+	return &FixtureClient{source: source, registry: registry}, nil
+}
+
+func (c *FixtureClient) readFixture(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.source.read(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("no recorded response: %w", err)
+	}
+	return data, nil
+}
+
+func (c *FixtureClient) readJSONFixture(ctx context.Context, key string, result interface{}) error {
+	data, err := c.readFixture(ctx, key)
+	if err != nil {
+		return err
+	}
+	return tmjson.Unmarshal(data, result)
+}
+
+func (c *FixtureClient) Status(ctx context.Context) (*coretypes.ResultStatus, error) {
+	resp := &coretypes.ResultStatus{}
+	if err := readJSONFixture(statusFixture(), resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *FixtureClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	resp := &coretypes.ResultBlock{}
+	if err := readJSONFixture(blockFixture(height), resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *FixtureClient) BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error) {
+	resp := &coretypes.ResultBlockResults{}
+	if err := readJSONFixture(blockResultsFixture(height), resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (c *FixtureClient) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	data, err := c.readFixture(ctx, txsFixture(height, req))
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &txTypes.GetTxsEventResponse{}
+	if err := resp.Unmarshal(data); err != nil {
+		return nil, err
+	}
+	if c.registry != nil {
+		for _, tx := range resp.GetTxs() {
+			tx.UnpackInterfaces(c.registry) //nolint:errcheck
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,102 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+)
+
+func (suite *RequestsTestSuite) TestRecordAndReplayFixtures() {
+	ctx := context.Background()
+	dir := suite.T().TempDir()
+
+	live := &fakeClient{txs: []string{"A", "B", "C"}, pageSize: 2}
+	live.status.SyncInfo.LatestBlockHeight = 10
+	live.block = &coretypes.ResultBlock{Block: &cmttypes.Block{
+		Header: cmttypes.Header{ChainID: "test-1", Height: 10, Time: time.Unix(1700000000, 0).UTC()},
+		Data:   cmttypes.Data{Txs: cmttypes.Txs{[]byte("tx")}},
+	}}
+	live.blockResults = &coretypes.ResultBlockResults{
+		Height:           10,
+		TxsResults:       []*abci.ResponseDeliverTx{{Code: 0, Log: "ok", Events: []abci.Event{{Type: "message", Attributes: []abci.EventAttribute{{Key: "sender", Value: "cosmos1"}}}}}},
+		BeginBlockEvents: []abci.Event{{Type: "transfer", Attributes: []abci.EventAttribute{{Key: "amount", Value: "1stake"}}}},
+	}
+
+	recorder, err := NewRecordingClient(live, dir)
+	suite.Require().NoError(err)
+
+	_, err = GetLatestBlockHeight(recorder)
+	suite.Require().NoError(err)
+	_, err = GetBlock(ctx, recorder, 10)
+	suite.Require().NoError(err)
+	_, err = GetBlockResult(ctx, recorder, 10)
+	suite.Require().NoError(err)
+	_, err = GetTxsByBlockHeight(ctx, recorder, 10)
+	suite.Require().NoError(err)
+
+	replay := NewFixtureClient(dir, nil)
+
+	latest, err := GetLatestBlockHeight(replay)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(10), latest)
+
+	block, err := GetBlock(ctx, replay, 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal(live.block.Block.Header.ChainID, block.Block.Header.ChainID)
+	suite.Require().True(live.block.Block.Header.Time.Equal(block.Block.Header.Time))
+	suite.Require().Equal(live.block.Block.Data.Txs, block.Block.Data.Txs)
+
+	blockResults, err := GetBlockResult(ctx, replay, 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal(live.blockResults.TxsResults, blockResults.TxsResults)
+	suite.Require().Equal(live.blockResults.BeginBlockEvents, blockResults.BeginBlockEvents)
+
+	txs, err := GetTxsByBlockHeight(ctx, replay, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(txs.TxResponses, 3)
+	suite.Require().Equal("C", txs.TxResponses[2].TxHash)
+
+	// Heights that were not recorded fail like an unavailable node
+	_, err = GetBlock(ctx, replay, 11)
+	suite.Require().Error(err)
+
+	// The directory uploaded to an S3 bucket is replayed the same way
+	archive := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/archive/cosmoshub/")
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(key)))
+		if key == r.URL.Path || r.Method != http.MethodGet || err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data) //nolint:errcheck
+	}))
+	defer archive.Close()
+	suite.T().Setenv("AWS_ACCESS_KEY_ID", "test")
+	suite.T().Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	suite.T().Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	s3Replay, err := NewS3FixtureClient("s3://archive/cosmoshub", "us-east-1", archive.URL, nil)
+	suite.Require().NoError(err)
+	latest, err = GetLatestBlockHeight(s3Replay)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(10), latest)
+	block, err = GetBlock(ctx, s3Replay, 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal(live.block.Block.Data.Txs, block.Block.Data.Txs)
+	txs, err = GetTxsByBlockHeight(ctx, s3Replay, 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(txs.TxResponses, 3)
+	_, err = GetBlock(ctx, s3Replay, 11)
+	suite.Require().Error(err)
+
+	_, err = NewS3FixtureClient("s3:///cosmoshub", "", "", nil)
+	suite.Require().Error(err)
+}
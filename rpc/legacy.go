@@ -0,0 +1,336 @@
+package rpc
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	tmjson "github.com/cometbft/cometbft/libs/json"
+	cryptoproto "github.com/cometbft/cometbft/proto/tendermint/crypto"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	jsonrpc "github.com/cometbft/cometbft/rpc/jsonrpc/client"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// maxTxSearchPageSize is the largest page the tx_search of the RPC server returns
+const maxTxSearchPageSize = 100
+
+// LegacyOptions select the compatibility behaviour for the nodes of an older chain era
+type LegacyOptions struct {
+	// AminoBlockResults decodes the block results of Tendermint 0.32 and 0.33, which nest the results differently and
+	// encode the public keys of validator updates with amino
+	AminoBlockResults bool
+	// TxSearch searches the transactions with the tx_search of the RPC server instead of the gRPC transaction service,
+	// which the nodes of older Cosmos SDK versions do not serve for every height
+	TxSearch bool
+}
+
+// LegacyClient answers the requests of a Client the way the nodes of an older chain era need. Requests not covered by
+// the options are passed on to the client.
+type LegacyClient struct {
+	Client
+	uri      URIClient
+	registry codectypes.InterfaceRegistry
+	opts     LegacyOptions
+}
+
+// NewLegacyClient wraps the client of the node at the RPC address. Transactions found by the tx_search are unpacked
+// with the registry.
+func NewLegacyClient(cl Client, address string, registry codectypes.InterfaceRegistry, opts LegacyOptions) *LegacyClient {
+	return &LegacyClient{
+		Client:   cl,
+		uri:      URIClient{Address: address, Client: &http.Client{}},
+		registry: registry,
+		opts:     opts,
+	}
+}
+
+func (c *LegacyClient) BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error) {
+	if !c.opts.AminoBlockResults {
+		return c.Client.BlockResults(ctx, height)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Second)
+	defer cancel()
+
+	result := new(coretypes.ResultBlockResults)
+	err := c.uri.requestBlockResults(ctx, &height, func(r io.Reader) error {
+		return DecodeLegacyBlockResults(r, collectBlockResults(result))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// TxsEvent searches the transactions with the tx_search of the RPC server when the options ask for it. Its pages are
+// numbered from 1 and hold at most 100 transactions, the offset of the request is rounded down to the start of its
+// page and the transactions before it are dropped. Transactions the codec cannot decode, such as the amino encoded
+// transactions of chains before Stargate, fail the request, so the block falls back to decoding them from the block.
+func (c *LegacyClient) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	if !c.opts.TxSearch {
+		return c.Client.TxsEvent(ctx, height, req)
+	}
+
+	limit, offset := uint64(maxTxSearchPageSize), uint64(0)
+	if req.Pagination != nil {
+		if req.Pagination.Limit > 0 && req.Pagination.Limit < limit {
+			limit = req.Pagination.Limit
+		}
+		offset = req.Pagination.Offset
+	}
+
+	// The results of the tx_search do not carry the time of their block
+	block, err := c.Client.Block(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	timestamp := block.Block.Time.Format(time.RFC3339)
+
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Second)
+	defer cancel()
+
+	responseBytes, err := c.uri.get(ctx, "tx_search", map[string]interface{}{
+		"query":    strings.Join(req.Events, " AND "),
+		"prove":    false,
+		"page":     offset/limit + 1,
+		"per_page": limit,
+		"order_by": "asc",
+	})
+	if err != nil {
+		return nil, err
+	}
+	raw, err := responseResult(responseBytes, jsonrpc.URIClientRequestID)
+	if err != nil {
+		return nil, err
+	}
+
+	var search struct {
+		Txs []struct {
+			Hash     string          `json:"hash"`
+			Height   json.Number     `json:"height"`
+			TxResult json.RawMessage `json:"tx_result"`
+			Tx       []byte          `json:"tx"`
+		} `json:"txs"`
+		TotalCount json.Number `json:"total_count"`
+	}
+	if err := json.Unmarshal(raw, &search); err != nil {
+		return nil, fmt.Errorf("error unmarshalling result: %w", err)
+	}
+	total, err := strconv.ParseUint(search.TotalCount.String(), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling result: %w", err)
+	}
+
+	resp := &txTypes.GetTxsEventResponse{Pagination: &query.PageResponse{Total: total}}
+	skip := int(offset % limit)
+	for i, found := range search.Txs {
+		if i < skip {
+			continue
+		}
+
+		result := &coretypes.ResultTx{Tx: found.Tx}
+		if result.Hash, err = hex.DecodeString(found.Hash); err != nil {
+			return nil, fmt.Errorf("error unmarshalling result: %w", err)
+		}
+		if result.Height, err = strconv.ParseInt(found.Height.String(), 10, 64); err != nil {
+			return nil, fmt.Errorf("error unmarshalling result: %w", err)
+		}
+		txResult, err := decodeLegacyTxResult(found.TxResult)
+		if err != nil {
+			return nil, fmt.Errorf("error unmarshalling result: %w", err)
+		}
+		result.TxResult = *txResult
+
+		// The raw transaction has the fields of the transaction with its body and auth info still encoded
+		tx := new(txTypes.Tx)
+		if err := tx.Unmarshal(found.Tx); err != nil {
+			return nil, fmt.Errorf("failed to decode transaction %s of block %d: %w", found.Hash, height, err)
+		}
+		// Like the gRPC query, messages that fail to unpack are left packed instead of failing the block
+		tx.UnpackInterfaces(c.registry) //nolint:errcheck
+		anyTx, err := codectypes.NewAnyWithValue(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Txs = append(resp.Txs, tx)
+		resp.TxResponses = append(resp.TxResponses, sdk.NewResponseResultTx(result, anyTx, timestamp))
+	}
+
+	return resp, nil
+}
+
+// DecodeLegacyBlockResults decodes a block results response of Tendermint 0.32 or 0.33 like DecodeBlockResults. The
+// nested results of 0.32 are handed to the visitor like the fields of later versions and the amino public keys of the
+// validator updates are converted. The consensus param updates of these versions have another shape and are skipped.
+func DecodeLegacyBlockResults(r io.Reader, visitor BlockResultsVisitor) error {
+	return decodeResponse(r, func(dec *json.Decoder) error {
+		return decodeObject(dec, func(key string) error {
+			switch {
+			case key == "height" && visitor.Height != nil:
+				var height int64
+				if err := decodeTMJSON(dec, &height); err != nil {
+					return err
+				}
+				return visitor.Height(height)
+			case key == "results":
+				return decodeLegacyResults(dec, visitor)
+			case key == "txs_results" && visitor.TxResult != nil:
+				return decodeLegacyTxResults(dec, visitor.TxResult)
+			case key == "begin_block_events" && visitor.BeginBlockEvent != nil:
+				return decodeEvents(dec, visitor.BeginBlockEvent)
+			case key == "end_block_events" && visitor.EndBlockEvent != nil:
+				return decodeEvents(dec, visitor.EndBlockEvent)
+			case key == "validator_updates" && visitor.ValidatorUpdate != nil:
+				return decodeLegacyValidatorUpdates(dec, visitor.ValidatorUpdate)
+			default:
+				return skipValue(dec)
+			}
+		})
+	})
+}
+
+// decodeLegacyResults decodes the results object of Tendermint 0.32, which holds the transaction results as deliver_tx
+// and the events and validator updates in begin_block and end_block objects
+func decodeLegacyResults(dec *json.Decoder, visitor BlockResultsVisitor) error {
+	return decodeObject(dec, func(key string) error {
+		switch {
+		case key == "deliver_tx" && visitor.TxResult != nil:
+			return decodeLegacyTxResults(dec, visitor.TxResult)
+		case key == "begin_block":
+			return decodeObject(dec, func(key string) error {
+				if key == "events" && visitor.BeginBlockEvent != nil {
+					return decodeEvents(dec, visitor.BeginBlockEvent)
+				}
+				return skipValue(dec)
+			})
+		case key == "end_block":
+			return decodeObject(dec, func(key string) error {
+				switch {
+				case key == "events" && visitor.EndBlockEvent != nil:
+					return decodeEvents(dec, visitor.EndBlockEvent)
+				case key == "validator_updates" && visitor.ValidatorUpdate != nil:
+					return decodeLegacyValidatorUpdates(dec, visitor.ValidatorUpdate)
+				default:
+					return skipValue(dec)
+				}
+			})
+		default:
+			return skipValue(dec)
+		}
+	})
+}
+
+func decodeLegacyTxResults(dec *json.Decoder, visit func(index int, result *abci.ResponseDeliverTx) error) error {
+	index := 0
+	return decodeArray(dec, func(raw json.RawMessage) error {
+		result, err := decodeLegacyTxResult(raw)
+		if err != nil {
+			return err
+		}
+		index++
+		return visit(index-1, result)
+	})
+}
+
+// decodeLegacyTxResult decodes a transaction result, which versions before Tendermint 0.34 encode with the gas fields
+// spelled gasWanted and gasUsed
+func decodeLegacyTxResult(raw json.RawMessage) (*abci.ResponseDeliverTx, error) {
+	result := new(abci.ResponseDeliverTx)
+	if err := tmjson.Unmarshal(raw, result); err != nil {
+		return nil, err
+	}
+
+	var gas struct {
+		GasWanted json.Number `json:"gasWanted"`
+		GasUsed   json.Number `json:"gasUsed"`
+	}
+	if err := json.Unmarshal(raw, &gas); err != nil {
+		return nil, err
+	}
+	if gas.GasWanted != "" {
+		wanted, err := strconv.ParseInt(gas.GasWanted.String(), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		result.GasWanted = wanted
+	}
+	if gas.GasUsed != "" {
+		used, err := strconv.ParseInt(gas.GasUsed.String(), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		result.GasUsed = used
+	}
+	return result, nil
+}
+
+// decodeLegacyValidatorUpdates decodes validator updates whose public keys are encoded with amino as a type and the
+// key bytes, instead of the protobuf oneof of later versions
+func decodeLegacyValidatorUpdates(dec *json.Decoder, visit func(update abci.ValidatorUpdate) error) error {
+	return decodeArray(dec, func(raw json.RawMessage) error {
+		var legacy struct {
+			PubKey struct {
+				Type string `json:"type"`
+				Data []byte `json:"data"`
+			} `json:"pub_key"`
+			Power json.Number `json:"power"`
+		}
+		if err := json.Unmarshal(raw, &legacy); err != nil {
+			return err
+		}
+
+		power, err := strconv.ParseInt(legacy.Power.String(), 10, 64)
+		if err != nil {
+			return err
+		}
+		update := abci.ValidatorUpdate{Power: power}
+		switch legacy.PubKey.Type {
+		case "ed25519":
+			update.PubKey.Sum = &cryptoproto.PublicKey_Ed25519{Ed25519: legacy.PubKey.Data}
+		case "secp256k1":
+			update.PubKey.Sum = &cryptoproto.PublicKey_Secp256K1{Secp256K1: legacy.PubKey.Data}
+		default:
+			return fmt.Errorf("unsupported validator public key type %q", legacy.PubKey.Type)
+		}
+		return visit(update)
+	})
+}
+
+// decodeObject hands the keys of the next object, or null, to field, which must consume their values
+func decodeObject(dec *json.Decoder, field func(key string) error) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("expected an object, got %v", token)
+	}
+
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return err
+		}
+		if err := field(key); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
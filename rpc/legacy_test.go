@@ -0,0 +1,104 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	cryptoproto "github.com/cometbft/cometbft/proto/tendermint/crypto"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+func (suite *RequestsTestSuite) TestDecodeLegacyBlockResults() {
+	key := []byte{1, 2, 3}
+	expected := &coretypes.ResultBlockResults{
+		Height: 10,
+		TxsResults: []*abci.ResponseDeliverTx{
+			{Code: 0, GasWanted: 200000, GasUsed: 150000, Events: []abci.Event{{Type: "transfer", Attributes: []abci.EventAttribute{{Key: "YW1vdW50", Value: "MTB1YXRvbQ=="}}}}},
+		},
+		BeginBlockEvents: []abci.Event{{Type: "mint"}},
+		EndBlockEvents:   []abci.Event{{Type: "complete_unbonding"}},
+		ValidatorUpdates: []abci.ValidatorUpdate{{PubKey: cryptoproto.PublicKey{Sum: &cryptoproto.PublicKey_Ed25519{Ed25519: key}}, Power: 10}},
+	}
+	txResult := `{"code": 0, "gasWanted": "200000", "gasUsed": "150000", "events": [{"type": "transfer", "attributes": [{"key": "YW1vdW50", "value": "MTB1YXRvbQ=="}]}]}`
+	validatorUpdate := fmt.Sprintf(`{"pub_key": {"type": "ed25519", "data": "%s"}, "power": "10"}`, base64.StdEncoding.EncodeToString(key))
+
+	// Tendermint 0.32 nests the results, 0.33 returns them like later versions
+	responses := []string{
+		fmt.Sprintf(`{"jsonrpc": "2.0", "id": -1, "result": {"height": "10", "results": {"deliver_tx": [%s], "begin_block": {"events": [{"type": "mint"}]},
+			"end_block": {"validator_updates": [%s], "consensus_param_updates": {"block": {"max_bytes": "200000"}}, "events": [{"type": "complete_unbonding"}]}}}}`, txResult, validatorUpdate),
+		fmt.Sprintf(`{"jsonrpc": "2.0", "id": -1, "result": {"height": "10", "txs_results": [%s], "begin_block_events": [{"type": "mint"}], "end_block_events": [{"type": "complete_unbonding"}],
+			"validator_updates": [%s], "consensus_param_updates": {"evidence": {"max_age": "100000"}}}}`, txResult, validatorUpdate),
+	}
+	for _, response := range responses {
+		decoded := new(coretypes.ResultBlockResults)
+		suite.Require().NoError(DecodeLegacyBlockResults(strings.NewReader(response), collectBlockResults(decoded)))
+		suite.Require().Equal(expected, decoded)
+	}
+
+	err := DecodeLegacyBlockResults(strings.NewReader(`{"jsonrpc": "2.0", "id": -1, "result": {"validator_updates": [{"pub_key": {"type": "sr25519", "data": ""}, "power": "1"}]}}`), collectBlockResults(new(coretypes.ResultBlockResults)))
+	suite.Require().ErrorContains(err, "sr25519")
+}
+
+func (suite *RequestsTestSuite) TestLegacyTxSearch() {
+	body, err := (&txTypes.TxBody{Memo: "legacy"}).Marshal()
+	suite.Require().NoError(err)
+	raw, err := (&txTypes.TxRaw{BodyBytes: body, AuthInfoBytes: []byte{}}).Marshal()
+	suite.Require().NoError(err)
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, fmt.Sprintf("%s %s page %s", r.URL.Path, r.URL.Query().Get("query"), r.URL.Query().Get("page")))
+		fmt.Fprintf(w, `{"jsonrpc": "2.0", "id": -1, "result": {"txs": [
+			{"hash": "AB01", "height": "10", "index": 0, "tx_result": {"code": 0, "log": "[]", "gasUsed": "5"}, "tx": "%[1]s"},
+			{"hash": "AB02", "height": "10", "index": 1, "tx_result": {"code": 0, "log": "[]", "gasUsed": "7"}, "tx": "%[1]s"}
+		], "total_count": "4"}}`, base64.StdEncoding.EncodeToString(raw))
+	}))
+	defer server.Close()
+
+	blockTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	node := &fakeClient{block: &coretypes.ResultBlock{Block: &cmttypes.Block{Header: cmttypes.Header{Height: 10, Time: blockTime}}}}
+	client := NewLegacyClient(node, server.URL, codectypes.NewInterfaceRegistry(), LegacyOptions{TxSearch: true})
+
+	req := txsRequest(10, 2)
+	req.Pagination.Offset = 3
+	resp, err := client.TxsEvent(context.Background(), 10, req)
+	suite.Require().NoError(err)
+
+	// The offset is rounded down to the start of its page, the transactions before it are dropped
+	suite.Require().Equal([]string{`/tx_search "tx.height=10" page "2"`}, requests)
+	suite.Require().Equal(&query.PageResponse{Total: 4}, resp.Pagination)
+	suite.Require().Len(resp.Txs, 1)
+	suite.Require().Equal("legacy", resp.Txs[0].Body.Memo)
+	suite.Require().Equal("AB02", resp.TxResponses[0].TxHash)
+	suite.Require().Equal(int64(7), resp.TxResponses[0].GasUsed)
+	suite.Require().Equal(blockTime.Format(time.RFC3339), resp.TxResponses[0].Timestamp)
+
+	// Without the option the transactions are searched through the wrapped client
+	node.txs, node.pageSize = []string{"CD01"}, 100
+	resp, err = NewLegacyClient(node, server.URL, codectypes.NewInterfaceRegistry(), LegacyOptions{}).TxsEvent(context.Background(), 10, txsRequest(10, 100))
+	suite.Require().NoError(err)
+	suite.Require().Equal("CD01", resp.TxResponses[0].TxHash)
+}
+
+func (suite *RequestsTestSuite) TestEraClient() {
+	ctx := context.Background()
+	legacy := &fakeClient{block: &coretypes.ResultBlock{Block: &cmttypes.Block{Header: cmttypes.Header{ChainID: "cosmoshub-3"}}}}
+	current := &fakeClient{block: &coretypes.ResultBlock{Block: &cmttypes.Block{Header: cmttypes.Header{ChainID: "cosmoshub-4"}}}}
+	client := NewEraClient(current, []Era{{StartHeight: 1, EndHeight: 100, Client: legacy}})
+
+	for height, chainID := range map[int64]string{1: "cosmoshub-3", 100: "cosmoshub-3", 101: "cosmoshub-4"} {
+		block, err := client.Block(ctx, height)
+		suite.Require().NoError(err)
+		suite.Require().Equal(chainID, block.Block.ChainID, height)
+	}
+}
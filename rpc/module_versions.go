@@ -0,0 +1,180 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	upgradeTypes "github.com/cosmos/cosmos-sdk/x/upgrade/types"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrModuleVersionsUnsupported is returned by GetModuleVersions for clients that can't query the module versions
+var ErrModuleVersionsUnsupported = errors.New("the client does not query module versions")
+
+// ModuleVersions maps the modules of a chain to their consensus versions, as the x/upgrade module tracks them
+type ModuleVersions map[string]uint64
+
+// Equal reports whether both maps hold the same modules at the same versions
+func (v ModuleVersions) Equal(other ModuleVersions) bool {
+	if len(v) != len(other) {
+		return false
+	}
+	for module, version := range v {
+		if otherVersion, ok := other[module]; !ok || otherVersion != version {
+			return false
+		}
+	}
+	return true
+}
+
+// ModuleVersionsClient is implemented by the clients that query the module version map of the x/upgrade module at a
+// height. The fakes and fixtures of the Client interface don't need to serve it.
+type ModuleVersionsClient interface {
+	// ModuleVersions returns the module versions in effect while the block of the height was executed, empty for
+	// heights before the chain tracked module versions (Cosmos SDK v0.43)
+	ModuleVersions(ctx context.Context, height int64) (ModuleVersions, error)
+}
+
+// GetModuleVersions queries the module versions of the height when the client supports it
+func GetModuleVersions(ctx context.Context, cl Client, height int64) (ModuleVersions, error) {
+	versionsClient, ok := cl.(ModuleVersionsClient)
+	if !ok {
+		return nil, ErrModuleVersionsUnsupported
+	}
+	return versionsClient.ModuleVersions(ctx, height)
+}
+
+// ModuleVersionChange is a module whose version changed at the height, to version 0 when it was removed
+type ModuleVersionChange struct {
+	Height  int64
+	Module  string
+	Version uint64
+}
+
+// ModuleVersionChanges finds the heights between start and end, exclusive and inclusive, at which the module versions
+// changed from the versions at start, which are queried unless given. The versions only change at upgrades, so the
+// range is bisected wherever the versions at its ends differ, which takes a query per halving around each upgrade.
+// The versions at end are returned with the changes.
+func ModuleVersionChanges(ctx context.Context, cl Client, start int64, startVersions ModuleVersions, end int64) ([]ModuleVersionChange, ModuleVersions, error) {
+	var err error
+	if startVersions == nil {
+		if startVersions, err = GetModuleVersions(ctx, cl, start); err != nil {
+			return nil, nil, fmt.Errorf("failed to query the module versions at height %d: %w", start, err)
+		}
+	}
+	endVersions, err := GetModuleVersions(ctx, cl, end)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query the module versions at height %d: %w", end, err)
+	}
+
+	var changes []ModuleVersionChange
+	var bisect func(low int64, lowVersions ModuleVersions, high int64, highVersions ModuleVersions) error
+	bisect = func(low int64, lowVersions ModuleVersions, high int64, highVersions ModuleVersions) error {
+		if lowVersions.Equal(highVersions) {
+			return nil
+		}
+		if high == low+1 {
+			changes = append(changes, versionChanges(high, lowVersions, highVersions)...)
+			return nil
+		}
+
+		middle := low + (high-low)/2
+		middleVersions, err := GetModuleVersions(ctx, cl, middle)
+		if err != nil {
+			return fmt.Errorf("failed to query the module versions at height %d: %w", middle, err)
+		}
+		if err := bisect(low, lowVersions, middle, middleVersions); err != nil {
+			return err
+		}
+		return bisect(middle, middleVersions, high, highVersions)
+	}
+
+	if err := bisect(start, startVersions, end, endVersions); err != nil {
+		return nil, nil, err
+	}
+	return changes, endVersions, nil
+}
+
+// versionChanges returns the modules whose version differs between the versions before and at the height, sorted by
+// module
+func versionChanges(height int64, before ModuleVersions, after ModuleVersions) []ModuleVersionChange {
+	var changes []ModuleVersionChange
+	for module, version := range after {
+		if previous, ok := before[module]; !ok || previous != version {
+			changes = append(changes, ModuleVersionChange{Height: height, Module: module, Version: version})
+		}
+	}
+	for module := range before {
+		if _, ok := after[module]; !ok {
+			changes = append(changes, ModuleVersionChange{Height: height, Module: module})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Module < changes[j].Module })
+	return changes
+}
+
+// isUnknownQuery reports whether the node has no module versions query, which x/upgrade only serves since v0.43
+func isUnknownQuery(err error) bool {
+	if status.Code(err) == codes.Unimplemented {
+		return true
+	}
+	message := err.Error()
+	return strings.Contains(message, "unknown query path") || strings.Contains(message, "unknown service")
+}
+
+func (c *ChainClient) ModuleVersions(ctx context.Context, height int64) (ModuleVersions, error) {
+	ctx, cancel := c.queryContext(ctx, height)
+	defer cancel()
+
+	resp, err := upgradeTypes.NewQueryClient(c.cl).ModuleVersions(ctx, &upgradeTypes.QueryModuleVersionsRequest{})
+	if err != nil && isUnknownQuery(err) {
+		return ModuleVersions{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make(ModuleVersions, len(resp.ModuleVersions))
+	for _, version := range resp.ModuleVersions {
+		versions[version.Name] = version.Version
+	}
+	return versions, nil
+}
+
+func (p *EndpointPool) ModuleVersions(ctx context.Context, height int64) (ModuleVersions, error) {
+	return poolRequest(ctx, p, height, func(client Client) (ModuleVersions, error) {
+		return GetModuleVersions(ctx, client, height)
+	})
+}
+
+func (c *EraClient) ModuleVersions(ctx context.Context, height int64) (ModuleVersions, error) {
+	return GetModuleVersions(ctx, c.client(height), height)
+}
+
+func (c *LegacyClient) ModuleVersions(ctx context.Context, height int64) (ModuleVersions, error) {
+	return GetModuleVersions(ctx, c.Client, height)
+}
+
+// ModuleVersions are not recorded, a FixtureClient does not serve them
+func (c *RecordingClient) ModuleVersions(ctx context.Context, height int64) (ModuleVersions, error) {
+	return GetModuleVersions(ctx, c.Client, height)
+}
+
+// ModuleVersions of the wrapped client are not compared, they only select the custom parsers
+func (c *ConsistencyClient) ModuleVersions(ctx context.Context, height int64) (ModuleVersions, error) {
+	return GetModuleVersions(ctx, c.Client, height)
+}
+
+func (c *ChaosClient) ModuleVersions(ctx context.Context, height int64) (ModuleVersions, error) {
+	return GetModuleVersions(ctx, c.Client, height)
+}
+
+// ModuleVersions are queried from the wrapped client unverified, the light client only verifies headers and the
+// versions are not part of them
+func (c *VerifyingClient) ModuleVersions(ctx context.Context, height int64) (ModuleVersions, error) {
+	return GetModuleVersions(ctx, c.Client, height)
+}
@@ -0,0 +1,66 @@
+package rpc
+
+import (
+	"context"
+)
+
+// upgradedClient serves the module versions of a chain that upgraded at the heights of upgrades
+type upgradedClient struct {
+	*fakeClient
+	initial  ModuleVersions
+	upgrades map[int64]ModuleVersions
+	requests int
+}
+
+func (c *upgradedClient) ModuleVersions(ctx context.Context, height int64) (ModuleVersions, error) {
+	c.requests++
+	versions := ModuleVersions{}
+	for module, version := range c.initial {
+		versions[module] = version
+	}
+	for upgradeHeight := int64(1); upgradeHeight <= height; upgradeHeight++ {
+		for module, version := range c.upgrades[upgradeHeight] {
+			if version == 0 {
+				delete(versions, module)
+				continue
+			}
+			versions[module] = version
+		}
+	}
+	return versions, nil
+}
+
+func (suite *RequestsTestSuite) TestModuleVersionChanges() {
+	ctx := context.Background()
+	cl := &upgradedClient{
+		fakeClient: &fakeClient{},
+		initial:    ModuleVersions{"bank": 1, "staking": 1, "crisis": 1},
+		upgrades: map[int64]ModuleVersions{
+			300: {"bank": 2},
+			301: {"staking": 2, "crisis": 0, "wasm": 1},
+		},
+	}
+
+	changes, endVersions, err := ModuleVersionChanges(ctx, cl, 1, nil, 1000)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]ModuleVersionChange{
+		{Height: 300, Module: "bank", Version: 2},
+		{Height: 301, Module: "crisis", Version: 0},
+		{Height: 301, Module: "staking", Version: 2},
+		{Height: 301, Module: "wasm", Version: 1},
+	}, changes)
+	suite.Require().Equal(ModuleVersions{"bank": 2, "staking": 2, "wasm": 1}, endVersions)
+	// A query per halving around the upgrades, not per height
+	suite.Require().Less(cl.requests, 40)
+
+	// Without upgrades only the ends are queried
+	cl.requests = 0
+	changes, _, err = ModuleVersionChanges(ctx, cl, 400, endVersions, 1000)
+	suite.Require().NoError(err)
+	suite.Require().Empty(changes)
+	suite.Require().Equal(1, cl.requests)
+
+	// Clients without the query can't be tracked
+	_, _, err = ModuleVersionChanges(ctx, &fakeClient{}, 1, nil, 1000)
+	suite.Require().ErrorIs(err, ErrModuleVersionsUnsupported)
+}
@@ -0,0 +1,30 @@
+package rpc
+
+import (
+	"regexp"
+	"strconv"
+)
+
+var (
+	// prunedPattern matches the errors of nodes asked for heights they pruned: the RPC server of CometBFT for blocks and
+	// block results, and the gRPC queries of the Cosmos SDK for the state the transactions are searched in
+	prunedPattern = regexp.MustCompile(`(?i)height \d+ is not available|lowest height is \d+|failed to load state at height|version does not exist|is pruned`)
+	// lowestHeightPattern extracts the lowest height the CometBFT RPC server still has
+	lowestHeightPattern = regexp.MustCompile(`lowest height is (\d+)`)
+)
+
+// IsPrunedError tells if a request failed because the node pruned the height
+func IsPrunedError(err error) bool {
+	return err != nil && prunedPattern.MatchString(err.Error())
+}
+
+// prunedLowestHeight returns the lowest height a node serves after it answered a request at height with a pruned error,
+// the height after it unless the error tells
+func prunedLowestHeight(height int64, err error) int64 {
+	if match := lowestHeightPattern.FindStringSubmatch(err.Error()); match != nil {
+		if lowest, parseErr := strconv.ParseInt(match[1], 10, 64); parseErr == nil && lowest > height {
+			return lowest
+		}
+	}
+	return height + 1
+}
@@ -1,35 +1,26 @@
 package rpc
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	coretypes "github.com/cometbft/cometbft/rpc/core/types"
 
 	"github.com/DefiantLabs/cosmos-indexer/config"
-	probeClient "github.com/DefiantLabs/probe/client"
-	probeQuery "github.com/DefiantLabs/probe/query"
 	"github.com/cosmos/cosmos-sdk/types/query"
 	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
 )
 
-// GetBlockTimestamp
-func GetBlock(cl *probeClient.ChainClient, height int64) (*coretypes.ResultBlock, error) {
-	options := probeQuery.QueryOptions{Height: height}
-	query := probeQuery.Query{Client: cl, Options: &options}
-	resp, err := query.Block()
-	if err != nil {
-		return nil, err
-	}
-
-	return resp, nil
+// GetBlock makes a request to the Cosmos RPC API and returns the block at height
+func GetBlock(ctx context.Context, cl Client, height int64) (*coretypes.ResultBlock, error) {
+	return cl.Block(ctx, height)
 }
 
 // GetTxsByBlockHeight makes a request to the Cosmos RPC API and returns all the transactions for a specific block
-func GetTxsByBlockHeight(cl *probeClient.ChainClient, height int64) (*txTypes.GetTxsEventResponse, error) {
-	pg := query.PageRequest{Limit: 100}
-	options := probeQuery.QueryOptions{Height: height, Pagination: &pg}
-	query := probeQuery.Query{Client: cl, Options: &options}
-	resp, err := query.TxByHeight(cl.Codec)
+func GetTxsByBlockHeight(ctx context.Context, cl Client, height int64) (*txTypes.GetTxsEventResponse, error) {
+	req := txsRequest(height, 100)
+	resp, err := cl.TxsEvent(ctx, height, req)
 	if err != nil {
 		return nil, err
 	}
@@ -38,8 +29,8 @@ func GetTxsByBlockHeight(cl *probeClient.ChainClient, height int64) (*txTypes.Ge
 	if resp != nil && resp.Pagination != nil {
 		// if there are more total objects than we have so far, keep going
 		for resp.Pagination.Total > uint64(len(resp.Txs)) {
-			query.Options.Pagination.Offset = uint64(len(resp.Txs))
-			chunkResp, err := query.TxByHeight(cl.Codec)
+			req.Pagination.Offset = uint64(len(resp.Txs))
+			chunkResp, err := cl.TxsEvent(ctx, height, req)
 			if err != nil {
 				return nil, err
 			}
@@ -51,32 +42,103 @@ func GetTxsByBlockHeight(cl *probeClient.ChainClient, height int64) (*txTypes.Ge
 	return resp, nil
 }
 
-// IsCatchingUp true if the node is catching up to the chain, false otherwise
-func IsCatchingUp(cl *probeClient.ChainClient) (bool, error) {
-	query := probeQuery.Query{Client: cl, Options: &probeQuery.QueryOptions{}}
-	ctx, cancel := query.GetQueryContext()
-	defer cancel()
+func txsRequest(height int64, limit uint64) *txTypes.GetTxsEventRequest {
+	return &txTypes.GetTxsEventRequest{
+		Events:     []string{fmt.Sprintf("tx.height=%d", height)},
+		Pagination: &query.PageRequest{Limit: limit},
+		OrderBy:    txTypes.OrderBy_ORDER_BY_UNSPECIFIED,
+	}
+}
+
+// OversizedBlock is true when the transaction search of the block is likely too large to be answered in time in pages
+// of 100 transactions, because the block has more than maxTxs transactions or they take more than maxBytes. A
+// threshold of 0 is disabled.
+func OversizedBlock(block *coretypes.ResultBlock, maxTxs int, maxBytes int64) bool {
+	if block == nil || block.Block == nil {
+		return false
+	}
+
+	txs := block.Block.Data.Txs
+	if maxTxs > 0 && len(txs) > maxTxs {
+		return true
+	}
+
+	if maxBytes > 0 {
+		var size int64
+		for _, tx := range txs {
+			size += int64(len(tx))
+		}
+		return size > maxBytes
+	}
+	return false
+}
+
+// GetTxsByBlockHeightChunked returns all the transactions of a block like GetTxsByBlockHeight, in pages of pageSize
+// transactions. A page that fails is fetched again one transaction at a time, so a few huge transactions of an
+// airdrop block only slow their page down instead of failing the block.
+func GetTxsByBlockHeightChunked(ctx context.Context, cl Client, height int64, pageSize uint64) (*txTypes.GetTxsEventResponse, error) {
+	req := txsRequest(height, pageSize)
+	resp := &txTypes.GetTxsEventResponse{}
+	for {
+		offset := uint64(len(resp.Txs))
+		req.Pagination.Offset = offset
+		page, err := cl.TxsEvent(ctx, height, req)
+		if err != nil && pageSize > 1 && ctx.Err() == nil {
+			config.Log.Warnf("Failed to get transactions %d to %d of block %d, getting them one at a time. Err: %v", offset, offset+pageSize-1, height, err)
+			page, err = getTxsOneByOne(ctx, cl, height, offset, pageSize)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		resp.Txs = append(resp.Txs, page.Txs...)
+		resp.TxResponses = append(resp.TxResponses, page.TxResponses...)
+		resp.Pagination = page.Pagination
+		if len(page.Txs) == 0 || page.Pagination == nil || page.Pagination.Total <= uint64(len(resp.Txs)) {
+			return resp, nil
+		}
+	}
+}
+
+// getTxsOneByOne gets up to count transactions of the block starting at offset, one request per transaction
+func getTxsOneByOne(ctx context.Context, cl Client, height int64, offset uint64, count uint64) (*txTypes.GetTxsEventResponse, error) {
+	req := txsRequest(height, 1)
+	resp := &txTypes.GetTxsEventResponse{}
+	for i := offset; i < offset+count; i++ {
+		req.Pagination.Offset = i
+		page, err := cl.TxsEvent(ctx, height, req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get transaction %d of block %d: %w", i, height, err)
+		}
 
-	resStatus, err := query.Client.RPCClient.Status(ctx)
+		resp.Txs = append(resp.Txs, page.Txs...)
+		resp.TxResponses = append(resp.TxResponses, page.TxResponses...)
+		resp.Pagination = page.Pagination
+		if len(page.Txs) == 0 || page.Pagination == nil || page.Pagination.Total <= i+1 {
+			break
+		}
+	}
+	return resp, nil
+}
+
+// IsCatchingUp true if the node is catching up to the chain, false otherwise
+func IsCatchingUp(cl Client) (bool, error) {
+	resStatus, err := cl.Status(context.Background())
 	if err != nil {
 		return false, err
 	}
 	return resStatus.SyncInfo.CatchingUp, nil
 }
 
-func GetLatestBlockHeight(cl *probeClient.ChainClient) (int64, error) {
-	query := probeQuery.Query{Client: cl, Options: &probeQuery.QueryOptions{}}
-	ctx, cancel := query.GetQueryContext()
-	defer cancel()
-
-	resStatus, err := query.Client.RPCClient.Status(ctx)
+func GetLatestBlockHeight(cl Client) (int64, error) {
+	resStatus, err := cl.Status(context.Background())
 	if err != nil {
 		return 0, err
 	}
 	return resStatus.SyncInfo.LatestBlockHeight, nil
 }
 
-func GetLatestBlockHeightWithRetry(cl *probeClient.ChainClient, retryMaxAttempts int64, retryMaxWaitSeconds uint64) (int64, error) {
+func GetLatestBlockHeightWithRetry(cl Client, retryMaxAttempts int64, retryMaxWaitSeconds uint64) (int64, error) {
 	if retryMaxAttempts == 0 {
 		return GetLatestBlockHeight(cl)
 	}
@@ -116,12 +178,8 @@ func GetLatestBlockHeightWithRetry(cl *probeClient.ChainClient, retryMaxAttempts
 	}
 }
 
-func GetEarliestAndLatestBlockHeights(cl *probeClient.ChainClient) (int64, int64, error) {
-	query := probeQuery.Query{Client: cl, Options: &probeQuery.QueryOptions{}}
-	ctx, cancel := query.GetQueryContext()
-	defer cancel()
-
-	resStatus, err := query.Client.RPCClient.Status(ctx)
+func GetEarliestAndLatestBlockHeights(cl Client) (int64, int64, error) {
+	resStatus, err := cl.Status(context.Background())
 	if err != nil {
 		return 0, 0, err
 	}
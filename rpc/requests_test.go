@@ -0,0 +1,166 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	"github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeClient serves a single block and its transactions in pages of at most pageSize. Requests for pages of more than
+// maxPage transactions fail like a response too large to be answered in time.
+type fakeClient struct {
+	status       coretypes.ResultStatus
+	block        *coretypes.ResultBlock
+	blockResults *coretypes.ResultBlockResults
+	txs          []string
+	tx           *txTypes.Tx // served for every hash, empty when nil
+	pageSize     int
+	failAt       uint64
+	maxPage      uint64
+	offsets      []uint64
+}
+
+func (c *fakeClient) Status(ctx context.Context) (*coretypes.ResultStatus, error) {
+	return &c.status, nil
+}
+
+func (c *fakeClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	if c.block == nil {
+		return nil, errors.New("block not found")
+	}
+	return c.block, nil
+}
+
+func (c *fakeClient) BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error) {
+	if c.blockResults == nil {
+		return nil, errors.New("block results not found")
+	}
+	return c.blockResults, nil
+}
+
+func (c *fakeClient) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	offset := req.Pagination.Offset
+	c.offsets = append(c.offsets, offset)
+	if c.failAt != 0 && offset == c.failAt {
+		return nil, errors.New("node unavailable")
+	}
+	if c.maxPage != 0 && req.Pagination.Limit > c.maxPage {
+		return nil, context.DeadlineExceeded
+	}
+
+	limit := uint64(c.pageSize)
+	if req.Pagination.Limit < limit {
+		limit = req.Pagination.Limit
+	}
+	end := offset + limit
+	if end > uint64(len(c.txs)) {
+		end = uint64(len(c.txs))
+	}
+	resp := &txTypes.GetTxsEventResponse{Pagination: &query.PageResponse{Total: uint64(len(c.txs))}}
+	for _, hash := range c.txs[offset:end] {
+		tx := c.tx
+		if tx == nil {
+			tx = &txTypes.Tx{}
+		}
+		resp.Txs = append(resp.Txs, tx)
+		resp.TxResponses = append(resp.TxResponses, &types.TxResponse{Height: height, TxHash: hash})
+	}
+	return resp, nil
+}
+
+type RequestsTestSuite struct {
+	suite.Suite
+}
+
+func (suite *RequestsTestSuite) TestGetTxsByBlockHeightPaginates() {
+	cl := &fakeClient{txs: []string{"A", "B", "C", "D", "E"}, pageSize: 2}
+
+	resp, err := GetTxsByBlockHeight(context.Background(), cl, 10)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]uint64{0, 2, 4}, cl.offsets)
+	suite.Require().Len(resp.Txs, 5)
+
+	var hashes []string
+	for _, txResp := range resp.TxResponses {
+		hashes = append(hashes, txResp.TxHash)
+	}
+	suite.Require().Equal(cl.txs, hashes)
+}
+
+func (suite *RequestsTestSuite) TestGetTxsByBlockHeightPageError() {
+	cl := &fakeClient{txs: []string{"A", "B", "C"}, pageSize: 2, failAt: 2}
+
+	_, err := GetTxsByBlockHeight(context.Background(), cl, 10)
+	suite.Require().Error(err)
+}
+
+func (suite *RequestsTestSuite) TestGetTxsByBlockHeightChunked() {
+	cl := &fakeClient{txs: []string{"A", "B", "C", "D", "E"}, pageSize: 100}
+
+	resp, err := GetTxsByBlockHeightChunked(context.Background(), cl, 10, 2)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]uint64{0, 2, 4}, cl.offsets)
+	suite.Require().Len(resp.TxResponses, 5)
+
+	// Pages that fail are fetched one transaction at a time
+	cl = &fakeClient{txs: []string{"A", "B", "C", "D", "E"}, pageSize: 100, maxPage: 1}
+	resp, err = GetTxsByBlockHeightChunked(context.Background(), cl, 10, 3)
+	suite.Require().NoError(err)
+	suite.Require().Equal([]uint64{0, 0, 1, 2, 3, 3, 4}, cl.offsets)
+
+	var hashes []string
+	for _, txResp := range resp.TxResponses {
+		hashes = append(hashes, txResp.TxHash)
+	}
+	suite.Require().Equal(cl.txs, hashes)
+
+	cl = &fakeClient{txs: []string{"A", "B", "C"}, pageSize: 100, maxPage: 1, failAt: 1}
+	_, err = GetTxsByBlockHeightChunked(context.Background(), cl, 10, 2)
+	suite.Require().ErrorContains(err, "transaction 1 of block 10")
+
+	resp, err = GetTxsByBlockHeightChunked(context.Background(), &fakeClient{pageSize: 100}, 10, 2)
+	suite.Require().NoError(err)
+	suite.Require().Empty(resp.Txs)
+}
+
+func (suite *RequestsTestSuite) TestOversizedBlock() {
+	block := &coretypes.ResultBlock{Block: &cmttypes.Block{}}
+	block.Block.Data.Txs = cmttypes.Txs{make([]byte, 600), make([]byte, 600)}
+
+	suite.Require().False(OversizedBlock(block, 2, 2000))
+	suite.Require().True(OversizedBlock(block, 1, 2000))
+	suite.Require().True(OversizedBlock(block, 2, 1000))
+	suite.Require().False(OversizedBlock(block, 0, 0))
+	suite.Require().False(OversizedBlock(&coretypes.ResultBlock{}, 1, 1))
+}
+
+func (suite *RequestsTestSuite) TestStatus() {
+	cl := &fakeClient{}
+	cl.status.SyncInfo.EarliestBlockHeight = 5
+	cl.status.SyncInfo.LatestBlockHeight = 100
+	cl.status.SyncInfo.CatchingUp = true
+
+	earliest, latest, err := GetEarliestAndLatestBlockHeights(cl)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(5), earliest)
+	suite.Require().Equal(int64(100), latest)
+
+	latest, err = GetLatestBlockHeightWithRetry(cl, 0, 0)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(100), latest)
+
+	catchingUp, err := IsCatchingUp(cl)
+	suite.Require().NoError(err)
+	suite.Require().True(catchingUp)
+}
+
+func TestRequestsSuite(t *testing.T) {
+	suite.Run(t, new(RequestsTestSuite))
+}
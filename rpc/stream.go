@@ -0,0 +1,341 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	tmjson "github.com/cometbft/cometbft/libs/json"
+	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	jsonrpc "github.com/cometbft/cometbft/rpc/jsonrpc/client"
+	types "github.com/cometbft/cometbft/rpc/jsonrpc/types"
+)
+
+// BlockResultsVisitor receives the parts of a block results response in the order they are decoded, so a response
+// with tens of thousands of events is processed without holding the whole response in memory. Nil callbacks skip
+// their part. An error returned by a callback stops the decoding.
+type BlockResultsVisitor struct {
+	Height                func(height int64) error
+	TxResult              func(index int, result *abci.ResponseDeliverTx) error
+	BeginBlockEvent       func(event abci.Event) error
+	EndBlockEvent         func(event abci.Event) error
+	ValidatorUpdate       func(update abci.ValidatorUpdate) error
+	ConsensusParamUpdates func(params *cmtproto.ConsensusParams) error
+}
+
+// StreamBlockResults requests the block results at height and hands their parts to the visitor while the response
+// is read
+func (c *URIClient) StreamBlockResults(ctx context.Context, height *int64, visitor BlockResultsVisitor) error {
+	return c.requestBlockResults(ctx, height, func(r io.Reader) error {
+		return DecodeBlockResults(r, visitor)
+	})
+}
+
+// requestBlockResults requests the block results at height and hands the response body to decode
+func (c *URIClient) requestBlockResults(ctx context.Context, height *int64, decode func(r io.Reader) error) error {
+	params := make(map[string]interface{})
+	if height != nil {
+		params["height"] = height
+	}
+
+	values, err := argsToURLValues(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode params: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.Address+"/block_results", nil)
+	if err != nil {
+		return fmt.Errorf("error creating new request: %w", err)
+	}
+	req.URL.RawQuery = values.Encode()
+	if c.AuthHeader != "" {
+		req.Header.Add("Authorization", c.AuthHeader)
+	}
+
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("get: %w", err)
+	}
+	defer resp.Body.Close()
+
+	return decode(resp.Body)
+}
+
+// DecodeBlockResults decodes a JSON-RPC block results response token by token. Only one transaction result or event
+// is held at a time, the parts the visitor does not ask for and unknown fields are skipped without buffering them.
+func DecodeBlockResults(r io.Reader, visitor BlockResultsVisitor) error {
+	return decodeResponse(r, func(dec *json.Decoder) error {
+		return decodeBlockResultsObject(dec, visitor)
+	})
+}
+
+// decodeResponse decodes the envelope of a JSON-RPC response and hands its result to decodeResult
+func decodeResponse(r io.Reader, decodeResult func(dec *json.Decoder) error) error {
+	dec := json.NewDecoder(r)
+	if err := expectDelim(dec, '{'); err != nil {
+		return fmt.Errorf("error unmarshalling: %w", err)
+	}
+
+	hasResult := false
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return fmt.Errorf("error unmarshalling: %w", err)
+		}
+
+		switch key {
+		case "id":
+			var id json.RawMessage
+			if err := dec.Decode(&id); err != nil {
+				return fmt.Errorf("error unmarshalling: %w", err)
+			}
+			if expected := fmt.Sprint(int(jsonrpc.URIClientRequestID)); string(bytes.TrimSpace(id)) != expected {
+				return fmt.Errorf("wrong ID: response ID (%s) does not match request ID (%s)", id, expected)
+			}
+		case "error":
+			var rpcErr *types.RPCError
+			if err := dec.Decode(&rpcErr); err != nil {
+				return fmt.Errorf("error unmarshalling: %w", err)
+			}
+			if rpcErr != nil {
+				return rpcErr
+			}
+		case "result":
+			hasResult = true
+			if err := decodeResult(dec); err != nil {
+				return fmt.Errorf("error unmarshalling result: %w", err)
+			}
+		default:
+			if err := skipValue(dec); err != nil {
+				return fmt.Errorf("error unmarshalling: %w", err)
+			}
+		}
+	}
+
+	if !hasResult {
+		return errors.New("error unmarshalling: the response has no result")
+	}
+	return nil
+}
+
+func decodeBlockResultsObject(dec *json.Decoder, visitor BlockResultsVisitor) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('{') {
+		return fmt.Errorf("expected an object, got %v", token)
+	}
+
+	for dec.More() {
+		key, err := objectKey(dec)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case key == "height" && visitor.Height != nil:
+			var height int64
+			if err := decodeTMJSON(dec, &height); err != nil {
+				return err
+			}
+			err = visitor.Height(height)
+		case key == "txs_results" && visitor.TxResult != nil:
+			index := 0
+			err = decodeArray(dec, func(raw json.RawMessage) error {
+				result := new(abci.ResponseDeliverTx)
+				if err := tmjson.Unmarshal(raw, result); err != nil {
+					return err
+				}
+				index++
+				return visitor.TxResult(index-1, result)
+			})
+		case key == "begin_block_events" && visitor.BeginBlockEvent != nil:
+			err = decodeEvents(dec, visitor.BeginBlockEvent)
+		case key == "end_block_events" && visitor.EndBlockEvent != nil:
+			err = decodeEvents(dec, visitor.EndBlockEvent)
+		case key == "finalize_block_events" && (visitor.BeginBlockEvent != nil || visitor.EndBlockEvent != nil):
+			err = decodeEvents(dec, func(event abci.Event) error {
+				return visitFinalizeBlockEvent(visitor, event)
+			})
+		case key == "validator_updates" && visitor.ValidatorUpdate != nil:
+			err = decodeArray(dec, func(raw json.RawMessage) error {
+				var update abci.ValidatorUpdate
+				if err := tmjson.Unmarshal(raw, &update); err != nil {
+					return err
+				}
+				return visitor.ValidatorUpdate(update)
+			})
+		case key == "consensus_param_updates" && visitor.ConsensusParamUpdates != nil:
+			var params *cmtproto.ConsensusParams
+			if err := decodeTMJSON(dec, &params); err != nil {
+				return err
+			}
+			err = visitor.ConsensusParamUpdates(params)
+		default:
+			err = skipValue(dec)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+func decodeEvents(dec *json.Decoder, visit func(abci.Event) error) error {
+	return decodeArray(dec, func(raw json.RawMessage) error {
+		var event abci.Event
+		if err := tmjson.Unmarshal(raw, &event); err != nil {
+			return err
+		}
+		return visit(event)
+	})
+}
+
+// visitFinalizeBlockEvent hands an event of the FinalizeBlock of ABCI 2.0 nodes (CometBFT 0.38+, Cosmos SDK v0.50+) to
+// the visitor of the lifecycle position it was emitted in. The Cosmos SDK marks the events of the blockers with a mode
+// attribute: PreBlock and BeginBlock events are begin block events, EndBlock events and events without a mode are end
+// block events. Unlike the begin and end block events of older nodes, the attributes are not base64 encoded, they are
+// encoded here so block events are processed the same whichever node version returned them.
+func visitFinalizeBlockEvent(visitor BlockResultsVisitor, event abci.Event) error {
+	mode := ""
+	for i, attribute := range event.Attributes {
+		if attribute.Key == "mode" {
+			mode = attribute.Value
+		}
+		event.Attributes[i].Key = base64.StdEncoding.EncodeToString([]byte(attribute.Key))
+		event.Attributes[i].Value = base64.StdEncoding.EncodeToString([]byte(attribute.Value))
+	}
+
+	visit := visitor.EndBlockEvent
+	if mode == "PreBlock" || mode == "BeginBlock" {
+		visit = visitor.BeginBlockEvent
+	}
+	if visit == nil {
+		return nil
+	}
+	return visit(event)
+}
+
+// decodeTMJSON decodes the next value with the amino JSON encoding of CometBFT, which encodes 64 bit integers as strings
+func decodeTMJSON(dec *json.Decoder, v interface{}) error {
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	return tmjson.Unmarshal(raw, v)
+}
+
+// decodeArray hands the elements of the next array, or null, to element one at a time
+func decodeArray(dec *json.Decoder, element func(raw json.RawMessage) error) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil
+	}
+	if token != json.Delim('[') {
+		return fmt.Errorf("expected an array, got %v", token)
+	}
+
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		if err := element(raw); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+// skipValue consumes the next value token by token
+func skipValue(dec *json.Decoder) error {
+	depth := 0
+	for {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+func objectKey(dec *json.Decoder) (string, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	key, ok := token.(string)
+	if !ok {
+		return "", fmt.Errorf("expected an object key, got %v", token)
+	}
+	return key, nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("expected %v, got %v", delim, token)
+	}
+	return nil
+}
+
+// collectBlockResults is the visitor materializing the whole response, for the callers of the Client interface. The
+// indexing pipeline needs the whole result, block event parsers are handed all events of the block.
+func collectBlockResults(result *ctypes.ResultBlockResults) BlockResultsVisitor {
+	return BlockResultsVisitor{
+		Height: func(height int64) error {
+			result.Height = height
+			return nil
+		},
+		TxResult: func(index int, txResult *abci.ResponseDeliverTx) error {
+			result.TxsResults = append(result.TxsResults, txResult)
+			return nil
+		},
+		BeginBlockEvent: func(event abci.Event) error {
+			result.BeginBlockEvents = append(result.BeginBlockEvents, event)
+			return nil
+		},
+		EndBlockEvent: func(event abci.Event) error {
+			result.EndBlockEvents = append(result.EndBlockEvents, event)
+			return nil
+		},
+		ValidatorUpdate: func(update abci.ValidatorUpdate) error {
+			result.ValidatorUpdates = append(result.ValidatorUpdates, update)
+			return nil
+		},
+		ConsensusParamUpdates: func(params *cmtproto.ConsensusParams) error {
+			result.ConsensusParamUpdates = params
+			return nil
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	tmjson "github.com/cometbft/cometbft/libs/json"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+)
+
+func (suite *RequestsTestSuite) TestDecodeBlockResults() {
+	expected := &ctypes.ResultBlockResults{
+		Height: 10,
+		TxsResults: []*abci.ResponseDeliverTx{
+			{Code: 0, GasWanted: 200000, GasUsed: 150000, Events: []abci.Event{{Type: "transfer", Attributes: []abci.EventAttribute{{Key: "amount", Value: "10uatom", Index: true}}}}},
+			{Code: 5, Log: "insufficient funds"},
+		},
+		BeginBlockEvents: []abci.Event{{Type: "mint", Attributes: []abci.EventAttribute{{Key: "amount", Value: "100"}}}},
+		EndBlockEvents:   []abci.Event{{Type: "complete_unbonding"}},
+	}
+	result, err := tmjson.Marshal(expected)
+	suite.Require().NoError(err)
+
+	// Fields of newer node versions are skipped
+	response := fmt.Sprintf(`{"jsonrpc": "2.0", "id": -1, "result": %s, "finalize_block_events": [{"type": "x", "attributes": [{"key": "a"}]}]}`, result)
+	decoded := new(ctypes.ResultBlockResults)
+	suite.Require().NoError(DecodeBlockResults(strings.NewReader(response), collectBlockResults(decoded)))
+	suite.Require().Equal(expected, decoded)
+
+	// Only the parts asked for are decoded
+	var events []string
+	err = DecodeBlockResults(strings.NewReader(response), BlockResultsVisitor{
+		BeginBlockEvent: func(event abci.Event) error {
+			events = append(events, event.Type)
+			return nil
+		},
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"mint"}, events)
+
+	err = DecodeBlockResults(strings.NewReader(`{"jsonrpc": "2.0", "id": -1, "error": {"code": -32603, "message": "Internal error", "data": "height 10 is not available"}}`), collectBlockResults(decoded))
+	suite.Require().ErrorContains(err, "height 10 is not available")
+
+	err = DecodeBlockResults(strings.NewReader(fmt.Sprintf(`{"jsonrpc": "2.0", "id": 7, "result": %s}`, result)), collectBlockResults(decoded))
+	suite.Require().ErrorContains(err, "wrong ID")
+
+	err = DecodeBlockResults(strings.NewReader(`{"jsonrpc": "2.0", "id": -1, "result": {"txs_results": [`), collectBlockResults(decoded))
+	suite.Require().Error(err)
+}
+
+func (suite *RequestsTestSuite) TestDecodeFinalizeBlockEvents() {
+	// Block results of an ABCI 2.0 node, whose block events are returned together with plain attributes
+	response := `{"jsonrpc": "2.0", "id": -1, "result": {"height": "10", "txs_results": null,
+		"finalize_block_events": [
+			{"type": "mint", "attributes": [{"key": "amount", "value": "100", "index": true}, {"key": "mode", "value": "BeginBlock", "index": true}]},
+			{"type": "upgrade", "attributes": [{"key": "mode", "value": "PreBlock"}]},
+			{"type": "complete_unbonding", "attributes": [{"key": "mode", "value": "EndBlock"}]},
+			{"type": "unmarked", "attributes": []}
+		],
+		"validator_updates": null, "consensus_param_updates": null, "app_hash": "AAAA"}}`
+
+	decoded := new(ctypes.ResultBlockResults)
+	suite.Require().NoError(DecodeBlockResults(strings.NewReader(response), collectBlockResults(decoded)))
+	suite.Require().Equal(int64(10), decoded.Height)
+
+	var begin, end []string
+	for _, event := range decoded.BeginBlockEvents {
+		begin = append(begin, event.Type)
+	}
+	for _, event := range decoded.EndBlockEvents {
+		end = append(end, event.Type)
+	}
+	suite.Require().Equal([]string{"mint", "upgrade"}, begin)
+	suite.Require().Equal([]string{"complete_unbonding", "unmarked"}, end)
+
+	// The attributes are encoded like the block events of older nodes
+	attribute := decoded.BeginBlockEvents[0].Attributes[0]
+	suite.Require().Equal(base64.StdEncoding.EncodeToString([]byte("amount")), attribute.Key)
+	suite.Require().Equal(base64.StdEncoding.EncodeToString([]byte("100")), attribute.Value)
+	suite.Require().True(attribute.Index)
+}
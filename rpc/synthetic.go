@@ -0,0 +1,289 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/p2p"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	"github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+	bankTypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+const (
+	// syntheticGenesis is the Unix time of height 0, the blocks follow every syntheticBlockTime seconds
+	syntheticGenesis   = 1704067200
+	syntheticBlockTime = 6
+	syntheticDenom     = "stake"
+)
+
+// SyntheticOptions shape the chain data a SyntheticClient generates
+type SyntheticOptions struct {
+	ChainID string
+	// LatestHeight is reported as the head of the chain, every height up to it can be fetched
+	LatestHeight int64
+	TxsPerBlock  int
+	MsgsPerTx    int
+	// BlockEvents is the number of begin block and of end block events of every block
+	BlockEvents int
+	// Addresses is the number of accounts sending and receiving, which sizes the address table
+	Addresses int
+}
+
+// SyntheticClient generates blocks of bank sends instead of fetching them, so the parse and DB stages can be loaded
+// without a node. Every height is generated the same way on every request.
+type SyntheticClient struct {
+	opts SyntheticOptions
+
+	keysOnce sync.Once
+	keys     []*secp256k1.PrivKey
+}
+
+func NewSyntheticClient(opts SyntheticOptions) *SyntheticClient {
+	return &SyntheticClient{opts: opts}
+}
+
+// accounts derives the keys of the accounts on first use, their addresses are encoded with the account prefix of the
+// chain config set by then
+func (c *SyntheticClient) accounts() []*secp256k1.PrivKey {
+	c.keysOnce.Do(func() {
+		c.keys = make([]*secp256k1.PrivKey, c.opts.Addresses)
+		for i := range c.keys {
+			c.keys[i] = secp256k1.GenPrivKeyFromSecret([]byte(fmt.Sprintf("synthetic-%d", i)))
+		}
+	})
+	return c.keys
+}
+
+// syntheticTx is a generated transaction with the results the node would report for it
+type syntheticTx struct {
+	tx     *txTypes.Tx
+	raw    []byte
+	hash   string
+	logs   types.ABCIMessageLogs
+	events []abci.Event
+}
+
+func (c *SyntheticClient) blockTime(height int64) time.Time {
+	return time.Unix(syntheticGenesis+height*syntheticBlockTime, 0).UTC()
+}
+
+func (c *SyntheticClient) generateTxs(height int64) ([]syntheticTx, error) {
+	rng := rand.New(rand.NewSource(height)) //nolint:gosec
+	keys := c.accounts()
+
+	txs := make([]syntheticTx, 0, c.opts.TxsPerBlock)
+	for i := 0; i < c.opts.TxsPerBlock; i++ {
+		sender := keys[rng.Intn(len(keys))]
+		from := types.AccAddress(sender.PubKey().Address()).String()
+
+		body := &txTypes.TxBody{Memo: fmt.Sprintf("synthetic %d/%d", height, i)}
+		var logs types.ABCIMessageLogs
+		for msgIdx := 0; msgIdx < c.opts.MsgsPerTx; msgIdx++ {
+			to := types.AccAddress(keys[rng.Intn(len(keys))].PubKey().Address()).String()
+			amount := types.NewCoins(types.NewInt64Coin(syntheticDenom, rng.Int63n(1_000_000)+1))
+
+			msg, err := codectypes.NewAnyWithValue(&bankTypes.MsgSend{FromAddress: from, ToAddress: to, Amount: amount})
+			if err != nil {
+				return nil, err
+			}
+			body.Messages = append(body.Messages, msg)
+			logs = append(logs, types.ABCIMessageLog{MsgIndex: uint32(msgIdx), Events: sendEvents(from, to, amount.String())})
+		}
+
+		pubKey, err := codectypes.NewAnyWithValue(sender.PubKey())
+		if err != nil {
+			return nil, err
+		}
+		authInfo := &txTypes.AuthInfo{
+			SignerInfos: []*txTypes.SignerInfo{{PublicKey: pubKey, Sequence: uint64(height)}},
+			Fee:         &txTypes.Fee{Amount: types.NewCoins(types.NewInt64Coin(syntheticDenom, 5000)), GasLimit: 200000},
+		}
+		signature := make([]byte, 64)
+		rng.Read(signature)
+
+		bodyBytes, err := body.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		authInfoBytes, err := authInfo.Marshal()
+		if err != nil {
+			return nil, err
+		}
+		raw, err := (&txTypes.TxRaw{BodyBytes: bodyBytes, AuthInfoBytes: authInfoBytes, Signatures: [][]byte{signature}}).Marshal()
+		if err != nil {
+			return nil, err
+		}
+
+		var events []abci.Event
+		for _, msgLog := range logs {
+			events = append(events, toABCIEvents(msgLog.Events)...)
+		}
+
+		txs = append(txs, syntheticTx{
+			tx:     &txTypes.Tx{Body: body, AuthInfo: authInfo, Signatures: [][]byte{signature}},
+			raw:    raw,
+			hash:   strings.ToUpper(hex.EncodeToString(cmttypes.Tx(raw).Hash())),
+			logs:   logs,
+			events: events,
+		})
+	}
+	return txs, nil
+}
+
+// sendEvents are the events the bank module emits for a send
+func sendEvents(from, to, amount string) types.StringEvents {
+	return types.StringEvents{
+		{Type: "coin_spent", Attributes: []types.Attribute{{Key: "spender", Value: from}, {Key: "amount", Value: amount}}},
+		{Type: "coin_received", Attributes: []types.Attribute{{Key: "receiver", Value: to}, {Key: "amount", Value: amount}}},
+		{Type: "transfer", Attributes: []types.Attribute{{Key: "recipient", Value: to}, {Key: "sender", Value: from}, {Key: "amount", Value: amount}}},
+		{Type: "message", Attributes: []types.Attribute{{Key: "action", Value: "/cosmos.bank.v1beta1.MsgSend"}, {Key: "sender", Value: from}, {Key: "module", Value: "bank"}}},
+	}
+}
+
+func toABCIEvents(stringEvents types.StringEvents) []abci.Event {
+	events := make([]abci.Event, 0, len(stringEvents))
+	for _, event := range stringEvents {
+		attributes := make([]abci.EventAttribute, 0, len(event.Attributes))
+		for _, attribute := range event.Attributes {
+			attributes = append(attributes, abci.EventAttribute{Key: attribute.Key, Value: attribute.Value, Index: true})
+		}
+		events = append(events, abci.Event{Type: event.Type, Attributes: attributes})
+	}
+	return events
+}
+
+// generateBlockEvents generates transfers like the rewards paid out at the beginning or end of a block
+func (c *SyntheticClient) generateBlockEvents(rng *rand.Rand) []abci.Event {
+	keys := c.accounts()
+
+	var events []abci.Event
+	for i := 0; i < c.opts.BlockEvents; i++ {
+		from := types.AccAddress(keys[rng.Intn(len(keys))].PubKey().Address()).String()
+		to := types.AccAddress(keys[rng.Intn(len(keys))].PubKey().Address()).String()
+		amount := types.NewInt64Coin(syntheticDenom, rng.Int63n(1_000_000)+1).String()
+		events = append(events, toABCIEvents(sendEvents(from, to, amount)[:3])...)
+	}
+
+	// Nodes return the block event attributes base64 encoded
+	for _, event := range events {
+		for i := range event.Attributes {
+			event.Attributes[i].Key = base64.StdEncoding.EncodeToString([]byte(event.Attributes[i].Key))
+			event.Attributes[i].Value = base64.StdEncoding.EncodeToString([]byte(event.Attributes[i].Value))
+		}
+	}
+	return events
+}
+
+func (c *SyntheticClient) checkHeight(height int64) error {
+	if height < 1 || height > c.opts.LatestHeight {
+		return fmt.Errorf("height %d is not in the synthetic chain of heights 1 to %d", height, c.opts.LatestHeight)
+	}
+	return nil
+}
+
+func (c *SyntheticClient) Status(ctx context.Context) (*coretypes.ResultStatus, error) {
+	return &coretypes.ResultStatus{
+		NodeInfo: p2p.DefaultNodeInfo{Network: c.opts.ChainID},
+		SyncInfo: coretypes.SyncInfo{
+			EarliestBlockHeight: 1,
+			EarliestBlockTime:   c.blockTime(1),
+			LatestBlockHeight:   c.opts.LatestHeight,
+			LatestBlockTime:     c.blockTime(c.opts.LatestHeight),
+		},
+	}, nil
+}
+
+func (c *SyntheticClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	if err := c.checkHeight(height); err != nil {
+		return nil, err
+	}
+	txs, err := c.generateTxs(height)
+	if err != nil {
+		return nil, err
+	}
+	keys := c.accounts()
+
+	block := &cmttypes.Block{
+		Header: cmttypes.Header{
+			ChainID:         c.opts.ChainID,
+			Height:          height,
+			Time:            c.blockTime(height),
+			ProposerAddress: keys[height%int64(len(keys))].PubKey().Address(),
+		},
+	}
+	for _, tx := range txs {
+		block.Data.Txs = append(block.Data.Txs, tx.raw)
+	}
+	return &coretypes.ResultBlock{Block: block}, nil
+}
+
+func (c *SyntheticClient) BlockResults(ctx context.Context, height int64) (*coretypes.ResultBlockResults, error) {
+	if err := c.checkHeight(height); err != nil {
+		return nil, err
+	}
+	txs, err := c.generateTxs(height)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &coretypes.ResultBlockResults{Height: height}
+	for _, tx := range txs {
+		resp.TxsResults = append(resp.TxsResults, &abci.ResponseDeliverTx{Log: tx.logs.String(), GasWanted: 200000, GasUsed: 100000, Events: tx.events})
+	}
+	// The block events are generated apart from the transactions, which draw from a source of their own
+	rng := rand.New(rand.NewSource(-height)) //nolint:gosec
+	resp.BeginBlockEvents = c.generateBlockEvents(rng)
+	resp.EndBlockEvents = c.generateBlockEvents(rng)
+	return resp, nil
+}
+
+func (c *SyntheticClient) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	if err := c.checkHeight(height); err != nil {
+		return nil, err
+	}
+	txs, err := c.generateTxs(height)
+	if err != nil {
+		return nil, err
+	}
+
+	offset, end := uint64(0), uint64(len(txs))
+	if req.Pagination != nil {
+		offset = req.Pagination.Offset
+		if req.Pagination.Limit > 0 && offset+req.Pagination.Limit < end {
+			end = offset + req.Pagination.Limit
+		}
+	}
+	if offset > end {
+		offset = end
+	}
+
+	resp := &txTypes.GetTxsEventResponse{Pagination: &query.PageResponse{Total: uint64(len(txs))}}
+	timestamp := c.blockTime(height).Format(time.RFC3339)
+	for _, tx := range txs[offset:end] {
+		resp.Txs = append(resp.Txs, tx.tx)
+		resp.TxResponses = append(resp.TxResponses, &types.TxResponse{
+			Height:    height,
+			TxHash:    tx.hash,
+			RawLog:    tx.logs.String(),
+			Logs:      tx.logs,
+			GasWanted: 200000,
+			GasUsed:   100000,
+			Timestamp: timestamp,
+			Events:    tx.events,
+		})
+	}
+	return resp, nil
+}
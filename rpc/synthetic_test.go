@@ -0,0 +1,55 @@
+package rpc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	bankTypes "github.com/cosmos/cosmos-sdk/x/bank/types"
+)
+
+func (suite *RequestsTestSuite) TestSyntheticClient() {
+	ctx := context.Background()
+	cl := NewSyntheticClient(SyntheticOptions{ChainID: "synthetic-1", LatestHeight: 10, TxsPerBlock: 150, MsgsPerTx: 2, BlockEvents: 3, Addresses: 20})
+
+	latest, err := GetLatestBlockHeight(cl)
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(10), latest)
+
+	block, err := GetBlock(ctx, cl, 5)
+	suite.Require().NoError(err)
+	suite.Require().Equal("synthetic-1", block.Block.ChainID)
+	suite.Require().Len(block.Block.Data.Txs, 150)
+
+	// The transactions are served in pages and match the block
+	txs, err := GetTxsByBlockHeight(ctx, cl, 5)
+	suite.Require().NoError(err)
+	suite.Require().Len(txs.Txs, 150)
+	for i, txResp := range txs.TxResponses {
+		suite.Require().Equal(strings.ToUpper(hex.EncodeToString(block.Block.Data.Txs[i].Hash())), txResp.TxHash)
+		suite.Require().Len(txResp.Logs, 2)
+		suite.Require().Equal(block.Block.Time.Format(time.RFC3339), txResp.Timestamp)
+	}
+	msg, ok := txs.Txs[0].Body.Messages[1].GetCachedValue().(*bankTypes.MsgSend)
+	suite.Require().True(ok)
+	suite.Require().Equal(msg.FromAddress, txs.Txs[0].GetMsgs()[0].GetSigners()[0].String())
+
+	blockResults, err := GetBlockResult(ctx, cl, 5)
+	suite.Require().NoError(err)
+	suite.Require().Len(blockResults.TxsResults, 150)
+	suite.Require().Len(blockResults.BeginBlockEvents, 9)
+	suite.Require().Len(blockResults.EndBlockEvents, 9)
+	key, err := base64.StdEncoding.DecodeString(blockResults.BeginBlockEvents[0].Attributes[0].Key)
+	suite.Require().NoError(err)
+	suite.Require().Equal("spender", string(key))
+
+	// Heights are generated the same way on every request
+	again, err := GetTxsByBlockHeight(ctx, cl, 5)
+	suite.Require().NoError(err)
+	suite.Require().Equal(txs.TxResponses, again.TxResponses)
+
+	_, err = GetBlock(ctx, cl, 11)
+	suite.Require().Error(err)
+}
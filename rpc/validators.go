@@ -0,0 +1,92 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	cmttypes "github.com/cometbft/cometbft/types"
+)
+
+// ErrValidatorsUnsupported is returned by GetValidators for clients that can't fetch validator sets
+var ErrValidatorsUnsupported = errors.New("the client does not fetch validator sets")
+
+// validatorsPageSize is the largest page the validators endpoint of the RPC server returns
+const validatorsPageSize = 100
+
+// ValidatorsClient is implemented by the clients that fetch the validator set of a height, which attributes the absent
+// signatures of a block commit to their validators. The fakes and fixtures of the Client interface don't need to
+// serve them.
+type ValidatorsClient interface {
+	// Validators returns the validator set of the height in the order of the commit signatures
+	Validators(ctx context.Context, height int64) ([]*cmttypes.Validator, error)
+}
+
+// GetValidators fetches the validator set of the height when the client supports it
+func GetValidators(ctx context.Context, cl Client, height int64) ([]*cmttypes.Validator, error) {
+	validatorsClient, ok := cl.(ValidatorsClient)
+	if !ok {
+		return nil, ErrValidatorsUnsupported
+	}
+	return validatorsClient.Validators(ctx, height)
+}
+
+func (c *ChainClient) Validators(ctx context.Context, height int64) ([]*cmttypes.Validator, error) {
+	ctx, cancel := c.queryContext(ctx, height)
+	defer cancel()
+
+	var validators []*cmttypes.Validator
+	page, perPage := 1, validatorsPageSize
+	for {
+		resp, err := c.cl.RPCClient.Validators(ctx, &height, &page, &perPage)
+		if err != nil {
+			return nil, err
+		}
+		validators = append(validators, resp.Validators...)
+		if len(resp.Validators) == 0 || len(validators) >= resp.Total {
+			return validators, nil
+		}
+		page++
+	}
+}
+
+func (p *EndpointPool) Validators(ctx context.Context, height int64) ([]*cmttypes.Validator, error) {
+	return poolRequest(ctx, p, height, func(client Client) ([]*cmttypes.Validator, error) {
+		return GetValidators(ctx, client, height)
+	})
+}
+
+func (c *EraClient) Validators(ctx context.Context, height int64) ([]*cmttypes.Validator, error) {
+	return GetValidators(ctx, c.client(height), height)
+}
+
+func (c *LegacyClient) Validators(ctx context.Context, height int64) ([]*cmttypes.Validator, error) {
+	return GetValidators(ctx, c.Client, height)
+}
+
+// Validators are not recorded, a FixtureClient does not serve them
+func (c *RecordingClient) Validators(ctx context.Context, height int64) ([]*cmttypes.Validator, error) {
+	return GetValidators(ctx, c.Client, height)
+}
+
+// Validators of the wrapped client are not compared, they only attribute the absent commit signatures
+func (c *ConsistencyClient) Validators(ctx context.Context, height int64) ([]*cmttypes.Validator, error) {
+	return GetValidators(ctx, c.Client, height)
+}
+
+func (c *ChaosClient) Validators(ctx context.Context, height int64) ([]*cmttypes.Validator, error) {
+	return GetValidators(ctx, c.Client, height)
+}
+
+// Validators returns the validator set of the header the light client verified at the height, it does not query the
+// wrapped client
+func (c *VerifyingClient) Validators(ctx context.Context, height int64) ([]*cmttypes.Validator, error) {
+	c.lightMu.Lock()
+	header, err := c.light.VerifyLightBlockAtHeight(ctx, height, time.Now())
+	c.lightMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the header of block %d: %w", height, err)
+	}
+	return header.ValidatorSet.Validators, nil
+}
@@ -0,0 +1,140 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	dbm "github.com/cometbft/cometbft-db"
+	"github.com/cometbft/cometbft/light"
+	lightdb "github.com/cometbft/cometbft/light/store/db"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	txTypes "github.com/cosmos/cosmos-sdk/types/tx"
+)
+
+// ErrUnverified is wrapped by the errors of responses that do not match the headers verified by the light client
+var ErrUnverified = errors.New("response does not match the verified header")
+
+// maxVerifiedBlocks is the number of recently verified blocks whose transactions are kept to check the transaction
+// searches of their heights against
+const maxVerifiedBlocks = 256
+
+// VerifyOptions set up the light client of a VerifyingClient. Headers are verified from the trusted header at
+// TrustedHeight with TrustedHash, which must be younger than TrustingPeriod. They are fetched from the Primary node
+// and cross-checked with the Witnesses.
+type VerifyOptions struct {
+	ChainID        string
+	Primary        string
+	Witnesses      []string
+	TrustedHeight  int64
+	TrustedHash    []byte
+	TrustingPeriod time.Duration
+}
+
+// VerifyingClient checks the blocks of a Client against the headers a CometBFT light client verified from a trusted
+// root, so the data of an untrusted node is not indexed unless it matches the chain. A block must hash to its verified
+// header and its transactions, evidence and last commit must match the hashes of the header. The transactions found
+// by a transaction search must be transactions of the verified block. Block results are not covered, the headers only
+// commit to parts of them.
+type VerifyingClient struct {
+	Client
+	light *light.Client
+	// lightMu serializes the verifications, which update the trusted store of the light client
+	lightMu sync.Mutex
+
+	mu sync.Mutex
+	// txs are the transaction hashes of the recently verified blocks by height
+	txs map[int64]map[string]struct{}
+}
+
+// NewVerifyingClient verifies the trusted header with the primary node and the witnesses
+func NewVerifyingClient(ctx context.Context, cl Client, opts VerifyOptions) (*VerifyingClient, error) {
+	trustOptions := light.TrustOptions{Period: opts.TrustingPeriod, Height: opts.TrustedHeight, Hash: opts.TrustedHash}
+	lightClient, err := light.NewHTTPClient(ctx, opts.ChainID, trustOptions, opts.Primary, opts.Witnesses, lightdb.New(dbm.NewMemDB(), opts.ChainID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up the light client: %w", err)
+	}
+
+	return &VerifyingClient{Client: cl, light: lightClient, txs: map[int64]map[string]struct{}{}}, nil
+}
+
+func (c *VerifyingClient) Block(ctx context.Context, height int64) (*coretypes.ResultBlock, error) {
+	block, err := c.Client.Block(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil || block.Block == nil {
+		return nil, fmt.Errorf("%w: the node returned no block %d", ErrUnverified, height)
+	}
+
+	c.lightMu.Lock()
+	header, err := c.light.VerifyLightBlockAtHeight(ctx, height, time.Now())
+	c.lightMu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify the header of block %d: %w", height, err)
+	}
+
+	if hash := block.Block.Hash(); !bytes.Equal(hash, header.Hash()) {
+		return nil, fmt.Errorf("%w: block %d hashes to %X, the verified header to %X", ErrUnverified, height, hash, header.Hash())
+	}
+	// The header hash covers the hashes of the transactions, evidence and last commit, not the data itself
+	if err := block.Block.ValidateBasic(); err != nil {
+		return nil, fmt.Errorf("%w: block %d: %v", ErrUnverified, height, err)
+	}
+
+	c.verified(height, block)
+	return block, nil
+}
+
+// TxsEvent returns the transaction search of the node when all the transactions found are in the verified block of
+// the height, which is fetched and verified first unless it was verified recently
+func (c *VerifyingClient) TxsEvent(ctx context.Context, height int64, req *txTypes.GetTxsEventRequest) (*txTypes.GetTxsEventResponse, error) {
+	resp, err := c.Client.TxsEvent(ctx, height, req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	txs, ok := c.txs[height]
+	c.mu.Unlock()
+	if !ok {
+		if _, err := c.Block(ctx, height); err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		txs = c.txs[height]
+		c.mu.Unlock()
+	}
+
+	for _, txResp := range resp.TxResponses {
+		if _, ok := txs[txResp.TxHash]; !ok || txResp.Height != height {
+			return nil, fmt.Errorf("%w: transaction %s is not in block %d", ErrUnverified, txResp.TxHash, height)
+		}
+	}
+	return resp, nil
+}
+
+// verified keeps the transaction hashes of the verified block, dropping the lowest height kept once there are too many
+func (c *VerifyingClient) verified(height int64, block *coretypes.ResultBlock) {
+	hashes := make(map[string]struct{}, len(block.Block.Txs))
+	for _, tx := range block.Block.Txs {
+		hashes[fmt.Sprintf("%X", tx.Hash())] = struct{}{}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.txs[height]; !ok && len(c.txs) >= maxVerifiedBlocks {
+		lowest := int64(-1)
+		for kept := range c.txs {
+			if lowest < 0 || kept < lowest {
+				lowest = kept
+			}
+		}
+		delete(c.txs, lowest)
+	}
+	c.txs[height] = hashes
+}
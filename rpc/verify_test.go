@@ -0,0 +1,33 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	cmttypes "github.com/cometbft/cometbft/types"
+)
+
+func (suite *RequestsTestSuite) TestVerifyingClientTxs() {
+	tx := cmttypes.Tx("verified")
+	block := &coretypes.ResultBlock{Block: &cmttypes.Block{Header: cmttypes.Header{Height: 10}, Data: cmttypes.Data{Txs: cmttypes.Txs{tx}}}}
+	node := &fakeClient{block: block, txs: []string{fmt.Sprintf("%X", tx.Hash())}, pageSize: 100}
+	client := &VerifyingClient{Client: node, txs: map[int64]map[string]struct{}{}}
+	client.verified(10, block)
+
+	resp, err := client.TxsEvent(context.Background(), 10, txsRequest(10, 100))
+	suite.Require().NoError(err)
+	suite.Require().Len(resp.TxResponses, 1)
+
+	// A transaction the verified block does not hold fails the search
+	node.txs = append(node.txs, "ABCD")
+	_, err = client.TxsEvent(context.Background(), 10, txsRequest(10, 100))
+	suite.Require().ErrorIs(err, ErrUnverified)
+
+	// Only the most recent blocks are kept
+	for height := int64(11); height < 11+maxVerifiedBlocks; height++ {
+		client.verified(height, block)
+	}
+	suite.Require().Len(client.txs, maxVerifiedBlocks)
+	suite.Require().NotContains(client.txs, int64(10))
+}
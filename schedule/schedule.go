@@ -0,0 +1,71 @@
+// Package schedule confines indexing to windows opened by a cron schedule, e.g. to keep backfills off an archive node
+// during peak hours.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Window opens at every activation of a cron schedule and stays open for a duration
+type Window struct {
+	schedule cron.Schedule
+	duration time.Duration
+}
+
+// Parse parses a standard 5 field cron spec or descriptor such as @daily, evaluated in the timezone
+func Parse(spec string, duration time.Duration, timezone string) (*Window, error) {
+	if duration <= 0 {
+		return nil, errors.New("the window duration must be positive")
+	}
+
+	schedule, err := ParseCron(spec, timezone)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Window{schedule: schedule, duration: duration}, nil
+}
+
+// ParseCron parses a standard 5 field cron spec or descriptor such as @daily, evaluated in the timezone
+func ParseCron(spec string, timezone string) (cron.Schedule, error) {
+	location, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron spec %q: %w", spec, err)
+	}
+	if specSchedule, ok := schedule.(*cron.SpecSchedule); ok {
+		specSchedule.Location = location
+	}
+
+	return schedule, nil
+}
+
+// maxLookahead bounds how far overlapping windows are merged, schedules that never close are checked again then
+const maxLookahead = 7 * 24 * time.Hour
+
+// At returns whether the window is open at t and when that changes next. Windows overlapping the next activation
+// stay open until the last of them closes. The zero time is returned as next for schedules that never activate again.
+func (w *Window) At(t time.Time) (open bool, next time.Time) {
+	start := w.schedule.Next(t.Add(-w.duration))
+	if start.IsZero() || start.After(t) {
+		return false, start
+	}
+
+	end := start.Add(w.duration)
+	for end.Sub(t) < maxLookahead {
+		following := w.schedule.Next(start)
+		if following.IsZero() || following.After(end) {
+			break
+		}
+		start, end = following, following.Add(w.duration)
+	}
+	return true, end
+}
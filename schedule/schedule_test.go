@@ -0,0 +1,68 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type ScheduleTestSuite struct {
+	suite.Suite
+}
+
+func (suite *ScheduleTestSuite) TestAt() {
+	window, err := Parse("0 0 * * *", 6*time.Hour, "UTC")
+	suite.Require().NoError(err)
+
+	open, next := window.At(time.Date(2023, 5, 1, 3, 0, 0, 0, time.UTC))
+	suite.Require().True(open)
+	suite.Require().Equal(time.Date(2023, 5, 1, 6, 0, 0, 0, time.UTC), next.UTC())
+
+	open, next = window.At(time.Date(2023, 5, 1, 0, 0, 0, 0, time.UTC))
+	suite.Require().True(open)
+	suite.Require().Equal(time.Date(2023, 5, 1, 6, 0, 0, 0, time.UTC), next.UTC())
+
+	open, next = window.At(time.Date(2023, 5, 1, 6, 0, 0, 0, time.UTC))
+	suite.Require().False(open)
+	suite.Require().Equal(time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC), next.UTC())
+
+	open, next = window.At(time.Date(2023, 5, 1, 22, 30, 0, 0, time.UTC))
+	suite.Require().False(open)
+	suite.Require().Equal(time.Date(2023, 5, 2, 0, 0, 0, 0, time.UTC), next.UTC())
+}
+
+func (suite *ScheduleTestSuite) TestTimezone() {
+	window, err := Parse("0 1 * * *", time.Hour, "Europe/Berlin")
+	suite.Require().NoError(err)
+
+	// 01:00 in Berlin is 00:00 UTC in winter
+	open, _ := window.At(time.Date(2023, 1, 11, 0, 30, 0, 0, time.UTC))
+	suite.Require().True(open)
+	open, _ = window.At(time.Date(2023, 1, 11, 1, 30, 0, 0, time.UTC))
+	suite.Require().False(open)
+}
+
+func (suite *ScheduleTestSuite) TestOverlappingWindows() {
+	window, err := Parse("0 * * * *", 90*time.Minute, "UTC")
+	suite.Require().NoError(err)
+
+	open, next := window.At(time.Date(2023, 5, 1, 3, 0, 0, 0, time.UTC))
+	suite.Require().True(open)
+	suite.Require().True(next.Sub(time.Date(2023, 5, 1, 3, 0, 0, 0, time.UTC)) >= maxLookahead)
+}
+
+func (suite *ScheduleTestSuite) TestParse() {
+	_, err := Parse("not a spec", time.Hour, "UTC")
+	suite.Require().Error(err)
+
+	_, err = Parse("@daily", 0, "UTC")
+	suite.Require().Error(err)
+
+	_, err = Parse("@daily", time.Hour, "Mars/Olympus")
+	suite.Require().Error(err)
+}
+
+func TestSchedule(t *testing.T) {
+	suite.Run(t, new(ScheduleTestSuite))
+}
@@ -0,0 +1,190 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+type AMQPConfig struct {
+	URL             string
+	Exchange        string
+	ExchangeType    string
+	DeclareExchange bool
+	// Per record type exchange and routing key overrides. Routing keys may contain the {chain_id} placeholder.
+	Exchanges   map[RecordType]string
+	RoutingKeys map[RecordType]string
+	Encoding    string
+	Confirms    bool
+}
+
+// AMQPSink publishes records to RabbitMQ (or any AMQP 0.9.1 broker). When publisher confirms are enabled,
+// Write only returns once the broker has acknowledged every record of the batch.
+type AMQPSink struct {
+	conf   AMQPConfig
+	encode Encoder
+
+	mu      sync.Mutex
+	conn    *amqp.Connection
+	channel *amqp.Channel
+}
+
+func NewAMQPSink(conf AMQPConfig) (*AMQPSink, error) {
+	encode, err := NewEncoder(conf.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	sink := &AMQPSink{conf: conf, encode: encode}
+	if err := sink.connect(); err != nil {
+		return nil, err
+	}
+
+	return sink, nil
+}
+
+func (s *AMQPSink) Name() string {
+	return "amqp"
+}
+
+func (s *AMQPSink) connect() error {
+	conn, err := amqp.Dial(s.conf.URL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to AMQP broker: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to open AMQP channel: %w", err)
+	}
+
+	if s.conf.Confirms {
+		if err := channel.Confirm(false); err != nil {
+			conn.Close()
+			return fmt.Errorf("failed to enable AMQP publisher confirms: %w", err)
+		}
+	}
+
+	if s.conf.DeclareExchange {
+		for _, exchange := range s.exchangeNames() {
+			if err := channel.ExchangeDeclare(exchange, s.conf.ExchangeType, true, false, false, false, nil); err != nil {
+				conn.Close()
+				return fmt.Errorf("failed to declare AMQP exchange %s: %w", exchange, err)
+			}
+		}
+	}
+
+	s.conn = conn
+	s.channel = channel
+	return nil
+}
+
+func (s *AMQPSink) exchangeNames() []string {
+	unique := make(map[string]struct{})
+	var names []string
+	for _, recordType := range []RecordType{RecordTypeBlock, RecordTypeTx, RecordTypeBlockEvent} {
+		name := s.exchange(recordType)
+		// The default exchange cannot be declared
+		if _, ok := unique[name]; ok || name == "" {
+			continue
+		}
+		unique[name] = struct{}{}
+		names = append(names, name)
+	}
+	return names
+}
+
+func (s *AMQPSink) exchange(recordType RecordType) string {
+	if exchange, ok := s.conf.Exchanges[recordType]; ok && exchange != "" {
+		return exchange
+	}
+	return s.conf.Exchange
+}
+
+func (s *AMQPSink) routingKey(record Record) string {
+	key, ok := s.conf.RoutingKeys[record.Type]
+	if !ok || key == "" {
+		key = fmt.Sprintf("{chain_id}.%s", record.Type)
+	}
+	return strings.ReplaceAll(key, "{chain_id}", record.ChainID)
+}
+
+func (s *AMQPSink) publishing(record Record) (amqp.Publishing, error) {
+	payload, err := s.encode(record)
+	if err != nil {
+		return amqp.Publishing{}, err
+	}
+
+	return amqp.Publishing{
+		ContentType:     "application/json",
+		ContentEncoding: contentEncoding(s.conf.Encoding),
+		DeliveryMode:    amqp.Persistent,
+		MessageId:       record.Key,
+		Timestamp:       record.Time,
+		Type:            string(record.Type),
+		Headers: amqp.Table{
+			"chain_id": record.ChainID,
+			"height":   record.Height,
+		},
+		Body: payload,
+	}, nil
+}
+
+func (s *AMQPSink) Write(ctx context.Context, records []Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Reconnect if the broker closed the channel since the last write
+	if s.channel == nil || s.channel.IsClosed() {
+		if s.conn != nil {
+			s.conn.Close()
+		}
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+
+	confirmations := make([]*amqp.DeferredConfirmation, 0, len(records))
+	for _, record := range records {
+		publishing, err := s.publishing(record)
+		if err != nil {
+			return err
+		}
+
+		confirmation, err := s.channel.PublishWithDeferredConfirmWithContext(ctx, s.exchange(record.Type), s.routingKey(record), false, false, publishing)
+		if err != nil {
+			return err
+		}
+
+		if confirmation != nil {
+			confirmations = append(confirmations, confirmation)
+		}
+	}
+
+	for _, confirmation := range confirmations {
+		acked, err := confirmation.WaitContext(ctx)
+		if err != nil {
+			return err
+		}
+		if !acked {
+			return errors.New("AMQP broker rejected a published record")
+		}
+	}
+
+	return nil
+}
+
+func (s *AMQPSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
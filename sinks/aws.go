@@ -0,0 +1,325 @@
+package sinks
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+const (
+	// SNS and SQS accept at most 10 entries and 256 KiB of payload per batch request
+	awsMaxBatchSize  = 10
+	awsMaxBatchBytes = 256 * 1024
+)
+
+// AWSMessageAttributes are the record fields that can be attached as SNS/SQS message attributes for subscription filter policies
+var AWSMessageAttributes = []string{"chain_id", "height", "type", "key"}
+
+type AWSConfig struct {
+	Region string
+	// Endpoint overrides the AWS endpoint for both services, e.g. LocalStack
+	Endpoint string
+	TopicARN string
+	QueueURL string
+	// Failed block records go to the failure destinations when set, otherwise to the regular ones
+	FailureTopicARN   string
+	FailureQueueURL   string
+	MessageAttributes []string
+	Encoding          string
+}
+
+// AWSSink publishes records to an SNS topic and/or an SQS queue. FIFO topics and queues (names ending in .fifo)
+// use the chain ID as message group, so records of a chain are delivered in order.
+type AWSSink struct {
+	conf   AWSConfig
+	sns    *sns.SNS
+	sqs    *sqs.SQS
+	encode Encoder
+}
+
+type awsMessage struct {
+	id         string
+	body       string
+	attributes map[string]string
+	groupID    string
+	dedupID    string
+}
+
+func NewAWSSink(ctx context.Context, conf AWSConfig) (*AWSSink, error) {
+	encode, err := NewEncoder(conf.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attribute := range conf.MessageAttributes {
+		if !isAWSMessageAttribute(attribute) {
+			return nil, fmt.Errorf("unsupported AWS message attribute %q, expected one of %s", attribute, strings.Join(AWSMessageAttributes, ", "))
+		}
+	}
+
+	awsConfig := aws.NewConfig()
+	if conf.Region != "" {
+		awsConfig = awsConfig.WithRegion(conf.Region)
+	}
+	if conf.Endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(conf.Endpoint)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsConfig,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	sink := &AWSSink{conf: conf, encode: encode, sns: sns.New(sess), sqs: sqs.New(sess)}
+
+	// Fail on startup if a destination does not exist or the credentials cannot publish to it
+	for _, topic := range []string{conf.TopicARN, conf.FailureTopicARN} {
+		if topic == "" {
+			continue
+		}
+		if _, err := sink.sns.GetTopicAttributesWithContext(ctx, &sns.GetTopicAttributesInput{TopicArn: aws.String(topic)}); err != nil {
+			return nil, fmt.Errorf("failed to get SNS topic %s: %w", topic, err)
+		}
+	}
+
+	for _, queue := range []string{conf.QueueURL, conf.FailureQueueURL} {
+		if queue == "" {
+			continue
+		}
+		if _, err := sink.sqs.GetQueueAttributesWithContext(ctx, &sqs.GetQueueAttributesInput{QueueUrl: aws.String(queue)}); err != nil {
+			return nil, fmt.Errorf("failed to get SQS queue %s: %w", queue, err)
+		}
+	}
+
+	return sink, nil
+}
+
+func isAWSMessageAttribute(attribute string) bool {
+	for _, supported := range AWSMessageAttributes {
+		if attribute == supported {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *AWSSink) Name() string {
+	return "aws"
+}
+
+func (s *AWSSink) destinations(recordType RecordType) (topic string, queue string) {
+	if recordType == RecordTypeFailedBlock {
+		topic, queue = s.conf.FailureTopicARN, s.conf.FailureQueueURL
+		if topic == "" && queue == "" {
+			return s.conf.TopicARN, s.conf.QueueURL
+		}
+		return topic, queue
+	}
+	return s.conf.TopicARN, s.conf.QueueURL
+}
+
+func (s *AWSSink) Write(ctx context.Context, records []Record) error {
+	topicMessages := map[string][]awsMessage{}
+	queueMessages := map[string][]awsMessage{}
+	var topics, queues []string
+
+	for _, record := range records {
+		topic, queue := s.destinations(record.Type)
+		if topic == "" && queue == "" {
+			continue
+		}
+
+		message, err := s.newMessage(record)
+		if err != nil {
+			return err
+		}
+
+		if topic != "" {
+			if _, ok := topicMessages[topic]; !ok {
+				topics = append(topics, topic)
+			}
+			topicMessages[topic] = append(topicMessages[topic], message)
+		}
+		if queue != "" {
+			if _, ok := queueMessages[queue]; !ok {
+				queues = append(queues, queue)
+			}
+			queueMessages[queue] = append(queueMessages[queue], message)
+		}
+	}
+
+	for _, topic := range topics {
+		for _, batch := range awsBatches(topicMessages[topic]) {
+			if err := s.publishBatch(ctx, topic, batch); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, queue := range queues {
+		for _, batch := range awsBatches(queueMessages[queue]) {
+			if err := s.sendBatch(ctx, queue, batch); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *AWSSink) newMessage(record Record) (awsMessage, error) {
+	payload, err := s.encode(record)
+	if err != nil {
+		return awsMessage{}, err
+	}
+
+	message := awsMessage{
+		body:       string(payload),
+		attributes: map[string]string{},
+		groupID:    record.ChainID,
+	}
+
+	// SNS and SQS message bodies must be text
+	if encoding := contentEncoding(s.conf.Encoding); encoding != "" {
+		message.body = base64.StdEncoding.EncodeToString(payload)
+		message.attributes["content_encoding"] = encoding + "+base64"
+	}
+
+	for _, attribute := range s.conf.MessageAttributes {
+		switch attribute {
+		case "chain_id":
+			message.attributes[attribute] = record.ChainID
+		case "height":
+			message.attributes[attribute] = strconv.FormatInt(record.Height, 10)
+		case "type":
+			message.attributes[attribute] = string(record.Type)
+		case "key":
+			message.attributes[attribute] = record.Key
+		}
+	}
+
+	// Deduplication IDs are limited to 128 characters, so the record identity is hashed
+	dedupHash := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s", record.ChainID, record.Type, record.Key)))
+	message.dedupID = hex.EncodeToString(dedupHash[:])
+
+	return message, nil
+}
+
+// Splits the messages into batches within the SNS/SQS entry count and payload size limits, assigning entry IDs
+func awsBatches(messages []awsMessage) [][]awsMessage {
+	var batches [][]awsMessage
+	var batch []awsMessage
+	batchBytes := 0
+
+	for _, message := range messages {
+		size := len(message.body)
+		for name, value := range message.attributes {
+			size += len(name) + len(value)
+		}
+
+		if len(batch) == awsMaxBatchSize || (len(batch) != 0 && batchBytes+size > awsMaxBatchBytes) {
+			batches = append(batches, batch)
+			batch = nil
+			batchBytes = 0
+		}
+
+		message.id = strconv.Itoa(len(batch))
+		batch = append(batch, message)
+		batchBytes += size
+	}
+
+	if len(batch) != 0 {
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
+
+func awsAttributeDataType(name string) string {
+	if name == "height" {
+		return "Number"
+	}
+	return "String"
+}
+
+func (s *AWSSink) publishBatch(ctx context.Context, topic string, batch []awsMessage) error {
+	fifo := strings.HasSuffix(topic, ".fifo")
+	entries := make([]*sns.PublishBatchRequestEntry, 0, len(batch))
+
+	for _, message := range batch {
+		entry := &sns.PublishBatchRequestEntry{
+			Id:                aws.String(message.id),
+			Message:           aws.String(message.body),
+			MessageAttributes: map[string]*sns.MessageAttributeValue{},
+		}
+		for name, value := range message.attributes {
+			entry.MessageAttributes[name] = &sns.MessageAttributeValue{DataType: aws.String(awsAttributeDataType(name)), StringValue: aws.String(value)}
+		}
+		if fifo {
+			entry.MessageGroupId = aws.String(message.groupID)
+			entry.MessageDeduplicationId = aws.String(message.dedupID)
+		}
+		entries = append(entries, entry)
+	}
+
+	output, err := s.sns.PublishBatchWithContext(ctx, &sns.PublishBatchInput{TopicArn: aws.String(topic), PublishBatchRequestEntries: entries})
+	if err != nil {
+		return fmt.Errorf("failed to publish to SNS topic %s: %w", topic, err)
+	}
+
+	if len(output.Failed) != 0 {
+		failed := output.Failed[0]
+		return fmt.Errorf("SNS topic %s rejected %d of %d messages: %s: %s", topic, len(output.Failed), len(batch), aws.StringValue(failed.Code), aws.StringValue(failed.Message))
+	}
+
+	return nil
+}
+
+func (s *AWSSink) sendBatch(ctx context.Context, queue string, batch []awsMessage) error {
+	fifo := strings.HasSuffix(queue, ".fifo")
+	entries := make([]*sqs.SendMessageBatchRequestEntry, 0, len(batch))
+
+	for _, message := range batch {
+		entry := &sqs.SendMessageBatchRequestEntry{
+			Id:                aws.String(message.id),
+			MessageBody:       aws.String(message.body),
+			MessageAttributes: map[string]*sqs.MessageAttributeValue{},
+		}
+		for name, value := range message.attributes {
+			entry.MessageAttributes[name] = &sqs.MessageAttributeValue{DataType: aws.String(awsAttributeDataType(name)), StringValue: aws.String(value)}
+		}
+		if fifo {
+			entry.MessageGroupId = aws.String(message.groupID)
+			entry.MessageDeduplicationId = aws.String(message.dedupID)
+		}
+		entries = append(entries, entry)
+	}
+
+	output, err := s.sqs.SendMessageBatchWithContext(ctx, &sqs.SendMessageBatchInput{QueueUrl: aws.String(queue), Entries: entries})
+	if err != nil {
+		return fmt.Errorf("failed to send to SQS queue %s: %w", queue, err)
+	}
+
+	if len(output.Failed) != 0 {
+		failed := output.Failed[0]
+		return fmt.Errorf("SQS queue %s rejected %d of %d messages: %s: %s", queue, len(output.Failed), len(batch), aws.StringValue(failed.Code), aws.StringValue(failed.Message))
+	}
+
+	return nil
+}
+
+func (s *AWSSink) Close() error {
+	return nil
+}
@@ -0,0 +1,425 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	bigQueryScope           = "https://www.googleapis.com/auth/bigquery"
+	bigQueryDefaultEndpoint = "https://bigquery.googleapis.com"
+	// Google recommends at most 500 rows per streaming insert request
+	bigQueryMaxInsertRows = 500
+
+	BigQueryModeStreaming = "streaming"
+	BigQueryModeBatch     = "batch"
+)
+
+type BigQueryConfig struct {
+	ProjectID string
+	Dataset   string
+	// Location is used when the dataset has to be created
+	Location        string
+	TablePrefix     string
+	CredentialsFile string
+	// Endpoint overrides the BigQuery API endpoint, e.g. a local emulator (which requires no credentials)
+	Endpoint string
+	// Mode is either streaming, which inserts the rows of every write, or batch, which buffers rows and loads them with
+	// load jobs. Load jobs are free of charge but buffered rows are lost if the indexer is killed.
+	Mode          string
+	BatchSize     int
+	BatchInterval time.Duration
+}
+
+// BigQuerySink writes records to one BigQuery table per record type. The dataset and tables are created on startup
+// with day partitions on the block time, and columns missing from existing tables are added.
+type BigQuerySink struct {
+	conf     BigQueryConfig
+	client   *http.Client
+	endpoint string
+	// batch mode buffers newline delimited JSON rows per table
+	buffered      map[string]*bytes.Buffer
+	bufferedRows  int
+	bufferedSince time.Time
+}
+
+func NewBigQuerySink(ctx context.Context, conf BigQueryConfig) (*BigQuerySink, error) {
+	if conf.Mode != BigQueryModeStreaming && conf.Mode != BigQueryModeBatch {
+		return nil, fmt.Errorf("unsupported BigQuery mode %q, expected %s or %s", conf.Mode, BigQueryModeStreaming, BigQueryModeBatch)
+	}
+
+	sink := &BigQuerySink{conf: conf, endpoint: strings.TrimSuffix(conf.Endpoint, "/"), buffered: map[string]*bytes.Buffer{}}
+	if sink.endpoint == "" {
+		sink.endpoint = bigQueryDefaultEndpoint
+	}
+
+	if strings.HasPrefix(sink.endpoint, "http://") {
+		// Plain HTTP endpoints are local emulators, which do not authenticate requests
+		sink.client = &http.Client{Timeout: 60 * time.Second}
+	} else {
+		var err error
+		sink.client, err = newGoogleHTTPClient(ctx, conf.CredentialsFile, bigQueryScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create BigQuery credentials: %w", err)
+		}
+	}
+
+	if err := sink.ensureDataset(ctx); err != nil {
+		return nil, err
+	}
+
+	for _, recordType := range []RecordType{RecordTypeBlock, RecordTypeTx, RecordTypeBlockEvent, RecordTypeFailedBlock} {
+		if err := sink.ensureTable(ctx, recordType); err != nil {
+			return nil, err
+		}
+	}
+
+	return sink, nil
+}
+
+func (s *BigQuerySink) Name() string {
+	return "bigquery"
+}
+
+func (s *BigQuerySink) datasetURL() string {
+	return fmt.Sprintf("%s/bigquery/v2/projects/%s/datasets/%s", s.endpoint, url.PathEscape(s.conf.ProjectID), url.PathEscape(s.conf.Dataset))
+}
+
+func (s *BigQuerySink) tableName(recordType RecordType) string {
+	switch recordType {
+	case RecordTypeBlock:
+		return s.conf.TablePrefix + "blocks"
+	case RecordTypeTx:
+		return s.conf.TablePrefix + "txs"
+	case RecordTypeBlockEvent:
+		return s.conf.TablePrefix + "block_events"
+	default:
+		return s.conf.TablePrefix + string(recordType) + "s"
+	}
+}
+
+func (s *BigQuerySink) ensureDataset(ctx context.Context) error {
+	err := doGoogleJSONRequest(ctx, s.client, http.MethodGet, s.datasetURL(), nil, nil)
+	if !isGoogleNotFound(err) {
+		return err
+	}
+
+	dataset := map[string]any{
+		"datasetReference": map[string]string{"projectId": s.conf.ProjectID, "datasetId": s.conf.Dataset},
+	}
+	if s.conf.Location != "" {
+		dataset["location"] = s.conf.Location
+	}
+
+	if err := doGoogleJSONRequest(ctx, s.client, http.MethodPost, fmt.Sprintf("%s/bigquery/v2/projects/%s/datasets", s.endpoint, url.PathEscape(s.conf.ProjectID)), dataset, nil); err != nil {
+		return fmt.Errorf("failed to create BigQuery dataset %s: %w", s.conf.Dataset, err)
+	}
+
+	return nil
+}
+
+// Creates the table of the record type, or adds the columns it is missing
+func (s *BigQuerySink) ensureTable(ctx context.Context, recordType RecordType) error {
+	name := s.tableName(recordType)
+	tableURL := fmt.Sprintf("%s/tables/%s", s.datasetURL(), url.PathEscape(name))
+	schema := bigQuerySchema(recordType)
+
+	var table struct {
+		Schema struct {
+			Fields []bigQueryField `json:"fields"`
+		} `json:"schema"`
+	}
+
+	err := doGoogleJSONRequest(ctx, s.client, http.MethodGet, tableURL, nil, &table)
+	if isGoogleNotFound(err) {
+		newTable := map[string]any{
+			"tableReference":   map[string]string{"projectId": s.conf.ProjectID, "datasetId": s.conf.Dataset, "tableId": name},
+			"schema":           map[string]any{"fields": schema},
+			"timePartitioning": map[string]string{"type": "DAY", "field": "block_time"},
+			"clustering":       map[string][]string{"fields": {"chain_id", "height"}},
+		}
+		if err := doGoogleJSONRequest(ctx, s.client, http.MethodPost, s.datasetURL()+"/tables", newTable, nil); err != nil {
+			return fmt.Errorf("failed to create BigQuery table %s: %w", name, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get BigQuery table %s: %w", name, err)
+	}
+
+	merged, changed := mergeBigQuerySchema(table.Schema.Fields, schema)
+	if !changed {
+		return nil
+	}
+
+	if err := doGoogleJSONRequest(ctx, s.client, http.MethodPatch, tableURL, map[string]any{"schema": map[string]any{"fields": merged}}, nil); err != nil {
+		return fmt.Errorf("failed to update the schema of BigQuery table %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// bigQueryRow flattens a record into a table row, the record data fields become top level columns
+func bigQueryRow(record Record) (map[string]any, error) {
+	row := map[string]any{}
+
+	data, err := json.Marshal(record.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&row); err != nil {
+		return nil, err
+	}
+
+	row["chain_id"] = record.ChainID
+	row["height"] = record.Height
+	row["key"] = record.Key
+	if !record.Time.IsZero() {
+		row["block_time"] = record.Time.UTC().Format(time.RFC3339Nano)
+	}
+
+	return row, nil
+}
+
+func (s *BigQuerySink) Write(ctx context.Context, records []Record) error {
+	if s.conf.Mode == BigQueryModeBatch {
+		return s.buffer(ctx, records)
+	}
+
+	rowsByTable := map[string][]map[string]any{}
+	var tables []string
+
+	for _, record := range records {
+		// Only the record types with a table are written, e.g. derived records are skipped
+		if _, ok := bigQueryDataFields[record.Type]; !ok {
+			continue
+		}
+
+		row, err := bigQueryRow(record)
+		if err != nil {
+			return err
+		}
+
+		table := s.tableName(record.Type)
+		if _, ok := rowsByTable[table]; !ok {
+			tables = append(tables, table)
+		}
+		// The insert ID lets BigQuery drop rows retried within a minute
+		rowsByTable[table] = append(rowsByTable[table], map[string]any{
+			"insertId": fmt.Sprintf("%s/%s/%s", record.ChainID, record.Type, record.Key),
+			"json":     row,
+		})
+	}
+
+	for _, table := range tables {
+		rows := rowsByTable[table]
+		for start := 0; start < len(rows); start += bigQueryMaxInsertRows {
+			end := start + bigQueryMaxInsertRows
+			if end > len(rows) {
+				end = len(rows)
+			}
+			if err := s.insertAll(ctx, table, rows[start:end]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *BigQuerySink) insertAll(ctx context.Context, table string, rows []map[string]any) error {
+	var response struct {
+		InsertErrors []struct {
+			Index  int `json:"index"`
+			Errors []struct {
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"errors"`
+		} `json:"insertErrors"`
+	}
+
+	request := map[string]any{"rows": rows}
+	if err := doGoogleJSONRequest(ctx, s.client, http.MethodPost, fmt.Sprintf("%s/tables/%s/insertAll", s.datasetURL(), url.PathEscape(table)), request, &response); err != nil {
+		return err
+	}
+
+	if len(response.InsertErrors) != 0 {
+		insertError := response.InsertErrors[0]
+		message := "unknown error"
+		if len(insertError.Errors) != 0 {
+			message = fmt.Sprintf("%s: %s", insertError.Errors[0].Reason, insertError.Errors[0].Message)
+		}
+		return fmt.Errorf("BigQuery rejected %d rows of table %s, row %d: %s", len(response.InsertErrors), table, insertError.Index, message)
+	}
+
+	return nil
+}
+
+func (s *BigQuerySink) buffer(ctx context.Context, records []Record) error {
+	for _, record := range records {
+		// Only the record types with a table are written, e.g. derived records are skipped
+		if _, ok := bigQueryDataFields[record.Type]; !ok {
+			continue
+		}
+
+		row, err := bigQueryRow(record)
+		if err != nil {
+			return err
+		}
+
+		table := s.tableName(record.Type)
+		if s.buffered[table] == nil {
+			s.buffered[table] = &bytes.Buffer{}
+		}
+		if err := json.NewEncoder(s.buffered[table]).Encode(row); err != nil {
+			return err
+		}
+
+		if s.bufferedRows == 0 {
+			s.bufferedSince = time.Now()
+		}
+		s.bufferedRows++
+	}
+
+	if s.bufferedRows >= s.conf.BatchSize || (s.bufferedRows != 0 && time.Since(s.bufferedSince) >= s.conf.BatchInterval) {
+		return s.flush(ctx)
+	}
+
+	return nil
+}
+
+// flush loads the buffered rows of every table with a load job each
+func (s *BigQuerySink) flush(ctx context.Context) error {
+	for table, rows := range s.buffered {
+		if rows.Len() == 0 {
+			continue
+		}
+		if err := s.load(ctx, table, rows.Bytes()); err != nil {
+			return err
+		}
+		rows.Reset()
+	}
+
+	s.bufferedRows = 0
+	return nil
+}
+
+type bigQueryJob struct {
+	JobReference struct {
+		JobID    string `json:"jobId"`
+		Location string `json:"location"`
+	} `json:"jobReference"`
+	Status struct {
+		State       string `json:"state"`
+		ErrorResult *struct {
+			Reason  string `json:"reason"`
+			Message string `json:"message"`
+		} `json:"errorResult"`
+	} `json:"status"`
+}
+
+// load uploads newline delimited JSON rows with a multipart load job and waits for the job to finish
+func (s *BigQuerySink) load(ctx context.Context, table string, rows []byte) error {
+	configuration := map[string]any{
+		"configuration": map[string]any{
+			"load": map[string]any{
+				"destinationTable": map[string]string{"projectId": s.conf.ProjectID, "datasetId": s.conf.Dataset, "tableId": table},
+				"sourceFormat":     "NEWLINE_DELIMITED_JSON",
+				"writeDisposition": "WRITE_APPEND",
+			},
+		},
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	configurationPart, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=UTF-8"}})
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(configurationPart).Encode(configuration); err != nil {
+		return err
+	}
+
+	dataPart, err := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/octet-stream"}})
+	if err != nil {
+		return err
+	}
+	if _, err := dataPart.Write(rows); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	uploadURL := fmt.Sprintf("%s/upload/bigquery/v2/projects/%s/jobs?uploadType=multipart", s.endpoint, url.PathEscape(s.conf.ProjectID))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, &body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "multipart/related; boundary="+writer.Boundary())
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return &googleAPIError{Method: http.MethodPost, URL: uploadURL, Status: response.Status, StatusCode: response.StatusCode, Body: responseBody}
+	}
+
+	var job bigQueryJob
+	if err := json.Unmarshal(responseBody, &job); err != nil {
+		return err
+	}
+
+	for job.Status.State != "DONE" {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+
+		jobURL := fmt.Sprintf("%s/bigquery/v2/projects/%s/jobs/%s?location=%s", s.endpoint, url.PathEscape(s.conf.ProjectID), url.PathEscape(job.JobReference.JobID), url.QueryEscape(job.JobReference.Location))
+		if err := doGoogleJSONRequest(ctx, s.client, http.MethodGet, jobURL, nil, &job); err != nil {
+			return err
+		}
+	}
+
+	if job.Status.ErrorResult != nil {
+		return fmt.Errorf("BigQuery load job %s into %s failed: %s: %s", job.JobReference.JobID, table, job.Status.ErrorResult.Reason, job.Status.ErrorResult.Message)
+	}
+
+	return nil
+}
+
+// Close loads the rows still buffered in batch mode
+func (s *BigQuerySink) Close() error {
+	if s.bufferedRows == 0 {
+		return nil
+	}
+
+	if err := s.flush(context.Background()); err != nil {
+		return errors.New("failed to load buffered BigQuery rows: " + err.Error())
+	}
+
+	return nil
+}
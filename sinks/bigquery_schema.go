@@ -0,0 +1,103 @@
+package sinks
+
+// bigQueryField is a BigQuery table schema field as used by the REST API
+type bigQueryField struct {
+	Name   string          `json:"name"`
+	Type   string          `json:"type"`
+	Mode   string          `json:"mode,omitempty"`
+	Fields []bigQueryField `json:"fields,omitempty"`
+}
+
+func bqField(name string, fieldType string) bigQueryField {
+	return bigQueryField{Name: name, Type: fieldType, Mode: "NULLABLE"}
+}
+
+func bqRepeated(name string, fieldType string, fields ...bigQueryField) bigQueryField {
+	return bigQueryField{Name: name, Type: fieldType, Mode: "REPEATED", Fields: fields}
+}
+
+func bqRecord(name string, fields ...bigQueryField) bigQueryField {
+	return bigQueryField{Name: name, Type: "RECORD", Mode: "NULLABLE", Fields: fields}
+}
+
+var bigQueryAttributeFields = []bigQueryField{bqField("key", "STRING"), bqField("value", "STRING")}
+
+var bigQueryEventFields = []bigQueryField{
+	bqField("type", "STRING"),
+	bqRepeated("attributes", "RECORD", bigQueryAttributeFields...),
+}
+
+// Columns shared by every table, block_time is the partitioning column
+var bigQueryRecordFields = []bigQueryField{
+	{Name: "chain_id", Type: "STRING", Mode: "REQUIRED"},
+	{Name: "height", Type: "INT64", Mode: "REQUIRED"},
+	bqField("block_time", "TIMESTAMP"),
+	bqField("key", "STRING"),
+}
+
+// The remaining columns mirror the JSON encoding of the record data
+var bigQueryDataFields = map[RecordType][]bigQueryField{
+	RecordTypeBlock: {
+		bqField("proposer_cons_address", "STRING"),
+	},
+	RecordTypeTx: {
+		bqField("hash", "STRING"),
+		bqField("code", "INT64"),
+		bqRepeated("signers", "STRING"),
+		// Amounts can exceed the BIGNUMERIC range, so they are stored as strings
+		bqRepeated("fees", "RECORD", bqField("amount", "STRING"), bqField("denom", "STRING"), bqField("payer", "STRING")),
+		bqRepeated("messages", "RECORD",
+			bqField("index", "INT64"),
+			bqField("type", "STRING"),
+			bqRepeated("events", "RECORD", bigQueryEventFields...),
+		),
+	},
+	RecordTypeBlockEvent: {
+		bqField("lifecycle_position", "STRING"),
+		bqField("index", "INT64"),
+		bqRecord("event", bigQueryEventFields...),
+	},
+	RecordTypeFailedBlock: {
+		bqField("reason", "STRING"),
+		bqField("error", "STRING"),
+	},
+}
+
+func bigQuerySchema(recordType RecordType) []bigQueryField {
+	fields := append([]bigQueryField{}, bigQueryRecordFields...)
+	return append(fields, bigQueryDataFields[recordType]...)
+}
+
+// mergeBigQuerySchema adds the desired fields missing from the existing schema. BigQuery only allows adding
+// columns to an existing table, so changed types are left as they are.
+func mergeBigQuerySchema(existing []bigQueryField, desired []bigQueryField) ([]bigQueryField, bool) {
+	merged := append([]bigQueryField{}, existing...)
+	changed := false
+
+	for _, field := range desired {
+		found := false
+		for i := range merged {
+			if merged[i].Name != field.Name {
+				continue
+			}
+			found = true
+			if merged[i].Type == "RECORD" && field.Type == "RECORD" {
+				var nestedChanged bool
+				merged[i].Fields, nestedChanged = mergeBigQuerySchema(merged[i].Fields, field.Fields)
+				changed = changed || nestedChanged
+			}
+			break
+		}
+
+		if !found {
+			// New columns must be nullable or repeated
+			if field.Mode == "REQUIRED" {
+				field.Mode = "NULLABLE"
+			}
+			merged = append(merged, field)
+			changed = true
+		}
+	}
+
+	return merged, changed
+}
@@ -0,0 +1,39 @@
+package sinks
+
+import (
+	"github.com/DefiantLabs/cosmos-indexer/db"
+	"gorm.io/gorm"
+)
+
+// CheckpointStore persists the last height a sink delivered for each record type, so records are not delivered again
+// after a restart or when a height range is reindexed
+type CheckpointStore interface {
+	Load(chainID string, sink string) (map[RecordType]int64, error)
+	Save(chainID string, sink string, recordType RecordType, height int64) error
+}
+
+type dbCheckpointStore struct {
+	db *gorm.DB
+}
+
+func NewDBCheckpointStore(db *gorm.DB) CheckpointStore {
+	return &dbCheckpointStore{db: db}
+}
+
+func (s *dbCheckpointStore) Load(chainID string, sink string) (map[RecordType]int64, error) {
+	heights, err := db.GetSinkCheckpoints(s.db, chainID, sink)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := make(map[RecordType]int64, len(heights))
+	for recordType, height := range heights {
+		checkpoints[RecordType(recordType)] = height
+	}
+
+	return checkpoints, nil
+}
+
+func (s *dbCheckpointStore) Save(chainID string, sink string, recordType RecordType, height int64) error {
+	return db.UpsertSinkCheckpoint(s.db, chainID, sink, string(recordType), height)
+}
@@ -0,0 +1,362 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/apache/arrow/go/v11/arrow"
+	"github.com/apache/arrow/go/v11/arrow/array"
+	"github.com/apache/arrow/go/v11/arrow/memory"
+	"github.com/apache/arrow/go/v11/parquet"
+	"github.com/apache/arrow/go/v11/parquet/compress"
+	"github.com/apache/arrow/go/v11/parquet/pqarrow"
+)
+
+type DeltaConfig struct {
+	// Location is the directory or s3://bucket/prefix URL the tables are created in
+	Location string
+	// Region and Endpoint configure the S3 client of S3 locations
+	Region   string
+	Endpoint string
+	// BatchBlocks is the number of blocks buffered before they are committed to the tables
+	BatchBlocks int
+}
+
+// DeltaSink writes records to Delta Lake tables, one per record type. The buffered records of every batch of blocks are
+// written to a Parquet file per table and committed by adding a version to the table log, so readers only ever see
+// whole batches. Buffered records that were not committed yet are lost if the indexer is killed.
+type DeltaSink struct {
+	conf     DeltaConfig
+	store    objectStore
+	schemas  map[RecordType]*arrow.Schema
+	versions map[RecordType]int64
+	buffered map[RecordType][]map[string]any
+	heights  map[int64]struct{}
+}
+
+// The tables use the same schema as the BigQuery tables
+var deltaRecordTypes = []RecordType{RecordTypeBlock, RecordTypeTx, RecordTypeBlockEvent, RecordTypeFailedBlock}
+
+func NewDeltaSink(conf DeltaConfig) (*DeltaSink, error) {
+	if conf.BatchBlocks < 1 {
+		return nil, errors.New("the Delta Lake batch size must be at least 1 block")
+	}
+
+	store, err := newObjectStore(conf.Location, conf.Region, conf.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	sink := &DeltaSink{
+		conf:     conf,
+		store:    store,
+		schemas:  map[RecordType]*arrow.Schema{},
+		versions: map[RecordType]int64{},
+		buffered: map[RecordType][]map[string]any{},
+		heights:  map[int64]struct{}{},
+	}
+
+	for _, recordType := range deltaRecordTypes {
+		sink.schemas[recordType] = arrowSchema(bigQuerySchema(recordType))
+	}
+
+	return sink, nil
+}
+
+func (s *DeltaSink) Name() string {
+	return "delta"
+}
+
+func deltaTable(recordType RecordType) string {
+	switch recordType {
+	case RecordTypeTx:
+		return "txs"
+	default:
+		return string(recordType) + "s"
+	}
+}
+
+func (s *DeltaSink) Write(ctx context.Context, records []Record) error {
+	for _, record := range records {
+		if _, ok := s.schemas[record.Type]; !ok {
+			continue
+		}
+
+		row, err := bigQueryRow(record)
+		if err != nil {
+			return err
+		}
+		// Delta timestamps have microsecond precision
+		if !record.Time.IsZero() {
+			row["block_time"] = record.Time.UTC().Format("2006-01-02T15:04:05.000000Z")
+		}
+
+		s.buffered[record.Type] = append(s.buffered[record.Type], row)
+		s.heights[record.Height] = struct{}{}
+	}
+
+	if len(s.heights) < s.conf.BatchBlocks {
+		return nil
+	}
+
+	return s.commit(ctx)
+}
+
+// commit writes and commits the buffered rows of every table, tables that were committed are not written again if
+// another table fails
+func (s *DeltaSink) commit(ctx context.Context) error {
+	for _, recordType := range deltaRecordTypes {
+		rows := s.buffered[recordType]
+		if len(rows) == 0 {
+			continue
+		}
+
+		if err := s.commitTable(ctx, recordType, rows); err != nil {
+			return fmt.Errorf("failed to commit to Delta table %s: %w", deltaTable(recordType), err)
+		}
+
+		delete(s.buffered, recordType)
+	}
+
+	s.heights = map[int64]struct{}{}
+	return nil
+}
+
+func (s *DeltaSink) commitTable(ctx context.Context, recordType RecordType, rows []map[string]any) error {
+	table := deltaTable(recordType)
+	schema := s.schemas[recordType]
+
+	data, err := writeParquet(schema, rows)
+	if err != nil {
+		return err
+	}
+
+	minHeight, maxHeight := rows[0]["height"].(int64), rows[0]["height"].(int64)
+	for _, row := range rows {
+		height := row["height"].(int64)
+		if height < minHeight {
+			minHeight = height
+		}
+		if height > maxHeight {
+			maxHeight = height
+		}
+	}
+
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return err
+	}
+	file := fmt.Sprintf("part-%d-%d-%s.snappy.parquet", minHeight, maxHeight, hex.EncodeToString(id))
+
+	if err := s.store.Put(ctx, path.Join(table, file), data, false); err != nil {
+		return err
+	}
+
+	version, ok := s.versions[recordType]
+	if !ok {
+		if version, err = s.nextVersion(ctx, table); err != nil {
+			return err
+		}
+	}
+
+	stats, err := json.Marshal(map[string]any{
+		"numRecords": len(rows),
+		"minValues":  map[string]int64{"height": minHeight},
+		"maxValues":  map[string]int64{"height": maxHeight},
+		"nullCount":  map[string]int64{"height": 0},
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UnixMilli()
+	actions := []map[string]any{
+		{"commitInfo": map[string]any{"timestamp": now, "operation": "WRITE", "operationParameters": map[string]string{"mode": "Append"}}},
+	}
+	if version == 0 {
+		schemaString, err := deltaSchemaString(schema)
+		if err != nil {
+			return err
+		}
+		actions = append(actions,
+			map[string]any{"protocol": map[string]int{"minReaderVersion": 1, "minWriterVersion": 2}},
+			map[string]any{"metaData": map[string]any{
+				"id":               newDeltaTableID(),
+				"name":             table,
+				"format":           map[string]any{"provider": "parquet", "options": map[string]string{}},
+				"schemaString":     schemaString,
+				"partitionColumns": []string{},
+				"configuration":    map[string]string{},
+				"createdTime":      now,
+			}},
+		)
+	}
+	actions = append(actions, map[string]any{"add": map[string]any{
+		"path":             file,
+		"partitionValues":  map[string]string{},
+		"size":             len(data),
+		"modificationTime": now,
+		"dataChange":       true,
+		"stats":            string(stats),
+	}})
+
+	var log bytes.Buffer
+	for _, action := range actions {
+		line, err := json.Marshal(action)
+		if err != nil {
+			return err
+		}
+		log.Write(line)
+		log.WriteByte('\n')
+	}
+
+	// The exclusive put is what makes the commit atomic, a version that already exists was written by another writer
+	if err := s.store.Put(ctx, path.Join(table, "_delta_log", fmt.Sprintf("%020d.json", version)), log.Bytes(), true); err != nil {
+		delete(s.versions, recordType)
+		return err
+	}
+
+	s.versions[recordType] = version + 1
+	return nil
+}
+
+// nextVersion returns the version following the latest commit of the table log
+func (s *DeltaSink) nextVersion(ctx context.Context, table string) (int64, error) {
+	keys, err := s.store.List(ctx, path.Join(table, "_delta_log"))
+	if err != nil {
+		return 0, err
+	}
+
+	var next int64
+	for _, key := range keys {
+		name := path.Base(key)
+		if !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		version, err := strconv.ParseInt(strings.TrimSuffix(name, ".json"), 10, 64)
+		if err == nil && version >= next {
+			next = version + 1
+		}
+	}
+
+	return next, nil
+}
+
+func newDeltaTableID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	id[6] = (id[6] & 0x0f) | 0x40
+	id[8] = (id[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
+
+func writeParquet(schema *arrow.Schema, rows []map[string]any) ([]byte, error) {
+	rowsJSON, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	record, _, err := array.RecordFromJSON(memory.DefaultAllocator, schema, bytes.NewReader(rowsJSON))
+	if err != nil {
+		return nil, err
+	}
+	defer record.Release()
+
+	var buf bytes.Buffer
+	writer, err := pqarrow.NewFileWriter(schema, &buf, parquet.NewWriterProperties(parquet.WithCompression(compress.Codecs.Snappy)), pqarrow.DefaultWriterProps())
+	if err != nil {
+		return nil, err
+	}
+	if err := writer.Write(record); err != nil {
+		writer.Close()
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// arrowSchema converts a BigQuery table schema to the Arrow schema of the Parquet files
+func arrowSchema(fields []bigQueryField) *arrow.Schema {
+	return arrow.NewSchema(arrowFields(fields), nil)
+}
+
+func arrowFields(fields []bigQueryField) []arrow.Field {
+	converted := make([]arrow.Field, 0, len(fields))
+	for _, field := range fields {
+		var dataType arrow.DataType
+		switch field.Type {
+		case "INT64":
+			dataType = arrow.PrimitiveTypes.Int64
+		case "TIMESTAMP":
+			dataType = &arrow.TimestampType{Unit: arrow.Microsecond, TimeZone: "UTC"}
+		case "RECORD":
+			dataType = arrow.StructOf(arrowFields(field.Fields)...)
+		default:
+			dataType = arrow.BinaryTypes.String
+		}
+
+		if field.Mode == "REPEATED" {
+			dataType = arrow.ListOf(dataType)
+		}
+
+		converted = append(converted, arrow.Field{Name: field.Name, Type: dataType, Nullable: field.Mode != "REQUIRED"})
+	}
+
+	return converted
+}
+
+// deltaSchemaString returns the Spark SQL JSON schema Delta stores in the table metadata
+func deltaSchemaString(schema *arrow.Schema) (string, error) {
+	data, err := json.Marshal(deltaStructType(schema.Fields()))
+	return string(data), err
+}
+
+func deltaStructType(fields []arrow.Field) map[string]any {
+	deltaFields := make([]map[string]any, 0, len(fields))
+	for _, field := range fields {
+		deltaFields = append(deltaFields, map[string]any{
+			"name":     field.Name,
+			"type":     deltaType(field.Type),
+			"nullable": field.Nullable,
+			"metadata": map[string]any{},
+		})
+	}
+
+	return map[string]any{"type": "struct", "fields": deltaFields}
+}
+
+func deltaType(dataType arrow.DataType) any {
+	switch t := dataType.(type) {
+	case *arrow.Int64Type:
+		return "long"
+	case *arrow.TimestampType:
+		return "timestamp"
+	case *arrow.StructType:
+		return deltaStructType(t.Fields())
+	case *arrow.ListType:
+		return map[string]any{"type": "array", "elementType": deltaType(t.Elem()), "containsNull": true}
+	default:
+		return "string"
+	}
+}
+
+// Close commits the records still buffered
+func (s *DeltaSink) Close() error {
+	if len(s.heights) == 0 {
+		return nil
+	}
+
+	return s.commit(context.Background())
+}
@@ -0,0 +1,63 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/config"
+	"github.com/DefiantLabs/cosmos-indexer/metrics"
+)
+
+// Dispatcher fans committed records out to all configured sinks
+type Dispatcher struct {
+	// Failure notifications are published from the block processing goroutine, so writes are serialized here
+	mu    sync.Mutex
+	sinks []Sink
+}
+
+func NewDispatcher(sinks ...Sink) *Dispatcher {
+	return &Dispatcher{sinks: sinks}
+}
+
+func (d *Dispatcher) Enabled() bool {
+	return d != nil && len(d.sinks) != 0
+}
+
+// Publish writes the records to every sink. All sinks are attempted, the first error is returned.
+func (d *Dispatcher) Publish(ctx context.Context, records []Record) error {
+	if !d.Enabled() || len(records) == 0 {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var firstErr error
+	for _, sink := range d.sinks {
+		start := time.Now()
+		err := sink.Write(ctx, records)
+		metrics.ObserveSinkWrite(sink.Name(), len(records), time.Since(start), err)
+		if err != nil {
+			config.Log.Errorf("Sink %s failed to write %d records: %v", sink.Name(), len(records), err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("sink %s: %w", sink.Name(), err)
+			}
+		}
+	}
+
+	return firstErr
+}
+
+func (d *Dispatcher) Close() {
+	if d == nil {
+		return
+	}
+
+	for _, sink := range d.sinks {
+		if err := sink.Close(); err != nil {
+			config.Log.Errorf("Error closing sink %s: %v", sink.Name(), err)
+		}
+	}
+}
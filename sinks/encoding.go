@@ -0,0 +1,82 @@
+package sinks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	EncodingJSON     = "json"
+	EncodingJSONGzip = "json-gzip"
+	// EncodingProtobuf is only supported by sinks carrying binary payloads, see newKafkaEncoder
+	EncodingProtobuf = "protobuf"
+)
+
+// Encoder serializes a record into the wire format of a sink
+type Encoder func(Record) ([]byte, error)
+
+func NewEncoder(encoding string) (Encoder, error) {
+	switch encoding {
+	case "", EncodingJSON:
+		return encodeJSON, nil
+	case EncodingJSONGzip:
+		return encodeJSONGzip, nil
+	default:
+		return nil, fmt.Errorf("unsupported sink encoding %q, must be one of %s, %s", encoding, EncodingJSON, EncodingJSONGzip)
+	}
+}
+
+// Returns the Content-Encoding that matches the sink encoding, if any
+func contentEncoding(encoding string) string {
+	if encoding == EncodingJSONGzip {
+		return "gzip"
+	}
+	return ""
+}
+
+func encodeJSON(record Record) ([]byte, error) {
+	return json.Marshal(record)
+}
+
+func encodeJSONGzip(record Record) ([]byte, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeProtobuf serializes the record as a google.protobuf.Struct message holding the fields of its JSON encoding, so
+// consumers decode it with the well-known types of any protobuf library. Numbers are doubles, like in JSON.
+func encodeProtobuf(record Record) ([]byte, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	message, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(message)
+}
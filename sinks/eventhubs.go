@@ -0,0 +1,246 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	eventHubsContentType = "application/vnd.microsoft.servicebus.json"
+	eventHubsTokenTTL    = time.Hour
+	// Event Hubs rejects batches larger than 1 MB, leave headroom for the batch envelope
+	eventHubsMaxBatchBytes = 900 * 1024
+)
+
+type EventHubsConfig struct {
+	// ConnectionString is a shared access policy connection string of the namespace or the event hub
+	ConnectionString string
+	// EventHub overrides the EntityPath of the connection string
+	EventHub string
+	Encoding string
+	ChainID  string
+	// Checkpoints enables checkpointed delivery when set
+	Checkpoints CheckpointStore
+}
+
+// EventHubsSink sends records to Azure Event Hubs using the REST API, with the chain ID as partition key so the
+// records of a chain land on the same partition in order. With checkpointing, the last delivered height of each
+// record type is saved after every successful write and records at or below it are not sent again.
+type EventHubsSink struct {
+	conf        EventHubsConfig
+	client      *http.Client
+	url         string
+	resource    string
+	keyName     string
+	key         string
+	encode      Encoder
+	checkpoints map[RecordType]int64
+}
+
+type eventHubsEvent struct {
+	Body             string            `json:"Body"`
+	BrokerProperties map[string]string `json:"BrokerProperties,omitempty"`
+	UserProperties   map[string]string `json:"UserProperties,omitempty"`
+}
+
+func NewEventHubsSink(conf EventHubsConfig) (*EventHubsSink, error) {
+	encode, err := NewEncoder(conf.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	properties := map[string]string{}
+	for _, part := range strings.Split(conf.ConnectionString, ";") {
+		if name, value, ok := strings.Cut(part, "="); ok {
+			properties[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+		}
+	}
+
+	endpoint, err := url.Parse(properties["endpoint"])
+	if err != nil || endpoint.Host == "" {
+		return nil, errors.New("the Event Hubs connection string has no valid Endpoint")
+	}
+	if properties["sharedaccesskeyname"] == "" || properties["sharedaccesskey"] == "" {
+		return nil, errors.New("the Event Hubs connection string must contain SharedAccessKeyName and SharedAccessKey")
+	}
+
+	eventHub := conf.EventHub
+	if eventHub == "" {
+		eventHub = properties["entitypath"]
+	}
+	if eventHub == "" {
+		return nil, errors.New("no event hub set, configure it or add EntityPath to the connection string")
+	}
+
+	resource := fmt.Sprintf("https://%s/%s", endpoint.Host, eventHub)
+	sink := &EventHubsSink{
+		conf:     conf,
+		client:   &http.Client{Timeout: 60 * time.Second},
+		url:      resource + "/messages?api-version=2014-01",
+		resource: resource,
+		keyName:  properties["sharedaccesskeyname"],
+		key:      properties["sharedaccesskey"],
+		encode:   encode,
+	}
+
+	if conf.Checkpoints != nil {
+		sink.checkpoints, err = conf.Checkpoints.Load(conf.ChainID, sink.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Event Hubs sink checkpoints: %w", err)
+		}
+		if sink.checkpoints == nil {
+			sink.checkpoints = map[RecordType]int64{}
+		}
+	}
+
+	return sink, nil
+}
+
+func (s *EventHubsSink) Name() string {
+	return "eventhubs"
+}
+
+// Builds a shared access signature token for the event hub
+func (s *EventHubsSink) sasToken(now time.Time) string {
+	resource := url.QueryEscape(strings.ToLower(s.resource))
+	expiry := strconv.FormatInt(now.Add(eventHubsTokenTTL).Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(s.key))
+	mac.Write([]byte(resource + "\n" + expiry))
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	return fmt.Sprintf("SharedAccessSignature sr=%s&sig=%s&se=%s&skn=%s", resource, url.QueryEscape(signature), expiry, s.keyName)
+}
+
+// Drops records that were already delivered according to the checkpoints. Failed block records are always sent.
+func (s *EventHubsSink) undelivered(records []Record) []Record {
+	if s.checkpoints == nil {
+		return records
+	}
+
+	pending := make([]Record, 0, len(records))
+	for _, record := range records {
+		if record.Type != RecordTypeFailedBlock && record.Height <= s.checkpoints[record.Type] {
+			continue
+		}
+		pending = append(pending, record)
+	}
+
+	return pending
+}
+
+func (s *EventHubsSink) Write(ctx context.Context, records []Record) error {
+	records = s.undelivered(records)
+
+	var batch []eventHubsEvent
+	batchBytes := 0
+	delivered := map[RecordType]int64{}
+
+	for _, record := range records {
+		payload, err := s.encode(record)
+		if err != nil {
+			return err
+		}
+
+		event := eventHubsEvent{
+			Body:             string(payload),
+			BrokerProperties: map[string]string{"PartitionKey": record.ChainID},
+			UserProperties: map[string]string{
+				"chain_id": record.ChainID,
+				"height":   strconv.FormatInt(record.Height, 10),
+				"type":     string(record.Type),
+				"key":      record.Key,
+			},
+		}
+		// The REST API only carries text bodies
+		if encoding := contentEncoding(s.conf.Encoding); encoding != "" {
+			event.Body = base64.StdEncoding.EncodeToString(payload)
+			event.UserProperties["content_encoding"] = encoding + "+base64"
+		}
+
+		size := len(event.Body) + 256
+		if len(batch) != 0 && batchBytes+size > eventHubsMaxBatchBytes {
+			if err := s.send(ctx, batch); err != nil {
+				return err
+			}
+			batch = nil
+			batchBytes = 0
+		}
+
+		batch = append(batch, event)
+		batchBytes += size
+		if record.Type != RecordTypeFailedBlock && record.Height > delivered[record.Type] {
+			delivered[record.Type] = record.Height
+		}
+	}
+
+	if len(batch) != 0 {
+		if err := s.send(ctx, batch); err != nil {
+			return err
+		}
+	}
+
+	return s.checkpoint(delivered)
+}
+
+func (s *EventHubsSink) send(ctx context.Context, batch []eventHubsEvent) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", eventHubsContentType)
+	request.Header.Set("Authorization", s.sasToken(time.Now()))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("Event Hubs returned %s: %s", response.Status, strings.TrimSpace(string(message)))
+	}
+
+	return nil
+}
+
+// Saves the delivered heights once every batch of the write has been accepted, a block split across batches is not checkpointed halfway
+func (s *EventHubsSink) checkpoint(delivered map[RecordType]int64) error {
+	if s.checkpoints == nil {
+		return nil
+	}
+
+	for recordType, height := range delivered {
+		if height <= s.checkpoints[recordType] {
+			continue
+		}
+		if err := s.conf.Checkpoints.Save(s.conf.ChainID, s.Name(), recordType, height); err != nil {
+			return fmt.Errorf("failed to save Event Hubs sink checkpoint: %w", err)
+		}
+		s.checkpoints[recordType] = height
+	}
+
+	return nil
+}
+
+func (s *EventHubsSink) Close() error {
+	return nil
+}
@@ -0,0 +1,167 @@
+package sinks
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// inProgressSuffix marks files that are still being written, consumers should skip them
+const inProgressSuffix = ".inprogress"
+
+type FileConfig struct {
+	Directory string
+	// MaxSize is the number of uncompressed bytes after which a file is rotated, 0 disables size based rotation
+	MaxSize int64
+	// MaxAge is the time after which a file is rotated on the next write, 0 disables time based rotation
+	MaxAge time.Duration
+	Gzip   bool
+}
+
+// FileSink writes records as newline delimited JSON, one file series per record type. Files are written with an
+// .inprogress suffix, which is removed once they are rotated or the sink is closed.
+type FileSink struct {
+	conf  FileConfig
+	files map[RecordType]*rotatingFile
+}
+
+type rotatingFile struct {
+	path    string
+	file    *os.File
+	gzip    *gzip.Writer
+	writer  io.Writer
+	size    int64
+	created time.Time
+}
+
+func NewFileSink(conf FileConfig) (*FileSink, error) {
+	if err := os.MkdirAll(conf.Directory, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create the file sink directory: %w", err)
+	}
+
+	return &FileSink{conf: conf, files: map[RecordType]*rotatingFile{}}, nil
+}
+
+func (s *FileSink) Name() string {
+	return "file"
+}
+
+func (s *FileSink) Write(_ context.Context, records []Record) error {
+	written := map[RecordType]struct{}{}
+
+	for _, record := range records {
+		line, err := encodeJSON(record)
+		if err != nil {
+			return err
+		}
+
+		current, err := s.file(record.Type)
+		if err != nil {
+			return err
+		}
+
+		n, err := current.writer.Write(append(line, '\n'))
+		current.size += int64(n)
+		if err != nil {
+			return err
+		}
+		written[record.Type] = struct{}{}
+
+		if s.conf.MaxSize > 0 && current.size >= s.conf.MaxSize {
+			if err := s.rotate(record.Type); err != nil {
+				return err
+			}
+			delete(written, record.Type)
+		}
+	}
+
+	// Flush so the written records can be read right away, e.g. with zcat or tail while debugging
+	for recordType := range written {
+		if current := s.files[recordType]; current != nil && current.gzip != nil {
+			if err := current.gzip.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// file returns the open file of the record type, rotating it first if it is older than the max age
+func (s *FileSink) file(recordType RecordType) (*rotatingFile, error) {
+	current := s.files[recordType]
+	if current != nil && s.conf.MaxAge > 0 && time.Since(current.created) >= s.conf.MaxAge {
+		if err := s.rotate(recordType); err != nil {
+			return nil, err
+		}
+		current = nil
+	}
+
+	if current != nil {
+		return current, nil
+	}
+
+	created := time.Now().UTC()
+	name := fmt.Sprintf("%s-%s.jsonl", recordType, created.Format("20060102T150405.000000000Z"))
+	if s.conf.Gzip {
+		name += ".gz"
+	}
+
+	path := filepath.Join(s.conf.Directory, name)
+	file, err := os.OpenFile(path+inProgressSuffix, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+
+	current = &rotatingFile{path: path, file: file, writer: file, created: created}
+	if s.conf.Gzip {
+		current.gzip = gzip.NewWriter(file)
+		current.writer = current.gzip
+	}
+
+	s.files[recordType] = current
+	return current, nil
+}
+
+// rotate completes the file of the record type, the next record starts a new one
+func (s *FileSink) rotate(recordType RecordType) error {
+	current := s.files[recordType]
+	if current == nil {
+		return nil
+	}
+	delete(s.files, recordType)
+
+	if current.gzip != nil {
+		if err := current.gzip.Close(); err != nil {
+			current.file.Close()
+			return err
+		}
+	}
+
+	if err := current.file.Sync(); err != nil {
+		current.file.Close()
+		return err
+	}
+
+	if err := current.file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(current.path+inProgressSuffix, current.path)
+}
+
+// Close completes all open files
+func (s *FileSink) Close() error {
+	var firstErr error
+	for recordType := range s.files {
+		if err := s.rotate(recordType); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
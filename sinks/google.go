@@ -0,0 +1,93 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// Returns an HTTP client authenticated for the Google Cloud REST APIs. Application default credentials are used
+// unless a service account credentials file is given.
+func newGoogleHTTPClient(ctx context.Context, credentialsFile string, scopes ...string) (*http.Client, error) {
+	if credentialsFile == "" {
+		return google.DefaultClient(ctx, scopes...)
+	}
+
+	data, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := google.CredentialsFromJSON(ctx, data, scopes...)
+	if err != nil {
+		return nil, err
+	}
+
+	return oauth2.NewClient(ctx, credentials.TokenSource), nil
+}
+
+type googleAPIError struct {
+	Method     string
+	URL        string
+	Status     string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *googleAPIError) Error() string {
+	return fmt.Sprintf("%s %s returned %s: %s", e.Method, e.URL, e.Status, e.Body)
+}
+
+func isGoogleNotFound(err error) bool {
+	var apiErr *googleAPIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// Sends a JSON request to a Google Cloud REST API and decodes the JSON response into out, if given
+func doGoogleJSONRequest(ctx context.Context, client *http.Client, method string, url string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &googleAPIError{Method: method, URL: url, Status: resp.Status, StatusCode: resp.StatusCode, Body: respBody}
+	}
+
+	if out != nil && len(respBody) != 0 {
+		return json.Unmarshal(respBody, out)
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaCompressions are the compression codecs of the Kafka sink, applied by the producer to its message batches
+var KafkaCompressions = map[string]kafka.Compression{
+	"gzip":   kafka.Gzip,
+	"snappy": kafka.Snappy,
+	"lz4":    kafka.Lz4,
+	"zstd":   kafka.Zstd,
+}
+
+type KafkaConfig struct {
+	Brokers []string
+	// Records are published to the topic <topic-prefix>.<record type>
+	TopicPrefix string
+	// Encoding is json, json-gzip or protobuf
+	Encoding string
+	// Compression is one of KafkaCompressions, or empty to not compress the batches
+	Compression  string
+	CreateTopics bool
+}
+
+// KafkaSink publishes records to a topic per record type. The chain ID is the message key, so the records of a chain
+// land on the same partition and are consumed in order. Write returns once all in-sync replicas have the records.
+type KafkaSink struct {
+	conf   KafkaConfig
+	writer *kafka.Writer
+	encode Encoder
+}
+
+func NewKafkaSink(conf KafkaConfig) (*KafkaSink, error) {
+	encode, err := newKafkaEncoder(conf.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	writer := &kafka.Writer{
+		Addr:                   kafka.TCP(conf.Brokers...),
+		Balancer:               &kafka.Hash{},
+		RequiredAcks:           kafka.RequireAll,
+		AllowAutoTopicCreation: conf.CreateTopics,
+		// Write hands over all records of a block at once, so batches are not held back for more records
+		BatchTimeout: 10 * time.Millisecond,
+	}
+	if conf.Compression != "" {
+		compression, ok := KafkaCompressions[conf.Compression]
+		if !ok {
+			return nil, fmt.Errorf("unsupported Kafka compression %q, must be gzip, snappy, lz4 or zstd", conf.Compression)
+		}
+		writer.Compression = compression
+	}
+
+	// Fail on startup if none of the brokers can be reached
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var dialErr error
+	for _, broker := range conf.Brokers {
+		conn, err := kafka.DialContext(ctx, "tcp", broker)
+		if err == nil {
+			conn.Close()
+			return &KafkaSink{conf: conf, writer: writer, encode: encode}, nil
+		}
+		dialErr = err
+	}
+	return nil, fmt.Errorf("failed to connect to the Kafka brokers %v: %w", conf.Brokers, dialErr)
+}
+
+// newKafkaEncoder accepts the protobuf encoding on top of the sink encodings, since Kafka messages carry binary values
+func newKafkaEncoder(encoding string) (Encoder, error) {
+	if encoding == EncodingProtobuf {
+		return encodeProtobuf, nil
+	}
+	return NewEncoder(encoding)
+}
+
+func (s *KafkaSink) Name() string {
+	return "kafka"
+}
+
+func (s *KafkaSink) topic(recordType RecordType) string {
+	if s.conf.TopicPrefix == "" {
+		return string(recordType)
+	}
+	return fmt.Sprintf("%s.%s", s.conf.TopicPrefix, recordType)
+}
+
+func (s *KafkaSink) message(record Record) (kafka.Message, error) {
+	payload, err := s.encode(record)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+
+	headers := []kafka.Header{
+		{Key: "chain_id", Value: []byte(record.ChainID)},
+		{Key: "height", Value: []byte(strconv.FormatInt(record.Height, 10))},
+		{Key: "type", Value: []byte(record.Type)},
+		{Key: "key", Value: []byte(record.Key)},
+	}
+	if encoding := contentEncoding(s.conf.Encoding); encoding != "" {
+		headers = append(headers, kafka.Header{Key: "content_encoding", Value: []byte(encoding)})
+	}
+
+	return kafka.Message{
+		Topic:   s.topic(record.Type),
+		Key:     []byte(record.ChainID),
+		Value:   payload,
+		Headers: headers,
+		Time:    record.Time,
+	}, nil
+}
+
+// Write publishes the records in batches per partition and waits for all of them to be acknowledged
+func (s *KafkaSink) Write(ctx context.Context, records []Record) error {
+	messages := make([]kafka.Message, 0, len(records))
+	for _, record := range records {
+		message, err := s.message(record)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, message)
+	}
+
+	return s.writer.WriteMessages(ctx, messages...)
+}
+
+func (s *KafkaSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,161 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+var errObjectExists = errors.New("object already exists")
+
+// objectStore is the storage of file based sinks, either a local directory or an S3 bucket
+type objectStore interface {
+	// Put writes an object, exclusive puts fail with errObjectExists if the object already exists
+	Put(ctx context.Context, key string, data []byte, exclusive bool) error
+	// List returns the keys of the objects below the prefix
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// newObjectStore returns the store of a location, which is either a local path or an s3://bucket/prefix URL
+func newObjectStore(location string, region string, endpoint string) (objectStore, error) {
+	if !strings.HasPrefix(location, "s3://") {
+		return &localStore{root: location}, nil
+	}
+
+	bucket, prefix, _ := strings.Cut(strings.TrimPrefix(location, "s3://"), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid S3 location %s", location)
+	}
+
+	awsConfig := aws.NewConfig()
+	if region != "" {
+		awsConfig = awsConfig.WithRegion(region)
+	}
+	if endpoint != "" {
+		awsConfig = awsConfig.WithEndpoint(endpoint).WithS3ForcePathStyle(true)
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Config:            *awsConfig,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS session: %w", err)
+	}
+
+	return &s3Store{client: s3.New(sess), bucket: bucket, prefix: strings.Trim(prefix, "/")}, nil
+}
+
+type localStore struct {
+	root string
+}
+
+func (s *localStore) Put(_ context.Context, key string, data []byte, exclusive bool) error {
+	target := filepath.Join(s.root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(target), ".tmp-"+filepath.Base(target))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+
+	if !exclusive {
+		return os.Rename(temp.Name(), target)
+	}
+
+	// Linking fails if the target exists, which makes exclusive puts atomic
+	if err := os.Link(temp.Name(), target); err != nil {
+		if os.IsExist(err) {
+			return errObjectExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *localStore) List(_ context.Context, prefix string) ([]string, error) {
+	dir := filepath.Join(s.root, filepath.FromSlash(prefix))
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			keys = append(keys, path.Join(prefix, entry.Name()))
+		}
+	}
+
+	return keys, nil
+}
+
+// s3Store does not lock, S3 has no put-if-absent, so exclusive puts are only safe with a single writer per location
+type s3Store struct {
+	client *s3.S3
+	bucket string
+	prefix string
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, data []byte, exclusive bool) error {
+	objectKey := path.Join(s.prefix, key)
+
+	if exclusive {
+		_, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(objectKey)})
+		if err == nil {
+			return errObjectExists
+		}
+		var awsErr awserr.RequestFailure
+		if !errors.As(err, &awsErr) || awsErr.StatusCode() != 404 {
+			return err
+		}
+	}
+
+	_, err := s.client.PutObjectWithContext(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(objectKey),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (s *s3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	listPrefix := path.Join(s.prefix, prefix) + "/"
+
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{Bucket: aws.String(s.bucket), Prefix: aws.String(listPrefix)},
+		func(page *s3.ListObjectsV2Output, _ bool) bool {
+			for _, object := range page.Contents {
+				keys = append(keys, path.Join(prefix, strings.TrimPrefix(aws.StringValue(object.Key), listPrefix)))
+			}
+			return true
+		})
+
+	return keys, err
+}
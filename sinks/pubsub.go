@@ -0,0 +1,178 @@
+package sinks
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	pubSubScope           = "https://www.googleapis.com/auth/pubsub"
+	pubSubDefaultEndpoint = "https://pubsub.googleapis.com"
+	// Pub/Sub accepts at most 1000 messages per publish request
+	pubSubMaxBatchSize = 1000
+)
+
+type PubSubConfig struct {
+	ProjectID       string
+	Topic           string
+	CredentialsFile string
+	// Endpoint overrides the Pub/Sub API endpoint, e.g. a regional endpoint or the local emulator (which requires no credentials)
+	Endpoint     string
+	OrderingKeys bool
+	// Schema is the ID of the Pub/Sub schema the topic is bound to. When set, the topic binding and the record payloads
+	// are validated against it on startup, and Pub/Sub rejects any published message that does not conform.
+	Schema   string
+	Encoding string
+}
+
+// PubSubSink publishes records to a Google Cloud Pub/Sub topic using the REST API
+type PubSubSink struct {
+	conf     PubSubConfig
+	client   *http.Client
+	endpoint string
+	encode   Encoder
+}
+
+type pubSubMessage struct {
+	Data        string            `json:"data"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	OrderingKey string            `json:"orderingKey,omitempty"`
+}
+
+func NewPubSubSink(ctx context.Context, conf PubSubConfig) (*PubSubSink, error) {
+	if conf.Schema != "" && conf.Encoding == EncodingJSONGzip {
+		return nil, errors.New("the Pub/Sub sink does not support compressed payloads when a schema is set")
+	}
+
+	encode, err := NewEncoder(conf.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	sink := &PubSubSink{conf: conf, encode: encode, endpoint: strings.TrimSuffix(conf.Endpoint, "/")}
+
+	if sink.endpoint == "" {
+		sink.endpoint = pubSubDefaultEndpoint
+	}
+
+	if strings.HasPrefix(sink.endpoint, "http://") {
+		// Plain HTTP is only used by the emulator, which does not authenticate requests
+		sink.client = &http.Client{Timeout: 30 * time.Second}
+	} else {
+		sink.client, err = newGoogleHTTPClient(ctx, conf.CredentialsFile, pubSubScope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Pub/Sub credentials: %w", err)
+		}
+	}
+
+	if conf.Schema != "" {
+		if err := sink.validateSchema(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return sink, nil
+}
+
+func (s *PubSubSink) Name() string {
+	return "pubsub"
+}
+
+func (s *PubSubSink) topicPath() string {
+	return fmt.Sprintf("projects/%s/topics/%s", s.conf.ProjectID, s.conf.Topic)
+}
+
+func (s *PubSubSink) schemaPath() string {
+	return fmt.Sprintf("projects/%s/schemas/%s", s.conf.ProjectID, s.conf.Schema)
+}
+
+// Checks the topic is bound to the configured schema with JSON encoding and that every record type validates against it
+func (s *PubSubSink) validateSchema(ctx context.Context) error {
+	var topic struct {
+		SchemaSettings struct {
+			Schema   string `json:"schema"`
+			Encoding string `json:"encoding"`
+		} `json:"schemaSettings"`
+	}
+
+	if err := doGoogleJSONRequest(ctx, s.client, http.MethodGet, fmt.Sprintf("%s/v1/%s", s.endpoint, s.topicPath()), nil, &topic); err != nil {
+		return fmt.Errorf("failed to get Pub/Sub topic: %w", err)
+	}
+
+	if topic.SchemaSettings.Schema != s.schemaPath() {
+		return fmt.Errorf("Pub/Sub topic %s is bound to schema %q, expected %s", s.conf.Topic, topic.SchemaSettings.Schema, s.schemaPath())
+	}
+
+	if topic.SchemaSettings.Encoding != "JSON" {
+		return fmt.Errorf("Pub/Sub topic %s must use JSON schema encoding, got %s", s.conf.Topic, topic.SchemaSettings.Encoding)
+	}
+
+	for _, record := range sampleRecords() {
+		payload, err := s.encode(record)
+		if err != nil {
+			return err
+		}
+
+		request := map[string]string{
+			"name":     s.schemaPath(),
+			"message":  base64.StdEncoding.EncodeToString(payload),
+			"encoding": "JSON",
+		}
+		if err := doGoogleJSONRequest(ctx, s.client, http.MethodPost, fmt.Sprintf("%s/v1/projects/%s/schemas:validateMessage", s.endpoint, s.conf.ProjectID), request, nil); err != nil {
+			return fmt.Errorf("%s records do not validate against Pub/Sub schema %s: %w", record.Type, s.conf.Schema, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *PubSubSink) Write(ctx context.Context, records []Record) error {
+	for start := 0; start < len(records); start += pubSubMaxBatchSize {
+		end := start + pubSubMaxBatchSize
+		if end > len(records) {
+			end = len(records)
+		}
+
+		messages := make([]pubSubMessage, 0, end-start)
+		for _, record := range records[start:end] {
+			payload, err := s.encode(record)
+			if err != nil {
+				return err
+			}
+
+			message := pubSubMessage{
+				Data: base64.StdEncoding.EncodeToString(payload),
+				Attributes: map[string]string{
+					"chain_id": record.ChainID,
+					"height":   fmt.Sprintf("%d", record.Height),
+					"type":     string(record.Type),
+					"key":      record.Key,
+				},
+			}
+			if encoding := contentEncoding(s.conf.Encoding); encoding != "" {
+				message.Attributes["content_encoding"] = encoding
+			}
+			// Records of a chain are delivered in publish order to subscriptions with message ordering enabled
+			if s.conf.OrderingKeys {
+				message.OrderingKey = record.ChainID
+			}
+			messages = append(messages, message)
+		}
+
+		request := map[string][]pubSubMessage{"messages": messages}
+		if err := doGoogleJSONRequest(ctx, s.client, http.MethodPost, fmt.Sprintf("%s/v1/%s:publish", s.endpoint, s.topicPath()), request, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *PubSubSink) Close() error {
+	return nil
+}
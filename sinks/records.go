@@ -0,0 +1,201 @@
+package sinks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+)
+
+type BlockData struct {
+	Height              int64  `json:"height"`
+	ProposerConsAddress string `json:"proposer_cons_address,omitempty"`
+}
+
+type TxData struct {
+	Hash     string        `json:"hash"`
+	Code     uint32        `json:"code"`
+	Signers  []string      `json:"signers,omitempty"`
+	Fees     []CoinData    `json:"fees,omitempty"`
+	Messages []MessageData `json:"messages,omitempty"`
+}
+
+type CoinData struct {
+	Amount string `json:"amount"`
+	Denom  string `json:"denom"`
+	Payer  string `json:"payer,omitempty"`
+}
+
+type MessageData struct {
+	Index  int         `json:"index"`
+	Type   string      `json:"type"`
+	Events []EventData `json:"events,omitempty"`
+}
+
+type EventData struct {
+	Type       string          `json:"type"`
+	Attributes []AttributeData `json:"attributes,omitempty"`
+}
+
+type AttributeData struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type BlockEventData struct {
+	LifecyclePosition string    `json:"lifecycle_position"`
+	Index             uint64    `json:"index"`
+	Event             EventData `json:"event"`
+}
+
+type FailedBlockData struct {
+	Height int64  `json:"height"`
+	Reason string `json:"reason"`
+	Error  string `json:"error,omitempty"`
+}
+
+func NewBlockRecord(chainID string, block models.Block) Record {
+	return Record{
+		ChainID: chainID,
+		Height:  block.Height,
+		Time:    block.TimeStamp,
+		Type:    RecordTypeBlock,
+		Key:     fmt.Sprintf("%d", block.Height),
+		Data: BlockData{
+			Height:              block.Height,
+			ProposerConsAddress: block.ProposerConsAddress.Address,
+		},
+	}
+}
+
+func NewTxRecords(chainID string, block models.Block, txs []db.TxDBWrapper) []Record {
+	records := make([]Record, 0, len(txs))
+	for _, tx := range txs {
+		txData := TxData{
+			Hash: tx.Tx.Hash,
+			Code: tx.Tx.Code,
+		}
+
+		for _, signer := range tx.Tx.SignerAddresses {
+			txData.Signers = append(txData.Signers, signer.Address)
+		}
+
+		for _, fee := range tx.Tx.Fees {
+			txData.Fees = append(txData.Fees, CoinData{
+				Amount: fee.Amount.String(),
+				Denom:  fee.Denomination.Base,
+				Payer:  fee.PayerAddress.Address,
+			})
+		}
+
+		for _, message := range tx.Messages {
+			messageData := MessageData{
+				Index: message.Message.MessageIndex,
+				Type:  message.Message.MessageType.MessageType,
+			}
+			for _, event := range message.MessageEvents {
+				eventData := EventData{Type: event.MessageEvent.MessageEventType.Type}
+				for _, attribute := range event.Attributes {
+					eventData.Attributes = append(eventData.Attributes, AttributeData{Key: attribute.MessageEventAttributeKey.Key, Value: attribute.Value})
+				}
+				messageData.Events = append(messageData.Events, eventData)
+			}
+			txData.Messages = append(txData.Messages, messageData)
+		}
+
+		records = append(records, Record{
+			ChainID: chainID,
+			Height:  block.Height,
+			Time:    block.TimeStamp,
+			Type:    RecordTypeTx,
+			Key:     tx.Tx.Hash,
+			Data:    txData,
+		})
+	}
+
+	return records
+}
+
+func NewBlockEventRecords(chainID string, blockDBWrapper *db.BlockDBWrapper) []Record {
+	records := make([]Record, 0, len(blockDBWrapper.BeginBlockEvents)+len(blockDBWrapper.EndBlockEvents))
+	records = appendBlockEventRecords(records, chainID, blockDBWrapper.Block, "begin_block", blockDBWrapper.BeginBlockEvents)
+	records = appendBlockEventRecords(records, chainID, blockDBWrapper.Block, "end_block", blockDBWrapper.EndBlockEvents)
+	return records
+}
+
+func appendBlockEventRecords(records []Record, chainID string, block *models.Block, position string, events []db.BlockEventDBWrapper) []Record {
+	for _, event := range events {
+		eventData := EventData{Type: event.BlockEvent.BlockEventType.Type}
+		for _, attribute := range event.Attributes {
+			eventData.Attributes = append(eventData.Attributes, AttributeData{Key: attribute.BlockEventAttributeKey.Key, Value: attribute.Value})
+		}
+
+		records = append(records, Record{
+			ChainID: chainID,
+			Height:  block.Height,
+			Time:    block.TimeStamp,
+			Type:    RecordTypeBlockEvent,
+			Key:     fmt.Sprintf("%d/%s/%d", block.Height, position, event.BlockEvent.Index),
+			Data: BlockEventData{
+				LifecyclePosition: position,
+				Index:             event.BlockEvent.Index,
+				Event:             eventData,
+			},
+		})
+	}
+	return records
+}
+
+// Returns one record of every type with all optional fields populated, used to validate sink schemas on startup
+func NewFailedBlockRecord(chainID string, height int64, reason string, err error) Record {
+	data := FailedBlockData{Height: height, Reason: reason}
+	if err != nil {
+		data.Error = err.Error()
+	}
+
+	return Record{
+		ChainID: chainID,
+		Height:  height,
+		Time:    time.Now(),
+		Type:    RecordTypeFailedBlock,
+		Key:     fmt.Sprintf("%d", height),
+		Data:    data,
+	}
+}
+
+func sampleRecords() []Record {
+	block := models.Block{
+		Height:              1,
+		ProposerConsAddress: models.Address{Address: "cosmosvalcons1example"},
+	}
+
+	txs := []db.TxDBWrapper{{
+		Tx: models.Tx{
+			Hash:            "EXAMPLE",
+			SignerAddresses: []models.Address{{Address: "cosmos1example"}},
+			Fees:            []models.Fee{{Denomination: models.Denom{Base: "uatom"}, PayerAddress: models.Address{Address: "cosmos1example"}}},
+		},
+		Messages: []db.MessageDBWrapper{{
+			Message: models.Message{MessageType: models.MessageType{MessageType: "/cosmos.bank.v1beta1.MsgSend"}},
+			MessageEvents: []db.MessageEventDBWrapper{{
+				MessageEvent: models.MessageEvent{MessageEventType: models.MessageEventType{Type: "transfer"}},
+				Attributes:   []models.MessageEventAttribute{{Value: "1uatom", MessageEventAttributeKey: models.MessageEventAttributeKey{Key: "amount"}}},
+			}},
+		}},
+	}}
+
+	blockEvents := &db.BlockDBWrapper{
+		Block: &block,
+		BeginBlockEvents: []db.BlockEventDBWrapper{{
+			BlockEvent: models.BlockEvent{BlockEventType: models.BlockEventType{Type: "mint"}},
+			Attributes: []models.BlockEventAttribute{{Value: "1", BlockEventAttributeKey: models.BlockEventAttributeKey{Key: "amount"}}},
+		}},
+	}
+
+	records := []Record{NewBlockRecord("example-1", block)}
+	records = append(records, NewTxRecords("example-1", block, txs)...)
+	records = append(records, NewBlockEventRecords("example-1", blockEvents)...)
+	return append(records, NewFailedBlockRecord("example-1", 1, "Failed to process block event", errors.New("example")))
+}
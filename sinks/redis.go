@@ -0,0 +1,101 @@
+package sinks
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisConfig struct {
+	Address      string
+	Username     string
+	Password     string
+	DB           int
+	KeyPrefix    string
+	Encoding     string
+	PubSub       bool
+	Streams      bool
+	StreamMaxLen int64
+}
+
+// RedisSink publishes records to Redis pub/sub channels and/or appends them to Redis streams.
+// Channels and streams are named <key-prefix>:<chain-id>:<record type>.
+type RedisSink struct {
+	client *redis.Client
+	conf   RedisConfig
+	encode Encoder
+}
+
+func NewRedisSink(conf RedisConfig) (*RedisSink, error) {
+	encode, err := NewEncoder(conf.Encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     conf.Address,
+		Username: conf.Username,
+		Password: conf.Password,
+		DB:       conf.DB,
+	})
+
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to Redis at %s: %w", conf.Address, err)
+	}
+
+	return &RedisSink{client: client, conf: conf, encode: encode}, nil
+}
+
+func (s *RedisSink) Name() string {
+	return "redis"
+}
+
+func (s *RedisSink) key(record Record) string {
+	if s.conf.KeyPrefix == "" {
+		return fmt.Sprintf("%s:%s", record.ChainID, record.Type)
+	}
+	return fmt.Sprintf("%s:%s:%s", s.conf.KeyPrefix, record.ChainID, record.Type)
+}
+
+// Write sends all records in a single pipeline
+func (s *RedisSink) Write(ctx context.Context, records []Record) error {
+	pipe := s.client.Pipeline()
+
+	for _, record := range records {
+		payload, err := s.encode(record)
+		if err != nil {
+			return err
+		}
+
+		key := s.key(record)
+
+		if s.conf.Streams {
+			args := &redis.XAddArgs{
+				Stream: key,
+				Values: map[string]any{
+					"key":    record.Key,
+					"height": record.Height,
+					"data":   payload,
+				},
+			}
+			if s.conf.StreamMaxLen > 0 {
+				args.MaxLen = s.conf.StreamMaxLen
+				args.Approx = true
+			}
+			pipe.XAdd(ctx, args)
+		}
+
+		if s.conf.PubSub {
+			pipe.Publish(ctx, key, payload)
+		}
+	}
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisSink) Close() error {
+	return s.client.Close()
+}
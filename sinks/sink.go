@@ -0,0 +1,34 @@
+package sinks
+
+import (
+	"context"
+	"time"
+)
+
+type RecordType string
+
+const (
+	RecordTypeBlock      RecordType = "block"
+	RecordTypeTx         RecordType = "tx"
+	RecordTypeBlockEvent RecordType = "block_event"
+	// RecordTypeFailedBlock records are published when a block could not be processed
+	RecordTypeFailedBlock RecordType = "failed_block"
+)
+
+// Record is a single indexed item handed to the sinks once the block it belongs to has been committed to the database
+type Record struct {
+	ChainID string     `json:"chain_id"`
+	Height  int64      `json:"height"`
+	Time    time.Time  `json:"time"`
+	Type    RecordType `json:"type"`
+	// Key uniquely identifies the record within the chain, e.g. the tx hash
+	Key  string `json:"key"`
+	Data any    `json:"data"`
+}
+
+// Sink is an output destination for indexed data. Write calls are serialized by the Dispatcher.
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, records []Record) error
+	Close() error
+}
@@ -0,0 +1,505 @@
+package sinks
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/apache/arrow/go/v11/parquet/file"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type memorySink struct {
+	records []Record
+	err     error
+}
+
+func (s *memorySink) Name() string {
+	return "memory"
+}
+
+func (s *memorySink) Write(_ context.Context, records []Record) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.records = append(s.records, records...)
+	return nil
+}
+
+func (s *memorySink) Close() error {
+	return nil
+}
+
+type SinksTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SinksTestSuite) TestNewTxRecords() {
+	block := models.Block{Height: 10}
+	txs := []db.TxDBWrapper{{
+		Tx: models.Tx{Hash: "ABC", SignerAddresses: []models.Address{{Address: "cosmos1signer"}}},
+		Messages: []db.MessageDBWrapper{{
+			Message: models.Message{MessageIndex: 0, MessageType: models.MessageType{MessageType: "/cosmos.bank.v1beta1.MsgSend"}},
+			MessageEvents: []db.MessageEventDBWrapper{{
+				MessageEvent: models.MessageEvent{MessageEventType: models.MessageEventType{Type: "transfer"}},
+				Attributes:   []models.MessageEventAttribute{{Value: "10uatom", MessageEventAttributeKey: models.MessageEventAttributeKey{Key: "amount"}}},
+			}},
+		}},
+	}}
+
+	records := NewTxRecords("cosmoshub-4", block, txs)
+	suite.Require().Len(records, 1)
+	suite.Require().Equal(RecordTypeTx, records[0].Type)
+	suite.Require().Equal("ABC", records[0].Key)
+
+	txData := records[0].Data.(TxData)
+	suite.Require().Equal([]string{"cosmos1signer"}, txData.Signers)
+	suite.Require().Equal("/cosmos.bank.v1beta1.MsgSend", txData.Messages[0].Type)
+	suite.Require().Equal(AttributeData{Key: "amount", Value: "10uatom"}, txData.Messages[0].Events[0].Attributes[0])
+}
+
+func (suite *SinksTestSuite) TestNewBlockEventRecords() {
+	wrapper := &db.BlockDBWrapper{
+		Block: &models.Block{Height: 5},
+		BeginBlockEvents: []db.BlockEventDBWrapper{{
+			BlockEvent: models.BlockEvent{Index: 0, BlockEventType: models.BlockEventType{Type: "mint"}},
+		}},
+		EndBlockEvents: []db.BlockEventDBWrapper{{
+			BlockEvent: models.BlockEvent{Index: 0, BlockEventType: models.BlockEventType{Type: "complete_unbonding"}},
+		}},
+	}
+
+	records := NewBlockEventRecords("cosmoshub-4", wrapper)
+	suite.Require().Len(records, 2)
+	suite.Require().Equal("5/begin_block/0", records[0].Key)
+	suite.Require().Equal("5/end_block/0", records[1].Key)
+}
+
+func (suite *SinksTestSuite) TestEncoders() {
+	record := Record{ChainID: "cosmoshub-4", Height: 1, Type: RecordTypeBlock, Key: "1"}
+
+	encode, err := NewEncoder(EncodingJSON)
+	suite.Require().NoError(err)
+	plain, err := encode(record)
+	suite.Require().NoError(err)
+
+	var decoded map[string]any
+	suite.Require().NoError(json.Unmarshal(plain, &decoded))
+	suite.Require().Equal("cosmoshub-4", decoded["chain_id"])
+
+	encode, err = NewEncoder(EncodingJSONGzip)
+	suite.Require().NoError(err)
+	compressed, err := encode(record)
+	suite.Require().NoError(err)
+
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	suite.Require().NoError(err)
+	decompressed, err := io.ReadAll(reader)
+	suite.Require().NoError(err)
+	suite.Require().Equal(plain, decompressed)
+
+	_, err = NewEncoder("xml")
+	suite.Require().Error(err)
+}
+
+func (suite *SinksTestSuite) TestDispatcherAttemptsAllSinks() {
+	failing := &memorySink{err: errors.New("unavailable")}
+	working := &memorySink{}
+	dispatcher := NewDispatcher(failing, working)
+	suite.Require().True(dispatcher.Enabled())
+
+	err := dispatcher.Publish(context.Background(), []Record{{Key: "1"}})
+	suite.Require().ErrorIs(err, failing.err)
+	suite.Require().Len(working.records, 1)
+
+	suite.Require().False(NewDispatcher().Enabled())
+}
+
+func (suite *SinksTestSuite) TestPubSubSinkWrite() {
+	var published struct {
+		Messages []pubSubMessage `json:"messages"`
+	}
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		suite.Require().NoError(json.NewDecoder(r.Body).Decode(&published))
+		_, _ = w.Write([]byte(`{"messageIds":["1"]}`))
+	}))
+	defer server.Close()
+
+	sink, err := NewPubSubSink(context.Background(), PubSubConfig{ProjectID: "project", Topic: "topic", Endpoint: server.URL, OrderingKeys: true})
+	suite.Require().NoError(err)
+
+	err = sink.Write(context.Background(), []Record{{ChainID: "cosmoshub-4", Height: 7, Type: RecordTypeTx, Key: "ABC"}})
+	suite.Require().NoError(err)
+	suite.Require().Equal("/v1/projects/project/topics/topic:publish", path)
+	suite.Require().Len(published.Messages, 1)
+	suite.Require().Equal("cosmoshub-4", published.Messages[0].OrderingKey)
+	suite.Require().Equal("7", published.Messages[0].Attributes["height"])
+}
+
+func (suite *SinksTestSuite) TestAWSMessagesAndBatches() {
+	encode, err := NewEncoder(EncodingJSON)
+	suite.Require().NoError(err)
+	sink := &AWSSink{conf: AWSConfig{FailureTopicARN: "arn:failures", TopicARN: "arn:data", MessageAttributes: []string{"chain_id", "height", "type"}}, encode: encode}
+
+	record := NewFailedBlockRecord("cosmoshub-4", 10, "Failed to process block event", errors.New("boom"))
+	message, err := sink.newMessage(record)
+	suite.Require().NoError(err)
+	suite.Require().Equal(map[string]string{"chain_id": "cosmoshub-4", "height": "10", "type": "failed_block"}, message.attributes)
+	suite.Require().Equal("cosmoshub-4", message.groupID)
+	suite.Require().Len(message.dedupID, 64)
+
+	topic, queue := sink.destinations(RecordTypeFailedBlock)
+	suite.Require().Equal("arn:failures", topic)
+	suite.Require().Empty(queue)
+	topic, _ = sink.destinations(RecordTypeTx)
+	suite.Require().Equal("arn:data", topic)
+
+	messages := make([]awsMessage, 25)
+	batches := awsBatches(messages)
+	suite.Require().Len(batches, 3)
+	suite.Require().Len(batches[2], 5)
+	suite.Require().Equal("9", batches[0][9].id)
+
+	large := []awsMessage{{body: string(make([]byte, 200*1024))}, {body: string(make([]byte, 100*1024))}}
+	suite.Require().Len(awsBatches(large), 2)
+}
+
+func (suite *SinksTestSuite) TestKafkaMessages() {
+	encode, err := newKafkaEncoder(EncodingProtobuf)
+	suite.Require().NoError(err)
+	sink := &KafkaSink{conf: KafkaConfig{TopicPrefix: "indexer", Encoding: EncodingProtobuf}, encode: encode}
+
+	record := Record{ChainID: "cosmoshub-4", Height: 7, Type: RecordTypeTx, Key: "ABC", Data: map[string]any{"code": 0}}
+	message, err := sink.message(record)
+	suite.Require().NoError(err)
+	suite.Require().Equal("indexer.tx", message.Topic)
+	suite.Require().Equal("cosmoshub-4", string(message.Key))
+	suite.Require().Len(message.Headers, 4)
+	suite.Require().Equal("7", string(message.Headers[1].Value))
+
+	var decoded structpb.Struct
+	suite.Require().NoError(proto.Unmarshal(message.Value, &decoded))
+	suite.Require().Equal("ABC", decoded.Fields["key"].GetStringValue())
+	suite.Require().Equal(float64(7), decoded.Fields["height"].GetNumberValue())
+	suite.Require().Equal(float64(0), decoded.Fields["data"].GetStructValue().Fields["code"].GetNumberValue())
+
+	sink.conf.TopicPrefix = ""
+	suite.Require().Equal("failed_block", sink.topic(RecordTypeFailedBlock))
+
+	// The other sinks only take the JSON encodings
+	_, err = NewEncoder(EncodingProtobuf)
+	suite.Require().Error(err)
+}
+
+func (suite *SinksTestSuite) TestAMQPRoutes() {
+	encode, err := NewEncoder(EncodingJSONGzip)
+	suite.Require().NoError(err)
+	sink := &AMQPSink{conf: AMQPConfig{
+		Exchange:    "indexer",
+		Exchanges:   map[RecordType]string{RecordTypeBlockEvent: "events", RecordTypeFailedBlock: ""},
+		RoutingKeys: map[RecordType]string{RecordTypeTx: "chains.{chain_id}.txs"},
+		Encoding:    EncodingJSONGzip,
+	}, encode: encode}
+
+	suite.Require().Equal("indexer", sink.exchange(RecordTypeTx))
+	suite.Require().Equal("events", sink.exchange(RecordTypeBlockEvent))
+	// An empty override falls back to the shared exchange
+	suite.Require().Equal("indexer", sink.exchange(RecordTypeFailedBlock))
+	suite.Require().Equal([]string{"indexer", "events"}, sink.exchangeNames())
+
+	suite.Require().Equal("chains.cosmoshub-4.txs", sink.routingKey(Record{ChainID: "cosmoshub-4", Type: RecordTypeTx}))
+	suite.Require().Equal("cosmoshub-4.block", sink.routingKey(Record{ChainID: "cosmoshub-4", Type: RecordTypeBlock}))
+
+	record := Record{ChainID: "cosmoshub-4", Height: 7, Type: RecordTypeTx, Key: "ABC", Time: time.Unix(100, 0)}
+	publishing, err := sink.publishing(record)
+	suite.Require().NoError(err)
+	suite.Require().Equal("ABC", publishing.MessageId)
+	suite.Require().Equal("tx", publishing.Type)
+	suite.Require().Equal("gzip", publishing.ContentEncoding)
+	suite.Require().Equal(int64(7), publishing.Headers["height"])
+	suite.Require().Equal("cosmoshub-4", publishing.Headers["chain_id"])
+
+	reader, err := gzip.NewReader(bytes.NewReader(publishing.Body))
+	suite.Require().NoError(err)
+	var decoded map[string]any
+	suite.Require().NoError(json.NewDecoder(reader).Decode(&decoded))
+	suite.Require().Equal("ABC", decoded["key"])
+
+	// The default exchange cannot be declared
+	sink.conf = AMQPConfig{}
+	suite.Require().Empty(sink.exchangeNames())
+}
+
+type memoryCheckpointStore struct {
+	heights map[RecordType]int64
+}
+
+func (s *memoryCheckpointStore) Load(_ string, _ string) (map[RecordType]int64, error) {
+	return s.heights, nil
+}
+
+func (s *memoryCheckpointStore) Save(_ string, _ string, recordType RecordType, height int64) error {
+	s.heights[recordType] = height
+	return nil
+}
+
+func (suite *SinksTestSuite) TestEventHubsCheckpointedDelivery() {
+	var received []eventHubsEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		suite.Require().Equal(eventHubsContentType, r.Header.Get("Content-Type"))
+		suite.Require().Contains(r.Header.Get("Authorization"), "skn=send")
+
+		var batch []eventHubsEvent
+		suite.Require().NoError(json.NewDecoder(r.Body).Decode(&batch))
+		received = append(received, batch...)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	store := &memoryCheckpointStore{heights: map[RecordType]int64{RecordTypeBlock: 5}}
+	sink, err := NewEventHubsSink(EventHubsConfig{
+		ConnectionString: "Endpoint=sb://example.servicebus.windows.net/;SharedAccessKeyName=send;SharedAccessKey=c2VjcmV0=;EntityPath=blocks",
+		ChainID:          "cosmoshub-4",
+		Checkpoints:      store,
+	})
+	suite.Require().NoError(err)
+	suite.Require().Equal("https://example.servicebus.windows.net/blocks", sink.resource)
+	sink.url = server.URL
+
+	records := []Record{
+		NewBlockRecord("cosmoshub-4", models.Block{Height: 5}),
+		NewBlockRecord("cosmoshub-4", models.Block{Height: 6}),
+	}
+	suite.Require().NoError(sink.Write(context.Background(), records))
+	suite.Require().Len(received, 1)
+	suite.Require().Equal("cosmoshub-4", received[0].BrokerProperties["PartitionKey"])
+	suite.Require().Equal("6", received[0].UserProperties["height"])
+	suite.Require().Equal(int64(6), store.heights[RecordTypeBlock])
+
+	// Redelivery after a restart is skipped
+	suite.Require().NoError(sink.Write(context.Background(), records))
+	suite.Require().Len(received, 1)
+}
+
+func (suite *SinksTestSuite) TestBigQuerySchemaAndStreamingInserts() {
+	var created []string
+	var patched []bigQueryField
+	var inserted struct {
+		Rows []struct {
+			InsertID string         `json:"insertId"`
+			JSON     map[string]any `json:"json"`
+		} `json:"rows"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/bigquery/v2/projects/project/datasets/dataset/tables/idx_txs":
+			// An older txs table without the fees column
+			_, _ = w.Write([]byte(`{"schema":{"fields":[{"name":"chain_id","type":"STRING","mode":"REQUIRED"},{"name":"hash","type":"STRING"}]}}`))
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+		case r.Method == http.MethodPost && r.URL.Path == "/bigquery/v2/projects/project/datasets/dataset/tables":
+			var table struct {
+				TableReference struct {
+					TableID string `json:"tableId"`
+				} `json:"tableReference"`
+				TimePartitioning map[string]string `json:"timePartitioning"`
+			}
+			suite.Require().NoError(json.NewDecoder(r.Body).Decode(&table))
+			suite.Require().Equal("block_time", table.TimePartitioning["field"])
+			created = append(created, table.TableReference.TableID)
+			_, _ = w.Write([]byte(`{}`))
+		case r.Method == http.MethodPatch:
+			var table struct {
+				Schema struct {
+					Fields []bigQueryField `json:"fields"`
+				} `json:"schema"`
+			}
+			suite.Require().NoError(json.NewDecoder(r.Body).Decode(&table))
+			patched = table.Schema.Fields
+			_, _ = w.Write([]byte(`{}`))
+		case r.URL.Path == "/bigquery/v2/projects/project/datasets/dataset/tables/idx_blocks/insertAll":
+			suite.Require().NoError(json.NewDecoder(r.Body).Decode(&inserted))
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			_, _ = w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	sink, err := NewBigQuerySink(context.Background(), BigQueryConfig{ProjectID: "project", Dataset: "dataset", TablePrefix: "idx_", Endpoint: server.URL, Mode: BigQueryModeStreaming})
+	suite.Require().NoError(err)
+	suite.Require().Equal([]string{"idx_blocks", "idx_block_events", "idx_failed_blocks"}, created)
+
+	// The existing columns are kept, missing ones are added as nullable
+	suite.Require().Equal("chain_id", patched[0].Name)
+	suite.Require().Equal("hash", patched[1].Name)
+	suite.Require().Len(patched, len(bigQuerySchema(RecordTypeTx)))
+	suite.Require().Equal("NULLABLE", patched[2].Mode)
+
+	record := NewBlockRecord("cosmoshub-4", models.Block{Height: 7, ProposerConsAddress: models.Address{Address: "cosmosvalcons1"}})
+	suite.Require().NoError(sink.Write(context.Background(), []Record{record}))
+	suite.Require().Len(inserted.Rows, 1)
+	suite.Require().Equal("cosmoshub-4/block/7", inserted.Rows[0].InsertID)
+	suite.Require().Equal("cosmoshub-4", inserted.Rows[0].JSON["chain_id"])
+	suite.Require().Contains(inserted.Rows[0].JSON, "proposer_cons_address")
+}
+
+func (suite *SinksTestSuite) TestDeltaCommitsPerBlockBatch() {
+	location := suite.T().TempDir()
+	sink, err := NewDeltaSink(DeltaConfig{Location: location, BatchBlocks: 2})
+	suite.Require().NoError(err)
+
+	blockTime := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	suite.Require().NoError(sink.Write(context.Background(), []Record{NewBlockRecord("cosmoshub-4", models.Block{Height: 2, TimeStamp: blockTime})}))
+	_, err = os.Stat(filepath.Join(location, "blocks", "_delta_log"))
+	suite.Require().True(os.IsNotExist(err), "a partial batch must not be committed")
+
+	suite.Require().NoError(sink.Write(context.Background(), sampleRecords()))
+	log, err := os.ReadFile(filepath.Join(location, "blocks", "_delta_log", "00000000000000000000.json"))
+	suite.Require().NoError(err)
+	suite.Require().Contains(string(log), `"protocol"`)
+	suite.Require().Contains(string(log), `"metaData"`)
+
+	var add struct {
+		Add struct {
+			Path string `json:"path"`
+		} `json:"add"`
+	}
+	lines := strings.Split(strings.TrimSpace(string(log)), "\n")
+	suite.Require().NoError(json.Unmarshal([]byte(lines[len(lines)-1]), &add))
+
+	data, err := os.ReadFile(filepath.Join(location, "blocks", add.Add.Path))
+	suite.Require().NoError(err)
+	reader, err := file.NewParquetReader(bytes.NewReader(data))
+	suite.Require().NoError(err)
+	suite.Require().Equal(int64(2), reader.NumRows())
+
+	// Later batches only add files
+	suite.Require().NoError(sink.Write(context.Background(), []Record{NewBlockRecord("cosmoshub-4", models.Block{Height: 3})}))
+	suite.Require().NoError(sink.Close())
+	log, err = os.ReadFile(filepath.Join(location, "blocks", "_delta_log", "00000000000000000001.json"))
+	suite.Require().NoError(err)
+	suite.Require().NotContains(string(log), `"metaData"`)
+	suite.Require().FileExists(filepath.Join(location, "txs", "_delta_log", "00000000000000000000.json"))
+}
+
+func (suite *SinksTestSuite) TestDeltaVersionsAndConflictingCommits() {
+	location := suite.T().TempDir()
+	logDir := filepath.Join(location, "blocks", "_delta_log")
+	first, err := NewDeltaSink(DeltaConfig{Location: location, BatchBlocks: 1})
+	suite.Require().NoError(err)
+	suite.Require().NoError(first.Write(context.Background(), []Record{NewBlockRecord("cosmoshub-4", models.Block{Height: 1})}))
+
+	// Another writer continues the table log after the versions it holds
+	second, err := NewDeltaSink(DeltaConfig{Location: location, BatchBlocks: 1})
+	suite.Require().NoError(err)
+	suite.Require().NoError(second.Write(context.Background(), []Record{NewBlockRecord("cosmoshub-4", models.Block{Height: 2})}))
+	log, err := os.ReadFile(filepath.Join(logDir, "00000000000000000001.json"))
+	suite.Require().NoError(err)
+	suite.Require().NotContains(string(log), `"metaData"`)
+	suite.Require().Contains(string(log), "part-2-2-")
+
+	// The first writer still expects version 1, its commit conflicts and does not overwrite the commit of the other
+	// writer. The records stay buffered.
+	err = first.Write(context.Background(), []Record{NewBlockRecord("cosmoshub-4", models.Block{Height: 3})})
+	suite.Require().ErrorIs(err, errObjectExists)
+	unchanged, err := os.ReadFile(filepath.Join(logDir, "00000000000000000001.json"))
+	suite.Require().NoError(err)
+	suite.Require().Equal(log, unchanged)
+
+	// The next commit reads the log again and takes the version after the latest
+	suite.Require().NoError(first.Close())
+	log, err = os.ReadFile(filepath.Join(logDir, "00000000000000000002.json"))
+	suite.Require().NoError(err)
+	suite.Require().Contains(string(log), "part-3-3-")
+	entries, err := os.ReadDir(logDir)
+	suite.Require().NoError(err)
+	suite.Require().Len(entries, 3)
+}
+
+func (suite *SinksTestSuite) TestFileSinkRotation() {
+	directory := suite.T().TempDir()
+	sink, err := NewFileSink(FileConfig{Directory: directory, MaxSize: 1, Gzip: true})
+	suite.Require().NoError(err)
+
+	// Every record exceeds the max size, so each gets its own file
+	records := []Record{NewBlockRecord("cosmoshub-4", models.Block{Height: 1}), NewBlockRecord("cosmoshub-4", models.Block{Height: 2})}
+	suite.Require().NoError(sink.Write(context.Background(), records))
+	completed, err := filepath.Glob(filepath.Join(directory, "block-*.jsonl.gz"))
+	suite.Require().NoError(err)
+	suite.Require().Len(completed, 2)
+
+	reader, err := os.Open(completed[0])
+	suite.Require().NoError(err)
+	defer reader.Close()
+	uncompressed, err := gzip.NewReader(reader)
+	suite.Require().NoError(err)
+	var record Record
+	suite.Require().NoError(json.NewDecoder(uncompressed).Decode(&record))
+	suite.Require().Equal(int64(1), record.Height)
+
+	sink.conf.MaxSize = 0
+	suite.Require().NoError(sink.Write(context.Background(), []Record{{ChainID: "cosmoshub-4", Height: 3, Type: RecordTypeTx}}))
+	inProgress, err := filepath.Glob(filepath.Join(directory, "tx-*.jsonl.gz"+inProgressSuffix))
+	suite.Require().NoError(err)
+	suite.Require().Len(inProgress, 1)
+
+	suite.Require().NoError(sink.Close())
+	suite.Require().NoFileExists(inProgress[0])
+	suite.Require().FileExists(strings.TrimSuffix(inProgress[0], inProgressSuffix))
+}
+
+func (suite *SinksTestSuite) TestTemplateOutputs() {
+	forwarded := &memorySink{}
+	outputFile := filepath.Join(suite.T().TempDir(), "transfers.log")
+	sink, err := NewTemplateSink([]TemplateOutput{
+		{
+			Name:        "transfers",
+			Match:       TemplateMatch{RecordType: RecordTypeTx, MessageType: "/cosmos.bank.v1beta1.MsgSend", EventType: "transfer"},
+			Template:    `{{ .Height }} {{ .Tx.Hash }} {{ attr .Event "amount" }}`,
+			Destination: TemplateDestination{Type: TemplateDestinationFile, Path: outputFile},
+		},
+		{
+			Name:        "mints",
+			Match:       TemplateMatch{RecordType: RecordTypeBlockEvent, EventType: "mint", Attributes: map[string]string{"amount": "1"}},
+			Template:    `{"amount": {{ json (attr .Event "amount") }}}`,
+			Format:      "json",
+			Destination: TemplateDestination{Type: TemplateDestinationSink, Sink: "memory"},
+		},
+	}, map[string]Sink{"memory": forwarded})
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(sink.Write(context.Background(), sampleRecords()))
+	suite.Require().NoError(sink.Close())
+
+	rendered, err := os.ReadFile(outputFile)
+	suite.Require().NoError(err)
+	suite.Require().Equal("1 EXAMPLE 1uatom\n", string(rendered))
+
+	suite.Require().Len(forwarded.records, 1)
+	suite.Require().Equal(RecordTypeDerived, forwarded.records[0].Type)
+	suite.Require().Equal(json.RawMessage(`{"amount":"1"}`), forwarded.records[0].Data.(DerivedData).Payload)
+
+	_, err = NewTemplateSink([]TemplateOutput{{Name: "invalid", Match: TemplateMatch{RecordType: RecordTypeTx}, Destination: TemplateDestination{Type: TemplateDestinationSink, Sink: "kafka"}}}, nil)
+	suite.Require().Error(err)
+}
+
+func TestSinksTestSuite(t *testing.T) {
+	suite.Run(t, new(SinksTestSuite))
+}
@@ -0,0 +1,397 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// RecordTypeDerived records carry the payloads rendered by template outputs to other sinks
+const RecordTypeDerived RecordType = "derived"
+
+const (
+	TemplateDestinationFile    = "file"
+	TemplateDestinationWebhook = "webhook"
+	TemplateDestinationSink    = "sink"
+)
+
+// TemplateOutput renders the messages or block events matching its filter with a Go template and sends the result to
+// a destination. Outputs are declared in a JSON file, e.g.
+//
+//	{"outputs": [{
+//	  "name": "delegations",
+//	  "match": {"record_type": "tx", "message_type": "/cosmos.staking.v1beta1.MsgDelegate", "event_type": "delegate"},
+//	  "template": "{\"validator\": {{ json (attr .Event \"validator\") }}, \"height\": {{ .Height }}}",
+//	  "format": "json",
+//	  "destination": {"type": "webhook", "url": "https://example.com/delegations"}
+//	}]}
+type TemplateOutput struct {
+	Name        string              `json:"name"`
+	Match       TemplateMatch       `json:"match"`
+	Template    string              `json:"template"`
+	Format      string              `json:"format"`
+	Destination TemplateDestination `json:"destination"`
+}
+
+// TemplateMatch selects what an output renders. Tx outputs render once per matching message, block event outputs once
+// per matching event. All set fields must match, the attributes must be present on the matched event.
+type TemplateMatch struct {
+	RecordType  RecordType        `json:"record_type"`
+	MessageType string            `json:"message_type"`
+	EventType   string            `json:"event_type"`
+	Attributes  map[string]string `json:"attributes"`
+}
+
+type TemplateDestination struct {
+	Type string `json:"type"`
+	// Path is the file rendered payloads are appended to, one per line
+	Path string `json:"path"`
+	// URL and Headers configure the webhook every payload is POSTed to
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	// Sink is the name of an enabled sink the payloads are written to as derived records, e.g. redis or amqp
+	Sink string `json:"sink"`
+}
+
+// TemplateContext is the data templates are executed with. Message is only set for tx outputs, Event is the first
+// event matching the output, if it filters on an event type.
+type TemplateContext struct {
+	ChainID    string
+	Height     int64
+	Time       time.Time
+	Tx         *TxData
+	Message    *MessageData
+	BlockEvent *BlockEventData
+	Event      *EventData
+}
+
+type DerivedData struct {
+	Output  string `json:"output"`
+	Payload any    `json:"payload"`
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(value any) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+	"attr": eventAttribute,
+	// event returns the first event of the message with the type
+	"event": func(message *MessageData, eventType string) *EventData {
+		if message == nil {
+			return nil
+		}
+		for i := range message.Events {
+			if message.Events[i].Type == eventType {
+				return &message.Events[i]
+			}
+		}
+		return nil
+	},
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// eventAttribute returns the value of the first attribute of the event with the key
+func eventAttribute(event *EventData, key string) string {
+	if event == nil {
+		return ""
+	}
+	for _, attribute := range event.Attributes {
+		if attribute.Key == key {
+			return attribute.Value
+		}
+	}
+	return ""
+}
+
+// LoadTemplateOutputs reads the outputs declared in a JSON file
+func LoadTemplateOutputs(path string) ([]TemplateOutput, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file struct {
+		Outputs []TemplateOutput `json:"outputs"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse template outputs %s: %w", path, err)
+	}
+
+	return file.Outputs, nil
+}
+
+type templateOutput struct {
+	TemplateOutput
+	template *template.Template
+	sink     Sink
+	file     *os.File
+}
+
+// TemplateSink renders template outputs for the records it receives
+type TemplateSink struct {
+	outputs []*templateOutput
+	client  *http.Client
+}
+
+// NewTemplateSink compiles the outputs, sinks holds the enabled sinks outputs may write to by name
+func NewTemplateSink(outputs []TemplateOutput, sinks map[string]Sink) (*TemplateSink, error) {
+	sink := &TemplateSink{client: &http.Client{Timeout: 30 * time.Second}}
+
+	for _, output := range outputs {
+		compiled, err := newTemplateOutput(output, sinks)
+		if err != nil {
+			sink.Close()
+			return nil, fmt.Errorf("invalid template output %q: %w", output.Name, err)
+		}
+		sink.outputs = append(sink.outputs, compiled)
+	}
+
+	return sink, nil
+}
+
+func newTemplateOutput(output TemplateOutput, sinks map[string]Sink) (*templateOutput, error) {
+	if output.Name == "" {
+		return nil, errors.New("outputs must be named")
+	}
+
+	if output.Match.RecordType != RecordTypeTx && output.Match.RecordType != RecordTypeBlockEvent {
+		return nil, fmt.Errorf("match.record_type must be %s or %s", RecordTypeTx, RecordTypeBlockEvent)
+	}
+
+	if output.Format != "" && output.Format != "text" && output.Format != "json" {
+		return nil, errors.New("format must be text or json")
+	}
+
+	tmpl, err := template.New(output.Name).Funcs(templateFuncs).Option("missingkey=error").Parse(output.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	compiled := &templateOutput{TemplateOutput: output, template: tmpl}
+
+	switch output.Destination.Type {
+	case TemplateDestinationFile:
+		compiled.file, err = os.OpenFile(output.Destination.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, err
+		}
+	case TemplateDestinationWebhook:
+		if output.Destination.URL == "" {
+			return nil, errors.New("webhook destinations need a url")
+		}
+	case TemplateDestinationSink:
+		compiled.sink = sinks[output.Destination.Sink]
+		if compiled.sink == nil {
+			return nil, fmt.Errorf("sink %q is not enabled", output.Destination.Sink)
+		}
+	default:
+		return nil, fmt.Errorf("destination type must be %s, %s or %s", TemplateDestinationFile, TemplateDestinationWebhook, TemplateDestinationSink)
+	}
+
+	return compiled, nil
+}
+
+func (s *TemplateSink) Name() string {
+	return "template"
+}
+
+func (s *TemplateSink) Write(ctx context.Context, records []Record) error {
+	for _, output := range s.outputs {
+		var payloads []Record
+
+		for _, record := range records {
+			if record.Type != output.Match.RecordType {
+				continue
+			}
+
+			for i, data := range output.matches(record) {
+				rendered, err := output.render(data)
+				if err != nil {
+					return fmt.Errorf("failed to render template output %s at height %d: %w", output.Name, record.Height, err)
+				}
+
+				payload := Record{
+					ChainID: record.ChainID,
+					Height:  record.Height,
+					Time:    record.Time,
+					Type:    RecordTypeDerived,
+					Key:     fmt.Sprintf("%s/%s/%d", output.Name, record.Key, i),
+					Data:    DerivedData{Output: output.Name, Payload: rendered},
+				}
+				payloads = append(payloads, payload)
+			}
+		}
+
+		if len(payloads) == 0 {
+			continue
+		}
+
+		if err := s.send(ctx, output, payloads); err != nil {
+			return fmt.Errorf("failed to send template output %s: %w", output.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// matches returns the template context of every match of the output in the record
+func (o *templateOutput) matches(record Record) []TemplateContext {
+	var matched []TemplateContext
+	base := TemplateContext{ChainID: record.ChainID, Height: record.Height, Time: record.Time}
+
+	switch data := record.Data.(type) {
+	case TxData:
+		for i := range data.Messages {
+			message := &data.Messages[i]
+			if o.Match.MessageType != "" && message.Type != o.Match.MessageType {
+				continue
+			}
+
+			match := base
+			match.Tx = &data
+			match.Message = message
+
+			if o.Match.EventType != "" || len(o.Match.Attributes) != 0 {
+				match.Event = o.matchEvent(message.Events)
+				if match.Event == nil {
+					continue
+				}
+			}
+
+			matched = append(matched, match)
+		}
+	case BlockEventData:
+		if event := o.matchEvent([]EventData{data.Event}); event != nil {
+			match := base
+			match.BlockEvent = &data
+			match.Event = event
+			matched = append(matched, match)
+		}
+	}
+
+	return matched
+}
+
+func (o *templateOutput) matchEvent(events []EventData) *EventData {
+	for i := range events {
+		if o.Match.EventType != "" && events[i].Type != o.Match.EventType {
+			continue
+		}
+
+		matchesAttributes := true
+		for key, value := range o.Match.Attributes {
+			if eventAttribute(&events[i], key) != value {
+				matchesAttributes = false
+				break
+			}
+		}
+
+		if matchesAttributes {
+			return &events[i]
+		}
+	}
+
+	return nil
+}
+
+// render executes the template, json outputs must render valid JSON and are passed on as raw JSON
+func (o *templateOutput) render(data TemplateContext) (any, error) {
+	var rendered bytes.Buffer
+	if err := o.template.Execute(&rendered, data); err != nil {
+		return nil, err
+	}
+
+	if o.Format != "json" {
+		return rendered.String(), nil
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, rendered.Bytes()); err != nil {
+		return nil, fmt.Errorf("rendered invalid JSON: %w", err)
+	}
+
+	return json.RawMessage(compacted.Bytes()), nil
+}
+
+func payloadBytes(payload any) []byte {
+	switch value := payload.(type) {
+	case json.RawMessage:
+		return value
+	default:
+		return []byte(fmt.Sprint(value))
+	}
+}
+
+func (s *TemplateSink) send(ctx context.Context, output *templateOutput, payloads []Record) error {
+	switch output.Destination.Type {
+	case TemplateDestinationFile:
+		var lines bytes.Buffer
+		for _, payload := range payloads {
+			lines.Write(payloadBytes(payload.Data.(DerivedData).Payload))
+			lines.WriteByte('\n')
+		}
+		_, err := output.file.Write(lines.Bytes())
+		return err
+	case TemplateDestinationWebhook:
+		for _, payload := range payloads {
+			if err := s.post(ctx, output, payloadBytes(payload.Data.(DerivedData).Payload)); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return output.sink.Write(ctx, payloads)
+	}
+}
+
+func (s *TemplateSink) post(ctx context.Context, output *templateOutput, body []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, output.Destination.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if output.Format == "json" {
+		contentType = "application/json"
+	}
+	request.Header.Set("Content-Type", contentType)
+	for key, value := range output.Destination.Headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded with %s", output.Destination.URL, response.Status)
+	}
+
+	return nil
+}
+
+// Close closes the output files, the sinks outputs write to are closed by the Dispatcher
+func (s *TemplateSink) Close() error {
+	var firstErr error
+	for _, output := range s.outputs {
+		if output.file == nil {
+			continue
+		}
+		if err := output.file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
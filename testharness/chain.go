@@ -0,0 +1,47 @@
+package testharness
+
+// Chain describes the image of a Cosmos SDK chain the harness runs as a single validator
+type Chain struct {
+	Name          string
+	Repository    string
+	Tag           string
+	Binary        string
+	Denom         string
+	AccountPrefix string
+}
+
+var (
+	// Simd is the simapp of the Cosmos SDK, with the SDK version the indexer is built against
+	Simd = Chain{Name: "simd", Repository: "ghcr.io/cosmos/ibc-go-simd", Tag: "v7.3.1", Binary: "simd", Denom: "stake", AccountPrefix: "cosmos"}
+	// Wasmd is the CosmWasm chain. Indexing its wasm messages requires registering the wasm module basics with
+	// cmd.RegisterCustomModuleBasics.
+	Wasmd = Chain{Name: "wasmd", Repository: "cosmwasm/wasmd", Tag: "v0.45.0", Binary: "wasmd", Denom: "stake", AccountPrefix: "wasm"}
+)
+
+// Keys of the genesis accounts, both are funded and ValidatorKey bonds the validator. Their mnemonics are fixed, so the
+// addresses and the transactions sent from them are the same on every run.
+const (
+	ValidatorKey = "validator"
+	UserKey      = "user"
+)
+
+var mnemonics = map[string]string{
+	ValidatorKey: "wave assume sun shoe wash once unfair master actual vessel diesel actor spend swear elder once fetch spider aim shift brown artefact jump wild",
+	UserKey:      "cup lend senior velvet sleep rely stock roast area color violin such urban endless strategy such more future crane cruel tone daring fly style",
+}
+
+// chainHome is writable by the unprivileged users the chain images run as
+const chainHome = "/tmp/chain"
+
+// initScript creates the genesis of a single validator chain with short blocks and starts it
+const initScript = `set -e
+$BINARY init harness --chain-id "$CHAIN_ID" --home "$CHAIN_HOME" > /dev/null 2>&1
+echo "$VALIDATOR_MNEMONIC" | $BINARY keys add validator --recover --keyring-backend test --home "$CHAIN_HOME" > /dev/null
+echo "$USER_MNEMONIC" | $BINARY keys add user --recover --keyring-backend test --home "$CHAIN_HOME" > /dev/null
+$BINARY genesis add-genesis-account validator "1000000000000$DENOM" --keyring-backend test --home "$CHAIN_HOME"
+$BINARY genesis add-genesis-account user "1000000000000$DENOM" --keyring-backend test --home "$CHAIN_HOME"
+$BINARY genesis gentx validator "1000000000$DENOM" --chain-id "$CHAIN_ID" --keyring-backend test --home "$CHAIN_HOME" > /dev/null 2>&1
+$BINARY genesis collect-gentxs --home "$CHAIN_HOME" > /dev/null 2>&1
+sed -i 's/^timeout_commit = .*/timeout_commit = "1s"/' "$CHAIN_HOME/config/config.toml"
+exec $BINARY start --home "$CHAIN_HOME" --rpc.laddr tcp://0.0.0.0:26657 --minimum-gas-prices "0$DENOM"
+`
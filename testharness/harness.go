@@ -0,0 +1,320 @@
+// Package testharness runs integration tests of the indexer against a local chain. It starts a single validator chain
+// and Postgres in Docker, sends transactions from genesis accounts with fixed keys, and runs the index command
+// in-process against them, so tests can check what custom parsers and the indexer wrote to the database.
+package testharness
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DefiantLabs/cosmos-indexer/cmd"
+	"github.com/DefiantLabs/cosmos-indexer/db"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+	"gorm.io/gorm"
+)
+
+// Options configures the harness, the zero value runs Simd
+type Options struct {
+	// Chain defaults to Simd
+	Chain Chain
+	// ChainID defaults to harness-1
+	ChainID string
+	// PostgresTag defaults to 15-alpine
+	PostgresTag string
+}
+
+// Harness is a running chain and database, both are removed when the test finishes
+type Harness struct {
+	Chain   Chain
+	ChainID string
+	// RPC is the address of the chain RPC server
+	RPC string
+	// DB is connected to the database the indexer writes to
+	DB *gorm.DB
+
+	pool     *dockertest.Pool
+	node     *dockertest.Resource
+	postgres *dockertest.Resource
+}
+
+// TxResult is a transaction included in a block
+type TxResult struct {
+	Hash   string
+	Height int64
+	Code   uint32
+}
+
+// Start runs the chain and the database, and waits until the chain produced its first block. The test is skipped when
+// Docker is not available.
+func Start(t testing.TB, options Options) *Harness {
+	t.Helper()
+
+	if options.Chain.Binary == "" {
+		options.Chain = Simd
+	}
+	if options.ChainID == "" {
+		options.ChainID = "harness-1"
+	}
+	if options.PostgresTag == "" {
+		options.PostgresTag = "15-alpine"
+	}
+
+	pool, err := dockertest.NewPool("")
+	if err == nil {
+		err = pool.Client.Ping()
+	}
+	if err != nil {
+		t.Skipf("Docker is not available: %v", err)
+	}
+	pool.MaxWait = 3 * time.Minute
+
+	h := &Harness{Chain: options.Chain, ChainID: options.ChainID, pool: pool}
+	t.Cleanup(h.close)
+
+	h.postgres, err = pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        options.PostgresTag,
+		Env:        []string{"POSTGRES_USER=test", "POSTGRES_PASSWORD=test", "POSTGRES_DB=test"},
+	}, autoRemove)
+	if err != nil {
+		t.Fatalf("Could not start Postgres: %v", err)
+	}
+
+	h.node, err = pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: options.Chain.Repository,
+		Tag:        options.Chain.Tag,
+		Entrypoint: []string{"sh", "-c"},
+		Cmd:        []string{initScript},
+		Env: []string{
+			"BINARY=" + options.Chain.Binary,
+			"CHAIN_ID=" + options.ChainID,
+			"CHAIN_HOME=" + chainHome,
+			"DENOM=" + options.Chain.Denom,
+			"VALIDATOR_MNEMONIC=" + mnemonics[ValidatorKey],
+			"USER_MNEMONIC=" + mnemonics[UserKey],
+		},
+		ExposedPorts: []string{"26657/tcp"},
+	}, autoRemove)
+	if err != nil {
+		t.Fatalf("Could not start %s: %v", options.Chain.Name, err)
+	}
+	h.RPC = "http://" + h.node.GetHostPort("26657/tcp")
+
+	if err := pool.Retry(func() error {
+		var err error
+		h.DB, err = db.PostgresDbConnect(h.postgres.GetBoundIP("5432/tcp"), h.postgres.GetPort("5432/tcp"), "test", "test", "test", "error", db.ConnectOptions{})
+		return err
+	}); err != nil {
+		t.Fatalf("Could not connect to Postgres: %v", err)
+	}
+
+	h.WaitForHeight(t, 1)
+	return h
+}
+
+func autoRemove(config *docker.HostConfig) {
+	config.AutoRemove = true
+	config.RestartPolicy = docker.RestartPolicy{Name: "no"}
+}
+
+func (h *Harness) close() {
+	for _, resource := range []*dockertest.Resource{h.node, h.postgres} {
+		if resource != nil {
+			h.pool.Purge(resource) //nolint:errcheck
+		}
+	}
+}
+
+// Height returns the latest height of the chain
+func (h *Harness) Height(t testing.TB) int64 {
+	t.Helper()
+
+	height, err := h.height()
+	if err != nil {
+		t.Fatalf("Could not query the chain height: %v", err)
+	}
+	return height
+}
+
+func (h *Harness) height() (int64, error) {
+	response, err := http.Get(h.RPC + "/status")
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	var status struct {
+		Result struct {
+			SyncInfo struct {
+				LatestBlockHeight string `json:"latest_block_height"`
+			} `json:"sync_info"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&status); err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(status.Result.SyncInfo.LatestBlockHeight, 10, 64)
+}
+
+// WaitForHeight waits until the chain produced the block at height
+func (h *Harness) WaitForHeight(t testing.TB, height int64) {
+	t.Helper()
+
+	err := h.pool.Retry(func() error {
+		latest, err := h.height()
+		if err != nil {
+			return err
+		}
+		if latest < height {
+			return fmt.Errorf("the chain is at height %d", latest)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("The chain did not reach height %d: %v", height, err)
+	}
+}
+
+// Address returns the address of a key
+func (h *Harness) Address(t testing.TB, key string) string {
+	t.Helper()
+
+	output := h.exec(t, "keys", "show", key, "-a", "--keyring-backend", "test", "--home", chainHome)
+	return strings.TrimSpace(output)
+}
+
+// ValidatorAddress returns the operator address of the validator
+func (h *Harness) ValidatorAddress(t testing.TB) string {
+	t.Helper()
+
+	output := h.exec(t, "keys", "show", ValidatorKey, "-a", "--bech", "val", "--keyring-backend", "test", "--home", chainHome)
+	return strings.TrimSpace(output)
+}
+
+// Send transfers amount, e.g. 1000stake, from the key to the address
+func (h *Harness) Send(t testing.TB, from string, to string, amount string) TxResult {
+	t.Helper()
+	return h.Tx(t, from, "bank", "send", from, to, amount)
+}
+
+// Delegate bonds amount from the key to the validator
+func (h *Harness) Delegate(t testing.TB, from string, amount string) TxResult {
+	t.Helper()
+	return h.Tx(t, from, "staking", "delegate", h.ValidatorAddress(t), amount)
+}
+
+// Tx signs a transaction of the tx subcommand args with the key, broadcasts it and waits until it is included in a
+// block. Transactions are sent one at a time, so their account sequences and content are the same on every run.
+func (h *Harness) Tx(t testing.TB, from string, args ...string) TxResult {
+	t.Helper()
+
+	args = append(append([]string{"tx"}, args...),
+		"--from", from, "--chain-id", h.ChainID, "--keyring-backend", "test", "--home", chainHome,
+		"--gas", "400000", "--broadcast-mode", "sync", "--output", "json", "--yes")
+
+	var broadcast struct {
+		TxHash string `json:"txhash"`
+		Code   uint32 `json:"code"`
+		RawLog string `json:"raw_log"`
+	}
+	if err := json.Unmarshal([]byte(h.exec(t, args...)), &broadcast); err != nil {
+		t.Fatalf("Could not parse the broadcast result: %v", err)
+	}
+	if broadcast.Code != 0 {
+		t.Fatalf("Transaction %s was rejected with code %d: %s", broadcast.TxHash, broadcast.Code, broadcast.RawLog)
+	}
+
+	var result TxResult
+	err := h.pool.Retry(func() error {
+		var stdout, stderr bytes.Buffer
+		code, err := h.node.Exec([]string{h.Chain.Binary, "query", "tx", broadcast.TxHash, "--output", "json", "--home", chainHome},
+			dockertest.ExecOptions{StdOut: &stdout, StdErr: &stderr})
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			return fmt.Errorf("transaction %s not included yet: %s", broadcast.TxHash, stderr.String())
+		}
+
+		var included struct {
+			Height string `json:"height"`
+			Code   uint32 `json:"code"`
+		}
+		if err := json.Unmarshal(stdout.Bytes(), &included); err != nil {
+			return err
+		}
+		result.Hash, result.Code = broadcast.TxHash, included.Code
+		result.Height, err = strconv.ParseInt(included.Height, 10, 64)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Transaction %s was not included: %v", broadcast.TxHash, err)
+	}
+	return result
+}
+
+// exec runs the chain binary in the node container and returns its output
+func (h *Harness) exec(t testing.TB, args ...string) string {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code, err := h.node.Exec(append([]string{h.Chain.Binary}, args...), dockertest.ExecOptions{StdOut: &stdout, StdErr: &stderr})
+	if err != nil {
+		t.Fatalf("Could not run %s %s: %v", h.Chain.Binary, strings.Join(args, " "), err)
+	}
+	if code != 0 {
+		t.Fatalf("%s %s exited with %d: %s", h.Chain.Binary, strings.Join(args, " "), code, stderr.String())
+	}
+	return stdout.String()
+}
+
+// Index runs the index command over the blocks from startHeight to endHeight, both inclusive, indexing transactions
+// and block events. Further flags, e.g. --base.filter-file, are appended to the command. Custom parsers and modules
+// are registered through the cmd package beforehand, as for the indexer binary.
+func (h *Harness) Index(t testing.TB, startHeight int64, endHeight int64, flags ...string) {
+	t.Helper()
+
+	h.WaitForHeight(t, endHeight)
+
+	// An empty config file keeps the config of the machine running the test out of it
+	configFile := filepath.Join(t.TempDir(), "config.toml")
+	if err := os.WriteFile(configFile, nil, 0o600); err != nil {
+		t.Fatalf("Could not write the config file: %v", err)
+	}
+
+	args := append([]string{
+		"index",
+		"--config", configFile,
+		"--log.level", "error",
+		"--database.host", h.postgres.GetBoundIP("5432/tcp"),
+		"--database.port", h.postgres.GetPort("5432/tcp"),
+		"--database.database", "test",
+		"--database.user", "test",
+		"--database.password", "test",
+		"--probe.rpc", h.RPC,
+		"--probe.account-prefix", h.Chain.AccountPrefix,
+		"--probe.chain-id", h.ChainID,
+		"--probe.chain-name", h.Chain.Name,
+		"--base.start-block", strconv.FormatInt(startHeight, 10),
+		"--base.end-block", strconv.FormatInt(endHeight, 10),
+		"--base.index-transactions",
+		"--base.index-block-events",
+		"--base.reindex",
+	}, flags...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if err := cmd.ExecuteContext(ctx, args); err != nil {
+		t.Fatalf("Indexing blocks %d-%d failed: %v", startHeight, endHeight, err)
+	}
+}
@@ -0,0 +1,48 @@
+package testharness
+
+import (
+	"testing"
+
+	"github.com/DefiantLabs/cosmos-indexer/db/models"
+	"github.com/stretchr/testify/suite"
+)
+
+type HarnessTestSuite struct {
+	suite.Suite
+	harness *Harness
+}
+
+func (suite *HarnessTestSuite) SetupSuite() {
+	if testing.Short() {
+		suite.T().Skip("the harness runs a chain in Docker")
+	}
+	suite.harness = Start(suite.T(), Options{})
+}
+
+func (suite *HarnessTestSuite) TestIndexTxs() {
+	h := suite.harness
+	send := h.Send(suite.T(), UserKey, h.Address(suite.T(), ValidatorKey), "1000"+h.Chain.Denom)
+	delegate := h.Delegate(suite.T(), UserKey, "5000"+h.Chain.Denom)
+	suite.Require().Zero(send.Code)
+	suite.Require().Zero(delegate.Code)
+
+	h.Index(suite.T(), send.Height, delegate.Height)
+
+	for _, result := range []TxResult{send, delegate} {
+		var tx models.Tx
+		suite.Require().NoError(h.DB.Where("hash = ?", result.Hash).First(&tx).Error)
+
+		var messages int64
+		suite.Require().NoError(h.DB.Model(&models.Message{}).Where("tx_id = ?", tx.ID).Count(&messages).Error)
+		suite.Require().Equal(int64(1), messages)
+	}
+
+	var block models.Block
+	suite.Require().NoError(h.DB.Where("height = ?", delegate.Height).First(&block).Error)
+	suite.Require().True(block.TxIndexed)
+	suite.Require().True(block.BlockEventsIndexed)
+}
+
+func TestHarness(t *testing.T) {
+	suite.Run(t, new(HarnessTestSuite))
+}
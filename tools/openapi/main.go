@@ -0,0 +1,27 @@
+// Writes the OpenAPI spec of the REST API to the path given as argument, run through go generate ./restapi
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DefiantLabs/cosmos-indexer/restapi"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: openapi <output path>")
+		os.Exit(2)
+	}
+
+	spec, err := restapi.Spec()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(os.Args[1], spec, 0o644); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}